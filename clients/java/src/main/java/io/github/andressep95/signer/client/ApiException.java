@@ -0,0 +1,57 @@
+// Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead of editing it.
+package io.github.andressep95.signer.client;
+
+import java.io.IOException;
+import java.util.Map;
+
+/**
+ * An error response. Decide on the code, status and retryable flag; error
+ * and message are for people and follow Accept-Language.
+ */
+public final class ApiException extends IOException {
+    private final int status;
+    private final Map<String, Object> body;
+    private final Integer retryAfter;
+
+    ApiException(int status, Map<String, Object> body, Integer retryAfter) {
+        super(status + " " + text(body, "code", text(body, "error", "")) + ": " + text(body, "message", ""));
+        this.status = status;
+        this.body = body;
+        this.retryAfter = retryAfter;
+    }
+
+    public int status() {
+        return status;
+    }
+
+    /** Machine-readable code, empty on endpoints that don't set one. */
+    public String code() {
+        return text(body, "code", "");
+    }
+
+    public String error() {
+        return text(body, "error", "");
+    }
+
+    public String errorMessage() {
+        return text(body, "message", "");
+    }
+
+    public boolean retryable() {
+        return Boolean.TRUE.equals(body.get("retryable"));
+    }
+
+    /** Seconds to wait before retrying, or null. */
+    public Integer retryAfter() {
+        return retryAfter;
+    }
+
+    public Map<String, Object> body() {
+        return body;
+    }
+
+    private static String text(Map<String, Object> body, String key, String fallback) {
+        Object value = body.get(key);
+        return value instanceof String && !((String) value).isEmpty() ? (String) value : fallback;
+    }
+}
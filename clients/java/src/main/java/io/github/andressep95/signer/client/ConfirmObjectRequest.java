@@ -0,0 +1,42 @@
+// Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead of editing it.
+package io.github.andressep95.signer.client;
+
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+/** Request body ConfirmObjectRequest. */
+public final class ConfirmObjectRequest {
+    private final Map<String, Object> fields = new LinkedHashMap<>();
+
+    public ConfirmObjectRequest() {
+    }
+
+    public ConfirmObjectRequest objectKey(String value) {
+        fields.put("object_key", value);
+        return this;
+    }
+
+    public ConfirmObjectRequest uploadId(String value) {
+        fields.put("upload_id", value);
+        return this;
+    }
+
+    public ConfirmObjectRequest runId(String value) {
+        fields.put("run_id", value);
+        return this;
+    }
+
+    public ConfirmObjectRequest filename(String value) {
+        fields.put("filename", value);
+        return this;
+    }
+
+    public ConfirmObjectRequest wrappedKey(String value) {
+        fields.put("wrapped_key", value);
+        return this;
+    }
+
+    Map<String, Object> toMap() {
+        return fields;
+    }
+}
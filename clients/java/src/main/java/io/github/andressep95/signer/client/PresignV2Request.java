@@ -0,0 +1,95 @@
+// Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead of editing it.
+package io.github.andressep95.signer.client;
+
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+/** Request body PresignV2Request. */
+public final class PresignV2Request {
+    private final Map<String, Object> fields = new LinkedHashMap<>();
+
+    public PresignV2Request(String operation) {
+        fields.put("operation", operation);
+    }
+
+    /** upload only */
+    public PresignV2Request filename(String value) {
+        fields.put("filename", value);
+        return this;
+    }
+
+    /** download and delete only */
+    public PresignV2Request objectKey(String value) {
+        fields.put("object_key", value);
+        return this;
+    }
+
+    public PresignV2Request contentType(String value) {
+        fields.put("content_type", value);
+        return this;
+    }
+
+    public PresignV2Request contentLength(long value) {
+        fields.put("content_length", value);
+        return this;
+    }
+
+    public PresignV2Request metadata(Map<String, String> value) {
+        fields.put("metadata", value);
+        return this;
+    }
+
+    public PresignV2Request objectLock(Map<String, Object> value) {
+        fields.put("object_lock", value);
+        return this;
+    }
+
+    public PresignV2Request dryRun(boolean value) {
+        fields.put("dry_run", value);
+        return this;
+    }
+
+    public PresignV2Request checksumSha256(String value) {
+        fields.put("checksum_sha256", value);
+        return this;
+    }
+
+    public PresignV2Request contentMd5(String value) {
+        fields.put("content_md5", value);
+        return this;
+    }
+
+    public PresignV2Request onDuplicate(String value) {
+        fields.put("on_duplicate", value);
+        return this;
+    }
+
+    public PresignV2Request contentEncoding(String value) {
+        fields.put("content_encoding", value);
+        return this;
+    }
+
+    public PresignV2Request notBefore(String value) {
+        fields.put("not_before", value);
+        return this;
+    }
+
+    public PresignV2Request subpath(String value) {
+        fields.put("subpath", value);
+        return this;
+    }
+
+    public PresignV2Request overwrite(boolean value) {
+        fields.put("overwrite", value);
+        return this;
+    }
+
+    public PresignV2Request sseCustomerKeyMd5(String value) {
+        fields.put("sse_customer_key_md5", value);
+        return this;
+    }
+
+    Map<String, Object> toMap() {
+        return fields;
+    }
+}
@@ -0,0 +1,68 @@
+// Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead of editing it.
+package io.github.andressep95.signer.client;
+
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+/** Request body PresignedURLRequest. */
+public final class PresignedURLRequest {
+    private final Map<String, Object> fields = new LinkedHashMap<>();
+
+    public PresignedURLRequest(String filename) {
+        fields.put("filename", filename);
+    }
+
+    public PresignedURLRequest contentType(String value) {
+        fields.put("content_type", value);
+        return this;
+    }
+
+    public PresignedURLRequest metadata(Map<String, String> value) {
+        fields.put("metadata", value);
+        return this;
+    }
+
+    public PresignedURLRequest runId(String value) {
+        fields.put("run_id", value);
+        return this;
+    }
+
+    public PresignedURLRequest dryRun(boolean value) {
+        fields.put("dry_run", value);
+        return this;
+    }
+
+    public PresignedURLRequest onDuplicate(String value) {
+        fields.put("on_duplicate", value);
+        return this;
+    }
+
+    public PresignedURLRequest contentEncoding(String value) {
+        fields.put("content_encoding", value);
+        return this;
+    }
+
+    public PresignedURLRequest notBefore(String value) {
+        fields.put("not_before", value);
+        return this;
+    }
+
+    public PresignedURLRequest contentMd5(String value) {
+        fields.put("content_md5", value);
+        return this;
+    }
+
+    public PresignedURLRequest overwrite(boolean value) {
+        fields.put("overwrite", value);
+        return this;
+    }
+
+    public PresignedURLRequest sseCustomerKeyMd5(String value) {
+        fields.put("sse_customer_key_md5", value);
+        return this;
+    }
+
+    Map<String, Object> toMap() {
+        return fields;
+    }
+}
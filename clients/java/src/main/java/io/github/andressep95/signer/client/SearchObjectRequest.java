@@ -0,0 +1,18 @@
+// Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead of editing it.
+package io.github.andressep95.signer.client;
+
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+/** Request body SearchObjectRequest. */
+public final class SearchObjectRequest {
+    private final Map<String, Object> fields = new LinkedHashMap<>();
+
+    public SearchObjectRequest(String filename) {
+        fields.put("filename", filename);
+    }
+
+    Map<String, Object> toMap() {
+        return fields;
+    }
+}
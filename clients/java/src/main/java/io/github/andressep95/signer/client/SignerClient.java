@@ -0,0 +1,145 @@
+// Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead of editing it.
+package io.github.andressep95.signer.client;
+
+import java.io.IOException;
+import java.net.URI;
+import java.net.URLEncoder;
+import java.net.http.HttpClient;
+import java.net.http.HttpRequest;
+import java.net.http.HttpResponse;
+import java.nio.charset.StandardCharsets;
+import java.time.Duration;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.StringJoiner;
+
+/**
+ * Client for the Signer Service API. Responses are returned as JSON
+ * maps; error responses throw {@link ApiException}.
+ */
+public final class SignerClient {
+    private final HttpClient http;
+    private final String baseUrl;
+    private final String apiKey;
+    private final Duration timeout;
+    private String language;
+
+    /** Creates a client sending apiKey as X-API-Key; null sends none. */
+    public SignerClient(String baseUrl, String apiKey) {
+        this(baseUrl, apiKey, Duration.ofSeconds(30));
+    }
+
+    public SignerClient(String baseUrl, String apiKey, Duration timeout) {
+        this.http = HttpClient.newBuilder().connectTimeout(timeout).build();
+        this.baseUrl = baseUrl.replaceAll("/+$", "");
+        this.apiKey = apiKey;
+        this.timeout = timeout;
+    }
+
+    /** Sets the Accept-Language of error messages, e.g. "es". */
+    public SignerClient withLanguage(String language) {
+        this.language = language;
+        return this;
+    }
+
+    /** Report that the service is up. GET /health; returns a HealthResponse. */
+    public Map<String, Object> healthCheck() throws IOException, InterruptedException {
+        String path = "/health";
+        Map<String, Object> query = new LinkedHashMap<>();
+        return call("GET", path, query, null, false);
+    }
+
+    /** Check whether a file was already uploaded under the company prefix. POST /api/v1/object/search; returns a SearchObjectResponse. */
+    public Map<String, Object> searchObject(SearchObjectRequest body) throws IOException, InterruptedException {
+        String path = "/api/v1/object/search";
+        Map<String, Object> query = new LinkedHashMap<>();
+        return call("POST", path, query, body.toMap(), true);
+    }
+
+    /** Issue a presigned PUT URL under inputs/date/time/filename. POST /api/v1/presigned-url/upload; returns a PresignedURLResponse. */
+    public Map<String, Object> createUploadURL(PresignedURLRequest body) throws IOException, InterruptedException {
+        String path = "/api/v1/presigned-url/upload";
+        Map<String, Object> query = new LinkedHashMap<>();
+        return call("POST", path, query, body.toMap(), true);
+    }
+
+    /** Confirm an upload finished and return the stored object. POST /api/v1/object/confirm; returns a ConfirmObjectResponse. */
+    public Map<String, Object> confirmObject(ConfirmObjectRequest body) throws IOException, InterruptedException {
+        String path = "/api/v1/object/confirm";
+        Map<String, Object> query = new LinkedHashMap<>();
+        return call("POST", path, query, body.toMap(), true);
+    }
+
+    /** Find the most recent upload of a file and presign a download. GET /api/v1/object/latest; returns a LatestObjectResponse. */
+    public Map<String, Object> getLatestObject(String filename) throws IOException, InterruptedException {
+        String path = "/api/v1/object/latest";
+        Map<String, Object> query = new LinkedHashMap<>();
+        query.put("filename", filename);
+        return call("GET", path, query, null, true);
+    }
+
+    /** Issue a presigned URL for an explicit operation with strict validation. POST /api/v2/presigned-urls; returns a PresignV2Response. */
+    public Map<String, Object> presign(PresignV2Request body) throws IOException, InterruptedException {
+        String path = "/api/v2/presigned-urls";
+        Map<String, Object> query = new LinkedHashMap<>();
+        return call("POST", path, query, body.toMap(), true);
+    }
+
+    private Map<String, Object> call(String method, String path, Map<String, Object> query, Map<String, Object> body, boolean authenticated)
+            throws IOException, InterruptedException {
+        StringJoiner params = new StringJoiner("&");
+        for (Map.Entry<String, Object> e : query.entrySet()) {
+            if (e.getValue() != null) {
+                params.add(encode(e.getKey()) + "=" + encode(String.valueOf(e.getValue())));
+            }
+        }
+        String url = baseUrl + path + (params.length() > 0 ? "?" + params : "");
+
+        HttpRequest.Builder request = HttpRequest.newBuilder(URI.create(url))
+                .timeout(timeout)
+                .header("Accept", "application/json");
+        if (authenticated && apiKey != null) {
+            request.header("X-API-Key", apiKey);
+        }
+        if (language != null) {
+            request.header("Accept-Language", language);
+        }
+        if (body != null) {
+            request.header("Content-Type", "application/json");
+            request.method(method, HttpRequest.BodyPublishers.ofString(Json.write(body)));
+        } else {
+            request.method(method, HttpRequest.BodyPublishers.noBody());
+        }
+
+        HttpResponse<String> response = http.send(request.build(), HttpResponse.BodyHandlers.ofString());
+        Map<String, Object> decoded = decode(response.body());
+        if (response.statusCode() >= 400) {
+            Integer retryAfter = response.headers().firstValue("Retry-After")
+                    .filter(v -> v.matches("\\d+")).map(Integer::valueOf).orElse(null);
+            throw new ApiException(response.statusCode(), decoded, retryAfter);
+        }
+        return decoded;
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> decode(String body) {
+        if (body == null || body.isBlank()) {
+            return new LinkedHashMap<>();
+        }
+        try {
+            Object value = Json.parse(body);
+            if (value instanceof Map) {
+                return (Map<String, Object>) value;
+            }
+        } catch (IllegalArgumentException e) {
+            // Not JSON, reported as the error text
+        }
+        Map<String, Object> error = new LinkedHashMap<>();
+        error.put("error", body);
+        return error;
+    }
+
+    private static String encode(String value) {
+        return URLEncoder.encode(value, StandardCharsets.UTF_8).replace("+", "%20");
+    }
+}
@@ -0,0 +1,141 @@
+"""Client for the Signer Service API.
+
+Generated by cmd/clientgen from api/openapi.yaml; run `make clients` instead
+of editing it. Uses only the Python standard library.
+"""
+
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+
+__all__ = ["Client", "APIError"]
+
+
+class APIError(Exception):
+    """An error response. Decide on code, status and retryable; error and
+    message are for people and follow Accept-Language."""
+
+    def __init__(self, status, body, retry_after=None):
+        self.status = status
+        self.body = body if isinstance(body, dict) else {}
+        self.error = self.body.get("error", "")
+        self.message = self.body.get("message", "")
+        self.code = self.body.get("code", "")
+        self.retryable = bool(self.body.get("retryable", False))
+        self.retry_after = retry_after
+        super().__init__(f"{status} {self.code or self.error}: {self.message}".rstrip(": "))
+
+
+class Client:
+    """Calls the service with an API key sent as X-API-Key."""
+
+    def __init__(self, base_url, api_key=None, timeout=30, language=None):
+        self.base_url = base_url.rstrip("/")
+        self.api_key = api_key
+        self.timeout = timeout
+        self.language = language
+
+    def _call(self, method, path, query=None, body=None, authenticated=True):
+        url = self.base_url + path
+        query = {k: _query_value(v) for k, v in (query or {}).items() if v is not None}
+        if query:
+            url += "?" + urllib.parse.urlencode(query)
+        headers = {"Accept": "application/json"}
+        if authenticated and self.api_key:
+            headers["X-API-Key"] = self.api_key
+        if self.language:
+            headers["Accept-Language"] = self.language
+        data = None
+        if body is not None:
+            data = json.dumps({k: v for k, v in body.items() if v is not None}).encode()
+            headers["Content-Type"] = "application/json"
+
+        request = urllib.request.Request(url, data=data, headers=headers, method=method)
+        try:
+            with urllib.request.urlopen(request, timeout=self.timeout) as response:
+                return _decode(response.read())
+        except urllib.error.HTTPError as err:
+            retry_after = err.headers.get("Retry-After")
+            raise APIError(err.code, _decode(err.read()), int(retry_after) if retry_after and retry_after.isdigit() else None) from None
+
+    def health_check(self):
+        """Report that the service is up.
+
+        GET /health; returns a HealthResponse dict.
+        """
+        return self._call(
+            "GET",
+            "/health",
+            authenticated=False,
+        )
+
+    def search_object(self, filename):
+        """Check whether a file was already uploaded under the company prefix.
+
+        POST /api/v1/object/search; returns a SearchObjectResponse dict.
+        """
+        return self._call(
+            "POST",
+            "/api/v1/object/search",
+            body={"filename": filename},
+        )
+
+    def create_upload_url(self, filename, *, content_type=None, metadata=None, run_id=None, dry_run=None, on_duplicate=None, content_encoding=None, not_before=None, content_md5=None, overwrite=None, sse_customer_key_md5=None):
+        """Issue a presigned PUT URL under inputs/date/time/filename.
+
+        POST /api/v1/presigned-url/upload; returns a PresignedURLResponse dict.
+        """
+        return self._call(
+            "POST",
+            "/api/v1/presigned-url/upload",
+            body={"filename": filename, "content_type": content_type, "metadata": metadata, "run_id": run_id, "dry_run": dry_run, "on_duplicate": on_duplicate, "content_encoding": content_encoding, "not_before": not_before, "content_md5": content_md5, "overwrite": overwrite, "sse_customer_key_md5": sse_customer_key_md5},
+        )
+
+    def confirm_object(self, *, object_key=None, upload_id=None, run_id=None, filename=None, wrapped_key=None):
+        """Confirm an upload finished and return the stored object.
+
+        POST /api/v1/object/confirm; returns a ConfirmObjectResponse dict.
+        """
+        return self._call(
+            "POST",
+            "/api/v1/object/confirm",
+            body={"object_key": object_key, "upload_id": upload_id, "run_id": run_id, "filename": filename, "wrapped_key": wrapped_key},
+        )
+
+    def get_latest_object(self, filename):
+        """Find the most recent upload of a file and presign a download.
+
+        GET /api/v1/object/latest; returns a LatestObjectResponse dict.
+        """
+        return self._call(
+            "GET",
+            "/api/v1/object/latest",
+            query={"filename": filename},
+        )
+
+    def presign(self, operation, *, filename=None, object_key=None, content_type=None, content_length=None, metadata=None, object_lock=None, dry_run=None, checksum_sha256=None, content_md5=None, on_duplicate=None, content_encoding=None, not_before=None, subpath=None, overwrite=None, sse_customer_key_md5=None):
+        """Issue a presigned URL for an explicit operation with strict validation.
+
+        POST /api/v2/presigned-urls; returns a PresignV2Response dict.
+        """
+        return self._call(
+            "POST",
+            "/api/v2/presigned-urls",
+            body={"operation": operation, "filename": filename, "object_key": object_key, "content_type": content_type, "content_length": content_length, "metadata": metadata, "object_lock": object_lock, "dry_run": dry_run, "checksum_sha256": checksum_sha256, "content_md5": content_md5, "on_duplicate": on_duplicate, "content_encoding": content_encoding, "not_before": not_before, "subpath": subpath, "overwrite": overwrite, "sse_customer_key_md5": sse_customer_key_md5},
+        )
+
+
+def _query_value(value):
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    return value
+
+
+def _decode(data):
+    if not data:
+        return {}
+    try:
+        return json.loads(data)
+    except ValueError:
+        return {"error": data.decode(errors="replace")}
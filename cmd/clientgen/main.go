@@ -0,0 +1,53 @@
+// Command clientgen generates the Python and Java clients from the OpenAPI
+// spec. With --check it only reports whether the checked-in clients are up to
+// date, for CI.
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/clientgen"
+)
+
+func main() {
+	specPath := pflag.String("spec", "api/openapi.yaml", "OpenAPI spec to generate from")
+	outDir := pflag.String("out", "clients", "directory the clients are written to")
+	check := pflag.Bool("check", false, "fail if the clients in --out differ from the spec instead of writing them")
+	pflag.Parse()
+
+	spec, err := clientgen.Load(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to load %s: %v", *specPath, err)
+	}
+	files, err := clientgen.Generate(spec)
+	if err != nil {
+		log.Fatalf("Failed to generate clients: %v", err)
+	}
+
+	stale := false
+	for _, f := range files {
+		path := filepath.Join(*outDir, filepath.FromSlash(f.Path))
+		if *check {
+			current, err := os.ReadFile(path)
+			if err != nil || !bytes.Equal(current, f.Content) {
+				log.Printf("%s is out of date", path)
+				stale = true
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, f.Content, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	if stale {
+		log.Fatalf("Clients are out of date; run make clients")
+	}
+}
@@ -2,67 +2,319 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/andressep95/aws-backup-bridge/signer-service/internal/config"
-	"github.com/andressep95/aws-backup-bridge/signer-service/internal/handler"
-	"github.com/andressep95/aws-backup-bridge/signer-service/internal/service"
+	"github.com/spf13/pflag"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/apikey"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/audit"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/catalog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cwlogs"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/envelope"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/handler"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/iampolicy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metaschema"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/scheduler"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	fs := pflag.NewFlagSet("signer-service", pflag.ExitOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML, TOML or JSON config file")
+	validateOnly := fs.Bool("validate-config", false, "check the configuration and exit")
+	printPolicy := fs.Bool("print-iam-policy", false, "print the minimal IAM policy the configuration needs and exit")
+	flags := config.RegisterFlags(fs)
+	fs.SortFlags = false
+	_ = fs.Parse(os.Args[1:])
+
+	// Load configuration (flags > environment > config file > defaults)
+	cfg, err := config.Load(*configPath, flags.Values())
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if *validateOnly {
+		if err := validateConfig(cfg); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		log.Println("Configuration is valid")
+		return
+	}
+
+	if *printPolicy {
+		out, _ := json.MarshalIndent(iampolicy.Generate(cfg), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	// Validate already checked the level
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logging.SetLevel(level)
+	go toggleDebugOnSignal(level)
+
+	// CloudWatch Logs and KMS clients sign as AWS_ROLE_ARN too, when set
+	credentials := service.CredentialsProvider(cfg)
+
+	// Ship logs and EMF metrics to CloudWatch Logs
+	var shipper *cwlogs.Shipper
+	if cfg.CloudWatchLogGroup != "" {
+		client := cwlogs.NewClient(cfg.CloudWatchLogGroup, cfg.CloudWatchLogStream, cfg.AWSRegion, credentials)
+		shipper = cwlogs.NewShipper(client, 5*time.Second)
+		shipper.Start()
+		if cfg.CloudWatchShipLogs {
+			logging.Export(func(e logging.Entry) { shipper.Send(e.Time, structuredLog(e)) })
+		}
+		log.Printf("CloudWatch Logs: %s/%s (logs: %t, metrics every %ds)",
+			cfg.CloudWatchLogGroup, cfg.CloudWatchLogStream, cfg.CloudWatchShipLogs, cfg.CloudWatchMetricsIntervalSeconds)
+	}
+
 	log.Printf("Starting signer-service on port %s", cfg.Port)
 	log.Printf("AWS Region: %s", cfg.AWSRegion)
-	log.Printf("S3 Bucket: %s", cfg.S3BucketName)
-	log.Printf("Presigned URL Expiration: %d minutes", cfg.PresignedURLExpirationMinutes)
+	if cfg.S3MRAPARN != "" {
+		log.Printf("S3 Multi-Region Access Point: %s", cfg.S3MRAPARN)
+	} else {
+		log.Printf("S3 Bucket: %s", cfg.S3BucketName)
+	}
+	if cfg.FailoverEnabled {
+		log.Printf("Failover bucket: %s (%s)", cfg.DRBucketName, cfg.DRRegion)
+	}
+	log.Printf("Presigned URL Expiration: upload %v, download %v", cfg.UploadURLExpiration(), cfg.DownloadURLExpiration())
+
+	// Shared metrics registry served on /metrics or pushed to statsd
+	registry := metrics.NewRegistry()
+	var statsd *metrics.StatsdSink
+	if cfg.MetricsSink == "statsd" || cfg.MetricsSink == "dogstatsd" {
+		statsd, err = metrics.NewStatsdSink(cfg.StatsdAddress, cfg.StatsdPrefix, cfg.MetricsSink == "dogstatsd", cfg.StatsdTags)
+		if err != nil {
+			log.Fatalf("Failed to configure statsd: %v", err)
+		}
+		registry.AddSink(statsd)
+		log.Printf("Metrics: %s at %s", cfg.MetricsSink, cfg.StatsdAddress)
+	}
+
+	// Stop on SIGINT or SIGTERM, also while initializing
+	quit, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Create HTTP server, serving degraded routes until the service's are set
+	routes := &routeSwitch{}
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%s", cfg.Port),
+		Handler:      routes,
+		ReadTimeout:  time.Duration(cfg.HTTPReadTimeoutSeconds) * time.Second,
+		WriteTimeout: time.Duration(cfg.HTTPWriteTimeoutSeconds) * time.Second,
+		IdleTimeout:  time.Duration(cfg.HTTPIdleTimeoutSeconds) * time.Second,
+	}
+
+	// HTTP/2 is negotiated via ALPN on TLS; h2c serves cleartext HTTP/2 to
+	// trusted proxies on the plaintext listener
+	server.Protocols = new(http.Protocols)
+	server.Protocols.SetHTTP1(true)
+	if cfg.TLSCertFile != "" {
+		server.Protocols.SetHTTP2(true)
+	}
+	if cfg.HTTPH2C {
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	// Internal listener for health, metrics and the admin API
+	adminRoutes := &routeSwitch{}
+	var adminServer *http.Server
+	if cfg.AdminPort != "" {
+		adminServer = &http.Server{
+			Addr:         net.JoinHostPort(cfg.AdminHost, cfg.AdminPort),
+			Handler:      adminRoutes,
+			ReadTimeout:  time.Duration(cfg.AdminHTTPReadTimeoutSeconds) * time.Second,
+			WriteTimeout: time.Duration(cfg.AdminHTTPWriteTimeoutSeconds) * time.Second,
+			IdleTimeout:  time.Duration(cfg.AdminHTTPIdleTimeoutSeconds) * time.Second,
+		}
+	}
+	startServer := sync.OnceFunc(func() {
+		go serve("Server", server, cfg.TLSCertFile, cfg.TLSKeyFile)
+		if adminServer != nil {
+			go serve("Internal server", adminServer, cfg.AdminTLSCertFile, cfg.AdminTLSKeyFile)
+		}
+	})
 
-	// Initialize S3 service
-	s3Service, err := service.NewS3Service(cfg)
+	// Initialize S3 service, retrying transient failures
+	s3Service, err := initS3Service(quit, cfg, registry, cfg.AWSInitMaxAttempts, nil)
+	if errors.Is(err, service.ErrAWSConfig) && cfg.AWSInitDegradedStart && quit.Err() == nil {
+		log.Printf("Warning: failed to create S3 service, serving not ready while retrying: %v", err)
+		var degradedMetrics *metrics.Registry
+		if cfg.MetricsSink == "" || cfg.MetricsSink == "prometheus" {
+			degradedMetrics = registry
+		}
+		degraded := handler.NewDegradedHandler(degradedMetrics)
+		degraded.SetError(err)
+		routes.set(degraded)
+		adminRoutes.set(degraded)
+		startServer()
+		s3Service, err = initS3Service(quit, cfg, registry, math.MaxInt, degraded.SetError)
+	}
 	if err != nil {
+		if quit.Err() != nil {
+			log.Println("Interrupted while initializing AWS")
+			return
+		}
 		log.Fatalf("Failed to create S3 service: %v", err)
 	}
 
+	// Verify bucket access before accepting requests
+	if cfg.PreflightCheck == "warn" || cfg.PreflightCheck == "fail" {
+		preflightCtx, cancelPreflight := context.WithTimeout(context.Background(), 30*time.Second)
+		report := s3Service.Preflight(preflightCtx)
+		cancelPreflight()
+		for _, check := range report.Checks {
+			if check.OK {
+				log.Printf("Preflight %s: ok (%dms)", check.Name, check.DurationMS)
+			} else {
+				log.Printf("Preflight %s: FAILED: %s", check.Name, check.Error)
+			}
+		}
+		if !report.OK {
+			if cfg.PreflightCheck == "fail" {
+				log.Fatalf("Preflight checks failed, check the bucket name, region and IAM policy")
+			}
+			log.Println("Warning: preflight checks failed, uploads may not work")
+		}
+	}
+
 	// Initialize handlers
-	h := handler.NewHandler(s3Service)
+	handlerOpts := []handler.Option{handler.WithMetrics(registry)}
+	if cfg.PolicyFile != "" {
+		p, err := policy.LoadFile(cfg.PolicyFile)
+		if err != nil {
+			log.Fatalf("Failed to load authorization policy: %v", err)
+		}
+		log.Printf("Authorization policy: %d rules from %s", len(p.Rules), cfg.PolicyFile)
+		handlerOpts = append(handlerOpts, handler.WithPolicy(p))
+	}
+	if cfg.MetadataSchemaFile != "" {
+		schema, err := metaschema.LoadFile(cfg.MetadataSchemaFile)
+		if err != nil {
+			log.Fatalf("Failed to load metadata schema: %v", err)
+		}
+		log.Printf("Metadata schema: %d keys from %s", len(schema.Keys), cfg.MetadataSchemaFile)
+		handlerOpts = append(handlerOpts, handler.WithMetadataSchema(schema))
+	}
+	if cfg.APIKeyStore == "memory" || cfg.APIKeyStore == "file" {
+		path := ""
+		if cfg.APIKeyStore == "file" {
+			path = cfg.APIKeyStoreFile
+		}
+		store, err := apikey.NewStore(path)
+		if err != nil {
+			log.Fatalf("Failed to load API key store: %v", err)
+		}
+		handlerOpts = append(handlerOpts, handler.WithAPIKeyStore(store))
+	}
+	switch cfg.CatalogStore {
+	case "memory":
+		log.Println("Catalog: in memory, entries are lost on restart")
+		handlerOpts = append(handlerOpts, handler.WithCatalog(catalog.NewMemoryStore()))
+	case "postgres":
+		catalogCtx, cancelCatalog := context.WithTimeout(context.Background(), 30*time.Second)
+		store, err := catalog.NewPostgresStore(catalogCtx, cfg.CatalogDatabaseURL)
+		cancelCatalog()
+		if err != nil {
+			log.Fatalf("Failed to open catalog: %v", err)
+		}
+		defer store.Close()
+		log.Println("Catalog: postgres")
+		handlerOpts = append(handlerOpts, handler.WithCatalog(store))
+	}
+	var auditForwarder *audit.Forwarder
+	if sink, err := newAuditSink(cfg, credentials); err != nil {
+		log.Fatalf("Failed to configure audit sink: %v", err)
+	} else if sink != nil {
+		log.Printf("Audit events: %s", cfg.AuditSink)
+		auditForwarder = audit.NewForwarder(sink, cfg.AuditBatchSize, time.Duration(cfg.AuditFlushIntervalSeconds)*time.Second, registry)
+		auditForwarder.Start()
+		handlerOpts = append(handlerOpts, handler.WithAudit(auditForwarder))
+	}
+	if sealer, err := newURLSealer(cfg, credentials); err != nil {
+		log.Fatalf("Failed to configure URL encryption: %v", err)
+	} else if sealer != nil {
+		log.Printf("Presigned URL encryption: %s", cfg.URLEncryption)
+		handlerOpts = append(handlerOpts, handler.WithURLSealer(sealer))
+	}
+	if cfg.ClientEncryptionKMSKeyID != "" {
+		log.Printf("Client-side encryption data keys: %s", cfg.ClientEncryptionKMSKeyID)
+		handlerOpts = append(handlerOpts, handler.WithDataKeys(envelope.NewKMSDataKeys(cfg.ClientEncryptionKMSKeyID, cfg.AWSRegion, credentials)))
+	}
+	var tenants tenant.Store
+	switch cfg.TenantStore {
+	case "memory":
+		log.Println("Tenant store: in memory, tenants are lost on restart")
+		tenants = tenant.NewMemoryStore()
+	case "file":
+		store, err := tenant.NewFileStore(cfg.TenantStoreFile)
+		if err != nil {
+			log.Fatalf("Failed to load tenant store: %v", err)
+		}
+		log.Printf("Tenant store: %s", cfg.TenantStoreFile)
+		tenants = store
+	}
+	if tenants != nil {
+		if err := seedTenants(tenants, cfg.Tenants); err != nil {
+			log.Fatalf("Failed to seed tenants from config file: %v", err)
+		}
+		handlerOpts = append(handlerOpts, handler.WithTenants(tenants))
+	}
+	h := handler.NewHandler(s3Service, cfg, handlerOpts...)
 
 	// Setup routes
 	router := h.SetupRoutes()
 
-	// Create HTTP server
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// Start background jobs, stopped on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	h.StartBackgroundJobs(jobsCtx)
+	var emitter *cwlogs.MetricsEmitter
+	if shipper != nil && cfg.CloudWatchMetricsIntervalSeconds > 0 {
+		emitter = cwlogs.NewMetricsEmitter(registry, cfg.CloudWatchMetricsNamespace)
+		scheduler.Start(jobsCtx, scheduler.Job{
+			Name:     "cloudwatch-metrics",
+			Interval: time.Duration(cfg.CloudWatchMetricsIntervalSeconds) * time.Second,
+			Run: func(context.Context) error {
+				emitMetrics(shipper, emitter)
+				return nil
+			},
+		})
 	}
 
-	// Start server in a goroutine
-	go func() {
-		addr := fmt.Sprintf("0.0.0.0:%s", cfg.Port)
-		log.Printf("Server listening on %s", addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+	routes.set(router)
+	if adminServer != nil {
+		adminRoutes.set(h.SetupInternalRoutes())
+	}
+	startServer()
 
 	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-quit.Done()
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Create a deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -72,6 +324,217 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Printf("Internal server forced to shutdown: %v", err)
+		}
+	}
+	if auditForwarder != nil {
+		if err := auditForwarder.Close(ctx); err != nil {
+			log.Printf("Failed to close audit sink: %v", err)
+		}
+	}
+
+	if statsd != nil {
+		_ = statsd.Close()
+	}
 
 	log.Println("Server exited")
+
+	// Ship the final metrics and pending logs
+	if shipper != nil {
+		if emitter != nil {
+			emitMetrics(shipper, emitter)
+		}
+		shipper.Close(ctx)
+	}
+}
+
+// initS3Service creates the S3 service, retrying AWS configuration failures
+// up to attempts times with backoff, or until ctx is cancelled. onRetry, when
+// set, receives each failure.
+func initS3Service(ctx context.Context, cfg *config.Config, registry *metrics.Registry, attempts int, onRetry func(error)) (*service.S3Service, error) {
+	policy := resilience.RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	retryable := func(err error) bool { return errors.Is(err, service.ErrAWSConfig) }
+
+	var s3Service *service.S3Service
+	err := resilience.Retry(ctx, policy, retryable, func(attempt int, err error) {
+		log.Printf("Warning: AWS initialization attempt %d failed, retrying: %v", attempt, err)
+		if onRetry != nil {
+			onRetry(err)
+		}
+	}, func(ctx context.Context) error {
+		initCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		var err error
+		s3Service, err = service.NewS3Service(initCtx, cfg, service.WithMetrics(registry))
+		return err
+	})
+	return s3Service, err
+}
+
+// serve listens until the server is shut down, with TLS when certFile is set
+func serve(name string, server *http.Server, certFile, keyFile string) {
+	addr := server.Addr
+	if strings.HasPrefix(addr, ":") {
+		addr = "0.0.0.0" + addr
+	}
+	var err error
+	if certFile != "" {
+		log.Printf("%s listening on %s (TLS, HTTP/2)", name, addr)
+		err = server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		log.Printf("%s listening on %s (h2c: %t)", name, addr, server.Protocols != nil && server.Protocols.UnencryptedHTTP2())
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Failed to start %s: %v", strings.ToLower(name), err)
+	}
+}
+
+// routeSwitch serves the current routes: degraded ones while initializing,
+// then the service's
+type routeSwitch struct {
+	routes atomic.Pointer[http.Handler]
+}
+
+func (s *routeSwitch) set(routes http.Handler) {
+	s.routes.Store(&routes)
+}
+
+func (s *routeSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.routes.Load()).ServeHTTP(w, r)
+}
+
+// newAuditSink returns the sink selected by AUDIT_SINK, or nil when auditing
+// is off
+func newAuditSink(cfg *config.Config, credentials aws.CredentialsProvider) (audit.Sink, error) {
+	switch cfg.AuditSink {
+	case "http":
+		return audit.NewHTTPSink(cfg.AuditHTTPURL, cfg.AuditHTTPAuthorization), nil
+	case "syslog":
+		return audit.NewSyslogSink(cfg.AuditSyslogAddress)
+	case "cloudwatch":
+		return audit.NewCloudWatchSink(cfg.AuditCloudWatchLogGroup, cfg.AuditCloudWatchLogStream, cfg.AWSRegion, credentials), nil
+	}
+	return nil, nil
+}
+
+// newURLSealer returns the sealer selected by URL_ENCRYPTION, or nil when
+// URLs are returned in the clear
+func newURLSealer(cfg *config.Config, credentials aws.CredentialsProvider) (envelope.Sealer, error) {
+	switch cfg.URLEncryption {
+	case "jwe":
+		pemData, err := os.ReadFile(cfg.URLEncryptionPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return envelope.NewJWESealer(pemData)
+	case "kms":
+		return envelope.NewKMSSealer(cfg.URLEncryptionKMSKeyID, cfg.AWSRegion, credentials), nil
+	}
+	return nil, nil
+}
+
+// structuredLog renders a log entry as the JSON message shipped to
+// CloudWatch Logs, queryable with Logs Insights
+func structuredLog(e logging.Entry) string {
+	data, _ := json.Marshal(struct {
+		Time    time.Time `json:"time"`
+		Level   string    `json:"level"`
+		Message string    `json:"message"`
+	}{e.Time, e.Level.String(), e.Message})
+	return string(data)
+}
+
+// emitMetrics ships the current metrics as EMF documents
+func emitMetrics(shipper *cwlogs.Shipper, emitter *cwlogs.MetricsEmitter) {
+	now := time.Now()
+	for _, document := range emitter.Documents(now) {
+		shipper.Send(now, document)
+	}
+}
+
+// seedTenants creates or replaces the tenants declared in the config file
+func seedTenants(store tenant.Store, declared []config.TenantConfig) error {
+	for _, tc := range declared {
+		now := time.Now().UTC()
+		t := tenant.Tenant{
+			ID:                  tc.ID,
+			Prefix:              tc.Prefix,
+			QuotaBytes:          tc.QuotaBytes,
+			AllowedContentTypes: tc.AllowedContentTypes,
+			MinRetentionHours:   tc.MinRetentionHours,
+			UploadWindows:       tc.UploadWindows,
+			UploadTimezone:      tc.UploadTimezone,
+			ExpectedBackups:     expectedBackups(tc.ExpectedBackups),
+			RoleARN:             tc.RoleARN,
+			RoleExternalID:      tc.RoleExternalID,
+			APIKeyHash:          tc.APIKeySHA256,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", tc.ID, err)
+		}
+
+		existing, err := store.Get(t.ID)
+		switch {
+		case errors.Is(err, tenant.ErrNotFound):
+			err = store.Create(t)
+		case err == nil:
+			t.CreatedAt = existing.CreatedAt
+			err = store.Update(t)
+		}
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", tc.ID, err)
+		}
+	}
+	if len(declared) > 0 {
+		log.Printf("Tenants: %d seeded from config file", len(declared))
+	}
+	return nil
+}
+
+// expectedBackups converts expected backups declared in the config file
+func expectedBackups(declared []config.ExpectedBackupConfig) []tenant.ExpectedBackup {
+	var expected []tenant.ExpectedBackup
+	for _, e := range declared {
+		expected = append(expected, tenant.ExpectedBackup{Filename: e.Filename, MaxAgeHours: e.MaxAgeHours})
+	}
+	return expected
+}
+
+// validateConfig checks what Load can't without starting the server: the
+// policy file, the metadata schema and the config file tenants
+func validateConfig(cfg *config.Config) error {
+	if cfg.PolicyFile != "" {
+		if _, err := policy.LoadFile(cfg.PolicyFile); err != nil {
+			return err
+		}
+	}
+	if cfg.MetadataSchemaFile != "" {
+		if _, err := metaschema.LoadFile(cfg.MetadataSchemaFile); err != nil {
+			return err
+		}
+	}
+	return seedTenants(tenant.NewMemoryStore(), cfg.Tenants)
+}
+
+// toggleDebugOnSignal switches between debug and the configured level on
+// every SIGUSR1
+func toggleDebugOnSignal(configured logging.Level) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	for range signals {
+		next := logging.LevelDebug
+		if logging.GetLevel() == logging.LevelDebug {
+			next = configured
+			if configured == logging.LevelDebug {
+				next = logging.LevelInfo
+			}
+		}
+		logging.SetLevel(next)
+		log.Printf("Log level set to %s (SIGUSR1)", next)
+	}
 }
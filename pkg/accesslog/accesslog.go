@@ -0,0 +1,122 @@
+// Package accesslog parses S3 server access logs and keeps the requests made
+// with presigned URLs issued by the signer, matched to their issuance records,
+// to tell who used which URL, from where and with what result.
+package accesslog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/urlregistry"
+)
+
+// MaxUsages bounds the usage records kept in memory
+const MaxUsages = 100000
+
+// Usage is a logged request made with an issued presigned URL
+type Usage struct {
+	Time       time.Time         `json:"time"`
+	RemoteIP   string            `json:"remote_ip"`
+	Operation  string            `json:"operation"`
+	HTTPStatus int               `json:"http_status"`
+	ErrorCode  string            `json:"error_code,omitempty"`
+	BytesSent  int64             `json:"bytes_sent"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	RequestID  string            `json:"request_id"`
+	URL        urlregistry.Entry `json:"url"` // Issuance record of the URL used
+	// Signature of the URL used; not returned, since with the rest of the
+	// URL it grants access
+	Signature string `json:"-"`
+}
+
+// Query filters usage records. Empty fields match any record.
+type Query struct {
+	Signature string
+	ObjectKey string
+	Subject   string
+	UploadID  string
+	Since     time.Time
+	Owns      func(objectKey string) bool // Restricts records to the caller's keys
+	Limit     int
+}
+
+// Store keeps usage records in memory for the retention, and the progress
+// of the ingestion through the log objects
+type Store struct {
+	mu         sync.Mutex
+	retention  time.Duration
+	usages     []Usage // In ingestion order
+	cursor     string  // Key of the last ingested log object
+	ingestedAt time.Time
+}
+
+// NewStore creates a store keeping usage records logged within retention
+func NewStore(retention time.Duration) *Store {
+	return &Store{retention: retention}
+}
+
+// Add stores usage records, dropping those past the retention and the oldest
+// beyond MaxUsages
+func (s *Store) Add(usages ...Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	kept := s.usages[:0]
+	for _, u := range append(s.usages, usages...) {
+		if u.Time.After(cutoff) {
+			kept = append(kept, u)
+		}
+	}
+	if len(kept) > MaxUsages {
+		kept = kept[len(kept)-MaxUsages:]
+	}
+	s.usages = kept
+}
+
+// Advance records that the log object key has been ingested
+func (s *Store) Advance(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursor = key
+	s.ingestedAt = time.Now().UTC()
+}
+
+// Cursor returns the key of the last ingested log object, empty before the
+// first one
+func (s *Store) Cursor() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursor
+}
+
+// IngestedAt returns when the last log object was ingested
+func (s *Store) IngestedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ingestedAt
+}
+
+// Find returns up to q.Limit usage records matching q, most recently
+// ingested first
+func (s *Store) Find(q Query) []Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usages := []Usage{}
+	for i := len(s.usages) - 1; i >= 0 && len(usages) < q.Limit; i-- {
+		u := s.usages[i]
+		switch {
+		case q.Signature != "" && u.Signature != q.Signature,
+			q.ObjectKey != "" && u.URL.ObjectKey != q.ObjectKey,
+			q.Subject != "" && u.URL.Subject != q.Subject,
+			q.UploadID != "" && u.URL.UploadID != q.UploadID,
+			u.Time.Before(q.Since),
+			q.Owns != nil && !q.Owns(u.URL.ObjectKey):
+			continue
+		}
+		usages = append(usages, u)
+	}
+	return usages
+}
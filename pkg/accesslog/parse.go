@@ -0,0 +1,140 @@
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayout is the format of the bracketed request time
+const timeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Positions of the fields read from a record; later fields vary between
+// log versions and are ignored
+const (
+	fieldBucket = iota + 1
+	fieldTime
+	fieldRemoteIP
+	fieldRequester
+	fieldRequestID
+	fieldOperation
+	fieldKey
+	fieldRequestURI
+	fieldHTTPStatus
+	fieldErrorCode
+	fieldBytesSent
+	fieldObjectSize
+	fieldTotalTime
+	fieldTurnAroundTime
+	fieldReferer
+	fieldUserAgent
+)
+
+// ErrMalformed is returned for lines that aren't S3 server access log records
+var ErrMalformed = errors.New("malformed access log record")
+
+// Record is a request S3 logged in a server access log
+type Record struct {
+	Bucket     string
+	Time       time.Time
+	RemoteIP   string
+	Requester  string
+	RequestID  string
+	Operation  string // e.g. REST.PUT.OBJECT
+	Key        string
+	RequestURI string // e.g. GET /key?X-Amz-Signature=... HTTP/1.1
+	HTTPStatus int
+	ErrorCode  string
+	BytesSent  int64
+	UserAgent  string
+}
+
+// ParseLine parses a record of an S3 server access log. Fields logged as -
+// are left empty.
+func ParseLine(line string) (Record, error) {
+	fields, err := split(line)
+	if err != nil {
+		return Record{}, err
+	}
+	if len(fields) <= fieldUserAgent {
+		return Record{}, fmt.Errorf("%w: %d fields", ErrMalformed, len(fields))
+	}
+
+	record := Record{
+		Bucket:     fields[fieldBucket],
+		RemoteIP:   fields[fieldRemoteIP],
+		Requester:  fields[fieldRequester],
+		RequestID:  fields[fieldRequestID],
+		Operation:  fields[fieldOperation],
+		Key:        fields[fieldKey],
+		RequestURI: fields[fieldRequestURI],
+		ErrorCode:  fields[fieldErrorCode],
+		UserAgent:  fields[fieldUserAgent],
+	}
+	if record.Time, err = time.Parse(timeLayout, fields[fieldTime]); err != nil {
+		return Record{}, fmt.Errorf("%w: time %q", ErrMalformed, fields[fieldTime])
+	}
+	if record.HTTPStatus, err = strconv.Atoi(fields[fieldHTTPStatus]); err != nil {
+		return Record{}, fmt.Errorf("%w: HTTP status %q", ErrMalformed, fields[fieldHTTPStatus])
+	}
+	if fields[fieldBytesSent] != "" {
+		if record.BytesSent, err = strconv.ParseInt(fields[fieldBytesSent], 10, 64); err != nil {
+			return Record{}, fmt.Errorf("%w: bytes sent %q", ErrMalformed, fields[fieldBytesSent])
+		}
+	}
+	// Keys are logged URL-encoded
+	if key, err := url.PathUnescape(record.Key); err == nil {
+		record.Key = key
+	}
+	return record, nil
+}
+
+// Signature returns the X-Amz-Signature of the request, empty unless it was
+// made with a presigned URL
+func (r Record) Signature() string {
+	parts := strings.Fields(r.RequestURI)
+	if len(parts) < 2 {
+		return ""
+	}
+	u, err := url.Parse(parts[1])
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("X-Amz-Signature")
+}
+
+// split splits a record into its space-separated fields. Quoted fields and
+// the bracketed time are kept whole without their delimiters, and - becomes
+// an empty field.
+func split(line string) ([]string, error) {
+	var fields []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeft(line, " ") {
+		var field string
+		switch line[0] {
+		case '"', '[':
+			closing := byte('"')
+			if line[0] == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(line[1:], closing)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated %c", ErrMalformed, line[0])
+			}
+			field, line = line[1:end+1], line[end+2:]
+		default:
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			field, line = line[:end], line[end:]
+		}
+		if field == "-" {
+			field = ""
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
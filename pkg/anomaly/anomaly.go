@@ -0,0 +1,179 @@
+// Package anomaly flags unusual presigned URL issuance: sudden spikes in a
+// caller's issuance rate, URLs requested from an IP the caller hasn't used
+// before, and delete URLs requested outside business hours.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cronwindow"
+)
+
+// Anomaly types
+const (
+	TypeRateSpike      = "rate_spike"
+	TypeNewIP          = "new_ip"
+	TypeOffHoursDelete = "off_hours_delete"
+)
+
+// LearningPeriod is how long a caller is observed before its rate and IPs
+// are judged
+const LearningPeriod = 24 * time.Hour
+
+// MaxAnomalies bounds the recent anomalies kept in memory
+const MaxAnomalies = 1000
+
+// maxIPs bounds the IPs remembered per caller; the least recently used one
+// is forgotten first
+const maxIPs = 256
+
+// smoothing is the weight of each hour in a caller's usual rate, a moving
+// average over about a day
+const smoothing = 1.0 / 24
+
+// Issuance is a presigned URL issued to a caller, identified by tenant and
+// subject
+type Issuance struct {
+	Time      time.Time
+	TenantID  string
+	Subject   string
+	ClientIP  string
+	Method    string
+	ObjectKey string
+}
+
+// Anomaly is an issuance flagged as unusual
+type Anomaly struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Method    string    `json:"method"`
+	ObjectKey string    `json:"object_key"`
+	Detail    string    `json:"detail"`
+}
+
+// Options tunes the detector
+type Options struct {
+	// An hour with more than SpikeFactor times the caller's usual hourly
+	// URLs, and at least SpikeMinimum, is a spike
+	SpikeFactor  float64
+	SpikeMinimum int
+	// Delete URLs are expected inside these windows; nil disables the check
+	BusinessHours *cronwindow.Schedule
+}
+
+// caller is the issuance history of a tenant and subject
+type caller struct {
+	firstSeen time.Time
+	hour      time.Time            // Start of the hour being counted
+	count     int                  // URLs issued in hour
+	usual     float64              // Moving average of hourly counts
+	spiked    bool                 // A spike was flagged in hour
+	ips       map[string]time.Time // Last issuance by client IP
+}
+
+// Detector tracks issuance per caller in memory
+type Detector struct {
+	mu        sync.Mutex
+	opts      Options
+	callers   map[string]*caller
+	anomalies []Anomaly // Oldest first
+}
+
+// NewDetector creates a detector without history
+func NewDetector(opts Options) *Detector {
+	return &Detector{opts: opts, callers: make(map[string]*caller)}
+}
+
+// Observe records an issuance and returns the anomalies it raises. Rate
+// spikes are flagged once per hour, and rates and IPs only once the caller
+// has been observed for LearningPeriod.
+func (d *Detector) Observe(i Issuance) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id := i.TenantID + "/" + i.Subject
+	c, ok := d.callers[id]
+	if !ok {
+		c = &caller{firstSeen: i.Time, hour: i.Time.Truncate(time.Hour), ips: make(map[string]time.Time)}
+		d.callers[id] = c
+	}
+	learned := i.Time.Sub(c.firstSeen) >= LearningPeriod
+
+	var found []Anomaly
+	flag := func(kind, detail string) {
+		found = append(found, Anomaly{
+			Type:      kind,
+			Time:      i.Time.UTC(),
+			TenantID:  i.TenantID,
+			Subject:   i.Subject,
+			ClientIP:  i.ClientIP,
+			Method:    i.Method,
+			ObjectKey: i.ObjectKey,
+			Detail:    detail,
+		})
+	}
+
+	if hour := i.Time.Truncate(time.Hour); hour.After(c.hour) {
+		// Fold the counted hour, then the empty hours since, into the
+		// usual rate
+		empty := int(hour.Sub(c.hour)/time.Hour) - 1
+		c.usual += smoothing * (float64(c.count) - c.usual)
+		c.usual *= math.Pow(1-smoothing, float64(empty))
+		c.hour, c.count, c.spiked = hour, 0, false
+	}
+	c.count++
+	if learned && !c.spiked && c.count >= d.opts.SpikeMinimum && float64(c.count) > d.opts.SpikeFactor*max(c.usual, 1) {
+		c.spiked = true
+		flag(TypeRateSpike, fmt.Sprintf("%d URLs this hour, usually %.1f", c.count, c.usual))
+	}
+
+	if i.ClientIP != "" {
+		if _, seen := c.ips[i.ClientIP]; !seen && learned && len(c.ips) > 0 {
+			flag(TypeNewIP, "first URL requested from "+i.ClientIP)
+		}
+		c.ips[i.ClientIP] = i.Time
+		if len(c.ips) > maxIPs {
+			forgetOldest(c.ips)
+		}
+	}
+
+	if i.Method == http.MethodDelete && d.opts.BusinessHours != nil && !d.opts.BusinessHours.Open(i.Time) {
+		flag(TypeOffHoursDelete, "delete URL requested outside business hours")
+	}
+
+	d.anomalies = append(d.anomalies, found...)
+	if len(d.anomalies) > MaxAnomalies {
+		d.anomalies = d.anomalies[len(d.anomalies)-MaxAnomalies:]
+	}
+	return found
+}
+
+// Recent returns up to limit anomalies, newest first
+func (d *Detector) Recent(limit int) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	anomalies := []Anomaly{}
+	for i := len(d.anomalies) - 1; i >= 0 && len(anomalies) < limit; i-- {
+		anomalies = append(anomalies, d.anomalies[i])
+	}
+	return anomalies
+}
+
+// forgetOldest drops the least recently used IP
+func forgetOldest(ips map[string]time.Time) {
+	var oldest string
+	for ip, last := range ips {
+		if oldest == "" || last.Before(ips[oldest]) {
+			oldest = ip
+		}
+	}
+	delete(ips, oldest)
+}
@@ -0,0 +1,301 @@
+// Package apikey manages API keys issued through the admin API. Keys are
+// stored as salted SHA-256 hashes; the plaintext is only returned when a key
+// is created or rotated.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyPrefix marks keys issued by this package
+const keyPrefix = "sk_"
+
+// lastUsedResolution limits how often a key's last use is persisted
+const lastUsedResolution = time.Minute
+
+var (
+	// ErrNotFound is returned when a key ID is unknown
+	ErrNotFound = errors.New("api key not found")
+	// ErrRevoked is returned when rotating a revoked key
+	ErrRevoked = errors.New("api key is revoked")
+)
+
+// Key is a managed API key without its secret
+type Key struct {
+	ID         string     `json:"key_id"`
+	Name       string     `json:"name"`
+	Masked     string     `json:"masked"` // Key ID and last characters of the secret
+	Scopes     []string   `json:"scopes,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	Salt string `json:"salt"`
+	Hash string `json:"hash"`
+
+	// The secret replaced by the last rotation stays valid until
+	// PreviousExpiresAt so clients can roll over
+	PreviousSalt      string     `json:"previous_salt,omitempty"`
+	PreviousHash      string     `json:"previous_hash,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// Store keeps managed keys in memory, optionally persisted to a JSON file
+type Store struct {
+	mu   sync.Mutex
+	keys map[string]*Key
+	path string
+}
+
+// NewStore creates a key store persisted to path, loading its keys if the
+// file exists. An empty path keeps keys in memory only.
+func NewStore(path string) (*Store, error) {
+	s := &Store{keys: make(map[string]*Key), path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api key store: %w", err)
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api key store %s: %w", path, err)
+	}
+	for i := range keys {
+		s.keys[keys[i].ID] = &keys[i]
+	}
+
+	return s, nil
+}
+
+// Create issues a new key granted scopes and returns it along with its
+// plaintext
+func (s *Store) Create(name string, scopes []string) (Key, string, error) {
+	id := randomHex(8)
+	secret := randomHex(32)
+	salt := randomHex(16)
+
+	key := &Key{
+		ID:        id,
+		Name:      name,
+		Masked:    mask(id, secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+		Salt:      salt,
+		Hash:      hashSecret(salt, secret),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[id] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, id)
+		return Key{}, "", err
+	}
+	return *key, plaintext(id, secret), nil
+}
+
+// List returns all keys, including revoked ones, ordered by creation
+func (s *Store) List() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// Rotate replaces a key's secret, keeping the old one valid for grace, and
+// returns the new plaintext
+func (s *Store) Rotate(id string, grace time.Duration) (Key, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, "", ErrNotFound
+	}
+	if key.RevokedAt != nil {
+		return Key{}, "", ErrRevoked
+	}
+
+	previous := *key
+	now := time.Now().UTC()
+	secret := randomHex(32)
+	salt := randomHex(16)
+
+	key.PreviousSalt, key.PreviousHash, key.PreviousExpiresAt = "", "", nil
+	if grace > 0 {
+		expires := now.Add(grace)
+		key.PreviousSalt, key.PreviousHash, key.PreviousExpiresAt = key.Salt, key.Hash, &expires
+	}
+	key.Salt = salt
+	key.Hash = hashSecret(salt, secret)
+	key.Masked = mask(id, secret)
+	key.RotatedAt = &now
+
+	if err := s.save(); err != nil {
+		*key = previous
+		return Key{}, "", err
+	}
+	return *key, plaintext(id, secret), nil
+}
+
+// Revoke invalidates a key immediately. The record is kept for auditing.
+func (s *Store) Revoke(id string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	if key.RevokedAt != nil {
+		return *key, nil
+	}
+
+	previous := *key
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	key.PreviousSalt, key.PreviousHash, key.PreviousExpiresAt = "", "", nil
+
+	if err := s.save(); err != nil {
+		*key = previous
+		return Key{}, err
+	}
+	return *key, nil
+}
+
+// Verify checks a plaintext key and records its use. It returns the key when
+// valid.
+func (s *Store) Verify(plain string) (Key, bool) {
+	id, secret, ok := parse(plain)
+	if !ok {
+		return Key{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return Key{}, false
+	}
+
+	now := time.Now().UTC()
+	valid := matches(key.Salt, key.Hash, secret) ||
+		(key.PreviousExpiresAt != nil && now.Before(*key.PreviousExpiresAt) && matches(key.PreviousSalt, key.PreviousHash, secret))
+	if !valid {
+		return Key{}, false
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		key.LastUsedAt = &now
+		// Failing to persist the timestamp must not fail authentication
+		_ = s.save()
+	}
+	return *key, true
+}
+
+// save atomically rewrites the store file. Must be called with the lock held.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode api key store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return fmt.Errorf("failed to write api key store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write api key store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write api key store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write api key store: %w", err)
+	}
+
+	return nil
+}
+
+// plaintext formats a key as sk_<id>_<secret>
+func plaintext(id, secret string) string {
+	return keyPrefix + id + "_" + secret
+}
+
+// parse splits a plaintext key into its ID and secret
+func parse(plain string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(plain, keyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// mask shows the key ID and the last four characters of the secret
+func mask(id, secret string) string {
+	return keyPrefix + id + "_…" + secret[len(secret)-4:]
+}
+
+// hashSecret returns the hex SHA-256 of salt and secret
+func hashSecret(salt, secret string) string {
+	sum := sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// matches compares secret against a stored salted hash in constant time
+func matches(salt, hash, secret string) bool {
+	if hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(salt, secret)), []byte(hash)) == 1
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
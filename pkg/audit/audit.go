@@ -0,0 +1,154 @@
+// Package audit forwards URL issuance events to a security monitoring (SIEM)
+// sink: an HTTP collector, syslog or CloudWatch Logs. Events are batched and
+// sent in the background with retries, so a slow or failing sink never
+// delays presigning.
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+)
+
+// Event records the issuance of a presigned URL
+type Event struct {
+	Time      time.Time  `json:"time"`
+	Type      string     `json:"type"` // Always url_issued
+	Method    string     `json:"method"`
+	ObjectKey string     `json:"object_key"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	UploadID  string     `json:"upload_id,omitempty"`
+	Subject   string     `json:"subject,omitempty"` // Authenticated principal
+	TenantID  string     `json:"tenant_id,omitempty"`
+	ClientIP  string     `json:"client_ip,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	Endpoint  string     `json:"endpoint"` // Request path that issued the URL
+}
+
+// EventURLIssued is the type of URL issuance events
+const EventURLIssued = "url_issued"
+
+// Sink delivers batches of events. Send is called from a single goroutine
+// and retried on error, so it should be safe to repeat.
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// queueSize bounds the events waiting to be sent; later events are dropped
+const queueSize = 10000
+
+// Forwarder batches events and sends them to a sink in the background
+type Forwarder struct {
+	sink     Sink
+	batch    int
+	interval time.Duration
+	retry    resilience.RetryPolicy
+	metrics  *metrics.Registry
+
+	events chan Event
+	done   chan struct{}
+	stop   sync.Once
+}
+
+// NewForwarder creates a forwarder sending batches of up to batchSize events
+// to sink, at least every interval while events are pending. Call Start to
+// begin sending and Close to flush on shutdown.
+func NewForwarder(sink Sink, batchSize int, interval time.Duration, registry *metrics.Registry) *Forwarder {
+	if registry == nil {
+		registry = metrics.NewRegistry()
+	}
+	registry.Describe("audit_events_total", "URL issuance audit events by result (sent, dropped or failed)")
+	return &Forwarder{
+		sink:     sink,
+		batch:    batchSize,
+		interval: interval,
+		retry:    resilience.RetryPolicy{MaxAttempts: 5, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second},
+		metrics:  registry,
+		events:   make(chan Event, queueSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// Publish queues an event without blocking. It is dropped when the queue is
+// full because the sink can't keep up.
+func (f *Forwarder) Publish(event Event) {
+	event.Type = EventURLIssued
+	select {
+	case f.events <- event:
+	default:
+		f.metrics.IncCounter("audit_events_total", metrics.Labels{"result": "dropped"})
+	}
+}
+
+// Start sends queued events until Close is called
+func (f *Forwarder) Start() {
+	go f.run()
+}
+
+// Close stops accepting events, sends the queued ones within ctx and closes
+// the sink
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.stop.Do(func() { close(f.events) })
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		logging.Warnf("Audit forwarder stopped with events pending: %v", ctx.Err())
+	}
+	return f.sink.Close()
+}
+
+func (f *Forwarder) run() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	pending := make([]Event, 0, f.batch)
+	for {
+		select {
+		case event, ok := <-f.events:
+			if !ok {
+				f.flush(pending)
+				return
+			}
+			pending = append(pending, event)
+			if len(pending) < f.batch {
+				continue
+			}
+		case <-ticker.C:
+			if len(pending) == 0 {
+				continue
+			}
+		}
+		f.flush(pending)
+		pending = pending[:0]
+	}
+}
+
+// flush sends a batch, retrying failures with backoff. A batch that still
+// fails is dropped and logged.
+func (f *Forwarder) flush(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	retryable := func(error) bool { return true }
+	err := resilience.Retry(ctx, f.retry, retryable, nil, func(ctx context.Context) error {
+		return f.sink.Send(ctx, events)
+	})
+	if err != nil {
+		logging.Errorf("Failed to forward %d audit events: %v", len(events), err)
+		f.metrics.AddCounter("audit_events_total", metrics.Labels{"result": "failed"}, float64(len(events)))
+		return
+	}
+	f.metrics.AddCounter("audit_events_total", metrics.Labels{"result": "sent"}, float64(len(events)))
+}
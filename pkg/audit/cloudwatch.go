@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cwlogs"
+)
+
+// CloudWatchSink writes events to a CloudWatch Logs stream, one JSON log
+// event per audit event
+type CloudWatchSink struct {
+	client *cwlogs.Client
+}
+
+// NewCloudWatchSink creates a sink for stream in the existing log group
+// group, signing requests with credentials retrieved from the given
+// provider. The credentials need logs:CreateLogStream and logs:PutLogEvents
+// on the group.
+func NewCloudWatchSink(group, stream, region string, credentials aws.CredentialsProvider) *CloudWatchSink {
+	return &CloudWatchSink{client: cwlogs.NewClient(group, stream, region, credentials)}
+}
+
+// Send writes events to the stream
+func (s *CloudWatchSink) Send(ctx context.Context, events []Event) error {
+	logEvents := make([]cwlogs.Event, len(events))
+	for i, event := range events {
+		message, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		logEvents[i] = cwlogs.Event{Timestamp: event.Time.UnixMilli(), Message: string(message)}
+	}
+	return s.client.PutLogEvents(ctx, logEvents)
+}
+
+// Close does nothing
+func (s *CloudWatchSink) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSink posts each batch to a collector URL as newline-delimited JSON
+type HTTPSink struct {
+	url           string
+	authorization string
+	client        *http.Client
+}
+
+// NewHTTPSink creates a sink posting to url, sending authorization (e.g.
+// "Splunk <token>" or "Bearer <token>") in the Authorization header when set
+func NewHTTPSink(url, authorization string) *HTTPSink {
+	return &HTTPSink{url: url, authorization: authorization, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Send posts events, one JSON object per line. Any non-2xx response is an
+// error.
+func (s *HTTPSink) Send(ctx context.Context, events []Event) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.authorization != "" {
+		req.Header.Set("Authorization", s.authorization)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("audit collector returned %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Close does nothing
+func (s *HTTPSink) Close() error {
+	return nil
+}
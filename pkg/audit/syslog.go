@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogPriority is facility log audit (13) at severity informational (6)
+const syslogPriority = 13*8 + 6
+
+// SyslogSink sends each event as an RFC 5424 message with a JSON body, over
+// UDP or TCP (octet-counting framing, RFC 6587)
+type SyslogSink struct {
+	network, address string
+	hostname         string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates a sink for an address like udp://siem:514 or
+// tcp://siem:6514. The connection is opened on the first send.
+func NewSyslogSink(address string) (*SyslogSink, error) {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Port() == "" {
+		return nil, fmt.Errorf("syslog address must look like udp://host:514 or tcp://host:514 (got %q)", address)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{network: u.Scheme, address: u.Host, hostname: hostname}, nil
+}
+
+// Send writes one message per event, reconnecting after a failed write
+func (s *SyslogSink) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, event := range events {
+		if _, err := s.conn.Write(s.format(event)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// format renders event as an RFC 5424 message, framed for TCP
+func (s *SyslogSink) format(event Event) []byte {
+	body, _ := json.Marshal(event)
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "<%d>1 %s %s signer-service %d %s - ",
+		syslogPriority, event.Time.UTC().Format(time.RFC3339Nano), s.hostname, os.Getpid(), EventURLIssued)
+	msg.Write(body)
+
+	if s.network == "tcp" {
+		return []byte(fmt.Sprintf("%d %s", msg.Len(), msg.String()))
+	}
+	return []byte(msg.String())
+}
+
+// Close closes the connection
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
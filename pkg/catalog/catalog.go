@@ -0,0 +1,109 @@
+// Package catalog records confirmed backup objects in a database, so they can
+// be searched without listing the bucket.
+package catalog
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxSearchLimit caps the entries returned by one search
+const MaxSearchLimit = 1000
+
+// Entry is a confirmed object
+type Entry struct {
+	Key            string    `json:"object_key"`
+	Size           int64     `json:"size"`
+	ETag           string    `json:"etag"`
+	ChecksumSHA256 string    `json:"checksum_sha256,omitempty"` // Base64, when uploaded with one
+	TenantID       string    `json:"tenant_id,omitempty"`
+	RunID          string    `json:"run_id,omitempty"`
+	LastModified   time.Time `json:"last_modified"`
+	ConfirmedAt    time.Time `json:"confirmed_at"`
+}
+
+// Filename returns the last segment of the entry's key
+func (e Entry) Filename() string {
+	return path.Base(e.Key)
+}
+
+// Query selects catalog entries. Prefix is required; the other fields narrow
+// the search when set.
+type Query struct {
+	Prefix   string
+	Filename string // Exact last key segment
+	RunID    string
+	Checksum string    // Base64 SHA-256 checksum
+	From     time.Time // Last modified at or after
+	To       time.Time // Last modified before
+	After    string    // Key to continue after, from the previous page
+	Limit    int       // 1 to MaxSearchLimit
+}
+
+// matches reports whether e is selected by q, ignoring After and Limit
+func (q Query) matches(e Entry) bool {
+	return strings.HasPrefix(e.Key, q.Prefix) &&
+		(q.Filename == "" || e.Filename() == q.Filename) &&
+		(q.RunID == "" || e.RunID == q.RunID) &&
+		(q.Checksum == "" || e.ChecksumSHA256 == q.Checksum) &&
+		(q.From.IsZero() || !e.LastModified.Before(q.From)) &&
+		(q.To.IsZero() || e.LastModified.Before(q.To))
+}
+
+// Store persists catalog entries. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Record adds an entry, replacing any entry with the same key
+	Record(ctx context.Context, e Entry) error
+	// Search returns up to q.Limit entries matching q in key order
+	Search(ctx context.Context, q Query) ([]Entry, error)
+	// Close releases the store's resources
+	Close() error
+}
+
+// MemoryStore keeps entries in memory; they are lost on restart
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+// Record adds an entry, replacing any entry with the same key
+func (s *MemoryStore) Record(ctx context.Context, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[e.Key] = e
+	return nil
+}
+
+// Search returns up to q.Limit entries matching q in key order
+func (s *MemoryStore) Search(ctx context.Context, q Query) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := []Entry{}
+	for _, e := range s.entries {
+		if e.Key > q.After && q.matches(e) {
+			found = append(found, e)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Key < found[j].Key })
+	if len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return found, nil
+}
+
+// Close does nothing
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,139 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq" // Registers the postgres driver
+)
+
+// schema creates the catalog table. Keys use the C collation so they sort
+// bytewise, as S3 lists them, and prefix searches can use the primary key.
+const schema = `
+CREATE TABLE IF NOT EXISTS catalog_objects (
+	object_key      TEXT COLLATE "C" PRIMARY KEY,
+	filename        TEXT NOT NULL,
+	size            BIGINT NOT NULL,
+	etag            TEXT NOT NULL,
+	checksum_sha256 TEXT NOT NULL DEFAULT '',
+	tenant_id       TEXT NOT NULL DEFAULT '',
+	run_id          TEXT NOT NULL DEFAULT '',
+	last_modified   TIMESTAMPTZ NOT NULL,
+	confirmed_at    TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS catalog_objects_filename ON catalog_objects (filename);
+CREATE INDEX IF NOT EXISTS catalog_objects_run_id ON catalog_objects (run_id) WHERE run_id <> '';
+CREATE INDEX IF NOT EXISTS catalog_objects_checksum ON catalog_objects (checksum_sha256) WHERE checksum_sha256 <> '';
+`
+
+// PostgresStore keeps entries in a Postgres table, created on first use
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore connects to the database at url (a postgres:// URL or
+// key=value connection string) and creates the catalog table if missing
+func NewPostgresStore(ctx context.Context, url string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog database: %w", err)
+	}
+	db.SetMaxOpenConns(10)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create catalog table: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Record adds an entry, replacing any entry with the same key
+func (s *PostgresStore) Record(ctx context.Context, e Entry) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO catalog_objects
+			(object_key, filename, size, etag, checksum_sha256, tenant_id, run_id, last_modified, confirmed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (object_key) DO UPDATE SET
+			filename = EXCLUDED.filename,
+			size = EXCLUDED.size,
+			etag = EXCLUDED.etag,
+			checksum_sha256 = EXCLUDED.checksum_sha256,
+			tenant_id = EXCLUDED.tenant_id,
+			run_id = EXCLUDED.run_id,
+			last_modified = EXCLUDED.last_modified,
+			confirmed_at = EXCLUDED.confirmed_at`,
+		e.Key, e.Filename(), e.Size, e.ETag, e.ChecksumSHA256, e.TenantID, e.RunID, e.LastModified, e.ConfirmedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record catalog entry: %w", err)
+	}
+	return nil
+}
+
+// Search returns up to q.Limit entries matching q in key order
+func (s *PostgresStore) Search(ctx context.Context, q Query) ([]Entry, error) {
+	conditions := []string{`object_key LIKE $1 ESCAPE '\'`}
+	args := []any{escapeLike(q.Prefix) + "%"}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if q.Filename != "" {
+		add("filename = $%d", q.Filename)
+	}
+	if q.RunID != "" {
+		add("run_id = $%d", q.RunID)
+	}
+	if q.Checksum != "" {
+		add("checksum_sha256 = $%d", q.Checksum)
+	}
+	if !q.From.IsZero() {
+		add("last_modified >= $%d", q.From)
+	}
+	if !q.To.IsZero() {
+		add("last_modified < $%d", q.To)
+	}
+	if q.After != "" {
+		add("object_key > $%d", q.After)
+	}
+	args = append(args, q.Limit)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT object_key, size, etag, checksum_sha256, tenant_id, run_id, last_modified, confirmed_at
+		FROM catalog_objects
+		WHERE %s
+		ORDER BY object_key
+		LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search catalog: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.Key, &e.Size, &e.ETag, &e.ChecksumSHA256, &e.TenantID, &e.RunID, &e.LastModified, &e.ConfirmedAt); err != nil {
+			return nil, fmt.Errorf("failed to read catalog entry: %w", err)
+		}
+		e.LastModified = e.LastModified.UTC()
+		e.ConfirmedAt = e.ConfirmedAt.UTC()
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search catalog: %w", err)
+	}
+	return entries, nil
+}
+
+// Close closes the database connections
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
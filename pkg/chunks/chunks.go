@@ -0,0 +1,211 @@
+// Package chunks tracks chunked backups: large backups a client splits into
+// separately uploaded chunk objects, tied together by a manifest object once
+// every chunk is in the bucket.
+package chunks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+// ManifestVersion is the format version written in manifest objects
+const ManifestVersion = 1
+
+// Status is the lifecycle state of a chunked backup
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusCompleted Status = "completed"
+)
+
+var (
+	// ErrNotFound is returned when a backup ID is unknown
+	ErrNotFound = errors.New("chunked backup not found")
+	// ErrCompleted is returned when completing a backup twice
+	ErrCompleted = errors.New("chunked backup is already completed")
+)
+
+// Chunk is one chunk of a backup, in manifest order
+type Chunk struct {
+	Index          int    `json:"index"`
+	ObjectKey      string `json:"object_key"`
+	Size           int64  `json:"size"`
+	ChecksumSHA256 string `json:"checksum_sha256,omitempty"`
+	ContentMD5     string `json:"content_md5,omitempty"`
+	ETag           string `json:"etag,omitempty"` // Set once the chunk is verified
+}
+
+// Backup is a registered chunked backup
+type Backup struct {
+	ID          string `json:"backup_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	// Encoding of the reassembled backup; chunks are stored without it, as
+	// they can only be decoded once concatenated
+	ContentEncoding string     `json:"content_encoding,omitempty"`
+	ManifestKey     string     `json:"manifest_key"`
+	TotalSize       int64      `json:"total_size"`
+	Status          Status     `json:"status"`
+	Chunks          []Chunk    `json:"chunks"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
+
+// Manifest is the object written next to the chunks of a completed backup.
+// Restores read it to list the chunks to fetch and concatenate, in order.
+type Manifest struct {
+	Version         int       `json:"version"`
+	BackupID        string    `json:"backup_id"`
+	Filename        string    `json:"filename"`
+	ContentType     string    `json:"content_type,omitempty"`
+	ContentEncoding string    `json:"content_encoding,omitempty"`
+	TotalSize       int64     `json:"total_size"`
+	Chunks          []Chunk   `json:"chunks"`
+	CompletedAt     time.Time `json:"completed_at"`
+}
+
+// Store keeps pending and completed chunked backups in memory
+type Store struct {
+	mu      sync.Mutex
+	backups map[string]*Backup
+}
+
+// NewStore creates an empty chunked backup store
+func NewStore() *Store {
+	return &Store{backups: make(map[string]*Backup)}
+}
+
+// Create registers a pending backup described by its filename, content
+// properties, manifest key and chunks. The ID, status, total size and
+// creation time are assigned.
+func (s *Store) Create(backup Backup) Backup {
+	stored := backup.snapshot()
+	b := &stored
+	b.ID = idgen.New()
+	b.Status = StatusPending
+	b.TotalSize = 0
+	b.CreatedAt = time.Now().UTC()
+	b.CompletedAt = nil
+	for _, c := range b.Chunks {
+		b.TotalSize += c.Size
+	}
+
+	s.mu.Lock()
+	s.backups[b.ID] = b
+	s.mu.Unlock()
+
+	return b.snapshot()
+}
+
+// Get returns a chunked backup
+func (s *Store) Get(id string) (Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.backups[id]
+	if !ok {
+		return Backup{}, ErrNotFound
+	}
+	return b.snapshot(), nil
+}
+
+// Complete marks a pending backup completed with its verified chunks and
+// returns the manifest to write
+func (s *Store) Complete(id string, chunks []Chunk) (Manifest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.backups[id]
+	if !ok {
+		return Manifest{}, ErrNotFound
+	}
+	if b.Status == StatusCompleted {
+		return Manifest{}, ErrCompleted
+	}
+
+	now := time.Now().UTC()
+	b.Status = StatusCompleted
+	b.Chunks = append([]Chunk(nil), chunks...)
+	b.CompletedAt = &now
+
+	return Manifest{
+		Version:         ManifestVersion,
+		BackupID:        b.ID,
+		Filename:        b.Filename,
+		ContentType:     b.ContentType,
+		ContentEncoding: b.ContentEncoding,
+		TotalSize:       b.TotalSize,
+		Chunks:          append([]Chunk(nil), chunks...),
+		CompletedAt:     now,
+	}, nil
+}
+
+// Reopen returns a backup to pending, after its manifest failed to be written
+func (s *Store) Reopen(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b, ok := s.backups[id]; ok {
+		b.Status = StatusPending
+		b.CompletedAt = nil
+	}
+}
+
+// snapshot copies the backup so callers can't modify the stored one
+func (b *Backup) snapshot() Backup {
+	c := *b
+	c.Chunks = append([]Chunk(nil), b.Chunks...)
+	return c
+}
+
+// ChunkChange is a chunk index present in both compared manifests with
+// different content
+type ChunkChange struct {
+	Index int   `json:"index"`
+	From  Chunk `json:"from"`
+	To    Chunk `json:"to"`
+}
+
+// ManifestDiff lists the chunks added, removed and changed from one backup's
+// manifest to another's, by index
+type ManifestDiff struct {
+	Added     []Chunk       `json:"added"`
+	Removed   []Chunk       `json:"removed"`
+	Changed   []ChunkChange `json:"changed"`
+	Unchanged int           `json:"unchanged"`
+}
+
+// Diff compares the chunks of two manifests by index. A chunk changed when
+// its size differs or, comparing SHA-256 checksums when both chunks have one
+// and ETags otherwise, its content does.
+func Diff(from, to Manifest) ManifestDiff {
+	diff := ManifestDiff{Added: []Chunk{}, Removed: []Chunk{}, Changed: []ChunkChange{}}
+	for i := 0; i < max(len(from.Chunks), len(to.Chunks)); i++ {
+		switch {
+		case i >= len(to.Chunks):
+			diff.Removed = append(diff.Removed, from.Chunks[i])
+		case i >= len(from.Chunks):
+			diff.Added = append(diff.Added, to.Chunks[i])
+		case sameChunk(from.Chunks[i], to.Chunks[i]):
+			diff.Unchanged++
+		default:
+			diff.Changed = append(diff.Changed, ChunkChange{Index: i, From: from.Chunks[i], To: to.Chunks[i]})
+		}
+	}
+	return diff
+}
+
+// sameChunk reports whether two chunks hold the same content
+func sameChunk(a, b Chunk) bool {
+	if a.Size != b.Size {
+		return false
+	}
+	if a.ChecksumSHA256 != "" && b.ChecksumSHA256 != "" {
+		return a.ChecksumSHA256 == b.ChecksumSHA256
+	}
+	return a.ETag == b.ETag
+}
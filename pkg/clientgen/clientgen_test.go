@@ -0,0 +1,209 @@
+package clientgen_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/clientgen"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/handler"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service/s3fake"
+)
+
+// Paths relative to this package
+const (
+	specPath   = "../../api/openapi.yaml"
+	clientsDir = "../../clients"
+)
+
+// seededKey is the backup the contract tests find, confirm and download
+const seededKey = "acme/inputs/2026-01-02/03-04-05/db.dump"
+
+func generate(t *testing.T) []clientgen.File {
+	t.Helper()
+	spec, err := clientgen.Load(specPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	files, err := clientgen.Generate(spec)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return files
+}
+
+func TestClientsUpToDate(t *testing.T) {
+	for _, f := range generate(t) {
+		current, err := os.ReadFile(filepath.Join(clientsDir, filepath.FromSlash(f.Path)))
+		if err != nil || !bytes.Equal(current, f.Content) {
+			t.Errorf("%s differs from api/openapi.yaml; run make clients", f.Path)
+		}
+	}
+}
+
+func TestParseRejectsUnsupportedSpecs(t *testing.T) {
+	for name, spec := range map[string]string{
+		"missing operationId":   "paths:\n  /x:\n    get:\n      summary: x\n",
+		"duplicate operationId": "paths:\n  /x:\n    get:\n      operationId: a\n  /y:\n    get:\n      operationId: a\n",
+		"inline body":           "paths:\n  /x:\n    post:\n      operationId: a\n      requestBody:\n        content:\n          application/json:\n            schema:\n              type: object\n",
+		"header parameter":      "paths:\n  /x:\n    get:\n      operationId: a\n      parameters:\n        - name: h\n          in: header\n          schema:\n            type: string\n",
+	} {
+		if _, err := clientgen.Parse([]byte(spec)); err == nil {
+			t.Errorf("%s: Parse succeeded", name)
+		}
+	}
+}
+
+// newServer serves the API over a fake bucket holding seededKey
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg, err := config.Load("", map[string]string{
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"S3_BUCKET_NAME":        "backups",
+		"COMPANY_PREFIX":        "acme",
+		"LOG_FORMAT":            "text",
+		"API_KEYS":              "backup-agent:agent-key",
+	})
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	bucket := s3fake.New()
+	bucket.Put(seededKey, s3fake.Object{Body: []byte("backup"), ContentType: "application/octet-stream"})
+	svc, err := service.NewS3Service(context.Background(), cfg, service.WithS3Client(bucket))
+	if err != nil {
+		t.Fatalf("NewS3Service: %v", err)
+	}
+	server := httptest.NewServer(handler.NewHandler(svc, cfg).SetupRoutes())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// run executes a contract check and fails the test with its output
+func run(t *testing.T, cmd *exec.Cmd) {
+	t.Helper()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", cmd, err, out)
+	}
+}
+
+const pythonContract = `
+import sys
+sys.path.insert(0, sys.argv[2])
+from signer_client import Client, APIError
+
+client = Client(sys.argv[1], api_key="agent-key")
+assert client.health_check()["status"] == "healthy"
+
+found = client.search_object("db.dump")
+assert found["exists"] and found["object_key"] == sys.argv[3], found
+assert not client.search_object("missing.dump")["exists"]
+
+upload = client.create_upload_url("new.dump", content_type="application/octet-stream", metadata={"host": "db1"})
+assert upload["url"].startswith("http") and upload["upload_id"], upload
+assert upload["required_headers"]["host"] == "x-amz-meta-host", upload
+
+confirmed = client.confirm_object(object_key=sys.argv[3])
+assert confirmed["confirmed"] and confirmed["object"]["size"] == 6, confirmed
+
+latest = client.get_latest_object("db.dump")
+assert latest["object"]["object_key"] == sys.argv[3] and latest["download"]["method"] == "GET", latest
+
+download = client.presign("download", object_key=sys.argv[3])
+assert download["method"] == "GET" and download["object_key"] == sys.argv[3], download
+
+try:
+    client.presign("download")
+    raise AssertionError("presign without object_key succeeded")
+except APIError as err:
+    assert err.status == 400 and err.code == "VALIDATION_FAILED" and not err.retryable, err
+
+try:
+    Client(sys.argv[1], api_key="wrong", language="es").search_object("db.dump")
+    raise AssertionError("wrong API key accepted")
+except APIError as err:
+    assert err.status == 401 and err.error == "No autorizado", err
+`
+
+func TestPythonClientContract(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+	server := newServer(t)
+	script := filepath.Join(t.TempDir(), "contract.py")
+	if err := os.WriteFile(script, []byte(pythonContract), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir, _ := filepath.Abs(filepath.Join(clientsDir, "python"))
+	run(t, exec.Command(python, "-B", script, server.URL, dir, seededKey))
+}
+
+const javaContract = `
+import io.github.andressep95.signer.client.*;
+import java.util.Map;
+
+public class Contract {
+    static void check(boolean ok, Object detail) {
+        if (!ok) throw new AssertionError(String.valueOf(detail));
+    }
+
+    @SuppressWarnings("unchecked")
+    public static void main(String[] args) throws Exception {
+        SignerClient client = new SignerClient(args[0], "agent-key");
+        check("healthy".equals(client.healthCheck().get("status")), "health");
+
+        Map<String, Object> found = client.searchObject(new SearchObjectRequest("db.dump"));
+        check(Boolean.TRUE.equals(found.get("exists")) && args[1].equals(found.get("object_key")), found);
+
+        Map<String, Object> upload = client.createUploadURL(new PresignedURLRequest("new.dump")
+                .contentType("application/octet-stream").metadata(Map.of("host", "db1")));
+        check(((String) upload.get("url")).startsWith("http") && upload.get("upload_id") != null, upload);
+
+        Map<String, Object> confirmed = client.confirmObject(new ConfirmObjectRequest().objectKey(args[1]));
+        check(Long.valueOf(6).equals(((Map<String, Object>) confirmed.get("object")).get("size")), confirmed);
+
+        Map<String, Object> latest = client.getLatestObject("db.dump");
+        check("GET".equals(((Map<String, Object>) latest.get("download")).get("method")), latest);
+
+        Map<String, Object> download = client.presign(new PresignV2Request("download").objectKey(args[1]));
+        check(args[1].equals(download.get("object_key")), download);
+
+        try {
+            client.presign(new PresignV2Request("download"));
+            check(false, "presign without object_key succeeded");
+        } catch (ApiException e) {
+            check(e.status() == 400 && "VALIDATION_FAILED".equals(e.code()) && !e.retryable(), e.getMessage());
+        }
+    }
+}
+`
+
+func TestJavaClientContract(t *testing.T) {
+	javac, err := exec.LookPath("javac")
+	if err != nil {
+		t.Skip("javac is not installed")
+	}
+	java, err := exec.LookPath("java")
+	if err != nil {
+		t.Skip("java is not installed")
+	}
+	server := newServer(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Contract.java"), []byte(javaContract), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sources, _ := filepath.Glob(filepath.Join(clientsDir, filepath.FromSlash(clientgen.JavaSources), "*.java"))
+	classes := filepath.Join(dir, "classes")
+	run(t, exec.Command(javac, append([]string{"-d", classes, filepath.Join(dir, "Contract.java")}, sources...)...))
+	run(t, exec.Command(java, "-cp", classes, "Contract", server.URL, seededKey))
+}
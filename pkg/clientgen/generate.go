@@ -0,0 +1,287 @@
+package clientgen
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// JavaPackage is the package of the generated Java client
+const JavaPackage = "io.github.andressep95.signer.client"
+
+// Paths of the generated clients, relative to the output directory
+const (
+	PythonClient = "python/signer_client.py"
+	JavaSources  = "java/src/main/java/io/github/andressep95/signer/client"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"pyString": pyString,
+	"comment":  func(s string) string { return strings.ReplaceAll(s, "*/", "*\\/") },
+}).ParseFS(templateFiles, "templates/*.tmpl"))
+
+// File is a generated source file
+type File struct {
+	Path    string // Relative to the output directory
+	Content []byte
+}
+
+// field is a parameter or body property of an operation
+type field struct {
+	Name        string // As sent on the wire
+	PyName      string
+	JavaName    string
+	JavaType    string
+	Description string
+	Required    bool
+	In          string // query, path or body
+}
+
+// operation is the template view of an Operation
+type operation struct {
+	PyName, JavaName string
+	Method, Path     string
+	Summary          string
+	Authenticated    bool
+	Body             string  // Request schema name, empty without a body
+	Response         string  // Response schema name
+	Params           []field // Path and query parameters, then body properties
+}
+
+// request is the template view of a request body schema
+type request struct {
+	Name        string
+	Description string
+	Fields      []field
+}
+
+// Required returns the fields a request can't be sent without
+func (r request) Required() []field {
+	return slices.DeleteFunc(slices.Clone(r.Fields), func(f field) bool { return !f.Required })
+}
+
+// Optional returns the fields a request may leave out
+func (r request) Optional() []field {
+	return slices.DeleteFunc(slices.Clone(r.Fields), func(f field) bool { return f.Required })
+}
+
+// model is what the templates render
+type model struct {
+	Spec        *Spec
+	Package     string
+	Operations  []operation
+	Requests    []request
+	requestName map[string]bool
+}
+
+// RequiredParams returns the operation's required parameters, path first
+func (o operation) RequiredParams() []field {
+	return slices.DeleteFunc(slices.Clone(o.Params), func(f field) bool { return !f.Required })
+}
+
+// OptionalParams returns the operation's optional parameters
+func (o operation) OptionalParams() []field {
+	return slices.DeleteFunc(slices.Clone(o.Params), func(f field) bool { return f.Required })
+}
+
+// Query returns the operation's query parameters
+func (o operation) Query() []field {
+	return slices.DeleteFunc(slices.Clone(o.Params), func(f field) bool { return f.In != "query" })
+}
+
+// PathParams returns the operation's path parameters
+func (o operation) PathParams() []field {
+	return slices.DeleteFunc(slices.Clone(o.Params), func(f field) bool { return f.In != "path" })
+}
+
+// BodyFields returns the operation's body properties
+func (o operation) BodyFields() []field {
+	return slices.DeleteFunc(slices.Clone(o.Params), func(f field) bool { return f.In != "body" })
+}
+
+// Generate renders the Python and Java clients for the spec
+func Generate(spec *Spec) ([]File, error) {
+	m := &model{Spec: spec, Package: JavaPackage, requestName: make(map[string]bool)}
+	for _, op := range spec.Operations() {
+		o, err := m.operation(op)
+		if err != nil {
+			return nil, err
+		}
+		m.Operations = append(m.Operations, o)
+	}
+
+	files := []File{}
+	render := func(name, file string, data any) error {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+			return fmt.Errorf("render %s: %w", file, err)
+		}
+		files = append(files, File{Path: file, Content: buf.Bytes()})
+		return nil
+	}
+
+	if err := render("python.tmpl", PythonClient, m); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"SignerClient", "ApiException", "Json"} {
+		if err := render(name+".java.tmpl", path.Join(JavaSources, name+".java"), m); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range m.Requests {
+		data := struct {
+			Package string
+			request
+		}{JavaPackage, r}
+		if err := render("request.java.tmpl", path.Join(JavaSources, r.Name+".java"), data); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// operation builds the template view of op, registering its request schema
+func (m *model) operation(op *Operation) (operation, error) {
+	body, err := m.Spec.RequestSchema(op)
+	if err != nil {
+		return operation{}, err
+	}
+	o := operation{
+		PyName:        snakeCase(op.OperationID),
+		JavaName:      lowerFirst(op.OperationID),
+		Method:        op.Method,
+		Path:          op.Path,
+		Summary:       op.Summary,
+		Authenticated: op.Authenticated(),
+		Body:          body,
+		Response:      m.Spec.ResponseSchema(op),
+	}
+	for _, p := range op.Parameters {
+		f, err := newField(p.Name, p.Schema, p.Required || p.In == "path", p.In)
+		if err != nil {
+			return operation{}, fmt.Errorf("%s: %w", op.OperationID, err)
+		}
+		f.Description = p.Description
+		o.Params = append(o.Params, f)
+	}
+
+	if body != "" {
+		schema := m.Spec.Components.Schemas[body]
+		r := request{Name: body, Description: schema.Description}
+		for _, prop := range schema.Properties {
+			f, err := newField(prop.Name, m.resolve(prop.Schema), slices.Contains(schema.Required, prop.Name), "body")
+			if err != nil {
+				return operation{}, fmt.Errorf("%s.%s: %w", body, prop.Name, err)
+			}
+			f.Description = prop.Schema.Description
+			r.Fields = append(r.Fields, f)
+		}
+		o.Params = append(o.Params, r.Fields...)
+		if !m.requestName[body] {
+			m.requestName[body] = true
+			m.Requests = append(m.Requests, r)
+		}
+	}
+
+	// Required parameters come first in the Python signature
+	slices.SortStableFunc(o.Params, func(a, b field) int {
+		switch {
+		case a.Required == b.Required:
+			return 0
+		case a.Required:
+			return -1
+		default:
+			return 1
+		}
+	})
+	return o, nil
+}
+
+// resolve marks references to component object schemas, which the clients
+// pass as plain maps
+func (m *model) resolve(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{Type: "object", Description: s.Description}
+	}
+	return s
+}
+
+// javaBoxed maps Java primitives to the classes that can hold null
+var javaBoxed = map[string]string{"long": "Long", "int": "Integer", "boolean": "Boolean"}
+
+// newField maps a schema to the types of each client
+func newField(name string, s *Schema, required bool, in string) (field, error) {
+	if s == nil {
+		return field{}, fmt.Errorf("%s has no schema", name)
+	}
+	f := field{Name: name, PyName: name, JavaName: camelCase(name), Required: required, In: in}
+	switch {
+	case s.Type == "string":
+		f.JavaType = "String"
+	case s.Type == "integer" && s.Format == "int64":
+		f.JavaType = "long"
+	case s.Type == "integer":
+		f.JavaType = "int"
+	case s.Type == "boolean":
+		f.JavaType = "boolean"
+	case s.Type == "object" && s.AdditionalProperties != nil && s.AdditionalProperties.Type == "string":
+		f.JavaType = "Map<String, String>"
+	case s.Type == "object":
+		f.JavaType = "Map<String, Object>"
+	default:
+		return field{}, fmt.Errorf("%s: unsupported type %q", name, s.Type)
+	}
+	// Optional parameters are passed as null
+	if boxed, ok := javaBoxed[f.JavaType]; ok && in != "body" && !required {
+		f.JavaType = boxed
+	}
+	return f, nil
+}
+
+// snakeCase converts an operationId such as createUploadURL to create_upload_url
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// camelCase converts a JSON name such as object_key to objectKey
+func camelCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// pyString quotes s as a Python string literal
+func pyString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
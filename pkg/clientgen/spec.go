@@ -0,0 +1,232 @@
+// Package clientgen generates the Python and Java backup agent clients from
+// the service's OpenAPI spec (api/openapi.yaml). It understands the subset of
+// OpenAPI the spec uses: JSON bodies referencing component schemas, query and
+// path parameters, and string, integer, boolean and string map properties.
+package clientgen
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the part of an OpenAPI document the generators read
+type Spec struct {
+	Info struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+		Version     string `yaml:"version"`
+	} `yaml:"info"`
+	Paths      Paths `yaml:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `yaml:"schemas"`
+		Responses map[string]*Response `yaml:"responses"`
+	} `yaml:"components"`
+}
+
+// Paths lists the spec's paths in document order
+type Paths []PathItem
+
+// PathItem is a path and its operations in document order
+type PathItem struct {
+	Path       string
+	Operations []*Operation
+}
+
+// Operation is an API call
+type Operation struct {
+	Method      string                 `yaml:"-"` // Upper case
+	Path        string                 `yaml:"-"`
+	OperationID string                 `yaml:"operationId"`
+	Summary     string                 `yaml:"summary"`
+	Security    *[]map[string][]string `yaml:"security"` // Empty disables authentication
+	Parameters  []Parameter            `yaml:"parameters"`
+	RequestBody *RequestBody           `yaml:"requestBody"`
+	Responses   map[string]*Response   `yaml:"responses"`
+}
+
+// Parameter is a query or path parameter
+type Parameter struct {
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Schema      *Schema `yaml:"schema"`
+}
+
+// RequestBody is an operation's request body
+type RequestBody struct {
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+// Response is an operation's response, or a reference to a shared one
+type Response struct {
+	Ref         string               `yaml:"$ref"`
+	Description string               `yaml:"description"`
+	Content     map[string]MediaType `yaml:"content"`
+}
+
+// MediaType is the schema of a body
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema describes a value, or references a component schema
+type Schema struct {
+	Ref                  string     `yaml:"$ref"`
+	Type                 string     `yaml:"type"`
+	Format               string     `yaml:"format"`
+	Description          string     `yaml:"description"`
+	Enum                 []string   `yaml:"enum"`
+	Required             []string   `yaml:"required"`
+	Properties           Properties `yaml:"properties"`
+	AdditionalProperties *Schema    `yaml:"additionalProperties"`
+}
+
+// Properties lists an object schema's properties in document order, so the
+// generated parameters follow the spec
+type Properties []Property
+
+// Property is a named property of an object schema
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// httpMethods are the path item keys that are operations
+var httpMethods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "patch": true, "head": true}
+
+// UnmarshalYAML keeps the document order of paths and their operations
+func (p *Paths) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: paths must be a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		item := PathItem{Path: node.Content[i].Value}
+		methods := node.Content[i+1]
+		for j := 0; j+1 < len(methods.Content); j += 2 {
+			method := methods.Content[j].Value
+			if !httpMethods[method] {
+				continue
+			}
+			op := &Operation{Method: strings.ToUpper(method), Path: item.Path}
+			if err := methods.Content[j+1].Decode(op); err != nil {
+				return err
+			}
+			item.Operations = append(item.Operations, op)
+		}
+		*p = append(*p, item)
+	}
+	return nil
+}
+
+// UnmarshalYAML keeps the document order of properties
+func (p *Properties) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: properties must be a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var schema Schema
+		if err := node.Content[i+1].Decode(&schema); err != nil {
+			return err
+		}
+		*p = append(*p, Property{Name: node.Content[i].Value, Schema: &schema})
+	}
+	return nil
+}
+
+// Load reads and checks a spec file
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes a spec and checks every operation can be generated
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, op := range spec.Operations() {
+		if op.OperationID == "" {
+			return nil, fmt.Errorf("%s %s: operationId is required", op.Method, op.Path)
+		}
+		if seen[op.OperationID] {
+			return nil, fmt.Errorf("%s %s: duplicate operationId %q", op.Method, op.Path, op.OperationID)
+		}
+		seen[op.OperationID] = true
+		if _, err := spec.RequestSchema(op); err != nil {
+			return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+		}
+		for _, p := range op.Parameters {
+			if p.In != "query" && p.In != "path" {
+				return nil, fmt.Errorf("%s: parameter %s is in %s; only query and path are supported", op.OperationID, p.Name, p.In)
+			}
+		}
+	}
+	return &spec, nil
+}
+
+// Operations returns every operation in document order
+func (s *Spec) Operations() []*Operation {
+	var ops []*Operation
+	for _, item := range s.Paths {
+		ops = append(ops, item.Operations...)
+	}
+	return ops
+}
+
+// RequestSchema returns the name of the component schema an operation's JSON
+// body references, or "" when it takes no body
+func (s *Spec) RequestSchema(op *Operation) (string, error) {
+	if op.RequestBody == nil {
+		return "", nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok || media.Schema == nil || media.Schema.Ref == "" {
+		return "", fmt.Errorf("request body must reference a component schema as application/json")
+	}
+	name := schemaName(media.Schema.Ref)
+	if s.Components.Schemas[name] == nil {
+		return "", fmt.Errorf("unknown schema %s", media.Schema.Ref)
+	}
+	return name, nil
+}
+
+// ResponseSchema returns the name of the component schema of an operation's
+// first 2xx JSON response, or "" when it has none
+func (s *Spec) ResponseSchema(op *Operation) string {
+	for _, code := range []string{"200", "201", "202"} {
+		resp, ok := op.Responses[code]
+		if !ok {
+			continue
+		}
+		if resp.Ref != "" {
+			resp = s.Components.Responses[schemaName(resp.Ref)]
+		}
+		if resp != nil {
+			if media, ok := resp.Content["application/json"]; ok && media.Schema != nil {
+				return schemaName(media.Schema.Ref)
+			}
+		}
+	}
+	return ""
+}
+
+// Authenticated reports whether the operation sends the API key
+func (op *Operation) Authenticated() bool {
+	return op.Security == nil || len(*op.Security) > 0
+}
+
+// schemaName is the last element of a #/components/... reference
+func schemaName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
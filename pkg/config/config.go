@@ -0,0 +1,1418 @@
+// Package config loads and validates signer-service configuration.
+//
+// Programs embedding the service can either call LoadConfig to read the same
+// environment variables as the standalone binary, LoadConfigFile to also read
+// a config file, Load to add command-line flags defined with RegisterFlags, or
+// build a Config literal and call Validate.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cronwindow"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/i18n"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// DefaultMiddlewareChain is the order in which built-in middleware wraps the
+// router, outermost first
+const DefaultMiddlewareChain = "i18n,recovery,realip,tracing,logging,metrics,gzip,cors,ratelimit,concurrency,auth,oidc,hmac"
+
+// knownMiddleware lists the names accepted in MIDDLEWARE_CHAIN
+var knownMiddleware = map[string]bool{
+	"i18n":        true,
+	"recovery":    true,
+	"realip":      true,
+	"tracing":     true,
+	"logging":     true,
+	"metrics":     true,
+	"gzip":        true,
+	"cors":        true,
+	"ratelimit":   true,
+	"concurrency": true,
+	"auth":        true,
+	"oidc":        true,
+	"hmac":        true,
+}
+
+// knownOperations lists the names accepted in ALLOWED_OPERATIONS
+var knownOperations = map[string]bool{
+	"upload":   true,
+	"download": true,
+	"delete":   true,
+}
+
+// roleARNPattern matches an IAM role ARN
+var roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]{1,512}$`)
+
+// roleSessionNamePattern matches an STS role session name
+var roleSessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+
+// mrapARNPattern matches Multi-Region Access Point ARNs, which have no region
+var mrapARNPattern = regexp.MustCompile(`^arn:[a-z-]+:s3::\d{12}:accesspoint/[a-z0-9]+\.mrap$`)
+
+// regionPattern matches AWS region names such as us-east-1 or us-gov-west-1
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// environmentPattern matches ENVIRONMENT names, a single key segment
+var environmentPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// prefixPattern matches key prefixes, with the same rules as tenant prefixes
+var prefixPattern = regexp.MustCompile(`^[A-Za-z0-9!_.*'()-]+(/[A-Za-z0-9!_.*'()-]+)*$`)
+
+// accessPointARNPattern matches S3 and S3 Object Lambda access point ARNs
+var accessPointARNPattern = regexp.MustCompile(`^arn:[a-z-]+:(s3|s3-object-lambda):[a-z0-9-]+:\d{12}:accesspoint/[a-z0-9-]+$`)
+
+// Config holds all configuration for the application
+type Config struct {
+	AWSRegion                     string
+	AWSAccessKeyID                string
+	AWSSecretAccessKey            string
+	AWSSessionToken               string // Set for temporary (STS) credentials
+	AWSRoleARN                    string // IAM role assumed through STS, e.g. for another account's bucket
+	AWSRoleExternalID             string
+	AWSRoleSessionName            string
+	AWSRoleDurationMinutes        int    // Role session duration, refreshed before URLs could outlive it
+	S3BucketName                  string // Bucket name or (Object Lambda) access point ARN
+	S3MRAPARN                     string // Multi-Region Access Point used instead of S3BucketName
+	S3EndpointURL                 string // S3-compatible endpoint (localstack, MinIO) addressed path-style
+	DRBucketName                  string // Replication destination used for download fallbacks
+	DRRegion                      string
+	FailoverEnabled               bool // Route requests to the DR bucket while the primary fails
+	FailoverCheckIntervalSeconds  int
+	FailoverThreshold             int // Consecutive health checks needed to switch buckets
+	CompanyPrefix                 string
+	Environment                   string // Key segment after the company or tenant prefix (e.g. prod), empty for none
+	PresignedURLExpirationMinutes int    // Default for the upload and download expirations
+	UploadURLExpirationMinutes    int    // Upload, multipart part and delete URLs
+	DownloadURLExpirationMinutes  int
+	Port                          string
+
+	// Download tokens: links redirecting to a short-lived download URL,
+	// redeemable a limited number of times
+	DownloadTokenMaxRedemptions  int // Most redemptions a client may request; tokens default to one
+	DownloadTokenTTLMinutes      int
+	DownloadTokenRedirectSeconds int // Lifetime of the URL a redemption redirects to
+
+	// Timeouts
+	HTTPReadTimeoutSeconds    int
+	HTTPWriteTimeoutSeconds   int
+	HTTPIdleTimeoutSeconds    int
+	S3OperationTimeoutSeconds int
+
+	// TLS listener (HTTP/2 is negotiated automatically) and h2c for the
+	// plaintext listener
+	TLSCertFile string
+	TLSKeyFile  string
+	HTTPH2C     bool
+
+	// Internal listener for /health, /ready, /metrics and the admin API
+	// (empty AdminPort serves them on Port), with its own timeouts and TLS
+	AdminPort                    string
+	AdminHost                    string
+	AdminTLSCertFile             string
+	AdminTLSKeyFile              string
+	AdminHTTPReadTimeoutSeconds  int
+	AdminHTTPWriteTimeoutSeconds int
+	AdminHTTPIdleTimeoutSeconds  int
+
+	// Signature debugging: off, header (honor X-Signer-Debug) or all (log
+	// every signature and honor the header)
+	SignerDebug string
+
+	// Minimum log level: debug, info, warn or error. Adjustable at runtime
+	// through the admin API or SIGUSR1.
+	LogLevel string
+
+	// Startup bucket validation: off, warn (log and continue) or fail (exit)
+	PreflightCheck string
+
+	// Handling of filenames whose upload key would exceed S3's 1024-byte
+	// limit: reject, truncate or hash
+	LongFilenameStrategy string
+
+	// Regular expression a client-suggested upload subpath must match as a
+	// whole (empty refuses subpaths)
+	UploadSubpathPattern string
+
+	// Operations the v2 API may presign (upload, download, delete)
+	AllowedOperations []string
+
+	// Signed header policy for upload URLs: headers every upload must sign,
+	// and (when non-empty) the only headers uploads may sign, as lowercase
+	// names or prefix patterns like x-amz-meta-*
+	RequiredSignedHeaders []string
+	AllowedSignedHeaders  []string
+
+	// Canned ACL (x-amz-acl) and account ID (x-amz-expected-bucket-owner)
+	// signed into every upload and sent on the service's own writes, for
+	// cross-account bucket policies that require them. Empty omits them.
+	UploadACL           string
+	ExpectedBucketOwner string
+
+	// Metadata signed into every upload as key=value entries. Values may use
+	// the {tenant}, {principal}, {request_id} and {client_ip} placeholders.
+	InjectedMetadata []string
+
+	// Middleware configuration
+	MiddlewareChain          []string
+	TrustedProxies           []string // IPs or CIDRs whose forwarding headers are honored
+	RealIPHeader             string   // Header the trusted proxies write: X-Forwarded-For or Forwarded
+	APIKeys                  []string
+	CORSAllowedOrigins       []string
+	RateLimitRPS             float64
+	RateLimitBurst           int
+	MaxConcurrentRequests    int // Requests served at once before shedding with 503 (0 disables)
+	GzipEnabled              bool
+	GzipMinBytes             int      // Smaller responses are sent uncompressed
+	HMACSecrets              []string // key-id:secret[:scopes]; a key ID naming a tenant scopes its requests to it
+	HMACChallengeTTLSeconds  int
+	HMACMaxOutstandingNonces int // Challenges issued and not yet used or expired
+
+	// Language of error messages when Accept-Language names no supported one
+	DefaultLanguage string
+
+	// OIDC access token validation (empty discovery URL disables it)
+	OIDCDiscoveryURL   string
+	OIDCAudience       string
+	OIDCRequiredScopes []string
+	OIDCScopePrefix    string
+
+	// Authorization policy file (empty disables policy checks)
+	PolicyFile string
+
+	// Metadata schema file (empty accepts any valid metadata)
+	MetadataSchemaFile string
+
+	// Key required in X-Admin-Key by the /admin/v1 endpoints (empty disables
+	// them)
+	AdminAPIKey string
+
+	// Serve the embedded operator web UI at /ui
+	WebUI bool
+
+	// Tenant store: off (COMPANY_PREFIX only), memory or file (persisted in
+	// TenantStoreFile)
+	TenantStore     string
+	TenantStoreFile string
+
+	// Managed API keys: off, memory or file (persisted in APIKeyStoreFile)
+	APIKeyStore     string
+	APIKeyStoreFile string
+
+	// Catalog of confirmed objects: off, memory or postgres (at
+	// CatalogDatabaseURL)
+	CatalogStore       string
+	CatalogDatabaseURL string
+	CatalogDeduplicate bool // Answer uploads whose checksum matches a confirmed object with it
+
+	// URL issuance audit events forwarded to a SIEM: off, http, syslog or
+	// cloudwatch, each with its own destination settings
+	AuditSink                 string
+	AuditHTTPURL              string
+	AuditHTTPAuthorization    string
+	AuditSyslogAddress        string
+	AuditCloudWatchLogGroup   string
+	AuditCloudWatchLogStream  string
+	AuditBatchSize            int
+	AuditFlushIntervalSeconds int
+
+	// Encryption of presigned URLs in responses: off, jwe (for the RSA key in
+	// URLEncryptionPublicKeyFile) or kms (with URLEncryptionKMSKeyID)
+	URLEncryption              string
+	URLEncryptionPublicKeyFile string
+	URLEncryptionKMSKeyID      string
+
+	// KMS key data keys for client-side encryption are generated under;
+	// empty disables /api/v1/data-keys
+	ClientEncryptionKMSKeyID string
+
+	// Metrics backend: prometheus (served on /metrics), statsd or dogstatsd
+	// (pushed to StatsdAddress)
+	MetricsSink   string
+	StatsdAddress string
+	StatsdPrefix  string
+	StatsdTags    []string
+
+	// CloudWatch Logs export, enabled by CloudWatchLogGroup: structured logs
+	// and EMF metrics every CloudWatchMetricsIntervalSeconds (0 disables)
+	CloudWatchLogGroup               string
+	CloudWatchLogStream              string
+	CloudWatchShipLogs               bool
+	CloudWatchMetricsNamespace       string
+	CloudWatchMetricsIntervalSeconds int
+
+	// Tenants declared in the config file, seeded into the tenant store
+	Tenants []TenantConfig
+
+	// Minimum age in hours before an object may be deleted (0 disables);
+	// tenants may set their own
+	MinRetentionHours int
+
+	// Soft delete: deletes move objects to the trash, purged after
+	// TrashRetentionDays (0 keeps them)
+	SoftDelete         bool
+	TrashRetentionDays int
+
+	// Read-only mode at startup: uploads, deletes and other writes answer
+	// 503 MAINTENANCE; the admin API toggles it at runtime
+	ReadOnly bool
+
+	// Scheduled maintenance: cron-like windows (see cronwindow) separated by
+	// semicolons, in the MaintenanceTimezone IANA zone (UTC when empty),
+	// during which writes are refused like in read-only mode
+	MaintenanceWindows  []string
+	MaintenanceTimezone string
+
+	// Background jobs (interval 0 disables the job)
+	MultipartCleanupIntervalMinutes int
+	MultipartCleanupMaxAgeHours     int
+	PrefixUsageIntervalMinutes      int
+	TrashPurgeIntervalMinutes       int
+	ClockDriftCheckIntervalSeconds  int
+
+	// Stale backup alerts: uploads expected under the company prefix as
+	// filename=hours entries (see ParseExpectedBackup), checked with tenant
+	// expectations every StaleBackupCheckIntervalMinutes and notified to
+	// StaleBackupWebhookURL
+	ExpectedBackups                 []string
+	StaleBackupCheckIntervalMinutes int
+	StaleBackupWebhookURL           string
+	StaleBackupWebhookSecret        string
+
+	// Restore drills: download a random upload modified within
+	// RestoreDrillMaxAgeHours and at most RestoreDrillMaxBytes, on demand or
+	// every RestoreDrillIntervalMinutes, keeping the last RestoreDrillHistory
+	RestoreDrillIntervalMinutes int
+	RestoreDrillMaxAgeHours     int
+	RestoreDrillMaxBytes        int
+	RestoreDrillHistory         int
+
+	// S3 server access logs delivered under AccessLogPrefix (empty disables)
+	// of AccessLogBucket (S3_BUCKET_NAME when empty), ingested every
+	// AccessLogIntervalMinutes to report how issued URLs were used. Usage and
+	// the issued URLs it is matched to are kept AccessLogRetentionHours.
+	AccessLogBucket          string
+	AccessLogPrefix          string
+	AccessLogIntervalMinutes int
+	AccessLogRetentionHours  int
+
+	// Issuance anomaly detection: an hour with more than AnomalySpikeFactor
+	// times a caller's usual URLs (and at least AnomalySpikeMinURLs), URLs
+	// requested from a caller's new IP, and delete URLs outside
+	// AnomalyBusinessHours (cron windows in AnomalyTimezone; empty disables
+	// the check), notified to AnomalyWebhookURL
+	AnomalyDetection     bool
+	AnomalySpikeFactor   int
+	AnomalySpikeMinURLs  int
+	AnomalyBusinessHours []string
+	AnomalyTimezone      string
+	AnomalyWebhookURL    string
+	AnomalyWebhookSecret string
+
+	// Clock drift against S3 past which readiness reports warn
+	ClockDriftThresholdSeconds int
+
+	// Seconds searches and browse listings are cached (0 disables)
+	ListCacheTTLSeconds int
+
+	// S3 call resilience
+	S3RetryMaxAttempts        int
+	S3RetryBaseDelayMS        int
+	S3RetryMaxDelayMS         int
+	S3BreakerFailureThreshold int
+	S3BreakerCooldownSeconds  int
+
+	// Attempts to initialize the AWS client at startup, and whether to serve
+	// degraded (not ready) while retrying it in the background afterwards
+	AWSInitMaxAttempts   int
+	AWSInitDegradedStart bool
+
+	// S3 calls allowed per minute (0 unlimited) and whether calls over it
+	// wait for the next minute ("queue") or fail ("reject")
+	S3CallBudgetPerMinute      int
+	S3CallBudgetMode           string
+	S3CallBudgetMaxWaitSeconds int
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() (*Config, error) {
+	return LoadConfigFile("")
+}
+
+// LoadConfigFile loads configuration from a YAML, TOML or JSON file using the
+// environment variable names in lowercase as keys. Environment variables
+// override file values. An empty path reads the environment only.
+func LoadConfigFile(path string) (*Config, error) {
+	return Load(path, nil)
+}
+
+// Load loads configuration with the precedence flags > environment > config
+// file > defaults. flags holds values keyed by environment variable name, as
+// returned by Flags.Values. Values that don't parse and those Validate
+// rejects are reported together as a *ValidationError.
+func Load(path string, flags map[string]string) (*Config, error) {
+	// Load .env file if it exists (ignore error if file doesn't exist)
+	_ = godotenv.Load()
+
+	l := &loader{flags: flags, read: make(map[string]bool)}
+	if path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.file = file
+	}
+
+	config := &Config{
+		AWSRegion:                  l.getEnv("AWS_REGION", "us-east-1"),
+		AWSAccessKeyID:             l.getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey:         l.getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:            l.getEnv("AWS_SESSION_TOKEN", ""),
+		AWSRoleARN:                 l.getEnv("AWS_ROLE_ARN", ""),
+		AWSRoleExternalID:          l.getEnv("AWS_ROLE_EXTERNAL_ID", ""),
+		AWSRoleSessionName:         l.getEnv("AWS_ROLE_SESSION_NAME", "signer-service"),
+		S3BucketName:               l.getEnv("S3_BUCKET_NAME", ""),
+		S3MRAPARN:                  l.getEnv("S3_MRAP_ARN", ""),
+		S3EndpointURL:              l.getEnv("S3_ENDPOINT_URL", ""),
+		DRBucketName:               l.getEnv("DR_BUCKET_NAME", ""),
+		DRRegion:                   l.getEnv("DR_REGION", ""),
+		CompanyPrefix:              l.getEnv("COMPANY_PREFIX", ""),
+		Environment:                l.getEnv("ENVIRONMENT", ""),
+		Port:                       l.getEnv("PORT", "8080"),
+		AllowedOperations:          l.getEnvList("ALLOWED_OPERATIONS", "upload,download"),
+		RequiredSignedHeaders:      l.getEnvList("REQUIRED_SIGNED_HEADERS", ""),
+		AllowedSignedHeaders:       l.getEnvList("ALLOWED_SIGNED_HEADERS", ""),
+		UploadACL:                  l.getEnv("UPLOAD_ACL", ""),
+		ExpectedBucketOwner:        l.getEnv("S3_EXPECTED_BUCKET_OWNER", ""),
+		InjectedMetadata:           l.getEnvList("INJECTED_METADATA", ""),
+		MiddlewareChain:            l.getEnvList("MIDDLEWARE_CHAIN", DefaultMiddlewareChain),
+		DefaultLanguage:            l.getEnv("DEFAULT_LANGUAGE", i18n.English),
+		TrustedProxies:             l.getEnvList("TRUSTED_PROXIES", ""),
+		RealIPHeader:               l.getEnv("REAL_IP_HEADER", "X-Forwarded-For"),
+		APIKeys:                    l.getEnvList("API_KEYS", ""),
+		CORSAllowedOrigins:         l.getEnvList("CORS_ALLOWED_ORIGINS", ""),
+		HMACSecrets:                l.getEnvList("HMAC_SECRETS", ""),
+		OIDCDiscoveryURL:           l.getEnv("OIDC_DISCOVERY_URL", ""),
+		OIDCAudience:               l.getEnv("OIDC_AUDIENCE", ""),
+		OIDCRequiredScopes:         l.getEnvList("OIDC_REQUIRED_SCOPES", ""),
+		OIDCScopePrefix:            l.getEnv("OIDC_SCOPE_PREFIX", "signer:"),
+		PolicyFile:                 l.getEnv("POLICY_FILE", ""),
+		MetadataSchemaFile:         l.getEnv("METADATA_SCHEMA_FILE", ""),
+		AdminAPIKey:                l.getEnv("ADMIN_API_KEY", ""),
+		TenantStore:                l.getEnv("TENANT_STORE", "off"),
+		TenantStoreFile:            l.getEnv("TENANT_STORE_FILE", ""),
+		APIKeyStore:                l.getEnv("API_KEY_STORE", "off"),
+		APIKeyStoreFile:            l.getEnv("API_KEY_STORE_FILE", ""),
+		CatalogStore:               l.getEnv("CATALOG_STORE", "off"),
+		CatalogDatabaseURL:         l.getEnv("CATALOG_DATABASE_URL", ""),
+		AuditSink:                  l.getEnv("AUDIT_SINK", "off"),
+		AuditHTTPURL:               l.getEnv("AUDIT_HTTP_URL", ""),
+		AuditHTTPAuthorization:     l.getEnv("AUDIT_HTTP_AUTHORIZATION", ""),
+		AuditSyslogAddress:         l.getEnv("AUDIT_SYSLOG_ADDRESS", ""),
+		AuditCloudWatchLogGroup:    l.getEnv("AUDIT_CLOUDWATCH_LOG_GROUP", ""),
+		AuditCloudWatchLogStream:   l.getEnv("AUDIT_CLOUDWATCH_LOG_STREAM", "signer-service"),
+		URLEncryption:              l.getEnv("URL_ENCRYPTION", "off"),
+		URLEncryptionPublicKeyFile: l.getEnv("URL_ENCRYPTION_PUBLIC_KEY_FILE", ""),
+		URLEncryptionKMSKeyID:      l.getEnv("URL_ENCRYPTION_KMS_KEY_ID", ""),
+		MetricsSink:                l.getEnv("METRICS_SINK", "prometheus"),
+		StatsdAddress:              l.getEnv("STATSD_ADDRESS", "127.0.0.1:8125"),
+		StatsdPrefix:               l.getEnv("STATSD_PREFIX", "signer_service"),
+		StatsdTags:                 l.getEnvList("STATSD_TAGS", ""),
+		CloudWatchLogGroup:         l.getEnv("CLOUDWATCH_LOG_GROUP", ""),
+		CloudWatchLogStream:        l.getEnv("CLOUDWATCH_LOG_STREAM", "signer-service"),
+		CloudWatchMetricsNamespace: l.getEnv("CLOUDWATCH_METRICS_NAMESPACE", "SignerService"),
+		PreflightCheck:             l.getEnv("PREFLIGHT_CHECK", "off"),
+		SignerDebug:                l.getEnv("SIGNER_DEBUG", "off"),
+		LogLevel:                   l.getEnv("LOG_LEVEL", "info"),
+		LongFilenameStrategy:       l.getEnv("LONG_FILENAME_STRATEGY", "reject"),
+		UploadSubpathPattern:       l.getEnv("UPLOAD_SUBPATH_PATTERN", ""),
+		TLSCertFile:                l.getEnv("TLS_CERT_FILE", ""),
+		AdminPort:                  l.getEnv("ADMIN_PORT", ""),
+		AdminHost:                  l.getEnv("ADMIN_HOST", "0.0.0.0"),
+		AdminTLSCertFile:           l.getEnv("ADMIN_TLS_CERT_FILE", ""),
+		AdminTLSKeyFile:            l.getEnv("ADMIN_TLS_KEY_FILE", ""),
+		TLSKeyFile:                 l.getEnv("TLS_KEY_FILE", ""),
+	}
+
+	// Parse presigned URL expiration
+	expiration := l.getEnvInt("PRESIGNED_URL_EXPIRATION_MINUTES", 3)
+	config.PresignedURLExpirationMinutes = expiration
+	config.UploadURLExpirationMinutes = l.getEnvInt("UPLOAD_URL_EXPIRATION_MINUTES", expiration)
+	config.DownloadURLExpirationMinutes = l.getEnvInt("DOWNLOAD_URL_EXPIRATION_MINUTES", expiration)
+	config.AWSRoleDurationMinutes = l.getEnvInt("AWS_ROLE_DURATION_MINUTES", 60)
+	config.DownloadTokenMaxRedemptions = l.getEnvInt("DOWNLOAD_TOKEN_MAX_REDEMPTIONS", 1)
+	config.DownloadTokenTTLMinutes = l.getEnvInt("DOWNLOAD_TOKEN_TTL_MINUTES", 60)
+	config.DownloadTokenRedirectSeconds = l.getEnvInt("DOWNLOAD_TOKEN_REDIRECT_SECONDS", 60)
+
+	// Parse HTTP server and S3 operation timeouts
+	config.HTTPReadTimeoutSeconds = l.getEnvInt("HTTP_READ_TIMEOUT_SECONDS", 15)
+	config.HTTPWriteTimeoutSeconds = l.getEnvInt("HTTP_WRITE_TIMEOUT_SECONDS", 15)
+	config.HTTPIdleTimeoutSeconds = l.getEnvInt("HTTP_IDLE_TIMEOUT_SECONDS", 60)
+	config.S3OperationTimeoutSeconds = l.getEnvInt("S3_OPERATION_TIMEOUT_SECONDS", 10)
+	config.AdminHTTPReadTimeoutSeconds = l.getEnvInt("ADMIN_HTTP_READ_TIMEOUT_SECONDS", 5)
+	config.AdminHTTPWriteTimeoutSeconds = l.getEnvInt("ADMIN_HTTP_WRITE_TIMEOUT_SECONDS", 30)
+	config.AdminHTTPIdleTimeoutSeconds = l.getEnvInt("ADMIN_HTTP_IDLE_TIMEOUT_SECONDS", 60)
+	config.ListCacheTTLSeconds = l.getEnvInt("LIST_CACHE_TTL_SECONDS", 0)
+
+	config.HTTPH2C = l.getEnvBool("HTTP_H2C", false)
+
+	// Parse rate limiting (0 disables the limiter)
+	config.RateLimitRPS = l.getEnvFloat("RATE_LIMIT_RPS", 0)
+	config.RateLimitBurst = l.getEnvInt("RATE_LIMIT_BURST", 10)
+
+	config.MaxConcurrentRequests = l.getEnvInt("MAX_CONCURRENT_REQUESTS", 0)
+	config.GzipEnabled = l.getEnvBool("GZIP_ENABLED", false)
+	config.GzipMinBytes = l.getEnvInt("GZIP_MIN_BYTES", 1024)
+
+	if l.getEnv("HMAC_SECRET", "") != "" {
+		l.fail(fmt.Errorf("%s was replaced by HMAC_SECRETS with key-id:secret entries", l.name("HMAC_SECRET")))
+	}
+	config.HMACChallengeTTLSeconds = l.getEnvInt("HMAC_CHALLENGE_TTL_SECONDS", 300)
+	config.HMACMaxOutstandingNonces = l.getEnvInt("HMAC_MAX_OUTSTANDING_NONCES", 10000)
+	config.CatalogDeduplicate = l.getEnvBool("CATALOG_DEDUPLICATE", false)
+
+	config.MinRetentionHours = l.getEnvInt("MIN_RETENTION_HOURS", 0)
+	config.SoftDelete = l.getEnvBool("SOFT_DELETE", false)
+	config.TrashRetentionDays = l.getEnvInt("TRASH_RETENTION_DAYS", 30)
+	config.ReadOnly = l.getEnvBool("READ_ONLY", false)
+	// Cron fields contain commas, so windows are separated by semicolons
+	config.MaintenanceWindows = splitWindows(l.getEnv("MAINTENANCE_WINDOWS", ""))
+	config.MaintenanceTimezone = l.getEnv("MAINTENANCE_TIMEZONE", "")
+
+	config.ClientEncryptionKMSKeyID = l.getEnv("CLIENT_ENCRYPTION_KMS_KEY_ID", "")
+
+	config.MultipartCleanupIntervalMinutes = l.getEnvInt("MULTIPART_CLEANUP_INTERVAL_MINUTES", 0)
+	config.MultipartCleanupMaxAgeHours = l.getEnvInt("MULTIPART_CLEANUP_MAX_AGE_HOURS", 24)
+	config.PrefixUsageIntervalMinutes = l.getEnvInt("PREFIX_USAGE_INTERVAL_MINUTES", 0)
+	config.TrashPurgeIntervalMinutes = l.getEnvInt("TRASH_PURGE_INTERVAL_MINUTES", 60)
+	config.ClockDriftCheckIntervalSeconds = l.getEnvInt("CLOCK_DRIFT_CHECK_INTERVAL_SECONDS", 300)
+	config.ClockDriftThresholdSeconds = l.getEnvInt("CLOCK_DRIFT_THRESHOLD_SECONDS", 30)
+	config.ExpectedBackups = l.getEnvList("EXPECTED_BACKUPS", "")
+	config.StaleBackupCheckIntervalMinutes = l.getEnvInt("STALE_BACKUP_CHECK_INTERVAL_MINUTES", 15)
+	config.StaleBackupWebhookURL = l.getEnv("STALE_BACKUP_WEBHOOK_URL", "")
+	config.StaleBackupWebhookSecret = l.getEnv("STALE_BACKUP_WEBHOOK_SECRET", "")
+	config.RestoreDrillIntervalMinutes = l.getEnvInt("RESTORE_DRILL_INTERVAL_MINUTES", 0)
+	config.RestoreDrillMaxAgeHours = l.getEnvInt("RESTORE_DRILL_MAX_AGE_HOURS", 168)
+	config.RestoreDrillMaxBytes = l.getEnvInt("RESTORE_DRILL_MAX_BYTES", 1<<30)
+	config.RestoreDrillHistory = l.getEnvInt("RESTORE_DRILL_HISTORY", 500)
+	config.AccessLogBucket = l.getEnv("ACCESS_LOG_BUCKET", "")
+	config.AccessLogPrefix = l.getEnv("ACCESS_LOG_PREFIX", "")
+	config.AccessLogIntervalMinutes = l.getEnvInt("ACCESS_LOG_INTERVAL_MINUTES", 15)
+	config.AccessLogRetentionHours = l.getEnvInt("ACCESS_LOG_RETENTION_HOURS", 72)
+	config.WebUI = l.getEnvBool("WEB_UI", false)
+	config.AnomalyDetection = l.getEnvBool("ANOMALY_DETECTION", false)
+	config.AnomalySpikeFactor = l.getEnvInt("ANOMALY_SPIKE_FACTOR", 10)
+	config.AnomalySpikeMinURLs = l.getEnvInt("ANOMALY_SPIKE_MIN_URLS", 50)
+	config.AnomalyBusinessHours = splitWindows(l.getEnv("ANOMALY_BUSINESS_HOURS", ""))
+	config.AnomalyTimezone = l.getEnv("ANOMALY_TIMEZONE", "")
+	config.AnomalyWebhookURL = l.getEnv("ANOMALY_WEBHOOK_URL", "")
+	config.AnomalyWebhookSecret = l.getEnv("ANOMALY_WEBHOOK_SECRET", "")
+	config.AuditBatchSize = l.getEnvInt("AUDIT_BATCH_SIZE", 100)
+	config.AuditFlushIntervalSeconds = l.getEnvInt("AUDIT_FLUSH_INTERVAL_SECONDS", 5)
+	config.CloudWatchShipLogs = l.getEnvBool("CLOUDWATCH_SHIP_LOGS", true)
+	config.CloudWatchMetricsIntervalSeconds = l.getEnvInt("CLOUDWATCH_METRICS_INTERVAL_SECONDS", 60)
+
+	config.FailoverEnabled = l.getEnvBool("FAILOVER_ENABLED", false)
+	config.FailoverCheckIntervalSeconds = l.getEnvInt("FAILOVER_CHECK_INTERVAL_SECONDS", 30)
+	config.FailoverThreshold = l.getEnvInt("FAILOVER_THRESHOLD", 3)
+
+	// Parse AWS initialization, S3 retry and circuit breaker settings
+	config.AWSInitMaxAttempts = l.getEnvInt("AWS_INIT_MAX_ATTEMPTS", 5)
+	config.AWSInitDegradedStart = l.getEnvBool("AWS_INIT_DEGRADED_START", false)
+	config.S3RetryMaxAttempts = l.getEnvInt("S3_RETRY_MAX_ATTEMPTS", 3)
+	config.S3RetryBaseDelayMS = l.getEnvInt("S3_RETRY_BASE_DELAY_MS", 100)
+	config.S3RetryMaxDelayMS = l.getEnvInt("S3_RETRY_MAX_DELAY_MS", 2000)
+	config.S3BreakerFailureThreshold = l.getEnvInt("S3_BREAKER_FAILURE_THRESHOLD", 5)
+	config.S3BreakerCooldownSeconds = l.getEnvInt("S3_BREAKER_COOLDOWN_SECONDS", 30)
+
+	// Parse S3 call budget settings
+	config.S3CallBudgetPerMinute = l.getEnvInt("S3_CALL_BUDGET_PER_MINUTE", 0)
+	config.S3CallBudgetMode = l.getEnv("S3_CALL_BUDGET_MODE", "queue")
+	config.S3CallBudgetMaxWaitSeconds = l.getEnvInt("S3_CALL_BUDGET_MAX_WAIT_SECONDS", 5)
+
+	if err := l.file.checkKnown(l.read); err != nil {
+		l.fail(err)
+	}
+	if l.file != nil {
+		config.Tenants = l.file.tenants
+	}
+
+	// Report values that didn't parse along with everything Validate finds,
+	// so operators fix them in one go
+	problems := l.problems
+	var invalid *ValidationError
+	if err := config.Validate(); errors.As(err, &invalid) {
+		problems = append(problems, invalid.Problems...)
+	} else if err != nil {
+		problems = append(problems, err)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	for _, warning := range config.Warnings() {
+		logging.Warnf("%s", warning)
+	}
+
+	return config, nil
+}
+
+// maxURLExpiration is the longest lifetime SigV4 allows a presigned URL
+const maxURLExpiration = 7 * 24 * time.Hour
+
+// maxTemporaryURLExpiration caps URLs signed with temporary credentials,
+// which stop working when the credentials expire: role sessions last at most
+// 12 hours
+const maxTemporaryURLExpiration = 12 * time.Hour
+
+// roleRefreshMargin is the least time an assumed role session must outlast
+// the longest URL, so its credentials aren't refreshed constantly
+const roleRefreshMargin = 15 * time.Minute
+
+// TemporaryCredentials reports whether URLs are signed with temporary STS
+// credentials, identified by a session token, an ASIA access key ID or a
+// role to assume
+func (c *Config) TemporaryCredentials() bool {
+	return c.AWSSessionToken != "" || strings.HasPrefix(c.AWSAccessKeyID, "ASIA") || c.AWSRoleARN != ""
+}
+
+// RoleURLExpiration returns the longest lifetime of URLs signed with an
+// assumed role: AWSRoleDurationMinutes less the refresh margin
+func (c *Config) RoleURLExpiration() time.Duration {
+	return time.Duration(c.AWSRoleDurationMinutes)*time.Minute - roleRefreshMargin
+}
+
+// MaintenanceSchedule returns the maintenance windows, nil when none are
+// configured
+func (c *Config) MaintenanceSchedule() (*cronwindow.Schedule, error) {
+	if len(c.MaintenanceWindows) == 0 {
+		return nil, nil
+	}
+	return cronwindow.NewSchedule(c.MaintenanceWindows, c.MaintenanceTimezone)
+}
+
+// AnomalyBusinessSchedule returns the business hours delete URLs are expected
+// in, nil when none are configured
+func (c *Config) AnomalyBusinessSchedule() (*cronwindow.Schedule, error) {
+	if len(c.AnomalyBusinessHours) == 0 {
+		return nil, nil
+	}
+	return cronwindow.NewSchedule(c.AnomalyBusinessHours, c.AnomalyTimezone)
+}
+
+// MaxURLExpiration returns the longest lifetime presigned URLs can have with
+// the configured credentials
+func (c *Config) MaxURLExpiration() time.Duration {
+	if c.TemporaryCredentials() {
+		return maxTemporaryURLExpiration
+	}
+	return maxURLExpiration
+}
+
+// UploadURLExpiration returns the lifetime of upload, multipart part and
+// delete URLs, falling back to PresignedURLExpirationMinutes when unset and
+// clamped to MaxURLExpiration
+func (c *Config) UploadURLExpiration() time.Duration {
+	return min(expirationMinutes(c.UploadURLExpirationMinutes, c.PresignedURLExpirationMinutes), c.MaxURLExpiration())
+}
+
+// DownloadURLExpiration returns the lifetime of download URLs, falling back
+// to PresignedURLExpirationMinutes when unset and clamped to
+// MaxURLExpiration
+func (c *Config) DownloadURLExpiration() time.Duration {
+	return min(expirationMinutes(c.DownloadURLExpirationMinutes, c.PresignedURLExpirationMinutes), c.MaxURLExpiration())
+}
+
+// expirationMinutes converts minutes, or fallback when minutes is zero, to a
+// duration
+func expirationMinutes(minutes, fallback int) time.Duration {
+	if minutes == 0 {
+		minutes = fallback
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Validate checks that required fields are set and values are in range. It
+// is called by LoadConfig and should be called by programs that build a
+// Config directly. It reports every problem found at once as a
+// *ValidationError.
+func (c *Config) Validate() error {
+	var problems []error
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.AWSAccessKeyID == "" {
+		fail("AWS_ACCESS_KEY_ID is required")
+	}
+	if c.AWSSecretAccessKey == "" {
+		fail("AWS_SECRET_ACCESS_KEY is required")
+	}
+	if c.S3BucketName == "" && c.S3MRAPARN == "" {
+		fail("S3_BUCKET_NAME or S3_MRAP_ARN is required")
+	}
+	if strings.HasPrefix(c.S3BucketName, "arn:") && !accessPointARNPattern.MatchString(c.S3BucketName) {
+		fail("S3_BUCKET_NAME must be a bucket name or an access point ARN like arn:aws:s3:<region>:<account-id>:accesspoint/<name> (got %q)", c.S3BucketName)
+	}
+	if c.S3MRAPARN != "" && !mrapARNPattern.MatchString(c.S3MRAPARN) {
+		fail("S3_MRAP_ARN must look like arn:aws:s3::<account-id>:accesspoint/<alias>.mrap (got %q)", c.S3MRAPARN)
+	}
+	if err := c.validateEndpointURL(); err != nil {
+		problems = append(problems, err)
+	}
+	if c.DRBucketName != "" && (c.DRRegion == "" || strings.HasPrefix(c.DRBucketName, "arn:")) {
+		fail("DR_BUCKET_NAME must be a bucket name and requires DR_REGION")
+	}
+	if c.FailoverEnabled {
+		if c.DRBucketName == "" {
+			fail("FAILOVER_ENABLED requires DR_BUCKET_NAME and DR_REGION")
+		}
+		if c.S3MRAPARN != "" {
+			fail("FAILOVER_ENABLED cannot be combined with S3_MRAP_ARN, which fails over by itself")
+		}
+		if c.FailoverCheckIntervalSeconds < 1 || c.FailoverThreshold < 1 {
+			fail("FAILOVER_CHECK_INTERVAL_SECONDS and FAILOVER_THRESHOLD must be at least 1")
+		}
+	}
+	if strings.HasPrefix(c.AWSAccessKeyID, "ASIA") && c.AWSSessionToken == "" {
+		fail("AWS_ACCESS_KEY_ID is a temporary (ASIA) key and requires AWS_SESSION_TOKEN")
+	}
+	// Tenants may assume roles too, so the session settings are always
+	// checked
+	if !roleSessionNamePattern.MatchString(c.AWSRoleSessionName) {
+		fail("AWS_ROLE_SESSION_NAME must be 2 to 64 characters of letters, digits and +=,.@- (got %q)", c.AWSRoleSessionName)
+	}
+	if d := time.Duration(c.AWSRoleDurationMinutes) * time.Minute; d < 2*roleRefreshMargin || d > maxTemporaryURLExpiration {
+		fail("AWS_ROLE_DURATION_MINUTES must be between %d and %d (got %d)", int(2*roleRefreshMargin.Minutes()), int(maxTemporaryURLExpiration.Minutes()), c.AWSRoleDurationMinutes)
+	}
+	if c.AWSRoleARN != "" {
+		if !roleARNPattern.MatchString(c.AWSRoleARN) {
+			fail("AWS_ROLE_ARN must look like arn:aws:iam::<account-id>:role/<name> (got %q)", c.AWSRoleARN)
+		}
+		if c.AWSRoleExternalID != "" && (len(c.AWSRoleExternalID) < 2 || len(c.AWSRoleExternalID) > 1224) {
+			fail("AWS_ROLE_EXTERNAL_ID must be 2 to 1224 characters")
+		}
+		// Credentials are refreshed once they can't cover a full URL
+		// lifetime, so sessions must outlast the longest URL
+		if longest := max(c.UploadURLExpiration(), c.DownloadURLExpiration()); longest > c.RoleURLExpiration() {
+			fail("AWS_ROLE_DURATION_MINUTES must exceed the longest URL expiration (%d minutes) by at least %d minutes (got %d)",
+				int(longest.Minutes()), int(roleRefreshMargin.Minutes()), c.AWSRoleDurationMinutes)
+		}
+	}
+	// SigV4 presigned URLs are valid for at most seven days; longer
+	// lifetimes are rejected by S3 when the URL is used
+	if d := expirationMinutes(c.UploadURLExpirationMinutes, c.PresignedURLExpirationMinutes); d <= 0 || d > maxURLExpiration {
+		fail("UPLOAD_URL_EXPIRATION_MINUTES must be between 1 and %d, the SigV4 maximum of 7 days (got %d)", int(maxURLExpiration.Minutes()), int(d.Minutes()))
+	}
+	if d := expirationMinutes(c.DownloadURLExpirationMinutes, c.PresignedURLExpirationMinutes); d <= 0 || d > maxURLExpiration {
+		fail("DOWNLOAD_URL_EXPIRATION_MINUTES must be between 1 and %d, the SigV4 maximum of 7 days (got %d)", int(maxURLExpiration.Minutes()), int(d.Minutes()))
+	}
+	if c.DownloadTokenMaxRedemptions < 1 {
+		fail("DOWNLOAD_TOKEN_MAX_REDEMPTIONS must be at least 1 (got %d)", c.DownloadTokenMaxRedemptions)
+	}
+	if c.DownloadTokenTTLMinutes < 1 {
+		fail("DOWNLOAD_TOKEN_TTL_MINUTES must be at least 1 (got %d)", c.DownloadTokenTTLMinutes)
+	}
+	if c.DownloadTokenRedirectSeconds < 1 || c.DownloadTokenRedirectSeconds > 3600 {
+		fail("DOWNLOAD_TOKEN_REDIRECT_SECONDS must be between 1 and 3600 (got %d)", c.DownloadTokenRedirectSeconds)
+	}
+	// Programs embedding the handler may serve it on their own listener
+	if port, err := strconv.Atoi(c.Port); c.Port != "" && (err != nil || port < 1 || port > 65535) {
+		fail("PORT must be a number between 1 and 65535 (got %q)", c.Port)
+	}
+	if c.CompanyPrefix != "" && (!prefixPattern.MatchString(c.CompanyPrefix) || strings.Contains(c.CompanyPrefix, "..")) {
+		fail("COMPANY_PREFIX must be a relative key path of letters, digits and !_.*'()- without empty, '.' or '..' segments (got %q)", c.CompanyPrefix)
+	}
+	if c.Environment != "" && !environmentPattern.MatchString(c.Environment) {
+		fail("ENVIRONMENT must be 1-32 lowercase letters, digits or dashes like dev, staging or prod (got %q)", c.Environment)
+	}
+	// Custom endpoints such as MinIO accept arbitrary region names
+	if c.S3EndpointURL == "" && !regionPattern.MatchString(c.AWSRegion) {
+		fail("AWS_REGION must be an AWS region like us-east-1 (got %q)", c.AWSRegion)
+	}
+	if c.DRRegion != "" && !regionPattern.MatchString(c.DRRegion) {
+		fail("DR_REGION must be an AWS region like us-east-1 (got %q)", c.DRRegion)
+	}
+	// An S3 call that outlives the write timeout would have its response dropped silently
+	if c.S3OperationTimeoutSeconds > 0 && c.HTTPWriteTimeoutSeconds > 0 && c.S3OperationTimeoutSeconds >= c.HTTPWriteTimeoutSeconds {
+		fail("S3_OPERATION_TIMEOUT_SECONDS (%d) must be less than HTTP_WRITE_TIMEOUT_SECONDS (%d)",
+			c.S3OperationTimeoutSeconds, c.HTTPWriteTimeoutSeconds)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		fail("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.HTTPH2C && c.TLSCertFile != "" {
+		fail("HTTP_H2C only applies to the plaintext listener and can't be combined with TLS")
+	}
+	if c.AdminPort != "" {
+		if port, err := strconv.Atoi(c.AdminPort); err != nil || port < 1 || port > 65535 {
+			fail("ADMIN_PORT must be a number between 1 and 65535 (got %q)", c.AdminPort)
+		} else if c.AdminPort == c.Port {
+			fail("ADMIN_PORT must differ from PORT (both are %s)", c.Port)
+		}
+		if (c.AdminTLSCertFile == "") != (c.AdminTLSKeyFile == "") {
+			fail("ADMIN_TLS_CERT_FILE and ADMIN_TLS_KEY_FILE must be set together")
+		}
+		if c.AdminHTTPReadTimeoutSeconds < 1 || c.AdminHTTPWriteTimeoutSeconds < 1 || c.AdminHTTPIdleTimeoutSeconds < 1 {
+			fail("ADMIN_HTTP_*_TIMEOUT_SECONDS must be at least 1")
+		}
+	} else if c.AdminTLSCertFile != "" || c.AdminTLSKeyFile != "" {
+		fail("ADMIN_TLS_CERT_FILE and ADMIN_TLS_KEY_FILE require ADMIN_PORT")
+	}
+	if c.GzipMinBytes < 0 {
+		fail("GZIP_MIN_BYTES must not be negative (got %d)", c.GzipMinBytes)
+	}
+	if c.MaxConcurrentRequests < 0 {
+		fail("MAX_CONCURRENT_REQUESTS must not be negative (got %d)", c.MaxConcurrentRequests)
+	}
+	if c.ListCacheTTLSeconds < 0 {
+		fail("LIST_CACHE_TTL_SECONDS must not be negative (got %d)", c.ListCacheTTLSeconds)
+	}
+	if c.ClockDriftThresholdSeconds < 1 {
+		fail("CLOCK_DRIFT_THRESHOLD_SECONDS must be at least 1 (got %d)", c.ClockDriftThresholdSeconds)
+	}
+	if c.AWSInitMaxAttempts < 1 {
+		fail("AWS_INIT_MAX_ATTEMPTS must be at least 1 (got %d)", c.AWSInitMaxAttempts)
+	}
+	if c.S3CallBudgetPerMinute < 0 {
+		fail("S3_CALL_BUDGET_PER_MINUTE must not be negative (got %d)", c.S3CallBudgetPerMinute)
+	}
+	if c.S3CallBudgetMaxWaitSeconds < 0 {
+		fail("S3_CALL_BUDGET_MAX_WAIT_SECONDS must not be negative (got %d)", c.S3CallBudgetMaxWaitSeconds)
+	}
+	switch c.S3CallBudgetMode {
+	case "", "queue", "reject":
+	default:
+		fail("S3_CALL_BUDGET_MODE must be queue or reject (got %q)", c.S3CallBudgetMode)
+	}
+	if c.MinRetentionHours < 0 {
+		fail("MIN_RETENTION_HOURS must not be negative (got %d)", c.MinRetentionHours)
+	}
+	if c.TrashRetentionDays < 0 {
+		fail("TRASH_RETENTION_DAYS must not be negative (got %d)", c.TrashRetentionDays)
+	}
+	if schedule, err := c.MaintenanceSchedule(); err != nil {
+		fail("MAINTENANCE_WINDOWS: %w", err)
+	} else if schedule != nil {
+		now := time.Now()
+		if _, ok := schedule.NextOpen(now); !ok {
+			fail("MAINTENANCE_WINDOWS never opens")
+		} else if _, ok := schedule.NextClose(now); !ok {
+			fail("MAINTENANCE_WINDOWS never closes: use READ_ONLY instead")
+		}
+	}
+	switch c.PreflightCheck {
+	case "", "off", "warn", "fail":
+	default:
+		fail("PREFLIGHT_CHECK must be off, warn or fail (got %q)", c.PreflightCheck)
+	}
+	switch c.SignerDebug {
+	case "", "off", "header", "all":
+	default:
+		fail("SIGNER_DEBUG must be off, header or all (got %q)", c.SignerDebug)
+	}
+	switch c.LongFilenameStrategy {
+	case "", "reject", "truncate", "hash":
+	default:
+		fail("LONG_FILENAME_STRATEGY must be reject, truncate or hash (got %q)", c.LongFilenameStrategy)
+	}
+	if _, err := regexp.Compile(c.UploadSubpathPattern); err != nil {
+		fail("UPLOAD_SUBPATH_PATTERN is not a valid regular expression: %w", err)
+	}
+	if c.LogLevel != "" {
+		if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+			fail("LOG_LEVEL: %w", err)
+		}
+	}
+	switch c.TenantStore {
+	case "", "off":
+		if len(c.Tenants) > 0 {
+			fail("tenants in the config file require TENANT_STORE=memory or file")
+		}
+	case "memory":
+		if c.AdminAPIKey == "" && len(c.Tenants) == 0 {
+			fail("TENANT_STORE=memory requires ADMIN_API_KEY to manage tenants or tenants in the config file")
+		}
+	case "file":
+		if c.TenantStoreFile == "" {
+			fail("TENANT_STORE=file requires TENANT_STORE_FILE")
+		}
+	default:
+		fail("TENANT_STORE must be off, memory or file (got %q)", c.TenantStore)
+	}
+	switch c.APIKeyStore {
+	case "", "off":
+	case "memory", "file":
+		if c.AdminAPIKey == "" {
+			fail("API_KEY_STORE requires ADMIN_API_KEY to manage keys")
+		}
+		if c.APIKeyStore == "file" && c.APIKeyStoreFile == "" {
+			fail("API_KEY_STORE=file requires API_KEY_STORE_FILE")
+		}
+	default:
+		fail("API_KEY_STORE must be off, memory or file (got %q)", c.APIKeyStore)
+	}
+	switch c.CatalogStore {
+	case "", "off", "memory":
+	case "postgres":
+		if c.CatalogDatabaseURL == "" {
+			fail("CATALOG_STORE=postgres requires CATALOG_DATABASE_URL")
+		}
+	default:
+		fail("CATALOG_STORE must be off, memory or postgres (got %q)", c.CatalogStore)
+	}
+	if c.CatalogDeduplicate && (c.CatalogStore == "" || c.CatalogStore == "off") {
+		fail("CATALOG_DEDUPLICATE requires CATALOG_STORE")
+	}
+	for _, entry := range c.ExpectedBackups {
+		if _, _, err := ParseExpectedBackup(entry); err != nil {
+			fail("EXPECTED_BACKUPS: %v", err)
+		}
+	}
+	if c.StaleBackupCheckIntervalMinutes < 0 {
+		fail("STALE_BACKUP_CHECK_INTERVAL_MINUTES must not be negative (got %d)", c.StaleBackupCheckIntervalMinutes)
+	}
+	if c.StaleBackupWebhookURL != "" {
+		if u, err := url.Parse(c.StaleBackupWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("STALE_BACKUP_WEBHOOK_URL must be an http(s) URL (got %q)", c.StaleBackupWebhookURL)
+		}
+	}
+	if c.RestoreDrillIntervalMinutes < 0 {
+		fail("RESTORE_DRILL_INTERVAL_MINUTES must not be negative (got %d)", c.RestoreDrillIntervalMinutes)
+	}
+	if c.RestoreDrillMaxAgeHours < 1 {
+		fail("RESTORE_DRILL_MAX_AGE_HOURS must be at least 1 (got %d)", c.RestoreDrillMaxAgeHours)
+	}
+	if c.RestoreDrillMaxBytes < 0 {
+		fail("RESTORE_DRILL_MAX_BYTES must not be negative (got %d)", c.RestoreDrillMaxBytes)
+	}
+	if c.RestoreDrillHistory < 1 || c.RestoreDrillHistory > 100000 {
+		fail("RESTORE_DRILL_HISTORY must be between 1 and 100000 (got %d)", c.RestoreDrillHistory)
+	}
+	if c.AnomalySpikeFactor < 2 {
+		fail("ANOMALY_SPIKE_FACTOR must be at least 2 (got %d)", c.AnomalySpikeFactor)
+	}
+	if c.AnomalySpikeMinURLs < 1 {
+		fail("ANOMALY_SPIKE_MIN_URLS must be at least 1 (got %d)", c.AnomalySpikeMinURLs)
+	}
+	if _, err := c.AnomalyBusinessSchedule(); err != nil {
+		fail("ANOMALY_BUSINESS_HOURS: %w", err)
+	}
+	if c.AnomalyWebhookURL != "" {
+		if u, err := url.Parse(c.AnomalyWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("ANOMALY_WEBHOOK_URL must be an http(s) URL (got %q)", c.AnomalyWebhookURL)
+		}
+	}
+	if c.AccessLogPrefix != "" {
+		if c.AccessLogIntervalMinutes < 1 {
+			fail("ACCESS_LOG_INTERVAL_MINUTES must be at least 1 (got %d)", c.AccessLogIntervalMinutes)
+		}
+		if c.AccessLogRetentionHours < 1 {
+			fail("ACCESS_LOG_RETENTION_HOURS must be at least 1 (got %d)", c.AccessLogRetentionHours)
+		}
+	}
+	switch c.AuditSink {
+	case "", "off":
+	case "http":
+		if u, err := url.Parse(c.AuditHTTPURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("AUDIT_SINK=http requires an http(s) AUDIT_HTTP_URL (got %q)", c.AuditHTTPURL)
+		}
+	case "syslog":
+		if u, err := url.Parse(c.AuditSyslogAddress); err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Port() == "" {
+			fail("AUDIT_SINK=syslog requires AUDIT_SYSLOG_ADDRESS like udp://host:514 or tcp://host:514 (got %q)", c.AuditSyslogAddress)
+		}
+	case "cloudwatch":
+		if c.AuditCloudWatchLogGroup == "" || c.AuditCloudWatchLogStream == "" {
+			fail("AUDIT_SINK=cloudwatch requires AUDIT_CLOUDWATCH_LOG_GROUP and AUDIT_CLOUDWATCH_LOG_STREAM")
+		}
+	default:
+		fail("AUDIT_SINK must be off, http, syslog or cloudwatch (got %q)", c.AuditSink)
+	}
+	if c.AuditBatchSize < 1 || c.AuditBatchSize > 10000 {
+		fail("AUDIT_BATCH_SIZE must be between 1 and 10000 (got %d)", c.AuditBatchSize)
+	}
+	if c.AuditFlushIntervalSeconds < 1 {
+		fail("AUDIT_FLUSH_INTERVAL_SECONDS must be at least 1 (got %d)", c.AuditFlushIntervalSeconds)
+	}
+	switch c.URLEncryption {
+	case "", "off":
+	case "jwe":
+		if c.URLEncryptionPublicKeyFile == "" {
+			fail("URL_ENCRYPTION=jwe requires URL_ENCRYPTION_PUBLIC_KEY_FILE")
+		}
+	case "kms":
+		if c.URLEncryptionKMSKeyID == "" {
+			fail("URL_ENCRYPTION=kms requires URL_ENCRYPTION_KMS_KEY_ID")
+		}
+	default:
+		fail("URL_ENCRYPTION must be off, jwe or kms (got %q)", c.URLEncryption)
+	}
+	switch c.MetricsSink {
+	case "", "prometheus":
+	case "statsd", "dogstatsd":
+		if c.StatsdAddress == "" {
+			fail("METRICS_SINK=%s requires STATSD_ADDRESS", c.MetricsSink)
+		}
+		if c.MetricsSink == "statsd" && len(c.StatsdTags) > 0 {
+			fail("STATSD_TAGS requires METRICS_SINK=dogstatsd; plain statsd has no tags")
+		}
+		for _, tag := range c.StatsdTags {
+			if !strings.Contains(tag, ":") {
+				fail("STATSD_TAGS entries must look like key:value (got %q)", tag)
+			}
+		}
+	default:
+		fail("METRICS_SINK must be prometheus, statsd or dogstatsd (got %q)", c.MetricsSink)
+	}
+	if c.CloudWatchLogGroup != "" {
+		if c.CloudWatchLogStream == "" {
+			fail("CLOUDWATCH_LOG_GROUP requires CLOUDWATCH_LOG_STREAM")
+		}
+		if c.CloudWatchMetricsIntervalSeconds < 0 {
+			fail("CLOUDWATCH_METRICS_INTERVAL_SECONDS must not be negative (got %d)", c.CloudWatchMetricsIntervalSeconds)
+		}
+		if c.CloudWatchMetricsIntervalSeconds > 0 && c.CloudWatchMetricsNamespace == "" {
+			fail("CLOUDWATCH_METRICS_INTERVAL_SECONDS requires CLOUDWATCH_METRICS_NAMESPACE")
+		}
+		if !c.CloudWatchShipLogs && c.CloudWatchMetricsIntervalSeconds == 0 {
+			fail("CLOUDWATCH_LOG_GROUP is set but CLOUDWATCH_SHIP_LOGS is false and CLOUDWATCH_METRICS_INTERVAL_SECONDS is 0; nothing would be sent")
+		}
+	}
+	for _, entry := range c.APIKeys {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		for _, scope := range strings.Split(parts[2], "+") {
+			if !knownOperations[scope] && scope != "admin" {
+				fail("unknown scope %q in API_KEYS entry for %q", scope, parts[0])
+			}
+		}
+	}
+	hmacKeyIDs := make(map[string]bool)
+	for _, entry := range c.HMACSecrets {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			fail("HMAC_SECRETS entries must be key-id:secret[:scopes]")
+			continue
+		}
+		if hmacKeyIDs[parts[0]] {
+			fail("duplicate key ID %q in HMAC_SECRETS", parts[0])
+		}
+		hmacKeyIDs[parts[0]] = true
+		if len(parts) < 3 {
+			continue
+		}
+		for _, scope := range strings.Split(parts[2], "+") {
+			if !knownOperations[scope] && scope != "admin" {
+				fail("unknown scope %q in HMAC_SECRETS entry for %q", scope, parts[0])
+			}
+		}
+	}
+	if len(c.HMACSecrets) > 0 && c.HMACMaxOutstandingNonces < 1 {
+		fail("HMAC_MAX_OUTSTANDING_NONCES must be at least 1")
+	}
+	// Without an audience any token of the provider, issued for any client,
+	// would be accepted
+	if c.OIDCDiscoveryURL != "" && c.OIDCAudience == "" {
+		fail("OIDC_AUDIENCE is required with OIDC_DISCOVERY_URL")
+	}
+	for _, op := range c.AllowedOperations {
+		if !knownOperations[op] {
+			fail("unknown operation %q in ALLOWED_OPERATIONS", op)
+		}
+	}
+	if err := c.validateSignedHeaders(); err != nil {
+		problems = append(problems, err)
+	}
+	if err := c.validateInjectedMetadata(); err != nil {
+		problems = append(problems, err)
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				fail("invalid TRUSTED_PROXIES entry %q: must be an IP or CIDR", proxy)
+			}
+		}
+	}
+	if c.RealIPHeader != "X-Forwarded-For" && c.RealIPHeader != "Forwarded" {
+		fail("REAL_IP_HEADER must be X-Forwarded-For or Forwarded (got %q)", c.RealIPHeader)
+	}
+	for _, name := range c.MiddlewareChain {
+		if !knownMiddleware[name] {
+			fail("unknown middleware %q in MIDDLEWARE_CHAIN", name)
+		}
+	}
+	if !i18n.Supported(c.DefaultLanguage) {
+		fail("DEFAULT_LANGUAGE must be en or es (got %q)", c.DefaultLanguage)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ParseExpectedBackup parses an EXPECTED_BACKUPS entry like
+// "db-*.dump.gz=26": a file name or glob that should receive a new upload at
+// least every given number of hours
+func ParseExpectedBackup(entry string) (string, int, error) {
+	filename, hours, ok := strings.Cut(entry, "=")
+	filename = strings.TrimSpace(filename)
+	if !ok || filename == "" || strings.Contains(filename, "/") {
+		return "", 0, fmt.Errorf("%q must look like filename=hours", entry)
+	}
+	if _, err := path.Match(filename, ""); err != nil {
+		return "", 0, fmt.Errorf("invalid filename pattern %q: %w", filename, err)
+	}
+	maxAgeHours, err := strconv.Atoi(strings.TrimSpace(hours))
+	if err != nil || maxAgeHours < 1 {
+		return "", 0, fmt.Errorf("%q must give a whole number of hours of at least 1", entry)
+	}
+	return filename, maxAgeHours, nil
+}
+
+// ValidationError lists every problem found in a config: values that don't
+// parse, when returned by Load, and those Validate rejects
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Error()
+	}
+	return fmt.Sprintf("%d problems: %s", len(e.Problems), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the problems, for errors.Is and errors.As
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// minHMACSecretBytes is the shortest HMAC_SECRETS secret not warned about
+const minHMACSecretBytes = 32
+
+// Warnings returns insecure but valid combinations of settings. Load logs
+// them; they don't prevent the service from starting.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if slices.Contains(c.CORSAllowedOrigins, "*") && (len(c.APIKeys) > 0 || len(c.HMACSecrets) > 0 || c.OIDCDiscoveryURL != "") {
+		warnings = append(warnings, "CORS_ALLOWED_ORIGINS allows any origin while requests are authenticated; any website can call the API with a user's credentials")
+	}
+	for _, e := range []struct {
+		name    string
+		minutes int
+	}{
+		{"UPLOAD_URL_EXPIRATION_MINUTES", c.UploadURLExpirationMinutes},
+		{"DOWNLOAD_URL_EXPIRATION_MINUTES", c.DownloadURLExpirationMinutes},
+	} {
+		if d := expirationMinutes(e.minutes, c.PresignedURLExpirationMinutes); c.TemporaryCredentials() && d > maxTemporaryURLExpiration {
+			warnings = append(warnings, fmt.Sprintf("%s is %d but URLs signed with temporary credentials are clamped to %d minutes, and stop working when AWS_SESSION_TOKEN expires",
+				e.name, int(d.Minutes()), int(maxTemporaryURLExpiration.Minutes())))
+		}
+	}
+	if c.SignerDebug == "all" {
+		warnings = append(warnings, "SIGNER_DEBUG=all logs every signature's canonical request; use header or off in production")
+	}
+	for _, entry := range c.HMACSecrets {
+		if parts := strings.SplitN(entry, ":", 3); len(parts) >= 2 && len(parts[1]) < minHMACSecretBytes {
+			warnings = append(warnings, fmt.Sprintf("HMAC_SECRETS secret for %q is shorter than %d bytes", parts[0], minHMACSecretBytes))
+		}
+	}
+	if c.AdminAPIKey != "" && c.AdminPort == "" && c.TLSCertFile == "" && len(c.TrustedProxies) == 0 {
+		warnings = append(warnings, "ADMIN_API_KEY is set without TLS_CERT_FILE or TRUSTED_PROXIES; admin keys may travel in plaintext")
+	}
+	if c.AdminAPIKey != "" && c.AdminPort != "" && c.AdminTLSCertFile == "" && c.AdminHost != "127.0.0.1" && c.AdminHost != "localhost" {
+		warnings = append(warnings, "ADMIN_API_KEY is set without ADMIN_TLS_CERT_FILE on a non-loopback ADMIN_HOST; admin keys may travel in plaintext")
+	}
+	if c.S3EndpointURL != "" && strings.HasPrefix(c.S3EndpointURL, "http://") && !isLocalEndpoint(c.S3EndpointURL) {
+		warnings = append(warnings, "S3_ENDPOINT_URL uses plain http to a non-local host; presigned URLs and object data travel unencrypted")
+	}
+	if strings.Contains(c.CatalogDatabaseURL, "sslmode=disable") {
+		warnings = append(warnings, "CATALOG_DATABASE_URL disables TLS (sslmode=disable)")
+	}
+	if c.AuditSink == "http" && strings.HasPrefix(c.AuditHTTPURL, "http://") && !isLocalEndpoint(c.AuditHTTPURL) {
+		warnings = append(warnings, "AUDIT_HTTP_URL uses plain http to a non-local host; audit events and AUDIT_HTTP_AUTHORIZATION travel unencrypted")
+	}
+	if c.StaleBackupWebhookURL != "" && c.StaleBackupWebhookSecret == "" {
+		warnings = append(warnings, "STALE_BACKUP_WEBHOOK_URL is set without STALE_BACKUP_WEBHOOK_SECRET; receivers can't verify alerts come from the signer")
+	}
+	if c.DownloadURLExpiration() > 24*time.Hour {
+		warnings = append(warnings, fmt.Sprintf("download URLs are valid for %s; leaked URLs stay usable that long", c.DownloadURLExpiration()))
+	}
+	return warnings
+}
+
+// isLocalEndpoint reports whether endpoint points at the local machine
+func isLocalEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.IsLoopback()
+}
+
+// requirableHeaders are the headers uploads can be made to sign through
+// request fields
+var requirableHeaders = map[string]bool{
+	"content-type":          true,
+	"content-length":        true,
+	"content-md5":           true,
+	"content-encoding":      true,
+	"x-amz-checksum-sha256": true,
+	// Requires SSE-C uploads
+	"x-amz-server-side-encryption-customer-key-md5": true,
+}
+
+// uploadACLs are the canned ACLs UPLOAD_ACL accepts; public ACLs are refused
+var uploadACLs = map[string]bool{
+	"private":                   true,
+	"bucket-owner-read":         true,
+	"bucket-owner-full-control": true,
+}
+
+// accountIDPattern matches an AWS account ID
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// validateEndpointURL checks S3_ENDPOINT_URL. Custom endpoints are addressed
+// path-style, so they cannot serve access point or MRAP ARNs.
+func (c *Config) validateEndpointURL() error {
+	if c.S3EndpointURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.S3EndpointURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("S3_ENDPOINT_URL must be an http(s) URL without a path like http://localhost:4566 (got %q)", c.S3EndpointURL)
+	}
+	if strings.HasPrefix(c.S3BucketName, "arn:") || c.S3MRAPARN != "" {
+		return fmt.Errorf("S3_ENDPOINT_URL cannot be combined with an access point ARN or S3_MRAP_ARN")
+	}
+	return nil
+}
+
+// validateSignedHeaders checks REQUIRED_SIGNED_HEADERS and
+// ALLOWED_SIGNED_HEADERS
+func (c *Config) validateSignedHeaders() error {
+	for _, header := range c.RequiredSignedHeaders {
+		if !requirableHeaders[header] {
+			return fmt.Errorf("REQUIRED_SIGNED_HEADERS entry %q must be one of content-type, content-length, content-md5, content-encoding, x-amz-checksum-sha256, x-amz-server-side-encryption-customer-key-md5", header)
+		}
+	}
+	for _, pattern := range c.AllowedSignedHeaders {
+		name := strings.TrimSuffix(pattern, "*")
+		if name == "" || strings.Contains(name, "*") || strings.ToLower(name) != name || strings.ContainsAny(name, " :") {
+			return fmt.Errorf("ALLOWED_SIGNED_HEADERS entry %q must be a lowercase header name or a prefix ending in *", pattern)
+		}
+	}
+	if c.UploadACL != "" && !uploadACLs[c.UploadACL] {
+		return fmt.Errorf("UPLOAD_ACL must be private, bucket-owner-read or bucket-owner-full-control (got %q)", c.UploadACL)
+	}
+	if c.ExpectedBucketOwner != "" && !accountIDPattern.MatchString(c.ExpectedBucketOwner) {
+		return fmt.Errorf("S3_EXPECTED_BUCKET_OWNER must be a 12-digit AWS account ID (got %q)", c.ExpectedBucketOwner)
+	}
+	if len(c.AllowedSignedHeaders) == 0 {
+		return nil
+	}
+	for _, header := range c.RequiredSignedHeaders {
+		if !c.signedHeaderAllowed(header) {
+			return fmt.Errorf("REQUIRED_SIGNED_HEADERS entry %q is not in ALLOWED_SIGNED_HEADERS", header)
+		}
+	}
+	if c.UploadACL != "" && !c.signedHeaderAllowed("x-amz-acl") {
+		return fmt.Errorf("UPLOAD_ACL requires x-amz-acl in ALLOWED_SIGNED_HEADERS")
+	}
+	if c.ExpectedBucketOwner != "" && !c.signedHeaderAllowed("x-amz-expected-bucket-owner") {
+		return fmt.Errorf("S3_EXPECTED_BUCKET_OWNER requires x-amz-expected-bucket-owner in ALLOWED_SIGNED_HEADERS")
+	}
+	return nil
+}
+
+// signedHeaderAllowed reports whether ALLOWED_SIGNED_HEADERS is empty or
+// lets uploads sign header
+func (c *Config) signedHeaderAllowed(header string) bool {
+	if len(c.AllowedSignedHeaders) == 0 {
+		return true
+	}
+	for _, pattern := range c.AllowedSignedHeaders {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); (ok && strings.HasPrefix(header, prefix)) || pattern == header {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataKeyPattern restricts injected metadata keys to characters valid in
+// HTTP header names
+var metadataKeyPattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// metadataPlaceholderPattern matches the placeholders of injected metadata
+var metadataPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// injectedMetadataPlaceholders are the placeholders INJECTED_METADATA values
+// may use, resolved per request
+var injectedMetadataPlaceholders = map[string]bool{
+	"{tenant}":     true,
+	"{principal}":  true,
+	"{request_id}": true,
+	"{client_ip}":  true,
+	"{upload_id}":  true,
+}
+
+// validateInjectedMetadata checks INJECTED_METADATA entries, and that uploads
+// may sign their headers
+func (c *Config) validateInjectedMetadata() error {
+	seen := map[string]bool{}
+	for _, entry := range c.InjectedMetadata {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !metadataKeyPattern.MatchString(key) || value == "" {
+			return fmt.Errorf("INJECTED_METADATA entry %q must be key=value with a lowercase key of letters, digits, - and _", entry)
+		}
+		if seen[key] {
+			return fmt.Errorf("INJECTED_METADATA key %q is set twice", key)
+		}
+		seen[key] = true
+		for _, placeholder := range metadataPlaceholderPattern.FindAllString(value, -1) {
+			if !injectedMetadataPlaceholders[placeholder] {
+				return fmt.Errorf("INJECTED_METADATA entry %q uses unknown placeholder %s", entry, placeholder)
+			}
+		}
+		if !c.signedHeaderAllowed("x-amz-meta-" + key) {
+			return fmt.Errorf("INJECTED_METADATA key %q is not in ALLOWED_SIGNED_HEADERS", "x-amz-meta-"+key)
+		}
+	}
+	return nil
+}
+
+// loader reads settings from flags and the environment, falling back to the
+// config file
+type loader struct {
+	flags    map[string]string
+	file     *configFile
+	read     map[string]bool // Settings looked up, to reject unknown file keys
+	problems []error         // Values that didn't parse
+}
+
+// fail records a problem with the settings read
+func (l *loader) fail(err error) {
+	l.problems = append(l.problems, err)
+}
+
+// lookup returns the flag value of key, its environment value or its config
+// file value, in that order
+func (l *loader) lookup(key string) string {
+	l.read[key] = true
+	if value, ok := l.flags[key]; ok {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	value, _ := l.file.lookup(key)
+	return value
+}
+
+// name describes where key was read from for error messages
+func (l *loader) name(key string) string {
+	if _, ok := l.flags[key]; ok {
+		return "--" + flagName(key)
+	}
+	if os.Getenv(key) == "" {
+		if _, ok := l.file.lookup(key); ok {
+			return fmt.Sprintf("%s (%s)", strings.ToLower(key), l.file.path)
+		}
+	}
+	return key
+}
+
+// getEnv gets an environment variable or returns a default value
+func (l *loader) getEnv(key, defaultValue string) string {
+	if value := l.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt gets an integer environment variable or returns a default value.
+// Values that don't parse are recorded as problems and read as the default.
+func (l *loader) getEnvInt(key string, defaultValue int) int {
+	return getEnvParsed(l, key, defaultValue, strconv.Atoi)
+}
+
+// getEnvFloat gets a float environment variable or returns a default value,
+// like getEnvInt
+func (l *loader) getEnvFloat(key string, defaultValue float64) float64 {
+	return getEnvParsed(l, key, defaultValue, func(value string) (float64, error) {
+		return strconv.ParseFloat(value, 64)
+	})
+}
+
+// getEnvBool gets a boolean environment variable or returns a default value,
+// like getEnvInt
+func (l *loader) getEnvBool(key string, defaultValue bool) bool {
+	return getEnvParsed(l, key, defaultValue, strconv.ParseBool)
+}
+
+// getEnvParsed reads key with parse, recording a problem and returning
+// defaultValue when the value doesn't parse
+func getEnvParsed[T any](l *loader, key string, defaultValue T, parse func(string) (T, error)) T {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		l.fail(fmt.Errorf("invalid %s value: %w", l.name(key), err))
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvList gets a comma-separated environment variable as a list,
+// trimming whitespace and dropping empty entries
+func (l *loader) getEnvList(key, defaultValue string) []string {
+	return splitList(l.getEnv(key, defaultValue))
+}
+
+// splitWindows splits semicolon-separated cron-like windows into trimmed,
+// non-empty entries
+func splitWindows(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// splitList splits a comma-separated string into trimmed, non-empty entries
+func splitList(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
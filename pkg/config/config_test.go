@@ -0,0 +1,170 @@
+package config_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+)
+
+// load loads a config from the minimal valid settings overridden by settings
+func load(settings map[string]string) (*config.Config, error) {
+	values := map[string]string{
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"S3_BUCKET_NAME":        "backups",
+		"COMPANY_PREFIX":        "acme",
+		"LOG_FORMAT":            "text",
+	}
+	for k, v := range settings {
+		values[k] = v
+	}
+	return config.Load("", values)
+}
+
+// problems returns the messages of a *config.ValidationError, failing the
+// test on any other error
+func problems(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var invalid *config.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v (%T), want a *config.ValidationError", err, err)
+	}
+	messages := make([]string, len(invalid.Problems))
+	for i, problem := range invalid.Problems {
+		messages[i] = problem.Error()
+	}
+	return messages
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	_, err := load(map[string]string{
+		"PRESIGNED_URL_EXPIRATION_MINUTES": "three",
+		"RATE_LIMIT_RPS":                   "fast",
+		"GZIP_ENABLED":                     "maybe",
+		"S3_RETRY_MAX_ATTEMPTS":            "1.5",
+		"AWS_REGION":                       "mars",
+		"S3_BUCKET_NAME":                   "",
+	})
+	got := problems(t, err)
+	// Settings are named as passed, here as flags
+	want := []string{
+		"invalid --presigned-url-expiration-minutes value",
+		"invalid --rate-limit-rps value",
+		"invalid --gzip-enabled value",
+		"invalid --s3-retry-max-attempts value",
+		"S3_BUCKET_NAME or S3_MRAP_ARN is required",
+		"AWS_REGION must be an AWS region",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %q, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(got[i], w) {
+			t.Errorf("problem %d = %q, want it to mention %q", i, got[i], w)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "6 problems: ") {
+		t.Errorf("Error() = %q, want the problem count first", err)
+	}
+
+	// Parse errors stay reachable through Unwrap
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("errors.Is(err, strconv.ErrSyntax) = false for %v", err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) || numErr.Num != "three" {
+		t.Errorf("errors.As(*strconv.NumError) = %+v, want the first unparsable value", numErr)
+	}
+}
+
+func TestValidationErrorSingleProblem(t *testing.T) {
+	_, err := load(map[string]string{"GZIP_ENABLED": "maybe"})
+	if got := problems(t, err); len(got) != 1 || err.Error() != got[0] {
+		t.Errorf("Error() = %q with problems %q, want just the problem", err, got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		problem  string // Empty when the settings are valid
+	}{
+		{"defaults", nil, ""},
+		{"region", map[string]string{"AWS_REGION": "us-gov-west-1"}, ""},
+		{"region without number", map[string]string{"AWS_REGION": "us-east"}, "AWS_REGION must be an AWS region"},
+		{"uppercase region", map[string]string{"AWS_REGION": "US-EAST-1"}, "AWS_REGION must be an AWS region"},
+		{"any region on a custom endpoint", map[string]string{"AWS_REGION": "garage", "S3_ENDPOINT_URL": "http://localhost:9000"}, ""},
+		{"DR region", map[string]string{"DR_BUCKET_NAME": "dr", "DR_REGION": "eu-west"}, "DR_REGION must be an AWS region"},
+		{"nested prefix", map[string]string{"COMPANY_PREFIX": "acme/backups"}, ""},
+		{"prefix with leading slash", map[string]string{"COMPANY_PREFIX": "/acme"}, "COMPANY_PREFIX must be a relative key path"},
+		{"prefix with empty segment", map[string]string{"COMPANY_PREFIX": "acme//db"}, "COMPANY_PREFIX must be a relative key path"},
+		{"prefix with dot dot", map[string]string{"COMPANY_PREFIX": "acme/../globex"}, "COMPANY_PREFIX must be a relative key path"},
+		{"prefix with space", map[string]string{"COMPANY_PREFIX": "acme corp"}, "COMPANY_PREFIX must be a relative key path"},
+		{"port", map[string]string{"PORT": "65535"}, ""},
+		{"port zero", map[string]string{"PORT": "0"}, "PORT must be a number between 1 and 65535"},
+		{"port too high", map[string]string{"PORT": "65536"}, "PORT must be a number between 1 and 65535"},
+		{"port not a number", map[string]string{"PORT": "http"}, "PORT must be a number between 1 and 65535"},
+		{"admin port same as port", map[string]string{"PORT": "8080", "ADMIN_PORT": "8080"}, "ADMIN_PORT must differ from PORT"},
+		{"HMAC key without secret", map[string]string{"HMAC_SECRETS": "agent"}, "HMAC_SECRETS entries must be key-id:secret"},
+		{"legacy HMAC secret", map[string]string{"HMAC_SECRET": "shared"}, "was replaced by HMAC_SECRETS"},
+		{"OIDC with audience", map[string]string{"OIDC_DISCOVERY_URL": "https://idp.example.com/.well-known/openid-configuration", "OIDC_AUDIENCE": "signer"}, ""},
+		{"real IP from Forwarded", map[string]string{"REAL_IP_HEADER": "Forwarded"}, ""},
+		{"unknown real IP header", map[string]string{"REAL_IP_HEADER": "X-Real-IP"}, "REAL_IP_HEADER must be X-Forwarded-For or Forwarded"},
+		{"OIDC without audience", map[string]string{"OIDC_DISCOVERY_URL": "https://idp.example.com/.well-known/openid-configuration"}, "OIDC_AUDIENCE is required"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := load(tc.settings)
+			got := problems(t, err)
+			switch {
+			case tc.problem == "" && len(got) > 0:
+				t.Errorf("problems = %q, want none", got)
+			case tc.problem != "" && (len(got) != 1 || !strings.Contains(got[0], tc.problem)):
+				t.Errorf("problems = %q, want one mentioning %q", got, tc.problem)
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		warning  string // Empty when no warning is expected
+	}{
+		{"defaults", nil, ""},
+		{"CORS without authentication", map[string]string{"CORS_ALLOWED_ORIGINS": "*"}, ""},
+		{"CORS with API keys", map[string]string{"CORS_ALLOWED_ORIGINS": "*", "API_KEYS": "k1"}, "CORS_ALLOWED_ORIGINS allows any origin"},
+		{"short HMAC secret", map[string]string{"HMAC_SECRETS": "agent:short"}, `HMAC_SECRETS secret for "agent" is shorter than 32 bytes`},
+		{"long HMAC secret", map[string]string{"HMAC_SECRETS": "agent:" + strings.Repeat("s", 32)}, ""},
+		{"debug all", map[string]string{"SIGNER_DEBUG": "all"}, "SIGNER_DEBUG=all"},
+		{"session token with long URLs", map[string]string{"AWS_SESSION_TOKEN": "token", "DOWNLOAD_URL_EXPIRATION_MINUTES": "1440"}, "DOWNLOAD_URL_EXPIRATION_MINUTES is 1440"},
+		{"admin key in plaintext", map[string]string{"ADMIN_API_KEY": "admin"}, "ADMIN_API_KEY is set without TLS_CERT_FILE"},
+		{"admin key behind a proxy", map[string]string{"ADMIN_API_KEY": "admin", "TRUSTED_PROXIES": "10.0.0.0/8"}, ""},
+		{"plain http endpoint", map[string]string{"S3_ENDPOINT_URL": "http://minio.internal:9000"}, "S3_ENDPOINT_URL uses plain http"},
+		{"local http endpoint", map[string]string{"S3_ENDPOINT_URL": "http://localhost:9000"}, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := load(tc.settings)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			got := cfg.Warnings()
+			switch {
+			case tc.warning == "" && len(got) > 0:
+				t.Errorf("Warnings() = %q, want none", got)
+			case tc.warning != "" && (len(got) != 1 || !strings.Contains(got[0], tc.warning)):
+				t.Errorf("Warnings() = %q, want one mentioning %q", got, tc.warning)
+			}
+		})
+	}
+}
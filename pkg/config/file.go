@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// sha256HexPattern matches a hex-encoded SHA-256 digest
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// TenantConfig declares a tenant in the config file. Tenants are seeded into
+// the tenant store at startup, replacing stored tenants with the same ID.
+type TenantConfig struct {
+	ID                  string                 `json:"id"`
+	Prefix              string                 `json:"prefix"`
+	QuotaBytes          int64                  `json:"quota_bytes,omitempty"`
+	AllowedContentTypes []string               `json:"allowed_content_types,omitempty"`
+	MinRetentionHours   int                    `json:"min_retention_hours,omitempty"`
+	UploadWindows       []string               `json:"upload_windows,omitempty"`
+	UploadTimezone      string                 `json:"upload_timezone,omitempty"`
+	ExpectedBackups     []ExpectedBackupConfig `json:"expected_backups,omitempty"`
+	RoleARN             string                 `json:"role_arn,omitempty"`
+	RoleExternalID      string                 `json:"role_external_id,omitempty"`
+	APIKeySHA256        string                 `json:"api_key_sha256"` // Hex SHA-256 of the tenant's API key
+}
+
+// ExpectedBackupConfig declares a tenant upload checked for staleness
+type ExpectedBackupConfig struct {
+	Filename    string `json:"filename"`
+	MaxAgeHours int    `json:"max_age_hours"`
+}
+
+// fileAPIKey is a static API key declared as a table in the config file
+type fileAPIKey struct {
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// configFile holds the values read from a config file
+type configFile struct {
+	path    string
+	values  map[string]string // Flat settings keyed by environment variable name
+	tenants []TenantConfig
+}
+
+// readConfigFile parses a YAML, TOML or JSON config file, chosen by
+// extension. Top-level keys are the environment variable names in lowercase.
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml, .toml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	f := &configFile{path: path, values: make(map[string]string, len(raw))}
+	for key, value := range raw {
+		switch key {
+		case "tenants":
+			if err := decodeSection(value, &f.tenants); err != nil {
+				return nil, fmt.Errorf("%s: invalid tenants: %w", path, err)
+			}
+			continue
+		case "api_keys":
+			// Keys may be declared as name:key[:scopes] strings or as tables
+			if entries, ok := value.([]any); ok && len(entries) > 0 {
+				if _, isTable := entries[0].(map[string]any); isTable {
+					var keys []fileAPIKey
+					if err := decodeSection(value, &keys); err != nil {
+						return nil, fmt.Errorf("%s: invalid api_keys: %w", path, err)
+					}
+					value = apiKeyEntries(keys)
+				}
+			}
+		}
+
+		flat, err := flatten(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s %w", path, key, err)
+		}
+		f.values[strings.ToUpper(key)] = flat
+	}
+
+	for i, t := range f.tenants {
+		if t.ID == "" || t.Prefix == "" {
+			return nil, fmt.Errorf("%s: tenants[%d] requires id and prefix", path, i)
+		}
+		if !sha256HexPattern.MatchString(t.APIKeySHA256) {
+			return nil, fmt.Errorf("%s: tenants[%d] (%s) requires api_key_sha256 as 64 lowercase hex characters", path, i, t.ID)
+		}
+	}
+
+	return f, nil
+}
+
+// lookup returns the file value for an environment variable name
+func (f *configFile) lookup(key string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	value, ok := f.values[key]
+	return value, ok
+}
+
+// checkKnown rejects file keys that no setting reads, suggesting the
+// closest known one
+func (f *configFile) checkKnown(known map[string]bool) error {
+	if f == nil {
+		return nil
+	}
+
+	var unknown []string
+	for key := range f.values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	key := unknown[0]
+	message := fmt.Sprintf("%s: unknown setting %q", f.path, strings.ToLower(key))
+	if suggestion := closest(key, known); suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %q?)", strings.ToLower(suggestion))
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// flatten converts a scalar or a list of scalars into the string form of the
+// equivalent environment variable
+func flatten(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]any); ok {
+				return "", fmt.Errorf("must be a value or a list of values")
+			}
+			if _, ok := item.(map[string]any); ok {
+				return "", fmt.Errorf("must be a value or a list of values")
+			}
+			part, err := flatten(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("must be a value or a list of values")
+	}
+}
+
+// decodeSection decodes a structured section into out, rejecting unknown
+// fields regardless of the file format
+func decodeSection(value any, out any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+// apiKeyEntries formats API keys declared as tables like API_KEYS entries
+func apiKeyEntries(keys []fileAPIKey) []any {
+	entries := make([]any, 0, len(keys))
+	for _, k := range keys {
+		entry := k.Name + ":" + k.Key
+		if len(k.Scopes) > 0 {
+			entry += ":" + strings.Join(k.Scopes, "+")
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// closest returns the known key within two edits of key, if any
+func closest(key string, known map[string]bool) string {
+	best, bestDistance := "", 3
+	for candidate := range known {
+		if d := editDistance(key, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
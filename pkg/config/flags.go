@@ -0,0 +1,214 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Kinds of flag values
+const (
+	kindString = iota
+	kindInt
+	kindBool
+	kindFloat
+	kindList // Comma-separated, like the environment variable
+)
+
+// setting describes a config value that can be set with a flag
+type setting struct {
+	key   string // Environment variable name; the flag is its kebab-case form
+	kind  int
+	usage string
+}
+
+// settings lists every value LoadConfig reads, in the order shown by --help
+var settings = []setting{
+	{"AWS_REGION", kindString, "AWS region (default us-east-1)"},
+	{"AWS_ACCESS_KEY_ID", kindString, "AWS access key ID"},
+	{"AWS_SECRET_ACCESS_KEY", kindString, "AWS secret access key (prefer the environment: flags are visible in the process list)"},
+	{"AWS_SESSION_TOKEN", kindString, "session token of temporary AWS credentials (prefer the environment)"},
+	{"AWS_ROLE_ARN", kindString, "IAM role assumed through STS to sign, e.g. for a bucket in another account"},
+	{"AWS_ROLE_EXTERNAL_ID", kindString, "external ID the role's trust policy requires (prefer the environment)"},
+	{"AWS_ROLE_SESSION_NAME", kindString, "role session name (default signer-service)"},
+	{"AWS_ROLE_DURATION_MINUTES", kindInt, "role session duration, 30 to 720 (default 60)"},
+	{"S3_BUCKET_NAME", kindString, "bucket name or (Object Lambda) access point ARN"},
+	{"S3_MRAP_ARN", kindString, "Multi-Region Access Point ARN used instead of the bucket"},
+	{"S3_ENDPOINT_URL", kindString, "S3-compatible endpoint such as localstack or MinIO, addressed path-style"},
+	{"DR_BUCKET_NAME", kindString, "replication destination bucket offered for downloads when the primary fails"},
+	{"DR_REGION", kindString, "region of the DR bucket"},
+	{"FAILOVER_ENABLED", kindBool, "route signing and S3 calls to the DR bucket while the primary fails health checks"},
+	{"FAILOVER_CHECK_INTERVAL_SECONDS", kindInt, "failover health check interval (default 30)"},
+	{"FAILOVER_THRESHOLD", kindInt, "consecutive health checks needed to fail over or back (default 3)"},
+	{"COMPANY_PREFIX", kindString, "key prefix for uploads"},
+	{"ENVIRONMENT", kindString, "environment segment inserted after the prefix in every key, e.g. prod (empty for none)"},
+	{"PRESIGNED_URL_EXPIRATION_MINUTES", kindInt, "default presigned URL lifetime in minutes (default 3)"},
+	{"UPLOAD_URL_EXPIRATION_MINUTES", kindInt, "upload, part and delete URL lifetime in minutes (default presigned-url-expiration-minutes)"},
+	{"DOWNLOAD_URL_EXPIRATION_MINUTES", kindInt, "download URL lifetime in minutes (default presigned-url-expiration-minutes)"},
+	{"DOWNLOAD_TOKEN_MAX_REDEMPTIONS", kindInt, "most redemptions a download token may allow (default 1, one-time use)"},
+	{"DOWNLOAD_TOKEN_TTL_MINUTES", kindInt, "download token lifetime in minutes (default 60)"},
+	{"DOWNLOAD_TOKEN_REDIRECT_SECONDS", kindInt, "lifetime of the URL a download token redirects to (default 60)"},
+	{"PORT", kindString, "listen port (default 8080)"},
+	{"HTTP_READ_TIMEOUT_SECONDS", kindInt, "HTTP read timeout (default 15)"},
+	{"HTTP_WRITE_TIMEOUT_SECONDS", kindInt, "HTTP write timeout (default 15)"},
+	{"HTTP_IDLE_TIMEOUT_SECONDS", kindInt, "HTTP idle timeout (default 60)"},
+	{"S3_OPERATION_TIMEOUT_SECONDS", kindInt, "upper bound for one S3 operation including retries (default 10)"},
+	{"TLS_CERT_FILE", kindString, "TLS certificate file"},
+	{"TLS_KEY_FILE", kindString, "TLS key file"},
+	{"HTTP_H2C", kindBool, "accept cleartext HTTP/2 on the plaintext listener"},
+	{"ADMIN_PORT", kindString, "internal port for /health, /ready, /metrics and /admin/v1 (empty serves them on port)"},
+	{"ADMIN_HOST", kindString, "interface the internal listener binds (default 0.0.0.0)"},
+	{"ADMIN_TLS_CERT_FILE", kindString, "TLS certificate file of the internal listener"},
+	{"ADMIN_TLS_KEY_FILE", kindString, "TLS key file of the internal listener"},
+	{"ADMIN_HTTP_READ_TIMEOUT_SECONDS", kindInt, "internal listener read timeout (default 5)"},
+	{"ADMIN_HTTP_WRITE_TIMEOUT_SECONDS", kindInt, "internal listener write timeout (default 30)"},
+	{"ADMIN_HTTP_IDLE_TIMEOUT_SECONDS", kindInt, "internal listener idle timeout (default 60)"},
+	{"SIGNER_DEBUG", kindString, "signature debugging: off, header or all"},
+	{"LOG_LEVEL", kindString, "minimum log level: debug, info, warn or error (default info)"},
+	{"PREFLIGHT_CHECK", kindString, "startup bucket validation: off, warn or fail"},
+	{"LONG_FILENAME_STRATEGY", kindString, "filenames whose key would exceed 1024 bytes: reject, truncate or hash (default reject)"},
+	{"UPLOAD_SUBPATH_PATTERN", kindString, "regular expression allowed upload subpaths must match (empty refuses subpaths)"},
+	{"ALLOWED_OPERATIONS", kindList, "operations the v2 API may presign (default upload,download)"},
+	{"REQUIRED_SIGNED_HEADERS", kindList, "headers every upload URL must sign, e.g. content-type,content-md5"},
+	{"ALLOWED_SIGNED_HEADERS", kindList, "the only headers upload URLs may sign, e.g. content-type,content-md5,x-amz-meta-* (empty allows any)"},
+	{"UPLOAD_ACL", kindString, "canned ACL signed into uploads: private, bucket-owner-read or bucket-owner-full-control"},
+	{"S3_EXPECTED_BUCKET_OWNER", kindString, "account ID signed into uploads as x-amz-expected-bucket-owner"},
+	{"INJECTED_METADATA", kindList, "metadata signed into every upload as key=value, e.g. issued-by=signer,tenant={tenant}"},
+	{"MIDDLEWARE_CHAIN", kindList, "middleware order, outermost first"},
+	{"DEFAULT_LANGUAGE", kindString, "language of error messages when Accept-Language names no supported one: en or es"},
+	{"TRUSTED_PROXIES", kindList, "IPs or CIDRs whose forwarding headers are honored"},
+	{"REAL_IP_HEADER", kindString, "header the trusted proxies write the client IP to: X-Forwarded-For or Forwarded"},
+	{"API_KEYS", kindList, "static API keys as name:key[:scope+scope] (prefer the environment)"},
+	{"CORS_ALLOWED_ORIGINS", kindList, "allowed CORS origins"},
+	{"RATE_LIMIT_RPS", kindFloat, "requests per second per client (0 disables)"},
+	{"RATE_LIMIT_BURST", kindInt, "rate limiter burst (default 10)"},
+	{"MAX_CONCURRENT_REQUESTS", kindInt, "requests served at once before shedding load with 503 (0 disables)"},
+	{"GZIP_ENABLED", kindBool, "gzip responses for clients sending Accept-Encoding: gzip"},
+	{"GZIP_MIN_BYTES", kindInt, "smallest response compressed with gzip (default 1024)"},
+	{"HMAC_SECRETS", kindList, "HMAC request signing keys as key-id:secret[:scopes] (prefer the environment)"},
+	{"HMAC_CHALLENGE_TTL_SECONDS", kindInt, "HMAC challenge lifetime (default 300)"},
+	{"HMAC_MAX_OUTSTANDING_NONCES", kindInt, "HMAC challenges outstanding at once (default 10000)"},
+	{"OIDC_DISCOVERY_URL", kindString, "OIDC discovery URL (empty disables OIDC)"},
+	{"OIDC_AUDIENCE", kindString, "OIDC token audience (required with OIDC_DISCOVERY_URL)"},
+	{"OIDC_REQUIRED_SCOPES", kindList, "scopes every OIDC token must have"},
+	{"OIDC_SCOPE_PREFIX", kindString, "prefix of operation scopes in OIDC tokens (default signer:)"},
+	{"POLICY_FILE", kindString, "authorization policy file"},
+	{"METADATA_SCHEMA_FILE", kindString, "metadata schema file"},
+	{"ADMIN_API_KEY", kindString, "key for the /admin/v1 endpoints (prefer the environment)"},
+	{"WEB_UI", kindBool, "serve the operator web UI at /ui"},
+	{"TENANT_STORE", kindString, "tenant store: off, memory or file"},
+	{"TENANT_STORE_FILE", kindString, "tenant store file"},
+	{"API_KEY_STORE", kindString, "managed API key store: off, memory or file"},
+	{"API_KEY_STORE_FILE", kindString, "managed API key store file"},
+	{"CATALOG_STORE", kindString, "catalog of confirmed objects: off, memory or postgres"},
+	{"CATALOG_DATABASE_URL", kindString, "catalog Postgres connection URL (prefer the environment)"},
+	{"CATALOG_DEDUPLICATE", kindBool, "answer v2 uploads whose checksum_sha256 matches a confirmed object with its key instead of a URL"},
+	{"AUDIT_SINK", kindString, "URL issuance audit event sink: off, http, syslog or cloudwatch"},
+	{"AUDIT_HTTP_URL", kindString, "collector URL audit events are posted to as NDJSON"},
+	{"AUDIT_HTTP_AUTHORIZATION", kindString, "Authorization header for the audit collector (prefer the environment)"},
+	{"AUDIT_SYSLOG_ADDRESS", kindString, "syslog address for audit events (udp://host:514 or tcp://host:514)"},
+	{"AUDIT_CLOUDWATCH_LOG_GROUP", kindString, "existing CloudWatch Logs group for audit events"},
+	{"AUDIT_CLOUDWATCH_LOG_STREAM", kindString, "CloudWatch Logs stream for audit events (default signer-service)"},
+	{"AUDIT_BATCH_SIZE", kindInt, "audit events sent per batch (default 100)"},
+	{"AUDIT_FLUSH_INTERVAL_SECONDS", kindInt, "maximum seconds audit events wait before being sent (default 5)"},
+	{"URL_ENCRYPTION", kindString, "encryption of presigned URLs in responses: off, jwe or kms"},
+	{"URL_ENCRYPTION_PUBLIC_KEY_FILE", kindString, "PEM RSA public key of the recipient of jwe-encrypted URLs"},
+	{"URL_ENCRYPTION_KMS_KEY_ID", kindString, "KMS key ID, ARN or alias kms-encrypted URLs are encrypted with"},
+	{"CLIENT_ENCRYPTION_KMS_KEY_ID", kindString, "KMS key ID, ARN or alias client-side encryption data keys are generated under"},
+	{"METRICS_SINK", kindString, "metrics backend: prometheus (/metrics), statsd or dogstatsd"},
+	{"STATSD_ADDRESS", kindString, "statsd daemon as host:port or unix:///path (default 127.0.0.1:8125)"},
+	{"STATSD_PREFIX", kindString, "prefix of statsd metric names (default signer_service)"},
+	{"STATSD_TAGS", kindList, "DogStatsD tags added to every metric, e.g. env:prod,service:signer"},
+	{"CLOUDWATCH_LOG_GROUP", kindString, "existing CloudWatch Logs group to ship logs and EMF metrics to"},
+	{"CLOUDWATCH_LOG_STREAM", kindString, "CloudWatch Logs stream for logs and metrics (default signer-service)"},
+	{"CLOUDWATCH_SHIP_LOGS", kindBool, "ship structured logs to CLOUDWATCH_LOG_GROUP (default true)"},
+	{"CLOUDWATCH_METRICS_NAMESPACE", kindString, "CloudWatch namespace of EMF metrics (default SignerService)"},
+	{"CLOUDWATCH_METRICS_INTERVAL_SECONDS", kindInt, "EMF metrics interval (default 60, 0 disables)"},
+	{"MIN_RETENTION_HOURS", kindInt, "minimum object age in hours before deletes are allowed (0 disables)"},
+	{"SOFT_DELETE", kindBool, "move deleted objects to the trash instead of deleting them"},
+	{"TRASH_RETENTION_DAYS", kindInt, "days before trashed objects are purged (default 30, 0 keeps them)"},
+	{"READ_ONLY", kindBool, "start in read-only mode: downloads and searches work, writes answer 503"},
+	{"MAINTENANCE_WINDOWS", kindString, "semicolon-separated cron windows during which writes answer 503, e.g. '* 2-3 * * SUN'"},
+	{"MAINTENANCE_TIMEZONE", kindString, "IANA time zone of MAINTENANCE_WINDOWS (default UTC)"},
+	{"MULTIPART_CLEANUP_INTERVAL_MINUTES", kindInt, "incomplete multipart upload cleanup interval (0 disables)"},
+	{"MULTIPART_CLEANUP_MAX_AGE_HOURS", kindInt, "age after which incomplete multipart uploads are aborted (default 24)"},
+	{"PREFIX_USAGE_INTERVAL_MINUTES", kindInt, "prefix usage collection interval (0 disables)"},
+	{"TRASH_PURGE_INTERVAL_MINUTES", kindInt, "trash purge interval with SOFT_DELETE (default 60, 0 disables)"},
+	{"CLOCK_DRIFT_CHECK_INTERVAL_SECONDS", kindInt, "interval of clock drift checks against S3 (default 300, 0 disables)"},
+	{"CLOCK_DRIFT_THRESHOLD_SECONDS", kindInt, "clock drift past which readiness reports warn (default 30)"},
+	{"EXPECTED_BACKUPS", kindList, "uploads expected under the company prefix as filename=hours, e.g. db-*.dump.gz=26"},
+	{"STALE_BACKUP_CHECK_INTERVAL_MINUTES", kindInt, "interval of stale backup checks (default 15, 0 disables)"},
+	{"STALE_BACKUP_WEBHOOK_URL", kindString, "URL notified when an expected backup becomes stale or recovers"},
+	{"RESTORE_DRILL_INTERVAL_MINUTES", kindInt, "interval of scheduled restore drills (0 disables)"},
+	{"RESTORE_DRILL_MAX_AGE_HOURS", kindInt, "restore drills pick uploads modified within this many hours (default 168)"},
+	{"RESTORE_DRILL_MAX_BYTES", kindInt, "largest object a restore drill downloads (default 1073741824, 0 for any size)"},
+	{"RESTORE_DRILL_HISTORY", kindInt, "restore drills kept in memory (default 500)"},
+	{"ACCESS_LOG_BUCKET", kindString, "bucket S3 delivers server access logs to (default S3_BUCKET_NAME)"},
+	{"ACCESS_LOG_PREFIX", kindString, "key prefix of S3 server access logs to match to issued URLs (empty disables)"},
+	{"ACCESS_LOG_INTERVAL_MINUTES", kindInt, "interval of access log ingestion (default 15)"},
+	{"ACCESS_LOG_RETENTION_HOURS", kindInt, "hours URL usage and expired issued URLs are kept (default 72)"},
+	{"ANOMALY_DETECTION", kindBool, "flag issuance rate spikes, new client IPs and off-hours delete URLs"},
+	{"ANOMALY_SPIKE_FACTOR", kindInt, "hourly URLs above this multiple of a caller's usual rate are a spike (default 10)"},
+	{"ANOMALY_SPIKE_MIN_URLS", kindInt, "fewest URLs in an hour flagged as a spike (default 50)"},
+	{"ANOMALY_BUSINESS_HOURS", kindString, "semicolon-separated cron windows delete URLs are expected in, e.g. '* 8-18 * * MON-FRI'"},
+	{"ANOMALY_TIMEZONE", kindString, "IANA time zone of ANOMALY_BUSINESS_HOURS (default UTC)"},
+	{"ANOMALY_WEBHOOK_URL", kindString, "URL anomalies are posted to"},
+	{"ANOMALY_WEBHOOK_SECRET", kindString, "HMAC secret signing anomaly webhooks"},
+	{"STALE_BACKUP_WEBHOOK_SECRET", kindString, "HMAC-SHA256 key signing stale backup webhooks (prefer the environment)"},
+	{"LIST_CACHE_TTL_SECONDS", kindInt, "seconds search and browse listings are cached (0 disables)"},
+	{"AWS_INIT_MAX_ATTEMPTS", kindInt, "attempts to initialize the AWS client at startup (default 5)"},
+	{"AWS_INIT_DEGRADED_START", kindBool, "serve not-ready and keep retrying when AWS initialization fails at startup"},
+	{"S3_RETRY_MAX_ATTEMPTS", kindInt, "attempts per S3 call (default 3)"},
+	{"S3_RETRY_BASE_DELAY_MS", kindInt, "first retry delay (default 100)"},
+	{"S3_RETRY_MAX_DELAY_MS", kindInt, "maximum retry delay (default 2000)"},
+	{"S3_BREAKER_FAILURE_THRESHOLD", kindInt, "consecutive failures that open the circuit (default 5, 0 disables)"},
+	{"S3_BREAKER_COOLDOWN_SECONDS", kindInt, "circuit breaker cooldown (default 30)"},
+	{"S3_CALL_BUDGET_PER_MINUTE", kindInt, "S3 API calls allowed per minute (0 unlimited)"},
+	{"S3_CALL_BUDGET_MODE", kindString, "calls over the budget: queue (wait for the next minute) or reject"},
+	{"S3_CALL_BUDGET_MAX_WAIT_SECONDS", kindInt, "longest a queued call waits for the budget (default 5)"},
+}
+
+// Flags holds the command-line flags defined for every config value
+type Flags struct {
+	fs *pflag.FlagSet
+}
+
+// RegisterFlags defines a flag for every config value on fs, named after its
+// environment variable in kebab case (AWS_REGION becomes --aws-region)
+func RegisterFlags(fs *pflag.FlagSet) *Flags {
+	for _, s := range settings {
+		name := flagName(s.key)
+		switch s.kind {
+		case kindInt:
+			fs.Int(name, 0, s.usage)
+		case kindBool:
+			fs.Bool(name, false, s.usage)
+		case kindFloat:
+			fs.Float64(name, 0, s.usage)
+		default:
+			fs.String(name, "", s.usage)
+		}
+	}
+	return &Flags{fs: fs}
+}
+
+// Values returns the flags set on the command line keyed by environment
+// variable name, in the form LoadConfig reads from the environment
+func (f *Flags) Values() map[string]string {
+	values := make(map[string]string)
+	if f == nil {
+		return values
+	}
+	for _, s := range settings {
+		flag := f.fs.Lookup(flagName(s.key))
+		if flag != nil && flag.Changed {
+			values[s.key] = flag.Value.String()
+		}
+	}
+	return values
+}
+
+// flagName converts an environment variable name to its flag name
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
@@ -0,0 +1,252 @@
+// Package cronwindow describes time windows with cron-like expressions. A
+// time is inside a window when the minute it falls in matches the
+// expression, so "* 1-4 * * *" is open from 01:00 to 04:59 every day and
+// "* 22-23 * * MON-FRI" from 22:00 to 23:59 on weekdays.
+package cronwindow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is the range and optional names of one expression field
+type field struct {
+	name     string
+	min, max int
+	names    []string // Index i names value min+i
+}
+
+var fields = [5]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// Expression is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week. Fields accept *, values, ranges (a-b),
+// steps (*/n, a-b/n) and comma-separated lists; months and weekdays also
+// accept three-letter names. As in cron, when both day fields are
+// restricted a day matches if either does.
+type Expression struct {
+	text string
+	sets [5]uint64 // Bit v is set when value v matches
+	// domAny and dowAny are set when the day fields are *
+	domAny, dowAny bool
+}
+
+// Parse parses a five-field cron expression
+func Parse(expr string) (*Expression, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	e := &Expression{text: strings.Join(parts, " ")}
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		e.sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if e.sets[4]&(1<<7) != 0 {
+		e.sets[4] |= 1
+	}
+	e.domAny = parts[2] == "*"
+	e.dowAny = parts[4] == "*"
+	return e, nil
+}
+
+// parseField returns the set of values matched by a comma-separated list
+func parseField(s string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(s, ",") {
+		rangePart, step := item, 1
+		if before, after, ok := strings.Cut(item, "/"); ok {
+			n, err := strconv.Atoi(after)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", after, f.name)
+			}
+			rangePart, step = before, n
+		}
+
+		lo, hi := f.min, f.max
+		if rangePart != "*" {
+			var err error
+			from, to, isRange := strings.Cut(rangePart, "-")
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value parses a number or name within the field's range
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s must be between %d and %d (got %q)", f.name, f.min, f.max, s)
+	}
+	return v, nil
+}
+
+// String returns the expression as parsed
+func (e *Expression) String() string {
+	return e.text
+}
+
+// Matches reports whether the minute containing t matches the expression,
+// in t's location
+func (e *Expression) Matches(t time.Time) bool {
+	return e.dayMatches(t) && has(e.sets[1], t.Hour()) && has(e.sets[0], t.Minute())
+}
+
+// dayMatches reports whether t's day matches the month and day fields
+func (e *Expression) dayMatches(t time.Time) bool {
+	if !has(e.sets[3], int(t.Month())) {
+		return false
+	}
+	dom, dow := has(e.sets[2], t.Day()), has(e.sets[4], int(t.Weekday()))
+	switch {
+	case e.domAny || e.dowAny:
+		return dom && dow
+	default:
+		return dom || dow
+	}
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
+
+// Schedule is a set of windows evaluated in one time zone. It is open when
+// any of its expressions matches.
+type Schedule struct {
+	expressions []*Expression
+	location    *time.Location
+}
+
+// NewSchedule parses the expressions of a schedule in the given IANA time
+// zone, UTC when empty
+func NewSchedule(expressions []string, timezone string) (*Schedule, error) {
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q", timezone)
+		}
+	}
+
+	s := &Schedule{location: location}
+	for _, expr := range expressions {
+		e, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		s.expressions = append(s.expressions, e)
+	}
+	return s, nil
+}
+
+// Open reports whether t falls inside one of the schedule's windows. A
+// schedule without expressions is always open.
+func (s *Schedule) Open(t time.Time) bool {
+	if len(s.expressions) == 0 {
+		return true
+	}
+	t = t.In(s.location)
+	for _, e := range s.expressions {
+		if e.Matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSearch bounds the search for the next window; every expression that
+// can match at all matches within a leap year cycle's worth of days
+const maxSearch = 4 * 366 * 24 * time.Hour
+
+// NextOpen returns the start of the first minute after t inside a window,
+// or false when no window opens within four years
+func (s *Schedule) NextOpen(t time.Time) (time.Time, bool) {
+	if len(s.expressions) == 0 {
+		return t, true
+	}
+	var next time.Time
+	for _, e := range s.expressions {
+		if candidate, ok := e.next(t.In(s.location)); ok && (next.IsZero() || candidate.Before(next)) {
+			next = candidate
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// NextClose returns the start of the first minute after t outside every
+// window, or false when the schedule has no windows or stays open for four
+// years
+func (s *Schedule) NextClose(t time.Time) (time.Time, bool) {
+	if len(s.expressions) == 0 {
+		return time.Time{}, false
+	}
+	limit := t.Add(maxSearch)
+	for t = t.Truncate(time.Minute).Add(time.Minute); t.Before(limit); t = t.Add(time.Minute) {
+		if !s.Open(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// next returns the start of the first matching minute after t, skipping
+// whole days and hours that don't match
+func (e *Expression) next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	limit := t.Add(maxSearch)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	for t.Before(limit) {
+		var advanced time.Time
+		switch {
+		case !e.dayMatches(t):
+			advanced = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(e.sets[1], t.Hour()):
+			advanced = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(e.sets[0], t.Minute()):
+			advanced = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t, true
+		}
+		// Wall clock arithmetic can step back when clocks fall back
+		if !advanced.After(t) {
+			advanced = t.Add(time.Minute)
+		}
+		t = advanced
+	}
+	return time.Time{}, false
+}
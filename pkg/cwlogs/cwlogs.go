@@ -0,0 +1,121 @@
+// Package cwlogs writes to CloudWatch Logs without the SDK's logs client: a
+// signed JSON API client, a background shipper batching log events, and an
+// emitter of metrics in the embedded metric format (EMF), which CloudWatch
+// extracts into metrics from the log events themselves.
+package cwlogs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Event is a CloudWatch Logs event
+type Event struct {
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+	Message   string `json:"message"`
+}
+
+// Client puts events in one log stream, creating the stream on the first put
+type Client struct {
+	group, stream string
+	region        string
+	credentials   aws.CredentialsProvider
+	endpoint      string
+	signer        *v4.Signer
+	client        *http.Client
+
+	mu      sync.Mutex
+	created bool
+}
+
+// NewClient creates a client for stream in the existing log group group,
+// signing requests with credentials retrieved from the given provider. The
+// credentials need logs:CreateLogStream and logs:PutLogEvents on the group.
+func NewClient(group, stream, region string, credentials aws.CredentialsProvider) *Client {
+	return &Client{
+		group:       group,
+		stream:      stream,
+		region:      region,
+		credentials: credentials,
+		endpoint:    fmt.Sprintf("https://logs.%s.amazonaws.com/", region),
+		signer:      v4.NewSigner(),
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetEndpoint sends requests to endpoint, such as localstack's, instead of
+// the regional CloudWatch Logs endpoint
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// PutLogEvents writes events in chronological order, as the API requires
+func (c *Client) PutLogEvents(ctx context.Context, events []Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.created {
+		err := c.call(ctx, "CreateLogStream", map[string]string{"logGroupName": c.group, "logStreamName": c.stream})
+		if err != nil && !strings.Contains(err.Error(), "ResourceAlreadyExistsException") {
+			return err
+		}
+		c.created = true
+	}
+
+	sorted := slices.Clone(events)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+	return c.call(ctx, "PutLogEvents", map[string]any{
+		"logGroupName":  c.group,
+		"logStreamName": c.stream,
+		"logEvents":     sorted,
+	})
+}
+
+// call invokes a CloudWatch Logs JSON API action
+func (c *Client) call(ctx context.Context, action string, input any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "logs", c.region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("CloudWatch Logs %s returned %d: %s", action, resp.StatusCode, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,133 @@
+package cwlogs
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+)
+
+// maxEMFMetrics is the number of metrics CloudWatch extracts from one
+// document
+const maxEMFMetrics = 100
+
+// MetricsEmitter renders a metrics registry in the embedded metric format.
+// Each label set becomes a set of dimensions; counters are emitted as their
+// increase since the previous call, gauges as their current value.
+type MetricsEmitter struct {
+	registry  *metrics.Registry
+	namespace string
+
+	mu       sync.Mutex
+	previous map[string]float64 // Counter values at the previous call
+}
+
+// NewMetricsEmitter creates an emitter of registry's metrics in namespace
+func NewMetricsEmitter(registry *metrics.Registry, namespace string) *MetricsEmitter {
+	return &MetricsEmitter{registry: registry, namespace: namespace, previous: make(map[string]float64)}
+}
+
+// emfMetric is a metric definition of an EMF document
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfDirective tells CloudWatch which fields of a document are metrics
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+// Documents returns the EMF documents, as JSON log messages, of every series
+// at now
+func (e *MetricsEmitter) Documents(now time.Time) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Group series by label set: each group is a document
+	type group struct {
+		labels  metrics.Labels
+		metrics []emfMetric
+		values  map[string]float64
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for _, sample := range e.registry.Snapshot() {
+		key := labelSetKey(sample.Labels)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{labels: sample.Labels, values: make(map[string]float64)}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+
+		value, unit := sample.Value, "None"
+		if sample.Kind == "counter" {
+			seriesKey := sample.Name + key
+			value, unit = sample.Value-e.previous[seriesKey], "Count"
+			e.previous[seriesKey] = sample.Value
+		}
+		g.metrics = append(g.metrics, emfMetric{Name: sample.Name, Unit: unit})
+		g.values[sample.Name] = value
+	}
+	sort.Strings(keys)
+
+	var documents []string
+	for _, key := range keys {
+		g := groups[key]
+		dimensions := make([]string, 0, len(g.labels))
+		for name := range g.labels {
+			dimensions = append(dimensions, name)
+		}
+		sort.Strings(dimensions)
+
+		for start := 0; start < len(g.metrics); start += maxEMFMetrics {
+			batch := g.metrics[start:min(start+maxEMFMetrics, len(g.metrics))]
+			document := map[string]any{
+				"_aws": map[string]any{
+					"Timestamp": now.UnixMilli(),
+					"CloudWatchMetrics": []emfDirective{{
+						Namespace:  e.namespace,
+						Dimensions: [][]string{dimensions},
+						Metrics:    batch,
+					}},
+				},
+			}
+			for name, value := range g.labels {
+				document[name] = value
+			}
+			for _, metric := range batch {
+				document[metric.Name] = g.values[metric.Name]
+			}
+			data, err := json.Marshal(document)
+			if err != nil {
+				continue
+			}
+			documents = append(documents, string(data))
+		}
+	}
+	return documents
+}
+
+// labelSetKey identifies a label set
+func labelSetKey(labels metrics.Labels) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(labels[name])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
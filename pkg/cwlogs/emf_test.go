@@ -0,0 +1,121 @@
+package cwlogs_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cwlogs"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+)
+
+// emfDocument is the part of an EMF document CloudWatch reads
+type emfDocument struct {
+	AWS struct {
+		Timestamp         int64 `json:"Timestamp"`
+		CloudWatchMetrics []struct {
+			Namespace  string     `json:"Namespace"`
+			Dimensions [][]string `json:"Dimensions"`
+			Metrics    []struct {
+				Name string `json:"Name"`
+				Unit string `json:"Unit"`
+			} `json:"Metrics"`
+		} `json:"CloudWatchMetrics"`
+	} `json:"_aws"`
+}
+
+// parseDocuments decodes documents into their directives and all their
+// fields
+func parseDocuments(t *testing.T, documents []string) ([]emfDocument, []map[string]any) {
+	t.Helper()
+	parsed := make([]emfDocument, len(documents))
+	fields := make([]map[string]any, len(documents))
+	for i, document := range documents {
+		if err := json.Unmarshal([]byte(document), &parsed[i]); err != nil {
+			t.Fatalf("document %d is not JSON: %v\n%s", i, err, document)
+		}
+		if err := json.Unmarshal([]byte(document), &fields[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return parsed, fields
+}
+
+func TestMetricsEmitterDocuments(t *testing.T) {
+	registry := metrics.NewRegistry()
+	presign := metrics.Labels{"operation": "download", "bucket": "backups"}
+	registry.AddCounter("presign_requests", presign, 3)
+	registry.SetGauge("presign_latency", presign, 0.25)
+	registry.SetGauge("queue_depth", nil, 7)
+
+	emitter := cwlogs.NewMetricsEmitter(registry, "SignerService")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	documents, fields := parseDocuments(t, emitter.Documents(now))
+
+	// One document per label set, the empty set first
+	if len(documents) != 2 {
+		t.Fatalf("documents = %d, want 2", len(documents))
+	}
+	for i, document := range documents {
+		if document.AWS.Timestamp != now.UnixMilli() {
+			t.Errorf("document %d: Timestamp = %d, want %d", i, document.AWS.Timestamp, now.UnixMilli())
+		}
+		if len(document.AWS.CloudWatchMetrics) != 1 || document.AWS.CloudWatchMetrics[0].Namespace != "SignerService" {
+			t.Errorf("document %d: CloudWatchMetrics = %+v, want one directive in SignerService", i, document.AWS.CloudWatchMetrics)
+		}
+	}
+
+	unlabeled := documents[0].AWS.CloudWatchMetrics[0]
+	if !reflect.DeepEqual(unlabeled.Dimensions, [][]string{{}}) {
+		t.Errorf("unlabeled Dimensions = %v, want one empty set", unlabeled.Dimensions)
+	}
+	if fields[0]["queue_depth"] != 7.0 {
+		t.Errorf("queue_depth = %v, want 7", fields[0]["queue_depth"])
+	}
+
+	labeled := documents[1].AWS.CloudWatchMetrics[0]
+	if !reflect.DeepEqual(labeled.Dimensions, [][]string{{"bucket", "operation"}}) {
+		t.Errorf("Dimensions = %v, want the sorted label names", labeled.Dimensions)
+	}
+	units := make(map[string]string)
+	for _, metric := range labeled.Metrics {
+		units[metric.Name] = metric.Unit
+	}
+	if want := map[string]string{"presign_requests": "Count", "presign_latency": "None"}; !reflect.DeepEqual(units, want) {
+		t.Errorf("metric units = %v, want %v", units, want)
+	}
+	// Dimension values and metric values are top-level fields
+	for name, want := range map[string]any{"bucket": "backups", "operation": "download", "presign_requests": 3.0, "presign_latency": 0.25} {
+		if fields[1][name] != want {
+			t.Errorf("%s = %v, want %v", name, fields[1][name], want)
+		}
+	}
+
+	// Counters report their increase since the previous call, gauges their
+	// value
+	registry.AddCounter("presign_requests", presign, 2)
+	_, fields = parseDocuments(t, emitter.Documents(now.Add(time.Minute)))
+	if fields[1]["presign_requests"] != 2.0 || fields[1]["presign_latency"] != 0.25 {
+		t.Errorf("second call: presign_requests = %v, presign_latency = %v; want 2 and 0.25",
+			fields[1]["presign_requests"], fields[1]["presign_latency"])
+	}
+}
+
+func TestMetricsEmitterSplitsLargeDocuments(t *testing.T) {
+	registry := metrics.NewRegistry()
+	for i := range 250 {
+		registry.SetGauge(fmt.Sprintf("gauge_%03d", i), metrics.Labels{"host": "db1"}, float64(i))
+	}
+	documents, _ := parseDocuments(t, cwlogs.NewMetricsEmitter(registry, "SignerService").Documents(time.Now()))
+
+	// CloudWatch extracts at most 100 metrics per document
+	var counts []int
+	for _, document := range documents {
+		counts = append(counts, len(document.AWS.CloudWatchMetrics[0].Metrics))
+	}
+	if !reflect.DeepEqual(counts, []int{100, 100, 50}) {
+		t.Errorf("metrics per document = %v, want [100 100 50]", counts)
+	}
+}
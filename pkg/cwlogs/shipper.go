@@ -0,0 +1,133 @@
+package cwlogs
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+)
+
+// PutLogEvents limits: events and bytes per batch, counting 26 bytes of
+// overhead per event
+const (
+	maxBatchEvents = 10000
+	maxBatchBytes  = 1048576
+	eventOverhead  = 26
+)
+
+// shipperQueueSize bounds the events waiting to be sent; later events are
+// dropped
+const shipperQueueSize = 20000
+
+// Shipper sends log events to a Client in the background, in batches sent
+// every interval or when full
+type Shipper struct {
+	client   *Client
+	interval time.Duration
+	retry    resilience.RetryPolicy
+
+	mu     sync.RWMutex // Guards closing events
+	closed bool
+	events chan Event
+	done   chan struct{}
+}
+
+// NewShipper creates a shipper flushing to client at least every interval.
+// Call Start to begin sending and Close to flush on shutdown.
+func NewShipper(client *Client, interval time.Duration) *Shipper {
+	return &Shipper{
+		client:   client,
+		interval: interval,
+		retry:    resilience.RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second},
+		events:   make(chan Event, shipperQueueSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// Send queues a message logged at t without blocking. It is dropped when the
+// queue is full or the shipper is closed.
+func (s *Shipper) Send(t time.Time, message string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- Event{Timestamp: t.UnixMilli(), Message: message}:
+	default:
+	}
+}
+
+// Start sends queued events until Close is called
+func (s *Shipper) Start() {
+	go s.run()
+}
+
+// Close stops accepting events and sends the queued ones within ctx
+func (s *Shipper) Close(ctx context.Context) {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+	}
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var (
+		pending []Event
+		size    int
+	)
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				s.flush(pending)
+				return
+			}
+			eventSize := len(event.Message) + eventOverhead
+			if len(pending) == maxBatchEvents || size+eventSize > maxBatchBytes {
+				s.flush(pending)
+				pending, size = nil, 0
+			}
+			pending = append(pending, event)
+			size += eventSize
+			continue
+		case <-ticker.C:
+		}
+		s.flush(pending)
+		pending, size = nil, 0
+	}
+}
+
+// flush sends a batch, retrying failures with backoff. A batch that still
+// fails is dropped.
+func (s *Shipper) flush(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	retryable := func(error) bool { return true }
+	err := resilience.Retry(ctx, s.retry, retryable, nil, func(ctx context.Context) error {
+		return s.client.PutLogEvents(ctx, events)
+	})
+	if err != nil {
+		// Written to stderr directly: logging it would ship it too
+		log.New(os.Stderr, "", log.LstdFlags).Printf("Failed to ship %d log events to CloudWatch Logs: %v", len(events), err)
+	}
+}
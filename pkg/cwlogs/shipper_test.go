@@ -0,0 +1,152 @@
+package cwlogs_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cwlogs"
+)
+
+// fakeLogs is a CloudWatch Logs endpoint recording the batches put
+type fakeLogs struct {
+	mu      sync.Mutex
+	actions []string
+	batches [][]cwlogs.Event
+	put     chan struct{} // Signalled on every PutLogEvents
+}
+
+func (f *fakeLogs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+	if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/logs/aws4_request") {
+		http.Error(w, `{"__type":"UnrecognizedClientException"}`, http.StatusForbidden)
+		return
+	}
+	var input struct {
+		LogGroupName  string         `json:"logGroupName"`
+		LogStreamName string         `json:"logStreamName"`
+		LogEvents     []cwlogs.Event `json:"logEvents"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.LogGroupName != "signer" || input.LogStreamName != "host-1" {
+		http.Error(w, `{"__type":"InvalidParameterException"}`, http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.actions = append(f.actions, action)
+	if action == "PutLogEvents" {
+		f.batches = append(f.batches, input.LogEvents)
+	}
+	f.mu.Unlock()
+	if action == "PutLogEvents" {
+		f.put <- struct{}{}
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_, _ = w.Write([]byte("{}"))
+}
+
+// newShipper starts a shipper flushing every interval to a fake endpoint
+func newShipper(t *testing.T, interval time.Duration) (*cwlogs.Shipper, *fakeLogs) {
+	t.Helper()
+	fake := &fakeLogs{put: make(chan struct{}, 100)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := cwlogs.NewClient("signer", "host-1", "us-east-1", credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""))
+	client.SetEndpoint(server.URL)
+	shipper := cwlogs.NewShipper(client, interval)
+	shipper.Start()
+	return shipper, fake
+}
+
+// closeShipper closes shipper, waiting for its queued events to reach the
+// fake
+func closeShipper(t *testing.T, shipper *cwlogs.Shipper) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	shipper.Close(ctx)
+}
+
+func TestShipperFlushesOnClose(t *testing.T) {
+	shipper, fake := newShipper(t, time.Hour)
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	shipper.Send(base.Add(time.Second), "second")
+	shipper.Send(base, "first")
+	closeShipper(t, shipper)
+
+	// The stream is created before the first put, and events are sorted
+	want := []string{"CreateLogStream", "PutLogEvents"}
+	if !reflect.DeepEqual(fake.actions, want) {
+		t.Errorf("actions = %v, want %v", fake.actions, want)
+	}
+	if len(fake.batches) != 1 || len(fake.batches[0]) != 2 {
+		t.Fatalf("batches = %v, want one of 2 events", fake.batches)
+	}
+	if first := fake.batches[0][0]; first.Message != "first" || first.Timestamp != base.UnixMilli() {
+		t.Errorf("first event = %+v, want the earliest", first)
+	}
+
+	// Events sent after Close are dropped
+	shipper.Send(base, "late")
+	if len(fake.batches) != 1 {
+		t.Errorf("batches after Close = %d, want 1", len(fake.batches))
+	}
+}
+
+func TestShipperFlushesEveryInterval(t *testing.T) {
+	shipper, fake := newShipper(t, 20*time.Millisecond)
+	defer closeShipper(t, shipper)
+
+	shipper.Send(time.Now(), "ticked")
+	select {
+	case <-fake.put:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no PutLogEvents before Close")
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.batches) != 1 || fake.batches[0][0].Message != "ticked" {
+		t.Errorf("batches = %v, want the one event", fake.batches)
+	}
+}
+
+func TestShipperBatching(t *testing.T) {
+	large := strings.Repeat("x", 100000)
+	tests := []struct {
+		name    string
+		count   int
+		message string
+		want    []int // Events per batch
+	}{
+		// 10 events of 100000 bytes plus overhead fit in 1 MiB, 11 don't
+		{"by size", 11, large, []int{10, 1}},
+		{"by count", 10001, "m", []int{10000, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shipper, fake := newShipper(t, time.Hour)
+			now := time.Now()
+			for range tc.count {
+				shipper.Send(now, tc.message)
+			}
+			closeShipper(t, shipper)
+
+			var got []int
+			for _, batch := range fake.batches {
+				got = append(got, len(batch))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("events per batch = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
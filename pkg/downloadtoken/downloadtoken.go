@@ -0,0 +1,92 @@
+// Package downloadtoken issues download tokens: opaque links that redirect
+// to a freshly presigned download URL and can be redeemed a limited number of
+// times, giving one-time-use semantics raw presigned URLs can't offer.
+package downloadtoken
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+var (
+	// ErrNotFound is returned when a token is unknown
+	ErrNotFound = errors.New("download token not found")
+	// ErrExpired is returned when redeeming a token past its expiry
+	ErrExpired = errors.New("download token has expired")
+	// ErrExhausted is returned when a token was already redeemed as many
+	// times as allowed
+	ErrExhausted = errors.New("download token has already been used")
+)
+
+// Token grants downloads of one object
+type Token struct {
+	Token          string    `json:"token"`
+	ObjectKey      string    `json:"object_key"`
+	TenantID       string    `json:"tenant_id,omitempty"` // Tenant that issued it, whose credentials sign the download
+	MaxRedemptions int       `json:"max_redemptions"`
+	Redemptions    int       `json:"redemptions"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// Store keeps download tokens in memory. Redemption counts are only enforced
+// within one instance.
+type Store struct {
+	mu     sync.Mutex
+	tokens map[string]*Token
+}
+
+// NewStore creates an empty token store
+func NewStore() *Store {
+	return &Store{tokens: make(map[string]*Token)}
+}
+
+// Issue creates a token for objectKey on behalf of tenantID (empty for
+// callers that aren't tenants), redeemable maxRedemptions times within ttl.
+// Expired tokens are dropped.
+func (s *Store) Issue(objectKey, tenantID string, maxRedemptions int, ttl time.Duration) Token {
+	now := time.Now().UTC()
+	t := &Token{
+		Token:          idgen.New(),
+		ObjectKey:      objectKey,
+		TenantID:       tenantID,
+		MaxRedemptions: maxRedemptions,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.tokens {
+		if !now.Before(existing.ExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	s.tokens[t.Token] = t
+	return *t
+}
+
+// Redeem counts one use of a token and returns it, failing once it expired
+// or was used up
+func (s *Store) Redeem(token string) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok {
+		return Token{}, ErrNotFound
+	}
+	if !time.Now().Before(t.ExpiresAt) {
+		delete(s.tokens, token)
+		return Token{}, ErrExpired
+	}
+	if t.Redemptions >= t.MaxRedemptions {
+		return Token{}, ErrExhausted
+	}
+	t.Redemptions++
+	return *t, nil
+}
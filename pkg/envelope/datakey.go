@@ -0,0 +1,55 @@
+package envelope
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// DataKeyEncryptionContext is bound to every data key generated for
+// client-side encryption. Restores pass it to kms:Decrypt with the wrapped
+// key, and key policies may require it.
+var DataKeyEncryptionContext = map[string]string{"purpose": "backup-data-key"}
+
+// DataKey is an AES-256 key in the clear and wrapped by a KMS key
+type DataKey struct {
+	KeyID      string // ARN of the KMS key that wrapped it
+	Plaintext  []byte
+	Ciphertext []byte // The wrapped key, a KMS ciphertext blob
+}
+
+// DataKeyGenerator generates data keys for client-side encryption
+type DataKeyGenerator interface {
+	GenerateDataKey(ctx context.Context) (*DataKey, error)
+}
+
+// KMSDataKeys generates data keys with kms:GenerateDataKey
+type KMSDataKeys struct {
+	kmsClient
+	keyID string
+}
+
+// NewKMSDataKeys creates a generator for the KMS key keyID (ID, ARN or
+// alias), signing requests with credentials retrieved from the given
+// provider. The credentials need kms:GenerateDataKey on the key.
+func NewKMSDataKeys(keyID, region string, credentials aws.CredentialsProvider) *KMSDataKeys {
+	return &KMSDataKeys{kmsClient: newKMSClient(region, credentials), keyID: keyID}
+}
+
+// GenerateDataKey returns a new AES-256 data key
+func (k *KMSDataKeys) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	var out struct {
+		KeyId          string
+		Plaintext      []byte
+		CiphertextBlob []byte
+	}
+	err := k.call(ctx, "GenerateDataKey", map[string]any{
+		"KeyId":             k.keyID,
+		"KeySpec":           "AES_256",
+		"EncryptionContext": DataKeyEncryptionContext,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &DataKey{KeyID: out.KeyId, Plaintext: out.Plaintext, Ciphertext: out.CiphertextBlob}, nil
+}
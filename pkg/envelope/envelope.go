@@ -0,0 +1,15 @@
+// Package envelope encrypts presigned URLs for their recipient, so proxies,
+// gateways and logs relaying a response can't use them: as a compact JWE for
+// an RSA public key, or with an AWS KMS key the recipient may decrypt with.
+// It also generates KMS data keys for clients encrypting backups themselves.
+package envelope
+
+import "context"
+
+// Sealer encrypts a presigned URL for its recipient
+type Sealer interface {
+	// Seal returns the encrypted form of plaintext, safe to put in JSON
+	Seal(ctx context.Context, plaintext []byte) (string, error)
+	// Scheme names the encryption, jwe or kms
+	Scheme() string
+}
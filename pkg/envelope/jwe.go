@@ -0,0 +1,123 @@
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// minRSABits is the smallest recipient key accepted
+const minRSABits = 2048
+
+// JWESealer encrypts URLs as JWE compact serializations (RFC 7516) with
+// RSA-OAEP-256 key wrapping and A256GCM content encryption, which any JOSE
+// library can decrypt with the recipient's private key
+type JWESealer struct {
+	key   *rsa.PublicKey
+	keyID string
+}
+
+// NewJWESealer creates a sealer for the RSA public key in pemData, either a
+// PUBLIC KEY, RSA PUBLIC KEY or CERTIFICATE block
+func NewJWESealer(pemData []byte) (*JWESealer, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block found in the recipient public key")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			parsed = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q for the recipient public key", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("recipient public key must be RSA (got %T)", parsed)
+	}
+	if key.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("recipient public key must have at least %d bits (got %d)", minRSABits, key.N.BitLen())
+	}
+	return &JWESealer{key: key, keyID: thumbprint(key)}, nil
+}
+
+// Scheme returns jwe
+func (s *JWESealer) Scheme() string {
+	return "jwe"
+}
+
+// KeyID returns the RFC 7638 thumbprint of the recipient key, sent as the
+// JWE kid header
+func (s *JWESealer) KeyID() string {
+	return s.keyID
+}
+
+// Seal encrypts plaintext under a fresh content key
+func (s *JWESealer) Seal(_ context.Context, plaintext []byte) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RSA-OAEP-256", "enc": "A256GCM", "kid": s.keyID})
+	if err != nil {
+		return "", err
+	}
+	protected := b64(header)
+
+	cek := make([]byte, 32)
+	iv := make([]byte, 12)
+	if _, err := rand.Read(cek); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, s.key, cek, nil)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{protected, b64(encryptedKey), b64(iv), b64(ciphertext), b64(tag)}, "."), nil
+}
+
+// thumbprint returns the base64url SHA-256 JWK thumbprint of key
+func thumbprint(key *rsa.PublicKey) string {
+	jwk := fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, b64(big.NewInt(int64(key.E)).Bytes()), b64(key.N.Bytes()))
+	sum := sha256.Sum256([]byte(jwk))
+	return b64(sum[:])
+}
+
+// b64 is base64url without padding, as JOSE uses
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
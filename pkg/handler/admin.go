@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/iampolicy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// LogLevelRequest represents the request body for changing the log level
+type LogLevelRequest struct {
+	Level string `json:"level"` // debug, info, warn or error
+}
+
+// LogLevelResponse reports the active log level
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// requireAdmin wraps an admin handler, requiring the configured admin key in
+// X-Admin-Key, or an API key or OIDC token granted the admin scope. Admin
+// routes skip the authentication middleware, so this is their only
+// credential check.
+func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-Admin-Key"); key != "" {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(h.cfg.AdminAPIKey)) != 1 {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "invalid admin key")
+				return
+			}
+			principal := &Principal{Subject: "admin", Method: "adminkey"}
+			next(w, r.WithContext(withPrincipal(r.Context(), principal)))
+			return
+		}
+
+		principal := h.adminPrincipal(r)
+		if principal == nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized", "missing admin key or credential")
+			return
+		}
+		// Admin access is never implied by a credential without scopes
+		if principal.Scopes == nil || !h.principalAllows(principal, ScopeAdmin) {
+			h.respondWithInsufficientScope(w, r.WithContext(withPrincipal(r.Context(), principal)), ScopeAdmin)
+			return
+		}
+		next(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	}
+}
+
+// adminPrincipal authenticates an admin request with an API key or, failing
+// that, an OIDC access token
+func (h *Handler) adminPrincipal(r *http.Request) *Principal {
+	if principal, _ := resolveAPIKey(requestAPIKey(r), h.cfg.APIKeys, h.apiKeys, nil); principal != nil {
+		return principal
+	}
+
+	auth := r.Header.Get("Authorization")
+	if h.oidc == nil || !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+	claims, err := h.oidc.Verify(r.Context(), strings.TrimPrefix(auth, "Bearer "))
+	if err != nil {
+		return nil
+	}
+	return &Principal{Subject: claims.Subject, ClientID: claims.ClientID, Method: "oidc", Scopes: claims.Scopes}
+}
+
+// GetBucketStatus reports the bucket's encryption, versioning, public access
+// block, policy status and lifecycle rules
+func (h *Handler) GetBucketStatus(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.s3Service.BucketStatus(r.Context()))
+}
+
+// GetIAMPolicy returns the minimal IAM policy the service's credentials need
+// for the running configuration
+func (h *Handler) GetIAMPolicy(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, iampolicy.Generate(h.cfg))
+}
+
+// GetLogLevel returns the active log level
+func (h *Handler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, LogLevelResponse{Level: logging.GetLevel().String()})
+}
+
+// SetLogLevel changes the log level until the next change or restart. debug
+// also logs the signing inputs of every presigned URL.
+func (h *Handler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid log level", err.Error())
+		return
+	}
+
+	previous := logging.GetLevel()
+	logging.SetLevel(level)
+	if previous != level {
+		subject := ""
+		if p, ok := PrincipalFromContext(r.Context()); ok {
+			subject = p.Subject
+		}
+		// Logged at error level so the change is recorded at any level
+		logging.Errorf("Log level changed from %s to %s by %s", previous, level, subject)
+	}
+
+	respondWithJSON(w, http.StatusOK, LogLevelResponse{Level: level.String()})
+}
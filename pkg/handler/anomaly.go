@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/anomaly"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/webhook"
+)
+
+// EventIssuanceAnomaly is the webhook event type of a flagged issuance
+const EventIssuanceAnomaly = "issuance.anomaly"
+
+// defaultAnomalyList is how many anomalies are listed without a limit
+const defaultAnomalyList = 100
+
+// AnomaliesResponse lists recent issuance anomalies
+type AnomaliesResponse struct {
+	Anomalies []anomaly.Anomaly `json:"anomalies"`
+}
+
+// detectAnomalies passes an issued URL to the anomaly detector, when enabled,
+// and reports what it flags in the log, metrics and webhook
+func (h *Handler) detectAnomalies(r *http.Request, presigned *service.PresignedURL) {
+	if h.anomalies == nil {
+		return
+	}
+	issuance := anomaly.Issuance{
+		Time:      time.Now(),
+		ClientIP:  clientIP(r),
+		Method:    presigned.Method,
+		ObjectKey: presigned.ObjectKey,
+	}
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		issuance.Subject = p.Subject
+	}
+	if t, ok := TenantFromContext(r.Context()); ok {
+		issuance.TenantID = t.ID
+	}
+
+	for _, a := range h.anomalies.Observe(issuance) {
+		logging.Warnf("Issuance anomaly %s for %s/%s from %s: %s", a.Type, a.TenantID, a.Subject, a.ClientIP, a.Detail)
+		h.metrics.IncCounter("issuance_anomalies_total", metrics.Labels{"type": a.Type})
+		if h.alerts != nil {
+			event := webhook.Event{Type: EventIssuanceAnomaly, Time: a.Time, Data: a}
+			// Delivered in the background so alerts never delay signing
+			go func() {
+				if err := h.alerts.Send(context.Background(), event); err != nil {
+					logging.Errorf("Failed to send %s webhook: %v", event.Type, err)
+				}
+			}()
+		}
+	}
+}
+
+// ListAnomalies returns the most recent issuance anomalies, newest first
+func (h *Handler) ListAnomalies(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAnomalyList
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > anomaly.MaxAnomalies {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 1000", v)
+			return
+		}
+		limit = n
+	}
+	respondWithJSON(w, http.StatusOK, AnomaliesResponse{Anomalies: h.anomalies.Recent(limit)})
+}
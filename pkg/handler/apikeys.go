@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/apikey"
+)
+
+// CreateAPIKeyRequest represents the request body for issuing an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"` // upload, download, delete and/or admin
+}
+
+// RotateAPIKeyRequest represents the optional request body for rotating an
+// API key
+type RotateAPIKeyRequest struct {
+	GracePeriodSeconds int `json:"grace_period_seconds,omitempty"` // How long the old secret stays valid
+}
+
+// APIKeyResponse describes a managed API key. APIKey holds the plaintext and
+// is only returned when the key is created or rotated.
+type APIKeyResponse struct {
+	KeyID             string     `json:"key_id"`
+	Name              string     `json:"name"`
+	Masked            string     `json:"masked"`
+	Scopes            []string   `json:"scopes,omitempty"`
+	APIKey            string     `json:"api_key,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// maxRotationGrace bounds how long a rotated secret may stay valid
+const maxRotationGrace = 7 * 24 * time.Hour
+
+// WithAPIKeyStore authenticates requests with keys managed through the admin
+// API, in addition to the static API_KEYS
+func WithAPIKeyStore(store *apikey.Store) Option {
+	return func(h *Handler) {
+		h.apiKeys = store
+	}
+}
+
+// ListAPIKeys returns all managed keys, masked, with their last use
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys := h.apiKeys.List()
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, apiKeyResponse(key, ""))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateAPIKey issues a new API key limited to the requested scopes
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required", "")
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "scopes is required", "grant upload, download, delete and/or admin")
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !knownScopes[scope] {
+			respondWithError(w, http.StatusBadRequest, "Unknown scope", scope)
+			return
+		}
+	}
+
+	key, plain, err := h.apiKeys.Create(req.Name, req.Scopes)
+	if err != nil {
+		respondWithAPIKeyError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, apiKeyResponse(key, plain))
+}
+
+// RotateAPIKey replaces a key's secret and returns the new plaintext
+func (h *Handler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req RotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	grace := time.Duration(req.GracePeriodSeconds) * time.Second
+	if grace < 0 || grace > maxRotationGrace {
+		respondWithError(w, http.StatusBadRequest, "grace_period_seconds must be between 0 and 604800", "")
+		return
+	}
+
+	key, plain, err := h.apiKeys.Rotate(mux.Vars(r)["id"], grace)
+	if err != nil {
+		respondWithAPIKeyError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiKeyResponse(key, plain))
+}
+
+// RevokeAPIKey invalidates a key immediately
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.apiKeys.Revoke(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAPIKeyError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiKeyResponse(key, ""))
+}
+
+// apiKeyResponse describes key without its hashes
+func apiKeyResponse(key apikey.Key, plain string) APIKeyResponse {
+	return APIKeyResponse{
+		KeyID:             key.ID,
+		Name:              key.Name,
+		Masked:            key.Masked,
+		Scopes:            key.Scopes,
+		APIKey:            plain,
+		CreatedAt:         key.CreatedAt,
+		RotatedAt:         key.RotatedAt,
+		RevokedAt:         key.RevokedAt,
+		LastUsedAt:        key.LastUsedAt,
+		PreviousExpiresAt: key.PreviousExpiresAt,
+	}
+}
+
+// respondWithAPIKeyError maps API key store errors to HTTP responses
+func respondWithAPIKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "API key not found", "")
+	case errors.Is(err, apikey.ErrRevoked):
+		respondWithError(w, http.StatusConflict, "API key is revoked", "")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "API key store error", err.Error())
+	}
+}
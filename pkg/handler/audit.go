@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/audit"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// WithAudit forwards an audit event for every issued URL to forwarder
+func WithAudit(forwarder *audit.Forwarder) Option {
+	return func(h *Handler) {
+		h.audit = forwarder
+	}
+}
+
+// publishIssued forwards the issuance of presigned when auditing is enabled
+func (h *Handler) publishIssued(r *http.Request, presigned *service.PresignedURL, uploadID string) {
+	if h.audit == nil {
+		return
+	}
+	event := audit.Event{
+		Time:      time.Now().UTC(),
+		Method:    presigned.Method,
+		ObjectKey: presigned.ObjectKey,
+		ExpiresAt: presigned.ExpiresAt,
+		UploadID:  uploadID,
+		ClientIP:  clientIP(r),
+		UserAgent: r.UserAgent(),
+		Endpoint:  r.URL.Path,
+	}
+	if notBefore := presigned.NotBefore; !notBefore.IsZero() {
+		event.NotBefore = &notBefore
+	}
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		event.Subject = p.Subject
+	}
+	if t, ok := TenantFromContext(r.Context()); ok {
+		event.TenantID = t.ID
+	}
+	if requestID := r.Header.Get(requestIDHeader); requestIDPattern.MatchString(requestID) {
+		event.RequestID = requestID
+	}
+	h.audit.Publish(event)
+}
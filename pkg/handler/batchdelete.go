@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// BatchDeleteRequest represents the request body for deleting objects in a
+// batch
+type BatchDeleteRequest struct {
+	ObjectKeys []string `json:"object_keys"`
+}
+
+// BatchDeleteResponse reports the outcome of a batch delete per key, in
+// request order
+type BatchDeleteResponse struct {
+	Deleted int                    `json:"deleted"`
+	Failed  int                    `json:"failed"`
+	Results []service.DeleteResult `json:"results"`
+}
+
+// DeleteObjects deletes up to 1000 objects server-side with S3
+// DeleteObjects. Keys outside the prefix, denied by policy or within their
+// minimum retention are reported as failed without being sent to S3, and the
+// rest are deleted even if some fail.
+func (h *Handler) DeleteObjects(w http.ResponseWriter, r *http.Request) {
+	// Server-side deletes would bypass the trash like presigned DELETEs
+	if h.cfg.SoftDelete {
+		respondWithCodedError(w, http.StatusForbidden, CodeOperationNotAllowed, "Deletes go through the trash", "use POST /api/v1/trash")
+		return
+	}
+
+	var req BatchDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if len(req.ObjectKeys) == 0 || len(req.ObjectKeys) > service.MaxDeleteObjects {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("object_keys must list 1 to %d keys", service.MaxDeleteObjects), "")
+		return
+	}
+
+	svc := h.service(r)
+	results := make([]service.DeleteResult, 0, len(req.ObjectKeys))
+	var deletable []string
+	seen := make(map[string]bool, len(req.ObjectKeys))
+	for _, key := range req.ObjectKeys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		result := h.checkBatchDelete(r, svc, key)
+		if result.Code == "" {
+			deletable = append(deletable, key)
+		}
+		results = append(results, result)
+	}
+
+	if len(deletable) > 0 {
+		deleted, err := svc.DeleteObjects(r.Context(), deletable)
+		if err != nil {
+			h.respondWithS3Error(w, "Failed to delete objects", err)
+			return
+		}
+		byKey := make(map[string]service.DeleteResult, len(deleted))
+		for _, result := range deleted {
+			byKey[result.ObjectKey] = result
+		}
+		for i, result := range results {
+			if result.Code == "" {
+				results[i] = byKey[result.ObjectKey]
+			}
+		}
+	}
+
+	response := BatchDeleteResponse{Results: results}
+	for _, result := range results {
+		if result.Deleted {
+			response.Deleted++
+		} else {
+			response.Failed++
+		}
+	}
+	subject := ""
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		subject = p.Subject
+	}
+	logging.Infof("Batch delete by %q: %d deleted, %d failed", subject, response.Deleted, response.Failed)
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// checkBatchDelete applies the checks of a single delete to key, returning
+// a result with the rejection's code, or without one when key may be sent
+// to S3
+func (h *Handler) checkBatchDelete(r *http.Request, svc *service.S3Service, key string) service.DeleteResult {
+	result := service.DeleteResult{ObjectKey: key}
+	reject := func(code, message string) service.DeleteResult {
+		result.Code, result.Error = code, message
+		return result
+	}
+
+	if key == "" {
+		return reject(CodeValidationFailed, "object key is empty")
+	}
+	if !svc.OwnsKey(key) {
+		return reject(CodeForbiddenKey, "object key is outside the company prefix")
+	}
+	if allowed, message := h.policyAllows(r, policy.Request{Operation: OperationDelete, ObjectKey: key}); !allowed {
+		return reject(CodePolicyDenied, message)
+	}
+
+	if h.minRetention(r) > 0 {
+		info, err := svc.HeadObject(r.Context(), key)
+		switch {
+		case errors.Is(err, service.ErrObjectNotFound):
+			// Deleting a missing key removes nothing; S3 reports it deleted
+		case err != nil:
+			return reject(service.S3ErrorCode(err), err.Error())
+		default:
+			if err := h.retentionError(r, info); err != nil {
+				return reject(CodeRetentionActive, err.Error())
+			}
+		}
+	}
+	return result
+}
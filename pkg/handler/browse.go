@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// maxListKeys caps the entries of one listing page, as S3 does
+const maxListKeys = 1000
+
+// BrowseRecord is one line of a streamed browse: a folder or an object
+type BrowseRecord struct {
+	Folder string               `json:"folder,omitempty"`
+	Object *service.FolderEntry `json:"object,omitempty"`
+}
+
+// BrowseObjects lists the folders and objects one level below the prefix
+// query parameter (default the company prefix), a page at a time. Pass the
+// returned next_token as continuation_token for the next page. With
+// Accept: application/x-ndjson every page is streamed as BrowseRecord lines.
+func (h *Handler) BrowseObjects(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	if prefix != "" && !h.service(r).OwnsKey(prefix) {
+		respondWithError(w, http.StatusForbidden, "prefix is outside the company prefix", prefix)
+		return
+	}
+	maxKeys, ok := parseMaxKeys(w, r)
+	if !ok {
+		return
+	}
+
+	if wantsNDJSON(r) {
+		h.streamBrowse(w, r, prefix, query.Get("continuation_token"), maxKeys)
+		return
+	}
+
+	listing, err := h.service(r).BrowseFolder(r.Context(), prefix, query.Get("continuation_token"), maxKeys)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to browse objects", err)
+		return
+	}
+
+	respondWithConditionalJSON(w, r, listing, time.Time{})
+}
+
+// streamBrowse writes every page of a folder listing as NDJSON, starting at
+// token, with maxKeys entries per S3 call
+func (h *Handler) streamBrowse(w http.ResponseWriter, r *http.Request, prefix, token string, maxKeys int32) {
+	svc := h.service(r)
+	stream := h.newNDJSONStream(w)
+	for {
+		listing, err := svc.BrowseFolder(r.Context(), prefix, token, maxKeys)
+		if err != nil {
+			stream.fail("Failed to browse objects", err)
+			return
+		}
+		for _, folder := range listing.Folders {
+			if stream.write(BrowseRecord{Folder: folder}) != nil {
+				return
+			}
+		}
+		for i := range listing.Objects {
+			if stream.write(BrowseRecord{Object: &listing.Objects[i]}) != nil {
+				return
+			}
+		}
+		stream.flush()
+
+		if listing.NextToken == "" || r.Context().Err() != nil {
+			return
+		}
+		token = listing.NextToken
+	}
+}
+
+// parseMaxKeys reads the max_keys query parameter of a paginated listing,
+// responding with 400 when it is out of range. It reports whether the handler
+// may continue.
+func parseMaxKeys(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	v := r.URL.Query().Get("max_keys")
+	if v == "" {
+		return maxListKeys, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxListKeys {
+		respondWithError(w, http.StatusBadRequest, "max_keys must be between 1 and 1000", v)
+		return 0, false
+	}
+	return int32(n), true
+}
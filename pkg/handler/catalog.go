@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/catalog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// CatalogPage is one page of a catalog search
+type CatalogPage struct {
+	Objects   []catalog.Entry `json:"objects"`
+	NextToken string          `json:"next_token,omitempty"` // Continuation token of the next page
+}
+
+// WithCatalog records confirmed objects in store and serves catalog searches
+// from it
+func WithCatalog(store catalog.Store) Option {
+	return func(h *Handler) {
+		h.catalog = store
+	}
+}
+
+// recordInCatalog records a confirmed object when a catalog is configured,
+// responding with 500 when it can't be written. It reports whether the
+// handler may continue.
+func (h *Handler) recordInCatalog(w http.ResponseWriter, r *http.Request, info *service.ObjectInfo, runID string) bool {
+	if h.catalog == nil {
+		return true
+	}
+
+	entry := catalog.Entry{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		RunID:        runID,
+		LastModified: info.LastModified.UTC(),
+		ConfirmedAt:  time.Now().UTC(),
+	}
+	if t, ok := TenantFromContext(r.Context()); ok {
+		entry.TenantID = t.ID
+	}
+	checksum, err := h.service(r).ChecksumSHA256(r.Context(), info.Key)
+	if err != nil {
+		logging.Warnf("Cataloging %s without its checksum: %v", info.Key, err)
+	}
+	entry.ChecksumSHA256 = checksum
+
+	if err := h.catalog.Record(r.Context(), entry); err != nil {
+		logging.Errorf("Failed to catalog %s: %v", info.Key, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to record object in the catalog", err.Error())
+		return false
+	}
+	return true
+}
+
+// SearchCatalog searches the confirmed objects under the prefix query
+// parameter (default the company prefix), optionally by filename, run_id and
+// a from/to last modified date range, a page at a time. It reads the catalog
+// database instead of listing the bucket.
+func (h *Handler) SearchCatalog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	svc := h.service(r)
+	prefix := query.Get("prefix")
+	if prefix == "" {
+		prefix = svc.KeyPrefix()
+	} else if !svc.OwnsKey(prefix) {
+		respondWithError(w, http.StatusForbidden, "prefix is outside the company prefix", prefix)
+		return
+	}
+	from, to, err := parseDateRange(query.Get("from"), query.Get("to"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid date range", err.Error())
+		return
+	}
+	maxKeys, ok := parseMaxKeys(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.catalog.Search(r.Context(), catalog.Query{
+		Prefix:   prefix,
+		Filename: query.Get("filename"),
+		RunID:    query.Get("run_id"),
+		From:     from,
+		To:       to,
+		After:    query.Get("continuation_token"),
+		Limit:    int(maxKeys),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search the catalog", err.Error())
+		return
+	}
+
+	page := CatalogPage{Objects: entries}
+	if len(entries) == int(maxKeys) {
+		page.NextToken = entries[len(entries)-1].Key
+	}
+	respondWithConditionalJSON(w, r, page, time.Time{})
+}
@@ -0,0 +1,475 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/chunks"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Chunked backup error codes
+const (
+	CodeChunksMissing     = "CHUNKS_MISSING"
+	CodeChunkSizeMismatch = "CHUNK_SIZE_MISMATCH"
+)
+
+const (
+	// chunkCheckConcurrency bounds the HeadObject calls verifying chunks
+	chunkCheckConcurrency = 8
+	// maxListedChunks bounds the chunk indexes named in an error message
+	maxListedChunks = 20
+)
+
+// ChunkSpec declares one chunk of a backup
+type ChunkSpec struct {
+	Size           int64  `json:"size"`
+	ChecksumSHA256 string `json:"checksum_sha256,omitempty"` // Hex or base64
+	ContentMD5     string `json:"content_md5,omitempty"`     // Hex or base64
+}
+
+// CreateChunkedBackupRequest represents the request body for starting a
+// chunked backup. Chunks are listed in the order they reassemble.
+type CreateChunkedBackupRequest struct {
+	Filename        string      `json:"filename"`
+	ContentType     string      `json:"content_type,omitempty"`
+	ContentEncoding string      `json:"content_encoding,omitempty"` // Of the reassembled backup
+	Chunks          []ChunkSpec `json:"chunks"`
+}
+
+// RestoreChunkedBackupRequest represents the request body for restoring a
+// completed chunked backup
+type RestoreChunkedBackupRequest struct {
+	ManifestKey string `json:"manifest_key"`
+}
+
+// ChunkURL is a presigned URL for one chunk
+type ChunkURL struct {
+	chunks.Chunk
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	Headers   map[string]string `json:"headers,omitempty"` // Headers that must be sent verbatim
+}
+
+// ChunkedBackupResponse represents the response for a new chunked backup
+type ChunkedBackupResponse struct {
+	BackupID    string     `json:"backup_id"`
+	ManifestKey string     `json:"manifest_key"`
+	TotalSize   int64      `json:"total_size"`
+	Chunks      []ChunkURL `json:"chunks"`
+}
+
+// ChunkedRestoreResponse represents the ordered download URLs of a backup
+type ChunkedRestoreResponse struct {
+	ManifestKey string `json:"manifest_key"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	// Encoding to decode the concatenated chunks with
+	ContentEncoding string     `json:"content_encoding,omitempty"`
+	TotalSize       int64      `json:"total_size"`
+	Chunks          []ChunkURL `json:"chunks"`
+}
+
+// CreateChunkedBackup registers a chunked backup and signs an upload URL for
+// every chunk. Chunk sizes, and checksums when given, are signed so S3
+// rejects a chunk that doesn't match its declaration.
+func (h *Handler) CreateChunkedBackup(w http.ResponseWriter, r *http.Request) {
+	var req CreateChunkedBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Filename == "" {
+		respondWithError(w, http.StatusBadRequest, "filename is required", "")
+		return
+	}
+	if !service.ValidContentEncoding(req.ContentEncoding) {
+		respondWithError(w, http.StatusBadRequest, "content_encoding must be gzip", "")
+		return
+	}
+	if len(req.Chunks) == 0 || len(req.Chunks) > service.MaxChunks {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("chunks must list between 1 and %d chunks", service.MaxChunks), "")
+		return
+	}
+
+	manifestKey, chunkKeys, err := h.service(r).ChunkedKeys(req.Filename, len(req.Chunks))
+	if err != nil {
+		respondWithKeyError(w, err)
+		return
+	}
+
+	metadata := h.injectMetadata(w, r, nil, "")
+	specs := make([]chunks.Chunk, len(req.Chunks))
+	var totalSize int64
+	for i, c := range req.Chunks {
+		if c.Size <= 0 {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("chunks[%d].size must be positive", i), "")
+			return
+		}
+		specs[i] = chunks.Chunk{Index: i, ObjectKey: chunkKeys[i], Size: c.Size}
+		if c.ChecksumSHA256 != "" {
+			checksum, err := service.NormalizeChecksumSHA256(c.ChecksumSHA256)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("chunks[%d].checksum_sha256 is invalid", i), err.Error())
+				return
+			}
+			specs[i].ChecksumSHA256 = checksum
+		}
+		if c.ContentMD5 != "" {
+			md5, err := service.NormalizeContentMD5(c.ContentMD5)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("chunks[%d].content_md5 is invalid", i), err.Error())
+				return
+			}
+			specs[i].ContentMD5 = md5
+		}
+		if err := h.service(r).CheckSignedHeaders(h.service(r).SignedUploadHeaders(chunkUploadOptions(specs[i], metadata))); err != nil {
+			respondWithSignedHeadersError(w, err)
+			return
+		}
+		totalSize += c.Size
+	}
+
+	if !h.authorize(w, r, policy.Request{
+		Operation:   OperationUpload,
+		ObjectKey:   manifestKey,
+		ContentType: req.ContentType,
+		Size:        totalSize,
+	}) {
+		return
+	}
+	if !h.checkTenantLimits(w, r, req.ContentType, totalSize) {
+		return
+	}
+
+	response := ChunkedBackupResponse{
+		ManifestKey: manifestKey,
+		TotalSize:   totalSize,
+		Chunks:      make([]ChunkURL, len(specs)),
+	}
+	for i, c := range specs {
+		presigned, err := h.presignChunk(r, c, metadata)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+			return
+		}
+		response.Chunks[i] = *presigned
+	}
+	if !h.sealURLs(w, r, chunkURLs(response.Chunks)...) {
+		return
+	}
+
+	backup := h.chunked.Create(chunks.Backup{
+		Filename:        req.Filename,
+		ContentType:     req.ContentType,
+		ContentEncoding: req.ContentEncoding,
+		ManifestKey:     manifestKey,
+		Chunks:          specs,
+	})
+	response.BackupID = backup.ID
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// GetChunkedBackup returns the state of a chunked backup
+func (h *Handler) GetChunkedBackup(w http.ResponseWriter, r *http.Request) {
+	backup, err := h.getChunkedBackup(r, mux.Vars(r)["id"])
+	if err != nil {
+		respondWithChunkedError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, backup)
+}
+
+// GenerateChunkURL signs a fresh upload URL for one chunk of a pending
+// backup, for chunks whose first URL expired before they were uploaded
+func (h *Handler) GenerateChunkURL(w http.ResponseWriter, r *http.Request) {
+	backup, err := h.getChunkedBackup(r, mux.Vars(r)["id"])
+	if err != nil {
+		respondWithChunkedError(w, err)
+		return
+	}
+	if backup.Status != chunks.StatusPending {
+		respondWithChunkedError(w, chunks.ErrCompleted)
+		return
+	}
+
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil || index < 0 || index >= len(backup.Chunks) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("index must be between 0 and %d", len(backup.Chunks)-1), "")
+		return
+	}
+
+	presigned, err := h.presignChunk(r, backup.Chunks[index], h.injectMetadata(w, r, nil, ""))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+		return
+	}
+	if !h.sealURLs(w, r, &presigned.URL) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, presigned)
+}
+
+// CompleteChunkedBackup verifies every chunk of a backup is in the bucket
+// with its declared size, then writes the manifest object restores read
+func (h *Handler) CompleteChunkedBackup(w http.ResponseWriter, r *http.Request) {
+	backup, err := h.getChunkedBackup(r, mux.Vars(r)["id"])
+	if err != nil {
+		respondWithChunkedError(w, err)
+		return
+	}
+	if backup.Status != chunks.StatusPending {
+		respondWithChunkedError(w, chunks.ErrCompleted)
+		return
+	}
+
+	verified, missing, mismatched, err := h.verifyChunks(r, backup.Chunks)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to verify chunks", err)
+		return
+	}
+	if len(missing) > 0 {
+		respondWithCodedError(w, http.StatusConflict, CodeChunksMissing, "Chunks have not been uploaded", "missing chunks "+listIndexes(missing))
+		return
+	}
+	if len(mismatched) > 0 {
+		respondWithCodedError(w, http.StatusConflict, CodeChunkSizeMismatch, "Chunks differ from the declared size", "mismatched chunks "+listIndexes(mismatched))
+		return
+	}
+
+	manifest, err := h.chunked.Complete(backup.ID, verified)
+	if err != nil {
+		respondWithChunkedError(w, err)
+		return
+	}
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		h.chunked.Reopen(backup.ID)
+		respondWithError(w, http.StatusInternalServerError, "Failed to encode manifest", err.Error())
+		return
+	}
+	if err := h.service(r).PutObject(r.Context(), backup.ManifestKey, body, "application/json"); err != nil {
+		h.chunked.Reopen(backup.ID)
+		h.respondWithS3Error(w, "Failed to write manifest", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		ManifestKey string `json:"manifest_key"`
+		chunks.Manifest
+	}{backup.ManifestKey, manifest})
+}
+
+// RestoreChunkedBackup reads a backup's manifest object and returns download
+// URLs for its chunks in reassembly order. It only needs the manifest key, so
+// it works for backups completed before a restart.
+func (h *Handler) RestoreChunkedBackup(w http.ResponseWriter, r *http.Request) {
+	var req RestoreChunkedBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	manifest, ok := h.readManifest(w, r, "manifest_key", req.ManifestKey)
+	if !ok {
+		return
+	}
+
+	svc := h.service(r)
+	response := ChunkedRestoreResponse{
+		ManifestKey:     req.ManifestKey,
+		Filename:        manifest.Filename,
+		ContentType:     manifest.ContentType,
+		ContentEncoding: manifest.ContentEncoding,
+		TotalSize:       manifest.TotalSize,
+		Chunks:          make([]ChunkURL, len(manifest.Chunks)),
+	}
+	for i, c := range manifest.Chunks {
+		// Chunks are served as stored: decoding each one separately would
+		// corrupt a backup compressed as a whole
+		presigned, err := svc.PresignDownload(c.ObjectKey, service.DownloadOptions{ContentEncoding: service.ContentEncodingIdentity})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+			return
+		}
+		h.recordIssued(r, presigned)
+		response.Chunks[i] = ChunkURL{Chunk: c, URL: presigned.URL, Method: presigned.Method, ExpiresAt: presigned.ExpiresAt}
+	}
+	if !h.sealURLs(w, r, chunkURLs(response.Chunks)...) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// readManifest reads the manifest object of a completed chunked backup under
+// the caller's prefix, responding with an error when it can't be downloaded
+// or is invalid. field names the request field holding manifestKey.
+func (h *Handler) readManifest(w http.ResponseWriter, r *http.Request, field, manifestKey string) (*chunks.Manifest, bool) {
+	if !service.IsManifestKey(manifestKey) {
+		respondWithError(w, http.StatusBadRequest, field+" must name a chunked backup manifest", "")
+		return nil, false
+	}
+	svc := h.service(r)
+	if !svc.OwnsKey(manifestKey) {
+		respondWithError(w, http.StatusForbidden, field+" is outside the company prefix", "")
+		return nil, false
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: manifestKey}) {
+		return nil, false
+	}
+
+	data, err := svc.GetObject(r.Context(), manifestKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Manifest not found", manifestKey)
+			return nil, false
+		}
+		h.respondWithS3Error(w, "Failed to read manifest", err)
+		return nil, false
+	}
+
+	var manifest chunks.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid manifest", err.Error())
+		return nil, false
+	}
+	if manifest.Version != chunks.ManifestVersion {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid manifest", fmt.Sprintf("unsupported version %d", manifest.Version))
+		return nil, false
+	}
+	for i, c := range manifest.Chunks {
+		// A manifest may only point at its own chunks, whatever was written
+		// into the object
+		if c.Index != i || c.ObjectKey != service.ManifestChunkKey(manifestKey, i) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid manifest", fmt.Sprintf("chunk %d does not belong to this backup", i))
+			return nil, false
+		}
+	}
+	return &manifest, true
+}
+
+// presignChunk signs an upload URL for a chunk, binding its size, checksums
+// and injected metadata
+func (h *Handler) presignChunk(r *http.Request, c chunks.Chunk, metadata map[string]string) (*ChunkURL, error) {
+	presigned, err := h.service(r).PresignUploadKey(c.ObjectKey, chunkUploadOptions(c, metadata))
+	if err != nil {
+		return nil, err
+	}
+	h.recordIssued(r, presigned)
+
+	return &ChunkURL{
+		Chunk:     c,
+		URL:       presigned.URL,
+		Method:    presigned.Method,
+		ExpiresAt: presigned.ExpiresAt,
+		Headers:   presigned.Headers,
+	}, nil
+}
+
+// chunkURLs returns pointers to the URLs of chunks, for sealing
+func chunkURLs(chunks []ChunkURL) []*string {
+	urls := make([]*string, len(chunks))
+	for i := range chunks {
+		urls[i] = &chunks[i].URL
+	}
+	return urls
+}
+
+// chunkUploadOptions returns the upload properties a chunk URL signs
+func chunkUploadOptions(c chunks.Chunk, metadata map[string]string) service.UploadOptions {
+	return service.UploadOptions{
+		ContentLength:  c.Size,
+		Metadata:       metadata,
+		ChecksumSHA256: c.ChecksumSHA256,
+		ContentMD5:     c.ContentMD5,
+	}
+}
+
+// verifyChunks looks up every chunk in the bucket, returning the chunks with
+// their ETags and the indexes of chunks that are missing or of another size
+func (h *Handler) verifyChunks(r *http.Request, specs []chunks.Chunk) ([]chunks.Chunk, []int, []int, error) {
+	svc := h.service(r)
+	verified := append([]chunks.Chunk(nil), specs...)
+	infos := make([]*service.ObjectInfo, len(specs))
+	errs := make([]error, len(specs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, chunkCheckConcurrency)
+	for i := range specs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			infos[i], errs[i] = svc.HeadObject(r.Context(), specs[i].ObjectKey)
+		}(i)
+	}
+	wg.Wait()
+
+	var missing, mismatched []int
+	for i, err := range errs {
+		switch {
+		case errors.Is(err, service.ErrObjectNotFound):
+			missing = append(missing, i)
+		case err != nil:
+			return nil, nil, nil, err
+		case infos[i].Size != specs[i].Size:
+			mismatched = append(mismatched, i)
+		default:
+			verified[i].ETag = infos[i].ETag
+		}
+	}
+	return verified, missing, mismatched, nil
+}
+
+// getChunkedBackup fetches a backup, hiding backups of other prefixes
+func (h *Handler) getChunkedBackup(r *http.Request, id string) (chunks.Backup, error) {
+	backup, err := h.chunked.Get(id)
+	if err != nil {
+		return chunks.Backup{}, err
+	}
+	if !h.service(r).OwnsKey(backup.ManifestKey) {
+		return chunks.Backup{}, chunks.ErrNotFound
+	}
+	return backup, nil
+}
+
+// listIndexes formats chunk indexes for an error message, naming at most
+// maxListedChunks of them
+func listIndexes(indexes []int) string {
+	parts := make([]string, 0, min(len(indexes), maxListedChunks))
+	for _, i := range indexes[:min(len(indexes), maxListedChunks)] {
+		parts = append(parts, strconv.Itoa(i))
+	}
+	list := strings.Join(parts, ", ")
+	if len(indexes) > maxListedChunks {
+		list += fmt.Sprintf(" and %d more", len(indexes)-maxListedChunks)
+	}
+	return list
+}
+
+// respondWithChunkedError maps chunked backup store errors to HTTP responses
+func respondWithChunkedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, chunks.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Chunked backup not found", "")
+	case errors.Is(err, chunks.ErrCompleted):
+		respondWithError(w, http.StatusConflict, "Chunked backup is already completed", "")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Chunked backup error", err.Error())
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// respondWithConditionalJSON responds with payload like respondWithJSON, adding
+// a weak ETag derived from the body and, when lastModified is not zero, a
+// Last-Modified header. It responds 304 Not Modified instead when the request's
+// If-None-Match lists the ETag or, without If-None-Match, If-Modified-Since is
+// not before lastModified. Pass a zero lastModified when no timestamp covers
+// every change to the payload (e.g. deletions in a listing).
+func respondWithConditionalJSON(w http.ResponseWriter, r *http.Request, payload any, lastModified time.Time) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Internal Server Error","message":"Failed to marshal response"}`))
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// notModified evaluates If-None-Match, which takes precedence, and
+// If-Modified-Since against the current representation
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/envelope"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+const (
+	// WrappedKeyMetadataKey is the metadata key confirmed uploads store their
+	// wrapped data key under
+	WrappedKeyMetadataKey = "wrapped-key"
+	// maxWrappedKeyBytes bounds the wrapped key stored on confirmation; KMS
+	// ciphertext blobs of AES-256 keys are under 200 bytes
+	maxWrappedKeyBytes = 1024
+)
+
+// DataKeyResponse is a data key for encrypting a backup client-side. The
+// client encrypts with plaintext_key, discards it, and confirms the upload
+// with wrapped_key; restores unwrap it with kms:Decrypt and
+// encryption_context.
+type DataKeyResponse struct {
+	KeyID             string            `json:"key_id"`
+	KeySpec           string            `json:"key_spec"`
+	PlaintextKey      []byte            `json:"plaintext_key"` // Base64
+	WrappedKey        []byte            `json:"wrapped_key"`   // Base64 KMS ciphertext blob
+	EncryptionContext map[string]string `json:"encryption_context"`
+}
+
+// WithDataKeys serves data keys for client-side encryption generated by
+// generator
+func WithDataKeys(generator envelope.DataKeyGenerator) Option {
+	return func(h *Handler) {
+		h.dataKeys = generator
+	}
+}
+
+// GenerateDataKey returns a new AES-256 data key, in the clear and wrapped by
+// the KMS key. The plaintext key is never stored or logged.
+func (h *Handler) GenerateDataKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.dataKeys.GenerateDataKey(r.Context())
+	if err != nil {
+		logging.Errorf("Failed to generate data key: %v", err)
+		respondWithError(w, http.StatusBadGateway, "Failed to generate data key", err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, DataKeyResponse{
+		KeyID:             key.KeyID,
+		KeySpec:           "AES_256",
+		PlaintextKey:      key.Plaintext,
+		WrappedKey:        key.Ciphertext,
+		EncryptionContext: envelope.DataKeyEncryptionContext,
+	})
+}
+
+// storeWrappedKey stores the base64 wrapped key of a confirmed upload as
+// object metadata, rewriting the object in place, and returns the updated
+// object. It responds with an error and returns nil on failure.
+func (h *Handler) storeWrappedKey(w http.ResponseWriter, r *http.Request, info *service.ObjectInfo, wrappedKey string) *service.ObjectInfo {
+	if h.dataKeys == nil {
+		respondWithError(w, http.StatusBadRequest, "wrapped_key requires CLIENT_ENCRYPTION_KMS_KEY_ID", "")
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil || len(decoded) == 0 || len(decoded) > maxWrappedKeyBytes {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("wrapped_key must be base64 of at most %d bytes", maxWrappedKeyBytes), "")
+		return nil
+	}
+	if !h.checkWritable(w) {
+		return nil
+	}
+
+	updated, err := h.service(r).UpdateMetadata(r.Context(), info, service.MetadataUpdate{
+		Set: map[string]string{WrappedKeyMetadataKey: wrappedKey},
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrObjectTooLarge) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Object is too large to store the wrapped key", err.Error())
+			return nil
+		}
+		h.respondWithS3Error(w, "Failed to store wrapped key", err)
+		return nil
+	}
+	return updated
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// datePrefixPattern matches the year or year-month accepted by ListDates
+var datePrefixPattern = regexp.MustCompile(`^\d{4}(-\d{2})?$`)
+
+// DatesResponse lists the date folders holding uploads
+type DatesResponse struct {
+	Prefix       string               `json:"prefix,omitempty"` // Year or month filter
+	TotalUploads int                  `json:"total_uploads"`
+	Dates        []service.DateFolder `json:"dates"`
+}
+
+// ListDates returns the YYYY-MM-DD folders under the prefix with their upload
+// counts, optionally limited to a year or month by the prefix query parameter
+// (YYYY or YYYY-MM), so clients can render a backup calendar. With
+// Accept: application/x-ndjson each date folder is written as one line.
+func (h *Handler) ListDates(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix != "" && !datePrefixPattern.MatchString(prefix) {
+		respondWithError(w, http.StatusBadRequest, "prefix must look like YYYY or YYYY-MM", prefix)
+		return
+	}
+
+	folders, err := h.service(r).ListDateFolders(r.Context(), prefix)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to list date folders", err)
+		return
+	}
+
+	if wantsNDJSON(r) {
+		stream := h.newNDJSONStream(w)
+		for _, f := range folders {
+			if stream.write(f) != nil {
+				return
+			}
+		}
+		stream.flush()
+		return
+	}
+
+	response := DatesResponse{Prefix: prefix, Dates: folders}
+	for _, f := range folders {
+		response.TotalUploads += f.Uploads
+	}
+	respondWithConditionalJSON(w, r, response, time.Time{})
+}
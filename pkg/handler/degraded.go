@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+)
+
+// DegradedHandler serves while AWS initialization is retried in the
+// background: /health answers 200 so the process isn't restarted, /ready
+// answers 503 with the last initialization error and every other route
+// answers 503 with Retry-After
+type DegradedHandler struct {
+	metrics *metrics.Registry
+
+	mu  sync.RWMutex
+	err error
+}
+
+// NewDegradedHandler creates a degraded handler serving registry on /metrics
+// (nil serves no metrics)
+func NewDegradedHandler(registry *metrics.Registry) *DegradedHandler {
+	return &DegradedHandler{metrics: registry}
+}
+
+// SetError records the last initialization error, reported by /ready
+func (d *DegradedHandler) SetError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
+
+func (d *DegradedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/health" && r.Method == http.MethodGet:
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "signer-service"})
+	case r.URL.Path == "/ready" && r.Method == http.MethodGet:
+		resp := ReadinessResponse{Status: "starting"}
+		d.mu.RLock()
+		if d.err != nil {
+			resp.Error = d.err.Error()
+		}
+		d.mu.RUnlock()
+		respondWithJSON(w, http.StatusServiceUnavailable, resp)
+	case r.URL.Path == "/metrics" && r.Method == http.MethodGet && d.metrics != nil:
+		d.metrics.ServeHTTP(w, r)
+	default:
+		respondWithRetryableError(w, http.StatusServiceUnavailable, 5, "Service is starting", "AWS initialization failed and is being retried")
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/chunks"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// uploadFolderPattern matches the date or date/time upload folders compared
+// by DiffObjects
+var uploadFolderPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(/\d{2}-\d{2}-\d{2})?$`)
+
+// ObjectDiffRequest represents the request body for comparing two upload
+// folders (from and to) or two chunked backup manifests (from_manifest and
+// to_manifest)
+type ObjectDiffRequest struct {
+	From         string `json:"from,omitempty"` // YYYY-MM-DD or YYYY-MM-DD/HH-MM-SS
+	To           string `json:"to,omitempty"`
+	FromManifest string `json:"from_manifest,omitempty"`
+	ToManifest   string `json:"to_manifest,omitempty"`
+}
+
+// ManifestDiffResponse lists the chunks that differ between two chunked
+// backups
+type ManifestDiffResponse struct {
+	FromManifest string `json:"from_manifest"`
+	ToManifest   string `json:"to_manifest"`
+	chunks.ManifestDiff
+}
+
+// DiffObjects compares two upload folders, or two chunked backup manifests,
+// and returns what was added, removed and changed from the first to the
+// second, so clients can upload only the changes and restores can tell which
+// files make up a point in time
+func (h *Handler) DiffObjects(w http.ResponseWriter, r *http.Request) {
+	var req ObjectDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	folders := req.From != "" || req.To != ""
+	manifests := req.FromManifest != "" || req.ToManifest != ""
+	if folders == manifests {
+		respondWithError(w, http.StatusBadRequest, "Either from and to or from_manifest and to_manifest are required", "")
+		return
+	}
+
+	if manifests {
+		from, ok := h.readManifest(w, r, "from_manifest", req.FromManifest)
+		if !ok {
+			return
+		}
+		to, ok := h.readManifest(w, r, "to_manifest", req.ToManifest)
+		if !ok {
+			return
+		}
+		respondWithJSON(w, http.StatusOK, ManifestDiffResponse{
+			FromManifest: req.FromManifest,
+			ToManifest:   req.ToManifest,
+			ManifestDiff: chunks.Diff(*from, *to),
+		})
+		return
+	}
+
+	for _, folder := range []string{req.From, req.To} {
+		if !uploadFolderPattern.MatchString(folder) {
+			respondWithError(w, http.StatusBadRequest, "from and to must look like YYYY-MM-DD or YYYY-MM-DD/HH-MM-SS", folder)
+			return
+		}
+	}
+	diff, err := h.service(r).DiffFolders(r.Context(), req.From, req.To)
+	if err != nil {
+		if errors.Is(err, service.ErrDiffTooLarge) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Folder is too large to compare", err.Error())
+			return
+		}
+		h.respondWithS3Error(w, "Failed to compare folders", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, diff)
+}
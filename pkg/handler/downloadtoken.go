@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/downloadtoken"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+)
+
+// downloadTokenPathPrefix is where tokens are redeemed. The token is the
+// credential, so these requests bypass authentication.
+const downloadTokenPathPrefix = "/t/"
+
+// DownloadTokenRequest represents the request body for issuing a download
+// token
+type DownloadTokenRequest struct {
+	ObjectKey      string `json:"object_key"`
+	MaxRedemptions int    `json:"max_redemptions,omitempty"` // Defaults to 1
+}
+
+// DownloadTokenResponse is an issued download token. GET URL redirects to a
+// short-lived presigned download URL until the token is used up or expires.
+type DownloadTokenResponse struct {
+	Token          string    `json:"token,omitempty"` // Omitted when URLs are encrypted
+	URL            string    `json:"url"`             // Path of the redemption link, relative to the service
+	ObjectKey      string    `json:"object_key"`
+	MaxRedemptions int       `json:"max_redemptions"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// IssueDownloadToken issues a download token for an object, redeemable at
+// most max_redemptions times
+func (h *Handler) IssueDownloadToken(w http.ResponseWriter, r *http.Request) {
+	var req DownloadTokenRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.ObjectKey == "" {
+		respondWithError(w, http.StatusBadRequest, "object_key is required", "")
+		return
+	}
+	if req.MaxRedemptions == 0 {
+		req.MaxRedemptions = 1
+	}
+	if req.MaxRedemptions < 1 || req.MaxRedemptions > h.cfg.DownloadTokenMaxRedemptions {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("max_redemptions must be between 1 and %d", h.cfg.DownloadTokenMaxRedemptions), "")
+		return
+	}
+	if !h.service(r).OwnsKey(req.ObjectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: req.ObjectKey}) {
+		return
+	}
+
+	var tenantID string
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	token := h.tokens.Issue(req.ObjectKey, tenantID, req.MaxRedemptions, time.Duration(h.cfg.DownloadTokenTTLMinutes)*time.Minute)
+	logging.Infof("Issued download token for %s (%d redemptions)", token.ObjectKey, token.MaxRedemptions)
+
+	response := DownloadTokenResponse{
+		Token:          token.Token,
+		URL:            downloadTokenPathPrefix + token.Token,
+		ObjectKey:      token.ObjectKey,
+		MaxRedemptions: token.MaxRedemptions,
+		ExpiresAt:      token.ExpiresAt,
+	}
+	if h.sealer != nil {
+		response.Token = ""
+	}
+	if !h.sealURLs(w, r, &response.URL) {
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// RedeemDownloadToken counts one use of a download token and redirects to a
+// download URL valid for DOWNLOAD_TOKEN_REDIRECT_SECONDS, signed with the
+// credentials of the tenant that issued it. Used up and expired tokens, and
+// tokens of deleted tenants, get 410.
+func (h *Handler) RedeemDownloadToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.tokens.Redeem(mux.Vars(r)["token"])
+	switch {
+	case errors.Is(err, downloadtoken.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Download token not found", "")
+		return
+	case err != nil:
+		respondWithError(w, http.StatusGone, "Download token is no longer valid", err.Error())
+		return
+	}
+
+	svc := h.s3Service
+	if token.TenantID != "" {
+		t, err := h.tenants.Get(token.TenantID)
+		switch {
+		case errors.Is(err, tenant.ErrNotFound):
+			respondWithError(w, http.StatusGone, "Download token is no longer valid", fmt.Sprintf("tenant %s no longer exists", token.TenantID))
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Tenant store error", err.Error())
+			return
+		}
+		svc = h.tenantService(&t)
+	}
+
+	presigned, err := svc.PresignDownload(token.ObjectKey, service.DownloadOptions{
+		Expires: time.Duration(h.cfg.DownloadTokenRedirectSeconds) * time.Second,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+		return
+	}
+	h.recordIssued(r, presigned)
+	logging.Infof("Redeemed download token for %s (%d of %d)", token.ObjectKey, token.Redemptions, token.MaxRedemptions)
+
+	// The redirect carries a live URL: keep it out of caches and referrers
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, presigned.URL, http.StatusFound)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/catalog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Policies for uploads of a filename that already exists under the prefix
+const (
+	OnDuplicateAllow    = "allow"    // Issue the URL anyway (default)
+	OnDuplicateReject   = "reject"   // Respond 409
+	OnDuplicateExisting = "existing" // Return the existing key instead of a URL
+)
+
+// CodeDuplicateObject is returned when on_duplicate is reject and a match
+// exists
+const CodeDuplicateObject = "DUPLICATE_OBJECT"
+
+// validOnDuplicate reports whether policy is a known on_duplicate value
+func validOnDuplicate(policy string) bool {
+	switch policy {
+	case "", OnDuplicateAllow, OnDuplicateReject, OnDuplicateExisting:
+		return true
+	}
+	return false
+}
+
+// checkDuplicate applies an upload's on_duplicate policy. It responds with
+// 409 when the policy is reject and a match exists, and returns the match
+// when the policy is existing. ok reports whether the handler may continue.
+func (h *Handler) checkDuplicate(w http.ResponseWriter, r *http.Request, policy, filename, checksum string) (*service.Duplicate, bool) {
+	if policy == "" || policy == OnDuplicateAllow {
+		return nil, true
+	}
+
+	existing, err := h.service(r).FindDuplicate(r.Context(), filename, checksum)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to check for duplicates", err)
+		return nil, false
+	}
+	if existing == nil {
+		return nil, true
+	}
+
+	if policy == OnDuplicateReject {
+		respondWithCodedError(w, http.StatusConflict, CodeDuplicateObject, "Object already exists", existing.ObjectKey)
+		return nil, false
+	}
+	return existing, true
+}
+
+// catalogDuplicate looks up confirmed objects under the prefix with the
+// upload's SHA-256 checksum (base64) in the catalog when CATALOG_DEDUPLICATE
+// is set, whatever their filename, and returns the most recent one still
+// stored with that checksum, or nil. on_duplicate=allow skips the lookup. ok
+// reports whether the handler may continue.
+func (h *Handler) catalogDuplicate(w http.ResponseWriter, r *http.Request, policy, checksum string) (*service.Duplicate, bool) {
+	if !h.cfg.CatalogDeduplicate || h.catalog == nil || checksum == "" || policy == OnDuplicateAllow {
+		return nil, true
+	}
+
+	svc := h.service(r)
+	entries, err := h.catalog.Search(r.Context(), catalog.Query{
+		Prefix:   svc.KeyPrefix(),
+		Checksum: checksum,
+		Limit:    catalog.MaxSearchLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search the catalog", err.Error())
+		return nil, false
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastModified.After(entries[j].LastModified) })
+
+	// The catalog isn't updated on deletes or overwrites, so S3 has the
+	// last word
+	for _, e := range entries {
+		stored, err := svc.ChecksumSHA256(r.Context(), e.Key)
+		if errors.Is(err, service.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			h.respondWithS3Error(w, "Failed to check for duplicates", err)
+			return nil, false
+		}
+		if stored == checksum {
+			return &service.Duplicate{ObjectKey: e.Key, Size: e.Size, LastModified: e.LastModified}, true
+		}
+	}
+	return nil, true
+}
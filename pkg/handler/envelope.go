@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/envelope"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// urlEncryptionHeader names the scheme presigned URLs in a response are
+// encrypted with
+const urlEncryptionHeader = "X-URL-Encryption"
+
+// WithURLSealer encrypts every presigned URL returned to clients with sealer
+func WithURLSealer(sealer envelope.Sealer) Option {
+	return func(h *Handler) {
+		h.sealer = sealer
+	}
+}
+
+// sealURLs encrypts the presigned URLs of a response in place when URL
+// encryption is enabled. URLs are recorded and audited before sealing. On
+// failure it responds with an error and returns false.
+func (h *Handler) sealURLs(w http.ResponseWriter, r *http.Request, urls ...*string) bool {
+	if h.sealer == nil {
+		return true
+	}
+	for _, u := range urls {
+		sealed, err := h.sealer.Seal(r.Context(), []byte(*u))
+		if err != nil {
+			logging.Errorf("Failed to encrypt presigned URL: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to encrypt presigned URL", err.Error())
+			return false
+		}
+		*u = sealed
+	}
+	w.Header().Set(urlEncryptionHeader, h.sealer.Scheme())
+	return true
+}
@@ -0,0 +1,753 @@
+// Package handler exposes the signer-service HTTP API.
+//
+// SetupRoutes returns a ready-to-serve http.Handler wrapped in the configured
+// middleware chain; RegisterRoutes mounts the same routes on a caller-owned
+// gorilla/mux router.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/accesslog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/anomaly"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/apikey"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/audit"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/catalog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/chunks"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cronwindow"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/downloadtoken"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/envelope"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/inventory"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metaschema"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/oidc"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/restoredrill"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/runs"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/session"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/urlregistry"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/webhook"
+	"github.com/gorilla/mux"
+)
+
+// Handler holds dependencies for HTTP handlers
+type Handler struct {
+	s3Service   *service.S3Service
+	cfg         *config.Config
+	metrics     *metrics.Registry
+	sessions    *session.Store
+	runs        *runs.Store
+	catalog     catalog.Store
+	chunked     *chunks.Store
+	tokens      *downloadtoken.Store
+	inventory   *inventory.Store
+	drills      *restoredrill.Store
+	jobs        jobState
+	nonces      *nonceStore
+	oidc        *oidc.Verifier
+	policy      *policy.Policy
+	metaschema  *metaschema.Schema
+	tenants     tenant.Store
+	apiKeys     *apikey.Store
+	issued      *urlregistry.Registry
+	usage       *accesslog.Store
+	audit       *audit.Forwarder
+	sealer      envelope.Sealer
+	dataKeys    envelope.DataKeyGenerator
+	webhook     *webhook.Sender
+	anomalies   *anomaly.Detector
+	alerts      *webhook.Sender // Anomaly webhook
+	tenantUsage tenantUsage
+	readOnly    atomic.Bool // Writes answer 503 MAINTENANCE while set
+	maintenance *cronwindow.Schedule
+	middlewares []Middleware
+}
+
+// Option configures optional Handler dependencies
+type Option func(*Handler)
+
+// WithMetrics serves and records metrics in registry instead of a private one,
+// so metrics from other components appear on the same /metrics endpoint
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(h *Handler) {
+		h.metrics = registry
+	}
+}
+
+// NewHandler creates a new handler instance
+func NewHandler(s3Service *service.S3Service, cfg *config.Config, opts ...Option) *Handler {
+	h := &Handler{
+		s3Service: s3Service,
+		cfg:       cfg,
+		metrics:   metrics.NewRegistry(),
+		sessions:  session.NewStore(),
+		runs:      runs.NewStore(),
+		chunked:   chunks.NewStore(),
+		tokens:    downloadtoken.NewStore(),
+		inventory: inventory.NewStore(),
+		drills:    restoredrill.NewStore(cfg.RestoreDrillHistory),
+		issued:    urlregistry.New(),
+	}
+	h.readOnly.Store(cfg.ReadOnly)
+	h.maintenance, _ = cfg.MaintenanceSchedule() // Validated by config.Load
+	if cfg.OIDCDiscoveryURL != "" {
+		h.oidc = oidc.NewVerifier(cfg.OIDCDiscoveryURL, cfg.OIDCAudience)
+	}
+	if len(cfg.HMACSecrets) > 0 {
+		h.nonces = newNonceStore(time.Duration(cfg.HMACChallengeTTLSeconds)*time.Second, cfg.HMACMaxOutstandingNonces)
+	}
+	if cfg.StaleBackupWebhookURL != "" {
+		h.webhook = webhook.NewSender(cfg.StaleBackupWebhookURL, cfg.StaleBackupWebhookSecret)
+	}
+	if cfg.AnomalyDetection {
+		businessHours, _ := cfg.AnomalyBusinessSchedule() // Validated by config.Load
+		h.anomalies = anomaly.NewDetector(anomaly.Options{
+			SpikeFactor:   float64(cfg.AnomalySpikeFactor),
+			SpikeMinimum:  cfg.AnomalySpikeMinURLs,
+			BusinessHours: businessHours,
+		})
+		if cfg.AnomalyWebhookURL != "" {
+			h.alerts = webhook.NewSender(cfg.AnomalyWebhookURL, cfg.AnomalyWebhookSecret)
+		}
+	}
+	if cfg.AccessLogPrefix != "" {
+		// Access logs arrive hours late, so issued URLs outlive their expiry
+		retention := time.Duration(cfg.AccessLogRetentionHours) * time.Hour
+		h.issued = urlregistry.NewWithRetention(retention)
+		h.usage = accesslog.NewStore(retention)
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.anomalies != nil {
+		h.metrics.Describe("issuance_anomalies_total", "Presigned URL issuances flagged as anomalous by type")
+	}
+	return h
+}
+
+// PresignedURLRequest represents the request body for presigned URL generation
+type PresignedURLRequest struct {
+	Filename    string            `json:"filename"` // Just the filename, server will add inputs/date/time/ prefix
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`     // Custom metadata headers (x-amz-meta-*)
+	RunID       string            `json:"run_id,omitempty"`       // Optional backup run this file belongs to
+	DryRun      bool              `json:"dry_run,omitempty"`      // Validate and return the object key without signing
+	OnDuplicate string            `json:"on_duplicate,omitempty"` // allow, reject or existing
+	// Encoding the file is already compressed with (gzip), signed as
+	// Content-Encoding
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	// Start of the URL's validity window, for scheduled backups
+	NotBefore time.Time `json:"not_before,omitzero"`
+	// MD5 of the file in hex or base64, signed as Content-MD5
+	ContentMD5 string `json:"content_md5,omitempty"`
+	// false signs If-None-Match: * so S3 refuses to overwrite an existing key
+	Overwrite *bool `json:"overwrite,omitempty"`
+	// MD5 of an SSE-C key in hex or base64; the PUT must send the key
+	SSECustomerKeyMD5 string `json:"sse_customer_key_md5,omitempty"`
+}
+
+// uploadOptionsV1 validates a v1 upload request, normalizing it in place,
+// and returns the upload properties it signs and the key it would be issued
+// for. On failure it responds and reports false.
+func (h *Handler) uploadOptionsV1(w http.ResponseWriter, r *http.Request, req *PresignedURLRequest, uploadID string) (service.UploadOptions, string, bool) {
+	if req.Filename == "" {
+		respondWithError(w, http.StatusBadRequest, "filename is required", "")
+		return service.UploadOptions{}, "", false
+	}
+	if !validOnDuplicate(req.OnDuplicate) {
+		respondWithError(w, http.StatusBadRequest, "on_duplicate must be allow, reject or existing", "")
+		return service.UploadOptions{}, "", false
+	}
+	if !service.ValidContentEncoding(req.ContentEncoding) {
+		respondWithError(w, http.StatusBadRequest, "content_encoding must be gzip", "")
+		return service.UploadOptions{}, "", false
+	}
+	if problems := validateNotBefore(req.NotBefore, time.Now()); len(problems) > 0 {
+		respondWithError(w, http.StatusBadRequest, problems[0], "")
+		return service.UploadOptions{}, "", false
+	}
+	if req.ContentMD5 != "" {
+		md5, err := service.NormalizeContentMD5(req.ContentMD5)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), "")
+			return service.UploadOptions{}, "", false
+		}
+		req.ContentMD5 = md5
+	}
+	if req.SSECustomerKeyMD5 != "" {
+		md5, err := service.NormalizeSSECustomerKeyMD5(req.SSECustomerKeyMD5)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), "")
+			return service.UploadOptions{}, "", false
+		}
+		req.SSECustomerKeyMD5 = md5
+	}
+
+	if !h.checkMetadataSchema(w, req.Metadata) {
+		return service.UploadOptions{}, "", false
+	}
+	req.Metadata = h.injectMetadata(w, r, req.Metadata, uploadID)
+
+	// v1 signs metadata, the content encoding, MD5, overwrite protection
+	// and SSE-C, but the content type only when the deployment requires it
+	opts := service.UploadOptions{
+		Metadata:          req.Metadata,
+		ContentEncoding:   req.ContentEncoding,
+		ContentMD5:        req.ContentMD5,
+		NotBefore:         req.NotBefore,
+		IfNoneMatch:       req.Overwrite != nil && !*req.Overwrite,
+		SSECustomerKeyMD5: req.SSECustomerKeyMD5,
+	}
+	if h.service(r).RequiresSignedHeader("content-type") {
+		opts.ContentType = req.ContentType
+	}
+	if err := h.service(r).CheckSignedHeaders(h.service(r).SignedUploadHeaders(opts)); err != nil {
+		respondWithSignedHeadersError(w, err)
+		return service.UploadOptions{}, "", false
+	}
+
+	objectKey, err := h.service(r).UploadKey(req.Filename)
+	if err != nil {
+		respondWithKeyError(w, err)
+		return service.UploadOptions{}, "", false
+	}
+	if !h.authorize(w, r, policy.Request{
+		Operation:   OperationUpload,
+		ObjectKey:   objectKey,
+		ContentType: req.ContentType,
+	}) {
+		return service.UploadOptions{}, "", false
+	}
+	return opts, objectKey, true
+}
+
+// PresignedURLResponse represents the response for presigned URL
+type PresignedURLResponse struct {
+	URL            string             `json:"url,omitempty"`
+	ExpiresIn      string             `json:"expires_in,omitempty"`
+	NotBefore      time.Time          `json:"not_before,omitzero"`
+	DryRun         bool               `json:"dry_run,omitempty"`
+	ObjectKey      string             `json:"object_key,omitempty"` // Dry run or existing object only
+	Headers        map[string]string  `json:"headers,omitempty"`    // Headers that must be sent verbatim
+	ExistingObject *service.Duplicate `json:"existing_object,omitempty"`
+	UploadID       string             `json:"upload_id,omitempty"` // Correlation ID of an issued URL
+	// Header each metadata key is signed as; clients must send these names,
+	// not the keys
+	RequiredHeaders map[string]string `json:"required_headers,omitempty"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"` // Machine-readable error code (v2)
+
+	AWSRequestID string `json:"aws_request_id,omitempty"` // x-amz-request-id of a failed S3 call
+
+	// Retryable tells clients the failure is transient and the request can be
+	// retried as is, after the Retry-After header's delay
+	Retryable bool `json:"retryable"`
+}
+
+// SearchObject handles searching for a file by name
+func (h *Handler) SearchObject(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Filename string `json:"filename"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Filename == "" {
+		respondWithError(w, http.StatusBadRequest, "filename is required", "")
+		return
+	}
+
+	exists, objectKey, err := h.service(r).SearchObjectByFilename(r.Context(), req.Filename)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to search object", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"exists":   exists,
+		"filename": req.Filename,
+	}
+
+	if exists {
+		response["object_key"] = objectKey
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// GeneratePutURL handles PUT presigned URL generation for uploading
+func (h *Handler) GeneratePutURL(w http.ResponseWriter, r *http.Request) {
+	var req PresignedURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	uploadID := h.service(r).NewUploadID()
+	opts, objectKey, ok := h.uploadOptionsV1(w, r, &req, uploadID)
+	if !ok {
+		return
+	}
+	if !h.checkTenantLimits(w, r, req.ContentType, 0) {
+		return
+	}
+
+	// Reject files that aren't part of the run before issuing a URL
+	if req.RunID != "" {
+		if _, err := h.runs.IssuedKey(req.RunID, req.Filename); err != nil {
+			respondWithRunError(w, err)
+			return
+		}
+	}
+
+	existing, ok := h.checkDuplicate(w, r, req.OnDuplicate, req.Filename, "")
+	if !ok {
+		return
+	}
+	if existing != nil {
+		if req.RunID != "" && !req.DryRun {
+			if err := h.runs.MarkIssued(req.RunID, req.Filename, existing.ObjectKey); err != nil {
+				respondWithRunError(w, err)
+				return
+			}
+		}
+		respondWithJSON(w, http.StatusOK, PresignedURLResponse{
+			DryRun:         req.DryRun,
+			ObjectKey:      existing.ObjectKey,
+			ExistingObject: existing,
+		})
+		return
+	}
+
+	if req.DryRun {
+		respondWithJSON(w, http.StatusOK, PresignedURLResponse{
+			ExpiresIn:       "configured expiration time",
+			DryRun:          true,
+			ObjectKey:       objectKey,
+			NotBefore:       req.NotBefore.UTC().Truncate(time.Second),
+			Headers:         h.service(r).SignedUploadHeaders(opts),
+			RequiredHeaders: requiredHeaders(opts.Metadata),
+		})
+		return
+	}
+
+	presigned, err := h.service(r).PresignUpload(req.Filename, opts)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+		return
+	}
+	url, fullPath := presigned.URL, presigned.ObjectKey
+
+	h.recordIssuedUpload(r, presigned, uploadID)
+
+	if req.RunID != "" {
+		if err := h.runs.MarkIssued(req.RunID, req.Filename, fullPath); err != nil {
+			respondWithRunError(w, err)
+			return
+		}
+	}
+
+	logging.Infof("Issued upload %s for %s", uploadID, fullPath)
+	logging.Debugf("Generated object path: %s", fullPath)
+	logging.Debugf("Generated presigned URL FULL: %s", url)
+
+	response := PresignedURLResponse{
+		URL:             url,
+		ExpiresIn:       "configured expiration time",
+		NotBefore:       presigned.NotBefore,
+		UploadID:        uploadID,
+		RequiredHeaders: requiredHeaders(opts.Metadata),
+	}
+	if req.ContentEncoding != "" || req.ContentMD5 != "" || opts.ContentType != "" || opts.IfNoneMatch || len(opts.Metadata) > 0 ||
+		opts.SSECustomerKeyMD5 != "" || len(h.service(r).OwnershipHeaders()) > 0 {
+		response.Headers = presigned.Headers
+	}
+	if !h.sealURLs(w, r, &response.URL) {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// requiredHeaders maps each metadata key to the x-amz-meta-* header it is
+// signed as, nil without metadata
+func requiredHeaders(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(metadata))
+	for key := range metadata {
+		headers[key] = service.MetadataHeaderName(key)
+	}
+	return headers
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// HealthCheck handles health check requests
+func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"status":  "healthy",
+		"service": "signer-service",
+	})
+}
+
+// ReadinessResponse reports whether the service can serve requests
+type ReadinessResponse struct {
+	Status     string                 `json:"status"` // ready, warn, unavailable or starting
+	Failover   *service.FailoverState `json:"failover,omitempty"`
+	ClockDrift *service.ClockDrift    `json:"clock_drift,omitempty"`
+	Error      string                 `json:"error,omitempty"` // Last initialization error while starting
+}
+
+// Readiness reports 503 while the bucket serving requests fails its failover
+// health checks. Without FAILOVER_ENABLED it is always ready. While the
+// local clock drifts from S3's past the threshold it reports warn with 200:
+// the service still serves, but its URLs may be rejected.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	state := h.s3Service.FailoverState()
+	drift := h.s3Service.ClockDriftState()
+	if state != nil && !state.Ready {
+		respondWithJSON(w, http.StatusServiceUnavailable, ReadinessResponse{Status: "unavailable", Failover: state, ClockDrift: drift})
+		return
+	}
+	if drift != nil && drift.Exceeded {
+		respondWithJSON(w, http.StatusOK, ReadinessResponse{Status: "warn", Failover: state, ClockDrift: drift})
+		return
+	}
+	respondWithJSON(w, http.StatusOK, ReadinessResponse{Status: "ready", Failover: state, ClockDrift: drift})
+}
+
+// SetupRoutes configures all routes for the application and wraps them in
+// the configured middleware chain. With ADMIN_PORT set it leaves out the
+// internal routes, served by SetupInternalRoutes.
+func (h *Handler) SetupRoutes() http.Handler {
+	router := mux.NewRouter()
+	if h.cfg.AdminPort == "" {
+		h.registerInternalRoutes(router)
+	}
+	h.registerAPIRoutes(router)
+
+	return applyChain(router, h.buildChain(router))
+}
+
+// SetupInternalRoutes configures the health, metrics and admin routes for the
+// internal listener, wrapped in the configured middleware chain
+func (h *Handler) SetupInternalRoutes() http.Handler {
+	router := mux.NewRouter()
+	h.registerInternalRoutes(router)
+
+	return applyChain(router, h.buildChain(router))
+}
+
+// RegisterRoutes adds the service routes to an existing router without
+// applying the middleware chain, for programs embedding the handlers in
+// their own server
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	h.registerInternalRoutes(router)
+	h.registerAPIRoutes(router)
+}
+
+// registerInternalRoutes adds the health, metrics and admin routes
+func (h *Handler) registerInternalRoutes(router *mux.Router) {
+	// Health check
+	router.HandleFunc("/health", h.HealthCheck).Methods("GET")
+	router.HandleFunc("/ready", h.Readiness).Methods("GET")
+
+	// Metrics, unless pushed to statsd
+	if h.cfg.MetricsSink == "" || h.cfg.MetricsSink == "prometheus" {
+		router.Handle("/metrics", h.metrics).Methods("GET")
+	}
+
+	// Admin API (only registered when ADMIN_API_KEY is set)
+	if h.cfg.AdminAPIKey != "" {
+		admin := router.PathPrefix("/admin/v1").Subrouter()
+		admin.HandleFunc("/bucket/status", h.requireAdmin(h.GetBucketStatus)).Methods("GET")
+		admin.HandleFunc("/iam-policy", h.requireAdmin(h.GetIAMPolicy)).Methods("GET")
+		admin.HandleFunc("/log-level", h.requireAdmin(h.GetLogLevel)).Methods("GET")
+		admin.HandleFunc("/log-level", h.requireAdmin(h.SetLogLevel)).Methods("PUT")
+		admin.HandleFunc("/read-only", h.requireAdmin(h.GetReadOnly)).Methods("GET")
+		admin.HandleFunc("/read-only", h.requireAdmin(h.SetReadOnly)).Methods("PUT")
+		admin.HandleFunc("/issued-urls", h.requireAdmin(h.ListIssuedURLs)).Methods("GET")
+		if h.anomalies != nil {
+			admin.HandleFunc("/anomalies", h.requireAdmin(h.ListAnomalies)).Methods("GET")
+		}
+
+		if h.tenants != nil {
+			admin.HandleFunc("/tenants", h.requireAdmin(h.ListTenants)).Methods("GET")
+			admin.HandleFunc("/tenants", h.requireAdmin(h.CreateTenant)).Methods("POST")
+			admin.HandleFunc("/tenants/{id}", h.requireAdmin(h.GetTenant)).Methods("GET")
+			admin.HandleFunc("/tenants/{id}/usage", h.requireAdmin(h.GetTenantUsage)).Methods("GET")
+			admin.HandleFunc("/tenants/{id}", h.requireAdmin(h.UpdateTenant)).Methods("PUT")
+			admin.HandleFunc("/tenants/{id}", h.requireAdmin(h.DeleteTenant)).Methods("DELETE")
+		}
+
+		if h.apiKeys != nil {
+			admin.HandleFunc("/api-keys", h.requireAdmin(h.ListAPIKeys)).Methods("GET")
+			admin.HandleFunc("/api-keys", h.requireAdmin(h.CreateAPIKey)).Methods("POST")
+			admin.HandleFunc("/api-keys/{id}/rotate", h.requireAdmin(h.RotateAPIKey)).Methods("POST")
+			admin.HandleFunc("/api-keys/{id}", h.requireAdmin(h.RevokeAPIKey)).Methods("DELETE")
+		}
+	}
+}
+
+// registerAPIRoutes adds the public API routes
+func (h *Handler) registerAPIRoutes(router *mux.Router) {
+	// API routes
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/auth/challenge", h.IssueChallenge).Methods("GET")
+	api.HandleFunc("/maintenance", h.GetMaintenance).Methods("GET")
+	api.HandleFunc("/object/search", h.requireOperation(OperationUpload, h.SearchObject)).Methods("POST")
+	api.HandleFunc("/object/dates", h.requireOperation(OperationDownload, h.ListDates)).Methods("GET")
+	api.HandleFunc("/object/browse", h.requireOperation(OperationDownload, h.BrowseObjects)).Methods("GET")
+	api.HandleFunc("/object/latest", h.requireOperation(OperationDownload, h.GetLatestObject)).Methods("GET")
+	api.HandleFunc("/object/diff", h.requireOperation(OperationDownload, h.DiffObjects)).Methods("POST")
+	api.HandleFunc("/presigned-url/upload", h.requireOperation(OperationUpload, h.requireWritable(h.GeneratePutURL))).Methods("POST")
+	api.HandleFunc("/presigned-url/preview", h.requireOperation(OperationUpload, h.PreviewUpload)).Methods("POST")
+	api.HandleFunc("/presigned-url/verify", h.VerifyPresignedURL).Methods("POST")
+	api.HandleFunc("/presigned-url/revoke", h.RevokePresignedURL).Methods("POST") // scope depends on the URL
+	api.HandleFunc("/object/confirm", h.requireOperation(OperationUpload, h.ConfirmObject)).Methods("POST")
+	api.HandleFunc("/object/move", h.requireOperation(OperationDelete, h.requireWritable(h.MoveObject))).Methods("POST")
+	api.HandleFunc("/objects/delete", h.requireOperation(OperationDelete, h.requireWritable(h.DeleteObjects))).Methods("POST")
+	api.HandleFunc("/object/metadata", h.requireOperation(OperationUpload, h.requireWritable(h.UpdateObjectMetadata))).Methods("PATCH")
+	api.HandleFunc("/object/lock", h.requireOperation(OperationDownload, h.GetObjectLock)).Methods("GET")
+	api.HandleFunc("/object/replication", h.requireOperation(OperationDownload, h.GetReplicationStatus)).Methods("GET")
+	api.HandleFunc("/object/select", h.requireOperation(OperationDownload, h.PresignSelect)).Methods("POST")
+	api.HandleFunc("/download-tokens", h.requireOperation(OperationDownload, h.IssueDownloadToken)).Methods("POST")
+	api.HandleFunc("/object/legal-hold", h.requireWritable(h.SetLegalHold)).Methods("PUT") // scope depends on the status
+
+	// Presigned URL round trip diagnostics
+	api.HandleFunc("/selftest", h.requireOperation(OperationUpload, h.requireWritable(h.SelfTest))).Methods("POST")
+	if h.cfg.SignerDebug == "header" || h.cfg.SignerDebug == "all" {
+		api.HandleFunc("/debug/signature", h.DiagnoseSignature).Methods("POST")
+	}
+
+	// Multipart upload sessions
+	api.HandleFunc("/sessions", h.requireOperation(OperationUpload, h.requireWritable(h.CreateSession))).Methods("POST")
+	api.HandleFunc("/sessions/{id}", h.requireOperation(OperationUpload, h.GetSession)).Methods("GET")
+	api.HandleFunc("/sessions/{id}", h.requireOperation(OperationUpload, h.AbortSession)).Methods("DELETE")
+	api.HandleFunc("/sessions/{id}/complete", h.requireOperation(OperationUpload, h.requireWritable(h.CompleteSession))).Methods("POST")
+	api.HandleFunc("/sessions/{id}/events", h.requireOperation(OperationUpload, h.StreamSessionEvents)).Methods("GET")
+	api.HandleFunc("/sessions/{id}/parts/{part}/url", h.requireOperation(OperationUpload, h.requireWritable(h.GeneratePartURL))).Methods("POST")
+	api.HandleFunc("/sessions/{id}/parts/{part}/complete", h.requireOperation(OperationUpload, h.CompletePart)).Methods("POST")
+
+	// Backup run manifests
+	api.HandleFunc("/runs", h.requireOperation(OperationUpload, h.CreateRun)).Methods("POST")
+	api.HandleFunc("/runs/{id}/status", h.requireOperation(OperationUpload, h.GetRunStatus)).Methods("GET")
+
+	// Chunked backups
+	api.HandleFunc("/chunked-backups", h.requireOperation(OperationUpload, h.requireWritable(h.CreateChunkedBackup))).Methods("POST")
+	api.HandleFunc("/chunked-backups/restore", h.requireOperation(OperationDownload, h.RestoreChunkedBackup)).Methods("POST")
+	api.HandleFunc("/chunked-backups/{id}", h.requireOperation(OperationUpload, h.GetChunkedBackup)).Methods("GET")
+	api.HandleFunc("/chunked-backups/{id}/complete", h.requireOperation(OperationUpload, h.requireWritable(h.CompleteChunkedBackup))).Methods("POST")
+	api.HandleFunc("/chunked-backups/{id}/chunks/{index}/url", h.requireOperation(OperationUpload, h.requireWritable(h.GenerateChunkURL))).Methods("POST")
+
+	// Catalog of confirmed objects (only registered with a catalog store)
+	if h.catalog != nil {
+		api.HandleFunc("/catalog/objects", h.requireOperation(OperationDownload, h.SearchCatalog)).Methods("GET")
+	}
+
+	// Data keys for client-side encryption (only registered with a KMS key)
+	if h.dataKeys != nil {
+		api.HandleFunc("/data-keys", h.requireOperation(OperationUpload, h.GenerateDataKey)).Methods("POST")
+	}
+
+	// Usage of issued URLs (only registered with ACCESS_LOG_PREFIX)
+	if h.usage != nil {
+		api.HandleFunc("/url-usage", h.requireOperation(OperationDownload, h.GetURLUsage)).Methods("GET")
+	}
+
+	// Inventory exports
+	api.HandleFunc("/inventory/exports", h.requireOperation(OperationDownload, h.requireWritable(h.CreateInventoryExport))).Methods("POST")
+	api.HandleFunc("/inventory/exports/{id}", h.requireOperation(OperationDownload, h.GetInventoryExport)).Methods("GET")
+
+	// Trash (only registered when SOFT_DELETE is set)
+	if h.cfg.SoftDelete {
+		api.HandleFunc("/trash", h.requireOperation(OperationDelete, h.requireWritable(h.TrashObject))).Methods("POST")
+		api.HandleFunc("/trash", h.requireOperation(OperationDelete, h.ListTrash)).Methods("GET")
+		api.HandleFunc("/trash/restore", h.requireOperation(OperationDelete, h.requireWritable(h.RestoreObject))).Methods("POST")
+	}
+
+	// Background job reports
+	api.HandleFunc("/maintenance/multipart-cleanup", h.requireOperation(OperationDownload, h.GetMultipartCleanupReport)).Methods("GET")
+	api.HandleFunc("/usage", h.requireOperation(OperationDownload, h.GetPrefixUsage)).Methods("GET")
+	api.HandleFunc("/backups/age", h.requireOperation(OperationDownload, h.GetBackupAges)).Methods("GET")
+	api.HandleFunc("/restore-plan", h.requireOperation(OperationDownload, h.GetRestorePlan)).Methods("GET")
+	api.HandleFunc("/restore-drills", h.requireOperation(OperationDownload, h.RunRestoreDrill)).Methods("POST")
+	api.HandleFunc("/restore-drills", h.requireOperation(OperationDownload, h.ListRestoreDrills)).Methods("GET")
+	api.HandleFunc("/restore-drills/{id}", h.requireOperation(OperationDownload, h.GetRestoreDrill)).Methods("GET")
+
+	// v2 API (operation scope is checked per request body)
+	v2 := router.PathPrefix("/api/v2").Subrouter()
+	v2.HandleFunc("/presigned-urls", h.PresignV2).Methods("POST")
+
+	// Download token redemption (the token authenticates the request)
+	if h.operationAllowed(OperationDownload) {
+		router.HandleFunc(downloadTokenPathPrefix+"{token}", h.RedeemDownloadToken).Methods("GET")
+	}
+
+	// Operator web UI (only registered with WEB_UI)
+	if h.cfg.WebUI {
+		router.Handle("/ui", http.RedirectHandler(uiPathPrefix, http.StatusMovedPermanently)).Methods("GET")
+		router.PathPrefix(uiPathPrefix).Handler(uiHandler()).Methods("GET", "HEAD")
+	}
+}
+
+// Helper functions
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	switch p := payload.(type) {
+	case ErrorResponse:
+		payload = localizeError(w, p)
+	case MaintenanceResponse:
+		p.ErrorResponse = localizeError(w, p.ErrorResponse)
+		payload = p
+	}
+
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Internal Server Error","message":"Failed to marshal response"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+// Error codes of S3 failures mapped by respondWithS3Error
+const (
+	CodeS3BucketNotFound = "S3_BUCKET_NOT_FOUND"
+	CodeS3AccessDenied   = "S3_ACCESS_DENIED"
+	CodeS3Throttled      = "S3_THROTTLED"
+)
+
+// s3ErrorStatuses maps S3 error codes to the response returned for them
+var s3ErrorStatuses = map[string]struct {
+	status int
+	code   string
+	error  string
+}{
+	"NoSuchBucket": {http.StatusNotFound, CodeS3BucketNotFound, "S3 bucket not found"},
+	"AccessDenied": {http.StatusForbidden, CodeS3AccessDenied, "Access denied by S3"},
+	"SlowDown":     {http.StatusTooManyRequests, CodeS3Throttled, "S3 is throttling requests"},
+}
+
+// respondWithS3Error responds to a failed S3 operation, returning 503 with
+// Retry-After while the circuit breaker is open, 504 when the operation timed
+// out, 404, 403 or 429 for the S3 errors in s3ErrorStatuses and 500
+// otherwise. When S3 answered, its request IDs are returned in the
+// x-amz-request-id and x-amz-id-2 headers and the body for AWS support cases.
+func (h *Handler) respondWithS3Error(w http.ResponseWriter, error string, err error) {
+	requestID, hostID := service.AWSRequestIDs(err)
+	if requestID != "" {
+		w.Header().Set("X-Amz-Request-Id", requestID)
+	}
+	if hostID != "" {
+		w.Header().Set("X-Amz-Id-2", hostID)
+	}
+	// retryAfter is the suggested delay in seconds for transient failures,
+	// 0 when retrying won't help
+	respond := func(status int, code, error string, retryAfter int) {
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		}
+		respondWithJSON(w, status, ErrorResponse{Error: error, Message: err.Error(), Code: code, AWSRequestID: requestID, Retryable: retryAfter > 0})
+	}
+
+	if errors.Is(err, resilience.ErrCircuitOpen) {
+		respond(http.StatusServiceUnavailable, "", "S3 is currently unavailable", h.cfg.S3BreakerCooldownSeconds)
+		return
+	}
+	var budgetErr *resilience.BudgetError
+	if errors.As(err, &budgetErr) {
+		respond(http.StatusServiceUnavailable, "", "S3 request budget exhausted", int(math.Ceil(budgetErr.RetryAfter.Seconds())))
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		respond(http.StatusGatewayTimeout, "", "S3 operation timed out", 1)
+		return
+	}
+	if mapped, ok := s3ErrorStatuses[service.S3ErrorCode(err)]; ok {
+		retryAfter := 0
+		if mapped.status == http.StatusTooManyRequests {
+			retryAfter = 1
+		}
+		respond(mapped.status, mapped.code, mapped.error, retryAfter)
+		return
+	}
+	if service.IsRetryable(err) {
+		respond(http.StatusInternalServerError, "", error, 1)
+		return
+	}
+	respond(http.StatusInternalServerError, "", error, 0)
+}
+
+// respondWithCodedError responds with an error carrying a machine-readable code
+func respondWithCodedError(w http.ResponseWriter, status int, code string, error string, message string) {
+	respondWithJSON(w, status, ErrorResponse{
+		Error:   error,
+		Message: message,
+		Code:    code,
+	})
+}
+
+// respondWithKeyError responds to an upload whose object key can't fit in the
+// S3 key length limit or whose subpath was rejected
+func respondWithKeyError(w http.ResponseWriter, err error) {
+	var subpathErr *service.SubpathError
+	if errors.As(err, &subpathErr) {
+		respondWithCodedError(w, http.StatusBadRequest, CodeValidationFailed, "Request validation failed", err.Error())
+		return
+	}
+	respondWithCodedError(w, http.StatusBadRequest, CodeKeyTooLong, "Object key too long", err.Error())
+}
+
+// respondWithSignedHeadersError responds to an upload whose signed headers
+// break the deployment's signed header policy
+func respondWithSignedHeadersError(w http.ResponseWriter, err error) {
+	respondWithCodedError(w, http.StatusBadRequest, CodeSignedHeadersPolicy, "Signed headers rejected by policy", err.Error())
+}
+
+func respondWithError(w http.ResponseWriter, code int, error string, message string) {
+	respondWithJSON(w, code, ErrorResponse{
+		Error:   error,
+		Message: message,
+	})
+}
+
+// respondWithRetryableError responds with a transient error, telling the
+// client to retry after retryAfter seconds
+func respondWithRetryableError(w http.ResponseWriter, code int, retryAfter int, error string, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	respondWithJSON(w, code, ErrorResponse{
+		Error:     error,
+		Message:   message,
+		Retryable: true,
+	})
+}
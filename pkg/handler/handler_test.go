@@ -0,0 +1,2324 @@
+package handler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/anomaly"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/audit"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/catalog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/chunks"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/envelope"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/handler"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/iampolicy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/restoredrill"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service/s3fake"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/webhook"
+)
+
+// testServer is a handler over an in-memory bucket
+type testServer struct {
+	t       *testing.T
+	http    http.Handler
+	handler *handler.Handler
+	bucket  *s3fake.Bucket
+}
+
+// newTestServer builds the routes with the middleware chain, configured from
+// settings (environment variable names) on top of test credentials
+func newTestServer(t *testing.T, settings map[string]string, opts ...handler.Option) *testServer {
+	t.Helper()
+	return newTestServerWith(t, settings, nil, opts...)
+}
+
+// newTestServerWith is newTestServer with extra options for the S3 service
+func newTestServerWith(t *testing.T, settings map[string]string, svcOpts []service.Option, opts ...handler.Option) *testServer {
+	t.Helper()
+
+	values := map[string]string{
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"S3_BUCKET_NAME":        "backups",
+		"COMPANY_PREFIX":        "acme",
+		"LOG_FORMAT":            "text",
+	}
+	for k, v := range settings {
+		values[k] = v
+	}
+	cfg, err := config.Load("", values)
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	bucket := s3fake.New()
+	svc, err := service.NewS3Service(context.Background(), cfg, append([]service.Option{service.WithS3Client(bucket)}, svcOpts...)...)
+	if err != nil {
+		t.Fatalf("NewS3Service: %v", err)
+	}
+	h := handler.NewHandler(svc, cfg, opts...)
+	return &testServer{t: t, http: h.SetupRoutes(), handler: h, bucket: bucket}
+}
+
+// do sends a request with an optional JSON body and returns the recorded
+// response
+func (s *testServer) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("json.Marshal: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.http.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a response body, failing the test on a status mismatch
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder, status int) T {
+	t.Helper()
+
+	var v T
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, status, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestHealthCheck(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	rec := s.do(http.MethodGet, "/health", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+}
+
+func TestStatsdMetrics(t *testing.T) {
+	daemon, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer daemon.Close()
+
+	sink, err := metrics.NewStatsdSink(daemon.LocalAddr().String(), "signer", true, []string{"env:test"})
+	if err != nil {
+		t.Fatalf("NewStatsdSink: %v", err)
+	}
+	registry := metrics.NewRegistry()
+	registry.AddSink(sink)
+	s := newTestServer(t, map[string]string{"METRICS_SINK": "dogstatsd"}, handler.WithMetrics(registry))
+
+	if rec := s.do(http.MethodGet, "/health", nil); rec.Code != http.StatusOK {
+		t.Fatalf("/health status = %d, want 200", rec.Code)
+	}
+	// Without a scrape endpoint metrics are only pushed
+	if rec := s.do(http.MethodGet, "/metrics", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("/metrics status = %d, want 404", rec.Code)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = daemon.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := daemon.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	want := "signer.http_requests_total:1|c|#env:test,method:GET,route:/health,status:200"
+	if lines := strings.Split(string(buf[:n]), "\n"); !slices.Contains(lines, want) {
+		t.Errorf("packet = %q, want a line %q", buf[:n], want)
+	}
+}
+
+func TestDegradedHandler(t *testing.T) {
+	degraded := handler.NewDegradedHandler(nil)
+	degraded.SetError(errors.New("failed to load AWS config: dial tcp: lookup sts.amazonaws.com: no such host"))
+	s := &testServer{t: t, http: degraded}
+
+	if rec := s.do(http.MethodGet, "/health", nil); rec.Code != http.StatusOK {
+		t.Errorf("/health status = %d, want 200", rec.Code)
+	}
+	ready := decode[handler.ReadinessResponse](t, s.do(http.MethodGet, "/ready", nil), http.StatusServiceUnavailable)
+	if ready.Status != "starting" || !strings.Contains(ready.Error, "no such host") {
+		t.Errorf("/ready = %+v, want starting with the initialization error", ready)
+	}
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"})
+	resp := decode[handler.ErrorResponse](t, rec, http.StatusServiceUnavailable)
+	if rec.Header().Get("Retry-After") == "" || !resp.Retryable {
+		t.Errorf("Retry-After = %q, retryable %t; want a retryable error", rec.Header().Get("Retry-After"), resp.Retryable)
+	}
+}
+
+func TestAdminPortSeparation(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ADMIN_PORT": "9090", "ADMIN_API_KEY": "admin-secret"})
+	internal := &testServer{t: t, http: s.handler.SetupInternalRoutes()}
+
+	for _, path := range []string{"/health", "/ready", "/metrics", "/admin/v1/log-level"} {
+		if rec := s.do(http.MethodGet, path, nil, "X-Admin-Key", "admin-secret"); rec.Code != http.StatusNotFound {
+			t.Errorf("public %s status = %d, want 404", path, rec.Code)
+		}
+		if rec := internal.do(http.MethodGet, path, nil, "X-Admin-Key", "admin-secret"); rec.Code != http.StatusOK {
+			t.Errorf("internal %s status = %d, want 200", path, rec.Code)
+		}
+	}
+	if rec := internal.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}); rec.Code != http.StatusNotFound {
+		t.Errorf("internal API status = %d, want 404", rec.Code)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}); rec.Code != http.StatusOK {
+		t.Errorf("public API status = %d, want 200", rec.Code)
+	}
+}
+
+func TestAPIKeyRequired(t *testing.T) {
+	s := newTestServer(t, map[string]string{"API_KEYS": "acme:k1"})
+
+	body := map[string]any{"filename": "a.txt"}
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a key: status = %d, want 401", rec.Code)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", body, "X-API-Key", "k1"); rec.Code != http.StatusOK {
+		t.Errorf("with a key: status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHMACSigning(t *testing.T) {
+	const (
+		globexSecret = "globex-secret-globex-secret-0123"
+		agentSecret  = "agent-secret-agent-secret-012345"
+	)
+	store := tenant.NewMemoryStore()
+	if err := store.Create(tenant.Tenant{ID: "globex", Prefix: "globex"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	s := newTestServer(t, map[string]string{
+		"MIDDLEWARE_CHAIN":            "recovery,hmac",
+		"HMAC_SECRETS":                "globex:" + globexSecret + ",agent:" + agentSecret + ":download",
+		"HMAC_MAX_OUTSTANDING_NONCES": "2",
+	}, handler.WithTenants(store))
+
+	challenge := func() string {
+		return decode[handler.ChallengeResponse](t, s.do(http.MethodGet, "/api/v1/auth/challenge", nil), http.StatusOK).Nonce
+	}
+	// signed returns the headers of a request signed over target's path and
+	// canonical query
+	signed := func(keyID, secret, nonce, method, target string, body any) []string {
+		data, _ := json.Marshal(body)
+		if body == nil {
+			data = nil
+		}
+		u, _ := url.Parse(target)
+		bodyHash := sha256.Sum256(data)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(strings.Join([]string{nonce, keyID, method, u.Path, u.Query().Encode(), hex.EncodeToString(bodyHash[:])}, "\n")))
+		return []string{"X-Signature-Key-Id", keyID, "X-Signature-Nonce", nonce, "X-Signature", "sha256=" + hex.EncodeToString(mac.Sum(nil))}
+	}
+
+	// The unauthenticated challenge endpoint caps outstanding nonces
+	first, second := challenge(), challenge()
+	rec := s.do(http.MethodGet, "/api/v1/auth/challenge", nil)
+	if resp := decode[handler.ErrorResponse](t, rec, http.StatusServiceUnavailable); !resp.Retryable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over the nonce cap: %+v, Retry-After %q", resp, rec.Header().Get("Retry-After"))
+	}
+
+	body := map[string]any{"filename": "db.dump"}
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", body, "X-Signature-Nonce", first, "X-Signature", "sha256=00"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a key ID: status = %d, want 401", rec.Code)
+	}
+
+	// A tenant's key scopes the request to the tenant's prefix
+	headers := signed("globex", globexSecret, first, http.MethodPost, "/api/v1/presigned-url/upload", body)
+	resp := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", body, headers...), http.StatusOK)
+	if !strings.Contains(resp.URL, "/globex/") {
+		t.Errorf("tenant upload URL = %s, want the globex prefix", resp.URL)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", body, headers...); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed nonce: status = %d, want 401", rec.Code)
+	}
+
+	// The query string is signed
+	headers = signed("globex", globexSecret, second, http.MethodGet, "/api/v1/object/latest?filename=db.dump", nil)
+	if rec := s.do(http.MethodGet, "/api/v1/object/latest?filename=other.dump", nil, headers...); rec.Code != http.StatusUnauthorized {
+		t.Errorf("tampered query: status = %d, want 401", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/object/latest?filename=db.dump", nil, headers...); rec.Code == http.StatusUnauthorized {
+		t.Errorf("signed query: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", body, signed("globex", agentSecret, challenge(), http.MethodPost, "/api/v1/presigned-url/upload", body)...); rec.Code != http.StatusUnauthorized {
+		t.Errorf("another key's secret: status = %d, want 401", rec.Code)
+	}
+
+	// Other keys authenticate as themselves, with their scopes
+	rec = s.do(http.MethodPost, "/api/v1/presigned-url/upload", body, signed("agent", agentSecret, challenge(), http.MethodPost, "/api/v1/presigned-url/upload", body)...)
+	if resp := decode[handler.ErrorResponse](t, rec, http.StatusForbidden); resp.Code != handler.CodeInsufficientScope {
+		t.Errorf("download-only key uploading: %+v", resp)
+	}
+}
+
+func TestGeneratePutURL(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	resp := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{
+		"filename":         "db.dump.gz",
+		"content_encoding": "gzip",
+		"content_md5":      "d41d8cd98f00b204e9800998ecf8427e",
+		"metadata":         map[string]string{"source": "nightly"},
+	}), http.StatusOK)
+
+	u, err := url.Parse(resp.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if !strings.HasPrefix(u.Path, "/acme/inputs/") || !strings.HasSuffix(u.Path, "/db.dump.gz") {
+		t.Errorf("path = %q", u.Path)
+	}
+	if got, want := u.Query().Get("X-Amz-SignedHeaders"), "content-encoding;content-md5;host;x-amz-meta-source"; got != want {
+		t.Errorf("X-Amz-SignedHeaders = %q, want %q", got, want)
+	}
+	if resp.Headers["content-md5"] != "1B2M2Y8AsgTpgAmY7PhCfg==" {
+		t.Errorf("headers = %v, want the base64 content-md5", resp.Headers)
+	}
+}
+
+func TestGeneratePutURLValidation(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"missing filename", map[string]any{}},
+		{"bad on_duplicate", map[string]any{"filename": "a", "on_duplicate": "overwrite"}},
+		{"bad content_encoding", map[string]any{"filename": "a", "content_encoding": "br"}},
+		{"bad content_md5", map[string]any{"filename": "a", "content_md5": "xyz"}},
+		{"not_before in the past", map[string]any{"filename": "a", "not_before": time.Now().Add(-time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPresignV2(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload,download"})
+	s.bucket.Put("acme/inputs/2025-11-24/14-30-00/db.dump", s3fake.Object{Body: []byte("x")})
+
+	tests := []struct {
+		name     string
+		body     map[string]any
+		status   int
+		code     string
+		method   string
+		objectOK bool
+	}{
+		{
+			name:   "upload",
+			body:   map[string]any{"operation": "upload", "filename": "db.dump", "content_type": "application/octet-stream", "content_length": 1024},
+			status: http.StatusOK, method: http.MethodPut,
+		},
+		{
+			name:   "download",
+			body:   map[string]any{"operation": "download", "object_key": "acme/inputs/2025-11-24/14-30-00/db.dump"},
+			status: http.StatusOK, method: http.MethodGet,
+		},
+		{
+			name:   "unknown operation",
+			body:   map[string]any{"operation": "list"},
+			status: http.StatusBadRequest, code: handler.CodeValidationFailed,
+		},
+		{
+			name:   "upload without content type",
+			body:   map[string]any{"operation": "upload", "filename": "db.dump"},
+			status: http.StatusBadRequest, code: handler.CodeValidationFailed,
+		},
+		{
+			name:   "subpath without a pattern",
+			body:   map[string]any{"operation": "upload", "filename": "db.dump", "content_type": "application/octet-stream", "subpath": "host-a"},
+			status: http.StatusBadRequest, code: handler.CodeValidationFailed,
+		},
+		{
+			name:   "delete not allowed",
+			body:   map[string]any{"operation": "delete", "object_key": "acme/inputs/2025-11-24/14-30-00/db.dump"},
+			status: http.StatusForbidden, code: handler.CodeOperationNotAllowed,
+		},
+		{
+			name:   "download outside the prefix",
+			body:   map[string]any{"operation": "download", "object_key": "globex/inputs/db.dump"},
+			status: http.StatusForbidden, code: handler.CodeForbiddenKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/api/v2/presigned-urls", tt.body)
+			if tt.code != "" {
+				resp := decode[handler.ErrorResponse](t, rec, tt.status)
+				if resp.Code != tt.code {
+					t.Errorf("code = %q, want %q", resp.Code, tt.code)
+				}
+				return
+			}
+			resp := decode[handler.PresignV2Response](t, rec, tt.status)
+			if resp.Method != tt.method || resp.URL == "" || resp.ObjectKey == "" {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestSSECustomerKey(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload,download,delete"})
+	keyMD5 := "1B2M2Y8AsgTpgAmY7PhCfg=="
+	want := map[string]string{
+		"x-amz-server-side-encryption-customer-algorithm": "AES256",
+		"x-amz-server-side-encryption-customer-key-md5":   keyMD5,
+	}
+	signed := func(t *testing.T, rawURL string) string {
+		t.Helper()
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Query().Get("X-Amz-SignedHeaders")
+	}
+
+	// Hex digests are converted to base64
+	upload := decode[handler.PresignV2Response](t, s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{
+		"operation": "upload", "filename": "db.dump", "content_type": "application/octet-stream", "sse_customer_key_md5": "d41d8cd98f00b204e9800998ecf8427e",
+	}), http.StatusOK)
+	for k, v := range want {
+		if upload.Headers[k] != v {
+			t.Errorf("upload header %s = %q, want %q", k, upload.Headers[k], v)
+		}
+	}
+	if got := signed(t, upload.URL); !strings.Contains(got, "x-amz-server-side-encryption-customer-key-md5") {
+		t.Errorf("upload X-Amz-SignedHeaders = %q, want the SSE-C headers", got)
+	}
+
+	download := decode[handler.PresignV2Response](t, s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{
+		"operation": "download", "object_key": "acme/inputs/2025-11-24/14-30-00/db.dump", "sse_customer_key_md5": keyMD5,
+	}), http.StatusOK)
+	if !maps.Equal(download.Headers, want) || signed(t, download.URL) != "host;x-amz-server-side-encryption-customer-algorithm;x-amz-server-side-encryption-customer-key-md5" {
+		t.Errorf("download headers = %v, signed %q", download.Headers, signed(t, download.URL))
+	}
+
+	v1 := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump", "sse_customer_key_md5": keyMD5}), http.StatusOK)
+	if v1.Headers["x-amz-server-side-encryption-customer-key-md5"] != keyMD5 {
+		t.Errorf("v1 headers = %v, want the SSE-C headers", v1.Headers)
+	}
+
+	for _, body := range []map[string]any{
+		{"operation": "upload", "filename": "db.dump", "content_type": "text/plain", "sse_customer_key_md5": "not-a-digest"},
+		{"operation": "delete", "object_key": "acme/inputs/2025-11-24/14-30-00/db.dump", "sse_customer_key_md5": keyMD5},
+	} {
+		decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v2/presigned-urls", body), http.StatusBadRequest)
+	}
+}
+
+func TestSignedHeadersPolicy(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"REQUIRED_SIGNED_HEADERS": "content-md5",
+		"ALLOWED_SIGNED_HEADERS":  "content-type,content-length,content-md5",
+	})
+
+	tests := []struct {
+		name string
+		path string
+		body map[string]any
+	}{
+		{"v1 without content_md5", "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}},
+		{"v1 with metadata", "/api/v1/presigned-url/upload", map[string]any{"filename": "a", "content_md5": "d41d8cd98f00b204e9800998ecf8427e", "metadata": map[string]string{"k": "v"}}},
+		{"v2 without content_md5", "/api/v2/presigned-urls", map[string]any{"operation": "upload", "filename": "a", "content_type": "text/plain"}},
+		{"sessions", "/api/v1/sessions", map[string]any{"filename": "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := decode[handler.ErrorResponse](t, s.do(http.MethodPost, tt.path, tt.body), http.StatusBadRequest)
+			if resp.Code != handler.CodeSignedHeadersPolicy {
+				t.Errorf("code = %q, want %q", resp.Code, handler.CodeSignedHeadersPolicy)
+			}
+		})
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a", "content_md5": "d41d8cd98f00b204e9800998ecf8427e"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("compliant upload: status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestInjectedMetadata(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"API_KEYS":          "backup-agent:k1",
+		"INJECTED_METADATA": "issued-by=signer,tenant={tenant},principal={principal},request-id={request_id}",
+	})
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload",
+		map[string]any{"filename": "a", "metadata": map[string]string{"Issued-By": "forged"}},
+		"X-API-Key", "k1", "X-Request-ID", "req-1")
+	resp := decode[handler.PresignedURLResponse](t, rec, http.StatusOK)
+
+	want := map[string]string{
+		"x-amz-meta-issued-by":  "signer",
+		"x-amz-meta-tenant":     "acme",
+		"x-amz-meta-principal":  "backup-agent",
+		"x-amz-meta-request-id": "req-1",
+	}
+	for k, v := range want {
+		if resp.Headers[k] != v {
+			t.Errorf("headers[%s] = %q, want %q", k, resp.Headers[k], v)
+		}
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want req-1", got)
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	// httptest requests come from 192.0.2.1, the trusted proxy
+	tests := []struct {
+		name    string
+		header  string // REAL_IP_HEADER
+		headers []string
+		want    string
+	}{
+		{"X-Forwarded-For", "X-Forwarded-For", []string{"X-Forwarded-For", "203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"prepended X-Forwarded-For hop", "X-Forwarded-For", []string{"X-Forwarded-For", "198.51.100.1, 203.0.113.7, 192.0.2.1"}, "203.0.113.7"},
+		{"spoofed Forwarded", "X-Forwarded-For", []string{"Forwarded", "for=198.51.100.1", "X-Forwarded-For", "203.0.113.7"}, "203.0.113.7"},
+		{"spoofed Forwarded without X-Forwarded-For", "X-Forwarded-For", []string{"Forwarded", "for=198.51.100.1"}, "192.0.2.1"},
+		{"Forwarded", "Forwarded", []string{"Forwarded", `for="[2001:db8::1]:4711";proto=https`}, "2001:db8::1"},
+		{"spoofed X-Forwarded-For", "Forwarded", []string{"X-Forwarded-For", "198.51.100.1", "Forwarded", "for=203.0.113.7"}, "203.0.113.7"},
+		{"spoofed X-Forwarded-For without Forwarded", "Forwarded", []string{"X-Forwarded-For", "198.51.100.1"}, "192.0.2.1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, map[string]string{
+				"TRUSTED_PROXIES":   "192.0.2.1,10.0.0.0/8",
+				"REAL_IP_HEADER":    tc.header,
+				"INJECTED_METADATA": "client-ip={client_ip}",
+			})
+			rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}, tc.headers...)
+			resp := decode[handler.PresignedURLResponse](t, rec, http.StatusOK)
+			if got := resp.Headers["x-amz-meta-client-ip"]; got != tc.want {
+				t.Errorf("client IP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	// Headers of untrusted connections are ignored
+	s := newTestServer(t, map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8", "INJECTED_METADATA": "client-ip={client_ip}"})
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}, "X-Forwarded-For", "203.0.113.7")
+	if got := decode[handler.PresignedURLResponse](t, rec, http.StatusOK).Headers["x-amz-meta-client-ip"]; got != "192.0.2.1" {
+		t.Errorf("client IP from an untrusted peer = %q, want 192.0.2.1", got)
+	}
+}
+
+func TestUploadIDCorrelation(t *testing.T) {
+	s := newTestServer(t, map[string]string{"INJECTED_METADATA": "upload-id={upload_id}"})
+
+	rec := s.do(http.MethodPost, "/api/v2/presigned-urls",
+		map[string]any{"operation": "upload", "filename": "db.dump", "content_type": "application/octet-stream"})
+	issued := decode[handler.PresignV2Response](t, rec, http.StatusOK)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(issued.UploadID) {
+		t.Fatalf("upload_id = %q, want a UUID", issued.UploadID)
+	}
+	if got := issued.Headers["x-amz-meta-upload-id"]; got != issued.UploadID {
+		t.Errorf("signed upload-id = %q, want %q", got, issued.UploadID)
+	}
+
+	// The client uploads with the signed headers, then confirms by upload ID
+	s.bucket.Put(issued.ObjectKey, s3fake.Object{Body: []byte("x"), Metadata: map[string]string{"upload-id": issued.UploadID}})
+	rec = s.do(http.MethodPost, "/api/v1/object/confirm", map[string]any{"upload_id": issued.UploadID})
+	confirmed := decode[handler.ConfirmObjectResponse](t, rec, http.StatusOK)
+	if confirmed.UploadID != issued.UploadID || confirmed.Object.Key != issued.ObjectKey || confirmed.Object.Metadata["upload-id"] != issued.UploadID {
+		t.Errorf("confirm = %+v, want object %s stored with upload-id %s", confirmed, issued.ObjectKey, issued.UploadID)
+	}
+
+	rec = s.do(http.MethodPost, "/api/v1/object/confirm", map[string]any{"upload_id": "00000000-0000-4000-8000-000000000000"})
+	decode[handler.ErrorResponse](t, rec, http.StatusNotFound)
+}
+
+func TestUpdateObjectMetadata(t *testing.T) {
+	s := newTestServer(t, map[string]string{"INJECTED_METADATA": "tenant={tenant}"})
+	key := "acme/inputs/2025-11-24/14-30-00/db.dump"
+	s.bucket.Put(key, s3fake.Object{Body: []byte("x"), Metadata: map[string]string{"tenant": "acme"}})
+
+	rec := s.do(http.MethodPatch, "/api/v1/object/metadata", map[string]any{
+		"object_key": key,
+		"metadata":   map[string]string{"verified": "true"},
+		"tags":       map[string]string{"status": "verified"},
+	})
+	info := decode[service.ObjectInfo](t, rec, http.StatusOK)
+	if info.Metadata["verified"] != "true" || info.Metadata["tenant"] != "acme" || info.TagCount != 1 {
+		t.Errorf("updated object = %+v", info)
+	}
+
+	// Injected provenance can't be rewritten
+	rec = s.do(http.MethodPatch, "/api/v1/object/metadata", map[string]any{"object_key": key, "remove": []string{"Tenant"}})
+	decode[handler.ErrorResponse](t, rec, http.StatusBadRequest)
+
+	rec = s.do(http.MethodPatch, "/api/v1/object/metadata", map[string]any{"object_key": "globex/db.dump", "metadata": map[string]string{"a": "b"}})
+	decode[handler.ErrorResponse](t, rec, http.StatusForbidden)
+}
+
+func TestAuditEvents(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []audit.Event
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			http.Error(w, "unexpected headers", http.StatusBadRequest)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		for decoder.More() {
+			var event audit.Event
+			if err := decoder.Decode(&event); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events = append(events, event)
+		}
+	}))
+	defer collector.Close()
+
+	forwarder := audit.NewForwarder(audit.NewHTTPSink(collector.URL, "Bearer token"), 10, time.Hour, nil)
+	forwarder.Start()
+	s := newTestServer(t, map[string]string{"API_KEYS": "backup-bot:k1"}, handler.WithAudit(forwarder))
+
+	rec := s.do(http.MethodPost, "/api/v2/presigned-urls",
+		map[string]any{"operation": "upload", "filename": "db.dump", "content_type": "application/octet-stream"},
+		"X-API-Key", "k1", "X-Request-ID", "req-7")
+	issued := decode[handler.PresignV2Response](t, rec, http.StatusOK)
+
+	// Close flushes the pending batch
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := forwarder.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 {
+		t.Fatalf("collector received %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Type != audit.EventURLIssued || e.Method != http.MethodPut || e.ObjectKey != issued.ObjectKey || e.UploadID != issued.UploadID {
+		t.Errorf("event = %+v, want url_issued PUT of %s with upload ID %s", e, issued.ObjectKey, issued.UploadID)
+	}
+	if e.Subject != "backup-bot" || e.RequestID != "req-7" || e.Endpoint != "/api/v2/presigned-urls" || e.ExpiresAt.IsZero() {
+		t.Errorf("event = %+v, want subject backup-bot, request req-7 and the v2 endpoint", e)
+	}
+}
+
+func TestPresignSelect(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	rec := s.do(http.MethodPost, "/api/v1/object/select", map[string]any{
+		"object_key":   "acme/inputs/2025-11-24/14-30-00/hosts.csv.gz",
+		"expression":   "SELECT s.host FROM S3Object s WHERE s.status = 'failed'",
+		"input_format": "CSV",
+		"compression":  "gzip",
+	})
+	resp := decode[handler.SelectResponse](t, rec, http.StatusOK)
+	if resp.Method != http.MethodPost || !strings.Contains(resp.URL, "&select=&select-type=2") {
+		t.Errorf("URL = %s %s, want a POST with the select subresource", resp.Method, resp.URL)
+	}
+	for _, want := range []string{"<CompressionType>GZIP</CompressionType>", "<FileHeaderInfo>USE</FileHeaderInfo>", "s.status = &#39;failed&#39;", "<JSON><RecordDelimiter>"} {
+		if !strings.Contains(resp.Body, want) {
+			t.Errorf("body lacks %s:\n%s", want, resp.Body)
+		}
+	}
+
+	rec = s.do(http.MethodPost, "/api/v1/object/select", map[string]any{
+		"object_key": "acme/inputs/a.csv", "expression": "SELECT * FROM S3Object", "input_format": "parquet",
+	})
+	decode[handler.ErrorResponse](t, rec, http.StatusBadRequest)
+}
+
+func TestCatalog(t *testing.T) {
+	if rec := newTestServer(t, nil).do(http.MethodGet, "/api/v1/catalog/objects", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("search without a catalog: status = %d, want 404", rec.Code)
+	}
+
+	s := newTestServer(t, nil, handler.WithCatalog(catalog.NewMemoryStore()))
+	s.bucket.Put("acme/inputs/2025-11-23/10-00-00/db.dump", s3fake.Object{Body: []byte("abc"), ChecksumSHA256: "c2hh"})
+	s.bucket.Put("acme/inputs/2025-11-24/10-00-00/db.dump", s3fake.Object{Body: []byte("abcd")})
+	s.bucket.Put("acme/inputs/2025-11-24/10-00-00/other.dump", s3fake.Object{Body: []byte("x")})
+	for _, key := range []string{"acme/inputs/2025-11-23/10-00-00/db.dump", "acme/inputs/2025-11-24/10-00-00/db.dump", "acme/inputs/2025-11-24/10-00-00/other.dump"} {
+		decode[handler.ConfirmObjectResponse](t, s.do(http.MethodPost, "/api/v1/object/confirm", map[string]any{"object_key": key}), http.StatusOK)
+	}
+
+	page := decode[handler.CatalogPage](t, s.do(http.MethodGet, "/api/v1/catalog/objects?filename=db.dump&max_keys=1", nil), http.StatusOK)
+	if len(page.Objects) != 1 || page.NextToken == "" {
+		t.Fatalf("first page = %+v, want 1 entry and a next token", page)
+	}
+	if got := page.Objects[0]; got.Key != "acme/inputs/2025-11-23/10-00-00/db.dump" || got.Size != 3 || got.ChecksumSHA256 != "c2hh" {
+		t.Errorf("entry = %+v", got)
+	}
+	page = decode[handler.CatalogPage](t, s.do(http.MethodGet, "/api/v1/catalog/objects?filename=db.dump&continuation_token="+url.QueryEscape(page.NextToken), nil), http.StatusOK)
+	if len(page.Objects) != 1 || page.Objects[0].Key != "acme/inputs/2025-11-24/10-00-00/db.dump" || page.NextToken != "" {
+		t.Errorf("second page = %+v", page)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/catalog/objects?prefix=other/", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("foreign prefix: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestCatalogDeduplication(t *testing.T) {
+	s := newTestServer(t, map[string]string{"CATALOG_STORE": "memory", "CATALOG_DEDUPLICATE": "true"}, handler.WithCatalog(catalog.NewMemoryStore()))
+	key := "acme/inputs/2025-11-23/10-00-00/nightly.dump"
+	sum := "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=" // SHA-256 of abc
+	s.bucket.Put(key, s3fake.Object{Body: []byte("abc"), ChecksumSHA256: sum})
+	decode[handler.ConfirmObjectResponse](t, s.do(http.MethodPost, "/api/v1/object/confirm", map[string]any{"object_key": key}), http.StatusOK)
+
+	upload := func(checksum, onDuplicate string) handler.PresignV2Response {
+		return decode[handler.PresignV2Response](t, s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{
+			"operation": "upload", "filename": "renamed.dump", "content_type": "application/octet-stream",
+			"checksum_sha256": checksum, "on_duplicate": onDuplicate,
+		}), http.StatusOK)
+	}
+
+	// Matched by checksum whatever the filename
+	if got := upload(sum, ""); !got.AlreadyExists || got.ObjectKey != key || got.URL != "" {
+		t.Errorf("confirmed checksum: response = %+v, want already_exists with %s", got, key)
+	}
+	if got := upload(sum, "allow"); got.AlreadyExists || got.URL == "" {
+		t.Errorf("on_duplicate=allow: response = %+v, want a URL", got)
+	}
+	if got := upload("2SmKENGwc1g33EvYXaxkGw887yekfl1TpU8vP1svz/o=", ""); got.AlreadyExists || got.URL == "" {
+		t.Errorf("unknown checksum: response = %+v, want a URL", got)
+	}
+
+	// Overwritten objects keep their catalog entry but are no longer answered
+	s.bucket.Put(key, s3fake.Object{Body: []byte("abcd"), ChecksumSHA256: "iNQmb9TmM40TuEX88olXnSCciXgjuSF9o+Fhk28DFYk="})
+	if got := upload(sum, ""); got.AlreadyExists || got.URL == "" {
+		t.Errorf("overwritten object: response = %+v, want a URL", got)
+	}
+}
+
+func TestObjectDiff(t *testing.T) {
+	s := newTestServer(t, nil)
+	day := time.Date(2025, 11, 23, 10, 0, 0, 0, time.UTC)
+	s.bucket.Put("acme/inputs/2025-11-23/10-00-00/db.dump", s3fake.Object{Body: []byte("v1"), LastModified: day})
+	s.bucket.Put("acme/inputs/2025-11-23/10-00-00/old.log", s3fake.Object{Body: []byte("x"), LastModified: day})
+	s.bucket.Put("acme/inputs/2025-11-23/10-00-00/conf/app.yml", s3fake.Object{Body: []byte("a"), LastModified: day})
+	// The later upload of the day stands for the path
+	s.bucket.Put("acme/inputs/2025-11-23/22-00-00/db.dump", s3fake.Object{Body: []byte("v2"), LastModified: day.Add(12 * time.Hour)})
+	s.bucket.Put("acme/inputs/2025-11-24/10-00-00/db.dump", s3fake.Object{Body: []byte("v2"), LastModified: day.AddDate(0, 0, 1)})
+	s.bucket.Put("acme/inputs/2025-11-24/10-00-00/conf/app.yml", s3fake.Object{Body: []byte("b"), LastModified: day.AddDate(0, 0, 1)})
+	s.bucket.Put("acme/inputs/2025-11-24/10-00-00/new.csv", s3fake.Object{Body: []byte("n"), LastModified: day.AddDate(0, 0, 1)})
+
+	diff := decode[service.FolderDiff](t, s.do(http.MethodPost, "/api/v1/object/diff", map[string]any{"from": "2025-11-23", "to": "2025-11-24"}), http.StatusOK)
+	if len(diff.Added) != 1 || diff.Added[0].Path != "new.csv" || diff.Added[0].Key != "acme/inputs/2025-11-24/10-00-00/new.csv" {
+		t.Errorf("added = %+v, want new.csv", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "old.log" {
+		t.Errorf("removed = %+v, want old.log", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Path != "conf/app.yml" || diff.Unchanged != 1 {
+		t.Errorf("changed = %+v, unchanged = %d, want conf/app.yml and db.dump unchanged", diff.Changed, diff.Unchanged)
+	}
+
+	// Upload folders compare their own files
+	diff = decode[service.FolderDiff](t, s.do(http.MethodPost, "/api/v1/object/diff", map[string]any{"from": "2025-11-23/10-00-00", "to": "2025-11-24/10-00-00"}), http.StatusOK)
+	if len(diff.Changed) != 2 || diff.Changed[0].Path != "conf/app.yml" || diff.Changed[1].Path != "db.dump" {
+		t.Errorf("upload folders: changed = %+v, want conf/app.yml and db.dump", diff.Changed)
+	}
+
+	manifest := func(key string, sums ...string) {
+		m := chunks.Manifest{Version: chunks.ManifestVersion, Filename: "big.tar"}
+		for i, sum := range sums {
+			m.Chunks = append(m.Chunks, chunks.Chunk{Index: i, ObjectKey: service.ManifestChunkKey(key, i), Size: 5, ChecksumSHA256: sum})
+		}
+		body, _ := json.Marshal(m)
+		s.bucket.Put(key, s3fake.Object{Body: body})
+	}
+	fromKey := "acme/inputs/2025-11-23/10-00-00/big.tar.manifest.json"
+	toKey := "acme/inputs/2025-11-24/10-00-00/big.tar.manifest.json"
+	manifest(fromKey, "a", "b")
+	manifest(toKey, "a", "c", "d")
+	chunkDiff := decode[handler.ManifestDiffResponse](t, s.do(http.MethodPost, "/api/v1/object/diff", map[string]any{"from_manifest": fromKey, "to_manifest": toKey}), http.StatusOK)
+	if chunkDiff.Unchanged != 1 || len(chunkDiff.Changed) != 1 || chunkDiff.Changed[0].Index != 1 || len(chunkDiff.Added) != 1 || len(chunkDiff.Removed) != 0 {
+		t.Errorf("manifest diff = %+v, want chunk 1 changed and chunk 2 added", chunkDiff)
+	}
+
+	for _, body := range []map[string]any{
+		{"from": "2025-11-23"},
+		{"from": "2025-11", "to": "2025-11-24"},
+		{"from": "2025-11-23", "to": "2025-11-24", "to_manifest": toKey},
+		{"from_manifest": fromKey, "to_manifest": "acme/inputs/2025-11-24/10-00-00/db.dump"},
+	} {
+		if rec := s.do(http.MethodPost, "/api/v1/object/diff", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestRestorePlan(t *testing.T) {
+	s := newTestServer(t, nil)
+	day := time.Date(2025, 11, 23, 2, 0, 0, 0, time.UTC)
+	s.bucket.Put("acme/inputs/2025-11-22/02-00-00/db.dump", s3fake.Object{Body: []byte("v1"), LastModified: day.AddDate(0, 0, -1)})
+	s.bucket.Put("acme/inputs/2025-11-22/02-00-00/conf/app.yml", s3fake.Object{Body: []byte("a"), LastModified: day.AddDate(0, 0, -1)})
+	s.bucket.Put("acme/inputs/2025-11-23/02-00-00/db.dump", s3fake.Object{Body: []byte("v2"), LastModified: day})
+	s.bucket.Put("acme/inputs/2025-11-24/02-00-00/db.dump", s3fake.Object{Body: []byte("v3"), LastModified: day.AddDate(0, 0, 1)})
+	s.bucket.Put("acme/inputs/2025-11-24/02-00-00/new.csv", s3fake.Object{Body: []byte("n"), LastModified: day.AddDate(0, 0, 1)})
+
+	plan := decode[handler.RestorePlanResponse](t, s.do(http.MethodGet, "/api/v1/restore-plan?at=2025-11-23T02:00:00Z&max_keys=1", nil), http.StatusOK)
+	if plan.TotalFiles != 2 || plan.TotalSize != 3 || len(plan.Files) != 1 || plan.NextToken != "conf/app.yml" {
+		t.Fatalf("first page = %+v, want conf/app.yml of 2 files", plan)
+	}
+	if f := plan.Files[0]; f.ObjectKey != "acme/inputs/2025-11-22/02-00-00/conf/app.yml" || f.Download == nil || f.Download.Method != http.MethodGet {
+		t.Errorf("file = %+v, want a download URL", f)
+	}
+
+	plan = decode[handler.RestorePlanResponse](t, s.do(http.MethodGet, "/api/v1/restore-plan?at=2025-11-23T02:00:00Z&max_keys=1&continuation_token="+plan.NextToken, nil), http.StatusOK)
+	if len(plan.Files) != 1 || plan.Files[0].ObjectKey != "acme/inputs/2025-11-23/02-00-00/db.dump" || plan.NextToken != "" {
+		t.Errorf("second page = %+v, want the db.dump uploaded at the target time", plan)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/restore-plan?at=yesterday", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid at: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestInventoryExport(t *testing.T) {
+	s := newTestServer(t, nil)
+	day := time.Date(2025, 11, 23, 10, 0, 0, 0, time.UTC)
+	s.bucket.Put("acme/inputs/2025-11-22/10-00-00/old.gz", s3fake.Object{Body: []byte("o"), LastModified: day.AddDate(0, 0, -1)})
+	s.bucket.Put("acme/inputs/2025-11-23/10-00-00/a,b.gz", s3fake.Object{Body: []byte("abc"), LastModified: day})
+	s.bucket.Put("acme/trash/2025-11-23T10-00-00Z/inputs/x.gz", s3fake.Object{Body: []byte("x"), LastModified: day})
+
+	// wait polls the export until it finishes
+	wait := func(id string) handler.InventoryExportResponse {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			resp := decode[handler.InventoryExportResponse](t, s.do(http.MethodGet, "/api/v1/inventory/exports/"+id, nil), http.StatusOK)
+			if resp.Status != "running" {
+				return resp
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("export %s did not finish", id)
+		return handler.InventoryExportResponse{}
+	}
+
+	created := decode[handler.InventoryExportResponse](t, s.do(http.MethodPost, "/api/v1/inventory/exports", map[string]any{
+		"from": "2025-11-23", "to": "2025-11-23",
+	}), http.StatusAccepted)
+	done := wait(created.ID)
+	if done.Status != "completed" || done.Rows != 1 || done.URL == "" {
+		t.Fatalf("export = %+v, want completed with 1 row and a download URL", done)
+	}
+	obj, ok := s.bucket.Get(done.ObjectKey)
+	if !ok || !strings.HasPrefix(done.ObjectKey, "acme/exports/inventory/") {
+		t.Fatalf("export object %q not written", done.ObjectKey)
+	}
+	want := "key,size,last_modified,etag,storage_class\n" +
+		"\"acme/inputs/2025-11-23/10-00-00/a,b.gz\",3,2025-11-23T10:00:00Z,900150983cd24fb0d6963f7d28e17f72,STANDARD\n"
+	if string(obj.Body) != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", obj.Body, want)
+	}
+
+	// Earlier exports are not listed again
+	created = decode[handler.InventoryExportResponse](t, s.do(http.MethodPost, "/api/v1/inventory/exports", map[string]any{"format": "parquet"}), http.StatusAccepted)
+	done = wait(created.ID)
+	obj, _ = s.bucket.Get(done.ObjectKey)
+	if done.Rows != 2 || !strings.HasSuffix(done.ObjectKey, ".parquet") || !bytes.HasPrefix(obj.Body, []byte("PAR1")) {
+		t.Errorf("parquet export = %+v", done)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/inventory/exports", map[string]any{"from": "2025-11-24", "to": "2025-11-23"})
+	decode[handler.ErrorResponse](t, rec, http.StatusBadRequest)
+	rec = s.do(http.MethodPost, "/api/v1/inventory/exports", map[string]any{"prefix": "other/"})
+	decode[handler.ErrorResponse](t, rec, http.StatusForbidden)
+	decode[handler.ErrorResponse](t, s.do(http.MethodGet, "/api/v1/inventory/exports/unknown", nil), http.StatusNotFound)
+}
+
+func TestUploadSession(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	type sessionResponse struct {
+		ID        string `json:"session_id"`
+		ObjectKey string `json:"object_key"`
+		UploadID  string `json:"upload_id"`
+		Status    string `json:"status"`
+	}
+	sess := decode[sessionResponse](t, s.do(http.MethodPost, "/api/v1/sessions", map[string]any{"filename": "big.tar"}), http.StatusCreated)
+
+	part := decode[handler.PartURLResponse](t, s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/parts/1/url", nil), http.StatusOK)
+	if !strings.Contains(part.URL, "partNumber=1") {
+		t.Errorf("part URL = %q", part.URL)
+	}
+
+	// Completing without parts is rejected
+	if rec := s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/complete", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("complete without parts: status = %d, want 400", rec.Code)
+	}
+
+	etag, err := s.bucket.UploadPart(sess.UploadID, 1, []byte("payload"))
+	if err != nil {
+		t.Fatalf("UploadPart: %v", err)
+	}
+	decode[sessionResponse](t, s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/parts/1/complete", map[string]string{"etag": etag}), http.StatusOK)
+
+	done := decode[sessionResponse](t, s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/complete", nil), http.StatusOK)
+	if done.Status != "completed" {
+		t.Errorf("status = %q, want completed", done.Status)
+	}
+	if obj, ok := s.bucket.Get(sess.ObjectKey); !ok || string(obj.Body) != "payload" {
+		t.Errorf("assembled object = %q, %v", obj.Body, ok)
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/complete", nil); rec.Code != http.StatusConflict {
+		t.Errorf("second complete: status = %d, want 409", rec.Code)
+	}
+}
+
+func TestChunkedBackup(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	created := decode[handler.ChunkedBackupResponse](t, s.do(http.MethodPost, "/api/v1/chunked-backups", map[string]any{
+		"filename": "db.dump.gz",
+		"chunks":   []map[string]any{{"size": 3}, {"size": 2}},
+	}), http.StatusCreated)
+	if len(created.Chunks) != 2 || created.TotalSize != 5 {
+		t.Fatalf("created = %+v", created)
+	}
+
+	// Nothing uploaded yet
+	resp := decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v1/chunked-backups/"+created.BackupID+"/complete", nil), http.StatusConflict)
+	if resp.Code != handler.CodeChunksMissing {
+		t.Errorf("code = %q, want %q", resp.Code, handler.CodeChunksMissing)
+	}
+
+	s.bucket.Put(created.Chunks[0].ObjectKey, s3fake.Object{Body: []byte("abc")})
+	s.bucket.Put(created.Chunks[1].ObjectKey, s3fake.Object{Body: []byte("toolong")})
+	resp = decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v1/chunked-backups/"+created.BackupID+"/complete", nil), http.StatusConflict)
+	if resp.Code != handler.CodeChunkSizeMismatch {
+		t.Errorf("code = %q, want %q", resp.Code, handler.CodeChunkSizeMismatch)
+	}
+
+	s.bucket.Put(created.Chunks[1].ObjectKey, s3fake.Object{Body: []byte("de")})
+	if rec := s.do(http.MethodPost, "/api/v1/chunked-backups/"+created.BackupID+"/complete", nil); rec.Code != http.StatusOK {
+		t.Fatalf("complete: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := s.bucket.Get(created.ManifestKey); !ok {
+		t.Fatalf("manifest %s was not written", created.ManifestKey)
+	}
+
+	restore := decode[handler.ChunkedRestoreResponse](t, s.do(http.MethodPost, "/api/v1/chunked-backups/restore",
+		map[string]string{"manifest_key": created.ManifestKey}), http.StatusOK)
+	if len(restore.Chunks) != 2 || restore.TotalSize != 5 || restore.Chunks[0].Method != http.MethodGet {
+		t.Errorf("restore = %+v", restore)
+	}
+}
+
+func TestListDates(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.bucket.Put("acme/inputs/2025-11-24/09-00-00/a.txt", s3fake.Object{Body: []byte("x")})
+	s.bucket.Put("acme/inputs/2025-11-24/14-30-00/b.txt", s3fake.Object{Body: []byte("x")})
+
+	resp := decode[handler.DatesResponse](t, s.do(http.MethodGet, "/api/v1/object/dates?prefix=2025-11", nil), http.StatusOK)
+	if resp.TotalUploads != 2 || len(resp.Dates) != 1 || resp.Dates[0].Date != "2025-11-24" {
+		t.Errorf("dates = %+v", resp)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/object/dates?prefix=11-2025", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad prefix: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBrowseObjects(t *testing.T) {
+	s := newTestServer(t, nil)
+	for _, key := range []string{
+		"acme/inputs/2025-11-24/09-00-00/a.txt",
+		"acme/inputs/2025-11-25/09-00-00/b.txt",
+		"acme/readme.txt",
+		"globex/inputs/2025-11-24/09-00-00/c.txt",
+	} {
+		s.bucket.Put(key, s3fake.Object{Body: []byte("x")})
+	}
+
+	root := decode[service.FolderListing](t, s.do(http.MethodGet, "/api/v1/object/browse", nil), http.StatusOK)
+	if root.Prefix != "acme/" || len(root.Folders) != 1 || root.Folders[0] != "acme/inputs/" ||
+		len(root.Objects) != 1 || root.Objects[0].Key != "acme/readme.txt" {
+		t.Errorf("root = %+v", root)
+	}
+
+	first := decode[service.FolderListing](t, s.do(http.MethodGet, "/api/v1/object/browse?prefix=acme/inputs/&max_keys=1", nil), http.StatusOK)
+	if len(first.Folders) != 1 || first.Folders[0] != "acme/inputs/2025-11-24/" || first.NextToken == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+	second := decode[service.FolderListing](t, s.do(http.MethodGet,
+		"/api/v1/object/browse?prefix=acme/inputs/&max_keys=1&continuation_token="+url.QueryEscape(first.NextToken), nil), http.StatusOK)
+	if len(second.Folders) != 1 || second.Folders[0] != "acme/inputs/2025-11-25/" || second.NextToken != "" {
+		t.Errorf("second page = %+v", second)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/object/browse?prefix=globex/", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("other prefix: status = %d, want 403", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/object/browse?max_keys=0", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("max_keys=0: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBrowseObjectsNDJSON(t *testing.T) {
+	s := newTestServer(t, nil)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		s.bucket.Put("acme/exports/"+name+".csv", s3fake.Object{Body: []byte("x")})
+	}
+	s.bucket.Put("acme/exports/2025/f.csv", s3fake.Object{Body: []byte("x")})
+
+	// Pages of two are streamed back to back
+	rec := s.do(http.MethodGet, "/api/v1/object/browse?prefix=acme/exports/&max_keys=2", nil, "Accept", "application/x-ndjson")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var folders, objects []string
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var line handler.BrowseRecord
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("invalid NDJSON line: %v", err)
+		}
+		if line.Object != nil {
+			objects = append(objects, line.Object.Key)
+		} else {
+			folders = append(folders, line.Folder)
+		}
+	}
+	if len(folders) != 1 || folders[0] != "acme/exports/2025/" || len(objects) != 5 {
+		t.Errorf("folders = %v, objects = %v; want 1 folder and 5 objects", folders, objects)
+	}
+}
+
+func TestConditionalListing(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.bucket.Put("acme/exports/a.csv", s3fake.Object{Body: []byte("x")})
+	path := "/api/v1/object/browse?prefix=acme/exports/"
+
+	first := s.do(http.MethodGet, path, nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("status = %d, ETag %q; want 200 with a weak ETag", first.Code, etag)
+	}
+
+	rec := s.do(http.MethodGet, path, nil, "If-None-Match", `"other", `+etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("unchanged listing: status = %d, body %q; want an empty 304", rec.Code, rec.Body.String())
+	}
+
+	// A new object changes the listing
+	s.bucket.Put("acme/exports/b.csv", s3fake.Object{Body: []byte("x")})
+	rec = s.do(http.MethodGet, path, nil, "If-None-Match", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed listing: status = %d, ETag %q; want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestGzipResponses(t *testing.T) {
+	s := newTestServer(t, map[string]string{"GZIP_ENABLED": "true", "GZIP_MIN_BYTES": "512"})
+	for i := 0; i < 50; i++ {
+		s.bucket.Put(fmt.Sprintf("acme/exports/%02d.csv", i), s3fake.Object{Body: []byte("x")})
+	}
+
+	rec := s.do(http.MethodGet, "/api/v1/object/browse?prefix=acme/exports/", nil, "Accept-Encoding", "gzip")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("status = %d, Content-Encoding %q; want gzip", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	var listing service.FolderListing
+	if err := json.NewDecoder(zr).Decode(&listing); err != nil || len(listing.Objects) != 50 {
+		t.Errorf("decompressed listing = %d objects, %v; want 50", len(listing.Objects), err)
+	}
+
+	// Small responses and clients without gzip get plain JSON
+	if rec := s.do(http.MethodGet, "/health", nil, "Accept-Encoding", "gzip"); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("small response Content-Encoding = %q, want none", rec.Header().Get("Content-Encoding"))
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/object/browse?prefix=acme/exports/", nil, "Accept-Encoding", "gzip;q=0"); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("refused gzip: Content-Encoding = %q, want none", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestGetLatestObject(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.bucket.Put("acme/inputs/2025-11-23/10-00-00/db.dump", s3fake.Object{Body: []byte("old")})
+	s.bucket.Put("acme/inputs/2025-11-24/10-00-00/db.dump", s3fake.Object{Body: []byte("new"), Metadata: map[string]string{"source": "nightly"}})
+
+	resp := decode[handler.LatestObjectResponse](t, s.do(http.MethodGet, "/api/v1/object/latest?filename=db.dump", nil), http.StatusOK)
+	if resp.Object.Key != "acme/inputs/2025-11-24/10-00-00/db.dump" || resp.Object.Metadata["source"] != "nightly" {
+		t.Errorf("object = %+v", resp.Object)
+	}
+	if resp.Download.Method != http.MethodGet || !strings.Contains(resp.Download.URL, "/acme/inputs/2025-11-24/10-00-00/db.dump?") {
+		t.Errorf("download = %+v", resp.Download)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/object/latest?filename=other.dump", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no match: status = %d, want 404", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/object/latest?filename=%5B", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad pattern: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRetentionBlocksDeletes(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload,download,delete", "MIN_RETENTION_HOURS": "24"})
+	s.bucket.Put("acme/inputs/fresh.dump", s3fake.Object{Body: []byte("x"), LastModified: time.Now().Add(-time.Hour)})
+	s.bucket.Put("acme/inputs/old.dump", s3fake.Object{Body: []byte("x"), LastModified: time.Now().Add(-48 * time.Hour)})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   map[string]any
+		status int
+	}{
+		{"delete URL for a fresh object", http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "delete", "object_key": "acme/inputs/fresh.dump"}, http.StatusForbidden},
+		{"delete URL for an old object", http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "delete", "object_key": "acme/inputs/old.dump"}, http.StatusOK},
+		{"delete URL for a missing object", http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "delete", "object_key": "acme/inputs/missing.dump"}, http.StatusOK},
+		{"move a fresh object", http.MethodPost, "/api/v1/object/move", map[string]any{"source_key": "acme/inputs/fresh.dump", "destination_key": "acme/archive/fresh.dump"}, http.StatusForbidden},
+		{"move an old object", http.MethodPost, "/api/v1/object/move", map[string]any{"source_key": "acme/inputs/old.dump", "destination_key": "acme/archive/old.dump"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(tt.method, tt.path, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status == http.StatusForbidden {
+				if resp := decode[handler.ErrorResponse](t, rec, tt.status); resp.Code != handler.CodeRetentionActive {
+					t.Errorf("code = %q, want %q", resp.Code, handler.CodeRetentionActive)
+				}
+			}
+		})
+	}
+	if _, ok := s.bucket.Get("acme/inputs/fresh.dump"); !ok {
+		t.Error("fresh object was deleted")
+	}
+}
+
+func TestMultipartCleanup(t *testing.T) {
+	store := tenant.NewMemoryStore()
+	if err := store.Create(tenant.Tenant{ID: "globex", Prefix: "globex", APIKeyHash: tenant.HashAPIKey("globex-key")}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	s := newTestServer(t, map[string]string{"API_KEYS": "agent-key"}, handler.WithTenants(store))
+
+	start := func(key string) {
+		if _, err := s.bucket.CreateMultipartUpload(context.Background(), &s3.CreateMultipartUploadInput{Bucket: aws.String("backups"), Key: aws.String(key)}); err != nil {
+			t.Fatalf("CreateMultipartUpload(%s): %v", key, err)
+		}
+	}
+	s.bucket.SetNow(func() time.Time { return time.Now().Add(-48 * time.Hour) })
+	start("acme/inputs/stale.dump")
+	start("globex/inputs/stale.dump")
+	s.bucket.SetNow(time.Now)
+	start("globex/inputs/fresh.dump")
+
+	if err := s.handler.AbortStaleMultipartUploads(context.Background()); err != nil {
+		t.Fatalf("AbortStaleMultipartUploads: %v", err)
+	}
+	uploads, err := s.bucket.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("backups")})
+	if err != nil {
+		t.Fatalf("ListMultipartUploads: %v", err)
+	}
+	if len(uploads.Uploads) != 1 || aws.ToString(uploads.Uploads[0].Key) != "globex/inputs/fresh.dump" {
+		t.Errorf("uploads left = %d, want only the fresh tenant upload", len(uploads.Uploads))
+	}
+
+	// Each caller sees the report of its own prefix
+	for key, want := range map[string]string{"agent-key": "acme/inputs/stale.dump", "globex-key": "globex/inputs/stale.dump"} {
+		report := decode[service.CleanupReport](t, s.do(http.MethodGet, "/api/v1/maintenance/multipart-cleanup", nil, "X-API-Key", key), http.StatusOK)
+		if len(report.Aborted) != 1 || report.Aborted[0].ObjectKey != want {
+			t.Errorf("%s: aborted = %+v, want only %s", key, report.Aborted, want)
+		}
+	}
+}
+
+func TestReportsScopedToTenant(t *testing.T) {
+	store := tenant.NewMemoryStore()
+	for _, id := range []string{"globex", "initech"} {
+		expected := []tenant.ExpectedBackup{{Filename: id + ".dump", MaxAgeHours: 24}}
+		if err := store.Create(tenant.Tenant{ID: id, Prefix: id, ExpectedBackups: expected, APIKeyHash: tenant.HashAPIKey(id + "-key")}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	s := newTestServer(t, map[string]string{"API_KEYS": "agent-key", "EXPECTED_BACKUPS": "acme.dump=24"}, handler.WithTenants(store))
+	s.bucket.Put("acme/inputs/2025-11-24/02-00-00/acme.dump", s3fake.Object{Body: []byte("a")})
+	s.bucket.Put("globex/inputs/2025-11-24/02-00-00/globex.dump", s3fake.Object{Body: []byte("gg")})
+	s.bucket.Put("initech/inputs/2025-11-24/02-00-00/initech.dump", s3fake.Object{Body: []byte("iii")})
+
+	if err := s.handler.CollectPrefixUsage(context.Background()); err != nil {
+		t.Fatalf("CollectPrefixUsage: %v", err)
+	}
+	if err := s.handler.CheckStaleBackups(context.Background()); err != nil {
+		t.Fatalf("CheckStaleBackups: %v", err)
+	}
+
+	// A key only reads the reports of its own prefix, never another tenant's
+	// or the company's
+	tests := []struct {
+		key    string
+		prefix string
+		bytes  int64
+		backup string
+	}{
+		{"agent-key", "acme/", 1, "acme.dump"},
+		{"globex-key", "globex/", 2, "globex.dump"},
+		{"initech-key", "initech/", 3, "initech.dump"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.key, func(t *testing.T) {
+			usage := decode[service.UsageReport](t, s.do(http.MethodGet, "/api/v1/usage", nil, "X-API-Key", tc.key), http.StatusOK)
+			if usage.Prefix != tc.prefix || usage.TotalObjects != 1 || usage.TotalBytes != tc.bytes {
+				t.Errorf("usage = %+v, want 1 object of %d bytes under %s", usage, tc.bytes, tc.prefix)
+			}
+			ages := decode[handler.BackupAgeReport](t, s.do(http.MethodGet, "/api/v1/backups/age", nil, "X-API-Key", tc.key), http.StatusOK)
+			if len(ages.Backups) != 1 || ages.Backups[0].Filename != tc.backup {
+				t.Errorf("backup ages = %+v, want only %s", ages.Backups, tc.backup)
+			}
+		})
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload,download,delete", "SOFT_DELETE": "true"})
+	s.bucket.Put("acme/inputs/db.dump", s3fake.Object{Body: []byte("x")})
+
+	resp := decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "delete", "object_key": "acme/inputs/db.dump"}), http.StatusForbidden)
+	if resp.Code != handler.CodeOperationNotAllowed {
+		t.Errorf("delete URL code = %q, want %q", resp.Code, handler.CodeOperationNotAllowed)
+	}
+
+	trashed := decode[service.TrashedObject](t, s.do(http.MethodPost, "/api/v1/trash", map[string]string{"object_key": "acme/inputs/db.dump"}), http.StatusOK)
+	if _, ok := s.bucket.Get("acme/inputs/db.dump"); ok {
+		t.Error("object still exists after trashing")
+	}
+
+	page := decode[service.TrashPage](t, s.do(http.MethodGet, "/api/v1/trash", nil), http.StatusOK)
+	if len(page.Objects) != 1 || page.Objects[0].OriginalKey != "acme/inputs/db.dump" {
+		t.Fatalf("trash = %+v, want the trashed object", page.Objects)
+	}
+
+	s.bucket.Put("acme/inputs/db.dump", s3fake.Object{Body: []byte("new")})
+	restore := map[string]any{"trash_key": trashed.TrashKey}
+	if rec := s.do(http.MethodPost, "/api/v1/trash/restore", restore); rec.Code != http.StatusConflict {
+		t.Fatalf("restore over an existing object: status = %d, want 409: %s", rec.Code, rec.Body.String())
+	}
+	restore["overwrite"] = true
+	decode[handler.RestoreResponse](t, s.do(http.MethodPost, "/api/v1/trash/restore", restore), http.StatusOK)
+	if obj, ok := s.bucket.Get("acme/inputs/db.dump"); !ok || string(obj.Body) != "x" {
+		t.Errorf("restored object = %q, %v; want the trashed body", obj.Body, ok)
+	}
+	if _, ok := s.bucket.Get(trashed.TrashKey); ok {
+		t.Error("trashed object still exists after restoring")
+	}
+}
+
+func TestReplicationStatus(t *testing.T) {
+	s := newTestServer(t, map[string]string{"DR_BUCKET_NAME": "backups-dr", "DR_REGION": "us-west-2", "S3_RETRY_MAX_ATTEMPTS": "1"})
+	s.bucket.Put("acme/inputs/db.dump", s3fake.Object{Body: []byte("x"), ReplicationStatus: types.ReplicationStatusCompleted})
+
+	resp := decode[handler.ReplicationResponse](t, s.do(http.MethodGet, "/api/v1/object/replication?object_key=acme/inputs/db.dump", nil), http.StatusOK)
+	if !resp.PrimaryAvailable || resp.ReplicationStatus != "COMPLETED" || resp.Fallback != nil {
+		t.Errorf("replication = %+v, want COMPLETED from the primary", resp)
+	}
+
+	s.bucket.FailWith("HeadObject", errors.New("connection refused"))
+	resp = decode[handler.ReplicationResponse](t, s.do(http.MethodGet, "/api/v1/object/replication?object_key=acme/inputs/db.dump", nil), http.StatusOK)
+	if resp.PrimaryAvailable || resp.Fallback == nil {
+		t.Fatalf("replication = %+v, want a DR fallback", resp)
+	}
+	if want := "https://backups-dr.s3.us-west-2.amazonaws.com/acme/inputs/db.dump?"; !strings.HasPrefix(resp.Fallback.URL, want) {
+		t.Errorf("fallback URL = %q, want prefix %q", resp.Fallback.URL, want)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	s := newTestServer(t, map[string]string{"MAX_CONCURRENT_REQUESTS": "1"})
+	s.bucket.Delay("ListObjectsV2", 200*time.Millisecond)
+
+	done := make(chan int)
+	go func() {
+		done <- s.do(http.MethodPost, "/api/v1/object/search", map[string]string{"filename": "db.dump"}).Code
+	}()
+	for s.bucket.Calls("ListObjectsV2") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/object/search", map[string]string{"filename": "db.dump"})
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("second request: status = %d, Retry-After %q; want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := s.do(http.MethodGet, "/health", nil); rec.Code != http.StatusOK {
+		t.Errorf("health while busy: status = %d, want 200", rec.Code)
+	}
+	if code := <-done; code == http.StatusServiceUnavailable {
+		t.Errorf("first request was shed")
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/object/search", map[string]string{"filename": "db.dump"}); rec.Code == http.StatusServiceUnavailable {
+		t.Errorf("request after the slot was released was shed")
+	}
+}
+
+func TestVerifyAndRevoke(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	issued := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}), http.StatusOK)
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/verify", map[string]string{"url": issued.URL}); rec.Code != http.StatusOK {
+		t.Fatalf("verify: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/revoke", map[string]string{"url": issued.URL, "reason": "leaked"}); rec.Code != http.StatusOK {
+		t.Fatalf("revoke: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/verify", map[string]string{"url": issued.URL}), http.StatusForbidden)
+	if resp.Code != handler.CodeURLRevoked {
+		t.Errorf("code = %q, want %q", resp.Code, handler.CodeURLRevoked)
+	}
+}
+
+func TestURLUsage(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ACCESS_LOG_PREFIX": "logs/"})
+
+	issued := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}), http.StatusOK)
+	u, err := url.Parse(issued.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	// record formats an access log record of a request to uri
+	record := func(uri string, status int) string {
+		when := time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700")
+		return fmt.Sprintf(`owner backups [%s] 203.0.113.7 - REQ%d REST.PUT.OBJECT %s "%s" %d - - 3 10 5 "-" "curl/8.5.0" - host SigV4 - QueryString backups.s3.amazonaws.com TLSv1.3 - -`,
+			when, status, url.PathEscape(key), uri, status)
+	}
+	logs := strings.Join([]string{
+		record("PUT "+u.RequestURI()+" HTTP/1.1", http.StatusOK),
+		record("PUT /acme/other?X-Amz-Signature=feed HTTP/1.1", http.StatusForbidden), // Not issued here
+		record("GET /acme/other HTTP/1.1", http.StatusOK),                             // Not presigned
+		"truncated record",
+	}, "\n")
+	s.bucket.Put("logs/2025-11-24-10-00-00-ABC", s3fake.Object{Body: []byte(logs)})
+
+	for range 2 { // Already ingested logs are skipped
+		if err := s.handler.IngestAccessLogs(context.Background()); err != nil {
+			t.Fatalf("ingest: %v", err)
+		}
+	}
+
+	usage := decode[handler.URLUsageResponse](t, s.do(http.MethodGet, "/api/v1/url-usage?url="+url.QueryEscape(issued.URL), nil), http.StatusOK)
+	if len(usage.Usage) != 1 || usage.IngestedAt.IsZero() {
+		t.Fatalf("usage = %+v, want the one upload", usage)
+	}
+	if got := usage.Usage[0]; got.RemoteIP != "203.0.113.7" || got.HTTPStatus != http.StatusOK || got.URL.ObjectKey != key || got.URL.Method != http.MethodPut {
+		t.Errorf("usage = %+v, want the PUT from 203.0.113.7 matched to the issued URL", got)
+	}
+
+	usage = decode[handler.URLUsageResponse](t, s.do(http.MethodGet, "/api/v1/url-usage?object_key=acme/other", nil), http.StatusOK)
+	if len(usage.Usage) != 0 {
+		t.Errorf("usage of an unknown key = %+v, want none", usage.Usage)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/url-usage?since=yesterday", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since: status = %d, want 400", rec.Code)
+	}
+}
+
+// TestConcurrentRequests exercises the shared stores from many goroutines;
+// run with -race
+func TestConcurrentRequests(t *testing.T) {
+	s := newTestServer(t, map[string]string{"RATE_LIMIT_RPS": "0"})
+
+	const workers = 16
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"})
+			if rec.Code != http.StatusOK {
+				t.Errorf("upload: status = %d", rec.Code)
+				return
+			}
+			var issued handler.PresignedURLResponse
+			_ = json.Unmarshal(rec.Body.Bytes(), &issued)
+			s.do(http.MethodPost, "/api/v1/presigned-url/verify", map[string]string{"url": issued.URL})
+
+			rec = s.do(http.MethodPost, "/api/v1/chunked-backups", map[string]any{"filename": "c", "chunks": []map[string]any{{"size": 1}}})
+			if rec.Code != http.StatusCreated {
+				t.Errorf("chunked backup: status = %d", rec.Code)
+				return
+			}
+			var backup handler.ChunkedBackupResponse
+			_ = json.Unmarshal(rec.Body.Bytes(), &backup)
+			s.bucket.Put(backup.Chunks[0].ObjectKey, s3fake.Object{Body: []byte("x")})
+			s.do(http.MethodPost, "/api/v1/chunked-backups/"+backup.BackupID+"/complete", nil)
+
+			rec = s.do(http.MethodPost, "/api/v1/sessions", map[string]any{"filename": "b"})
+			var sess struct {
+				ID string `json:"session_id"`
+			}
+			_ = json.Unmarshal(rec.Body.Bytes(), &sess)
+			s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/parts/1/complete", map[string]string{"etag": "e"})
+			s.do(http.MethodGet, "/metrics", nil)
+		}()
+	}
+	wg.Wait()
+}
+
+// TestConcurrentSessionComplete checks that completing a session from several
+// requests at once assembles the object exactly once
+func TestConcurrentSessionComplete(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	var sess struct {
+		ID       string `json:"session_id"`
+		UploadID string `json:"upload_id"`
+	}
+	rec := s.do(http.MethodPost, "/api/v1/sessions", map[string]any{"filename": "big.tar"})
+	if err := json.Unmarshal(rec.Body.Bytes(), &sess); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("create session: %d %s", rec.Code, rec.Body.String())
+	}
+	etag, _ := s.bucket.UploadPart(sess.UploadID, 1, []byte("payload"))
+	s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/parts/1/complete", map[string]string{"etag": etag})
+	s.bucket.Delay("CompleteMultipartUpload", 50*time.Millisecond)
+
+	const requests = 8
+	codes := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = s.do(http.MethodPost, "/api/v1/sessions/"+sess.ID+"/complete", nil).Code
+		}(i)
+	}
+	wg.Wait()
+
+	completed := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			completed++
+		case http.StatusConflict:
+		default:
+			t.Errorf("complete: status = %d, want 200 or 409", code)
+		}
+	}
+	if completed != 1 {
+		t.Errorf("%d requests completed the session, want 1", completed)
+	}
+	if calls := s.bucket.Calls("CompleteMultipartUpload"); calls != 1 {
+		t.Errorf("CompleteMultipartUpload called %d times, want 1", calls)
+	}
+}
+
+func TestDiagnoseSignature(t *testing.T) {
+	s := newTestServer(t, map[string]string{"SIGNER_DEBUG": "header"})
+
+	issued := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{
+		"filename": "db.dump.gz",
+		"metadata": map[string]string{"source": "nightly"},
+	}), http.StatusOK)
+	diagnose := func(headers map[string]string, s3Error string) service.SignatureDiagnosis {
+		t.Helper()
+		return decode[service.SignatureDiagnosis](t, s.do(http.MethodPost, "/api/v1/debug/signature", handler.SignatureDiagnosisRequest{
+			Method:  http.MethodPut,
+			URL:     issued.URL,
+			Headers: headers,
+			S3Error: s3Error,
+		}), http.StatusOK)
+	}
+
+	// The request as issued carries a valid signature
+	signed := map[string]string{"X-Amz-Meta-Source": "nightly"}
+	sent := diagnose(signed, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+	if sent.SignatureValid == nil || !*sent.SignatureValid || sent.Divergence != nil {
+		t.Fatalf("diagnosis of the issued request = %+v, want a valid signature", sent)
+	}
+
+	// A proxy rewrote a signed header on the way to S3
+	rewritten := strings.Replace(sent.CanonicalRequest, "x-amz-meta-source:nightly", "x-amz-meta-source:other", 1)
+	got := diagnose(signed, "<Error><Code>SignatureDoesNotMatch</Code><CanonicalRequest>"+
+		strings.ReplaceAll(rewritten, "&", "&amp;")+"</CanonicalRequest></Error>")
+	want := service.SignatureDivergence{Component: "header", Name: "x-amz-meta-source", Expected: "nightly", Actual: "other"}
+	if got.Divergence == nil || *got.Divergence != want {
+		t.Errorf("divergence = %+v, want %+v", got.Divergence, want)
+	}
+
+	// Headers other than those signed don't match the URL's signature
+	got = diagnose(map[string]string{"X-Amz-Meta-Source": "other"}, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+	if got.SignatureValid == nil || *got.SignatureValid {
+		t.Errorf("signature_valid = %v with other headers, want false", got.SignatureValid)
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/debug/signature", handler.SignatureDiagnosisRequest{
+		Method: http.MethodPut, URL: "https://backups.s3.amazonaws.com/acme/db.dump", S3Error: "<Error/>",
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("unsigned URL status = %d, want 400", rec.Code)
+	}
+}
+
+func TestS3ErrorMapping(t *testing.T) {
+	s3Error := func(status int, code string) error {
+		return &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      &smithy.GenericAPIError{Code: code, Message: code},
+			},
+			RequestID: "4442587FB7D0A2F9",
+		}
+	}
+
+	for _, tc := range []struct {
+		err       error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{s3Error(http.StatusNotFound, "NoSuchBucket"), http.StatusNotFound, handler.CodeS3BucketNotFound, false},
+		{s3Error(http.StatusForbidden, "AccessDenied"), http.StatusForbidden, handler.CodeS3AccessDenied, false},
+		{s3Error(http.StatusServiceUnavailable, "SlowDown"), http.StatusTooManyRequests, handler.CodeS3Throttled, true},
+		{s3Error(http.StatusInternalServerError, "InternalError"), http.StatusInternalServerError, "", true},
+		{s3Error(http.StatusBadRequest, "InvalidRequest"), http.StatusInternalServerError, "", false},
+	} {
+		s := newTestServer(t, map[string]string{"S3_RETRY_MAX_ATTEMPTS": "1"})
+		s.bucket.FailWith("ListObjectsV2", tc.err)
+
+		rec := s.do(http.MethodGet, "/api/v1/object/browse", nil, "X-Request-ID", "req-1", "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		resp := decode[handler.ErrorResponse](t, rec, tc.status)
+		if resp.Code != tc.code {
+			t.Errorf("%v: code = %q, want %q", tc.err, resp.Code, tc.code)
+		}
+		if got := rec.Header().Get("X-Amz-Request-Id"); got != "4442587FB7D0A2F9" {
+			t.Errorf("%v: x-amz-request-id = %q, want the S3 request ID", tc.err, got)
+		}
+		if resp.AWSRequestID != "4442587FB7D0A2F9" {
+			t.Errorf("%v: aws_request_id = %q, want the S3 request ID", tc.err, resp.AWSRequestID)
+		}
+		if resp.Retryable != tc.retryable || (rec.Header().Get("Retry-After") != "") != tc.retryable {
+			t.Errorf("%v: retryable = %t with Retry-After %q, want retryable %t", tc.err, resp.Retryable, rec.Header().Get("Retry-After"), tc.retryable)
+		}
+	}
+
+	// Errors S3 didn't answer carry no request ID
+	s := newTestServer(t, nil)
+	s.bucket.FailWith("ListObjectsV2", errors.New("connection refused"))
+	rec := s.do(http.MethodGet, "/api/v1/object/browse", nil)
+	resp := decode[handler.ErrorResponse](t, rec, http.StatusInternalServerError)
+	if rec.Header().Get("X-Amz-Request-Id") != "" || resp.AWSRequestID != "" {
+		t.Errorf("request ID = %q / %q, want none", rec.Header().Get("X-Amz-Request-Id"), resp.AWSRequestID)
+	}
+}
+
+// fakeDataKeys generates fixed data keys
+type fakeDataKeys struct{}
+
+func (fakeDataKeys) GenerateDataKey(ctx context.Context) (*envelope.DataKey, error) {
+	return &envelope.DataKey{KeyID: "arn:aws:kms:us-east-1:111122223333:key/test", Plaintext: bytes.Repeat([]byte{1}, 32), Ciphertext: []byte("wrapped")}, nil
+}
+
+func TestDataKeys(t *testing.T) {
+	if rec := newTestServer(t, nil).do(http.MethodPost, "/api/v1/data-keys", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("data keys without a KMS key: status = %d, want 404", rec.Code)
+	}
+
+	s := newTestServer(t, nil, handler.WithDataKeys(fakeDataKeys{}))
+	rec := s.do(http.MethodPost, "/api/v1/data-keys", nil)
+	key := decode[handler.DataKeyResponse](t, rec, http.StatusOK)
+	if len(key.PlaintextKey) != 32 || string(key.WrappedKey) != "wrapped" || key.EncryptionContext["purpose"] != "backup-data-key" {
+		t.Errorf("data key = %+v", key)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+
+	objectKey := "acme/inputs/2025-11-23/10-00-00/db.dump.enc"
+	s.bucket.Put(objectKey, s3fake.Object{Body: []byte("ciphertext"), ContentType: "application/octet-stream"})
+	wrapped := base64.StdEncoding.EncodeToString(key.WrappedKey)
+	confirmed := decode[handler.ConfirmObjectResponse](t, s.do(http.MethodPost, "/api/v1/object/confirm", map[string]any{"object_key": objectKey, "wrapped_key": wrapped}), http.StatusOK)
+	if confirmed.Object.Metadata[handler.WrappedKeyMetadataKey] != wrapped {
+		t.Errorf("confirmed metadata = %v, want the wrapped key", confirmed.Object.Metadata)
+	}
+	if obj, _ := s.bucket.Get(objectKey); obj.Metadata[handler.WrappedKeyMetadataKey] != wrapped || string(obj.Body) != "ciphertext" || obj.ContentType != "application/octet-stream" {
+		t.Errorf("stored object = %+v, want the wrapped key in its metadata", obj)
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/object/confirm", map[string]any{"object_key": objectKey, "wrapped_key": "not base64!"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid wrapped key: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestURLEncryption(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealer, err := envelope.NewJWESealer(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("NewJWESealer: %v", err)
+	}
+	s := newTestServer(t, nil, handler.WithURLSealer(sealer))
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"})
+	resp := decode[handler.PresignedURLResponse](t, rec, http.StatusOK)
+	if got := rec.Header().Get("X-URL-Encryption"); got != "jwe" {
+		t.Errorf("X-URL-Encryption = %q, want jwe", got)
+	}
+	if strings.Contains(resp.URL, "X-Amz-Signature") {
+		t.Fatalf("url = %q, want it encrypted", resp.URL)
+	}
+
+	// Decrypt the compact JWE as a recipient would
+	parts := strings.Split(resp.URL, ".")
+	if len(parts) != 5 {
+		t.Fatalf("url has %d JWE parts, want 5", len(parts))
+	}
+	field := func(i int) []byte {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("JWE part %d: %v", i, err)
+		}
+		return data
+	}
+	var header map[string]string
+	if err := json.Unmarshal(field(0), &header); err != nil || header["alg"] != "RSA-OAEP-256" || header["enc"] != "A256GCM" || header["kid"] != sealer.KeyID() {
+		t.Fatalf("JWE header = %v (%v)", header, err)
+	}
+	cek, err := rsa.DecryptOAEP(sha256.New(), nil, key, field(1), nil)
+	if err != nil {
+		t.Fatalf("unwrapping the content key: %v", err)
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, field(2), append(field(3), field(4)...), []byte(parts[0]))
+	if err != nil {
+		t.Fatalf("decrypting the URL: %v", err)
+	}
+	if !strings.HasPrefix(string(plaintext), "https://backups.s3.") || !strings.Contains(string(plaintext), "X-Amz-Signature=") {
+		t.Errorf("decrypted url = %q, want the presigned URL", plaintext)
+	}
+}
+
+func TestDownloadTokens(t *testing.T) {
+	s := newTestServer(t, map[string]string{"API_KEYS": "backup-bot:k1", "DOWNLOAD_TOKEN_MAX_REDEMPTIONS": "2"})
+	auth := []string{"X-API-Key", "k1"}
+
+	if rec := s.do(http.MethodPost, "/api/v1/download-tokens", map[string]any{"object_key": "acme/inputs/db.dump", "max_redemptions": 3}, auth...); rec.Code != http.StatusBadRequest {
+		t.Errorf("max_redemptions above the limit: status = %d, want 400", rec.Code)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/download-tokens", map[string]any{"object_key": "globex/inputs/db.dump"}, auth...); rec.Code != http.StatusForbidden {
+		t.Errorf("key outside the prefix: status = %d, want 403", rec.Code)
+	}
+
+	// Tokens are one-time use by default, and redeemed without credentials
+	issued := decode[handler.DownloadTokenResponse](t, s.do(http.MethodPost, "/api/v1/download-tokens", map[string]any{"object_key": "acme/inputs/db.dump"}, auth...), http.StatusCreated)
+	if issued.MaxRedemptions != 1 || issued.URL != "/t/"+issued.Token {
+		t.Fatalf("token = %+v, want one redemption at /t/<token>", issued)
+	}
+	rec := s.do(http.MethodGet, issued.URL, nil)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("first redemption: status = %d, want 302", rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "https://backups.s3.us-east-1.amazonaws.com/acme/inputs/db.dump?") || !strings.Contains(location, "X-Amz-Expires=60&") {
+		t.Errorf("Location = %q, want a 60 second download URL", location)
+	}
+	if rec := s.do(http.MethodGet, issued.URL, nil); rec.Code != http.StatusGone {
+		t.Errorf("second redemption: status = %d, want 410", rec.Code)
+	}
+
+	twice := decode[handler.DownloadTokenResponse](t, s.do(http.MethodPost, "/api/v1/download-tokens", map[string]any{"object_key": "acme/inputs/db.dump", "max_redemptions": 2}, auth...), http.StatusCreated)
+	for i, want := range []int{http.StatusFound, http.StatusFound, http.StatusGone} {
+		if rec := s.do(http.MethodGet, twice.URL, nil); rec.Code != want {
+			t.Errorf("redemption %d: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+
+	if rec := s.do(http.MethodGet, "/t/unknown", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown token: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestTenantDownloadTokens(t *testing.T) {
+	store := tenant.NewMemoryStore()
+	if err := store.Create(tenant.Tenant{
+		ID:             "globex",
+		Prefix:         "globex",
+		APIKeyHash:     tenant.HashAPIKey("globex-key"),
+		RoleARN:        "arn:aws:iam::123456789012:role/globex-backups",
+		RoleExternalID: "globex-external",
+	}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	var assumed []string
+	roleCredentials := service.WithRoleCredentials(func(roleARN, externalID string) aws.CredentialsProvider {
+		assumed = append(assumed, roleARN+" "+externalID)
+		return credentials.NewStaticCredentialsProvider("AKIDGLOBEXROLE", "role-secret", "role-session-token")
+	})
+	s := newTestServerWith(t, map[string]string{"ADMIN_API_KEY": "admin"}, []service.Option{roleCredentials}, handler.WithTenants(store))
+
+	// The token is redeemed without credentials, but signed as the issuing tenant's role
+	issued := decode[handler.DownloadTokenResponse](t, s.do(http.MethodPost, "/api/v1/download-tokens", map[string]any{"object_key": "globex/inputs/db.dump"}, "X-API-Key", "globex-key"), http.StatusCreated)
+	rec := s.do(http.MethodGet, issued.URL, nil)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("redemption: status = %d, want 302: %s", rec.Code, rec.Body.String())
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Location: %v", err)
+	}
+	query := location.Query()
+	if !strings.HasPrefix(query.Get("X-Amz-Credential"), "AKIDGLOBEXROLE/") || query.Get("X-Amz-Security-Token") != "role-session-token" {
+		t.Errorf("Location = %s, want it signed with the tenant role's credentials", location)
+	}
+	if want := []string{"arn:aws:iam::123456789012:role/globex-backups globex-external"}; !slices.Equal(assumed, want) {
+		t.Errorf("assumed roles = %q, want %q", assumed, want)
+	}
+
+	// Tokens die with their tenant
+	again := decode[handler.DownloadTokenResponse](t, s.do(http.MethodPost, "/api/v1/download-tokens", map[string]any{"object_key": "globex/inputs/db.dump"}, "X-API-Key", "globex-key"), http.StatusCreated)
+	if err := store.Delete("globex"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if rec := s.do(http.MethodGet, again.URL, nil); rec.Code != http.StatusGone {
+		t.Errorf("token of a deleted tenant: status = %d, want 410", rec.Code)
+	}
+}
+
+func TestTenantUploadWindows(t *testing.T) {
+	store := tenant.NewMemoryStore()
+	closedHour := (time.Now().UTC().Hour() + 12) % 24
+	for id, windows := range map[string][]string{
+		"acme":   {fmt.Sprintf("* %d * * *", closedHour)},
+		"globex": {fmt.Sprintf("* %d * * *", closedHour), "* * * * *"},
+	} {
+		if err := store.Create(tenant.Tenant{ID: id, Prefix: id, UploadWindows: windows, APIKeyHash: tenant.HashAPIKey(id + "-key")}); err != nil {
+			t.Fatalf("Create(%s): %v", id, err)
+		}
+	}
+	s := newTestServer(t, map[string]string{"ADMIN_API_KEY": "admin"}, handler.WithTenants(store))
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}, "X-API-Key", "acme-key")
+	resp := decode[handler.ErrorResponse](t, rec, http.StatusForbidden)
+	retryAfter, _ := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if resp.Code != handler.CodeWindowClosed || !resp.Retryable || retryAfter <= 0 || retryAfter > 12*3600 {
+		t.Errorf("outside the window: %+v, Retry-After %q", resp, rec.Header().Get("Retry-After"))
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}, "X-API-Key", "globex-key"); rec.Code != http.StatusOK {
+		t.Errorf("inside the window: status = %d, want 200", rec.Code)
+	}
+
+	rec = s.do(http.MethodPost, "/admin/v1/tenants", map[string]any{"tenant_id": "initech", "prefix": "initech", "upload_windows": []string{"* 25 * * *"}}, "X-Admin-Key", "admin")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid window: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestStaleBackupAlerts(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]any
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get("X-Signature-256"), "sha256="+webhook.Sign([]byte("s3cr3t"), body); got != want {
+			t.Errorf("X-Signature-256 = %q, want %q", got, want)
+		}
+		var event map[string]any
+		_ = json.Unmarshal(body, &event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	s := newTestServer(t, map[string]string{
+		"EXPECTED_BACKUPS":            "db-*.dump.gz=26,etc.tar.gz=24,never.tar=1",
+		"STALE_BACKUP_WEBHOOK_URL":    receiver.URL,
+		"STALE_BACKUP_WEBHOOK_SECRET": "s3cr3t",
+	})
+	now := time.Now()
+	s.bucket.Put("acme/inputs/2025-11-24/02-00-00/db-1.dump.gz", s3fake.Object{Body: []byte("x"), LastModified: now.Add(-2 * time.Hour)})
+	s.bucket.Put("acme/inputs/2025-11-23/02-00-00/etc.tar.gz", s3fake.Object{Body: []byte("x"), LastModified: now.Add(-30 * time.Hour)})
+
+	if rec := s.do(http.MethodGet, "/api/v1/backups/age", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("before the first check: status = %d, want 404", rec.Code)
+	}
+	if err := s.handler.CheckStaleBackups(context.Background()); err != nil {
+		t.Fatalf("CheckStaleBackups: %v", err)
+	}
+	report := decode[handler.BackupAgeReport](t, s.do(http.MethodGet, "/api/v1/backups/age", nil), http.StatusOK)
+	stale := map[string]bool{}
+	for _, age := range report.Backups {
+		stale[age.Filename] = age.Stale
+	}
+	if report.Stale != 2 || stale["db-*.dump.gz"] || !stale["etc.tar.gz"] || !stale["never.tar"] {
+		t.Errorf("report = %+v, want etc.tar.gz and never.tar stale", report)
+	}
+
+	// Alerts are sent when a backup becomes stale or recovers, not on every check
+	s.bucket.Put("acme/inputs/2025-11-25/02-00-00/etc.tar.gz", s3fake.Object{Body: []byte("x")})
+	if err := s.handler.CheckStaleBackups(context.Background()); err != nil {
+		t.Fatalf("CheckStaleBackups: %v", err)
+	}
+	mu.Lock()
+	var types []string
+	for _, event := range events {
+		data, _ := event["data"].(map[string]any)
+		types = append(types, fmt.Sprintf("%s %s", event["type"], data["filename"]))
+	}
+	mu.Unlock()
+	want := []string{"backup.stale etc.tar.gz", "backup.stale never.tar", "backup.recovered etc.tar.gz"}
+	if !slices.Equal(types, want) {
+		t.Errorf("webhook events = %v, want %v", types, want)
+	}
+
+	report = decode[handler.BackupAgeReport](t, s.do(http.MethodGet, "/api/v1/backups/age?stale=true", nil), http.StatusOK)
+	if len(report.Backups) != 1 || report.Backups[0].Filename != "never.tar" || report.Backups[0].LatestUploadAt != nil {
+		t.Errorf("stale backups = %+v, want never.tar, never uploaded", report.Backups)
+	}
+}
+
+func TestIssuanceAnomalies(t *testing.T) {
+	events := make(chan webhook.Event, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer receiver.Close()
+
+	// Business hours are the hour twelve hours from now
+	offHour := (time.Now().UTC().Hour() + 12) % 24
+	s := newTestServer(t, map[string]string{
+		"ADMIN_API_KEY":          "admin",
+		"ALLOWED_OPERATIONS":     "upload,download,delete",
+		"ANOMALY_DETECTION":      "true",
+		"ANOMALY_BUSINESS_HOURS": fmt.Sprintf("* %d * * *", offHour),
+		"ANOMALY_WEBHOOK_URL":    receiver.URL,
+	})
+
+	decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}), http.StatusOK)
+	resp := decode[handler.AnomaliesResponse](t, s.do(http.MethodGet, "/admin/v1/anomalies", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	if len(resp.Anomalies) != 0 {
+		t.Fatalf("anomalies after an upload URL = %+v, want none", resp.Anomalies)
+	}
+
+	s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "delete", "object_key": "acme/inputs/2025-11-24/14-30-00/db.dump"})
+	resp = decode[handler.AnomaliesResponse](t, s.do(http.MethodGet, "/admin/v1/anomalies", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	if len(resp.Anomalies) != 1 || resp.Anomalies[0].Type != anomaly.TypeOffHoursDelete || resp.Anomalies[0].ObjectKey != "acme/inputs/2025-11-24/14-30-00/db.dump" {
+		t.Fatalf("anomalies = %+v, want the off-hours delete URL", resp.Anomalies)
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != handler.EventIssuanceAnomaly {
+			t.Errorf("webhook event type = %q, want %q", event.Type, handler.EventIssuanceAnomaly)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("anomaly webhook not sent")
+	}
+
+	if rec := s.do(http.MethodGet, "/admin/v1/anomalies?limit=0", nil, "X-Admin-Key", "admin"); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRestoreDrills(t *testing.T) {
+	var s *testServer
+	var corrupt atomic.Bool
+	// Serves presigned GETs from the fake bucket, path style
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		obj, ok := s.bucket.Get(strings.TrimPrefix(r.URL.Path, "/backups/"))
+		if !ok || r.URL.Query().Get("X-Amz-Signature") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.ContentType)
+		for name, value := range obj.Metadata {
+			w.Header().Set("X-Amz-Meta-"+name, value)
+		}
+		body := obj.Body
+		if corrupt.Load() {
+			body = bytes.ToUpper(body)
+		}
+		_, _ = w.Write(body)
+	}))
+	defer endpoint.Close()
+
+	s = newTestServer(t, map[string]string{"S3_ENDPOINT_URL": endpoint.URL})
+	if rec := s.do(http.MethodPost, "/api/v1/restore-drills", nil); rec.Code != http.StatusBadGateway {
+		t.Errorf("without uploads: status = %d, want 502", rec.Code)
+	}
+
+	s.bucket.Put("acme/inputs/2025-11-20/02-00-00/old.dump.gz", s3fake.Object{Body: []byte("old"), LastModified: time.Now().Add(-30 * 24 * time.Hour)})
+	s.bucket.Put("acme/inputs/2025-11-24/02-00-00/db.dump.gz", s3fake.Object{
+		Body:        []byte("backup"),
+		ContentType: "application/gzip",
+		Metadata:    map[string]string{"host": "db-1"},
+	})
+
+	drill := decode[restoredrill.Drill](t, s.do(http.MethodPost, "/api/v1/restore-drills", nil), http.StatusCreated)
+	if !drill.OK || drill.ObjectKey != "acme/inputs/2025-11-24/02-00-00/db.dump.gz" || drill.Checksum != "md5" || drill.Trigger != restoredrill.TriggerAPI {
+		t.Errorf("drill = %+v, want the recent upload verified by MD5", drill)
+	}
+
+	corrupt.Store(true)
+	failed := decode[restoredrill.Drill](t, s.do(http.MethodPost, "/api/v1/restore-drills", nil), http.StatusBadGateway)
+	var failedChecks []string
+	for _, check := range failed.Checks {
+		if !check.OK {
+			failedChecks = append(failedChecks, check.Name)
+		}
+	}
+	if !slices.Equal(failedChecks, []string{"verify_checksum"}) {
+		t.Errorf("failed checks = %v, want verify_checksum", failedChecks)
+	}
+
+	history := decode[handler.RestoreDrillsResponse](t, s.do(http.MethodGet, "/api/v1/restore-drills?limit=2", nil), http.StatusOK)
+	if len(history.Drills) != 2 || history.Drills[0].ID != failed.ID || history.Drills[1].ID != drill.ID {
+		t.Errorf("history = %+v, want the last two drills, newest first", history.Drills)
+	}
+	if got := decode[restoredrill.Drill](t, s.do(http.MethodGet, "/api/v1/restore-drills/"+drill.ID, nil), http.StatusOK); got.ID != drill.ID || !got.OK {
+		t.Errorf("drill = %+v, want %s", got, drill.ID)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/restore-drills/unknown", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown drill: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestBatchDelete(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload,download,delete", "MIN_RETENTION_HOURS": "24"})
+	old := time.Now().Add(-48 * time.Hour)
+	s.bucket.Put("acme/inputs/a.dump", s3fake.Object{Body: []byte("a"), LastModified: old})
+	s.bucket.Put("acme/inputs/b.dump", s3fake.Object{Body: []byte("b"), LastModified: old})
+	s.bucket.Put("acme/inputs/held.dump", s3fake.Object{Body: []byte("h"), LastModified: old, LegalHold: types.ObjectLockLegalHoldStatusOn})
+	s.bucket.Put("acme/inputs/fresh.dump", s3fake.Object{Body: []byte("f")})
+
+	keys := []string{"acme/inputs/a.dump", "acme/inputs/b.dump", "acme/inputs/a.dump", "acme/inputs/held.dump", "acme/inputs/fresh.dump", "globex/inputs/x.dump"}
+	resp := decode[handler.BatchDeleteResponse](t, s.do(http.MethodPost, "/api/v1/objects/delete", map[string]any{"object_keys": keys}), http.StatusOK)
+	codes := map[string]string{}
+	for _, result := range resp.Results {
+		codes[result.ObjectKey] = result.Code
+	}
+	want := map[string]string{
+		"acme/inputs/a.dump":     "",
+		"acme/inputs/b.dump":     "",
+		"acme/inputs/held.dump":  "AccessDenied",
+		"acme/inputs/fresh.dump": handler.CodeRetentionActive,
+		"globex/inputs/x.dump":   handler.CodeForbiddenKey,
+	}
+	if resp.Deleted != 2 || resp.Failed != 3 || !maps.Equal(codes, want) {
+		t.Errorf("response = %+v, want a and b deleted and per-key errors", resp)
+	}
+	if resp.Results[0].ObjectKey != "acme/inputs/a.dump" || resp.Results[3].ObjectKey != "acme/inputs/fresh.dump" {
+		t.Errorf("results = %+v, want request order without duplicates", resp.Results)
+	}
+	for _, key := range []string{"acme/inputs/a.dump", "acme/inputs/b.dump"} {
+		if _, ok := s.bucket.Get(key); ok {
+			t.Errorf("%s was not deleted", key)
+		}
+	}
+	if _, ok := s.bucket.Get("acme/inputs/fresh.dump"); !ok {
+		t.Error("object within its retention was deleted")
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/objects/delete", map[string]any{"object_keys": []string{}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestUploadOverwriteProtection(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload,download"})
+
+	v1 := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload",
+		map[string]any{"filename": "a", "overwrite": false}), http.StatusOK)
+	u, err := url.Parse(v1.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if got, want := u.Query().Get("X-Amz-SignedHeaders"), "host;if-none-match"; got != want {
+		t.Errorf("v1 X-Amz-SignedHeaders = %q, want %q", got, want)
+	}
+	if v1.Headers["if-none-match"] != "*" {
+		t.Errorf("v1 headers = %v, want if-none-match: *", v1.Headers)
+	}
+
+	v2 := decode[handler.PresignV2Response](t, s.do(http.MethodPost, "/api/v2/presigned-urls",
+		map[string]any{"operation": "upload", "filename": "a", "content_type": "text/plain", "overwrite": false}), http.StatusOK)
+	if v2.Headers["if-none-match"] != "*" {
+		t.Errorf("v2 headers = %v, want if-none-match: *", v2.Headers)
+	}
+
+	allowed := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload",
+		map[string]any{"filename": "a", "overwrite": true}), http.StatusOK)
+	if _, ok := allowed.Headers["if-none-match"]; ok {
+		t.Errorf("overwrite: true headers = %v, want no if-none-match", allowed.Headers)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "download", "object_key": "acme/a", "overwrite": false})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("download with overwrite: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestUploadOwnershipHeaders(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"UPLOAD_ACL":               "bucket-owner-full-control",
+		"S3_EXPECTED_BUCKET_OWNER": "111122223333",
+	})
+
+	resp := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "a"}), http.StatusOK)
+	u, err := url.Parse(resp.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if got, want := u.Query().Get("X-Amz-SignedHeaders"), "host;x-amz-acl;x-amz-expected-bucket-owner"; got != want {
+		t.Errorf("X-Amz-SignedHeaders = %q, want %q", got, want)
+	}
+	want := map[string]string{"x-amz-acl": "bucket-owner-full-control", "x-amz-expected-bucket-owner": "111122223333"}
+	if !maps.Equal(resp.Headers, want) {
+		t.Errorf("headers = %v, want %v", resp.Headers, want)
+	}
+
+	v2 := decode[handler.PresignV2Response](t, s.do(http.MethodPost, "/api/v2/presigned-urls",
+		map[string]any{"operation": "upload", "filename": "a", "content_type": "text/plain", "dry_run": true}), http.StatusOK)
+	if v2.Headers["x-amz-acl"] != "bucket-owner-full-control" || v2.Headers["x-amz-expected-bucket-owner"] != "111122223333" {
+		t.Errorf("v2 dry run headers = %v, want the ownership headers", v2.Headers)
+	}
+}
+
+func TestPreviewUpload(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	resp := decode[handler.UploadPreviewResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/preview", map[string]any{
+		"filename":    "db.dump",
+		"content_md5": "d41d8cd98f00b204e9800998ecf8427e",
+		"metadata":    map[string]string{"Backup_ID": "  nightly   run "},
+	}), http.StatusOK)
+
+	if !strings.HasPrefix(resp.ObjectKey, "acme/inputs/") || resp.Method != http.MethodPut {
+		t.Errorf("object_key = %q, method = %q", resp.ObjectKey, resp.Method)
+	}
+	if want := []string{"content-md5", "host", "x-amz-meta-backup-id"}; !slices.Equal(resp.SignedHeaders, want) {
+		t.Errorf("signed_headers = %v, want %v", resp.SignedHeaders, want)
+	}
+	if resp.Headers["x-amz-meta-backup-id"] != "nightly run" || resp.Headers["content-md5"] != "1B2M2Y8AsgTpgAmY7PhCfg==" {
+		t.Errorf("headers = %v", resp.Headers)
+	}
+	if resp.MetadataHeaders["Backup_ID"] != "x-amz-meta-backup-id" {
+		t.Errorf("metadata_headers = %v", resp.MetadataHeaders)
+	}
+	if resp.ContentTypeSigned || len(resp.Notes) != 2 {
+		t.Errorf("content_type_signed = %v, notes = %v", resp.ContentTypeSigned, resp.Notes)
+	}
+
+	// The same request issues a URL signed with exactly those headers
+	issued := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{
+		"filename":    "db.dump",
+		"content_md5": "d41d8cd98f00b204e9800998ecf8427e",
+		"metadata":    map[string]string{"Backup_ID": "  nightly   run "},
+	}), http.StatusOK)
+	u, err := url.Parse(issued.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if got := u.Query().Get("X-Amz-SignedHeaders"); got != strings.Join(resp.SignedHeaders, ";") {
+		t.Errorf("issued X-Amz-SignedHeaders = %q, preview %v", got, resp.SignedHeaders)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/preview", map[string]any{"filename": ""})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing filename: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestUploadRequiredHeaders(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	resp := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{
+		"filename": "db.dump",
+		"metadata": map[string]string{"Backup_ID": "nightly"},
+	}), http.StatusOK)
+	if resp.RequiredHeaders["Backup_ID"] != "x-amz-meta-backup-id" || resp.Headers["x-amz-meta-backup-id"] != "nightly" {
+		t.Errorf("required_headers = %v, headers = %v", resp.RequiredHeaders, resp.Headers)
+	}
+
+	v2 := decode[handler.PresignV2Response](t, s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{
+		"operation":    "upload",
+		"filename":     "db.dump",
+		"content_type": "application/octet-stream",
+		"metadata":     map[string]string{"Backup_ID": "nightly"},
+	}), http.StatusOK)
+	if v2.RequiredHeaders["Backup_ID"] != "x-amz-meta-backup-id" {
+		t.Errorf("v2 required_headers = %v", v2.RequiredHeaders)
+	}
+
+	plain := decode[handler.PresignedURLResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"}), http.StatusOK)
+	if plain.RequiredHeaders != nil {
+		t.Errorf("without metadata: required_headers = %v", plain.RequiredHeaders)
+	}
+}
+
+func TestIAMPolicy(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"ADMIN_API_KEY":                      "admin",
+		"CLOCK_DRIFT_CHECK_INTERVAL_SECONDS": "0",
+		"UPLOAD_ACL":                         "bucket-owner-full-control",
+	})
+
+	doc := decode[iampolicy.Document](t, s.do(http.MethodGet, "/admin/v1/iam-policy", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	statements := make(map[string]iampolicy.Statement, len(doc.Statement))
+	for _, st := range doc.Statement {
+		statements[st.Sid] = st
+	}
+
+	list := statements["ListPrefixes"]
+	if !slices.Equal(list.Resource, []string{"arn:aws:s3:::backups"}) || !slices.Equal(list.Condition["StringLike"]["s3:prefix"], []string{"acme", "acme/*"}) {
+		t.Errorf("ListPrefixes = %+v", list)
+	}
+	objects := statements["Objects"]
+	if !slices.Equal(objects.Resource, []string{"arn:aws:s3:::backups/acme/*"}) || !slices.Contains(objects.Action, "s3:PutObjectAcl") {
+		t.Errorf("Objects = %+v", objects)
+	}
+	if _, ok := statements["BucketStatus"]; !ok {
+		t.Errorf("statements = %v, want BucketStatus with the admin API", doc.Statement)
+	}
+	if _, ok := statements["AssumeRoles"]; ok {
+		t.Errorf("AssumeRoles without any role: %+v", statements["AssumeRoles"])
+	}
+
+	// HeadBucket needs listing the whole bucket
+	s = newTestServer(t, map[string]string{"ADMIN_API_KEY": "admin"})
+	doc = decode[iampolicy.Document](t, s.do(http.MethodGet, "/admin/v1/iam-policy", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	if doc.Statement[0].Condition != nil {
+		t.Errorf("ListPrefixes with the clock drift check: condition = %v", doc.Statement[0].Condition)
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ADMIN_API_KEY": "admin", "READ_ONLY": "true", "ALLOWED_OPERATIONS": "upload,download,delete"})
+	s.bucket.Put("acme/inputs/2025-01-01/00-00-00/db.dump", s3fake.Object{Body: []byte("data")})
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"})
+	if rec.Code != http.StatusServiceUnavailable || decode[handler.ErrorResponse](t, rec, http.StatusServiceUnavailable).Code != handler.CodeMaintenance {
+		t.Errorf("v1 upload: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("v1 upload: missing Retry-After")
+	}
+	rec = s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "delete", "object_key": "acme/inputs/2025-01-01/00-00-00/db.dump"})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("v2 delete: status = %d, want 503", rec.Code)
+	}
+	rec = s.do(http.MethodPost, "/api/v1/objects/delete", map[string]any{"object_keys": []string{"acme/inputs/2025-01-01/00-00-00/db.dump"}})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("batch delete: status = %d, want 503", rec.Code)
+	}
+
+	// Reads keep working
+	rec = s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "download", "object_key": "acme/inputs/2025-01-01/00-00-00/db.dump"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("v2 download: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec = s.do(http.MethodPost, "/api/v1/object/search", map[string]any{"filename": "db.dump"}); rec.Code != http.StatusOK {
+		t.Errorf("search: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	resp := decode[handler.ReadOnlyResponse](t, s.do(http.MethodPut, "/admin/v1/read-only", map[string]any{"read_only": false}, "X-Admin-Key", "admin"), http.StatusOK)
+	if resp.ReadOnly {
+		t.Error("read_only = true after switching it off")
+	}
+	if rec = s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"}); rec.Code != http.StatusOK {
+		t.Errorf("v1 upload after switching off: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec = s.do(http.MethodPut, "/admin/v1/read-only", map[string]any{}, "X-Admin-Key", "admin"); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing read_only: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestMaintenanceWindow(t *testing.T) {
+	today := time.Now().UTC().Weekday()
+	s := newTestServer(t, map[string]string{"MAINTENANCE_WINDOWS": fmt.Sprintf("* * * * %d", today)})
+
+	rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"})
+	refused := decode[handler.MaintenanceResponse](t, rec, http.StatusServiceUnavailable)
+	midnight := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if refused.Code != handler.CodeMaintenance || !refused.EndsAt.Equal(midnight) || !refused.Retryable {
+		t.Errorf("upload during the window = %+v, want ends_at %v", refused, midnight)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After")
+	}
+
+	status := decode[handler.MaintenanceStatusResponse](t, s.do(http.MethodGet, "/api/v1/maintenance", nil), http.StatusOK)
+	if status.WritesAllowed || !status.InWindow || !status.EndsAt.Equal(midnight) || !status.NextWindowAt.Equal(midnight.Add(6*24*time.Hour)) {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Outside the window writes work and the next window is announced
+	s = newTestServer(t, map[string]string{"MAINTENANCE_WINDOWS": fmt.Sprintf("* * * * %d", (today+1)%7)})
+	if rec := s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"}); rec.Code != http.StatusOK {
+		t.Errorf("upload outside the window: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	status = decode[handler.MaintenanceStatusResponse](t, s.do(http.MethodGet, "/api/v1/maintenance", nil), http.StatusOK)
+	if !status.WritesAllowed || status.InWindow || !status.NextWindowAt.Equal(midnight) {
+		t.Errorf("status = %+v, want next_window_at %v", status, midnight)
+	}
+}
+
+func TestWebUI(t *testing.T) {
+	if rec := newTestServer(t, nil).do(http.MethodGet, "/ui/", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("UI while disabled: status = %d, want 404", rec.Code)
+	}
+
+	store := tenant.NewMemoryStore()
+	if err := store.Create(tenant.Tenant{ID: "globex", Prefix: "globex", QuotaBytes: 1000, APIKeyHash: tenant.HashAPIKey("globex-key")}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	s := newTestServer(t, map[string]string{"WEB_UI": "true", "ADMIN_API_KEY": "admin"}, handler.WithTenants(store))
+	rec := s.do(http.MethodGet, "/ui/", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "app.js") {
+		t.Fatalf("GET /ui/: status = %d: %s", rec.Code, rec.Body)
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'self'") {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+	if rec = s.do(http.MethodGet, "/ui/app.js", nil); rec.Code != http.StatusOK {
+		t.Errorf("GET /ui/app.js: status = %d, want 200", rec.Code)
+	}
+	if rec = s.do(http.MethodGet, "/ui", nil); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/ui/" {
+		t.Errorf("GET /ui: status = %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// The audit panel lists issued URLs, filtered by method
+	s.bucket.Put("globex/db.dump", s3fake.Object{Body: make([]byte, 300)})
+	s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{"filename": "db.dump"}, "X-API-Key", "globex-key")
+	if rec = s.do(http.MethodGet, "/admin/v1/issued-urls", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("issued URLs without the admin key: status = %d, want 401", rec.Code)
+	}
+	issued := decode[handler.IssuedURLsResponse](t, s.do(http.MethodGet, "/admin/v1/issued-urls?method=PUT", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	if len(issued.URLs) != 1 || !strings.HasPrefix(issued.URLs[0].ObjectKey, "globex/") {
+		t.Errorf("issued URLs = %+v, want the upload", issued.URLs)
+	}
+	issued = decode[handler.IssuedURLsResponse](t, s.do(http.MethodGet, "/admin/v1/issued-urls?method=GET", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	if len(issued.URLs) != 0 {
+		t.Errorf("issued GET URLs = %+v, want none", issued.URLs)
+	}
+
+	// The tenants panel shows usage against the quota
+	usage := decode[handler.TenantUsageResponse](t, s.do(http.MethodGet, "/admin/v1/tenants/globex/usage", nil, "X-Admin-Key", "admin"), http.StatusOK)
+	if usage.UsedBytes != 300 || usage.QuotaBytes != 1000 {
+		t.Errorf("usage = %+v, want 300 of 1000 bytes", usage)
+	}
+	if rec = s.do(http.MethodGet, "/admin/v1/tenants/initech/usage", nil, "X-Admin-Key", "admin"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown tenant usage: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	s := newTestServer(t, map[string]string{"ALLOWED_OPERATIONS": "upload"})
+
+	// Spanish is picked from a regional tag; the code stays as it is
+	rec := s.do(http.MethodPost, "/api/v2/presigned-urls", map[string]any{"operation": "download", "object_key": "acme/db.dump"},
+		"Accept-Language", "es-CL,es;q=0.9,en;q=0.8")
+	resp := decode[handler.ErrorResponse](t, rec, http.StatusForbidden)
+	if resp.Code != handler.CodeOperationNotAllowed || resp.Error != "Operación no permitida" {
+		t.Errorf("Spanish error = %+v", resp)
+	}
+	if rec.Header().Get("Content-Language") != "es" || !strings.Contains(rec.Header().Get("Vary"), "Accept-Language") {
+		t.Errorf("headers = %v", rec.Header())
+	}
+
+	// Unsupported or missing languages get English
+	for _, accept := range []string{"", "fr-FR,de;q=0.5", "es;q=0,en"} {
+		resp = decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", map[string]any{}, "Accept-Language", accept), http.StatusBadRequest)
+		if resp.Error != "filename is required" {
+			t.Errorf("Accept-Language %q: error = %q, want English", accept, resp.Error)
+		}
+	}
+
+	// DEFAULT_LANGUAGE applies when the header names no supported language
+	s = newTestServer(t, map[string]string{"DEFAULT_LANGUAGE": "es"})
+	resp = decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", "{", "Accept-Language", "fr"), http.StatusBadRequest)
+	if resp.Error != "Cuerpo de la petición inválido" {
+		t.Errorf("default language error = %q", resp.Error)
+	}
+	resp = decode[handler.ErrorResponse](t, s.do(http.MethodPost, "/api/v1/presigned-url/upload", "{", "Accept-Language", "en-US"), http.StatusBadRequest)
+	if resp.Error != "Invalid request body" {
+		t.Errorf("English error = %q", resp.Error)
+	}
+}
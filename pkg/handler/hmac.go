@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+)
+
+// challengePath issues nonces and is exempt from signature checks
+const challengePath = "/api/v1/auth/challenge"
+
+// maxSignedBodyBytes bounds the body read into memory for signature checks
+const maxSignedBodyBytes = 1 << 20
+
+// ChallengeResponse represents a nonce issued for HMAC request signing
+type ChallengeResponse struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// nonceStore tracks issued, unused challenge nonces. The challenge endpoint
+// is unauthenticated, so at most max nonces are outstanding at once.
+type nonceStore struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	max    int
+	nonces map[string]time.Time
+}
+
+func newNonceStore(ttl time.Duration, max int) *nonceStore {
+	return &nonceStore{ttl: ttl, max: max, nonces: make(map[string]time.Time)}
+}
+
+// issue creates a nonce valid for the store's TTL, or reports false when max
+// unexpired nonces are already outstanding
+func (s *nonceStore) issue() (ChallengeResponse, bool) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for n, expiresAt := range s.nonces {
+		if now.After(expiresAt) {
+			delete(s.nonces, n)
+		}
+	}
+	if len(s.nonces) >= s.max {
+		return ChallengeResponse{}, false
+	}
+	nonce := idgen.New()
+	s.nonces[nonce] = now.Add(s.ttl)
+
+	return ChallengeResponse{Nonce: nonce, ExpiresAt: now.Add(s.ttl)}, true
+}
+
+// consume reports whether nonce was issued and unexpired, and invalidates it
+// so it can't be replayed
+func (s *nonceStore) consume(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.nonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(s.nonces, nonce)
+	return time.Now().Before(expiresAt)
+}
+
+// IssueChallenge returns a single-use nonce for signing the next request
+func (h *Handler) IssueChallenge(w http.ResponseWriter, r *http.Request) {
+	if h.nonces == nil {
+		respondWithError(w, http.StatusNotFound, "HMAC signing is not enabled", "")
+		return
+	}
+	challenge, ok := h.nonces.issue()
+	if !ok {
+		respondWithRetryableError(w, http.StatusServiceUnavailable, int(h.nonces.ttl.Seconds()), "Too many outstanding challenges", "retry once issued nonces are used or expire")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, challenge)
+}
+
+// hmacMiddleware requires requests to carry X-Signature-Key-Id naming one of
+// the HMAC_SECRETS entries, X-Signature-Nonce with a nonce from the challenge
+// endpoint and X-Signature with the hex HMAC-SHA256 of the string to sign
+// (see requestStringToSign) under that key's secret. It attaches the key's
+// Principal, and the tenant when the key ID is a tenant ID.
+func hmacMiddleware(secrets []string, tenants tenant.Store, nonces *nonceStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipsAuth(r) || r.URL.Path == challengePath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			keyID := r.Header.Get("X-Signature-Key-Id")
+			nonce := r.Header.Get("X-Signature-Nonce")
+			signature := strings.TrimPrefix(r.Header.Get("X-Signature"), "sha256=")
+			if keyID == "" || nonce == "" || signature == "" {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "X-Signature-Key-Id, X-Signature-Nonce and X-Signature headers are required")
+				return
+			}
+			principal, secret := signingKey(keyID, secrets)
+			if principal == nil {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "unknown signing key")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodyBytes))
+			if err != nil {
+				respondWithError(w, http.StatusRequestEntityTooLarge, "Request body too large", err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(requestStringToSign(nonce, keyID, r.Method, r.URL.Path, canonicalQuery(r.URL), body)))
+			expected := hex.EncodeToString(mac.Sum(nil))
+
+			// Check the signature before consuming so a forged request can't burn a valid nonce
+			if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "invalid request signature")
+				return
+			}
+			if !nonces.consume(nonce) {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "unknown, expired or already used nonce")
+				return
+			}
+
+			var t *tenant.Tenant
+			if tenants != nil {
+				found, err := tenants.Get(keyID)
+				switch {
+				case err == nil:
+					t = &found
+				case !errors.Is(err, tenant.ErrNotFound):
+					respondWithError(w, http.StatusInternalServerError, "Tenant store error", err.Error())
+					return
+				}
+			}
+
+			// With auth earlier in the chain, the API key's caller stays the
+			// principal, but both must agree on the tenant
+			ctx := r.Context()
+			current, scoped := TenantFromContext(ctx)
+			if scoped && t != nil && current.ID != t.ID {
+				respondWithError(w, http.StatusForbidden, "Forbidden", "signing key belongs to a different tenant than the API key")
+				return
+			}
+			if _, ok := PrincipalFromContext(ctx); !ok {
+				ctx = withPrincipal(ctx, principal)
+			}
+			if t != nil && !scoped {
+				ctx = withTenant(ctx, t)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// signingKey returns the Principal and secret of the HMAC_SECRETS entry
+// (key-id:secret[:scopes]) for keyID, or nil if there's none
+func signingKey(keyID string, secrets []string) (*Principal, []byte) {
+	for _, entry := range secrets {
+		id, secret, scopes := splitAPIKey(entry)
+		if id == keyID {
+			return &Principal{Subject: id, Method: "hmac", Scopes: scopes}, []byte(secret)
+		}
+	}
+	return nil, nil
+}
+
+// canonicalQuery encodes the query parameters sorted by key, so clients and
+// the server agree on the signed form however the URL was written
+func canonicalQuery(u *url.URL) string {
+	return u.Query().Encode()
+}
+
+// requestStringToSign is the string clients sign:
+// nonce \n key-id \n METHOD \n /path \n canonical query \n hex(sha256(body))
+func requestStringToSign(nonce, keyID, method, path, query string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{nonce, keyID, method, path, query, hex.EncodeToString(bodyHash[:])}, "\n")
+}
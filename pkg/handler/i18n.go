@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/i18n"
+)
+
+// i18nMiddleware negotiates the language of error messages from
+// Accept-Language, falling back to defaultLanguage, and hands it to
+// respondWithJSON through the response writer
+func i18nMiddleware(defaultLanguage string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Language")
+			language, ok := i18n.Negotiate(r.Header.Get("Accept-Language"))
+			if !ok {
+				language = defaultLanguage
+			}
+			next.ServeHTTP(&languageWriter{ResponseWriter: w, language: language}, r)
+		})
+	}
+}
+
+// languageWriter carries the negotiated language of a response
+type languageWriter struct {
+	http.ResponseWriter
+	language string
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *languageWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// responseLanguage finds the language negotiated for w, looking through the
+// writers other middleware wrapped it in
+func responseLanguage(w http.ResponseWriter) (string, bool) {
+	for {
+		switch rw := w.(type) {
+		case *languageWriter:
+			return rw.language, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return "", false
+		}
+	}
+}
+
+// localizeError translates the error and message of resp into the language
+// negotiated for w. Codes are left as they are for clients to match on.
+func localizeError(w http.ResponseWriter, resp ErrorResponse) ErrorResponse {
+	language, ok := responseLanguage(w)
+	if !ok {
+		return resp
+	}
+	w.Header().Set("Content-Language", language)
+	resp.Error = i18n.Translate(language, resp.Error)
+	resp.Message = i18n.Translate(language, resp.Message)
+	return resp
+}
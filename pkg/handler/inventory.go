@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/inventory"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Inventory exports run in the background, bounded in time and size
+const (
+	inventoryExportTimeout = 30 * time.Minute
+	maxInventoryObjects    = 1_000_000
+)
+
+// InventoryExportRequest represents the request body for exporting the
+// object inventory of a prefix
+type InventoryExportRequest struct {
+	Prefix string `json:"prefix,omitempty"` // Defaults to the company prefix
+	From   string `json:"from,omitempty"`   // First modification date (YYYY-MM-DD), inclusive
+	To     string `json:"to,omitempty"`     // Last modification date (YYYY-MM-DD), inclusive
+	Format string `json:"format,omitempty"` // csv (default) or parquet
+}
+
+// InventoryExportResponse is the state of an export, with a download URL of
+// the export object once it is completed
+type InventoryExportResponse struct {
+	inventory.Export
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// CreateInventoryExport starts exporting the objects under a prefix,
+// optionally limited to a modification date range, to a CSV or Parquet
+// object in the bucket. Poll GetInventoryExport for the download URL.
+func (h *Handler) CreateInventoryExport(w http.ResponseWriter, r *http.Request) {
+	var req InventoryExportRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	format := inventory.Format(strings.ToLower(req.Format))
+	if format == "" {
+		format = inventory.FormatCSV
+	}
+	if format != inventory.FormatCSV && format != inventory.FormatParquet {
+		respondWithError(w, http.StatusBadRequest, "format must be csv or parquet", req.Format)
+		return
+	}
+	from, to, err := parseDateRange(req.From, req.To)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid date range", err.Error())
+		return
+	}
+	svc := h.service(r)
+	if req.Prefix != "" && !svc.OwnsKey(req.Prefix) {
+		respondWithError(w, http.StatusForbidden, "prefix is outside the company prefix", req.Prefix)
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: req.Prefix}) {
+		return
+	}
+
+	id := idgen.New()
+	export := h.inventory.Create(inventory.Export{
+		ID:        id,
+		Format:    format,
+		Prefix:    req.Prefix,
+		From:      from,
+		To:        to,
+		ObjectKey: svc.InventoryExportKey(id, string(format)),
+	})
+	logging.Infof("Inventory export %s of %q started", export.ID, req.Prefix)
+
+	go h.runInventoryExport(svc, export)
+
+	respondWithJSON(w, http.StatusAccepted, InventoryExportResponse{Export: export})
+}
+
+// runInventoryExport lists the objects of export and writes them to its
+// object key, recording the outcome in the store
+func (h *Handler) runInventoryExport(svc *service.S3Service, export inventory.Export) {
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryExportTimeout)
+	defer cancel()
+
+	objects, err := svc.ListInventory(ctx, export.Prefix, export.From, export.To, maxInventoryObjects)
+	if err != nil {
+		logging.Errorf("Inventory export %s failed: %v", export.ID, err)
+		h.inventory.Fail(export.ID, err)
+		return
+	}
+	body, err := inventory.Encode(export.Format, objects)
+	if err == nil {
+		err = svc.PutObject(ctx, export.ObjectKey, body, export.Format.ContentType())
+	}
+	if err != nil {
+		logging.Errorf("Inventory export %s failed: %v", export.ID, err)
+		h.inventory.Fail(export.ID, err)
+		return
+	}
+
+	logging.Infof("Inventory export %s wrote %d objects to %s", export.ID, len(objects), export.ObjectKey)
+	h.inventory.Complete(export.ID, len(objects), int64(len(body)))
+}
+
+// GetInventoryExport reports the state of an export, with a presigned GET of
+// the export object once it is completed
+func (h *Handler) GetInventoryExport(w http.ResponseWriter, r *http.Request) {
+	export, err := h.inventory.Get(mux.Vars(r)["id"])
+	svc := h.service(r)
+	// Exports of other tenants are reported as unknown
+	if errors.Is(err, inventory.ErrNotFound) || !svc.OwnsKey(export.ObjectKey) {
+		respondWithError(w, http.StatusNotFound, "Inventory export not found", mux.Vars(r)["id"])
+		return
+	}
+
+	response := InventoryExportResponse{Export: export}
+	if export.Status == inventory.StatusCompleted {
+		if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: export.ObjectKey}) {
+			return
+		}
+		presigned, err := svc.PresignDownload(export.ObjectKey, service.DownloadOptions{})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+			return
+		}
+		h.recordIssued(r, presigned)
+		response.URL = presigned.URL
+		response.ExpiresAt = presigned.ExpiresAt
+		if !h.sealURLs(w, r, &response.URL) {
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseDateRange parses an inclusive YYYY-MM-DD range into the half-open
+// interval of times it covers. Empty bounds are left zero.
+func parseDateRange(fromDate, toDate string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if fromDate != "" {
+		if from, err = time.Parse(time.DateOnly, fromDate); err != nil {
+			return from, to, errors.New("from must be a YYYY-MM-DD date")
+		}
+	}
+	if toDate != "" {
+		if to, err = time.Parse(time.DateOnly, toDate); err != nil {
+			return from, to, errors.New("to must be a YYYY-MM-DD date")
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, errors.New("from must not be after to")
+	}
+	return from, to, nil
+}
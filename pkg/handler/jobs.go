@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/scheduler"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// jobState holds the latest results of background jobs
+type jobState struct {
+	mu               sync.RWMutex
+	multipartCleanup map[string]*service.CleanupReport // By tenant ID, "" for COMPANY_PREFIX
+	prefixUsage      map[string]*service.UsageReport   // By tenant ID, "" for COMPANY_PREFIX
+	backupAges       *BackupAgeReport
+	staleBackups     map[string]bool // Stale state by tenant and filename, for alerts
+}
+
+// StartBackgroundJobs starts the background jobs enabled in the configuration.
+// Jobs stop when ctx is cancelled.
+func (h *Handler) StartBackgroundJobs(ctx context.Context) {
+	if h.cfg.MultipartCleanupIntervalMinutes > 0 {
+		h.metrics.Describe("multipart_cleanup_aborted_total", "Incomplete multipart uploads aborted by cleanup")
+		h.metrics.Describe("multipart_cleanup_bytes_reclaimed_total", "Bytes of orphaned parts reclaimed by cleanup")
+
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "multipart-cleanup",
+			Interval: time.Duration(h.cfg.MultipartCleanupIntervalMinutes) * time.Minute,
+			Run:      h.AbortStaleMultipartUploads,
+		})
+	}
+
+	if h.cfg.PrefixUsageIntervalMinutes > 0 {
+		h.metrics.Describe("prefix_objects", "Objects stored under the company prefix per day folder")
+		h.metrics.Describe("prefix_bytes", "Bytes stored under the company prefix per day folder")
+		h.metrics.Describe("prefix_usage_collected_timestamp_seconds", "Unix time of the last prefix usage collection")
+
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "prefix-usage",
+			Interval: time.Duration(h.cfg.PrefixUsageIntervalMinutes) * time.Minute,
+			Run:      h.CollectPrefixUsage,
+		})
+	}
+
+	if h.s3Service.FailoverEnabled() {
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "failover-check",
+			Interval: time.Duration(h.cfg.FailoverCheckIntervalSeconds) * time.Second,
+			Run: func(ctx context.Context) error {
+				h.s3Service.CheckFailover(ctx)
+				return nil
+			},
+		})
+	}
+
+	if h.cfg.ClockDriftCheckIntervalSeconds > 0 {
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "clock-drift-check",
+			Interval: time.Duration(h.cfg.ClockDriftCheckIntervalSeconds) * time.Second,
+			Run: func(ctx context.Context) error {
+				h.s3Service.CheckClockDrift(ctx)
+				return nil
+			},
+		})
+	}
+
+	if h.cfg.StaleBackupCheckIntervalMinutes > 0 && (len(h.cfg.ExpectedBackups) > 0 || h.tenants != nil) {
+		h.metrics.Describe("backup_age_seconds", "Seconds since the latest upload of an expected backup")
+		h.metrics.Describe("backup_stale", "Whether an expected backup is overdue (1) or not (0)")
+
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "stale-backup-check",
+			Interval: time.Duration(h.cfg.StaleBackupCheckIntervalMinutes) * time.Minute,
+			Run:      h.CheckStaleBackups,
+		})
+	}
+
+	if h.cfg.RestoreDrillIntervalMinutes > 0 {
+		h.metrics.Describe("restore_drills_total", "Restore drills by result (ok or failed)")
+		h.metrics.Describe("restore_drill_last_success_timestamp_seconds", "Unix time of the last successful restore drill")
+
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "restore-drill",
+			Interval: time.Duration(h.cfg.RestoreDrillIntervalMinutes) * time.Minute,
+			Run:      h.runScheduledRestoreDrills,
+		})
+	}
+
+	if h.usage != nil {
+		h.metrics.Describe("access_log_objects_total", "S3 access log objects ingested")
+		h.metrics.Describe("access_log_requests_total", "Access log records of presigned requests by result: matched, unknown (not issued here) or malformed")
+
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "access-log-ingest",
+			Interval: time.Duration(h.cfg.AccessLogIntervalMinutes) * time.Minute,
+			Run:      h.IngestAccessLogs,
+		})
+	}
+
+	if h.cfg.SoftDelete && h.cfg.TrashRetentionDays > 0 && h.cfg.TrashPurgeIntervalMinutes > 0 {
+		h.metrics.Describe("trash_purged_total", "Trashed objects permanently deleted by the purge")
+		h.metrics.Describe("trash_bytes_freed_total", "Bytes of trashed objects freed by the purge")
+
+		scheduler.Start(ctx, scheduler.Job{
+			Name:     "trash-purge",
+			Interval: time.Duration(h.cfg.TrashPurgeIntervalMinutes) * time.Minute,
+			Run:      h.runTrashPurge,
+		})
+	}
+}
+
+// AbortStaleMultipartUploads aborts multipart uploads older than
+// MULTIPART_CLEANUP_MAX_AGE_HOURS in the company prefix and every tenant
+// prefix, keeping each prefix's report for its own callers
+func (h *Handler) AbortStaleMultipartUploads(ctx context.Context) error {
+	if refused, _ := h.writesRefused(time.Now()); refused {
+		logging.Debugf("Multipart cleanup skipped: writes are paused for maintenance")
+		return nil
+	}
+	services := map[string]*service.S3Service{"": h.s3Service}
+	if h.tenants != nil {
+		tenants, err := h.tenants.List()
+		if err != nil {
+			return err
+		}
+		for _, t := range tenants {
+			services[t.ID] = h.tenantService(&t)
+		}
+	}
+
+	maxAge := time.Duration(h.cfg.MultipartCleanupMaxAgeHours) * time.Hour
+	reports := make(map[string]*service.CleanupReport, len(services))
+	for tenantID, svc := range services {
+		report, err := svc.AbortStaleMultipartUploads(ctx, maxAge)
+		if err != nil {
+			return err
+		}
+		reports[tenantID] = report
+
+		h.metrics.AddCounter("multipart_cleanup_aborted_total", nil, float64(len(report.Aborted)))
+		h.metrics.AddCounter("multipart_cleanup_bytes_reclaimed_total", nil, float64(report.BytesReclaimed))
+
+		if len(report.Aborted) > 0 || len(report.Errors) > 0 {
+			logging.Infof("Multipart cleanup of %s: scanned %d, aborted %d, reclaimed %d bytes, %d errors",
+				report.Prefix, report.Scanned, len(report.Aborted), report.BytesReclaimed, len(report.Errors))
+		}
+	}
+
+	h.jobs.mu.Lock()
+	h.jobs.multipartCleanup = reports
+	h.jobs.mu.Unlock()
+
+	return nil
+}
+
+// CollectPrefixUsage collects per-day storage usage of the company prefix and
+// every tenant prefix, keeping each prefix's report for its own callers. The
+// company prefix's usage is also published as gauges.
+func (h *Handler) CollectPrefixUsage(ctx context.Context) error {
+	services := map[string]*service.S3Service{"": h.s3Service}
+	if h.tenants != nil {
+		tenants, err := h.tenants.List()
+		if err != nil {
+			return err
+		}
+		for _, t := range tenants {
+			services[t.ID] = h.tenantService(&t)
+		}
+	}
+
+	reports := make(map[string]*service.UsageReport, len(services))
+	for tenantID, svc := range services {
+		report, err := svc.CollectPrefixUsage(ctx)
+		if err != nil {
+			return err
+		}
+		reports[tenantID] = report
+	}
+
+	h.jobs.mu.Lock()
+	h.jobs.prefixUsage = reports
+	h.jobs.mu.Unlock()
+
+	report := reports[""]
+	for _, day := range report.Days {
+		labels := metrics.Labels{"day": day.Date}
+		h.metrics.SetGauge("prefix_objects", labels, float64(day.Objects))
+		h.metrics.SetGauge("prefix_bytes", labels, float64(day.Bytes))
+	}
+	h.metrics.SetGauge("prefix_objects", metrics.Labels{"day": "total"}, float64(report.TotalObjects))
+	h.metrics.SetGauge("prefix_bytes", metrics.Labels{"day": "total"}, float64(report.TotalBytes))
+	h.metrics.SetGauge("prefix_usage_collected_timestamp_seconds", nil, float64(report.CollectedAt.Unix()))
+
+	return nil
+}
+
+// runTrashPurge permanently deletes objects trashed more than
+// TRASH_RETENTION_DAYS ago, in the company prefix and every tenant prefix
+func (h *Handler) runTrashPurge(ctx context.Context) error {
+	if refused, _ := h.writesRefused(time.Now()); refused {
+		logging.Debugf("Trash purge skipped: writes are paused for maintenance")
+		return nil
+	}
+	services := []*service.S3Service{h.s3Service}
+	if h.tenants != nil {
+		tenants, err := h.tenants.List()
+		if err != nil {
+			return err
+		}
+		for _, t := range tenants {
+			services = append(services, h.tenantService(&t))
+		}
+	}
+
+	maxAge := time.Duration(h.cfg.TrashRetentionDays) * 24 * time.Hour
+	for _, svc := range services {
+		report, err := svc.PurgeTrash(ctx, maxAge)
+		if err != nil {
+			return err
+		}
+
+		h.metrics.AddCounter("trash_purged_total", nil, float64(len(report.Purged)))
+		h.metrics.AddCounter("trash_bytes_freed_total", nil, float64(report.BytesFreed))
+
+		if len(report.Purged) > 0 || len(report.Errors) > 0 {
+			logging.Infof("Trash purge of %s: scanned %d, purged %d, freed %d bytes, %d errors",
+				report.Prefix, report.Scanned, len(report.Purged), report.BytesFreed, len(report.Errors))
+		}
+	}
+
+	return nil
+}
+
+// GetPrefixUsage returns the cached per-day storage usage of the caller's
+// prefix: the tenant's, or COMPANY_PREFIX for callers that aren't tenants
+func (h *Handler) GetPrefixUsage(w http.ResponseWriter, r *http.Request) {
+	var tenantID string
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	h.jobs.mu.RLock()
+	report := h.jobs.prefixUsage[tenantID]
+	h.jobs.mu.RUnlock()
+
+	if report == nil {
+		respondWithError(w, http.StatusNotFound, "Prefix usage has not been collected yet", "")
+		return
+	}
+
+	respondWithConditionalJSON(w, r, report, report.CollectedAt)
+}
+
+// GetMultipartCleanupReport returns the last cleanup pass over the caller's
+// prefix: the tenant's, or COMPANY_PREFIX for callers that aren't tenants
+func (h *Handler) GetMultipartCleanupReport(w http.ResponseWriter, r *http.Request) {
+	var tenantID string
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	h.jobs.mu.RLock()
+	report := h.jobs.multipartCleanup[tenantID]
+	h.jobs.mu.RUnlock()
+
+	if report == nil {
+		respondWithError(w, http.StatusNotFound, "No cleanup has run yet", "")
+		return
+	}
+
+	respondWithConditionalJSON(w, r, report, report.FinishedAt)
+}
+
+// Metrics returns the registry backing the /metrics endpoint
+func (h *Handler) Metrics() *metrics.Registry {
+	return h.metrics
+}
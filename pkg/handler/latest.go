@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// LatestObjectResponse describes the most recent upload of a file with a
+// presigned URL to download it
+type LatestObjectResponse struct {
+	Object   *service.ObjectInfo   `json:"object"`
+	Download *service.PresignedURL `json:"download"`
+}
+
+// GetLatestObject finds the most recent upload whose file name matches the
+// filename query parameter (a name or a glob such as db-*.dump.gz) and returns
+// its metadata with a presigned GET URL
+func (h *Handler) GetLatestObject(w http.ResponseWriter, r *http.Request) {
+	if !h.operationAllowed(OperationDownload) {
+		respondWithCodedError(w, http.StatusForbidden, CodeOperationNotAllowed, "Downloads are not allowed", "")
+		return
+	}
+
+	pattern := r.URL.Query().Get("filename")
+	if err := service.ValidateFilenamePattern(pattern); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid filename", err.Error())
+		return
+	}
+
+	svc := h.service(r)
+	info, err := svc.FindLatest(r.Context(), pattern)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "No object matches the filename", pattern)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to find latest object", err)
+		return
+	}
+
+	if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: info.Key}) {
+		return
+	}
+
+	presigned, err := svc.PresignDownload(info.Key, service.DownloadOptions{})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+		return
+	}
+	h.recordIssued(r, presigned)
+	if !h.sealURLs(w, r, &presigned.URL) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, LatestObjectResponse{Object: info, Download: presigned})
+}
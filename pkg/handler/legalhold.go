@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// LegalHoldRequest represents the request body for changing a legal hold
+type LegalHoldRequest struct {
+	ObjectKey string `json:"object_key"`
+	Status    string `json:"status"` // ON or OFF
+}
+
+// ObjectLockResponse represents an object's Object Lock state
+type ObjectLockResponse struct {
+	ObjectKey  string              `json:"object_key"`
+	ObjectLock *service.ObjectLock `json:"object_lock"` // null when the object has no retention or legal hold
+}
+
+// GetObjectLock returns the retention and legal hold of the object given by
+// the object_key query parameter
+func (h *Handler) GetObjectLock(w http.ResponseWriter, r *http.Request) {
+	objectKey := r.URL.Query().Get("object_key")
+	if objectKey == "" {
+		respondWithError(w, http.StatusBadRequest, "object_key is required", "")
+		return
+	}
+	if !h.service(r).OwnsKey(objectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+
+	info, err := h.service(r).HeadObject(r.Context(), objectKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Object not found", objectKey)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to read object lock", err)
+		return
+	}
+
+	// Lock changes do not touch LastModified, so only the ETag applies
+	respondWithConditionalJSON(w, r, ObjectLockResponse{ObjectKey: objectKey, ObjectLock: info.ObjectLock}, time.Time{})
+}
+
+// SetLegalHold places or removes a legal hold on an existing object. Removing
+// a hold makes the object deletable again, so it is authorized as a delete.
+func (h *Handler) SetLegalHold(w http.ResponseWriter, r *http.Request) {
+	var req LegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.ObjectKey == "" {
+		respondWithError(w, http.StatusBadRequest, "object_key is required", "")
+		return
+	}
+	if req.Status != service.LegalHoldOn && req.Status != service.LegalHoldOff {
+		respondWithError(w, http.StatusBadRequest, "status must be ON or OFF", "")
+		return
+	}
+	if !h.service(r).OwnsKey(req.ObjectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+
+	op := OperationUpload
+	if req.Status == service.LegalHoldOff {
+		op = OperationDelete
+	}
+	if !h.allows(r, op) {
+		h.respondWithInsufficientScope(w, r, op)
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: op, ObjectKey: req.ObjectKey}) {
+		return
+	}
+
+	if err := h.service(r).SetLegalHold(r.Context(), req.ObjectKey, req.Status); err != nil {
+		h.respondWithS3Error(w, "Failed to set legal hold", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ObjectLockResponse{
+		ObjectKey:  req.ObjectKey,
+		ObjectLock: &service.ObjectLock{LegalHold: req.Status},
+	})
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// S3 limits on object tags
+const (
+	maxObjectTags     = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// UpdateMetadataRequest represents the request body for annotating an object
+type UpdateMetadataRequest struct {
+	ObjectKey string            `json:"object_key"`
+	Metadata  map[string]string `json:"metadata,omitempty"` // Entries added or replaced
+	Remove    []string          `json:"remove,omitempty"`   // Metadata keys removed
+	Tags      map[string]string `json:"tags,omitempty"`     // Replaces every tag when present, {} clears them
+}
+
+// UpdateObjectMetadata records annotations such as a processing status on an
+// existing object by rewriting it in place with updated metadata and tags
+func (h *Handler) UpdateObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	var req UpdateMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.ObjectKey == "" {
+		respondWithError(w, http.StatusBadRequest, "object_key is required", "")
+		return
+	}
+	if len(req.Metadata) == 0 && len(req.Remove) == 0 && req.Tags == nil {
+		respondWithError(w, http.StatusBadRequest, "metadata, remove or tags is required", "")
+		return
+	}
+	if problems := h.validateMetadataUpdate(&req); len(problems) > 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid metadata update", strings.Join(problems, "; "))
+		return
+	}
+	svc := h.service(r)
+	if !svc.OwnsKey(req.ObjectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationUpload, ObjectKey: req.ObjectKey}) {
+		return
+	}
+
+	source, err := svc.HeadObject(r.Context(), req.ObjectKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Object not found", req.ObjectKey)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to read object", err)
+		return
+	}
+
+	update := service.MetadataUpdate{Set: req.Metadata, Remove: req.Remove, Tags: req.Tags}
+	if problems := validateMetadata(service.MergeMetadata(source.Metadata, update)); len(problems) > 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid metadata update", strings.Join(problems, "; "))
+		return
+	}
+
+	updated, err := svc.UpdateMetadata(r.Context(), source, update)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectTooLarge) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Object is too large to update", err.Error())
+			return
+		}
+		h.respondWithS3Error(w, "Failed to update object metadata", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// validateMetadataUpdate checks the keys touched and the tags. Injected
+// metadata keys can't be changed, so provenance stays trustworthy.
+func (h *Handler) validateMetadataUpdate(req *UpdateMetadataRequest) []string {
+	injected := make(map[string]bool, len(h.cfg.InjectedMetadata))
+	for _, entry := range h.cfg.InjectedMetadata {
+		key, _, _ := strings.Cut(entry, "=")
+		injected[key] = true
+	}
+
+	var problems []string
+	for _, k := range slices.Sorted(maps.Keys(req.Metadata)) {
+		if injected[strings.ToLower(k)] {
+			problems = append(problems, fmt.Sprintf("metadata key %q is injected by the service and can't be changed", k))
+		}
+	}
+	for _, k := range req.Remove {
+		switch {
+		case k == "":
+			problems = append(problems, "remove must not contain empty keys")
+		case injected[strings.ToLower(k)]:
+			problems = append(problems, fmt.Sprintf("metadata key %q is injected by the service and can't be removed", k))
+		}
+	}
+
+	if len(req.Tags) > maxObjectTags {
+		problems = append(problems, fmt.Sprintf("at most %d tags are allowed", maxObjectTags))
+	}
+	for _, k := range slices.Sorted(maps.Keys(req.Tags)) {
+		v := req.Tags[k]
+		if k == "" || len(k) > maxTagKeyLength || len(v) > maxTagValueLength {
+			problems = append(problems, fmt.Sprintf("tag %q must have a key of 1 to %d bytes and a value of at most %d", k, maxTagKeyLength, maxTagValueLength))
+		}
+		if strings.IndexFunc(k+v, unicode.IsControl) >= 0 {
+			problems = append(problems, fmt.Sprintf("tag %q must not contain control characters", k))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metaschema"
+)
+
+// CodeMetadataSchema is returned when upload metadata doesn't match the
+// metadata schema
+const CodeMetadataSchema = "METADATA_SCHEMA_VIOLATION"
+
+// WithMetadataSchema checks upload metadata against schema. Without a schema
+// any metadata passing the basic key and size checks is signed.
+func WithMetadataSchema(schema *metaschema.Schema) Option {
+	return func(h *Handler) {
+		h.metaschema = schema
+	}
+}
+
+// checkMetadataSchema responds with 400 when metadata breaks the schema. It
+// reports whether the handler may continue.
+func (h *Handler) checkMetadataSchema(w http.ResponseWriter, metadata map[string]string) bool {
+	if h.metaschema == nil {
+		return true
+	}
+
+	if problems := h.metaschema.Check(metadata); len(problems) > 0 {
+		respondWithCodedError(w, http.StatusBadRequest, CodeMetadataSchema, "Metadata does not match schema", strings.Join(problems, "; "))
+		return false
+	}
+	return true
+}
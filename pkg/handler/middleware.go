@@ -0,0 +1,453 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/apikey"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/oidc"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+)
+
+// Middleware wraps an http.Handler with additional behavior
+type Middleware func(http.Handler) http.Handler
+
+// publicPaths are never subject to authentication
+var publicPaths = map[string]bool{
+	"/health":  true,
+	"/ready":   true,
+	"/metrics": true,
+}
+
+// adminPathPrefix marks the admin API, authenticated by requireAdmin instead
+// of the middleware chain so the first managed key can be issued
+const adminPathPrefix = "/admin/"
+
+// skipsAuth reports whether a request bypasses the authentication middleware.
+// The web UI's files are public; its API requests carry credentials.
+func skipsAuth(r *http.Request) bool {
+	return publicPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, adminPathPrefix) ||
+		strings.HasPrefix(r.URL.Path, downloadTokenPathPrefix) || strings.HasPrefix(r.URL.Path+"/", uiPathPrefix) ||
+		r.Method == http.MethodOptions
+}
+
+// Use registers additional middleware that runs after the built-in chain,
+// in the order given. It must be called before SetupRoutes.
+func (h *Handler) Use(mw ...Middleware) {
+	h.middlewares = append(h.middlewares, mw...)
+}
+
+// buildChain resolves the configured middleware names followed by the
+// middleware registered via Use, outermost first
+func (h *Handler) buildChain(router *mux.Router) []Middleware {
+	var chain []Middleware
+
+	for _, name := range h.cfg.MiddlewareChain {
+		switch name {
+		case "i18n":
+			chain = append(chain, i18nMiddleware(h.cfg.DefaultLanguage))
+		case "recovery":
+			chain = append(chain, recoveryMiddleware)
+		case "realip":
+			if len(h.cfg.TrustedProxies) > 0 {
+				chain = append(chain, realIPMiddleware(parseTrustedProxies(h.cfg.TrustedProxies), h.cfg.RealIPHeader))
+			}
+		case "tracing":
+			chain = append(chain, tracingMiddleware)
+		case "logging":
+			chain = append(chain, loggingMiddleware)
+		case "metrics":
+			chain = append(chain, metricsMiddleware(h.metrics, router))
+		case "gzip":
+			if h.cfg.GzipEnabled {
+				chain = append(chain, gzipMiddleware(h.cfg.GzipMinBytes))
+			}
+		case "cors":
+			if len(h.cfg.CORSAllowedOrigins) > 0 {
+				chain = append(chain, corsMiddleware(h.cfg.CORSAllowedOrigins))
+			}
+		case "ratelimit":
+			if h.cfg.RateLimitRPS > 0 {
+				chain = append(chain, rateLimitMiddleware(h.cfg.RateLimitRPS, h.cfg.RateLimitBurst))
+			}
+		case "concurrency":
+			if h.cfg.MaxConcurrentRequests > 0 {
+				chain = append(chain, concurrencyMiddleware(h.cfg.MaxConcurrentRequests, h.metrics, router))
+			}
+		case "oidc":
+			if h.oidc != nil {
+				chain = append(chain, oidcMiddleware(h.oidc, h.cfg.OIDCRequiredScopes))
+			}
+		case "hmac":
+			if len(h.cfg.HMACSecrets) > 0 {
+				chain = append(chain, hmacMiddleware(h.cfg.HMACSecrets, h.tenants, h.nonces))
+			}
+		case "auth":
+			if len(h.cfg.APIKeys) > 0 || h.apiKeys != nil || h.tenants != nil {
+				chain = append(chain, authMiddleware(h.cfg.APIKeys, h.apiKeys, h.tenants))
+			} else {
+				logging.Warnf("auth middleware enabled but no API keys or tenants are configured, requests are not authenticated")
+			}
+		}
+	}
+
+	return append(chain, h.middlewares...)
+}
+
+// applyChain wraps handler with the chain so that chain[0] is the outermost
+func applyChain(handler http.Handler, chain []Middleware) http.Handler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+	return handler
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController so
+// streaming handlers can flush and adjust deadlines
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// recoveryMiddleware converts panics into 500 responses
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logging.Errorf("Panic serving %s %s: %v", r.Method, r.URL.Path, rec)
+				respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loggingMiddleware logs client IP, method, path, status and duration of each request
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logging.Infof("%s %s %s %d %s", clientIP(r), r.Method, r.URL.Path, rec.status, time.Since(start))
+	})
+}
+
+// metricsMiddleware records request counts and durations per route template
+func metricsMiddleware(registry *metrics.Registry, router *mux.Router) Middleware {
+	registry.Describe("http_requests_total", "Total HTTP requests by method, route and status")
+	registry.Describe("http_request_duration_seconds_sum", "Total time spent serving HTTP requests")
+	registry.Describe("http_request_duration_seconds_count", "Number of timed HTTP requests")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// Use the route template rather than the raw path to bound cardinality
+			route := "unmatched"
+			var match mux.RouteMatch
+			if router.Match(r, &match) && match.Route != nil {
+				if tpl, err := match.Route.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+
+			registry.IncCounter("http_requests_total", metrics.Labels{
+				"method": r.Method,
+				"route":  route,
+				"status": strconv.Itoa(rec.status),
+			})
+			labels := metrics.Labels{"route": route}
+			registry.AddCounter("http_request_duration_seconds_sum", labels, time.Since(start).Seconds())
+			registry.IncCounter("http_request_duration_seconds_count", labels)
+		})
+	}
+}
+
+// corsMiddleware sets CORS headers for allowed origins and answers preflights
+func corsMiddleware(allowedOrigins []string) Middleware {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && (allowAll || allowed[origin]) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Signature, X-Signature-Key-Id, X-Signature-Nonce, X-Signer-Debug, X-Admin-Key")
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tokenBucket is a simple token bucket used for per-client rate limiting
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimitMiddleware limits each client IP to rps requests per second with
+// the given burst
+func rateLimitMiddleware(rps float64, burst int) Middleware {
+	var mu sync.Mutex
+	buckets := make(map[string]*tokenBucket)
+
+	allow := func(client string, now time.Time) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Drop idle buckets so the map doesn't grow without bound
+		if len(buckets) > 10000 {
+			for k, b := range buckets {
+				if now.Sub(b.lastSeen) > time.Minute {
+					delete(buckets, k)
+				}
+			}
+		}
+
+		b, ok := buckets[client]
+		if !ok {
+			b = &tokenBucket{tokens: float64(burst), lastSeen: now}
+			buckets[client] = b
+		}
+
+		b.tokens += now.Sub(b.lastSeen).Seconds() * rps
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+		b.lastSeen = now
+
+		if b.tokens < 1 {
+			return false
+		}
+		b.tokens--
+		return true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allow(clientIP(r), time.Now()) {
+				respondWithRetryableError(w, http.StatusTooManyRequests, 1, "Rate limit exceeded", "")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unlimitedRoutes are long-lived streams that would hold a concurrency slot
+// for their whole lifetime
+var unlimitedRoutes = map[string]bool{
+	"/api/v1/sessions/{id}/events": true,
+}
+
+// concurrencyMiddleware serves at most limit requests at once, shedding the
+// rest with 503 and Retry-After instead of queueing them. Public paths and
+// event streams don't take a slot.
+func concurrencyMiddleware(limit int, registry *metrics.Registry, router *mux.Router) Middleware {
+	registry.Describe("http_requests_in_flight", "Requests holding a concurrency slot")
+	registry.Describe("http_requests_shed_total", "Requests rejected because all concurrency slots were taken")
+	slots := make(chan struct{}, limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var match mux.RouteMatch
+			if router.Match(r, &match) && match.Route != nil {
+				if tpl, err := match.Route.GetPathTemplate(); err == nil && unlimitedRoutes[tpl] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			select {
+			case slots <- struct{}{}:
+			default:
+				registry.IncCounter("http_requests_shed_total", nil)
+				respondWithRetryableError(w, http.StatusServiceUnavailable, 1, "Server is busy", "too many concurrent requests")
+				return
+			}
+			registry.SetGauge("http_requests_in_flight", nil, float64(len(slots)))
+			defer func() {
+				<-slots
+				registry.SetGauge("http_requests_in_flight", nil, float64(len(slots)))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authMiddleware requires a valid API key via X-API-Key or Authorization:
+// Bearer and attaches the caller's Principal (see resolveAPIKey)
+func authMiddleware(apiKeys []string, managed *apikey.Store, tenants tenant.Store) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipsAuth(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, t := resolveAPIKey(requestAPIKey(r), apiKeys, managed, tenants)
+			if principal == nil {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "missing or invalid API key")
+				return
+			}
+
+			ctx := withPrincipal(r.Context(), principal)
+			if t != nil {
+				ctx = withTenant(ctx, t)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// resolveAPIKey identifies the caller behind key, returning nil if it's not
+// valid. Static keys configured as name:key[:scopes] and managed keys use
+// their name as subject, so policies can refer to the caller, and carry their
+// scopes. Keys of tenants in the store also return the tenant, scoping the
+// request to its prefix.
+func resolveAPIKey(key string, apiKeys []string, managed *apikey.Store, tenants tenant.Store) (*Principal, *tenant.Tenant) {
+	if key == "" {
+		return nil, nil
+	}
+
+	for _, entry := range apiKeys {
+		name, valid, scopes := splitAPIKey(entry)
+		if subtle.ConstantTimeCompare([]byte(key), []byte(valid)) == 1 {
+			return &Principal{Subject: name, Method: "apikey", Scopes: scopes}, nil
+		}
+	}
+
+	if managed != nil {
+		if k, ok := managed.Verify(key); ok {
+			return &Principal{Subject: k.Name, Method: "apikey", Scopes: k.Scopes}, nil
+		}
+	}
+
+	if tenants != nil {
+		if t, err := tenants.GetByAPIKeyHash(tenant.HashAPIKey(key)); err == nil {
+			return &Principal{Subject: t.ID, Method: "tenant"}, &t
+		}
+	}
+
+	return nil, nil
+}
+
+// oidcMiddleware requires a valid OAuth2 access token in Authorization:
+// Bearer carrying all requiredScopes, and attaches the caller's Principal
+func oidcMiddleware(verifier *oidc.Verifier, requiredScopes []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipsAuth(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized", "missing bearer token")
+				return
+			}
+
+			claims, err := verifier.Verify(r.Context(), strings.TrimPrefix(auth, "Bearer "))
+			if err != nil {
+				if errors.Is(err, oidc.ErrInvalidToken) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					respondWithError(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+					return
+				}
+				respondWithRetryableError(w, http.StatusServiceUnavailable, 5, "Identity provider unavailable", err.Error())
+				return
+			}
+
+			principal := &Principal{
+				Subject:  claims.Subject,
+				ClientID: claims.ClientID,
+				Method:   "oidc",
+				Scopes:   claims.Scopes,
+			}
+			for _, scope := range requiredScopes {
+				if !principal.HasScope(scope) {
+					respondWithCodedError(w, http.StatusForbidden, CodeInsufficientScope, "Insufficient scope", "requires scope "+scope)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// splitAPIKey splits a configured "name:key" or "name:key:scope+scope"
+// entry; unnamed keys have an empty name and keys without scopes nil scopes
+func splitAPIKey(entry string) (string, string, []string) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", entry, nil
+	}
+	if len(parts) == 2 {
+		return parts[0], parts[1], nil
+	}
+	return parts[0], parts[1], strings.Split(parts[2], "+")
+}
+
+// requestAPIKey extracts the API key from the request headers
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// clientIP returns the client IP resolved by the realip middleware, or the
+// remote IP of the connection without the port
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
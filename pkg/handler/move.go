@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// MoveObjectRequest represents the request body for moving an object
+type MoveObjectRequest struct {
+	SourceKey      string `json:"source_key"`
+	DestinationKey string `json:"destination_key"`
+	Overwrite      bool   `json:"overwrite,omitempty"` // Replace an existing destination object
+	DryRun         bool   `json:"dry_run,omitempty"`   // Run the checks without moving anything
+}
+
+// MoveObjectResponse represents the result of a move
+type MoveObjectResponse struct {
+	SourceKey      string              `json:"source_key"`
+	DestinationKey string              `json:"destination_key"`
+	DryRun         bool                `json:"dry_run"`
+	Moved          bool                `json:"moved"`
+	Object         *service.ObjectInfo `json:"object"` // Source on dry runs, destination otherwise
+}
+
+// MoveObject copies an object to a new key within the company prefix and
+// deletes the original
+func (h *Handler) MoveObject(w http.ResponseWriter, r *http.Request) {
+	var req MoveObjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.SourceKey == "" || req.DestinationKey == "" {
+		respondWithError(w, http.StatusBadRequest, "source_key and destination_key are required", "")
+		return
+	}
+	if req.SourceKey == req.DestinationKey {
+		respondWithError(w, http.StatusBadRequest, "source_key and destination_key must differ", "")
+		return
+	}
+	svc := h.service(r)
+	if !svc.OwnsKey(req.SourceKey) || !svc.OwnsKey(req.DestinationKey) {
+		respondWithError(w, http.StatusForbidden, "source_key and destination_key must be inside the company prefix", "")
+		return
+	}
+
+	if !h.authorize(w, r, policy.Request{Operation: OperationDelete, ObjectKey: req.SourceKey}) {
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationUpload, ObjectKey: req.DestinationKey}) {
+		return
+	}
+
+	source, err := svc.HeadObject(r.Context(), req.SourceKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Source object not found", req.SourceKey)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to read source object", err)
+		return
+	}
+	if !h.checkRetention(w, r, source) {
+		return
+	}
+
+	if !req.Overwrite {
+		_, err := svc.HeadObject(r.Context(), req.DestinationKey)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "Destination object already exists", "set overwrite to replace it")
+			return
+		}
+		if !errors.Is(err, service.ErrObjectNotFound) {
+			h.respondWithS3Error(w, "Failed to check destination object", err)
+			return
+		}
+	}
+
+	response := MoveObjectResponse{
+		SourceKey:      req.SourceKey,
+		DestinationKey: req.DestinationKey,
+		DryRun:         req.DryRun,
+	}
+
+	if req.DryRun {
+		response.Object = source
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	moved, err := svc.MoveObject(r.Context(), source, req.DestinationKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectTooLarge) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Object is too large to move", err.Error())
+			return
+		}
+		h.respondWithS3Error(w, "Failed to move object", err)
+		return
+	}
+
+	response.Moved = true
+	response.Object = moved
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// contentTypeNDJSON is the media type of newline-delimited JSON responses
+const contentTypeNDJSON = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client asked for a streamed listing with
+// Accept: application/x-ndjson
+func wantsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == contentTypeNDJSON {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ndjsonStream writes listing entries one JSON document per line, flushing
+// after every page so clients can process results as they arrive. Once the
+// first line is written the status is committed, so failures are reported as
+// a final {"error": ...} line.
+type ndjsonStream struct {
+	h            *Handler
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	enc          *json.Encoder
+	writeTimeout time.Duration
+	started      bool
+}
+
+// newNDJSONStream prepares w for an NDJSON response. Each flush extends the
+// write deadline by HTTP_WRITE_TIMEOUT_SECONDS, so long listings aren't cut
+// off by the server's write timeout while they keep making progress.
+func (h *Handler) newNDJSONStream(w http.ResponseWriter) *ndjsonStream {
+	return &ndjsonStream{
+		h:            h,
+		w:            w,
+		rc:           http.NewResponseController(w),
+		enc:          json.NewEncoder(w),
+		writeTimeout: time.Duration(h.cfg.HTTPWriteTimeoutSeconds) * time.Second,
+	}
+}
+
+// start commits the 200 status and headers
+func (s *ndjsonStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", contentTypeNDJSON)
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// write encodes v as one line
+func (s *ndjsonStream) write(v any) error {
+	s.start()
+	return s.enc.Encode(v)
+}
+
+// flush sends buffered lines to the client
+func (s *ndjsonStream) flush() {
+	s.start()
+	if s.writeTimeout > 0 {
+		_ = s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	}
+	_ = s.rc.Flush()
+}
+
+// fail ends the stream with an error line, or responds normally when nothing
+// was written yet
+func (s *ndjsonStream) fail(message string, err error) {
+	if !s.started {
+		s.h.respondWithS3Error(s.w, message, err)
+		return
+	}
+	_ = s.enc.Encode(ErrorResponse{Error: message, Message: err.Error()})
+	_ = s.rc.Flush()
+}
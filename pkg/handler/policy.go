@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+)
+
+// CodePolicyDenied is returned when the authorization policy rejects a request
+const CodePolicyDenied = "POLICY_DENIED"
+
+// WithPolicy authorizes presign requests against p. Without a policy every
+// authenticated caller may sign any operation allowed by the configuration.
+func WithPolicy(p *policy.Policy) Option {
+	return func(h *Handler) {
+		h.policy = p
+	}
+}
+
+// authorize evaluates req for the request's caller and responds with 403 when
+// the policy denies it. It reports whether the handler may continue.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, req policy.Request) bool {
+	if allowed, message := h.policyAllows(r, req); !allowed {
+		respondWithCodedError(w, http.StatusForbidden, CodePolicyDenied, "Denied by policy", message)
+		return false
+	}
+	return true
+}
+
+// policyAllows evaluates the policy for the request's principal, returning
+// why it was denied
+func (h *Handler) policyAllows(r *http.Request, req policy.Request) (bool, string) {
+	if h.policy == nil {
+		return true, ""
+	}
+
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		req.Subjects = []string{p.Subject, p.ClientID}
+	}
+
+	decision := h.policy.Evaluate(req)
+	if !decision.Allowed {
+		message := decision.Reason
+		if decision.Rule != "" {
+			message += " " + decision.Rule
+		}
+		return false, message
+	}
+	return true, ""
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// UploadPreviewResponse describes the PUT a v1 upload URL issued for the
+// same request would expect
+type UploadPreviewResponse struct {
+	Method        string   `json:"method"`
+	ObjectKey     string   `json:"object_key"`     // Key a URL issued now would get
+	SignedHeaders []string `json:"signed_headers"` // X-Amz-SignedHeaders, host included
+	// Every header the PUT must send verbatim; signed headers S3 rejects
+	// when missing or changed
+	Headers map[string]string `json:"headers"`
+	// Header name each requested metadata key is signed as
+	MetadataHeaders   map[string]string `json:"metadata_headers,omitempty"`
+	ContentTypeSigned bool              `json:"content_type_signed"`
+	Notes             []string          `json:"notes,omitempty"`
+}
+
+// PreviewUpload validates a v1 upload request like GeneratePutURL and
+// returns the headers the PUT must send, without issuing a URL. Quota,
+// duplicate and run checks are not applied.
+func (h *Handler) PreviewUpload(w http.ResponseWriter, r *http.Request) {
+	var req PresignedURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	requested := req.Metadata
+
+	opts, objectKey, ok := h.uploadOptionsV1(w, r, &req, h.service(r).NewUploadID())
+	if !ok {
+		return
+	}
+	headers := h.service(r).SignedUploadHeaders(opts)
+
+	response := UploadPreviewResponse{
+		Method:            http.MethodPut,
+		ObjectKey:         objectKey,
+		SignedHeaders:     append([]string{"host"}, slices.Collect(maps.Keys(headers))...),
+		Headers:           headers,
+		ContentTypeSigned: opts.ContentType != "",
+	}
+	slices.Sort(response.SignedHeaders)
+
+	response.MetadataHeaders = requiredHeaders(requested)
+	for _, key := range slices.Sorted(maps.Keys(requested)) {
+		if value := service.MetadataHeaderValue(requested[key]); value != requested[key] {
+			response.Notes = append(response.Notes, fmt.Sprintf("metadata %s is signed as %q: surrounding and repeated whitespace is removed", key, value))
+		}
+	}
+
+	if !response.ContentTypeSigned {
+		response.Notes = append(response.Notes, "Content-Type is not signed: the PUT may send any value and S3 stores it as sent")
+	}
+	if len(h.cfg.InjectedMetadata) > 0 {
+		response.Notes = append(response.Notes, "injected metadata values are computed per URL: send the headers returned with the issued URL")
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
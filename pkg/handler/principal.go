@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+)
+
+// CodeInsufficientScope is returned when the caller lacks the operation scope
+const CodeInsufficientScope = "INSUFFICIENT_SCOPE"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject  string   `json:"subject"`
+	ClientID string   `json:"client_id,omitempty"`
+	Method   string   `json:"method"` // How the caller authenticated, e.g. "oidc"
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is the context key for the request Principal
+type principalKey struct{}
+
+// withPrincipal returns a copy of ctx carrying p
+func withPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// ScopeAdmin grants access to the admin API
+const ScopeAdmin = "admin"
+
+// knownScopes are the scopes API keys can be granted: the operations plus
+// admin
+var knownScopes = map[string]bool{
+	OperationUpload:   true,
+	OperationDownload: true,
+	OperationDelete:   true,
+	ScopeAdmin:        true,
+}
+
+// allows reports whether the request's caller may perform op. OIDC tokens
+// need the prefixed scope and API or HMAC keys with scopes the bare one;
+// callers authenticated without scopes (or not at all) are unrestricted.
+func (h *Handler) allows(r *http.Request, op string) bool {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return true
+	}
+	return h.principalAllows(p, op)
+}
+
+// principalAllows reports whether p was granted scope
+func (h *Handler) principalAllows(p *Principal, scope string) bool {
+	switch p.Method {
+	case "oidc":
+		return p.HasScope(h.cfg.OIDCScopePrefix + scope)
+	case "apikey", "hmac":
+		return p.Scopes == nil || p.HasScope(scope)
+	}
+	return true
+}
+
+// respondWithInsufficientScope responds with 403 naming the scope the
+// caller's credential lacks
+func (h *Handler) respondWithInsufficientScope(w http.ResponseWriter, r *http.Request, scope string) {
+	if p, ok := PrincipalFromContext(r.Context()); ok && p.Method == "oidc" {
+		scope = h.cfg.OIDCScopePrefix + scope
+	}
+	respondWithCodedError(w, http.StatusForbidden, CodeInsufficientScope, "Insufficient scope", "requires scope "+scope)
+}
+
+// requireOperation wraps next so it only runs for callers allowed to perform op
+func (h *Handler) requireOperation(op string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.allows(r, op) {
+			h.respondWithInsufficientScope(w, r, op)
+			return
+		}
+		next(w, r)
+	}
+}
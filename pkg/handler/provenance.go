@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+// requestIDHeader carries the request ID injected metadata refers to, taken
+// from the client when valid and echoed in the response
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern bounds the client request IDs that are trusted as-is
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// injectMetadata returns metadata with the INJECTED_METADATA entries resolved
+// for r and uploadID (empty outside single-URL uploads). Injected keys replace
+// client keys of the same name, so clients can't forge provenance, and entries
+// resolving to an empty value are left out.
+func (h *Handler) injectMetadata(w http.ResponseWriter, r *http.Request, metadata map[string]string, uploadID string) map[string]string {
+	if len(h.cfg.InjectedMetadata) == 0 {
+		return metadata
+	}
+
+	requestID := r.Header.Get(requestIDHeader)
+	if !requestIDPattern.MatchString(requestID) {
+		requestID = idgen.New()
+	}
+	w.Header().Set(requestIDHeader, requestID)
+
+	tenantID := h.cfg.CompanyPrefix
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	var subject string
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		subject = p.Subject
+	}
+	replacer := strings.NewReplacer(
+		"{tenant}", tenantID,
+		"{principal}", subject,
+		"{request_id}", requestID,
+		"{client_ip}", clientIP(r),
+		"{upload_id}", uploadID,
+	)
+
+	injected := make(map[string]string, len(metadata)+len(h.cfg.InjectedMetadata))
+	for k, v := range metadata {
+		injected[k] = v
+	}
+	for _, entry := range h.cfg.InjectedMetadata {
+		key, template, _ := strings.Cut(entry, "=")
+		for k := range injected {
+			if strings.EqualFold(k, key) {
+				delete(injected, k)
+			}
+		}
+		if value := metadataValue(replacer.Replace(template)); value != "" {
+			injected[key] = value
+		}
+	}
+	return injected
+}
+
+// metadataValue replaces characters S3 can't store in a metadata header,
+// which caller identities from tokens may contain, with "_"
+func metadataValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '_'
+		}
+		return r
+	}, s)
+}
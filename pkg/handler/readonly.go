@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// CodeMaintenance is returned for writes while the service is read-only
+const CodeMaintenance = "MAINTENANCE"
+
+// maintenanceRetrySeconds is the Retry-After of writes refused in read-only
+// mode; how long the mode lasts is up to the operator
+const maintenanceRetrySeconds = 60
+
+// MaintenanceResponse refuses a write during maintenance. EndsAt is the end
+// of the current maintenance window, omitted in read-only mode.
+type MaintenanceResponse struct {
+	ErrorResponse
+	EndsAt time.Time `json:"ends_at,omitzero"`
+}
+
+// MaintenanceStatusResponse reports whether writes are refused now and when
+// the next maintenance window opens, so agents can pause ahead of it
+type MaintenanceStatusResponse struct {
+	WritesAllowed bool      `json:"writes_allowed"`
+	ReadOnly      bool      `json:"read_only"`
+	InWindow      bool      `json:"in_window"`
+	EndsAt        time.Time `json:"ends_at,omitzero"`        // End of the current window
+	NextWindowAt  time.Time `json:"next_window_at,omitzero"` // Start of the next window
+	Windows       []string  `json:"windows,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+}
+
+// ReadOnlyRequest represents the request body for switching read-only mode
+type ReadOnlyRequest struct {
+	ReadOnly *bool `json:"read_only"`
+}
+
+// ReadOnlyResponse reports whether the service is read-only
+type ReadOnlyResponse struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+// requireWritable wraps a handler that issues upload or delete URLs or writes
+// to the bucket, refusing it with 503 MAINTENANCE while read-only or inside a
+// maintenance window
+func (h *Handler) requireWritable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.checkWritable(w) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writesRefused reports whether writes are refused at now and, inside a
+// maintenance window, when the window ends (zero in read-only mode)
+func (h *Handler) writesRefused(now time.Time) (bool, time.Time) {
+	if h.readOnly.Load() {
+		return true, time.Time{}
+	}
+	if h.maintenance == nil || !h.maintenance.Open(now) {
+		return false, time.Time{}
+	}
+	end, _ := h.maintenance.NextClose(now) // Validated to close
+	return true, end
+}
+
+// checkWritable responds with 503 MAINTENANCE when writes are refused, with
+// Retry-After set to the end of the maintenance window
+func (h *Handler) checkWritable(w http.ResponseWriter) bool {
+	now := time.Now()
+	refused, end := h.writesRefused(now)
+	if !refused {
+		return true
+	}
+
+	response := MaintenanceResponse{ErrorResponse: ErrorResponse{
+		Error:     "Service is read-only",
+		Message:   "uploads and deletes are paused for maintenance; downloads and searches still work",
+		Code:      CodeMaintenance,
+		Retryable: true,
+	}}
+	retryAfter := maintenanceRetrySeconds
+	if !end.IsZero() {
+		response.Error = "Maintenance window"
+		response.Message = fmt.Sprintf("uploads and deletes are paused until %s; downloads and searches still work", end.UTC().Format(time.RFC3339))
+		response.EndsAt = end.UTC()
+		retryAfter = int(math.Ceil(end.Sub(now).Seconds()))
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	respondWithJSON(w, http.StatusServiceUnavailable, response)
+	return false
+}
+
+// GetMaintenance reports whether writes are refused and the maintenance
+// windows, for agents to pause uploads ahead of them
+func (h *Handler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	response := MaintenanceStatusResponse{
+		ReadOnly: h.readOnly.Load(),
+		Windows:  h.cfg.MaintenanceWindows,
+		Timezone: h.cfg.MaintenanceTimezone,
+	}
+	if h.maintenance != nil {
+		// The next window opens after the current one ends
+		from := now
+		if h.maintenance.Open(now) {
+			end, _ := h.maintenance.NextClose(now)
+			response.InWindow, response.EndsAt = true, end.UTC()
+			from = end
+		}
+		if next, ok := h.maintenance.NextOpen(from); ok {
+			response.NextWindowAt = next.UTC()
+		}
+	}
+	response.WritesAllowed = !response.ReadOnly && !response.InWindow
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetReadOnly reports whether the service is read-only
+func (h *Handler) GetReadOnly(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, ReadOnlyResponse{ReadOnly: h.readOnly.Load()})
+}
+
+// SetReadOnly switches read-only mode until the next change or restart,
+// which goes back to READ_ONLY
+func (h *Handler) SetReadOnly(w http.ResponseWriter, r *http.Request) {
+	var req ReadOnlyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if req.ReadOnly == nil {
+		respondWithError(w, http.StatusBadRequest, "read_only is required", "")
+		return
+	}
+
+	if previous := h.readOnly.Swap(*req.ReadOnly); previous != *req.ReadOnly {
+		subject := ""
+		if p, ok := PrincipalFromContext(r.Context()); ok {
+			subject = p.Subject
+		}
+		// Logged at error level so the change is recorded at any level
+		logging.Errorf("Read-only mode set to %t by %s", *req.ReadOnly, subject)
+	}
+
+	respondWithJSON(w, http.StatusOK, ReadOnlyResponse{ReadOnly: *req.ReadOnly})
+}
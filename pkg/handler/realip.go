@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// clientIPKey is the context key for the client IP resolved from proxy headers
+type clientIPKey struct{}
+
+// parseTrustedProxies converts IPs and CIDRs into prefixes. Invalid entries
+// are skipped; config.Validate rejects them up front.
+func parseTrustedProxies(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// realIPMiddleware resolves the client IP from header, Forwarded or
+// X-Forwarded-For, when the request comes from a trusted proxy. Only the
+// header the proxies write is read: a client could send the other one, which
+// passes through untouched. The chain of addresses is walked right to left,
+// skipping trusted proxies, so clients can't spoof their IP by prepending
+// hops either.
+func realIPMiddleware(trusted []netip.Prefix, header string) Middleware {
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remote, err := netip.ParseAddr(remoteHost(r))
+			if err != nil || !isTrusted(remote) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hops := forwardedFor(r, header)
+			if len(hops) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := hops[0]
+			for i := len(hops) - 1; i >= 0; i-- {
+				addr, err := netip.ParseAddr(hops[i])
+				if err != nil {
+					// Unparseable hop: stop at the last address we could trust
+					if i < len(hops)-1 {
+						client = hops[i+1]
+					} else {
+						client = remote.String()
+					}
+					break
+				}
+				if !isTrusted(addr) {
+					client = addr.Unmap().String()
+					break
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, client)))
+		})
+	}
+}
+
+// forwardedFor returns the client chain from header, the Forwarded header
+// (RFC 7239) or X-Forwarded-For, leftmost (original client) first
+func forwardedFor(r *http.Request, header string) []string {
+	var hops []string
+
+	if header == "Forwarded" {
+		for _, value := range r.Header.Values("Forwarded") {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(key, "for") {
+						hops = append(hops, forwardedNode(val))
+					}
+				}
+			}
+		}
+		return hops
+	}
+
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// forwardedNode strips quotes, IPv6 brackets and ports from a Forwarded
+// "for" value, e.g. "[2001:db8::1]:4711" -> 2001:db8::1
+func forwardedNode(value string) string {
+	value = strings.Trim(value, `"`)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return host
+	}
+	return strings.Trim(value, "[]")
+}
+
+// remoteHost returns the peer address of the connection without the port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// ReplicationResponse reports an object's cross-region replication state
+type ReplicationResponse struct {
+	ObjectKey         string                `json:"object_key"`
+	ReplicationStatus string                `json:"replication_status"` // Empty when not replicated or the primary failed
+	PrimaryAvailable  bool                  `json:"primary_available"`
+	PrimaryError      string                `json:"primary_error,omitempty"`
+	Fallback          *service.PresignedURL `json:"fallback,omitempty"` // GET against the DR bucket when the primary failed
+}
+
+// GetReplicationStatus returns the replication status of the object given by
+// the object_key query parameter. When the primary bucket fails and
+// DR_BUCKET_NAME is set, it responds with a presigned GET against the DR
+// bucket instead.
+func (h *Handler) GetReplicationStatus(w http.ResponseWriter, r *http.Request) {
+	objectKey := r.URL.Query().Get("object_key")
+	if objectKey == "" {
+		respondWithError(w, http.StatusBadRequest, "object_key is required", "")
+		return
+	}
+	svc := h.service(r)
+	if !svc.OwnsKey(objectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+
+	info, err := svc.HeadObject(r.Context(), objectKey)
+	if err == nil {
+		respondWithConditionalJSON(w, r, ReplicationResponse{
+			ObjectKey:         objectKey,
+			ReplicationStatus: info.ReplicationStatus,
+			PrimaryAvailable:  true,
+		}, time.Time{})
+		return
+	}
+	if errors.Is(err, service.ErrObjectNotFound) {
+		respondWithError(w, http.StatusNotFound, "Object not found", objectKey)
+		return
+	}
+	if !svc.HasReplica() || !h.operationAllowed(OperationDownload) {
+		h.respondWithS3Error(w, "Failed to read replication status", err)
+		return
+	}
+
+	if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: objectKey}) {
+		return
+	}
+	fallback, presignErr := svc.PresignReplicaDownload(objectKey)
+	if presignErr != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", presignErr.Error())
+		return
+	}
+	h.recordIssued(r, fallback)
+	if !h.sealURLs(w, r, &fallback.URL) {
+		return
+	}
+	logging.Warnf("Primary bucket failed for %s, offering the DR bucket: %v", objectKey, err)
+
+	respondWithJSON(w, http.StatusOK, ReplicationResponse{
+		ObjectKey:    objectKey,
+		PrimaryError: err.Error(),
+		Fallback:     fallback,
+	})
+}
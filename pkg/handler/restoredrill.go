@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/restoredrill"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// defaultRestoreDrillList is the number of drills listed without a limit
+const defaultRestoreDrillList = 20
+
+// RestoreDrillsResponse lists recorded restore drills, newest first
+type RestoreDrillsResponse struct {
+	Drills []restoredrill.Drill `json:"drills"`
+}
+
+// RunRestoreDrill restores a random recent upload of the caller's prefix
+// and records the result. Responds 201 when every check passed and 502 when
+// one failed; the drill is recorded either way.
+func (h *Handler) RunRestoreDrill(w http.ResponseWriter, r *http.Request) {
+	var tenantID string
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+
+	drill := h.restoreDrill(r.Context(), h.service(r), tenantID, restoredrill.TriggerAPI)
+
+	status := http.StatusCreated
+	if !drill.OK {
+		status = http.StatusBadGateway
+	}
+	respondWithJSON(w, status, drill)
+}
+
+// ListRestoreDrills returns the drills of the caller's prefix, newest first,
+// up to the limit query parameter
+func (h *Handler) ListRestoreDrills(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRestoreDrillList
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > h.cfg.RestoreDrillHistory {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and RESTORE_DRILL_HISTORY", v)
+			return
+		}
+		limit = n
+	}
+
+	var tenantID string
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	respondWithJSON(w, http.StatusOK, RestoreDrillsResponse{Drills: h.drills.List(tenantID, limit)})
+}
+
+// GetRestoreDrill returns a drill of the caller's prefix
+func (h *Handler) GetRestoreDrill(w http.ResponseWriter, r *http.Request) {
+	drill, err := h.drills.Get(mux.Vars(r)["id"])
+	t, isTenant := TenantFromContext(r.Context())
+	if errors.Is(err, restoredrill.ErrNotFound) || (isTenant && drill.TenantID != t.ID) || (!isTenant && drill.TenantID != "") {
+		respondWithError(w, http.StatusNotFound, "Restore drill not found", "")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, drill)
+}
+
+// runScheduledRestoreDrills runs a drill for the company prefix and every
+// tenant prefix
+func (h *Handler) runScheduledRestoreDrills(ctx context.Context) error {
+	h.restoreDrill(ctx, h.s3Service, "", restoredrill.TriggerSchedule)
+	if h.tenants == nil {
+		return nil
+	}
+
+	tenants, err := h.tenants.List()
+	if err != nil {
+		return err
+	}
+	for _, t := range tenants {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		h.restoreDrill(ctx, h.tenantService(&t), t.ID, restoredrill.TriggerSchedule)
+	}
+	return nil
+}
+
+// restoreDrill runs, records and counts a drill against svc
+func (h *Handler) restoreDrill(ctx context.Context, svc *service.S3Service, tenantID, trigger string) restoredrill.Drill {
+	started := time.Now().UTC()
+	report := svc.RestoreDrill(ctx, time.Duration(h.cfg.RestoreDrillMaxAgeHours)*time.Hour, int64(h.cfg.RestoreDrillMaxBytes))
+	drill := h.drills.Record(restoredrill.Drill{
+		TenantID:           tenantID,
+		Trigger:            trigger,
+		StartedAt:          started,
+		FinishedAt:         time.Now().UTC(),
+		RestoreDrillReport: report,
+	})
+
+	if drill.OK {
+		h.metrics.IncCounter("restore_drills_total", metrics.Labels{"result": "ok"})
+		h.metrics.SetGauge("restore_drill_last_success_timestamp_seconds", metrics.Labels{"tenant": tenantID}, float64(drill.FinishedAt.Unix()))
+	} else {
+		h.metrics.IncCounter("restore_drills_total", metrics.Labels{"result": "failed"})
+		logging.Warnf("Restore drill %s of tenant %q failed on %s", drill.ID, tenantID, drill.ObjectKey)
+	}
+	return drill
+}
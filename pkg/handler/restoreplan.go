@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// RestorePlanFile is a file of a restore plan with the URL to download it.
+// Files the policy denies carry its code instead of a URL.
+type RestorePlanFile struct {
+	service.RestorePlanFile
+	Download *service.PresignedURL `json:"download,omitempty"`
+	Code     string                `json:"code,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// RestorePlanResponse is one page of the files to restore to reconstruct the
+// prefix as of At
+type RestorePlanResponse struct {
+	At         time.Time         `json:"at"`
+	TotalFiles int               `json:"total_files"`
+	TotalSize  int64             `json:"total_size"`
+	Files      []RestorePlanFile `json:"files"`
+	NextToken  string            `json:"next_token,omitempty"` // Continuation token of the next page
+}
+
+// GetRestorePlan returns the latest upload at or before the at query
+// parameter (RFC 3339) of every file under the caller's prefix, with
+// presigned GET URLs, a page of max_keys files at a time. The plan is
+// computed from a full listing on every page; continuation_token is the last
+// path of the previous page.
+func (h *Handler) GetRestorePlan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	at, err := time.Parse(time.RFC3339, query.Get("at"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "at must be an RFC 3339 timestamp", query.Get("at"))
+		return
+	}
+	maxKeys, ok := parseMaxKeys(w, r)
+	if !ok {
+		return
+	}
+
+	svc := h.service(r)
+	files, err := svc.RestorePlan(r.Context(), at)
+	if err != nil {
+		if errors.Is(err, service.ErrInventoryTooLarge) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Prefix holds too many uploads to plan a restore", err.Error())
+			return
+		}
+		h.respondWithS3Error(w, "Failed to plan restore", err)
+		return
+	}
+
+	response := RestorePlanResponse{At: at.UTC(), TotalFiles: len(files), Files: []RestorePlanFile{}}
+	for _, f := range files {
+		response.TotalSize += f.Size
+	}
+
+	token := query.Get("continuation_token")
+	start := sort.Search(len(files), func(i int) bool { return files[i].Path > token })
+	page := files[start:min(start+int(maxKeys), len(files))]
+	if start+len(page) < len(files) {
+		response.NextToken = page[len(page)-1].Path
+	}
+
+	for _, f := range page {
+		file := RestorePlanFile{RestorePlanFile: f}
+		if allowed, message := h.policyAllows(r, policy.Request{Operation: OperationDownload, ObjectKey: f.ObjectKey}); !allowed {
+			file.Code, file.Error = CodePolicyDenied, message
+			response.Files = append(response.Files, file)
+			continue
+		}
+		presigned, err := svc.PresignDownload(f.ObjectKey, service.DownloadOptions{})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+			return
+		}
+		h.recordIssued(r, presigned)
+		file.Download = presigned
+		response.Files = append(response.Files, file)
+	}
+
+	var urls []*string
+	for i := range response.Files {
+		if response.Files[i].Download != nil {
+			urls = append(urls, &response.Files[i].Download.URL)
+		}
+	}
+	if !h.sealURLs(w, r, urls...) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
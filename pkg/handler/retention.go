@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// CodeRetentionActive is returned when deleting an object younger than the
+// minimum retention age
+const CodeRetentionActive = "RETENTION_ACTIVE"
+
+// minRetention returns how old an object of the request's tenant must be
+// before it may be deleted, 0 if deletes are not restricted
+func (h *Handler) minRetention(r *http.Request) time.Duration {
+	hours := h.cfg.MinRetentionHours
+	if t, ok := TenantFromContext(r.Context()); ok && t.MinRetentionHours > 0 {
+		hours = t.MinRetentionHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// checkRetention responds with 403 RETENTION_ACTIVE when info was written
+// less than the minimum retention age ago. It reports whether the handler may
+// continue.
+func (h *Handler) checkRetention(w http.ResponseWriter, r *http.Request, info *service.ObjectInfo) bool {
+	if err := h.retentionError(r, info); err != nil {
+		respondWithCodedError(w, http.StatusForbidden, CodeRetentionActive, "Object is within its minimum retention period", err.Error())
+		return false
+	}
+	return true
+}
+
+// retentionError describes why info may not be deleted yet, or returns nil
+// once it is older than the minimum retention age
+func (h *Handler) retentionError(r *http.Request, info *service.ObjectInfo) error {
+	retention := h.minRetention(r)
+	if retention == 0 {
+		return nil
+	}
+
+	deletableAt := info.LastModified.Add(retention)
+	if time.Now().Before(deletableAt) {
+		return fmt.Errorf("%s may be deleted after %s", info.Key, deletableAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkRetentionKey looks up objectKey and applies checkRetention. Missing
+// objects pass, since deleting them removes nothing.
+func (h *Handler) checkRetentionKey(w http.ResponseWriter, r *http.Request, objectKey string) bool {
+	if h.minRetention(r) == 0 {
+		return true
+	}
+
+	info, err := h.service(r).HeadObject(r.Context(), objectKey)
+	if errors.Is(err, service.ErrObjectNotFound) {
+		return true
+	}
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to check object retention", err)
+		return false
+	}
+	return h.checkRetention(w, r, info)
+}
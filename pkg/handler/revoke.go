@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/urlregistry"
+)
+
+// Presigned URL verification error codes
+const (
+	CodeURLUnknown = "URL_UNKNOWN"
+	CodeURLExpired = "URL_EXPIRED"
+	CodeURLRevoked = "URL_REVOKED"
+	// Returned for deferred URLs before their not_before time
+	CodeURLNotYetValid = "URL_NOT_YET_VALID"
+)
+
+// PresignedURLCheckRequest represents the request body for verifying a
+// presigned URL
+type PresignedURLCheckRequest struct {
+	URL string `json:"url"`
+}
+
+// RevokeURLRequest represents the request body for revoking a presigned URL
+type RevokeURLRequest struct {
+	URL          string `json:"url"`
+	Reason       string `json:"reason,omitempty"`
+	DeleteObject bool   `json:"delete_object,omitempty"` // Upload URLs only
+}
+
+// PresignedURLStatusResponse describes an issued presigned URL
+type PresignedURLStatusResponse struct {
+	Valid         bool              `json:"valid"`
+	URL           urlregistry.Entry `json:"url"`
+	ObjectDeleted bool              `json:"object_deleted,omitempty"`
+}
+
+// IssuedURLsResponse lists presigned URLs issued by this signer
+type IssuedURLsResponse struct {
+	URLs []urlregistry.Entry `json:"urls"`
+}
+
+// methodOperations maps presigned URL methods to the operation they perform
+var methodOperations = map[string]string{
+	http.MethodPut:    OperationUpload,
+	http.MethodGet:    OperationDownload,
+	http.MethodDelete: OperationDelete,
+}
+
+// recordIssued registers a presigned URL issued to the request's caller so it
+// can later be verified or revoked, and audits its issuance
+func (h *Handler) recordIssued(r *http.Request, presigned *service.PresignedURL) {
+	h.recordIssuedUpload(r, presigned, "")
+}
+
+// recordIssuedUpload is recordIssued for an upload URL correlated by
+// uploadID, which confirm accepts in place of the object key
+func (h *Handler) recordIssuedUpload(r *http.Request, presigned *service.PresignedURL, uploadID string) {
+	entry := urlregistry.Entry{
+		Method:    presigned.Method,
+		ObjectKey: presigned.ObjectKey,
+		IssuedAt:  time.Now().UTC(),
+		ExpiresAt: presigned.ExpiresAt,
+		UploadID:  uploadID,
+	}
+	if notBefore := presigned.NotBefore; !notBefore.IsZero() {
+		entry.NotBefore = &notBefore
+	}
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		entry.Subject = p.Subject
+	}
+	if err := h.issued.Record(presigned.URL, entry); err != nil {
+		logging.Warnf("failed to record issued URL for %s: %v", presigned.ObjectKey, err)
+	}
+	h.publishIssued(r, presigned, uploadID)
+	h.detectAnomalies(r, presigned)
+}
+
+// urlExpiry returns when a URL for method issued now expires
+func (h *Handler) urlExpiry(method string) time.Time {
+	return time.Now().UTC().Truncate(time.Second).Add(h.s3Service.Expiration(method))
+}
+
+// lookupIssued returns the registry entry of a URL issued for the caller's
+// prefix, responding with 400 or 404 otherwise
+func (h *Handler) lookupIssued(w http.ResponseWriter, r *http.Request, url string) (urlregistry.Entry, bool) {
+	if url == "" {
+		respondWithError(w, http.StatusBadRequest, "url is required", "")
+		return urlregistry.Entry{}, false
+	}
+
+	entry, err := h.issued.Lookup(url)
+	if errors.Is(err, urlregistry.ErrNotFound) || (err == nil && !h.service(r).OwnsKey(entry.ObjectKey)) {
+		respondWithCodedError(w, http.StatusNotFound, CodeURLUnknown, "Presigned URL not found", "not issued by this signer or already expired")
+		return urlregistry.Entry{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid presigned URL", err.Error())
+		return urlregistry.Entry{}, false
+	}
+	return entry, true
+}
+
+// VerifyPresignedURL reports whether a presigned URL was issued by this
+// signer and is neither expired nor revoked. Revoked URLs are refused with
+// 403 and expired ones with 410. Deferred URLs are refused with 403 until
+// their not_before time, enforcing it exactly where S3 allows clock skew.
+func (h *Handler) VerifyPresignedURL(w http.ResponseWriter, r *http.Request) {
+	var req PresignedURLCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	entry, ok := h.lookupIssued(w, r, req.URL)
+	if !ok {
+		return
+	}
+
+	switch {
+	case entry.RevokedAt != nil:
+		respondWithCodedError(w, http.StatusForbidden, CodeURLRevoked, "Presigned URL has been revoked", entry.Reason)
+	case time.Now().After(entry.ExpiresAt):
+		respondWithCodedError(w, http.StatusGone, CodeURLExpired, "Presigned URL has expired", "")
+	case entry.NotBefore != nil && time.Now().Before(*entry.NotBefore):
+		respondWithCodedError(w, http.StatusForbidden, CodeURLNotYetValid, "Presigned URL is not valid yet",
+			"valid from "+entry.NotBefore.Format(time.RFC3339))
+	default:
+		respondWithJSON(w, http.StatusOK, PresignedURLStatusResponse{Valid: true, URL: entry})
+	}
+}
+
+// RevokePresignedURL revokes an issued presigned URL. With delete_object, an
+// upload URL's target object is deleted if it was written after the URL was
+// issued, since it may have been uploaded through the compromised URL.
+func (h *Handler) RevokePresignedURL(w http.ResponseWriter, r *http.Request) {
+	var req RevokeURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	entry, ok := h.lookupIssued(w, r, req.URL)
+	if !ok {
+		return
+	}
+
+	// Revoking requires the scope of the operation the URL grants
+	op := methodOperations[entry.Method]
+	if !h.allows(r, op) {
+		h.respondWithInsufficientScope(w, r, op)
+		return
+	}
+
+	response := PresignedURLStatusResponse{}
+	if req.DeleteObject {
+		if entry.Method != http.MethodPut {
+			respondWithError(w, http.StatusBadRequest, "delete_object only applies to upload URLs", "")
+			return
+		}
+		if !h.checkWritable(w) {
+			return
+		}
+		if !h.allows(r, OperationDelete) {
+			h.respondWithInsufficientScope(w, r, OperationDelete)
+			return
+		}
+		if !h.authorize(w, r, policy.Request{Operation: OperationDelete, ObjectKey: entry.ObjectKey}) {
+			return
+		}
+		if !h.checkRetentionKey(w, r, entry.ObjectKey) {
+			return
+		}
+
+		deleted, err := h.deleteUploadedSince(r, entry)
+		if err != nil {
+			h.respondWithS3Error(w, "Failed to delete target object", err)
+			return
+		}
+		response.ObjectDeleted = deleted
+	}
+
+	revoked, err := h.issued.Revoke(req.URL, req.Reason)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke presigned URL", err.Error())
+		return
+	}
+	response.URL = revoked
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// ListIssuedURLs returns the presigned URLs still held by the registry, most
+// recently issued first, up to max_keys. The prefix, subject and method query
+// parameters filter them.
+func (h *Handler) ListIssuedURLs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	maxKeys, ok := parseMaxKeys(w, r)
+	if !ok {
+		return
+	}
+
+	prefix, subject, method := query.Get("prefix"), query.Get("subject"), strings.ToUpper(query.Get("method"))
+	urls := h.issued.List(func(e urlregistry.Entry) bool {
+		return strings.HasPrefix(e.ObjectKey, prefix) &&
+			(subject == "" || e.Subject == subject) &&
+			(method == "" || e.Method == method)
+	}, int(maxKeys))
+	respondWithJSON(w, http.StatusOK, IssuedURLsResponse{URLs: urls})
+}
+
+// deleteUploadedSince deletes (or trashes, with soft delete) the URL's target
+// object if it was written after the URL was issued, reporting whether it did
+func (h *Handler) deleteUploadedSince(r *http.Request, entry urlregistry.Entry) (bool, error) {
+	svc := h.service(r)
+	info, err := svc.HeadObject(r.Context(), entry.ObjectKey)
+	if errors.Is(err, service.ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Last-Modified has second precision
+	if info.LastModified.Before(entry.IssuedAt.Truncate(time.Second)) {
+		return false, nil
+	}
+	if h.cfg.SoftDelete {
+		_, err = svc.TrashObject(r.Context(), info)
+	} else {
+		err = svc.DeleteObject(r.Context(), entry.ObjectKey)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/runs"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// CreateRunRequest represents the request body for registering a backup run
+type CreateRunRequest struct {
+	Name  string   `json:"name,omitempty"`
+	Files []string `json:"files"`
+}
+
+// ConfirmObjectRequest represents the request body for confirming an upload.
+// Either ObjectKey, UploadID, or RunID together with Filename, must be set.
+type ConfirmObjectRequest struct {
+	ObjectKey string `json:"object_key,omitempty"`
+	UploadID  string `json:"upload_id,omitempty"` // Returned with the upload URL, until it expires
+	RunID     string `json:"run_id,omitempty"`
+	Filename  string `json:"filename,omitempty"`
+	// Base64 data key from /api/v1/data-keys the object was encrypted with,
+	// stored as its wrapped-key metadata
+	WrappedKey string `json:"wrapped_key,omitempty"`
+}
+
+// ConfirmObjectResponse represents the response for a confirmed upload
+type ConfirmObjectResponse struct {
+	Confirmed bool                `json:"confirmed"`
+	UploadID  string              `json:"upload_id,omitempty"`
+	RunID     string              `json:"run_id,omitempty"`
+	Object    *service.ObjectInfo `json:"object"`
+}
+
+// CreateRun registers a backup run manifest listing the expected files
+func (h *Handler) CreateRun(w http.ResponseWriter, r *http.Request) {
+	var req CreateRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if len(req.Files) == 0 {
+		respondWithError(w, http.StatusBadRequest, "files is required", "")
+		return
+	}
+	for _, f := range req.Files {
+		if f == "" {
+			respondWithError(w, http.StatusBadRequest, "files must not contain empty names", "")
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusCreated, h.runs.Create(req.Name, req.Files))
+}
+
+// GetRunStatus reports which files of a run are confirmed and which are missing
+func (h *Handler) GetRunStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.runs.Status(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithRunError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// ConfirmObject verifies an uploaded object exists in the bucket and, when a
+// run is given, marks the file as confirmed in that run. With a catalog the
+// object is recorded in it.
+func (h *Handler) ConfirmObject(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmObjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	objectKey := req.ObjectKey
+	if objectKey == "" && req.UploadID != "" {
+		entry, err := h.issued.LookupUpload(req.UploadID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Upload ID not found", "the upload URL is unknown or expired; confirm by object_key")
+			return
+		}
+		objectKey = entry.ObjectKey
+	}
+	if objectKey == "" {
+		if req.RunID == "" || req.Filename == "" {
+			respondWithError(w, http.StatusBadRequest, "object_key, upload_id, or run_id and filename are required", "")
+			return
+		}
+		key, err := h.runs.IssuedKey(req.RunID, req.Filename)
+		if err != nil {
+			respondWithRunError(w, err)
+			return
+		}
+		if key == "" {
+			respondWithError(w, http.StatusConflict, "No upload URL has been issued for this file", "")
+			return
+		}
+		objectKey = key
+	}
+
+	if !h.service(r).OwnsKey(objectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+
+	info, err := h.service(r).HeadObject(r.Context(), objectKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Object not found", objectKey)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to confirm object", err)
+		return
+	}
+	// The upload went through a presigned URL, past the listing cache
+	h.service(r).InvalidateListings(objectKey)
+
+	if req.WrappedKey != "" {
+		if info = h.storeWrappedKey(w, r, info, req.WrappedKey); info == nil {
+			return
+		}
+	}
+
+	if req.RunID != "" {
+		filename := req.Filename
+		if filename == "" {
+			filename = path.Base(objectKey)
+		}
+		if err := h.runs.MarkConfirmed(req.RunID, filename, objectKey); err != nil {
+			respondWithRunError(w, err)
+			return
+		}
+	}
+
+	if !h.recordInCatalog(w, r, info, req.RunID) {
+		return
+	}
+
+	if req.UploadID != "" {
+		logging.Infof("Confirmed upload %s as %s", req.UploadID, objectKey)
+	}
+
+	respondWithJSON(w, http.StatusOK, ConfirmObjectResponse{
+		Confirmed: true,
+		UploadID:  req.UploadID,
+		RunID:     req.RunID,
+		Object:    info,
+	})
+}
+
+// respondWithRunError maps run store errors to HTTP responses
+func respondWithRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, runs.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Run not found", "")
+	case errors.Is(err, runs.ErrFileNotInManifest):
+		respondWithError(w, http.StatusBadRequest, "File is not part of the run manifest", "")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Run error", err.Error())
+	}
+}
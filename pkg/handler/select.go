@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// SelectRequest represents the request body for presigning an S3 Select query
+type SelectRequest struct {
+	ObjectKey    string `json:"object_key"`
+	Expression   string `json:"expression"`
+	InputFormat  string `json:"input_format"`            // csv or json
+	Compression  string `json:"compression,omitempty"`   // NONE, GZIP or BZIP2
+	CSVHeader    string `json:"csv_header,omitempty"`    // csv input: USE, IGNORE or NONE
+	JSONType     string `json:"json_type,omitempty"`     // json input: LINES or DOCUMENT
+	OutputFormat string `json:"output_format,omitempty"` // csv or json
+}
+
+// SelectResponse is a presigned S3 Select query: POST Body to URL
+type SelectResponse struct {
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ObjectKey string    `json:"object_key"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Body      string    `json:"body"` // XML request body, sent verbatim
+}
+
+// PresignSelect issues a presigned S3 Select query so analytics tools can
+// pull filtered rows out of a CSV or JSON object without downloading it
+func (h *Handler) PresignSelect(w http.ResponseWriter, r *http.Request) {
+	var req SelectRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if problems := validateSelect(&req); len(problems) > 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid select query", strings.Join(problems, "; "))
+		return
+	}
+	svc := h.service(r)
+	if !svc.OwnsKey(req.ObjectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationDownload, ObjectKey: req.ObjectKey}) {
+		return
+	}
+
+	presigned, body, err := svc.PresignSelect(req.ObjectKey, service.SelectQuery{
+		Expression:   req.Expression,
+		InputFormat:  req.InputFormat,
+		Compression:  req.Compression,
+		CSVHeader:    req.CSVHeader,
+		JSONType:     req.JSONType,
+		OutputFormat: req.OutputFormat,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+		return
+	}
+	h.recordIssued(r, presigned)
+	if !h.sealURLs(w, r, &presigned.URL) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, SelectResponse{
+		URL:       presigned.URL,
+		Method:    presigned.Method,
+		ObjectKey: presigned.ObjectKey,
+		ExpiresAt: presigned.ExpiresAt,
+		Body:      body,
+	})
+}
+
+// validateSelect returns every problem found in the query, normalizing the
+// enumerated options to the case S3 expects
+func validateSelect(req *SelectRequest) []string {
+	var problems []string
+	if req.ObjectKey == "" {
+		problems = append(problems, "object_key is required")
+	}
+	switch {
+	case strings.TrimSpace(req.Expression) == "":
+		problems = append(problems, "expression is required")
+	case len(req.Expression) > service.MaxSelectExpressionBytes:
+		problems = append(problems, fmt.Sprintf("expression must be at most %d bytes", service.MaxSelectExpressionBytes))
+	}
+
+	req.InputFormat = strings.ToLower(req.InputFormat)
+	req.OutputFormat = strings.ToLower(req.OutputFormat)
+	req.Compression = strings.ToUpper(req.Compression)
+	req.CSVHeader = strings.ToUpper(req.CSVHeader)
+	req.JSONType = strings.ToUpper(req.JSONType)
+
+	switch req.InputFormat {
+	case service.SelectFormatCSV:
+		if req.JSONType != "" {
+			problems = append(problems, "json_type is only allowed for json input")
+		}
+		if !oneOf(req.CSVHeader, "", "USE", "IGNORE", "NONE") {
+			problems = append(problems, fmt.Sprintf("csv_header must be USE, IGNORE or NONE (got %q)", req.CSVHeader))
+		}
+	case service.SelectFormatJSON:
+		if req.CSVHeader != "" {
+			problems = append(problems, "csv_header is only allowed for csv input")
+		}
+		if !oneOf(req.JSONType, "", "LINES", "DOCUMENT") {
+			problems = append(problems, fmt.Sprintf("json_type must be LINES or DOCUMENT (got %q)", req.JSONType))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("input_format must be csv or json (got %q)", req.InputFormat))
+	}
+	if !oneOf(req.Compression, "", "NONE", "GZIP", "BZIP2") {
+		problems = append(problems, fmt.Sprintf("compression must be NONE, GZIP or BZIP2 (got %q)", req.Compression))
+	}
+	if !oneOf(req.OutputFormat, "", service.SelectFormatCSV, service.SelectFormatJSON) {
+		problems = append(problems, fmt.Sprintf("output_format must be csv or json (got %q)", req.OutputFormat))
+	}
+	return problems
+}
+
+// oneOf reports whether value is one of allowed
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// SelfTest uploads, downloads and deletes a canary object through presigned
+// URLs and reports each step. Responds 502 when any step fails.
+func (h *Handler) SelfTest(w http.ResponseWriter, r *http.Request) {
+	report := h.service(r).SelfTest(r.Context())
+
+	status := http.StatusOK
+	if !report.OK {
+		status = http.StatusBadGateway
+	}
+	respondWithJSON(w, status, report)
+}
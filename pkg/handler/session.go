@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/session"
+)
+
+// sseKeepAliveInterval is how often a comment line is sent on idle event streams
+const sseKeepAliveInterval = 15 * time.Second
+
+// CompletePartRequest represents the request body for reporting an uploaded part
+type CompletePartRequest struct {
+	ETag string `json:"etag"`
+}
+
+// PartURLResponse represents the response for a presigned part URL
+type PartURLResponse struct {
+	URL        string `json:"url"`
+	PartNumber int    `json:"part_number"`
+}
+
+// CreateSession starts a multipart upload session
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var req PresignedURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Filename == "" {
+		respondWithError(w, http.StatusBadRequest, "filename is required", "")
+		return
+	}
+	if !service.ValidContentEncoding(req.ContentEncoding) {
+		respondWithError(w, http.StatusBadRequest, "content_encoding must be gzip", "")
+		return
+	}
+	if !req.NotBefore.IsZero() {
+		respondWithError(w, http.StatusBadRequest, "not_before is not supported for upload sessions", "")
+		return
+	}
+	if !h.checkMetadataSchema(w, req.Metadata) {
+		return
+	}
+	req.Metadata = h.injectMetadata(w, r, req.Metadata, "")
+	if err := h.service(r).CheckSignedHeaders(nil); err != nil {
+		respondWithSignedHeadersError(w, err)
+		return
+	}
+
+	objectKey, err := h.service(r).UploadKey(req.Filename)
+	if err != nil {
+		respondWithKeyError(w, err)
+		return
+	}
+	if !h.authorize(w, r, policy.Request{
+		Operation:   OperationUpload,
+		ObjectKey:   objectKey,
+		ContentType: req.ContentType,
+	}) {
+		return
+	}
+	if !h.checkTenantLimits(w, r, req.ContentType, 0) {
+		return
+	}
+
+	uploadID, objectKey, err := h.service(r).CreateMultipartUpload(r.Context(), req.Filename, req.ContentType, req.ContentEncoding, req.Metadata)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to create upload session", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, h.sessions.Create(req.Filename, objectKey, uploadID))
+}
+
+// GetSession returns the current state of an upload session
+func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := h.getSession(r, mux.Vars(r)["id"])
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sess)
+}
+
+// GeneratePartURL returns a presigned URL for uploading one part
+func (h *Handler) GeneratePartURL(w http.ResponseWriter, r *http.Request) {
+	partNumber, ok := parsePartNumber(w, r)
+	if !ok {
+		return
+	}
+
+	sess, err := h.getSession(r, mux.Vars(r)["id"])
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+	if sess.Status != session.StatusActive {
+		respondWithSessionError(w, session.ErrNotActive)
+		return
+	}
+
+	url, err := h.service(r).GeneratePresignedUploadPartURL(sess.ObjectKey, sess.UploadID, partNumber)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate presigned URL", err.Error())
+		return
+	}
+	h.recordIssued(r, &service.PresignedURL{
+		URL:       url,
+		Method:    http.MethodPut,
+		ObjectKey: sess.ObjectKey,
+		ExpiresAt: h.urlExpiry(http.MethodPut),
+	})
+	if !h.sealURLs(w, r, &url) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, PartURLResponse{URL: url, PartNumber: partNumber})
+}
+
+// CompletePart records a part the client finished uploading
+func (h *Handler) CompletePart(w http.ResponseWriter, r *http.Request) {
+	partNumber, ok := parsePartNumber(w, r)
+	if !ok {
+		return
+	}
+
+	var req CompletePartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.ETag == "" {
+		respondWithError(w, http.StatusBadRequest, "etag is required", "")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if _, err := h.getSession(r, id); err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	sess, err := h.sessions.CompletePart(id, partNumber, req.ETag)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sess)
+}
+
+// CompleteSession assembles the uploaded parts and confirms the session
+func (h *Handler) CompleteSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := h.getSession(r, id); err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+	sess, err := h.sessions.Claim(id)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+	if len(sess.Parts) == 0 {
+		h.sessions.Release(id)
+		respondWithError(w, http.StatusBadRequest, "No parts have been completed", "")
+		return
+	}
+
+	parts := make([]service.CompletedPart, 0, len(sess.Parts))
+	for _, p := range sess.Parts {
+		parts = append(parts, service.CompletedPart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+
+	if err := h.service(r).CompleteMultipartUpload(r.Context(), sess.ObjectKey, sess.UploadID, parts); err != nil {
+		h.sessions.Release(id)
+		h.respondWithS3Error(w, "Failed to complete upload session", err)
+		return
+	}
+
+	sess, err = h.sessions.Finish(id, session.StatusCompleted)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sess)
+}
+
+// AbortSession cancels a multipart upload session
+func (h *Handler) AbortSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := h.getSession(r, id); err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+	sess, err := h.sessions.Claim(id)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	if err := h.service(r).AbortMultipartUpload(r.Context(), sess.ObjectKey, sess.UploadID); err != nil {
+		h.sessions.Release(id)
+		h.respondWithS3Error(w, "Failed to abort upload session", err)
+		return
+	}
+
+	sess, err = h.sessions.Finish(id, session.StatusAborted)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sess)
+}
+
+// StreamSessionEvents streams part-completion and confirmation events for a
+// session as Server-Sent Events until the session finishes or the client
+// disconnects
+func (h *Handler) StreamSessionEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	sess, err := h.getSession(r, id)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+
+	events, cancel, err := h.sessions.Subscribe(id)
+	if err != nil {
+		respondWithSessionError(w, err)
+		return
+	}
+	defer cancel()
+
+	// Event streams outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// Send the current state first so dashboards can render immediately
+	writeSSE(w, "snapshot", sess)
+	_ = rc.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			_ = rc.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeSSE(w, event.Type, event)
+			_ = rc.Flush()
+		}
+	}
+}
+
+// writeSSE writes a single Server-Sent Event with a JSON payload
+func writeSSE(w http.ResponseWriter, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// parsePartNumber reads and validates the part number route variable
+func parsePartNumber(w http.ResponseWriter, r *http.Request) (int, bool) {
+	partNumber, err := strconv.Atoi(mux.Vars(r)["part"])
+	if err != nil || partNumber < 1 || partNumber > 10000 {
+		respondWithError(w, http.StatusBadRequest, "part number must be between 1 and 10000", "")
+		return 0, false
+	}
+	return partNumber, true
+}
+
+// getSession returns a session whose object lies under the caller's prefix,
+// so tenants can't reach each other's sessions by ID
+func (h *Handler) getSession(r *http.Request, id string) (session.Session, error) {
+	sess, err := h.sessions.Get(id)
+	if err != nil {
+		return session.Session{}, err
+	}
+	if !h.service(r).OwnsKey(sess.ObjectKey) {
+		return session.Session{}, session.ErrNotFound
+	}
+	return sess, nil
+}
+
+// respondWithSessionError maps session store errors to HTTP responses
+func respondWithSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, session.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Session not found", "")
+	case errors.Is(err, session.ErrNotActive):
+		respondWithError(w, http.StatusConflict, "Session is not active", "")
+	case errors.Is(err, session.ErrBusy):
+		respondWithError(w, http.StatusConflict, "Session is being completed or aborted", "")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Session error", err.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// SignatureDiagnosisRequest reports a request S3 rejected with
+// SignatureDoesNotMatch
+type SignatureDiagnosisRequest struct {
+	Method  string            `json:"method"`            // HTTP method the client used
+	URL     string            `json:"url"`               // Presigned URL the client used
+	Headers map[string]string `json:"headers,omitempty"` // Headers the client sent
+	S3Error string            `json:"s3_error"`          // XML error body S3 returned
+}
+
+// DiagnoseSignature re-derives the canonical request of a presigned URL and
+// reports the first point where it diverges from the one in S3's
+// SignatureDoesNotMatch error, with a hint at the likely cause
+func (h *Handler) DiagnoseSignature(w http.ResponseWriter, r *http.Request) {
+	var req SignatureDiagnosisRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if req.Method == "" || req.URL == "" || req.S3Error == "" {
+		respondWithError(w, http.StatusBadRequest, "method, url and s3_error are required", "")
+		return
+	}
+
+	diagnosis, err := h.service(r).DiagnoseSignature(service.SignatureReport{
+		Method:  req.Method,
+		URL:     req.URL,
+		Headers: req.Headers,
+		S3Error: req.S3Error,
+	})
+	if errors.Is(err, service.ErrInvalidSignatureReport) {
+		respondWithError(w, http.StatusBadRequest, "Invalid signature report", err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to diagnose signature", err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, diagnosis)
+}
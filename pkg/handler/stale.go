@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/webhook"
+)
+
+// Stale backup webhook event types
+const (
+	EventBackupStale     = "backup.stale"
+	EventBackupRecovered = "backup.recovered"
+)
+
+// BackupAge describes how long ago an expected backup was last uploaded
+type BackupAge struct {
+	TenantID        string     `json:"tenant_id,omitempty"`
+	Filename        string     `json:"filename"`
+	MaxAgeHours     int        `json:"max_age_hours"`
+	LatestObjectKey string     `json:"latest_object_key,omitempty"`
+	LatestUploadAt  *time.Time `json:"latest_upload_at,omitempty"` // Nil when never uploaded
+	AgeHours        float64    `json:"age_hours,omitempty"`
+	Stale           bool       `json:"stale"`
+	// Error is set when the check failed; Stale keeps the previous result
+	Error string `json:"error,omitempty"`
+}
+
+// BackupAgeReport is the result of the last stale backup check
+type BackupAgeReport struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	Stale     int         `json:"stale"`
+	Backups   []BackupAge `json:"backups"`
+}
+
+// expectedBackups are the backups expected under one prefix
+type expectedBackups struct {
+	tenantID string
+	svc      *service.S3Service
+	backups  []tenant.ExpectedBackup
+}
+
+// CheckStaleBackups finds the latest upload of every expected backup, under
+// the company prefix and every tenant prefix, and flags those older than
+// their maximum age. Backups that become stale or recover are posted to
+// STALE_BACKUP_WEBHOOK_URL.
+func (h *Handler) CheckStaleBackups(ctx context.Context) error {
+	targets, err := h.expectedBackups()
+	if err != nil {
+		return err
+	}
+
+	h.jobs.mu.RLock()
+	previous := h.jobs.staleBackups
+	h.jobs.mu.RUnlock()
+
+	now := time.Now()
+	report := &BackupAgeReport{CheckedAt: now.UTC(), Backups: []BackupAge{}}
+	stale := make(map[string]bool)
+	var events []webhook.Event
+	for _, target := range targets {
+		for _, expected := range target.backups {
+			age := BackupAge{TenantID: target.tenantID, Filename: expected.Filename, MaxAgeHours: expected.MaxAgeHours}
+			id := target.tenantID + "/" + expected.Filename
+			labels := metrics.Labels{"tenant": target.tenantID, "filename": expected.Filename}
+
+			info, err := target.svc.FindLatest(ctx, expected.Filename)
+			switch {
+			case errors.Is(err, service.ErrObjectNotFound):
+				age.Stale = true
+			case err != nil:
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				age.Error = err.Error()
+				age.Stale = previous[id]
+			default:
+				uploaded := info.LastModified
+				elapsed := now.Sub(uploaded)
+				age.LatestObjectKey = info.Key
+				age.LatestUploadAt = &uploaded
+				age.AgeHours = math.Round(elapsed.Hours()*10) / 10
+				age.Stale = elapsed > time.Duration(expected.MaxAgeHours)*time.Hour
+				h.metrics.SetGauge("backup_age_seconds", labels, math.Floor(elapsed.Seconds()))
+			}
+
+			stale[id] = age.Stale
+			if age.Stale {
+				report.Stale++
+				h.metrics.SetGauge("backup_stale", labels, 1)
+			} else {
+				h.metrics.SetGauge("backup_stale", labels, 0)
+			}
+			if age.Error == "" && age.Stale != previous[id] {
+				eventType := EventBackupRecovered
+				if age.Stale {
+					eventType = EventBackupStale
+					logging.Warnf("Backup %s of tenant %q is stale: no upload in the last %d hours", expected.Filename, target.tenantID, expected.MaxAgeHours)
+				}
+				events = append(events, webhook.Event{Type: eventType, Time: report.CheckedAt, Data: age})
+			}
+			report.Backups = append(report.Backups, age)
+		}
+	}
+
+	h.jobs.mu.Lock()
+	h.jobs.backupAges = report
+	h.jobs.staleBackups = stale
+	h.jobs.mu.Unlock()
+
+	if h.webhook != nil {
+		for _, event := range events {
+			if err := h.webhook.Send(ctx, event); err != nil {
+				logging.Errorf("Failed to send %s webhook: %v", event.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expectedBackups returns EXPECTED_BACKUPS for the company prefix and the
+// expected backups of every tenant
+func (h *Handler) expectedBackups() ([]expectedBackups, error) {
+	company := expectedBackups{svc: h.s3Service}
+	for _, entry := range h.cfg.ExpectedBackups {
+		filename, maxAgeHours, err := config.ParseExpectedBackup(entry)
+		if err != nil {
+			return nil, err
+		}
+		company.backups = append(company.backups, tenant.ExpectedBackup{Filename: filename, MaxAgeHours: maxAgeHours})
+	}
+	targets := []expectedBackups{company}
+
+	if h.tenants != nil {
+		tenants, err := h.tenants.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tenants {
+			targets = append(targets, expectedBackups{tenantID: t.ID, svc: h.tenantService(&t), backups: t.ExpectedBackups})
+		}
+	}
+	return targets, nil
+}
+
+// GetBackupAges returns the last stale backup check for the caller's
+// prefix: the tenant's expected backups, or EXPECTED_BACKUPS for callers
+// that aren't tenants. With stale=true only overdue backups are listed.
+func (h *Handler) GetBackupAges(w http.ResponseWriter, r *http.Request) {
+	h.jobs.mu.RLock()
+	report := h.jobs.backupAges
+	h.jobs.mu.RUnlock()
+
+	if report == nil {
+		respondWithError(w, http.StatusNotFound, "Backup ages have not been checked yet", "")
+		return
+	}
+
+	var tenantID string
+	if t, ok := TenantFromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	onlyStale := r.URL.Query().Get("stale") == "true"
+
+	scoped := BackupAgeReport{CheckedAt: report.CheckedAt, Backups: []BackupAge{}}
+	for _, age := range report.Backups {
+		if age.TenantID != tenantID || (onlyStale && !age.Stale) {
+			continue
+		}
+		if age.Stale {
+			scoped.Stale++
+		}
+		scoped.Backups = append(scoped.Backups, age)
+	}
+
+	respondWithConditionalJSON(w, r, scoped, scoped.CheckedAt)
+}
@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/tenant"
+)
+
+// Tenant limit error codes
+const (
+	CodeQuotaExceeded         = "QUOTA_EXCEEDED"
+	CodeContentTypeNotAllowed = "CONTENT_TYPE_NOT_ALLOWED"
+	CodeWindowClosed          = "WINDOW_CLOSED"
+)
+
+// Tenant usage for quota checks is collected at most every
+// tenantUsageCacheTTL, bounded by tenantUsageCollectTimeout
+const (
+	tenantUsageCacheTTL       = 5 * time.Minute
+	tenantUsageCollectTimeout = 30 * time.Second
+)
+
+// TenantRequest represents the request body for creating or updating a
+// tenant. TenantID is only read on creation.
+type TenantRequest struct {
+	TenantID            string                  `json:"tenant_id,omitempty"`
+	Prefix              string                  `json:"prefix"`
+	QuotaBytes          int64                   `json:"quota_bytes,omitempty"`
+	AllowedContentTypes []string                `json:"allowed_content_types,omitempty"`
+	MinRetentionHours   int                     `json:"min_retention_hours,omitempty"`
+	UploadWindows       []string                `json:"upload_windows,omitempty"`
+	UploadTimezone      string                  `json:"upload_timezone,omitempty"`
+	ExpectedBackups     []tenant.ExpectedBackup `json:"expected_backups,omitempty"`
+	RoleARN             string                  `json:"role_arn,omitempty"`
+	RoleExternalID      string                  `json:"role_external_id,omitempty"`
+}
+
+// TenantResponse describes a tenant. APIKey is only returned on creation.
+type TenantResponse struct {
+	TenantID            string                  `json:"tenant_id"`
+	Prefix              string                  `json:"prefix"`
+	QuotaBytes          int64                   `json:"quota_bytes,omitempty"`
+	AllowedContentTypes []string                `json:"allowed_content_types,omitempty"`
+	MinRetentionHours   int                     `json:"min_retention_hours,omitempty"`
+	UploadWindows       []string                `json:"upload_windows,omitempty"`
+	UploadTimezone      string                  `json:"upload_timezone,omitempty"`
+	ExpectedBackups     []tenant.ExpectedBackup `json:"expected_backups,omitempty"`
+	RoleARN             string                  `json:"role_arn,omitempty"`
+	RoleExternalID      string                  `json:"role_external_id,omitempty"`
+	APIKey              string                  `json:"api_key,omitempty"`
+	CreatedAt           time.Time               `json:"created_at"`
+	UpdatedAt           time.Time               `json:"updated_at"`
+}
+
+// TenantUsageResponse reports the bytes stored under a tenant's prefix
+// against its quota
+type TenantUsageResponse struct {
+	TenantID   string `json:"tenant_id"`
+	QuotaBytes int64  `json:"quota_bytes,omitempty"`
+	UsedBytes  int64  `json:"used_bytes"`
+}
+
+// tenantUsage caches the bytes stored under each tenant's prefix for quota
+// checks, since collecting it lists the whole prefix
+type tenantUsage struct {
+	mu      sync.Mutex
+	entries map[string]tenantUsageEntry
+}
+
+type tenantUsageEntry struct {
+	bytes       int64
+	collectedAt time.Time
+}
+
+// WithTenants serves requests authenticated with a tenant's API key under the
+// tenant's prefix and enables the tenant admin endpoints
+func WithTenants(store tenant.Store) Option {
+	return func(h *Handler) {
+		h.tenants = store
+	}
+}
+
+// tenantKey is the context key for the request's tenant
+type tenantKey struct{}
+
+// withTenant returns a copy of ctx carrying t
+func withTenant(ctx context.Context, t *tenant.Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext returns the tenant the request was authenticated as, if
+// any
+func TenantFromContext(ctx context.Context) (*tenant.Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(*tenant.Tenant)
+	return t, ok
+}
+
+// service returns the S3 service scoped to the request's tenant prefix, or
+// to COMPANY_PREFIX for callers that aren't tenants
+func (h *Handler) service(r *http.Request) *service.S3Service {
+	if t, ok := TenantFromContext(r.Context()); ok {
+		return h.tenantService(t)
+	}
+	return h.s3Service
+}
+
+// tenantService returns the service scoped to the tenant's prefix, calling
+// S3 and signing with the tenant's role when it has one
+func (h *Handler) tenantService(t *tenant.Tenant) *service.S3Service {
+	return h.s3Service.ForPrefix(t.Prefix).ForRole(t.RoleARN, t.RoleExternalID)
+}
+
+// checkTenantLimits enforces the tenant's upload windows, content type
+// allowlist and quota on an upload of size bytes (0 if undeclared),
+// responding with 403 when exceeded. It reports whether the handler may
+// continue.
+func (h *Handler) checkTenantLimits(w http.ResponseWriter, r *http.Request, contentType string, size int64) bool {
+	t, ok := TenantFromContext(r.Context())
+	if !ok {
+		return true
+	}
+
+	if !checkUploadWindow(w, t) {
+		return false
+	}
+
+	if len(t.AllowedContentTypes) > 0 && !policy.MatchesContentType(t.AllowedContentTypes, contentType) {
+		respondWithCodedError(w, http.StatusForbidden, CodeContentTypeNotAllowed, "Content type not allowed",
+			fmt.Sprintf("tenant %s accepts %v", t.ID, t.AllowedContentTypes))
+		return false
+	}
+
+	if t.QuotaBytes > 0 {
+		used, err := h.tenantUsedBytes(r.Context(), t)
+		if err != nil {
+			h.respondWithS3Error(w, "Failed to check tenant quota", err)
+			return false
+		}
+		if used >= t.QuotaBytes || size > t.QuotaBytes-used {
+			respondWithCodedError(w, http.StatusForbidden, CodeQuotaExceeded, "Storage quota exceeded",
+				fmt.Sprintf("tenant %s uses %d of %d bytes", t.ID, used, t.QuotaBytes))
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkUploadWindow responds with 403 WINDOW_CLOSED outside the tenant's
+// upload windows, with Retry-After set to when the next one opens
+func checkUploadWindow(w http.ResponseWriter, t *tenant.Tenant) bool {
+	schedule, err := t.UploadSchedule()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Invalid tenant upload windows", err.Error())
+		return false
+	}
+	now := time.Now()
+	if schedule.Open(now) {
+		return true
+	}
+
+	response := ErrorResponse{
+		Error:   "Upload window closed",
+		Message: fmt.Sprintf("tenant %s has no upcoming upload window", t.ID),
+		Code:    CodeWindowClosed,
+	}
+	if next, ok := schedule.NextOpen(now); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(next.Sub(now).Seconds()))))
+		response.Message = fmt.Sprintf("tenant %s accepts uploads during %v; the next window opens at %s",
+			t.ID, t.UploadWindows, next.UTC().Format(time.RFC3339))
+		response.Retryable = true
+	}
+	respondWithJSON(w, http.StatusForbidden, response)
+	return false
+}
+
+// tenantUsedBytes returns the bytes stored under the tenant's prefix,
+// collected at most every tenantUsageCacheTTL
+func (h *Handler) tenantUsedBytes(ctx context.Context, t *tenant.Tenant) (int64, error) {
+	h.tenantUsage.mu.Lock()
+	entry, ok := h.tenantUsage.entries[t.ID]
+	h.tenantUsage.mu.Unlock()
+	if ok && time.Since(entry.collectedAt) < tenantUsageCacheTTL {
+		return entry.bytes, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tenantUsageCollectTimeout)
+	defer cancel()
+	report, err := h.tenantService(t).CollectPrefixUsage(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	h.tenantUsage.mu.Lock()
+	if h.tenantUsage.entries == nil {
+		h.tenantUsage.entries = make(map[string]tenantUsageEntry)
+	}
+	h.tenantUsage.entries[t.ID] = tenantUsageEntry{bytes: report.TotalBytes, collectedAt: time.Now()}
+	h.tenantUsage.mu.Unlock()
+
+	return report.TotalBytes, nil
+}
+
+// ListTenants returns all tenants
+func (h *Handler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.tenants.List()
+	if err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+
+	response := make([]TenantResponse, 0, len(tenants))
+	for _, t := range tenants {
+		response = append(response, tenantResponse(t))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetTenant returns one tenant
+func (h *Handler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	t, err := h.tenants.Get(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tenantResponse(t))
+}
+
+// GetTenantUsage returns the bytes stored under a tenant's prefix as counted
+// for its quota, up to tenantUsageCacheTTL old
+func (h *Handler) GetTenantUsage(w http.ResponseWriter, r *http.Request) {
+	t, err := h.tenants.Get(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+
+	used, err := h.tenantUsedBytes(r.Context(), &t)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to collect tenant usage", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, TenantUsageResponse{TenantID: t.ID, QuotaBytes: t.QuotaBytes, UsedBytes: used})
+}
+
+// CreateTenant registers a tenant and returns its generated API key, which
+// is not stored and can't be retrieved later
+func (h *Handler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req TenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	key, hash := tenant.NewAPIKey()
+	now := time.Now().UTC()
+	t := tenant.Tenant{
+		ID:                  req.TenantID,
+		Prefix:              req.Prefix,
+		QuotaBytes:          req.QuotaBytes,
+		AllowedContentTypes: req.AllowedContentTypes,
+		MinRetentionHours:   req.MinRetentionHours,
+		UploadWindows:       req.UploadWindows,
+		UploadTimezone:      req.UploadTimezone,
+		ExpectedBackups:     req.ExpectedBackups,
+		RoleARN:             req.RoleARN,
+		RoleExternalID:      req.RoleExternalID,
+		APIKeyHash:          hash,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	if err := t.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tenant", err.Error())
+		return
+	}
+
+	if err := h.tenants.Create(t); err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+
+	response := tenantResponse(t)
+	response.APIKey = key
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// UpdateTenant replaces a tenant's prefix, quota, content types, retention,
+// upload windows and expected backups, keeping its API key
+func (h *Handler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	var req TenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	t, err := h.tenants.Get(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+
+	t.Prefix = req.Prefix
+	t.QuotaBytes = req.QuotaBytes
+	t.AllowedContentTypes = req.AllowedContentTypes
+	t.MinRetentionHours = req.MinRetentionHours
+	t.UploadWindows = req.UploadWindows
+	t.UploadTimezone = req.UploadTimezone
+	t.ExpectedBackups = req.ExpectedBackups
+	t.RoleARN = req.RoleARN
+	t.RoleExternalID = req.RoleExternalID
+	t.UpdatedAt = time.Now().UTC()
+	if err := t.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tenant", err.Error())
+		return
+	}
+
+	if err := h.tenants.Update(t); err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+	h.forgetTenantUsage(t.ID)
+
+	respondWithJSON(w, http.StatusOK, tenantResponse(t))
+}
+
+// DeleteTenant removes a tenant, revoking its API key. Its objects are kept.
+func (h *Handler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.tenants.Delete(id); err != nil {
+		respondWithTenantError(w, err)
+		return
+	}
+	h.forgetTenantUsage(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forgetTenantUsage drops the cached usage of a tenant
+func (h *Handler) forgetTenantUsage(id string) {
+	h.tenantUsage.mu.Lock()
+	delete(h.tenantUsage.entries, id)
+	h.tenantUsage.mu.Unlock()
+}
+
+// tenantResponse describes t without its API key hash
+func tenantResponse(t tenant.Tenant) TenantResponse {
+	return TenantResponse{
+		TenantID:            t.ID,
+		Prefix:              t.Prefix,
+		QuotaBytes:          t.QuotaBytes,
+		AllowedContentTypes: t.AllowedContentTypes,
+		MinRetentionHours:   t.MinRetentionHours,
+		UploadWindows:       t.UploadWindows,
+		UploadTimezone:      t.UploadTimezone,
+		ExpectedBackups:     t.ExpectedBackups,
+		RoleARN:             t.RoleARN,
+		RoleExternalID:      t.RoleExternalID,
+		CreatedAt:           t.CreatedAt,
+		UpdatedAt:           t.UpdatedAt,
+	}
+}
+
+// respondWithTenantError maps tenant store errors to HTTP responses
+func respondWithTenantError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tenant.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Tenant not found", "")
+	case errors.Is(err, tenant.ErrExists):
+		respondWithError(w, http.StatusConflict, "Tenant already exists", "")
+	case errors.Is(err, tenant.ErrPrefixConflict):
+		respondWithError(w, http.StatusConflict, "Prefix is already in use", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Tenant store error", err.Error())
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// traceParentPattern matches a W3C trace context traceparent header
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// tracingMiddleware passes the client's X-Request-ID and traceparent headers
+// on to the S3 calls made for the request. Malformed values are dropped.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c service.Correlation
+		if requestID := r.Header.Get(requestIDHeader); requestIDPattern.MatchString(requestID) {
+			c.RequestID = requestID
+		}
+		if traceParent := r.Header.Get("traceparent"); traceParentPattern.MatchString(traceParent) {
+			c.TraceParent = traceParent
+		}
+		if c != (service.Correlation{}) {
+			r = r.WithContext(service.WithCorrelation(r.Context(), c))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
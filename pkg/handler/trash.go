@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// TrashRequest represents the request body for soft-deleting an object
+type TrashRequest struct {
+	ObjectKey string `json:"object_key"`
+}
+
+// RestoreRequest represents the request body for restoring a trashed object
+type RestoreRequest struct {
+	TrashKey  string `json:"trash_key"`
+	Overwrite bool   `json:"overwrite,omitempty"` // Replace an object written to the original key since
+}
+
+// RestoreResponse represents a restored object
+type RestoreResponse struct {
+	TrashKey string              `json:"trash_key"`
+	Object   *service.ObjectInfo `json:"object"`
+}
+
+// TrashObject soft-deletes an object by moving it to the trash
+func (h *Handler) TrashObject(w http.ResponseWriter, r *http.Request) {
+	var req TrashRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.ObjectKey == "" {
+		respondWithError(w, http.StatusBadRequest, "object_key is required", "")
+		return
+	}
+	svc := h.service(r)
+	if !svc.OwnsKey(req.ObjectKey) {
+		respondWithError(w, http.StatusForbidden, "object_key is outside the company prefix", "")
+		return
+	}
+	if svc.IsTrashKey(req.ObjectKey) {
+		respondWithError(w, http.StatusBadRequest, "Object is already in the trash", req.ObjectKey)
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationDelete, ObjectKey: req.ObjectKey}) {
+		return
+	}
+
+	info, err := svc.HeadObject(r.Context(), req.ObjectKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Object not found", req.ObjectKey)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to read object", err)
+		return
+	}
+	if !h.checkRetention(w, r, info) {
+		return
+	}
+
+	trashed, err := svc.TrashObject(r.Context(), info)
+	if err != nil {
+		var keyErr *service.KeyTooLongError
+		switch {
+		case errors.As(err, &keyErr):
+			respondWithKeyError(w, err)
+		case errors.Is(err, service.ErrObjectTooLarge):
+			respondWithError(w, http.StatusUnprocessableEntity, "Object is too large to move to the trash", err.Error())
+		default:
+			h.respondWithS3Error(w, "Failed to move object to the trash", err)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, trashed)
+}
+
+// ListTrash lists trashed objects a page at a time. Pass the returned
+// next_token as continuation_token for the next page. With
+// Accept: application/x-ndjson every page is streamed, one object per line.
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	maxKeys, ok := parseMaxKeys(w, r)
+	if !ok {
+		return
+	}
+	if wantsNDJSON(r) {
+		h.streamTrash(w, r, r.URL.Query().Get("continuation_token"), maxKeys)
+		return
+	}
+
+	page, err := h.service(r).ListTrash(r.Context(), r.URL.Query().Get("continuation_token"), maxKeys)
+	if err != nil {
+		h.respondWithS3Error(w, "Failed to list trash", err)
+		return
+	}
+
+	respondWithConditionalJSON(w, r, page, time.Time{})
+}
+
+// streamTrash writes every page of the trash listing as NDJSON, starting at
+// token
+func (h *Handler) streamTrash(w http.ResponseWriter, r *http.Request, token string, maxKeys int32) {
+	svc := h.service(r)
+	stream := h.newNDJSONStream(w)
+	for {
+		page, err := svc.ListTrash(r.Context(), token, maxKeys)
+		if err != nil {
+			stream.fail("Failed to list trash", err)
+			return
+		}
+		for _, trashed := range page.Objects {
+			if stream.write(trashed) != nil {
+				return
+			}
+		}
+		stream.flush()
+
+		if page.NextToken == "" || r.Context().Err() != nil {
+			return
+		}
+		token = page.NextToken
+	}
+}
+
+// RestoreObject moves a trashed object back to its original key
+func (h *Handler) RestoreObject(w http.ResponseWriter, r *http.Request) {
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	svc := h.service(r)
+	originalKey, ok := svc.TrashOriginalKey(req.TrashKey)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "trash_key must be a key in the trash", req.TrashKey)
+		return
+	}
+	if !h.authorize(w, r, policy.Request{Operation: OperationUpload, ObjectKey: originalKey}) {
+		return
+	}
+
+	trashed, err := svc.HeadObject(r.Context(), req.TrashKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, "Trashed object not found", req.TrashKey)
+			return
+		}
+		h.respondWithS3Error(w, "Failed to read trashed object", err)
+		return
+	}
+
+	if !req.Overwrite {
+		_, err := svc.HeadObject(r.Context(), originalKey)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "Original object already exists", "set overwrite to replace it")
+			return
+		}
+		if !errors.Is(err, service.ErrObjectNotFound) {
+			h.respondWithS3Error(w, "Failed to check original object", err)
+			return
+		}
+	}
+
+	restored, err := svc.MoveObject(r.Context(), trashed, originalKey)
+	if err != nil {
+		if errors.Is(err, service.ErrObjectTooLarge) {
+			respondWithError(w, http.StatusUnprocessableEntity, "Object is too large to restore", err.Error())
+			return
+		}
+		h.respondWithS3Error(w, "Failed to restore object", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, RestoreResponse{TrashKey: req.TrashKey, Object: restored})
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiPathPrefix is where the embedded web UI is served
+const uiPathPrefix = "/ui/"
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler serves the embedded web UI. Pages only load scripts and styles
+// from the service and can't be framed.
+func uiHandler() http.Handler {
+	files, _ := fs.Sub(uiFiles, "ui") // ui is embedded above
+	fileServer := http.StripPrefix(uiPathPrefix, http.FileServer(http.FS(files)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(w, r)
+	})
+}
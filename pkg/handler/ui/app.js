@@ -0,0 +1,214 @@
+'use strict';
+
+// Keys are kept for the browser tab only
+const credentials = {
+  get apiKey() { return sessionStorage.getItem('apiKey') || ''; },
+  get adminKey() { return sessionStorage.getItem('adminKey') || ''; },
+};
+
+// el creates an element; strings become text, never HTML
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  Object.entries(attrs || {}).forEach(([name, value]) => {
+    if (name.startsWith('on')) {
+      node.addEventListener(name.slice(2), value);
+    } else {
+      node.setAttribute(name, value);
+    }
+  });
+  children.forEach((child) => node.append(child instanceof Node ? child : String(child ?? '')));
+  return node;
+}
+
+function status(message, isError) {
+  const node = document.getElementById('status');
+  node.textContent = message || '';
+  node.className = isError ? 'error' : '';
+}
+
+function formatSize(bytes) {
+  const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
+  let i = 0;
+  for (; bytes >= 1024 && i < units.length - 1; i++) bytes /= 1024;
+  return `${i ? bytes.toFixed(1) : bytes} ${units[i]}`;
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : '';
+}
+
+// request calls the service with the API key, or the admin key for /admin
+// paths, and returns the decoded JSON body. Tokens (JWTs) are sent as bearer
+// tokens, anything else as X-API-Key.
+async function request(method, path, body) {
+  const headers = {};
+  if (path.startsWith('/admin/')) {
+    headers['X-Admin-Key'] = credentials.adminKey;
+  } else if (credentials.apiKey.split('.').length === 3) {
+    headers.Authorization = `Bearer ${credentials.apiKey}`;
+  } else if (credentials.apiKey) {
+    headers['X-API-Key'] = credentials.apiKey;
+  }
+  if (body !== undefined) headers['Content-Type'] = 'application/json';
+
+  const response = await fetch(path, { method, headers, body: body === undefined ? undefined : JSON.stringify(body) });
+  const data = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    const error = new Error([data.error || response.statusText, data.message].filter(Boolean).join(': '));
+    error.status = response.status;
+    throw error;
+  }
+  return data;
+}
+
+// run reports the outcome of an action in the status line
+async function run(action) {
+  status('Loading…');
+  try {
+    await action();
+    status('');
+  } catch (err) {
+    status(err.message, true);
+  }
+}
+
+// Objects
+
+let browseToken = '';
+
+async function browse(prefix, append) {
+  const query = new URLSearchParams({ prefix, max_keys: '200' });
+  if (append && browseToken) query.set('continuation_token', browseToken);
+  const listing = await request('GET', `/api/v1/object/browse?${query}`);
+
+  const rows = document.getElementById('browse-rows');
+  if (!append) rows.replaceChildren();
+  document.querySelector('#browse [name=prefix]').value = listing.prefix;
+  listing.folders.forEach((folder) => {
+    rows.append(el('tr', {},
+      el('td', {}, el('a', { href: '#', onclick: (e) => { e.preventDefault(); run(() => browse(folder)); } }, folder)),
+      el('td', {}), el('td', {}), el('td', {})));
+  });
+  listing.objects.forEach((object) => {
+    rows.append(el('tr', {},
+      el('td', {}, object.object_key),
+      el('td', {}, formatSize(object.size)),
+      el('td', {}, formatTime(object.last_modified)),
+      el('td', {}, el('button', { type: 'button', onclick: () => run(() => downloadURL(object.object_key)) }, 'Download URL'))));
+  });
+
+  browseToken = listing.next_token || '';
+  document.getElementById('browse-more').hidden = !browseToken;
+}
+
+async function downloadURL(objectKey) {
+  const issued = await request('POST', '/api/v2/presigned-urls', { operation: 'download', object_key: objectKey });
+  showTab('issue');
+  document.getElementById('issue-result').replaceChildren(
+    el('a', { href: issued.url, target: '_blank', rel: 'noopener noreferrer' }, 'Open'), '\n\n',
+    JSON.stringify(issued, null, 2));
+}
+
+// Issue URL
+
+async function issue(form) {
+  const body = { operation: form.operation.value, dry_run: form.dry_run.checked };
+  ['filename', 'object_key', 'content_type'].forEach((name) => {
+    if (form[name].value) body[name] = form[name].value;
+  });
+  const issued = await request('POST', '/api/v2/presigned-urls', body);
+  document.getElementById('issue-result').textContent = JSON.stringify(issued, null, 2);
+}
+
+// Audit
+
+async function audit(form) {
+  const query = new URLSearchParams({ max_keys: '200' });
+  ['prefix', 'subject', 'method'].forEach((name) => {
+    if (form[name].value) query.set(name, form[name].value);
+  });
+  const issued = await request('GET', `/admin/v1/issued-urls?${query}`);
+  document.getElementById('audit-rows').replaceChildren(...issued.urls.map((url) => el('tr', {},
+    el('td', {}, formatTime(url.issued_at)),
+    el('td', {}, url.method),
+    el('td', {}, url.object_key),
+    el('td', {}, url.subject),
+    el('td', {}, formatTime(url.expires_at)),
+    el('td', {}, url.revoked_at ? `${formatTime(url.revoked_at)} ${url.reason || ''}` : ''))));
+
+  const rows = document.getElementById('anomaly-rows');
+  try {
+    const recent = await request('GET', '/admin/v1/anomalies?limit=100');
+    rows.replaceChildren(...recent.anomalies.map((a) => el('tr', {},
+      el('td', {}, formatTime(a.time)),
+      el('td', {}, a.type),
+      el('td', {}, a.tenant_id),
+      el('td', {}, a.subject),
+      el('td', {}, a.client_ip),
+      el('td', {}, a.detail))));
+  } catch (err) {
+    if (err.status !== 404) throw err;
+    rows.replaceChildren(el('tr', {}, el('td', { colspan: '6' }, 'Anomaly detection is disabled')));
+  }
+}
+
+// Tenants
+
+async function loadTenants() {
+  const tenants = await request('GET', '/admin/v1/tenants');
+  document.getElementById('tenant-rows').replaceChildren(...tenants.map((t) => {
+    const used = el('td', {});
+    return el('tr', {},
+      el('td', {}, t.tenant_id),
+      el('td', {}, t.prefix),
+      el('td', {}, t.quota_bytes ? formatSize(t.quota_bytes) : 'unlimited'),
+      used,
+      el('td', {}, el('button', { type: 'button', onclick: () => run(() => tenantUsage(t.tenant_id, used)) }, 'Usage')));
+  }));
+}
+
+async function tenantUsage(id, cell) {
+  const usage = await request('GET', `/admin/v1/tenants/${encodeURIComponent(id)}/usage`);
+  cell.replaceChildren(formatSize(usage.used_bytes));
+  if (usage.quota_bytes) {
+    const percent = Math.min(100, Math.round((100 * usage.used_bytes) / usage.quota_bytes));
+    cell.append(' ', el('meter', { min: '0', max: '100', value: String(percent) }), ` ${percent}%`);
+  }
+}
+
+// Navigation
+
+function showTab(name) {
+  document.querySelectorAll('nav button').forEach((b) => b.classList.toggle('active', b.dataset.tab === name));
+  document.querySelectorAll('main section').forEach((s) => { s.hidden = s.id !== name; });
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+  const keys = document.getElementById('credentials');
+  keys.apiKey.value = credentials.apiKey;
+  keys.adminKey.value = credentials.adminKey;
+  keys.addEventListener('submit', (e) => {
+    e.preventDefault();
+    sessionStorage.setItem('apiKey', keys.apiKey.value);
+    sessionStorage.setItem('adminKey', keys.adminKey.value);
+    status('Keys saved for this tab');
+  });
+
+  document.querySelectorAll('nav button').forEach((b) => b.addEventListener('click', () => showTab(b.dataset.tab)));
+
+  const browseForm = document.getElementById('browse');
+  browseForm.addEventListener('submit', (e) => { e.preventDefault(); run(() => browse(browseForm.prefix.value)); });
+  document.getElementById('browse-up').addEventListener('click', () => {
+    const parent = browseForm.prefix.value.replace(/[^/]*\/?$/, '');
+    run(() => browse(parent));
+  });
+  document.getElementById('browse-more').addEventListener('click', () => run(() => browse(browseForm.prefix.value, true)));
+
+  const issueForm = document.getElementById('issue-form');
+  issueForm.addEventListener('submit', (e) => { e.preventDefault(); run(() => issue(issueForm)); });
+
+  const auditForm = document.getElementById('audit-form');
+  auditForm.addEventListener('submit', (e) => { e.preventDefault(); run(() => audit(auditForm)); });
+
+  document.getElementById('tenants-load').addEventListener('click', () => run(loadTenants));
+});
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/accesslog"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/urlregistry"
+)
+
+// URLUsageResponse lists logged requests made with issued presigned URLs
+type URLUsageResponse struct {
+	Usage      []accesslog.Usage `json:"usage"`
+	IngestedAt time.Time         `json:"ingested_at,omitzero"` // When the latest access log was read
+}
+
+// IngestAccessLogs reads the access log objects delivered since the last run
+// and stores the requests made with URLs issued here. Logs older than the
+// retention are skipped, so the first run doesn't replay the whole history.
+// A log that fails to read is retried on the next run.
+func (h *Handler) IngestAccessLogs(ctx context.Context) error {
+	cutoff := time.Now().Add(-time.Duration(h.cfg.AccessLogRetentionHours) * time.Hour)
+	for {
+		objects, more, err := h.s3Service.ListAccessLogs(ctx, h.cfg.AccessLogBucket, h.cfg.AccessLogPrefix, h.usage.Cursor())
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range objects {
+			if obj.LastModified.After(cutoff) {
+				data, err := h.s3Service.ReadAccessLog(ctx, h.cfg.AccessLogBucket, obj.Key)
+				if err != nil {
+					return err
+				}
+				h.usage.Add(h.matchAccessLog(obj.Key, data)...)
+				h.metrics.IncCounter("access_log_objects_total", nil)
+			}
+			h.usage.Advance(obj.Key)
+		}
+
+		if !more || len(objects) == 0 {
+			return nil
+		}
+	}
+}
+
+// matchAccessLog returns the requests of an access log made with URLs issued
+// here
+func (h *Handler) matchAccessLog(key string, data []byte) []accesslog.Usage {
+	var usages []accesslog.Usage
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		record, err := accesslog.ParseLine(line)
+		if err != nil {
+			logging.Debugf("Skipping access log record of %s: %v", key, err)
+			h.metrics.IncCounter("access_log_requests_total", metrics.Labels{"result": "malformed"})
+			continue
+		}
+		signature := record.Signature()
+		if signature == "" {
+			continue // Not made with a presigned URL
+		}
+		entry, err := h.issued.LookupSignature(signature)
+		if err != nil {
+			h.metrics.IncCounter("access_log_requests_total", metrics.Labels{"result": "unknown"})
+			continue
+		}
+
+		h.metrics.IncCounter("access_log_requests_total", metrics.Labels{"result": "matched"})
+		usages = append(usages, accesslog.Usage{
+			Time:       record.Time.UTC(),
+			RemoteIP:   record.RemoteIP,
+			Operation:  record.Operation,
+			HTTPStatus: record.HTTPStatus,
+			ErrorCode:  record.ErrorCode,
+			BytesSent:  record.BytesSent,
+			UserAgent:  record.UserAgent,
+			RequestID:  record.RequestID,
+			URL:        entry,
+			Signature:  signature,
+		})
+	}
+	return usages
+}
+
+// GetURLUsage returns the logged requests made with URLs issued for the
+// caller's prefix, most recent first, filtered by the url, object_key,
+// subject, upload_id and since (RFC 3339) query parameters. S3 delivers
+// access logs hours after the requests, so recent usage may be missing.
+func (h *Handler) GetURLUsage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	maxKeys, ok := parseMaxKeys(w, r)
+	if !ok {
+		return
+	}
+
+	q := accesslog.Query{
+		ObjectKey: query.Get("object_key"),
+		Subject:   query.Get("subject"),
+		UploadID:  query.Get("upload_id"),
+		Owns:      h.service(r).OwnsKey,
+		Limit:     int(maxKeys),
+	}
+	if rawURL := query.Get("url"); rawURL != "" {
+		signature, err := urlregistry.Signature(rawURL)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid presigned URL", err.Error())
+			return
+		}
+		q.Signature = signature
+	}
+	if since := query.Get("since"); since != "" {
+		var err error
+		if q.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			respondWithError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp", since)
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, URLUsageResponse{
+		Usage:      h.usage.Find(q),
+		IngestedAt: h.usage.IngestedAt(),
+	})
+}
@@ -0,0 +1,482 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Operations accepted by the v2 presign endpoint
+const (
+	OperationUpload   = "upload"
+	OperationDownload = "download"
+	OperationDelete   = "delete"
+)
+
+// Error codes returned by the v2 API
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeValidationFailed    = "VALIDATION_FAILED"
+	CodeOperationNotAllowed = "OPERATION_NOT_ALLOWED"
+	CodeForbiddenKey        = "FORBIDDEN_KEY"
+	CodeSigningFailed       = "SIGNING_FAILED"
+	CodeKeyTooLong          = "KEY_TOO_LONG"
+	CodeSignedHeadersPolicy = "SIGNED_HEADERS_POLICY"
+)
+
+const (
+	// maxFilenameLength bounds the client-supplied filename
+	maxFilenameLength = 255
+	// maxMetadataBytes is S3's limit for user-defined metadata
+	maxMetadataBytes = 2048
+	// maxSinglePutBytes is S3's limit for a single PUT upload
+	maxSinglePutBytes = 5 << 30
+)
+
+// metadataKeyPattern restricts metadata keys to characters valid in HTTP header names
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// PresignV2Request represents the request body for the v2 presign endpoint
+type PresignV2Request struct {
+	Operation      string              `json:"operation"`
+	Filename       string              `json:"filename,omitempty"`   // upload only
+	ObjectKey      string              `json:"object_key,omitempty"` // download and delete only
+	ContentType    string              `json:"content_type,omitempty"`
+	ContentLength  int64               `json:"content_length,omitempty"` // upload only, signed when set
+	Metadata       map[string]string   `json:"metadata,omitempty"`
+	ObjectLock     *service.ObjectLock `json:"object_lock,omitempty"`     // upload only, signed when set
+	DryRun         bool                `json:"dry_run,omitempty"`         // Validate without issuing a URL
+	ChecksumSHA256 string              `json:"checksum_sha256,omitempty"` // upload only, hex or base64, signed when set
+	ContentMD5     string              `json:"content_md5,omitempty"`     // upload only, hex or base64, signed when set
+	OnDuplicate    string              `json:"on_duplicate,omitempty"`    // upload only: allow, reject or existing
+	// upload: gzip, signed when set; download: gzip or identity, overrides
+	// the response Content-Encoding
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	// upload and download: start of the URL's validity window
+	NotBefore time.Time `json:"not_before,omitzero"`
+	// upload only: folder under the timestamped prefix, e.g. a host name
+	Subpath string `json:"subpath,omitempty"`
+	// upload only: false signs If-None-Match: * so S3 refuses to overwrite
+	// an existing key
+	Overwrite *bool `json:"overwrite,omitempty"`
+	// upload and download: MD5 of an SSE-C key in hex or base64, signed with
+	// the SSE-C algorithm; the request must send the key
+	SSECustomerKeyMD5 string `json:"sse_customer_key_md5,omitempty"`
+}
+
+// PresignV2Response represents the response of the v2 presign endpoint
+type PresignV2Response struct {
+	Operation      string                `json:"operation"`
+	URL            string                `json:"url,omitempty"` // Omitted on dry runs and existing objects
+	DryRun         bool                  `json:"dry_run,omitempty"`
+	Method         string                `json:"method,omitempty"`
+	ObjectKey      string                `json:"object_key"`
+	NotBefore      time.Time             `json:"not_before,omitzero"`
+	ExpiresAt      time.Time             `json:"expires_at,omitzero"`
+	Headers        map[string]string     `json:"headers,omitempty"`
+	ExistingObject *service.Duplicate    `json:"existing_object,omitempty"` // on_duplicate=existing match
+	UploadID       string                `json:"upload_id,omitempty"`       // Correlation ID of an issued upload URL
+	Debug          *service.SigningDebug `json:"debug,omitempty"`           // Only with X-Signer-Debug
+	// Header each metadata key of an upload is signed as
+	RequiredHeaders map[string]string `json:"required_headers,omitempty"`
+	// The catalog holds a confirmed object with the upload's checksum
+	AlreadyExists bool `json:"already_exists,omitempty"`
+}
+
+// PresignV2 issues a presigned URL for an explicit operation with strict
+// validation. Uploads must declare a content type, which is signed.
+func (h *Handler) PresignV2(w http.ResponseWriter, r *http.Request) {
+	var req PresignV2Request
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithCodedError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if problems := validatePresignV2(&req); len(problems) > 0 {
+		respondWithCodedError(w, http.StatusBadRequest, CodeValidationFailed, "Request validation failed", strings.Join(problems, "; "))
+		return
+	}
+
+	if !h.operationAllowed(req.Operation) {
+		respondWithCodedError(w, http.StatusForbidden, CodeOperationNotAllowed, "Operation not allowed", req.Operation)
+		return
+	}
+	if req.Operation != OperationDownload && !h.checkWritable(w) {
+		return
+	}
+	if !h.allows(r, req.Operation) {
+		h.respondWithInsufficientScope(w, r, req.Operation)
+		return
+	}
+	// A presigned DELETE would bypass the trash
+	if req.Operation == OperationDelete && h.cfg.SoftDelete {
+		respondWithCodedError(w, http.StatusForbidden, CodeOperationNotAllowed, "Deletes go through the trash", "use POST /api/v1/trash")
+		return
+	}
+
+	svc := h.service(r)
+	objectKey := req.ObjectKey
+	var uploadID string
+	if req.Operation == OperationUpload {
+		var err error
+		if objectKey, err = svc.UploadKeyIn(req.Subpath, req.Filename); err != nil {
+			respondWithKeyError(w, err)
+			return
+		}
+	} else if !svc.OwnsKey(objectKey) {
+		respondWithCodedError(w, http.StatusForbidden, CodeForbiddenKey, "object_key is outside the company prefix", "")
+		return
+	}
+	if !h.authorize(w, r, policy.Request{
+		Operation:   req.Operation,
+		ObjectKey:   objectKey,
+		ContentType: req.ContentType,
+		Size:        req.ContentLength,
+	}) {
+		return
+	}
+	if req.Operation == OperationUpload {
+		if !h.checkMetadataSchema(w, req.Metadata) {
+			return
+		}
+		uploadID = svc.NewUploadID()
+		req.Metadata = h.injectMetadata(w, r, req.Metadata, uploadID)
+		if err := svc.CheckSignedHeaders(svc.SignedUploadHeaders(uploadOptionsV2(&req))); err != nil {
+			respondWithSignedHeadersError(w, err)
+			return
+		}
+		if !h.checkTenantLimits(w, r, req.ContentType, req.ContentLength) {
+			return
+		}
+	}
+	if req.Operation == OperationDelete && !h.checkRetentionKey(w, r, objectKey) {
+		return
+	}
+	if req.Operation == OperationUpload {
+		existing, ok := h.catalogDuplicate(w, r, req.OnDuplicate, req.ChecksumSHA256)
+		if !ok {
+			return
+		}
+		alreadyExists := existing != nil
+		if existing == nil {
+			if existing, ok = h.checkDuplicate(w, r, req.OnDuplicate, req.Filename, req.ChecksumSHA256); !ok {
+				return
+			}
+		}
+		if existing != nil {
+			respondWithJSON(w, http.StatusOK, PresignV2Response{
+				Operation:      req.Operation,
+				DryRun:         req.DryRun,
+				ObjectKey:      existing.ObjectKey,
+				ExistingObject: existing,
+				AlreadyExists:  alreadyExists,
+			})
+			return
+		}
+	}
+
+	if req.DryRun {
+		respondWithJSON(w, http.StatusOK, h.dryRunV2(svc, &req, objectKey))
+		return
+	}
+
+	var presigned *service.PresignedURL
+	var err error
+	switch req.Operation {
+	case OperationUpload:
+		presigned, err = svc.PresignUpload(req.Filename, uploadOptionsV2(&req))
+	case OperationDownload:
+		presigned, err = svc.PresignDownload(req.ObjectKey, service.DownloadOptions{
+			ContentEncoding:   req.ContentEncoding,
+			NotBefore:         req.NotBefore,
+			SSECustomerKeyMD5: req.SSECustomerKeyMD5,
+		})
+	case OperationDelete:
+		presigned, err = svc.PresignDelete(req.ObjectKey)
+	}
+	if err != nil {
+		respondWithCodedError(w, http.StatusInternalServerError, CodeSigningFailed, "Failed to generate presigned URL", err.Error())
+		return
+	}
+
+	h.recordIssuedUpload(r, presigned, uploadID)
+	if uploadID != "" {
+		logging.Infof("Issued upload %s for %s", uploadID, presigned.ObjectKey)
+	}
+
+	response := PresignV2Response{
+		Operation: req.Operation,
+		URL:       presigned.URL,
+		Method:    presigned.Method,
+		ObjectKey: presigned.ObjectKey,
+		NotBefore: presigned.NotBefore,
+		ExpiresAt: presigned.ExpiresAt,
+		Headers:   presigned.Headers,
+		UploadID:  uploadID,
+	}
+	if req.Operation == OperationUpload {
+		response.RequiredHeaders = requiredHeaders(req.Metadata)
+	}
+	if h.signerDebugRequested(r) {
+		response.Debug = presigned.Debug
+		if h.cfg.SignerDebug == "header" {
+			service.LogSigningDebug(presigned.Method, presigned.ObjectKey, presigned.Debug)
+		}
+	}
+	if !h.sealURLs(w, r, &response.URL) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// dryRunV2 describes the URL a request would get, with the headers that
+// would be signed, without signing it
+func (h *Handler) dryRunV2(svc *service.S3Service, req *PresignV2Request, objectKey string) PresignV2Response {
+	response := PresignV2Response{
+		Operation: req.Operation,
+		DryRun:    true,
+		ObjectKey: objectKey,
+	}
+	switch req.Operation {
+	case OperationUpload:
+		response.Method = http.MethodPut
+		response.Headers = svc.SignedUploadHeaders(uploadOptionsV2(req))
+		response.RequiredHeaders = requiredHeaders(req.Metadata)
+	case OperationDownload:
+		response.Method = http.MethodGet
+		response.Headers = service.DownloadHeaders(service.DownloadOptions{SSECustomerKeyMD5: req.SSECustomerKeyMD5})
+	case OperationDelete:
+		response.Method = http.MethodDelete
+	}
+	response.ExpiresAt = h.urlExpiry(response.Method)
+	if !req.NotBefore.IsZero() {
+		response.NotBefore = req.NotBefore.UTC().Truncate(time.Second)
+		response.ExpiresAt = response.NotBefore.Add(h.s3Service.Expiration(response.Method))
+	}
+	return response
+}
+
+// uploadOptionsV2 returns the upload properties a v2 request signs
+func uploadOptionsV2(req *PresignV2Request) service.UploadOptions {
+	return service.UploadOptions{
+		ContentType:       req.ContentType,
+		ContentLength:     req.ContentLength,
+		Metadata:          req.Metadata,
+		ObjectLock:        req.ObjectLock,
+		ChecksumSHA256:    req.ChecksumSHA256,
+		ContentMD5:        req.ContentMD5,
+		ContentEncoding:   req.ContentEncoding,
+		NotBefore:         req.NotBefore,
+		Subpath:           req.Subpath,
+		IfNoneMatch:       req.Overwrite != nil && !*req.Overwrite,
+		SSECustomerKeyMD5: req.SSECustomerKeyMD5,
+	}
+}
+
+// signerDebugRequested reports whether the client asked for signing details
+// via X-Signer-Debug and the deployment allows it
+func (h *Handler) signerDebugRequested(r *http.Request) bool {
+	if h.cfg.SignerDebug != "header" && h.cfg.SignerDebug != "all" {
+		return false
+	}
+	enabled, _ := strconv.ParseBool(r.Header.Get("X-Signer-Debug"))
+	return enabled
+}
+
+// operationAllowed reports whether the deployment allows presigning op
+func (h *Handler) operationAllowed(op string) bool {
+	for _, allowed := range h.cfg.AllowedOperations {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePresignV2 returns every problem found in the request
+func validatePresignV2(req *PresignV2Request) []string {
+	var problems []string
+
+	switch req.Operation {
+	case OperationUpload:
+		problems = append(problems, validateFilename(req.Filename)...)
+		if req.ObjectKey != "" {
+			problems = append(problems, "object_key is not allowed for upload")
+		}
+		if req.ContentType == "" {
+			problems = append(problems, "content_type is required for upload")
+		} else if _, _, err := mime.ParseMediaType(req.ContentType); err != nil {
+			problems = append(problems, fmt.Sprintf("content_type is invalid: %v", err))
+		}
+		if req.ContentLength < 0 || req.ContentLength > maxSinglePutBytes {
+			problems = append(problems, fmt.Sprintf("content_length must be between 0 and %d", int64(maxSinglePutBytes)))
+		}
+		problems = append(problems, validateMetadata(req.Metadata)...)
+		if req.ObjectLock != nil {
+			problems = append(problems, validateObjectLock(req.ObjectLock, time.Now())...)
+		}
+		if req.ChecksumSHA256 != "" {
+			checksum, err := service.NormalizeChecksumSHA256(req.ChecksumSHA256)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+			req.ChecksumSHA256 = checksum
+		}
+		if req.ContentMD5 != "" {
+			md5, err := service.NormalizeContentMD5(req.ContentMD5)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+			req.ContentMD5 = md5
+		}
+		if !validOnDuplicate(req.OnDuplicate) {
+			problems = append(problems, fmt.Sprintf("on_duplicate must be allow, reject or existing (got %q)", req.OnDuplicate))
+		}
+		if !service.ValidContentEncoding(req.ContentEncoding) {
+			problems = append(problems, fmt.Sprintf("content_encoding must be gzip for upload (got %q)", req.ContentEncoding))
+		}
+		problems = append(problems, validateNotBefore(req.NotBefore, time.Now())...)
+		problems = append(problems, normalizeSSECustomerKeyMD5(req)...)
+	case OperationDownload, OperationDelete:
+		if req.ObjectKey == "" {
+			problems = append(problems, "object_key is required for "+req.Operation)
+		}
+		if req.Filename != "" || req.ContentType != "" || req.ContentLength != 0 || len(req.Metadata) > 0 || req.ObjectLock != nil ||
+			req.ChecksumSHA256 != "" || req.ContentMD5 != "" || req.OnDuplicate != "" || req.Subpath != "" || req.Overwrite != nil {
+			problems = append(problems, "filename, content_type, content_length, metadata, object_lock, checksum_sha256, content_md5, on_duplicate, subpath and overwrite are only allowed for upload")
+		}
+		if req.Operation == OperationDelete && !req.NotBefore.IsZero() {
+			problems = append(problems, "not_before is not allowed for delete")
+		} else {
+			problems = append(problems, validateNotBefore(req.NotBefore, time.Now())...)
+		}
+		if req.Operation == OperationDelete && req.SSECustomerKeyMD5 != "" {
+			problems = append(problems, "sse_customer_key_md5 is not allowed for delete")
+		} else {
+			problems = append(problems, normalizeSSECustomerKeyMD5(req)...)
+		}
+		switch {
+		case req.Operation == OperationDelete && req.ContentEncoding != "":
+			problems = append(problems, "content_encoding is not allowed for delete")
+		case !service.ValidResponseContentEncoding(req.ContentEncoding):
+			problems = append(problems, fmt.Sprintf("content_encoding must be gzip or identity for download (got %q)", req.ContentEncoding))
+		}
+	case "":
+		problems = append(problems, "operation is required")
+	default:
+		problems = append(problems, fmt.Sprintf("operation must be one of upload, download, delete (got %q)", req.Operation))
+	}
+
+	return problems
+}
+
+// normalizeSSECustomerKeyMD5 converts the request's SSE-C key MD5 to base64
+// in place, returning the problem when it isn't an MD5 digest
+func normalizeSSECustomerKeyMD5(req *PresignV2Request) []string {
+	if req.SSECustomerKeyMD5 == "" {
+		return nil
+	}
+	md5, err := service.NormalizeSSECustomerKeyMD5(req.SSECustomerKeyMD5)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	req.SSECustomerKeyMD5 = md5
+	return nil
+}
+
+// validateNotBefore checks that a deferred URL starts in the future, within
+// service.MaxNotBeforeAhead
+func validateNotBefore(notBefore, now time.Time) []string {
+	switch {
+	case notBefore.IsZero():
+		return nil
+	case !notBefore.After(now):
+		return []string{"not_before must be a future date"}
+	case notBefore.Sub(now) > service.MaxNotBeforeAhead:
+		return []string{fmt.Sprintf("not_before must be at most %v ahead", service.MaxNotBeforeAhead)}
+	}
+	return nil
+}
+
+// validateObjectLock checks the retention mode and date and the legal hold
+// status of an upload
+func validateObjectLock(lock *service.ObjectLock, now time.Time) []string {
+	var problems []string
+	switch lock.Mode {
+	case "":
+		if !lock.RetainUntil.IsZero() {
+			problems = append(problems, "object_lock.mode is required with retain_until")
+		}
+	case service.ObjectLockGovernance, service.ObjectLockCompliance:
+		if !lock.RetainUntil.After(now) {
+			problems = append(problems, "object_lock.retain_until must be a future date")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("object_lock.mode must be GOVERNANCE or COMPLIANCE (got %q)", lock.Mode))
+	}
+	switch lock.LegalHold {
+	case "", service.LegalHoldOn, service.LegalHoldOff:
+	default:
+		problems = append(problems, fmt.Sprintf("object_lock.legal_hold must be ON or OFF (got %q)", lock.LegalHold))
+	}
+	if lock.Mode == "" && lock.LegalHold == "" {
+		problems = append(problems, "object_lock requires mode and retain_until, or legal_hold")
+	}
+	return problems
+}
+
+// validateFilename checks that filename is a single, printable path segment
+func validateFilename(filename string) []string {
+	var problems []string
+	switch {
+	case filename == "":
+		problems = append(problems, "filename is required")
+	case len(filename) > maxFilenameLength:
+		problems = append(problems, fmt.Sprintf("filename must be at most %d bytes", maxFilenameLength))
+	case strings.ContainsAny(filename, `/\`) || filename == "." || filename == "..":
+		problems = append(problems, "filename must not contain path separators")
+	case strings.IndexFunc(filename, unicode.IsControl) >= 0:
+		problems = append(problems, "filename must not contain control characters")
+	}
+	return problems
+}
+
+// validateMetadata checks metadata keys and the total size S3 accepts
+func validateMetadata(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	size := 0
+	for _, k := range keys {
+		v := metadata[k]
+		if !metadataKeyPattern.MatchString(k) {
+			problems = append(problems, fmt.Sprintf("metadata key %q must match %s", k, metadataKeyPattern))
+		}
+		if strings.IndexFunc(v, unicode.IsControl) >= 0 {
+			problems = append(problems, fmt.Sprintf("metadata value for %q must not contain control characters", k))
+		}
+		size += len(k) + len(v)
+	}
+	if size > maxMetadataBytes {
+		problems = append(problems, fmt.Sprintf("metadata must be at most %d bytes in total", maxMetadataBytes))
+	}
+	return problems
+}
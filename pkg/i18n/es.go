@@ -0,0 +1,205 @@
+package i18n
+
+// spanish translates error summaries and fixed messages
+var spanish = map[string]string{
+	// Requests and validation
+	"Internal Server Error":             "Error interno del servidor",
+	"Invalid request body":              "Cuerpo de la petición inválido",
+	"Request body too large":            "Cuerpo de la petición demasiado grande",
+	"Request validation failed":         "La validación de la petición falló",
+	"Invalid filename":                  "Nombre de archivo inválido",
+	"Invalid date range":                "Rango de fechas inválido",
+	"Invalid log level":                 "Nivel de log inválido",
+	"Invalid manifest":                  "Manifiesto inválido",
+	"Invalid metadata update":           "Actualización de metadatos inválida",
+	"Invalid select query":              "Consulta select inválida",
+	"Invalid signature report":          "Reporte de firma inválido",
+	"Metadata does not match schema":    "Los metadatos no cumplen el esquema",
+	"Object key too long":               "Clave de objeto demasiado larga",
+	"Signed headers rejected by policy": "Headers firmados rechazados por la política",
+	"Either from and to or from_manifest and to_manifest are required": "Se requieren from y to o from_manifest y to_manifest",
+	"at must be an RFC 3339 timestamp":                                 "at debe ser una fecha RFC 3339",
+	"content_encoding must be gzip":                                    "content_encoding debe ser gzip",
+	"delete_object only applies to upload URLs":                        "delete_object solo aplica a URLs de subida",
+	"etag is required":                                                 "etag es obligatorio",
+	"filename is required":                                             "filename es obligatorio",
+	"files is required":                                                "files es obligatorio",
+	"files must not contain empty names":                               "files no debe contener nombres vacíos",
+	"format must be csv or parquet":                                    "format debe ser csv o parquet",
+	"from and to must look like YYYY-MM-DD or YYYY-MM-DD/HH-MM-SS":     "from y to deben tener la forma YYYY-MM-DD o YYYY-MM-DD/HH-MM-SS",
+	"grace_period_seconds must be between 0 and 604800":                "grace_period_seconds debe estar entre 0 y 604800",
+	"limit must be between 1 and 1000":                                 "limit debe estar entre 1 y 1000",
+	"limit must be between 1 and RESTORE_DRILL_HISTORY":                "limit debe estar entre 1 y RESTORE_DRILL_HISTORY",
+	"max_keys must be between 1 and 1000":                              "max_keys debe estar entre 1 y 1000",
+	"metadata, remove or tags is required":                             "Se requiere metadata, remove o tags",
+	"method, url and s3_error are required":                            "method, url y s3_error son obligatorios",
+	"name is required":                                                 "name es obligatorio",
+	"not_before is not supported for upload sessions":                  "not_before no está soportado en sesiones de subida",
+	"object_key is outside the company prefix":                         "object_key está fuera del prefijo de la empresa",
+	"object_key is required":                                           "object_key es obligatorio",
+	"object_key, upload_id, or run_id and filename are required":       "Se requiere object_key, upload_id, o run_id y filename",
+	"on_duplicate must be allow, reject or existing":                   "on_duplicate debe ser allow, reject o existing",
+	"part number must be between 1 and 10000":                          "El número de parte debe estar entre 1 y 10000",
+	"prefix is outside the company prefix":                             "prefix está fuera del prefijo de la empresa",
+	"prefix must look like YYYY or YYYY-MM":                            "prefix debe tener la forma YYYY o YYYY-MM",
+	"read_only is required":                                            "read_only es obligatorio",
+	"scopes is required":                                               "scopes es obligatorio",
+	"since must be an RFC 3339 timestamp":                              "since debe ser una fecha RFC 3339",
+	"source_key and destination_key are required":                      "source_key y destination_key son obligatorios",
+	"source_key and destination_key must be inside the company prefix": "source_key y destination_key deben estar dentro del prefijo de la empresa",
+	"source_key and destination_key must differ":                       "source_key y destination_key deben ser distintos",
+	"status must be ON or OFF":                                         "status debe ser ON u OFF",
+	"trash_key must be a key in the trash":                             "trash_key debe ser una clave de la papelera",
+	"url is required":                                                  "url es obligatorio",
+	"wrapped_key requires CLIENT_ENCRYPTION_KMS_KEY_ID":                "wrapped_key requiere CLIENT_ENCRYPTION_KMS_KEY_ID",
+
+	// Authentication and authorization
+	"Unauthorized":                           "No autorizado",
+	"Insufficient scope":                     "Scope insuficiente",
+	"Unknown scope":                          "Scope desconocido",
+	"Operation not allowed":                  "Operación no permitida",
+	"Downloads are not allowed":              "Las descargas no están permitidas",
+	"Denied by policy":                       "Denegado por la política",
+	"Identity provider unavailable":          "Proveedor de identidad no disponible",
+	"HMAC signing is not enabled":            "La firma HMAC no está habilitada",
+	"API key is revoked":                     "La API key está revocada",
+	"API key not found":                      "API key no encontrada",
+	"API key store error":                    "Error del almacén de API keys",
+	"missing or invalid API key":             "API key ausente o inválida",
+	"missing bearer token":                   "Falta el bearer token",
+	"missing admin key or credential":        "Falta la admin key o la credencial",
+	"invalid admin key":                      "Admin key inválida",
+	"invalid request signature":              "Firma de la petición inválida",
+	"unknown, expired or already used nonce": "Nonce desconocido, expirado o ya usado",
+	"unknown signing key":                    "Clave de firma desconocida",
+	"Forbidden":                              "Prohibido",
+	"signing key belongs to a different tenant than the API key":                 "La clave de firma pertenece a otro tenant que la API key",
+	"X-Signature-Key-Id, X-Signature-Nonce and X-Signature headers are required": "Los headers X-Signature-Key-Id, X-Signature-Nonce y X-Signature son obligatorios",
+	"grant upload, download, delete and/or admin":                                "otorga upload, download, delete y/o admin",
+
+	// Availability and limits
+	"Rate limit exceeded":                            "Límite de peticiones excedido",
+	"Server is busy":                                 "El servidor está ocupado",
+	"too many concurrent requests":                   "Demasiadas peticiones simultáneas",
+	"Too many outstanding challenges":                "Demasiados desafíos pendientes",
+	"retry once issued nonces are used or expire":    "reintente cuando los nonces emitidos se usen o expiren",
+	"Service is starting":                            "El servicio está iniciando",
+	"AWS initialization failed and is being retried": "La inicialización de AWS falló y se está reintentando",
+	"Service is read-only":                           "El servicio está en modo solo lectura",
+	"Maintenance window":                             "Ventana de mantenimiento",
+	"Upload window closed":                           "Ventana de subida cerrada",
+	"Storage quota exceeded":                         "Cuota de almacenamiento excedida",
+	"Content type not allowed":                       "Tipo de contenido no permitido",
+
+	// S3
+	"S3 bucket not found":                "Bucket de S3 no encontrado",
+	"Access denied by S3":                "Acceso denegado por S3",
+	"S3 is throttling requests":          "S3 está limitando las peticiones",
+	"S3 is currently unavailable":        "S3 no está disponible en este momento",
+	"S3 request budget exhausted":        "Presupuesto de peticiones a S3 agotado",
+	"S3 operation timed out":             "La operación en S3 excedió el tiempo límite",
+	"Failed to abort upload session":     "No se pudo abortar la sesión de subida",
+	"Failed to browse objects":           "No se pudieron navegar los objetos",
+	"Failed to check destination object": "No se pudo revisar el objeto de destino",
+	"Failed to check for duplicates":     "No se pudo buscar duplicados",
+	"Failed to check object retention":   "No se pudo revisar la retención del objeto",
+	"Failed to check original object":    "No se pudo revisar el objeto original",
+	"Failed to check tenant quota":       "No se pudo revisar la cuota del tenant",
+	"Failed to collect tenant usage":     "No se pudo calcular el uso del tenant",
+	"Failed to compare folders":          "No se pudieron comparar las carpetas",
+	"Failed to complete upload session":  "No se pudo completar la sesión de subida",
+	"Failed to confirm object":           "No se pudo confirmar el objeto",
+	"Failed to create upload session":    "No se pudo crear la sesión de subida",
+	"Failed to delete objects":           "No se pudieron borrar los objetos",
+	"Failed to delete target object":     "No se pudo borrar el objeto de destino",
+	"Failed to find latest object":       "No se pudo encontrar el objeto más reciente",
+	"Failed to list date folders":        "No se pudieron listar las carpetas por fecha",
+	"Failed to list trash":               "No se pudo listar la papelera",
+	"Failed to move object":              "No se pudo mover el objeto",
+	"Failed to move object to the trash": "No se pudo mover el objeto a la papelera",
+	"Failed to plan restore":             "No se pudo planificar la restauración",
+	"Failed to read manifest":            "No se pudo leer el manifiesto",
+	"Failed to read object":              "No se pudo leer el objeto",
+	"Failed to read object lock":         "No se pudo leer el object lock",
+	"Failed to read replication status":  "No se pudo leer el estado de replicación",
+	"Failed to read source object":       "No se pudo leer el objeto de origen",
+	"Failed to read trashed object":      "No se pudo leer el objeto en la papelera",
+	"Failed to restore object":           "No se pudo restaurar el objeto",
+	"Failed to search object":            "No se pudo buscar el objeto",
+	"Failed to set legal hold":           "No se pudo aplicar el legal hold",
+	"Failed to store wrapped key":        "No se pudo guardar la clave envuelta",
+	"Failed to update object metadata":   "No se pudieron actualizar los metadatos del objeto",
+	"Failed to verify chunks":            "No se pudieron verificar los chunks",
+	"Failed to write manifest":           "No se pudo escribir el manifiesto",
+
+	// Signing
+	"Failed to generate presigned URL":             "No se pudo generar la URL prefirmada",
+	"Failed to encrypt presigned URL":              "No se pudo cifrar la URL prefirmada",
+	"Failed to generate data key":                  "No se pudo generar la clave de datos",
+	"Failed to revoke presigned URL":               "No se pudo revocar la URL prefirmada",
+	"Failed to diagnose signature":                 "No se pudo diagnosticar la firma",
+	"Failed to encode manifest":                    "No se pudo codificar el manifiesto",
+	"Failed to record object in the catalog":       "No se pudo registrar el objeto en el catálogo",
+	"Failed to search the catalog":                 "No se pudo buscar en el catálogo",
+	"Invalid presigned URL":                        "URL prefirmada inválida",
+	"Presigned URL not found":                      "URL prefirmada no encontrada",
+	"Presigned URL has expired":                    "La URL prefirmada expiró",
+	"Presigned URL has been revoked":               "La URL prefirmada fue revocada",
+	"Presigned URL is not valid yet":               "La URL prefirmada aún no es válida",
+	"not issued by this signer or already expired": "no fue emitida por este firmador o ya expiró",
+	"Download token not found":                     "Token de descarga no encontrado",
+	"Download token is no longer valid":            "El token de descarga ya no es válido",
+
+	// Objects
+	"Object not found":                                            "Objeto no encontrado",
+	"Object already exists":                                       "El objeto ya existe",
+	"set overwrite to replace it":                                 "usa overwrite para reemplazarlo",
+	"Source object not found":                                     "Objeto de origen no encontrado",
+	"Destination object already exists":                           "El objeto de destino ya existe",
+	"Original object already exists":                              "El objeto original ya existe",
+	"No object matches the filename":                              "Ningún objeto coincide con el nombre de archivo",
+	"Object is within its minimum retention period":               "El objeto está dentro de su período mínimo de retención",
+	"Object is already in the trash":                              "El objeto ya está en la papelera",
+	"Trashed object not found":                                    "Objeto de la papelera no encontrado",
+	"Deletes go through the trash":                                "Los borrados pasan por la papelera",
+	"use POST /api/v1/trash":                                      "usa POST /api/v1/trash",
+	"Object is too large to move":                                 "El objeto es demasiado grande para moverlo",
+	"Object is too large to move to the trash":                    "El objeto es demasiado grande para moverlo a la papelera",
+	"Object is too large to restore":                              "El objeto es demasiado grande para restaurarlo",
+	"Object is too large to store the wrapped key":                "El objeto es demasiado grande para guardar la clave envuelta",
+	"Object is too large to update":                               "El objeto es demasiado grande para actualizarlo",
+	"Folder is too large to compare":                              "La carpeta es demasiado grande para compararla",
+	"Prefix holds too many uploads to plan a restore":             "El prefijo tiene demasiadas subidas para planificar una restauración",
+	"Prefix usage has not been collected yet":                     "El uso del prefijo aún no se ha calculado",
+	"No upload URL has been issued for this file":                 "No se ha emitido una URL de subida para este archivo",
+	"the upload URL is unknown or expired; confirm by object_key": "la URL de subida es desconocida o expiró; confirma por object_key",
+	"Upload ID not found":                                         "Upload ID no encontrado",
+	"Inventory export not found":                                  "Exportación de inventario no encontrada",
+	"Backup ages have not been checked yet":                       "La antigüedad de los backups aún no se ha revisado",
+	"No cleanup has run yet":                                      "Aún no se ha ejecutado ninguna limpieza",
+
+	// Sessions, chunked backups and runs
+	"Session error":                         "Error de sesión",
+	"Session not found":                     "Sesión no encontrada",
+	"Session is not active":                 "La sesión no está activa",
+	"Session is being completed or aborted": "La sesión se está completando o abortando",
+	"No parts have been completed":          "No se ha completado ninguna parte",
+	"Chunked backup error":                  "Error del backup por chunks",
+	"Chunked backup not found":              "Backup por chunks no encontrado",
+	"Chunked backup is already completed":   "El backup por chunks ya está completo",
+	"Chunks differ from the declared size":  "Los chunks no coinciden con el tamaño declarado",
+	"Chunks have not been uploaded":         "Los chunks no se han subido",
+	"Manifest not found":                    "Manifiesto no encontrado",
+	"Run error":                             "Error de la ejecución",
+	"Run not found":                         "Ejecución no encontrada",
+	"File is not part of the run manifest":  "El archivo no es parte del manifiesto de la ejecución",
+	"Restore drill not found":               "Simulacro de restauración no encontrado",
+
+	// Tenants
+	"Tenant not found":              "Tenant no encontrado",
+	"Tenant already exists":         "El tenant ya existe",
+	"Tenant store error":            "Error del almacén de tenants",
+	"Invalid tenant":                "Tenant inválido",
+	"Invalid tenant upload windows": "Ventanas de subida del tenant inválidas",
+	"Prefix is already in use":      "El prefijo ya está en uso",
+}
@@ -0,0 +1,60 @@
+// Package i18n localizes the human-readable text of API errors. Messages are
+// looked up by their English text, so anything missing from a catalog, such
+// as details carrying S3 or validation errors, is returned in English.
+package i18n
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Supported languages, as primary language subtags
+const (
+	English = "en"
+	Spanish = "es"
+)
+
+// catalogs maps a language to its translations of English messages
+var catalogs = map[string]map[string]string{
+	Spanish: spanish,
+}
+
+// Supported reports whether lang is a language with error messages
+func Supported(lang string) bool {
+	return lang == English || catalogs[lang] != nil
+}
+
+// Negotiate picks the supported language the Accept-Language header prefers,
+// matching tags by their primary subtag ("es-CL" selects Spanish). It
+// returns false when the header names no supported language.
+func Negotiate(acceptLanguage string) (string, bool) {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				parsed, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		// Earlier tags win ties
+		if q > bestQ && Supported(primary) {
+			best, bestQ = primary, q
+		}
+	}
+	return best, best != ""
+}
+
+// Translate returns message in lang, or message itself when lang is English
+// or the catalog has no translation for it
+func Translate(lang, message string) string {
+	if translated, ok := catalogs[lang][message]; ok {
+		return translated
+	}
+	return message
+}
@@ -0,0 +1,273 @@
+// Package iampolicy builds the minimal IAM policy the service's credentials
+// need for a configuration: S3 actions scoped to the configured bucket and
+// key prefixes, plus the STS, KMS and CloudWatch Logs actions of the
+// features that are enabled.
+package iampolicy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+)
+
+// Version is the IAM policy language version
+const Version = "2012-10-17"
+
+// Document is an IAM policy document
+type Document struct {
+	Version   string      `json:"Version"`
+	Statement []Statement `json:"Statement"`
+}
+
+// Statement is an Allow statement of a policy document
+type Statement struct {
+	Sid       string                         `json:"Sid"`
+	Effect    string                         `json:"Effect"`
+	Action    []string                       `json:"Action"`
+	Resource  []string                       `json:"Resource"`
+	Condition map[string]map[string][]string `json:"Condition,omitempty"`
+}
+
+// objectActions are called on objects under the prefixes: presigned URLs,
+// HeadObject (which needs the Object Lock reads to report retention),
+// copies, deletes, multipart sessions and the metadata and legal hold
+// endpoints
+var objectActions = []string{
+	"s3:GetObject",
+	"s3:PutObject",
+	"s3:DeleteObject",
+	"s3:AbortMultipartUpload",
+	"s3:ListMultipartUploadParts",
+	"s3:PutObjectTagging",
+	"s3:GetObjectRetention",
+	"s3:GetObjectLegalHold",
+	"s3:PutObjectLegalHold",
+}
+
+// bucketStatusActions back GET /admin/v1/bucket/status
+var bucketStatusActions = []string{
+	"s3:GetEncryptionConfiguration",
+	"s3:GetBucketVersioning",
+	"s3:GetBucketPublicAccessBlock",
+	"s3:GetBucketPolicyStatus",
+	"s3:GetLifecycleConfiguration",
+}
+
+// Generate returns the policy cfg needs. With AWS_ROLE_ARN the S3
+// statements belong on the role and the credentials only need the
+// sts:AssumeRole statement.
+//
+// Listing is restricted to the prefixes with an s3:prefix condition unless
+// HeadBucket is called (clock drift check, preflight check or failover),
+// which needs s3:ListBucket on the whole bucket. Tenants managed at runtime
+// (an admin key or a tenant file) can use any prefix, so they widen objects
+// and listing to the whole bucket.
+func Generate(cfg *config.Config) *Document {
+	partition := partitionOf(cfg.AWSRegion)
+	bucket := cfg.S3BucketName
+	if cfg.S3MRAPARN != "" {
+		bucket = cfg.S3MRAPARN
+	}
+
+	prefixes := keyPrefixes(cfg)
+	service, bucketARN, objectsARN := bucketResources(partition, bucket)
+
+	doc := &Document{Version: Version}
+	add := func(sid string, actions, resources []string, condition map[string]map[string][]string) {
+		doc.Statement = append(doc.Statement, Statement{
+			Sid:       sid,
+			Effect:    "Allow",
+			Action:    actions,
+			Resource:  resources,
+			Condition: condition,
+		})
+	}
+
+	var listCondition map[string]map[string][]string
+	headBucket := cfg.ClockDriftCheckIntervalSeconds > 0 || cfg.PreflightCheck == "warn" || cfg.PreflightCheck == "fail" || cfg.FailoverEnabled
+	if prefixes != nil && !headBucket {
+		patterns := make([]string, 0, 2*len(prefixes))
+		for _, prefix := range prefixes {
+			patterns = append(patterns, prefix, prefix+"/*")
+		}
+		listCondition = map[string]map[string][]string{"StringLike": {"s3:prefix": patterns}}
+	}
+	add("ListPrefixes", withService(service, "s3:ListBucket", "s3:ListBucketMultipartUploads"), []string{bucketARN}, listCondition)
+
+	actions := objectActions
+	if cfg.UploadACL != "" {
+		actions = append(slices.Clone(actions), "s3:PutObjectAcl")
+	}
+	add("Objects", withService(service, actions...), objectResources(objectsARN, prefixes), nil)
+
+	if cfg.DRBucketName != "" {
+		_, drBucketARN, drObjectsARN := bucketResources(partition, cfg.DRBucketName)
+		if cfg.FailoverEnabled {
+			// Every request may be routed to the replica
+			add("FailoverBucket", []string{"s3:ListBucket", "s3:ListBucketMultipartUploads"}, []string{drBucketARN}, nil)
+			add("FailoverObjects", actions, objectResources(drObjectsARN, prefixes), nil)
+		} else {
+			add("ReplicaDownloads", []string{"s3:GetObject"}, objectResources(drObjectsARN, prefixes), nil)
+		}
+	}
+
+	if cfg.AccessLogPrefix != "" {
+		logBucket := cfg.AccessLogBucket
+		if logBucket == "" {
+			logBucket = cfg.S3BucketName
+		}
+		_, logBucketARN, logObjectsARN := bucketResources(partition, logBucket)
+		add("ListAccessLogs", []string{"s3:ListBucket"}, []string{logBucketARN},
+			map[string]map[string][]string{"StringLike": {"s3:prefix": {cfg.AccessLogPrefix + "*"}}})
+		add("ReadAccessLogs", []string{"s3:GetObject"}, []string{logObjectsARN + cfg.AccessLogPrefix + "*"}, nil)
+	}
+
+	if cfg.AdminAPIKey != "" {
+		add("BucketStatus", bucketStatusActions, []string{bucketARN}, nil)
+	}
+
+	if roles := assumedRoles(cfg); len(roles) > 0 {
+		add("AssumeRoles", []string{"sts:AssumeRole"}, roles, nil)
+	}
+
+	if cfg.URLEncryption == "kms" {
+		resources, condition := kmsKeyResources(partition, cfg.AWSRegion, cfg.URLEncryptionKMSKeyID, "presigned-url")
+		add("EncryptURLs", []string{"kms:Encrypt"}, resources, condition)
+	}
+	if cfg.ClientEncryptionKMSKeyID != "" {
+		resources, condition := kmsKeyResources(partition, cfg.AWSRegion, cfg.ClientEncryptionKMSKeyID, "backup-data-key")
+		add("GenerateDataKeys", []string{"kms:GenerateDataKey"}, resources, condition)
+	}
+
+	var groups []string
+	if cfg.CloudWatchLogGroup != "" {
+		groups = append(groups, cfg.CloudWatchLogGroup)
+	}
+	if cfg.AuditSink == "cloudwatch" && cfg.AuditCloudWatchLogGroup != "" && !slices.Contains(groups, cfg.AuditCloudWatchLogGroup) {
+		groups = append(groups, cfg.AuditCloudWatchLogGroup)
+	}
+	if len(groups) > 0 {
+		resources := make([]string, len(groups))
+		for i, group := range groups {
+			resources[i] = fmt.Sprintf("arn:%s:logs:%s:*:log-group:%s:*", partition, cfg.AWSRegion, group)
+		}
+		add("ShipLogs", []string{"logs:CreateLogStream", "logs:PutLogEvents"}, resources, nil)
+	}
+
+	return doc
+}
+
+// kmsKeyResources returns the resource of a KMS key given as an ID, ARN or
+// alias. Key IDs and aliases don't name the key ARN, so they grant every key
+// with a condition on the encryption context purpose the service sends.
+func kmsKeyResources(partition, region, keyID, purpose string) ([]string, map[string]map[string][]string) {
+	if strings.HasPrefix(keyID, "arn:") && strings.Contains(keyID, ":key/") {
+		return []string{keyID}, nil
+	}
+	return []string{fmt.Sprintf("arn:%s:kms:%s:*:key/*", partition, region)},
+		map[string]map[string][]string{"StringEquals": {"kms:EncryptionContext:purpose": {purpose}}}
+}
+
+// keyPrefixes returns the prefixes keys are built under, including the
+// environment segment, or nil when keys may use any prefix
+func keyPrefixes(cfg *config.Config) []string {
+	dynamicTenants := cfg.TenantStore == "file" || (cfg.TenantStore == "memory" && cfg.AdminAPIKey != "")
+	company := withEnvironment(cfg.CompanyPrefix, cfg.Environment)
+	if company == "" || dynamicTenants {
+		return nil
+	}
+
+	prefixes := []string{company}
+	for _, t := range cfg.Tenants {
+		// Tenants with their own role call S3 with it
+		if t.RoleARN != "" {
+			continue
+		}
+		if prefix := withEnvironment(t.Prefix, cfg.Environment); !slices.Contains(prefixes, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// withEnvironment appends the environment segment to prefix like the
+// service does
+func withEnvironment(prefix, environment string) string {
+	switch {
+	case environment == "":
+		return prefix
+	case prefix == "":
+		return environment
+	}
+	return prefix + "/" + environment
+}
+
+// assumedRoles returns the roles the service assumes: AWS_ROLE_ARN and the
+// roles of config file tenants
+func assumedRoles(cfg *config.Config) []string {
+	var roles []string
+	if cfg.AWSRoleARN != "" {
+		roles = append(roles, cfg.AWSRoleARN)
+	}
+	for _, t := range cfg.Tenants {
+		if t.RoleARN != "" && !slices.Contains(roles, t.RoleARN) {
+			roles = append(roles, t.RoleARN)
+		}
+	}
+	return roles
+}
+
+// bucketResources returns the action service prefix and the ARNs of a bucket
+// name or access point ARN and of the objects in it, without the key part
+func bucketResources(partition, bucket string) (service, bucketARN, objectsARN string) {
+	if !strings.HasPrefix(bucket, "arn:") {
+		arn := fmt.Sprintf("arn:%s:s3:::%s", partition, bucket)
+		return "s3", arn, arn + "/"
+	}
+	// Access points take objects as <ARN>/object/<key>; Object Lambda
+	// access points authorize their own s3-object-lambda actions
+	service = "s3"
+	if parts := strings.SplitN(bucket, ":", 4); len(parts) == 4 {
+		service = parts[2]
+	}
+	return service, bucket, bucket + "/object/"
+}
+
+// objectResources returns the object ARNs under each prefix, or every object
+// when prefixes is nil
+func objectResources(objectsARN string, prefixes []string) []string {
+	if prefixes == nil {
+		return []string{objectsARN + "*"}
+	}
+	resources := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		resources[i] = objectsARN + prefix + "/*"
+	}
+	return resources
+}
+
+// withService rewrites s3 actions for the service of an Object Lambda access
+// point
+func withService(service string, actions ...string) []string {
+	if service == "s3" {
+		return actions
+	}
+	rewritten := make([]string, len(actions))
+	for i, action := range actions {
+		rewritten[i] = service + strings.TrimPrefix(action, "s3")
+	}
+	return rewritten
+}
+
+// partitionOf returns the AWS partition of a region
+func partitionOf(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
@@ -0,0 +1,68 @@
+// Package idgen generates random identifiers for sessions, runs and other
+// server-side records.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand/v2"
+	"sync"
+)
+
+// Generator produces identifiers in the format of New
+type Generator interface {
+	NewID() string
+}
+
+// Random generates identifiers with New
+type Random struct{}
+
+// NewID returns a random 128-bit hex identifier
+func (Random) NewID() string {
+	return New()
+}
+
+// Seeded generates a reproducible sequence of identifiers from a seed, for
+// golden tests. It must not be used in production.
+type Seeded struct {
+	mu  sync.Mutex
+	rng *mrand.Rand
+}
+
+// NewSeeded creates a generator whose sequence depends only on seed
+func NewSeeded(seed uint64) *Seeded {
+	return &Seeded{rng: mrand.New(mrand.NewPCG(seed, 0))}
+}
+
+// NewID returns the next 128-bit hex identifier of the sequence
+func (s *Seeded) NewID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], s.rng.Uint64())
+	binary.BigEndian.PutUint64(b[8:], s.rng.Uint64())
+	return hex.EncodeToString(b)
+}
+
+// UUID draws an identifier from g and formats it as a version 4 UUID
+func UUID(g Generator) string {
+	b, err := hex.DecodeString(g.NewID())
+	if err != nil || len(b) != 16 {
+		panic("idgen: generator must return 128-bit hex identifiers")
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// New returns a random 128-bit hex identifier
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
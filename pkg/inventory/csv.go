@@ -0,0 +1,48 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Columns are the columns of an export, in order
+var Columns = []string{"key", "size", "last_modified", "etag", "storage_class"}
+
+// Encode writes objects in format
+func Encode(format Format, objects []service.InventoryObject) ([]byte, error) {
+	if format == FormatParquet {
+		return EncodeParquet(objects), nil
+	}
+	return EncodeCSV(objects)
+}
+
+// EncodeCSV writes objects as CSV with a header row. Timestamps are RFC 3339
+// in UTC.
+func EncodeCSV(objects []service.InventoryObject) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(Columns); err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		record := []string{
+			obj.Key,
+			strconv.FormatInt(obj.Size, 10),
+			obj.LastModified.UTC().Format(time.RFC3339),
+			obj.ETag,
+			obj.StorageClass,
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
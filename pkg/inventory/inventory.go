@@ -0,0 +1,119 @@
+// Package inventory tracks inventory exports: snapshots of the objects under
+// a prefix written to the bucket as CSV or Parquet, for reconciliation with
+// a backup catalog.
+package inventory
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Format is the file format of an export
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ContentType returns the Content-Type an export in format is stored with
+func (f Format) ContentType() string {
+	if f == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// Status is the lifecycle state of an export
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// ErrNotFound is returned when an export ID is unknown
+var ErrNotFound = errors.New("inventory export not found")
+
+// Export is an inventory export job
+type Export struct {
+	ID         string     `json:"export_id"`
+	Status     Status     `json:"status"`
+	Format     Format     `json:"format"`
+	Prefix     string     `json:"prefix"`
+	From       time.Time  `json:"from,omitzero"`
+	To         time.Time  `json:"to,omitzero"`
+	ObjectKey  string     `json:"object_key"`
+	Rows       int        `json:"rows"`
+	Bytes      int64      `json:"bytes"` // Size of the export object
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Store keeps inventory exports in memory
+type Store struct {
+	mu      sync.Mutex
+	exports map[string]*Export
+}
+
+// NewStore creates an empty export store
+func NewStore() *Store {
+	return &Store{exports: make(map[string]*Export)}
+}
+
+// Create registers a running export under export.ID
+func (s *Store) Create(export Export) Export {
+	export.Status = StatusRunning
+	export.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := export
+	s.exports[export.ID] = &stored
+	return stored
+}
+
+// Get returns an export
+func (s *Store) Get(id string) (Export, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	export, ok := s.exports[id]
+	if !ok {
+		return Export{}, ErrNotFound
+	}
+	return *export, nil
+}
+
+// Complete records that an export was written with rows objects in size bytes
+func (s *Store) Complete(id string, rows int, size int64) {
+	s.finish(id, func(e *Export) {
+		e.Status = StatusCompleted
+		e.Rows = rows
+		e.Bytes = size
+	})
+}
+
+// Fail records that an export could not be written
+func (s *Store) Fail(id string, err error) {
+	s.finish(id, func(e *Export) {
+		e.Status = StatusFailed
+		e.Error = err.Error()
+	})
+}
+
+func (s *Store) finish(id string, update func(*Export)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	export, ok := s.exports[id]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	update(export)
+	export.FinishedAt = &now
+}
@@ -0,0 +1,234 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Enum values from parquet.thrift used by the writer
+const (
+	parquetInt64             = 2
+	parquetByteArray         = 6
+	repetitionRequired       = 0
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+	encodingPlain            = 0
+	encodingRLE              = 3
+	codecUncompressed        = 0
+	pageTypeData             = 0
+)
+
+const parquetMagic = "PAR1"
+
+// parquetColumn is one column of the single row group, PLAIN-encoded
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	values    bytes.Buffer
+}
+
+func (c *parquetColumn) appendString(s string) {
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	c.values.WriteString(s)
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+}
+
+// EncodeParquet writes objects as a Parquet file: one row group of required
+// columns, each a single uncompressed PLAIN data page. last_modified is an
+// INT64 timestamp in milliseconds (UTC).
+func EncodeParquet(objects []service.InventoryObject) []byte {
+	columns := []*parquetColumn{
+		{name: Columns[0], physical: parquetByteArray, converted: convertedUTF8},
+		{name: Columns[1], physical: parquetInt64, converted: -1},
+		{name: Columns[2], physical: parquetInt64, converted: convertedTimestampMillis},
+		{name: Columns[3], physical: parquetByteArray, converted: convertedUTF8},
+		{name: Columns[4], physical: parquetByteArray, converted: convertedUTF8},
+	}
+	for _, obj := range objects {
+		columns[0].appendString(obj.Key)
+		columns[1].appendInt64(obj.Size)
+		columns[2].appendInt64(obj.LastModified.UnixMilli())
+		columns[3].appendString(obj.ETag)
+		columns[4].appendString(obj.StorageClass)
+	}
+
+	var out bytes.Buffer
+	out.WriteString(parquetMagic)
+
+	// Column chunks: a page header followed by the page. An empty inventory
+	// is written without a row group.
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(columns))
+	if len(objects) > 0 {
+		for i, c := range columns {
+			w := newCompactWriter()
+			w.i32(1, pageTypeData)
+			w.i32(2, int32(c.values.Len()))
+			w.i32(3, int32(c.values.Len()))
+			w.beginStruct(5) // DataPageHeader
+			w.i32(1, int32(len(objects)))
+			w.i32(2, encodingPlain)
+			w.i32(3, encodingRLE) // No levels are written for required columns
+			w.i32(4, encodingRLE)
+			w.endStruct()
+			w.end()
+
+			chunks[i] = chunk{offset: int64(out.Len()), size: int64(w.buf.Len() + c.values.Len())}
+			out.Write(w.buf.Bytes())
+			out.Write(c.values.Bytes())
+		}
+	}
+
+	w := newCompactWriter()
+	w.i32(1, 1) // version
+	w.listBegin(2, compactStruct, len(columns)+1)
+	w.elementBegin() // Root of the schema
+	w.binary(4, "schema")
+	w.i32(5, int32(len(columns)))
+	w.endStruct()
+	for _, c := range columns {
+		w.elementBegin()
+		w.i32(1, c.physical)
+		w.i32(3, repetitionRequired)
+		w.binary(4, c.name)
+		if c.converted >= 0 {
+			w.i32(6, c.converted)
+		}
+		w.endStruct()
+	}
+	w.i64(3, int64(len(objects)))
+	if len(objects) == 0 {
+		w.listBegin(4, compactStruct, 0)
+	} else {
+		var total int64
+		for _, ch := range chunks {
+			total += ch.size
+		}
+		w.listBegin(4, compactStruct, 1)
+		w.elementBegin() // RowGroup
+		w.listBegin(1, compactStruct, len(columns))
+		for i, c := range columns {
+			w.elementBegin() // ColumnChunk
+			w.i64(2, chunks[i].offset)
+			w.beginStruct(3) // ColumnMetaData
+			w.i32(1, c.physical)
+			w.listBegin(2, compactI32, 1)
+			w.uvarint(zigzag(encodingPlain))
+			w.listBegin(3, compactBinary, 1)
+			w.uvarint(uint64(len(c.name)))
+			w.buf.WriteString(c.name)
+			w.i32(4, codecUncompressed)
+			w.i64(5, int64(len(objects)))
+			w.i64(6, chunks[i].size)
+			w.i64(7, chunks[i].size)
+			w.i64(9, chunks[i].offset)
+			w.endStruct()
+			w.endStruct()
+		}
+		w.i64(2, total)
+		w.i64(3, int64(len(objects)))
+		w.endStruct()
+	}
+	w.binary(6, "signer-service")
+	w.end()
+
+	out.Write(w.buf.Bytes())
+	out.Write(binary.LittleEndian.AppendUint32(nil, uint32(w.buf.Len())))
+	out.WriteString(parquetMagic)
+	return out.Bytes()
+}
+
+// Thrift compact protocol type IDs
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes the Thrift compact protocol Parquet metadata uses.
+// Field IDs must increase within each struct.
+type compactWriter struct {
+	buf  bytes.Buffer
+	last []int16 // Last field ID of each open struct
+}
+
+func newCompactWriter() *compactWriter {
+	return &compactWriter{last: []int16{0}}
+}
+
+func (w *compactWriter) field(id int16, typ byte) {
+	top := len(w.last) - 1
+	if delta := id - w.last[top]; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.uvarint(zigzag(int64(id)))
+	}
+	w.last[top] = id
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, compactI32)
+	w.uvarint(zigzag(int64(v)))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, compactI64)
+	w.uvarint(zigzag(v))
+}
+
+func (w *compactWriter) binary(id int16, s string) {
+	w.field(id, compactBinary)
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// listBegin writes a list field header; the n elements follow
+func (w *compactWriter) listBegin(id int16, elem byte, n int) {
+	w.field(id, compactList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elem)
+	w.uvarint(uint64(n))
+}
+
+// beginStruct opens a struct field, closed by endStruct
+func (w *compactWriter) beginStruct(id int16) {
+	w.field(id, compactStruct)
+	w.last = append(w.last, 0)
+}
+
+// elementBegin opens a struct list element, closed by endStruct
+func (w *compactWriter) elementBegin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *compactWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+// end closes the top-level struct
+func (w *compactWriter) end() {
+	w.buf.WriteByte(0)
+}
+
+func (w *compactWriter) uvarint(v uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
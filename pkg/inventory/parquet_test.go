@@ -0,0 +1,255 @@
+package inventory_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/inventory"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// thriftStruct is a decoded Thrift compact struct: field ID to int64,
+// string, []any or thriftStruct
+type thriftStruct map[int16]any
+
+// compactReader decodes the Thrift compact protocol independently of the
+// writer, so the test checks the bytes against the format rather than
+// against the encoder's assumptions
+type compactReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *compactReader) byte() byte {
+	if r.pos >= len(r.data) {
+		panic("unexpected end of metadata")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 5, 6: // i32, i64
+		return r.varint()
+	case 8: // binary
+		n := int(r.uvarint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case 9: // list
+		header := r.byte()
+		n, elem := int(header>>4), header&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case 12: // struct
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unsupported compact type %d", typ))
+}
+
+func (r *compactReader) structure() thriftStruct {
+	s := thriftStruct{}
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return s
+		}
+		id, typ := last+int16(header>>4), header&0x0f
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		s[id] = r.value(typ)
+		last = id
+	}
+}
+
+// decode reads a struct at offset in data, returning it and its length
+func decode(t *testing.T, data []byte, offset int) (s thriftStruct, n int) {
+	t.Helper()
+	defer func() {
+		if err := recover(); err != nil {
+			t.Fatalf("decoding Thrift at %d: %v", offset, err)
+		}
+	}()
+	r := &compactReader{data: data, pos: offset}
+	s = r.structure()
+	return s, r.pos - offset
+}
+
+// footer checks the magic numbers and returns the decoded FileMetaData and
+// where it starts
+func footer(t *testing.T, file []byte) (thriftStruct, int) {
+	t.Helper()
+	if len(file) < 12 || string(file[:4]) != "PAR1" || string(file[len(file)-4:]) != "PAR1" {
+		t.Fatalf("file does not start and end with PAR1: %q", file)
+	}
+	length := int(binary.LittleEndian.Uint32(file[len(file)-8 : len(file)-4]))
+	start := len(file) - 8 - length
+	if start < 4 {
+		t.Fatalf("footer length %d exceeds the file (%d bytes)", length, len(file))
+	}
+	meta, n := decode(t, file, start)
+	if n != length {
+		t.Fatalf("FileMetaData is %d bytes, footer length says %d", n, length)
+	}
+	return meta, start
+}
+
+func TestEncodeParquet(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := []service.InventoryObject{
+		{Key: "acme/inputs/db.dump", Size: 1024, LastModified: modified, ETag: `"abc"`, StorageClass: "STANDARD"},
+		{Key: "acme/inputs/logs.tar", Size: 1 << 40, LastModified: modified.Add(time.Hour), ETag: `"def"`, StorageClass: "GLACIER"},
+	}
+	file := inventory.EncodeParquet(objects)
+	meta, metaStart := footer(t, file)
+
+	if meta[1] != int64(1) {
+		t.Errorf("version = %v, want 1", meta[1])
+	}
+	if meta[3] != int64(len(objects)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(objects))
+	}
+
+	// The schema is a root with one required element per column
+	schema := meta[2].([]any)
+	if root := schema[0].(thriftStruct); root[4] != "schema" || root[5] != int64(len(inventory.Columns)) {
+		t.Errorf("schema root = %v, want %d children", root, len(inventory.Columns))
+	}
+	type column struct {
+		name      string
+		physical  any // parquet.thrift Type
+		converted any // ConvertedType, nil when absent
+	}
+	var got []column
+	for _, e := range schema[1:] {
+		el := e.(thriftStruct)
+		if el[3] != int64(0) {
+			t.Errorf("%v: repetition = %v, want REQUIRED", el[4], el[3])
+		}
+		got = append(got, column{el[4].(string), el[1], el[6]})
+	}
+	want := []column{
+		{"key", int64(6), int64(0)},           // BYTE_ARRAY UTF8
+		{"size", int64(2), nil},               // INT64
+		{"last_modified", int64(2), int64(9)}, // INT64 TIMESTAMP_MILLIS
+		{"etag", int64(6), int64(0)},
+		{"storage_class", int64(6), int64(0)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schema columns = %v, want %v", got, want)
+	}
+
+	// One row group whose column chunks follow the leading magic back to
+	// back, up to the footer
+	rowGroups := meta[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatalf("row groups = %d, want 1", len(rowGroups))
+	}
+	rowGroup := rowGroups[0].(thriftStruct)
+	if rowGroup[3] != int64(len(objects)) {
+		t.Errorf("row group num_rows = %v, want %d", rowGroup[3], len(objects))
+	}
+	chunks := rowGroup[1].([]any)
+	if len(chunks) != len(inventory.Columns) {
+		t.Fatalf("column chunks = %d, want %d", len(chunks), len(inventory.Columns))
+	}
+	offset, total := int64(4), int64(0)
+	pages := make([][]byte, len(chunks))
+	for i, c := range chunks {
+		chunk := c.(thriftStruct)
+		cm := chunk[3].(thriftStruct)
+		name := inventory.Columns[i]
+		if chunk[2] != offset || cm[9] != offset {
+			t.Errorf("%s: file_offset %v, data_page_offset %v, want %d", name, chunk[2], cm[9], offset)
+		}
+		if cm[1] != want[i].physical || !reflect.DeepEqual(cm[3], []any{name}) || cm[4] != int64(0) || cm[5] != int64(len(objects)) {
+			t.Errorf("%s: column metadata = %v", name, cm)
+		}
+		size := cm[7].(int64)
+		if cm[6] != size {
+			t.Errorf("%s: uncompressed size %v != compressed size %d", name, cm[6], size)
+		}
+
+		// The chunk is a data page header and the PLAIN values
+		header, n := decode(t, file, int(offset))
+		page := header[5].(thriftStruct)
+		if header[1] != int64(0) || page[1] != int64(len(objects)) || page[2] != int64(0) || int64(n)+header[3].(int64) != size {
+			t.Errorf("%s: page header = %v, %d bytes, chunk %d bytes", name, header, n, size)
+		}
+		pages[i] = file[int(offset)+n : offset+size]
+		offset += size
+		total += size
+	}
+	if offset != int64(metaStart) {
+		t.Errorf("column chunks end at %d, FileMetaData starts at %d", offset, metaStart)
+	}
+	if rowGroup[2] != total {
+		t.Errorf("row group total_byte_size = %v, want %d", rowGroup[2], total)
+	}
+
+	// PLAIN values: length-prefixed strings and little-endian int64s
+	var keys []string
+	for page := pages[0]; len(page) > 0; {
+		n := binary.LittleEndian.Uint32(page)
+		keys = append(keys, string(page[4:4+n]))
+		page = page[4+n:]
+	}
+	if !reflect.DeepEqual(keys, []string{objects[0].Key, objects[1].Key}) {
+		t.Errorf("key values = %q", keys)
+	}
+	if size := int64(binary.LittleEndian.Uint64(pages[1][8:])); size != objects[1].Size {
+		t.Errorf("second size = %d, want %d", size, objects[1].Size)
+	}
+	if millis := int64(binary.LittleEndian.Uint64(pages[2])); millis != modified.UnixMilli() {
+		t.Errorf("first last_modified = %d, want %d", millis, modified.UnixMilli())
+	}
+	if !bytes.HasSuffix(pages[4], []byte("GLACIER")) {
+		t.Errorf("storage_class values = %q", pages[4])
+	}
+}
+
+func TestEncodeParquetEmpty(t *testing.T) {
+	file := inventory.EncodeParquet(nil)
+	meta, metaStart := footer(t, file)
+	if metaStart != 4 {
+		t.Errorf("FileMetaData starts at %d, want right after the magic", metaStart)
+	}
+	if meta[3] != int64(0) || len(meta[4].([]any)) != 0 {
+		t.Errorf("num_rows = %v, row groups = %v; want none", meta[3], meta[4])
+	}
+	if schema := meta[2].([]any); len(schema) != len(inventory.Columns)+1 {
+		t.Errorf("schema has %d elements, want the root and %d columns", len(schema), len(inventory.Columns))
+	}
+}
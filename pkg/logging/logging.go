@@ -0,0 +1,156 @@
+// Package logging adds a level, adjustable at runtime, to the standard
+// logger so verbose output can be enabled without restarting.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the minimum severity that is logged
+type Level int32
+
+// Log levels, from most to least verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// current is the active level, info until SetLevel is called
+var current atomic.Int32
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// String returns the level name
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("log level must be debug, info, warn or error (got %q)", name)
+}
+
+// SetLevel changes the active level. It is safe to call concurrently with
+// logging.
+func SetLevel(level Level) {
+	current.Store(int32(level))
+}
+
+// GetLevel returns the active level
+func GetLevel() Level {
+	return Level(current.Load())
+}
+
+// Enabled reports whether messages at level are logged
+func Enabled(level Level) bool {
+	return level >= GetLevel()
+}
+
+// Debugf logs verbose diagnostics such as signing inputs
+func Debugf(format string, args ...any) {
+	logf(LevelDebug, format, args...)
+}
+
+// Infof logs routine events such as requests
+func Infof(format string, args ...any) {
+	logf(LevelInfo, format, args...)
+}
+
+// Warnf logs recoverable problems
+func Warnf(format string, args ...any) {
+	logf(LevelWarn, "Warning: "+format, args...)
+}
+
+// Errorf logs failures
+func Errorf(format string, args ...any) {
+	logf(LevelError, format, args...)
+}
+
+// logf logs through the standard logger if level is enabled
+func logf(level Level, format string, args ...any) {
+	if !Enabled(level) {
+		return
+	}
+	if e := exporter.Load(); e != nil {
+		message := fmt.Sprintf(format, args...)
+		_ = e.direct.Output(3, message)
+		e.fn(Entry{Time: time.Now().UTC(), Level: level, Message: message})
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Entry is an exported log message
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+}
+
+// export sends messages to fn. direct writes this package's messages to the
+// original output, bypassing the export of standard logger lines.
+type export struct {
+	fn     func(Entry)
+	direct *log.Logger
+}
+
+var exporter atomic.Pointer[export]
+
+// Export sends every message logged from now on to fn as well as to the
+// standard logger's output. Messages logged directly with the standard
+// logger are exported at info level, or warn when they start with
+// "Warning: ". Call it once, before logging concurrently.
+func Export(fn func(Entry)) {
+	out := log.Writer()
+	exporter.Store(&export{fn: fn, direct: log.New(out, log.Prefix(), log.Flags())})
+	log.SetOutput(&exportWriter{out: out, fn: fn})
+}
+
+// stdTimestamp is the date and time the standard logger prepends with
+// log.LstdFlags
+const stdTimestamp = "2006/01/02 15:04:05 "
+
+// exportWriter exports the lines written by the standard logger
+type exportWriter struct {
+	out io.Writer
+	fn  func(Entry)
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSuffix(string(p), "\n")
+	if len(message) >= len(stdTimestamp) {
+		if _, err := time.Parse(stdTimestamp, message[:len(stdTimestamp)]); err == nil {
+			message = message[len(stdTimestamp):]
+		}
+	}
+	level := LevelInfo
+	if strings.HasPrefix(message, "Warning: ") {
+		level = LevelWarn
+	}
+	w.fn(Entry{Time: time.Now().UTC(), Level: level, Message: message})
+	return w.out.Write(p)
+}
@@ -0,0 +1,123 @@
+// Package metaschema checks upload metadata against a schema of required and
+// allowed keys loaded from a JSON document.
+//
+// Keys are compared in lowercase, as S3 stores them. Each key may constrain
+// its value with a regular expression that must match the whole value.
+package metaschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Key constrains one metadata key
+type Key struct {
+	Required    bool   `json:"required,omitempty"`
+	Pattern     string `json:"pattern,omitempty"` // Regular expression the whole value must match
+	Description string `json:"description,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Schema lists the metadata keys uploads may carry
+type Schema struct {
+	Keys map[string]*Key `json:"keys"`
+	// AllowUnknown accepts keys that aren't listed, without checking them
+	AllowUnknown bool `json:"allow_unknown,omitempty"`
+}
+
+// LoadFile reads and validates a schema from a JSON file
+func LoadFile(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata schema file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a JSON schema document
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid metadata schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile lowercases keys and compiles value patterns
+func (s *Schema) compile() error {
+	keys := make(map[string]*Key, len(s.Keys))
+	for name, key := range s.Keys {
+		if key == nil {
+			key = &Key{}
+		}
+		lower := strings.ToLower(name)
+		if _, dup := keys[lower]; dup {
+			return fmt.Errorf("metadata schema key %q is declared twice", lower)
+		}
+		if key.Pattern != "" {
+			pattern, err := regexp.Compile(`^(?:` + key.Pattern + `)$`)
+			if err != nil {
+				return fmt.Errorf("metadata schema key %q: invalid pattern: %w", lower, err)
+			}
+			key.pattern = pattern
+		}
+		keys[lower] = key
+	}
+	s.Keys = keys
+	return nil
+}
+
+// Check returns every way metadata breaks the schema, or nil if it matches
+func (s *Schema) Check(metadata map[string]string) []string {
+	values := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		values[strings.ToLower(k)] = v
+	}
+
+	var problems []string
+	for _, name := range s.names() {
+		key := s.Keys[name]
+		value, ok := values[name]
+		switch {
+		case !ok && key.Required:
+			problems = append(problems, fmt.Sprintf("metadata key %q is required", name))
+		case ok && key.pattern != nil && !key.pattern.MatchString(value):
+			problems = append(problems, fmt.Sprintf("metadata value for %q must match %s", name, key.Pattern))
+		}
+	}
+
+	if !s.AllowUnknown {
+		var unknown []string
+		for name := range values {
+			if _, ok := s.Keys[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			problems = append(problems, fmt.Sprintf("metadata key %q is not allowed", name))
+		}
+	}
+	return problems
+}
+
+// names returns the schema's keys in sorted order
+func (s *Schema) names() []string {
+	names := make([]string, 0, len(s.Keys))
+	for name := range s.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,214 @@
+// Package metrics provides a minimal in-process metrics registry.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Sink receives every counter and gauge update, to push metrics to a
+// backend as they happen
+type Sink interface {
+	Count(name string, labels Labels, delta float64)
+	Gauge(name string, labels Labels, value float64)
+}
+
+// Registry holds in-process counters and gauges and renders them in the
+// Prometheus text exposition format. Updates are also passed to the sinks
+// added with AddSink.
+type Registry struct {
+	mu       sync.RWMutex
+	sinks    []Sink
+	help     map[string]string
+	kinds    map[string]string
+	counters map[string]map[string]float64
+	gauges   map[string]map[string]float64
+	labels   map[string]Labels // Label sets by rendered key
+}
+
+// NewRegistry creates an empty metrics registry
+func NewRegistry() *Registry {
+	return &Registry{
+		help:     make(map[string]string),
+		kinds:    make(map[string]string),
+		counters: make(map[string]map[string]float64),
+		gauges:   make(map[string]map[string]float64),
+		labels:   make(map[string]Labels),
+	}
+}
+
+// Labels is a set of label name/value pairs attached to a series
+type Labels map[string]string
+
+// IncCounter increments a counter series by one
+func (r *Registry) IncCounter(name string, labels Labels) {
+	r.AddCounter(name, labels, 1)
+}
+
+// AddCounter adds delta to a counter series
+func (r *Registry) AddCounter(name string, labels Labels, delta float64) {
+	key := labelKey(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sink := range r.sinks {
+		sink.Count(name, labels, delta)
+	}
+	r.kinds[name] = "counter"
+	r.remember(key, labels)
+	series, ok := r.counters[name]
+	if !ok {
+		series = make(map[string]float64)
+		r.counters[name] = series
+	}
+	series[key] += delta
+}
+
+// SetGauge sets a gauge series to value
+func (r *Registry) SetGauge(name string, labels Labels, value float64) {
+	key := labelKey(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sink := range r.sinks {
+		sink.Gauge(name, labels, value)
+	}
+	r.kinds[name] = "gauge"
+	r.remember(key, labels)
+	series, ok := r.gauges[name]
+	if !ok {
+		series = make(map[string]float64)
+		r.gauges[name] = series
+	}
+	series[key] = value
+}
+
+// AddSink passes every later update to sink. Sinks must not block.
+func (r *Registry) AddSink(sink Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks = append(r.sinks, sink)
+}
+
+// Describe registers the help text shown for a metric
+func (r *Registry) Describe(name, help string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.help[name] = help
+}
+
+// ServeHTTP renders all metrics in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(r.Render()))
+}
+
+// Render returns all metrics in the Prometheus text format
+func (r *Registry) Render() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.kinds))
+	for name := range r.kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if help, ok := r.help[name]; ok {
+			fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		}
+		kind := r.kinds[name]
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, kind)
+
+		series := r.counters[name]
+		if kind == "gauge" {
+			series = r.gauges[name]
+		}
+
+		keys := make([]string, 0, len(series))
+		for k := range series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s%s %g\n", name, k, series[k])
+		}
+	}
+
+	return b.String()
+}
+
+// remember keeps a copy of the label set rendered as key, for Snapshot
+func (r *Registry) remember(key string, labels Labels) {
+	if _, ok := r.labels[key]; ok || len(labels) == 0 {
+		return
+	}
+	copied := make(Labels, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	r.labels[key] = copied
+}
+
+// Sample is the value of one series at the time of a Snapshot
+type Sample struct {
+	Name   string
+	Kind   string // counter or gauge
+	Labels Labels
+	Value  float64
+}
+
+// Snapshot returns the current value of every series, sorted by name and
+// label set. Counters are cumulative since the registry was created.
+func (r *Registry) Snapshot() []Sample {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var samples []Sample
+	for name, kind := range r.kinds {
+		series := r.counters[name]
+		if kind == "gauge" {
+			series = r.gauges[name]
+		}
+		for key, value := range series {
+			samples = append(samples, Sample{Name: name, Kind: kind, Labels: r.labels[key], Value: value})
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Name != samples[j].Name {
+			return samples[i].Name < samples[j].Name
+		}
+		return labelKey(samples[i].Labels) < labelKey(samples[j].Labels)
+	})
+	return samples
+}
+
+// labelKey renders labels as a sorted Prometheus label set, e.g. {a="1",b="2"}
+func labelKey(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, k := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, value))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
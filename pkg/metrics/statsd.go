@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsdMaxPacket keeps buffered lines within one UDP datagram on common
+// networks
+const statsdMaxPacket = 1432
+
+// statsdFlushInterval bounds how long buffered lines wait
+const statsdFlushInterval = time.Second
+
+// statsdUnsafe matches characters replaced in names and tags
+var statsdUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.\-/]`)
+
+// statsdSegmentUnsafe matches characters replaced in labels folded into a
+// plain statsd name, where dots and slashes would split the hierarchy
+var statsdSegmentUnsafe = regexp.MustCompile(`[^A-Za-z0-9_\-]`)
+
+// StatsdSink pushes updates to a statsd or DogStatsD daemon over UDP (or a
+// Unix datagram socket), buffering lines into packets. DogStatsD receives
+// labels as tags; plain statsd receives them folded into the metric name as
+// name.label.value.
+type StatsdSink struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	tags      string // Constant DogStatsD tags, rendered
+
+	mu   sync.Mutex
+	buf  []byte
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStatsdSink creates a sink sending to address (host:port, or
+// unix:///path for a datagram socket), prefixing names with prefix and a
+// dot. tags (key:value) are added to every DogStatsD metric.
+func NewStatsdSink(address, prefix string, dogstatsd bool, tags []string) (*StatsdSink, error) {
+	network := "udp"
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		network, address = "unixgram", path
+	}
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	s := &StatsdSink{
+		conn:      conn,
+		prefix:    prefix,
+		dogstatsd: dogstatsd,
+		tags:      strings.Join(tags, ","),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Count sends a counter increment
+func (s *StatsdSink) Count(name string, labels Labels, delta float64) {
+	s.write(s.line(name, labels, delta, "c"))
+}
+
+// Gauge sends a gauge value. Plain statsd reads a signed value as a change,
+// so a negative gauge is sent as 0 followed by the value.
+func (s *StatsdSink) Gauge(name string, labels Labels, value float64) {
+	if value < 0 && !s.dogstatsd {
+		s.write(s.line(name, labels, 0, "g"))
+	}
+	s.write(s.line(name, labels, value, "g"))
+}
+
+// line renders an update as name:value|type[|#tags]
+func (s *StatsdSink) line(name string, labels Labels, value float64, kind string) string {
+	var b strings.Builder
+	if s.prefix != "" {
+		b.WriteString(s.prefix)
+		b.WriteByte('.')
+	}
+	b.WriteString(statsdUnsafe.ReplaceAllString(name, "_"))
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if !s.dogstatsd {
+		for _, k := range keys {
+			b.WriteByte('.')
+			b.WriteString(statsdSegmentUnsafe.ReplaceAllString(k, "_"))
+			b.WriteByte('.')
+			b.WriteString(statsdSegmentUnsafe.ReplaceAllString(labels[k], "_"))
+		}
+	}
+
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(kind)
+
+	if s.dogstatsd && (len(keys) > 0 || s.tags != "") {
+		b.WriteString("|#")
+		b.WriteString(s.tags)
+		for i, k := range keys {
+			if i > 0 || s.tags != "" {
+				b.WriteByte(',')
+			}
+			b.WriteString(statsdUnsafe.ReplaceAllString(k, "_"))
+			b.WriteByte(':')
+			b.WriteString(statsdUnsafe.ReplaceAllString(labels[k], "_"))
+		}
+	}
+	return b.String()
+}
+
+// write buffers a line, sending the buffer first when the line doesn't fit
+func (s *StatsdSink) write(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > statsdMaxPacket {
+		s.flushLocked()
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+}
+
+// flushLocked sends the buffer. Send errors are ignored: statsd is lossy by
+// design and a missing daemon must not affect requests.
+func (s *StatsdSink) flushLocked() {
+	if len(s.buf) == 0 {
+		return
+	}
+	_, _ = s.conn.Write(s.buf)
+	s.buf = s.buf[:0]
+}
+
+func (s *StatsdSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(statsdFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Close sends the buffered lines and closes the connection
+func (s *StatsdSink) Close() error {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+	return s.conn.Close()
+}
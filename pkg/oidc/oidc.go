@@ -0,0 +1,342 @@
+// Package oidc validates OAuth2 access tokens (JWTs) issued by an OpenID
+// Connect provider, using the provider's discovery document and JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is the leeway applied to exp and nbf checks
+const clockSkew = time.Minute
+
+// jwksMinRefresh limits JWKS refetches triggered by unknown key IDs, failed
+// ones included
+const jwksMinRefresh = time.Minute
+
+// ErrInvalidToken is wrapped by all token validation failures
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the validated claims of an access token
+type Claims struct {
+	Subject  string   `json:"sub"`
+	Issuer   string   `json:"iss"`
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Verifier validates access tokens against an OIDC provider
+type Verifier struct {
+	discoveryURL string
+	audience     string
+	httpClient   *http.Client
+	now          func() time.Time
+
+	mu          sync.Mutex
+	issuer      string
+	jwksURI     string
+	keys        map[string]crypto.PublicKey
+	refreshedAt time.Time // Last refresh attempt
+	refreshErr  error     // Error of the last refresh attempt
+}
+
+// NewVerifier creates a verifier for tokens issued by the provider at
+// discoveryURL (.../.well-known/openid-configuration) for audience, which
+// every token must name in aud. Provider metadata is fetched lazily on first
+// use.
+func NewVerifier(discoveryURL, audience string) *Verifier {
+	return &Verifier{
+		discoveryURL: discoveryURL,
+		audience:     audience,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+	}
+}
+
+// SetNow makes the verifier read the time, for token validity and JWKS
+// refreshes, from now
+func (v *Verifier) SetNow(now func() time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.now = now
+}
+
+// Verify checks the token's signature, issuer, audience and validity window
+// and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed JWT", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header: %v", ErrInvalidToken, err)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Subject   string          `json:"sub"`
+		Issuer    string          `json:"iss"`
+		Audience  json.RawMessage `json:"aud"`
+		Expiry    int64           `json:"exp"`
+		NotBefore int64           `json:"nbf"`
+		ClientID  string          `json:"client_id"`
+		AZP       string          `json:"azp"`
+		Scope     string          `json:"scope"`
+		SCP       []string        `json:"scp"`
+	}
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return nil, fmt.Errorf("%w: bad payload: %v", ErrInvalidToken, err)
+	}
+
+	v.mu.Lock()
+	issuer, now := v.issuer, v.now()
+	v.mu.Unlock()
+
+	if payload.Issuer != issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, payload.Issuer)
+	}
+	if v.audience == "" || !audienceContains(payload.Audience, v.audience) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	}
+	if payload.Expiry == 0 || now.After(time.Unix(payload.Expiry, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if payload.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(payload.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalidToken)
+	}
+
+	claims := &Claims{
+		Subject:  payload.Subject,
+		Issuer:   payload.Issuer,
+		ClientID: payload.ClientID,
+		Scopes:   payload.SCP,
+	}
+	if claims.ClientID == "" {
+		claims.ClientID = payload.AZP
+	}
+	if payload.Scope != "" {
+		claims.Scopes = append(claims.Scopes, strings.Fields(payload.Scope)...)
+	}
+
+	return claims, nil
+}
+
+// key returns the public key for kid, fetching provider metadata and the
+// JWKS when needed
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+
+	// Unknown key: the provider may have rotated keys. Refetch at most every
+	// jwksMinRefresh, so made-up key IDs or a provider outage don't turn
+	// every request into two fetches under v.mu.
+	if !v.refreshedAt.IsZero() && v.now().Sub(v.refreshedAt) < jwksMinRefresh {
+		if v.refreshErr != nil {
+			return nil, v.refreshErr
+		}
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, kid)
+	}
+	// The result is shared by later requests, so it must not depend on
+	// whether this one is cancelled
+	v.refreshedAt = v.now()
+	v.refreshErr = v.refresh(context.WithoutCancel(ctx))
+	if v.refreshErr != nil {
+		return nil, v.refreshErr
+	}
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// refresh fetches the discovery document and JWKS. Must hold v.mu.
+func (v *Verifier) refresh(ctx context.Context) error {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.discoveryURL, &discovery); err != nil {
+		return fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	if discovery.Issuer == "" || discovery.JWKSURI == "" {
+		return fmt.Errorf("OIDC discovery document is missing issuer or jwks_uri")
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	v.issuer = discovery.Issuer
+	v.jwksURI = discovery.JWKSURI
+	v.keys = keys
+	return nil
+}
+
+// getJSON fetches url and decodes the JSON response into out
+func (v *Verifier) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jwk is a JSON Web Key as published in a JWKS
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey converts an RSA or P-256 EC JWK into a Go public key
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// verifySignature checks a JWS signature for the RS256 and ES256 algorithms
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key type does not match alg %s", ErrInvalidToken, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("%w: key type does not match alg %s", ErrInvalidToken, alg)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+	}
+
+	return nil
+}
+
+// decodeSegment base64url-decodes a JWT segment into out
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// audienceContains reports whether the aud claim (string or array) includes audience
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err == nil {
+		for _, a := range many {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/oidc"
+)
+
+const audience = "signer"
+
+// provider is an OIDC provider serving a discovery document and a JWKS of
+// its current keys
+type provider struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]crypto.Signer // By key ID
+	failJWKS bool
+	fetches  int // JWKS requests
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	p := &provider{keys: make(map[string]crypto.Signer)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": p.server.URL, "jwks_uri": p.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.fetches++
+		if p.failJWKS {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		keys := []map[string]string{}
+		for kid, key := range p.keys {
+			keys = append(keys, publicJWK(kid, key.Public()))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// rotate replaces the published keys
+func (p *provider) rotate(keys map[string]crypto.Signer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+}
+
+func (p *provider) jwksFetches() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fetches
+}
+
+func (p *provider) verifier(now *time.Time) *oidc.Verifier {
+	v := oidc.NewVerifier(p.server.URL+"/.well-known/openid-configuration", audience)
+	v.SetNow(func() time.Time { return *now })
+	return v
+}
+
+// claims returns valid claims of p for now
+func (p *provider) claims(now time.Time) map[string]any {
+	return map[string]any{
+		"iss":   p.server.URL,
+		"sub":   "backup-agent",
+		"aud":   audience,
+		"exp":   now.Add(time.Hour).Unix(),
+		"azp":   "agent-client",
+		"scope": "signer:upload signer:download",
+	}
+}
+
+func publicJWK(kid string, key crypto.PublicKey) map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))}
+	}
+	panic("unsupported key")
+}
+
+// sign returns a JWT of claims signed with key under alg: RS256, ES256, none,
+// or HS256 keyed with secret
+func sign(t *testing.T, alg, kid string, key crypto.Signer, secret []byte, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case "HS256":
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newProvider(t)
+	p.rotate(map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey})
+	now := time.Now()
+
+	// with returns valid claims changed by edit
+	with := func(edit func(map[string]any)) map[string]any {
+		claims := p.claims(now)
+		edit(claims)
+		return claims
+	}
+	publicKeyJWK, _ := json.Marshal(publicJWK("rsa", rsaKey.Public()))
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", sign(t, "RS256", "rsa", rsaKey, nil, p.claims(now)), true},
+		{"ES256", sign(t, "ES256", "ec", ecKey, nil, p.claims(now)), true},
+		{"audience in a list", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["aud"] = []string{"other", audience} })), true},
+		{"within clock skew", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() })), true},
+		{"bad signature", sign(t, "RS256", "rsa", otherKey, nil, p.claims(now)), false},
+		{"key of another alg", sign(t, "RS256", "ec", rsaKey, nil, p.claims(now)), false},
+		{"wrong issuer", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["iss"] = "https://evil.example.com" })), false},
+		{"wrong audience", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["aud"] = "another-client" })), false},
+		{"no audience", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { delete(c, "aud") })), false},
+		{"expired", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() })), false},
+		{"no expiry", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { delete(c, "exp") })), false},
+		{"not yet valid", sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["nbf"] = now.Add(2 * time.Minute).Unix() })), false},
+		{"alg none", sign(t, "none", "rsa", nil, nil, p.claims(now)), false},
+		{"HS256 keyed with the public key", sign(t, "HS256", "rsa", nil, publicKeyJWK, p.claims(now)), false},
+		{"unknown key id", sign(t, "RS256", "retired", rsaKey, nil, p.claims(now)), false},
+		{"malformed", "not.a-jwt", false},
+	}
+	v := p.verifier(&now)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tc.token)
+			switch {
+			case tc.valid && err != nil:
+				t.Errorf("Verify: %v", err)
+			case !tc.valid && !errors.Is(err, oidc.ErrInvalidToken):
+				t.Errorf("Verify = %+v, %v; want ErrInvalidToken", claims, err)
+			}
+		})
+	}
+
+	claims, err := v.Verify(context.Background(), sign(t, "RS256", "rsa", rsaKey, nil, p.claims(now)))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	want := &oidc.Claims{Subject: "backup-agent", Issuer: p.server.URL, ClientID: "agent-client", Scopes: []string{"signer:upload", "signer:download"}}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("claims = %+v, want %+v", claims, want)
+	}
+
+	// Without an audience to check, no token is accepted
+	noAudience := oidc.NewVerifier(p.server.URL+"/.well-known/openid-configuration", "")
+	if _, err := noAudience.Verify(context.Background(), sign(t, "RS256", "rsa", rsaKey, nil, with(func(c map[string]any) { c["aud"] = "" }))); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("Verify without a configured audience = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newProvider(t)
+	p.rotate(map[string]crypto.Signer{"2025": oldKey})
+	now := time.Now()
+	v := p.verifier(&now)
+
+	verify := func(kid string, key crypto.Signer) error {
+		_, err := v.Verify(context.Background(), sign(t, "ES256", kid, key, nil, p.claims(now)))
+		return err
+	}
+	if err := verify("2025", oldKey); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	// A token of the new key refetches the JWKS, but at most once a minute
+	p.rotate(map[string]crypto.Signer{"2026": newKey})
+	now = now.Add(2 * time.Minute)
+	if err := verify("2026", newKey); err != nil {
+		t.Fatalf("new key after rotation: %v", err)
+	}
+	if err := verify("2025", oldKey); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("retired key = %v, want ErrInvalidToken", err)
+	}
+	if fetches := p.jwksFetches(); fetches != 2 {
+		t.Errorf("JWKS fetches = %d, want 2", fetches)
+	}
+}
+
+func TestRefreshThrottledAfterFailure(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newProvider(t)
+	p.rotate(map[string]crypto.Signer{"k1": key})
+	p.failJWKS = true
+	now := time.Now()
+	v := p.verifier(&now)
+
+	// Failed fetches are retried no more often than successful ones
+	token := sign(t, "ES256", "k1", key, nil, p.claims(now))
+	for range 5 {
+		if _, err := v.Verify(context.Background(), token); err == nil {
+			t.Fatal("Verify succeeded without a JWKS")
+		}
+	}
+	if fetches := p.jwksFetches(); fetches != 1 {
+		t.Errorf("JWKS fetches while the provider is down = %d, want 1", fetches)
+	}
+
+	p.mu.Lock()
+	p.failJWKS = false
+	p.mu.Unlock()
+	now = now.Add(2 * time.Minute)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Errorf("Verify after the provider recovered: %v", err)
+	}
+}
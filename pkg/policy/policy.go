@@ -0,0 +1,187 @@
+// Package policy decides whether a caller may presign a given operation on an
+// object, based on rules loaded from a JSON document.
+//
+// A request is denied if any deny rule matches it, allowed if any allow rule
+// matches it, and denied otherwise.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+)
+
+// Rule effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Rule matches requests by caller, operation, object key prefix, content type
+// and size. Empty fields match any value.
+type Rule struct {
+	Name         string   `json:"name,omitempty"`
+	Effect       string   `json:"effect"`
+	Subjects     []string `json:"subjects,omitempty"`      // Caller subject or client ID, "*" for any
+	Operations   []string `json:"operations,omitempty"`    // upload, download, delete
+	Prefixes     []string `json:"prefixes,omitempty"`      // Object key prefixes, e.g. "acme/inputs/"
+	ContentTypes []string `json:"content_types,omitempty"` // Media types, "image/*" matches any subtype
+	MaxSizeBytes int64    `json:"max_size_bytes,omitempty"`
+}
+
+// Policy is an ordered set of rules
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Request describes a presign request to authorize
+type Request struct {
+	Subjects    []string // Identities of the caller (subject, client ID)
+	Operation   string
+	ObjectKey   string
+	ContentType string
+	Size        int64 // Declared object size, 0 if unknown
+}
+
+// Decision is the outcome of evaluating a request
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule,omitempty"` // Name of the deciding rule, if any
+	Reason  string `json:"reason"`
+}
+
+// LoadFile reads and validates a policy from a JSON file
+func LoadFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a JSON policy document
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks rule effects and size limits
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("policy rule %d: effect must be %q or %q", i, EffectAllow, EffectDeny)
+		}
+		if rule.MaxSizeBytes < 0 {
+			return fmt.Errorf("policy rule %d: max_size_bytes must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// Evaluate decides req against the policy's rules
+func (p *Policy) Evaluate(req Request) Decision {
+	var allow *Rule
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.matches(req) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			return Decision{Allowed: false, Rule: rule.Name, Reason: "denied by rule"}
+		}
+		if allow == nil {
+			allow = rule
+		}
+	}
+
+	if allow == nil {
+		return Decision{Allowed: false, Reason: "no rule allows this request"}
+	}
+	return Decision{Allowed: true, Rule: allow.Name, Reason: "allowed by rule"}
+}
+
+// matches reports whether every condition of the rule holds for req
+func (r *Rule) matches(req Request) bool {
+	if len(r.Subjects) > 0 && !matchesSubject(r.Subjects, req.Subjects) {
+		return false
+	}
+	if len(r.Operations) > 0 && !contains(r.Operations, req.Operation) {
+		return false
+	}
+	if len(r.Prefixes) > 0 && !hasAnyPrefix(req.ObjectKey, r.Prefixes) {
+		return false
+	}
+	if len(r.ContentTypes) > 0 && !MatchesContentType(r.ContentTypes, req.ContentType) {
+		return false
+	}
+	// A size limit can only be satisfied when the size is declared
+	if r.MaxSizeBytes > 0 && (req.Size <= 0 || req.Size > r.MaxSizeBytes) {
+		return false
+	}
+	return true
+}
+
+// matchesSubject reports whether any caller identity is listed, or "*" is
+func matchesSubject(allowed, identities []string) bool {
+	for _, a := range allowed {
+		if a == "*" {
+			return true
+		}
+		for _, id := range identities {
+			if id != "" && a == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchesContentType reports whether contentType matches any pattern (exact
+// or type/*), comparing media types ignoring parameters and case
+func MatchesContentType(patterns []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mediaType {
+			return true
+		}
+		if base, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, base+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether value is in values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyPrefix reports whether key starts with any of prefixes
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
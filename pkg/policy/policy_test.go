@@ -0,0 +1,119 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	p := &policy.Policy{Rules: []policy.Rule{
+		{Name: "no-deletes-for-agents", Effect: policy.EffectDeny, Subjects: []string{"backup-agent"}, Operations: []string{"delete"}},
+		{Name: "no-secrets", Effect: policy.EffectDeny, Prefixes: []string{"acme/secrets/"}},
+		{Name: "agent-dumps", Effect: policy.EffectAllow, Subjects: []string{"backup-agent"}, Operations: []string{"upload"},
+			Prefixes: []string{"acme/inputs/"}, ContentTypes: []string{"application/gzip", "image/*"}, MaxSizeBytes: 1 << 20},
+		{Name: "anyone-reads", Effect: policy.EffectAllow, Subjects: []string{"*"}, Operations: []string{"download"}},
+		{Name: "operator-all", Effect: policy.EffectAllow, Subjects: []string{"operator"}},
+		{Name: "no-scratch", Effect: policy.EffectDeny, Prefixes: []string{"acme/tmp/"}},
+	}}
+	upload := func(edit func(*policy.Request)) policy.Request {
+		req := policy.Request{
+			Subjects:    []string{"backup-agent", "agent-client"},
+			Operation:   "upload",
+			ObjectKey:   "acme/inputs/2025-11-24/02-00-00/db.dump.gz",
+			ContentType: "application/gzip",
+			Size:        1024,
+		}
+		if edit != nil {
+			edit(&req)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		req     policy.Request
+		allowed bool
+		rule    string // Deciding rule, empty when none matched
+	}{
+		{"allow", upload(nil), true, "agent-dumps"},
+		{"subject by client ID", upload(func(r *policy.Request) { r.Subjects = []string{"", "backup-agent"} }), true, "agent-dumps"},
+		{"deny overrides allow", upload(func(r *policy.Request) { r.Subjects = []string{"operator", "backup-agent"}; r.Operation = "delete" }), false, "no-deletes-for-agents"},
+		{"deny listed before the allow", policy.Request{Subjects: []string{"operator"}, Operation: "download", ObjectKey: "acme/secrets/key.pem"}, false, "no-secrets"},
+		{"deny listed after the allow", policy.Request{Subjects: []string{"operator"}, Operation: "download", ObjectKey: "acme/tmp/a"}, false, "no-scratch"},
+		{"no match denies", upload(func(r *policy.Request) { r.ObjectKey = "globex/inputs/db.dump.gz" }), false, ""},
+		{"no identity", upload(func(r *policy.Request) { r.Subjects = nil }), false, ""},
+		{"empty identity is not a subject", upload(func(r *policy.Request) { r.Subjects = []string{""} }), false, ""},
+		{"wildcard subject", policy.Request{Subjects: []string{"someone"}, Operation: "download", ObjectKey: "acme/inputs/a"}, true, "anyone-reads"},
+		{"wildcard subject without identity", policy.Request{Operation: "download", ObjectKey: "acme/inputs/a"}, true, "anyone-reads"},
+		{"concrete subject", policy.Request{Subjects: []string{"operator"}, Operation: "delete", ObjectKey: "acme/inputs/a"}, true, "operator-all"},
+		{"other subject", policy.Request{Subjects: []string{"intruder"}, Operation: "delete", ObjectKey: "acme/inputs/a"}, false, ""},
+		{"content type wildcard", upload(func(r *policy.Request) { r.ContentType = "image/png" }), true, "agent-dumps"},
+		{"content type parameters and case", upload(func(r *policy.Request) { r.ContentType = "Application/GZIP; charset=binary" }), true, "agent-dumps"},
+		{"content type outside the wildcard", upload(func(r *policy.Request) { r.ContentType = "imagex/png" }), false, ""},
+		{"content type not listed", upload(func(r *policy.Request) { r.ContentType = "text/plain" }), false, ""},
+		{"missing content type", upload(func(r *policy.Request) { r.ContentType = "" }), false, ""},
+		{"size at the limit", upload(func(r *policy.Request) { r.Size = 1 << 20 }), true, "agent-dumps"},
+		{"size over the limit", upload(func(r *policy.Request) { r.Size = 1<<20 + 1 }), false, ""},
+		// v1 uploads don't declare a size: a size limit can't be checked, so
+		// the allow rule must not match
+		{"undeclared size fails closed", upload(func(r *policy.Request) { r.Size = 0 }), false, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := p.Evaluate(tc.req)
+			if got.Allowed != tc.allowed || got.Rule != tc.rule {
+				t.Errorf("Evaluate = %+v, want allowed %v by %q", got, tc.allowed, tc.rule)
+			}
+		})
+	}
+}
+
+func TestEvaluateEmptyPolicy(t *testing.T) {
+	if got := (&policy.Policy{}).Evaluate(policy.Request{Subjects: []string{"operator"}, Operation: "download"}); got.Allowed {
+		t.Errorf("Evaluate = %+v, want denied without rules", got)
+	}
+}
+
+func TestMatchesContentType(t *testing.T) {
+	tests := []struct {
+		patterns    []string
+		contentType string
+		want        bool
+	}{
+		{[]string{"application/gzip"}, "application/gzip", true},
+		{[]string{"Application/Gzip"}, "application/gzip", true},
+		{[]string{"image/*"}, "image/jpeg", true},
+		{[]string{"image/*"}, "image", false},
+		{[]string{"*/*"}, "text/plain", false}, // Only type/* wildcards
+		{[]string{"text/plain"}, "text/plain; charset=utf-8", true},
+		{[]string{"text/plain"}, "not a media type", false},
+		{nil, "text/plain", false},
+	}
+	for _, tc := range tests {
+		if got := policy.MatchesContentType(tc.patterns, tc.contentType); got != tc.want {
+			t.Errorf("MatchesContentType(%q, %q) = %v, want %v", tc.patterns, tc.contentType, got, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		valid    bool
+	}{
+		{"valid", `{"rules": [{"effect": "allow", "subjects": ["*"], "max_size_bytes": 10}]}`, true},
+		{"unknown effect", `{"rules": [{"effect": "permit"}]}`, false},
+		{"negative size", `{"rules": [{"effect": "allow", "max_size_bytes": -1}]}`, false},
+		{"unknown field", `{"rules": [{"effect": "allow", "subject": "*"}]}`, false},
+		{"not JSON", `rules: []`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := policy.Parse([]byte(tc.document)); (err == nil) != tc.valid {
+				t.Errorf("Parse error = %v, want valid %v", err, tc.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// Package resilience provides retry with exponential backoff, a circuit
+// breaker and a call budget for calls to external dependencies such as S3.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the dependency while the
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// String returns the lowercase name of the state
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// RetryPolicy configures exponential backoff with full jitter
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff returns the jittered delay before retry number attempt (1-based)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// Retry calls fn until it succeeds, returns a non-retryable error, or the
+// policy's attempts are exhausted. onRetry, if set, is called before each
+// retry.
+func Retry(ctx context.Context, policy RetryPolicy, retryable func(error) bool, onRetry func(attempt int, err error), fn func(ctx context.Context) error) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt == attempts || !retryable(err) {
+			return err
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+
+		timer := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+// Breaker is a consecutive-failure circuit breaker. After Threshold failures
+// in a row it opens and rejects calls for Cooldown, then lets a single trial
+// call through (half-open) to decide whether to close again.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewBreaker creates a breaker. A threshold of 0 disables it. onChange, if
+// set, is called with the new state whenever it changes.
+func NewBreaker(threshold int, cooldown time.Duration, onChange func(State)) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, onChange: onChange}
+}
+
+// State returns the current breaker state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a call may proceed. Callers that are allowed must
+// report the outcome with Record.
+func (b *Breaker) Allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(StateHalfOpen)
+		b.trial = true
+		return true
+	case StateHalfOpen:
+		// Only one trial call at a time while half-open
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record reports the outcome of an allowed call. Only failures that indicate
+// the dependency is degraded should be passed as failed.
+func (b *Breaker) Record(failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		if b.state != StateClosed {
+			b.setState(StateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.setState(StateOpen)
+	}
+}
+
+// setState changes state and notifies the listener. Must hold b.mu.
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+// BudgetError is returned when a call would exceed its window's budget and
+// can't wait for the next window
+type BudgetError struct {
+	RetryAfter time.Duration // Until the next window
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("call budget exhausted, next window in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Budget caps calls per fixed window (e.g. a minute), to stay under a rate
+// limit shared with other clients. Calls over the budget wait for the next
+// window when it starts within maxWait, and fail with *BudgetError otherwise.
+type Budget struct {
+	limit   int
+	window  time.Duration
+	maxWait time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+	used  int
+}
+
+// NewBudget creates a budget of limit calls per window. A maxWait of 0
+// rejects calls over the budget without waiting. now supplies the time
+// windows are counted in.
+func NewBudget(limit int, window, maxWait time.Duration, now func() time.Time) *Budget {
+	return &Budget{limit: limit, window: window, maxWait: maxWait, now: now}
+}
+
+// Take counts a call against the budget, waiting for the next window if
+// needed. It reports whether the call waited.
+func (b *Budget) Take(ctx context.Context) (bool, error) {
+	waited := false
+	for {
+		wait, ok := b.reserve()
+		if ok {
+			return waited, nil
+		}
+		if wait > b.maxWait {
+			return waited, &BudgetError{RetryAfter: wait}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return waited, &BudgetError{RetryAfter: wait}
+		}
+
+		waited = true
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return waited, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a call from the current window, or returns how long until
+// the next one
+func (b *Budget) reserve() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if start := now.Truncate(b.window); !start.Equal(b.start) {
+		b.start = start
+		b.used = 0
+	}
+	if b.used < b.limit {
+		b.used++
+		return 0, true
+	}
+	return b.start.Add(b.window).Sub(now), false
+}
@@ -0,0 +1,87 @@
+// Package restoredrill keeps the history of restore drills: downloads of a
+// randomly picked recent upload through a presigned URL, verified against
+// what S3 stored for it, to prove backups can actually be restored.
+package restoredrill
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// ErrNotFound is returned when a drill ID is unknown
+var ErrNotFound = errors.New("restore drill not found")
+
+// Drill triggers
+const (
+	TriggerAPI      = "api"
+	TriggerSchedule = "schedule"
+)
+
+// Drill is a recorded restore drill
+type Drill struct {
+	ID         string    `json:"drill_id"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	*service.RestoreDrillReport
+}
+
+// Store keeps the most recent drills in memory
+type Store struct {
+	mu     sync.Mutex
+	limit  int
+	drills []Drill // Oldest first
+}
+
+// NewStore creates a store keeping up to limit drills
+func NewStore(limit int) *Store {
+	return &Store{limit: limit}
+}
+
+// Record assigns the drill an ID and stores it, dropping the oldest drill
+// when the store is full
+func (s *Store) Record(d Drill) Drill {
+	d.ID = idgen.New()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drills = append(s.drills, d)
+	if len(s.drills) > s.limit {
+		s.drills = s.drills[len(s.drills)-s.limit:]
+	}
+	return d
+}
+
+// Get returns a drill
+func (s *Store) Get(id string) (Drill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.drills {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return Drill{}, ErrNotFound
+}
+
+// List returns up to limit drills of a tenant ("" for the company prefix),
+// newest first
+func (s *Store) List(tenantID string, limit int) []Drill {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	drills := []Drill{}
+	for i := len(s.drills) - 1; i >= 0 && len(drills) < limit; i-- {
+		if s.drills[i].TenantID == tenantID {
+			drills = append(drills, s.drills[i])
+		}
+	}
+	return drills
+}
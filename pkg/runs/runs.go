@@ -0,0 +1,206 @@
+// Package runs tracks backup run manifests: the set of files a client expects
+// to upload in one run and which of them have been confirmed.
+package runs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+// FileStatus is the upload state of a file listed in a run manifest
+type FileStatus string
+
+const (
+	FilePending   FileStatus = "pending"
+	FileIssued    FileStatus = "issued"
+	FileConfirmed FileStatus = "confirmed"
+)
+
+var (
+	// ErrNotFound is returned when a run ID is unknown
+	ErrNotFound = errors.New("run not found")
+	// ErrFileNotInManifest is returned when a filename isn't part of the run
+	ErrFileNotInManifest = errors.New("file is not part of the run manifest")
+)
+
+// File is the tracked state of one expected file
+type File struct {
+	Filename    string     `json:"filename"`
+	Status      FileStatus `json:"status"`
+	ObjectKey   string     `json:"object_key,omitempty"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+}
+
+// Run is a registered backup run manifest
+type Run struct {
+	ID        string    `json:"run_id"`
+	Name      string    `json:"name,omitempty"`
+	Files     []File    `json:"files"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Status summarizes the completeness of a run
+type Status struct {
+	RunID     string   `json:"run_id"`
+	Name      string   `json:"name,omitempty"`
+	Total     int      `json:"total"`
+	Confirmed int      `json:"confirmed"`
+	Missing   []string `json:"missing"`
+	Complete  bool     `json:"complete"`
+	Files     []File   `json:"files"`
+}
+
+// run is the internal mutable record behind a Run
+type run struct {
+	id        string
+	name      string
+	files     map[string]*File
+	createdAt time.Time
+}
+
+// Store keeps run manifests in memory
+type Store struct {
+	mu   sync.Mutex
+	runs map[string]*run
+}
+
+// NewStore creates an empty run store
+func NewStore() *Store {
+	return &Store{runs: make(map[string]*run)}
+}
+
+// Create registers a manifest of expected filenames. Duplicate names are
+// collapsed.
+func (s *Store) Create(name string, filenames []string) Run {
+	r := &run{
+		id:        idgen.New(),
+		name:      name,
+		files:     make(map[string]*File, len(filenames)),
+		createdAt: time.Now().UTC(),
+	}
+	for _, f := range filenames {
+		r.files[f] = &File{Filename: f, Status: FilePending}
+	}
+
+	s.mu.Lock()
+	s.runs[r.id] = r
+	s.mu.Unlock()
+
+	return r.snapshot()
+}
+
+// Get returns a run manifest
+func (s *Store) Get(id string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.runs[id]
+	if !ok {
+		return Run{}, ErrNotFound
+	}
+	return r.snapshot(), nil
+}
+
+// MarkIssued records that an upload URL was issued for a file in the run
+func (s *Store) MarkIssued(id, filename, objectKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.file(id, filename)
+	if err != nil {
+		return err
+	}
+	// A re-issued URL for an already confirmed file doesn't reset its state
+	if f.Status != FileConfirmed {
+		f.Status = FileIssued
+		f.ObjectKey = objectKey
+	}
+	return nil
+}
+
+// MarkConfirmed records that a file in the run was verified in the bucket
+func (s *Store) MarkConfirmed(id, filename, objectKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.file(id, filename)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	f.Status = FileConfirmed
+	f.ObjectKey = objectKey
+	f.ConfirmedAt = &now
+	return nil
+}
+
+// IssuedKey returns the object key issued for a file in the run, if any
+func (s *Store) IssuedKey(id, filename string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.file(id, filename)
+	if err != nil {
+		return "", err
+	}
+	return f.ObjectKey, nil
+}
+
+// Status reports which files of the run are confirmed and which are missing
+func (s *Store) Status(id string) (Status, error) {
+	run, err := s.Get(id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{
+		RunID:   run.ID,
+		Name:    run.Name,
+		Total:   len(run.Files),
+		Missing: []string{},
+		Files:   run.Files,
+	}
+	for _, f := range run.Files {
+		if f.Status == FileConfirmed {
+			status.Confirmed++
+		} else {
+			status.Missing = append(status.Missing, f.Filename)
+		}
+	}
+	status.Complete = status.Confirmed == status.Total
+
+	return status, nil
+}
+
+// file looks up a file in a run. Must hold s.mu.
+func (s *Store) file(id, filename string) (*File, error) {
+	r, ok := s.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	f, ok := r.files[filename]
+	if !ok {
+		return nil, ErrFileNotInManifest
+	}
+	return f, nil
+}
+
+// snapshot copies the run with files sorted by name
+func (r *run) snapshot() Run {
+	files := make([]File, 0, len(r.files))
+	for _, f := range r.files {
+		files = append(files, *f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Filename < files[j].Filename })
+
+	return Run{
+		ID:        r.id,
+		Name:      r.name,
+		Files:     files,
+		CreatedAt: r.createdAt,
+	}
+}
@@ -0,0 +1,39 @@
+// Package scheduler runs background jobs at fixed intervals.
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// Job is a named unit of background work
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs job every Interval in a new goroutine until ctx is cancelled.
+// The first run happens immediately. Errors are logged and don't stop the job.
+func Start(ctx context.Context, job Job) {
+	go func() {
+		ticker := time.NewTicker(job.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+				logging.Errorf("Background job %s failed: %v", job.Name, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	logging.Infof("Background job %s scheduled every %s", job.Name, job.Interval)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// maxAccessLogBytes bounds an access log object read by ReadAccessLog; S3
+// delivers logs in small objects
+const maxAccessLogBytes = 64 << 20
+
+// ListAccessLogs returns a page of the access log objects under prefix of
+// bucket (the service's bucket when empty) with keys after startAfter, in
+// key order, and whether more follow. S3 names log objects after their
+// delivery time, so key order is delivery order. Logs are read in the
+// primary region, also while failed over.
+func (s *S3Service) ListAccessLogs(ctx context.Context, bucket, prefix, startAfter string) ([]FolderEntry, bool, error) {
+	if bucket == "" {
+		bucket = s.bucketName
+	}
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if startAfter != "" {
+		input.StartAfter = aws.String(startAfter)
+	}
+
+	var page *s3.ListObjectsV2Output
+	err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+		var err error
+		page, err = s.client.ListObjectsV2(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list access logs: %w", err)
+	}
+
+	entries := make([]FolderEntry, 0, len(page.Contents))
+	for _, obj := range page.Contents {
+		entries = append(entries, FolderEntry{
+			Key:          aws.ToString(obj.Key),
+			Size:         aws.ToInt64(obj.Size),
+			ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+			LastModified: aws.ToTime(obj.LastModified).UTC(),
+		})
+	}
+	return entries, aws.ToBool(page.IsTruncated), nil
+}
+
+// ReadAccessLog reads an access log object of bucket (the service's bucket
+// when empty)
+func (s *S3Service) ReadAccessLog(ctx context.Context, bucket, key string) ([]byte, error) {
+	if bucket == "" {
+		bucket = s.bucketName
+	}
+	var data []byte
+	err := s.call(ctx, "GetObject", func(ctx context.Context) error {
+		result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+		defer result.Body.Close()
+
+		data, err = io.ReadAll(io.LimitReader(result.Body, maxAccessLogBytes+1))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access log %s: %w", key, err)
+	}
+	if len(data) > maxAccessLogBytes {
+		return nil, fmt.Errorf("access log %s is larger than %d bytes", key, maxAccessLogBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+)
+
+// assumeRoleProvider returns the credentials of roleARN, assumed with the
+// credentials of awsCfg. They are cached and refreshed once less than
+// window remains, so no URL signed with them outlives its session.
+func assumeRoleProvider(awsCfg aws.Config, cfg *config.Config, roleARN, externalID string, window time.Duration) aws.CredentialsProvider {
+	client := sts.NewFromConfig(awsCfg, func(o *sts.Options) {
+		// S3-compatible endpoints such as localstack serve STS as well
+		if cfg.S3EndpointURL != "" {
+			o.BaseEndpoint = aws.String(cfg.S3EndpointURL)
+		}
+	})
+	provider := stscreds.NewAssumeRoleProvider(client, roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = cfg.AWSRoleSessionName
+		o.Duration = time.Duration(cfg.AWSRoleDurationMinutes) * time.Minute
+		if externalID != "" {
+			o.ExternalID = aws.String(externalID)
+		}
+	})
+	return aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = window
+	})
+}
+
+// apiCredentialsWindow is how long before expiry the role credentials of API
+// clients are refreshed: unlike presigned URLs, a request only needs them
+// while it's signed
+const apiCredentialsWindow = time.Minute
+
+// CredentialsProvider returns the credentials of the AWS clients other than
+// S3's, such as CloudWatch Logs and KMS: the configured ones or, with
+// AWS_ROLE_ARN, those of the role assumed with them, as for S3
+func CredentialsProvider(cfg *config.Config) aws.CredentialsProvider {
+	static := credentials.NewStaticCredentialsProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+	if cfg.AWSRoleARN == "" {
+		return static
+	}
+	awsCfg := aws.Config{Region: cfg.AWSRegion, Credentials: static}
+	return assumeRoleProvider(awsCfg, cfg, cfg.AWSRoleARN, cfg.AWSRoleExternalID, apiCredentialsWindow)
+}
+
+// WithRoleCredentials makes ForRole sign and call S3 with the credentials
+// provide returns for a role instead of assuming it through STS
+func WithRoleCredentials(provide func(roleARN, externalID string) aws.CredentialsProvider) Option {
+	return func(s *S3Service) {
+		s.roles.newProvider = provide
+	}
+}
+
+// roleSet builds the clients and signers of the roles tenants assume, once
+// per role, so their credentials are cached and refreshed across requests
+type roleSet struct {
+	newProvider func(roleARN, externalID string) aws.CredentialsProvider
+	// newClient returns an SDK client calling S3 with the credentials, nil
+	// to keep the service's client (see WithS3Client)
+	newClient func(aws.CredentialsProvider) S3API
+	maxExpiry time.Duration // Longest URL lifetime within a role session
+
+	mu    sync.Mutex
+	roles map[roleKey]*roleClients
+}
+
+// roleKey identifies an assumed role
+type roleKey struct {
+	roleARN    string
+	externalID string
+}
+
+// roleClients are the client and signers of an assumed role
+type roleClients struct {
+	client        S3API
+	signer        *AWSSigner
+	replicaSigner *AWSSigner
+}
+
+// ForRole returns a service that calls S3 and signs URLs with the
+// credentials of roleARN, assumed with the configured credentials, so the
+// role's IAM policy bounds what its URLs can do. URLs expire within the
+// role session (see config.Config.RoleURLExpiration). An empty roleARN
+// returns s.
+func (s *S3Service) ForRole(roleARN, externalID string) *S3Service {
+	if roleARN == "" {
+		return s
+	}
+	role := s.roles.get(s, roleKey{roleARN: roleARN, externalID: externalID})
+
+	scoped := *s
+	scoped.client = role.client
+	scoped.signer = role.signer
+	scoped.replicaSigner = role.replicaSigner
+	scoped.uploadExpiry = min(s.uploadExpiry, s.roles.maxExpiry)
+	scoped.downloadExpiry = min(s.downloadExpiry, s.roles.maxExpiry)
+	return &scoped
+}
+
+// get returns the client and signers of a role, building them from those
+// of s on first use
+func (r *roleSet) get(s *S3Service, key roleKey) *roleClients {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if role, ok := r.roles[key]; ok {
+		return role
+	}
+	provider := r.newProvider(key.roleARN, key.externalID)
+	role := &roleClients{client: s.client, signer: s.signer.withCredentials(provider)}
+	if r.newClient != nil {
+		role.client = r.newClient(provider)
+	}
+	if s.replicaSigner != nil {
+		role.replicaSigner = s.replicaSigner.withCredentials(provider)
+	}
+	if r.roles == nil {
+		r.roles = make(map[roleKey]*roleClients)
+	}
+	r.roles[key] = role
+	return role
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// AWSSigner handles AWS Signature Version 4 signing
+type AWSSigner struct {
+	accessKey string
+	secretKey string
+	token     string // Session token of temporary credentials
+	region    string
+	service   string
+	logDebug  bool // Log the signing inputs of every URL
+	clock     Clock
+	endpoint  *url.URL // S3-compatible endpoint addressed path-style, nil for AWS
+	// Refreshing credentials (see SetCredentialsProvider), nil signs with
+	// the static keys
+	provider aws.CredentialsProvider
+
+	cachedKey atomic.Pointer[derivedKey]      // Last SigV4 signing key
+	sigV4AKey atomic.Pointer[derivedECDSAKey] // Last SigV4A signing key
+}
+
+// derivedKey is a SigV4 signing key and the access key and
+// date/region/service it is for
+type derivedKey struct {
+	scope string
+	key   []byte
+}
+
+// derivedECDSAKey is a SigV4A signing key and the access key it is for
+type derivedECDSAKey struct {
+	accessKey string
+	key       *ecdsa.PrivateKey
+}
+
+// credentialsTimeout bounds retrieving credentials from the provider, which
+// may call STS
+const credentialsTimeout = 10 * time.Second
+
+// SigningDebug exposes the intermediate values of a signature so mismatches
+// reported by S3 (which returns its own canonical request) can be compared.
+// It never contains the secret key or the derived signing key.
+type SigningDebug struct {
+	CanonicalRequest string   `json:"canonical_request"`
+	StringToSign     string   `json:"string_to_sign"`
+	SignedHeaders    []string `json:"signed_headers"`
+	CredentialScope  string   `json:"credential_scope"`
+}
+
+// NewAWSSigner creates a new AWS signer
+func NewAWSSigner(accessKey, secretKey, region, service string) *AWSSigner {
+	return &AWSSigner{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		service:   service,
+		clock:     systemClock{},
+	}
+}
+
+// GeneratePresignedPutURL generates a presigned URL for PUT operations
+func (s *AWSSigner) GeneratePresignedPutURL(bucket, key, contentType string, metadata map[string]string, expiration time.Duration) (string, error) {
+	return s.PresignURL("PUT", bucket, key, MetadataHeaders(metadata), nil, expiration)
+}
+
+// GeneratePresignedUploadPartURL generates a presigned URL for uploading one
+// part of a multipart upload
+func (s *AWSSigner) GeneratePresignedUploadPartURL(bucket, key, uploadID string, partNumber int, expiration time.Duration) (string, error) {
+	query := map[string]string{
+		"partNumber": fmt.Sprintf("%d", partNumber),
+		"uploadId":   uploadID,
+	}
+	return s.PresignURL("PUT", bucket, key, nil, query, expiration)
+}
+
+// MetadataHeaders converts user metadata into normalized x-amz-meta-* headers
+func MetadataHeaders(metadata map[string]string) map[string]string {
+	headers := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		headers[MetadataHeaderName(k)] = MetadataHeaderValue(v)
+	}
+	return headers
+}
+
+// MetadataHeaderName returns the x-amz-meta-* header a metadata key is
+// signed as: lowercase, with underscores replaced by hyphens (HTTP standard)
+func MetadataHeaderName(key string) string {
+	return strings.ToLower("x-amz-meta-" + strings.ReplaceAll(key, "_", "-"))
+}
+
+// MetadataHeaderValue returns a metadata value as signed: trimmed, with
+// runs of whitespace collapsed to a single space
+func MetadataHeaderValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// SetDebugLogging logs the canonical request and string to sign of every
+// presigned URL
+func (s *AWSSigner) SetDebugLogging(enabled bool) {
+	s.logDebug = enabled
+}
+
+// SetSessionToken signs URLs with the session token of temporary
+// credentials. Such URLs stop working when the credentials expire.
+func (s *AWSSigner) SetSessionToken(token string) {
+	s.token = token
+}
+
+// SetCredentialsProvider makes the signer sign with the credentials of
+// provider, such as an assumed role, instead of the static keys. The
+// provider should cache them (see aws.CredentialsCache).
+func (s *AWSSigner) SetCredentialsProvider(provider aws.CredentialsProvider) {
+	s.provider = provider
+}
+
+// withCredentials returns a signer like s that signs with the credentials
+// of provider
+func (s *AWSSigner) withCredentials(provider aws.CredentialsProvider) *AWSSigner {
+	signer := NewAWSSigner(s.accessKey, s.secretKey, s.region, s.service)
+	signer.logDebug = s.logDebug
+	signer.clock = s.clock
+	signer.endpoint = s.endpoint
+	signer.provider = provider
+	return signer
+}
+
+// credentials returns the keys to sign with
+func (s *AWSSigner) credentials() (aws.Credentials, error) {
+	if s.provider == nil {
+		return aws.Credentials{AccessKeyID: s.accessKey, SecretAccessKey: s.secretKey, SessionToken: s.token}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), credentialsTimeout)
+	defer cancel()
+	creds, err := s.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// SetEndpoint makes the signer address buckets path-style on an
+// S3-compatible endpoint such as localstack instead of AWS
+func (s *AWSSigner) SetEndpoint(endpoint *url.URL) {
+	s.endpoint = endpoint
+}
+
+// SetClock makes the signer read the signing time from clock
+func (s *AWSSigner) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// PresignURL generates a presigned URL for the given method. signedHeaders
+// are added to the signature alongside host and must be sent by the client
+// verbatim; query holds extra parameters (e.g. uploadId) that are signed and
+// included in the URL.
+func (s *AWSSigner) PresignURL(method, bucket, key string, signedHeaders map[string]string, query map[string]string, expiration time.Duration) (string, error) {
+	url, _, err := s.presign(s.clock.Now(), method, bucket, key, signedHeaders, query, expiration)
+	return url, err
+}
+
+// presign builds the presigned URL using now as the signing time and returns
+// it with the signing inputs
+func (s *AWSSigner) presign(now time.Time, method, bucket, key string, signedHeaders map[string]string, query map[string]string, expiration time.Duration) (string, *SigningDebug, error) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	// Resolve host and algorithm for the bucket or access point
+	target, err := s.resolveEndpoint(bucket)
+	if err != nil {
+		return "", nil, err
+	}
+	creds, err := s.credentials()
+	if err != nil {
+		return "", nil, err
+	}
+	host := target.host
+
+	// Access point ARNs carry their own region, Object Lambda its own service
+	region, service := s.region, s.service
+	if target.region != "" {
+		region = target.region
+	}
+	if target.service != "" {
+		service = target.service
+	}
+
+	algorithm := algorithmSigV4
+	credentialScope := dateStamp + "/" + region + "/" + service + "/aws4_request"
+	if target.sigV4A {
+		// SigV4A scopes are region-less; regions are listed in X-Amz-Region-Set
+		algorithm = algorithmSigV4A
+		credentialScope = dateStamp + "/" + service + "/aws4_request"
+	}
+
+	canonicalURI := target.pathPrefix + "/" + key
+
+	// Canonical headers: host plus caller-supplied signed headers (e.g.
+	// x-amz-meta-*), sorted by lowercase name
+	headerKeys := make([]string, 0, len(signedHeaders)+1)
+	headerKeys = append(headerKeys, "host")
+	headers := make(map[string]string, len(signedHeaders)+1)
+	headers["host"] = host
+	for k, v := range signedHeaders {
+		lower := strings.ToLower(k)
+		if _, dup := headers[lower]; !dup {
+			headerKeys = append(headerKeys, lower)
+		}
+		headers[lower] = v
+	}
+	sort.Strings(headerKeys)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range headerKeys {
+		canonicalHeaders.WriteString(k)
+		canonicalHeaders.WriteByte(':')
+		canonicalHeaders.WriteString(strings.TrimSpace(headers[k]))
+		canonicalHeaders.WriteByte('\n')
+	}
+	signedHeaderList := strings.Join(headerKeys, ";")
+
+	// Query parameters, sorted once and reused for the URL. Content-Type is
+	// not a query parameter: clients send it as a header on the PUT.
+	params := make([]queryParam, 0, len(query)+7)
+	params = append(params,
+		queryParam{"X-Amz-Algorithm", algorithm},
+		queryParam{"X-Amz-Credential", creds.AccessKeyID + "/" + credentialScope},
+		queryParam{"X-Amz-Date", amzDate},
+		queryParam{"X-Amz-Expires", strconv.Itoa(int(expiration.Seconds()))},
+		queryParam{"X-Amz-SignedHeaders", signedHeaderList},
+	)
+	if target.sigV4A {
+		params = append(params, queryParam{"X-Amz-Region-Set", sigV4ARegionSet})
+	}
+	if creds.SessionToken != "" {
+		params = append(params, queryParam{"X-Amz-Security-Token", creds.SessionToken})
+	}
+	for k, v := range query {
+		params = setQueryParam(params, k, v)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].key < params[j].key })
+
+	// Presigned URLs always use UNSIGNED-PAYLOAD as the payload hash
+	var canonicalRequest strings.Builder
+	canonicalRequest.Grow(len(method) + len(canonicalURI) + canonicalHeaders.Len() + 2*len(signedHeaderList) + 512)
+	canonicalRequest.WriteString(method)
+	canonicalRequest.WriteByte('\n')
+	canonicalRequest.WriteString(canonicalURI)
+	canonicalRequest.WriteByte('\n')
+	writeQueryString(&canonicalRequest, params, true)
+	canonicalRequest.WriteByte('\n')
+	canonicalRequest.WriteString(canonicalHeaders.String())
+	canonicalRequest.WriteByte('\n')
+	canonicalRequest.WriteString(signedHeaderList)
+	canonicalRequest.WriteString("\nUNSIGNED-PAYLOAD")
+
+	stringToSign := algorithm + "\n" + amzDate + "\n" + credentialScope + "\n" + s.hash(canonicalRequest.String())
+
+	// Calculate signature
+	var signature string
+	if target.sigV4A {
+		key, err := s.ecdsaKey(creds)
+		if err != nil {
+			return "", nil, err
+		}
+		if signature, err = signSigV4A(key, stringToSign); err != nil {
+			return "", nil, err
+		}
+	} else {
+		signingKey := s.signingKey(creds, dateStamp, region, service)
+		signature = s.hmacSHA256Hex(signingKey, stringToSign)
+	}
+
+	// Final URL with the signature in sorted position. Slashes in the
+	// credential stay unencoded to avoid double-encoding by HTTP clients.
+	i := sort.Search(len(params), func(i int) bool { return params[i].key >= "X-Amz-Signature" })
+	params = append(params, queryParam{})
+	copy(params[i+1:], params[i:])
+	params[i] = queryParam{"X-Amz-Signature", signature}
+
+	var presignedURL strings.Builder
+	presignedURL.Grow(len(host) + len(canonicalURI) + canonicalRequest.Len())
+	presignedURL.WriteString(target.urlScheme())
+	presignedURL.WriteString("://")
+	presignedURL.WriteString(host)
+	presignedURL.WriteString(canonicalURI)
+	presignedURL.WriteByte('?')
+	writeQueryString(&presignedURL, params, false)
+
+	debug := &SigningDebug{
+		CanonicalRequest: canonicalRequest.String(),
+		StringToSign:     stringToSign,
+		SignedHeaders:    headerKeys,
+		CredentialScope:  credentialScope,
+	}
+	if s.logDebug || logging.Enabled(logging.LevelDebug) {
+		LogSigningDebug(method, key, debug)
+	}
+
+	return presignedURL.String(), debug, nil
+}
+
+// LogSigningDebug logs the signing inputs of a presigned URL
+func LogSigningDebug(method, key string, debug *SigningDebug) {
+	log.Printf("Signer debug %s %s\n--- canonical request ---\n%s\n--- string to sign ---\n%s",
+		method, key, debug.CanonicalRequest, debug.StringToSign)
+}
+
+// queryParam is a presigned URL query parameter
+type queryParam struct {
+	key, value string
+}
+
+// setQueryParam sets key in params, replacing an existing value
+func setQueryParam(params []queryParam, key, value string) []queryParam {
+	for i := range params {
+		if params[i].key == key {
+			params[i].value = value
+			return params
+		}
+	}
+	return append(params, queryParam{key, value})
+}
+
+// writeQueryString writes sorted params as a query string. The canonical
+// form encodes every slash; the URL form leaves those of X-Amz-Credential.
+func writeQueryString(b *strings.Builder, params []queryParam, canonical bool) {
+	for i, p := range params {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		writeURIEncoded(b, p.key, true)
+		b.WriteByte('=')
+		writeURIEncoded(b, p.value, canonical || p.key != "X-Amz-Credential")
+	}
+}
+
+// writeURIEncoded writes input percent-encoded byte by byte as SigV4
+// requires, leaving unreserved characters (and slashes unless encodeSlash)
+// as they are
+func writeURIEncoded(b *strings.Builder, input string, encodeSlash bool) {
+	const hexDigits = "0123456789ABCDEF"
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '_' || c == '-' || c == '~' || c == '.':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xF])
+		}
+	}
+}
+
+// hash returns the SHA256 hash of the input
+func (s *AWSSigner) hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 computes HMAC-SHA256
+func (s *AWSSigner) hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// hmacSHA256Hex computes HMAC-SHA256 and returns hex string
+func (s *AWSSigner) hmacSHA256Hex(key []byte, data string) string {
+	return hex.EncodeToString(s.hmacSHA256(key, data))
+}
+
+// ecdsaKey returns the SigV4A signing key of creds, reusing the last derived
+// key until the credentials change
+func (s *AWSSigner) ecdsaKey(creds aws.Credentials) (*ecdsa.PrivateKey, error) {
+	if cached := s.sigV4AKey.Load(); cached != nil && cached.accessKey == creds.AccessKeyID {
+		return cached.key, nil
+	}
+	key, err := sigV4AKey(creds.AccessKeyID, creds.SecretAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	s.sigV4AKey.Store(&derivedECDSAKey{accessKey: creds.AccessKeyID, key: key})
+	return key, nil
+}
+
+// signingKey returns the SigV4 signing key of creds for the date and scope,
+// reusing the last derived key since it only changes daily or when the
+// credentials are refreshed
+func (s *AWSSigner) signingKey(creds aws.Credentials, dateStamp, region, service string) []byte {
+	scope := creds.AccessKeyID + "/" + dateStamp + "/" + region + "/" + service
+	if cached := s.cachedKey.Load(); cached != nil && cached.scope == scope {
+		return cached.key
+	}
+	key := s.getSignatureKey(creds.SecretAccessKey, dateStamp, region, service)
+	s.cachedKey.Store(&derivedKey{scope: scope, key: key})
+	return key
+}
+
+// getSignatureKey derives the signing key
+func (s *AWSSigner) getSignatureKey(secretKey, dateStamp, region, service string) []byte {
+	kSecret := []byte("AWS4" + secretKey)
+	kDate := s.hmacSHA256(kSecret, dateStamp)
+	kRegion := s.hmacSHA256(kDate, region)
+	kService := s.hmacSHA256(kRegion, service)
+	kSigning := s.hmacSHA256(kService, "aws4_request")
+	return kSigning
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// FolderEntry is an object directly inside a browsed folder
+type FolderEntry struct {
+	Key          string    `json:"object_key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// FolderListing is one page of the folders and objects one level below a
+// prefix
+type FolderListing struct {
+	Prefix    string        `json:"prefix"`
+	Folders   []string      `json:"folders"`
+	Objects   []FolderEntry `json:"objects"`
+	NextToken string        `json:"next_token,omitempty"` // Continuation token of the next page
+}
+
+// BrowseFolder lists one page of the common prefixes and objects directly
+// under prefix, or under the company prefix when prefix is empty. Callers
+// must check the prefix with OwnsKey.
+func (s *S3Service) BrowseFolder(ctx context.Context, prefix, token string, maxKeys int32) (*FolderListing, error) {
+	if prefix == "" {
+		prefix = s.buildObjectKey("")
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket()),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+	if maxKeys > 0 {
+		input.MaxKeys = aws.Int32(maxKeys)
+	}
+
+	page, err := s.listObjects(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder: %w", err)
+	}
+
+	listing := &FolderListing{
+		Prefix:  prefix,
+		Folders: make([]string, 0, len(page.CommonPrefixes)),
+		Objects: make([]FolderEntry, 0, len(page.Contents)),
+	}
+	for _, p := range page.CommonPrefixes {
+		listing.Folders = append(listing.Folders, aws.ToString(p.Prefix))
+	}
+	for _, obj := range page.Contents {
+		listing.Objects = append(listing.Objects, FolderEntry{
+			Key:          aws.ToString(obj.Key),
+			Size:         aws.ToInt64(obj.Size),
+			ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+			LastModified: aws.ToTime(obj.LastModified),
+		})
+	}
+	if aws.ToBool(page.IsTruncated) {
+		listing.NextToken = aws.ToString(page.NextContinuationToken)
+	}
+	return listing, nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// BucketStatus reports the security-relevant configuration of the bucket.
+// Each section is read independently; a section that can't be read carries
+// the error instead of failing the whole report.
+type BucketStatus struct {
+	Bucket            string                  `json:"bucket"`
+	Encryption        EncryptionStatus        `json:"encryption"`
+	Versioning        VersioningStatus        `json:"versioning"`
+	PublicAccessBlock PublicAccessBlockStatus `json:"public_access_block"`
+	Policy            PolicyStatus            `json:"policy"`
+	Lifecycle         LifecycleStatus         `json:"lifecycle"`
+}
+
+// EncryptionStatus describes the bucket's default encryption
+type EncryptionStatus struct {
+	Configured bool             `json:"configured"`
+	Rules      []EncryptionRule `json:"rules,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// EncryptionRule is a default server-side encryption rule
+type EncryptionRule struct {
+	Algorithm        string `json:"algorithm"`
+	KMSKeyID         string `json:"kms_key_id,omitempty"`
+	BucketKeyEnabled bool   `json:"bucket_key_enabled"`
+}
+
+// VersioningStatus describes the bucket's versioning state. Status is
+// Enabled, Suspended or Disabled (never enabled).
+type VersioningStatus struct {
+	Status    string `json:"status,omitempty"`
+	MFADelete string `json:"mfa_delete,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PublicAccessBlockStatus describes the bucket's public access block
+type PublicAccessBlockStatus struct {
+	Configured            bool   `json:"configured"`
+	BlockPublicACLs       bool   `json:"block_public_acls"`
+	IgnorePublicACLs      bool   `json:"ignore_public_acls"`
+	BlockPublicPolicy     bool   `json:"block_public_policy"`
+	RestrictPublicBuckets bool   `json:"restrict_public_buckets"`
+	Error                 string `json:"error,omitempty"`
+}
+
+// PolicyStatus reports whether the bucket policy makes the bucket public
+type PolicyStatus struct {
+	Configured bool   `json:"configured"`
+	IsPublic   bool   `json:"is_public"`
+	Error      string `json:"error,omitempty"`
+}
+
+// LifecycleStatus lists the bucket's lifecycle rules
+type LifecycleStatus struct {
+	Rules []LifecycleRule `json:"rules"`
+	Error string          `json:"error,omitempty"`
+}
+
+// LifecycleRule summarizes a lifecycle rule. Day counts are 0 when the rule
+// has no such action.
+type LifecycleRule struct {
+	ID                              string                `json:"id,omitempty"`
+	Status                          string                `json:"status"`
+	Prefix                          string                `json:"prefix,omitempty"`
+	ExpirationDays                  int32                 `json:"expiration_days,omitempty"`
+	NoncurrentVersionExpirationDays int32                 `json:"noncurrent_version_expiration_days,omitempty"`
+	AbortIncompleteMultipartDays    int32                 `json:"abort_incomplete_multipart_days,omitempty"`
+	Transitions                     []LifecycleTransition `json:"transitions,omitempty"`
+}
+
+// LifecycleTransition moves objects to another storage class after Days
+type LifecycleTransition struct {
+	Days         int32  `json:"days"`
+	StorageClass string `json:"storage_class"`
+}
+
+// BucketStatus reads the bucket's encryption, versioning, public access
+// block, policy status and lifecycle configuration
+func (s *S3Service) BucketStatus(ctx context.Context) *BucketStatus {
+	bucket := aws.String(s.bucket())
+	status := &BucketStatus{Bucket: s.bucket(), Lifecycle: LifecycleStatus{Rules: []LifecycleRule{}}}
+
+	var encryption *s3.GetBucketEncryptionOutput
+	err := s.call(ctx, "GetBucketEncryption", func(ctx context.Context) error {
+		var err error
+		encryption, err = s.api().GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: bucket})
+		return err
+	})
+	switch {
+	case isAPIError(err, "ServerSideEncryptionConfigurationNotFoundError"):
+	case err != nil:
+		status.Encryption.Error = err.Error()
+	case encryption.ServerSideEncryptionConfiguration != nil:
+		for _, rule := range encryption.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault == nil {
+				continue
+			}
+			status.Encryption.Rules = append(status.Encryption.Rules, EncryptionRule{
+				Algorithm:        string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm),
+				KMSKeyID:         aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+				BucketKeyEnabled: aws.ToBool(rule.BucketKeyEnabled),
+			})
+		}
+		status.Encryption.Configured = len(status.Encryption.Rules) > 0
+	}
+
+	var versioning *s3.GetBucketVersioningOutput
+	err = s.call(ctx, "GetBucketVersioning", func(ctx context.Context) error {
+		var err error
+		versioning, err = s.api().GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: bucket})
+		return err
+	})
+	if err != nil {
+		status.Versioning.Error = err.Error()
+	} else {
+		status.Versioning.Status = string(versioning.Status)
+		if status.Versioning.Status == "" {
+			status.Versioning.Status = "Disabled"
+		}
+		status.Versioning.MFADelete = string(versioning.MFADelete)
+	}
+
+	var publicAccess *s3.GetPublicAccessBlockOutput
+	err = s.call(ctx, "GetPublicAccessBlock", func(ctx context.Context) error {
+		var err error
+		publicAccess, err = s.api().GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: bucket})
+		return err
+	})
+	switch {
+	case isAPIError(err, "NoSuchPublicAccessBlockConfiguration"):
+	case err != nil:
+		status.PublicAccessBlock.Error = err.Error()
+	case publicAccess.PublicAccessBlockConfiguration != nil:
+		block := publicAccess.PublicAccessBlockConfiguration
+		status.PublicAccessBlock = PublicAccessBlockStatus{
+			Configured:            true,
+			BlockPublicACLs:       aws.ToBool(block.BlockPublicAcls),
+			IgnorePublicACLs:      aws.ToBool(block.IgnorePublicAcls),
+			BlockPublicPolicy:     aws.ToBool(block.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.ToBool(block.RestrictPublicBuckets),
+		}
+	}
+
+	var policy *s3.GetBucketPolicyStatusOutput
+	err = s.call(ctx, "GetBucketPolicyStatus", func(ctx context.Context) error {
+		var err error
+		policy, err = s.api().GetBucketPolicyStatus(ctx, &s3.GetBucketPolicyStatusInput{Bucket: bucket})
+		return err
+	})
+	switch {
+	case isAPIError(err, "NoSuchBucketPolicy"):
+	case err != nil:
+		status.Policy.Error = err.Error()
+	case policy.PolicyStatus != nil:
+		status.Policy = PolicyStatus{Configured: true, IsPublic: aws.ToBool(policy.PolicyStatus.IsPublic)}
+	}
+
+	var lifecycle *s3.GetBucketLifecycleConfigurationOutput
+	err = s.call(ctx, "GetBucketLifecycleConfiguration", func(ctx context.Context) error {
+		var err error
+		lifecycle, err = s.api().GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: bucket})
+		return err
+	})
+	switch {
+	case isAPIError(err, "NoSuchLifecycleConfiguration"):
+	case err != nil:
+		status.Lifecycle.Error = err.Error()
+	default:
+		for _, rule := range lifecycle.Rules {
+			status.Lifecycle.Rules = append(status.Lifecycle.Rules, lifecycleRule(rule))
+		}
+	}
+
+	return status
+}
+
+// lifecycleRule summarizes an S3 lifecycle rule
+func lifecycleRule(rule types.LifecycleRule) LifecycleRule {
+	summary := LifecycleRule{
+		ID:     aws.ToString(rule.ID),
+		Status: string(rule.Status),
+		Prefix: aws.ToString(rule.Prefix),
+	}
+	if rule.Filter != nil && rule.Filter.Prefix != nil {
+		summary.Prefix = *rule.Filter.Prefix
+	}
+	if rule.Expiration != nil {
+		summary.ExpirationDays = aws.ToInt32(rule.Expiration.Days)
+	}
+	if rule.NoncurrentVersionExpiration != nil {
+		summary.NoncurrentVersionExpirationDays = aws.ToInt32(rule.NoncurrentVersionExpiration.NoncurrentDays)
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		summary.AbortIncompleteMultipartDays = aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+	}
+	for _, transition := range rule.Transitions {
+		summary.Transitions = append(summary.Transitions, LifecycleTransition{
+			Days:         aws.ToInt32(transition.Days),
+			StorageClass: string(transition.StorageClass),
+		})
+	}
+
+	return summary
+}
+
+// isAPIError reports whether err is an S3 API error with the given code
+func isAPIError(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
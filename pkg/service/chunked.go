@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// MaxChunks is the most chunks a chunked backup may have
+const MaxChunks = 10000
+
+const (
+	// manifestSuffix is appended to a chunked backup's key to name its
+	// manifest object
+	manifestSuffix = ".manifest.json"
+	// chunkKeyFormat names chunk objects after the backup's key and the
+	// zero-padded chunk index
+	chunkKeyFormat = "%s.chunks/%05d"
+	// chunkKeyReserve is the longest suffix added to a chunked backup's key
+	chunkKeyReserve = len(manifestSuffix)
+	// maxManifestBytes bounds the manifest objects GetObject reads back
+	maxManifestBytes = 8 << 20
+)
+
+// ChunkedKeys returns the keys of a chunked backup of filename: the manifest
+// key and one object key per chunk, in order. Like UploadKey, it returns a
+// *KeyTooLongError when the keys can't be made to fit.
+func (s *S3Service) ChunkedKeys(filename string, chunks int) (string, []string, error) {
+	key, err := s.uploadKey("", filename, chunkKeyReserve)
+	if err != nil {
+		return "", nil, err
+	}
+
+	chunkKeys := make([]string, chunks)
+	for i := range chunkKeys {
+		chunkKeys[i] = fmt.Sprintf(chunkKeyFormat, key, i)
+	}
+	return key + manifestSuffix, chunkKeys, nil
+}
+
+// IsManifestKey reports whether objectKey names a chunked backup manifest
+func IsManifestKey(objectKey string) bool {
+	return strings.HasSuffix(objectKey, manifestSuffix)
+}
+
+// ManifestChunkKey returns the key ChunkedKeys gave chunk index of the backup
+// whose manifest is manifestKey
+func ManifestChunkKey(manifestKey string, index int) string {
+	return fmt.Sprintf(chunkKeyFormat, strings.TrimSuffix(manifestKey, manifestSuffix), index)
+}
+
+// PresignUploadKey generates a PUT URL for an exact object key, signing the
+// same headers as PresignUpload under the same policy
+func (s *S3Service) PresignUploadKey(objectKey string, opts UploadOptions) (*PresignedURL, error) {
+	headers := s.SignedUploadHeaders(opts)
+	if err := s.CheckSignedHeaders(headers); err != nil {
+		return nil, err
+	}
+	return s.presign(http.MethodPut, objectKey, headers, nil, opts.NotBefore)
+}
+
+// PutObject writes a small object, such as a chunked backup manifest,
+// directly from the service
+func (s *S3Service) PutObject(ctx context.Context, objectKey string, body []byte, contentType string) error {
+	err := s.call(ctx, "PutObject", func(ctx context.Context) error {
+		_, err := s.api().PutObject(ctx, &s3.PutObjectInput{
+			Bucket:              aws.String(s.bucket()),
+			Key:                 aws.String(objectKey),
+			Body:                bytes.NewReader(body),
+			ContentType:         aws.String(contentType),
+			ACL:                 types.ObjectCannedACL(s.uploadACL),
+			ExpectedBucketOwner: s.bucketOwner(),
+		})
+		return err
+	})
+	s.InvalidateListings(objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+	return nil
+}
+
+// GetObject reads a manifest object written by PutObject
+// Returns ErrObjectNotFound if the object doesn't exist
+func (s *S3Service) GetObject(ctx context.Context, objectKey string) ([]byte, error) {
+	var data []byte
+	err := s.call(ctx, "GetObject", func(ctx context.Context) error {
+		result, err := s.api().GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket()),
+			Key:    aws.String(objectKey),
+		})
+		if err != nil {
+			return err
+		}
+		defer result.Body.Close()
+
+		data, err = io.ReadAll(io.LimitReader(result.Body, maxManifestBytes+1))
+		return err
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	if len(data) > maxManifestBytes {
+		return nil, fmt.Errorf("object %s is larger than %d bytes", objectKey, maxManifestBytes)
+	}
+	return data, nil
+}
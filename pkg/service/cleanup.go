@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// AbortedUpload describes an incomplete multipart upload removed by cleanup
+type AbortedUpload struct {
+	ObjectKey string    `json:"object_key"`
+	UploadID  string    `json:"upload_id"`
+	Initiated time.Time `json:"initiated"`
+	Parts     int       `json:"parts"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// CleanupReport summarizes one multipart cleanup pass
+type CleanupReport struct {
+	Prefix         string          `json:"prefix"`
+	StartedAt      time.Time       `json:"started_at"`
+	FinishedAt     time.Time       `json:"finished_at"`
+	MaxAge         string          `json:"max_age"`
+	Scanned        int             `json:"scanned"`
+	Aborted        []AbortedUpload `json:"aborted"`
+	BytesReclaimed int64           `json:"bytes_reclaimed"`
+	Errors         []string        `json:"errors,omitempty"`
+}
+
+// searchPrefix returns the prefix under which this service's objects live
+func (s *S3Service) searchPrefix() string {
+	if s.companyPrefix == "" {
+		return "inputs/" // Search in inputs folder when no company prefix
+	}
+	return s.companyPrefix + "/"
+}
+
+// AbortStaleMultipartUploads aborts incomplete multipart uploads under the
+// company prefix that were initiated more than maxAge ago, reporting the
+// parts and bytes reclaimed. Failures on individual uploads are recorded in
+// the report rather than stopping the pass.
+func (s *S3Service) AbortStaleMultipartUploads(ctx context.Context, maxAge time.Duration) (*CleanupReport, error) {
+	report := &CleanupReport{
+		Prefix:    s.searchPrefix(),
+		StartedAt: time.Now().UTC(),
+		MaxAge:    maxAge.String(),
+		Aborted:   []AbortedUpload{},
+	}
+	cutoff := report.StartedAt.Add(-maxAge)
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(report.Prefix),
+	}
+
+	for {
+		var result *s3.ListMultipartUploadsOutput
+		err := s.call(ctx, "ListMultipartUploads", func(ctx context.Context) error {
+			var err error
+			result, err = s.api().ListMultipartUploads(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+
+		for _, upload := range result.Uploads {
+			report.Scanned++
+			initiated := aws.ToTime(upload.Initiated)
+			if initiated.After(cutoff) {
+				continue
+			}
+
+			key := aws.ToString(upload.Key)
+			uploadID := aws.ToString(upload.UploadId)
+			parts, bytes, err := s.uploadedPartsSize(ctx, key, uploadID)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+
+			if err := s.AbortMultipartUpload(ctx, key, uploadID); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", key, err))
+				continue
+			}
+
+			report.Aborted = append(report.Aborted, AbortedUpload{
+				ObjectKey: key,
+				UploadID:  uploadID,
+				Initiated: initiated,
+				Parts:     parts,
+				Bytes:     bytes,
+			})
+			report.BytesReclaimed += bytes
+		}
+
+		if !aws.ToBool(result.IsTruncated) {
+			break
+		}
+		input.KeyMarker = result.NextKeyMarker
+		input.UploadIdMarker = result.NextUploadIdMarker
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+// uploadedPartsSize counts the parts and bytes stored for a multipart upload
+func (s *S3Service) uploadedPartsSize(ctx context.Context, objectKey, uploadID string) (int, int64, error) {
+	input := &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket()),
+		Key:      aws.String(objectKey),
+		UploadId: aws.String(uploadID),
+	}
+
+	var parts int
+	var bytes int64
+	for {
+		var result *s3.ListPartsOutput
+		err := s.call(ctx, "ListParts", func(ctx context.Context) error {
+			var err error
+			result, err = s.api().ListParts(ctx, input)
+			return err
+		})
+		if err != nil {
+			return parts, bytes, fmt.Errorf("failed to list parts of %s: %w", objectKey, err)
+		}
+
+		for _, p := range result.Parts {
+			parts++
+			bytes += aws.ToInt64(p.Size)
+		}
+
+		if !aws.ToBool(result.IsTruncated) {
+			return parts, bytes, nil
+		}
+		input.PartNumberMarker = result.NextPartNumberMarker
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+// Clock supplies the signing time and the timestamp in upload keys, so tests
+// can pin them and assert exact URLs
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock reads the wall clock
+type systemClock struct{}
+
+// Now returns the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock always returns the same time
+type FixedClock time.Time
+
+// Now returns the fixed time
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// WithClock makes the service and its signer read the time from clock.
+// Combined with WithIDGenerator and a fixed seed, presigned URLs and object
+// keys are reproducible; SigV4A signatures stay randomized by ECDSA.
+func WithClock(clock Clock) Option {
+	return func(s *S3Service) {
+		s.clock = clock
+	}
+}
+
+// WithIDGenerator makes the service draw generated identifiers (canary
+// object keys, upload IDs) from ids
+func WithIDGenerator(ids idgen.Generator) Option {
+	return func(s *S3Service) {
+		s.ids = ids
+	}
+}
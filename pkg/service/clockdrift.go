@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+)
+
+// ClockDrift is the outcome of the last comparison of the local clock with
+// S3's. A drifting clock signs URLs that S3 rejects as not yet valid or
+// expired, surfacing as SignatureDoesNotMatch or AccessDenied.
+type ClockDrift struct {
+	DriftSeconds     float64   `json:"drift_seconds"` // Local minus S3 time; positive when ahead
+	ThresholdSeconds float64   `json:"threshold_seconds"`
+	Exceeded         bool      `json:"exceeded"`
+	CheckedAt        time.Time `json:"checked_at"`
+	Error            string    `json:"error,omitempty"` // Why the last check failed
+}
+
+// driftMonitor holds the last clock drift check, shared by scoped services
+type driftMonitor struct {
+	threshold time.Duration
+
+	mu   sync.Mutex
+	last *ClockDrift
+}
+
+// ClockDriftState returns the last clock drift check, or nil before the
+// first one
+func (s *S3Service) ClockDriftState() *ClockDrift {
+	s.drift.mu.Lock()
+	defer s.drift.mu.Unlock()
+
+	if s.drift.last == nil {
+		return nil
+	}
+	last := *s.drift.last
+	return &last
+}
+
+// CheckClockDrift compares the local clock with the Date header of a
+// HeadBucket response. The header has a resolution of one second, so drift
+// below that is not detected. A failed check keeps the previous drift and
+// records the error. Like the failover probes it bypasses the retry policy
+// and circuit breaker.
+func (s *S3Service) CheckClockDrift(ctx context.Context) *ClockDrift {
+	drift, err := s.measureDrift(ctx)
+
+	s.drift.mu.Lock()
+	defer s.drift.mu.Unlock()
+
+	state := ClockDrift{ThresholdSeconds: s.drift.threshold.Seconds(), CheckedAt: s.clock.Now().UTC()}
+	wasExceeded := s.drift.last != nil && s.drift.last.Exceeded
+	if err != nil {
+		if s.drift.last != nil {
+			state.DriftSeconds = s.drift.last.DriftSeconds
+			state.Exceeded = s.drift.last.Exceeded
+		}
+		state.Error = err.Error()
+		logging.Warnf("Clock drift check failed: %v", err)
+	} else {
+		state.DriftSeconds = drift.Seconds()
+		state.Exceeded = drift.Abs() > s.drift.threshold
+		s.metrics.SetGauge("clock_drift_seconds", nil, state.DriftSeconds)
+		switch {
+		case state.Exceeded:
+			logging.Warnf("Local clock is %s off S3's (threshold %s); presigned URLs may fail with SignatureDoesNotMatch", drift, s.drift.threshold)
+		case wasExceeded:
+			logging.Infof("Local clock is back within %s of S3's", s.drift.threshold)
+		}
+	}
+	s.metrics.SetGauge("clock_drift_exceeded", nil, boolGauge(state.Exceeded))
+	s.drift.last = &state
+
+	result := state
+	return &result
+}
+
+// measureDrift returns the local time minus S3's, taking the local time
+// halfway through the request
+func (s *S3Service) measureDrift(ctx context.Context) (time.Duration, error) {
+	if s.opTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opTimeout)
+		defer cancel()
+	}
+
+	start := s.clock.Now()
+	out, err := s.api().HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket())})
+	if err != nil {
+		return 0, fmt.Errorf("HeadBucket %s: %w", s.bucket(), err)
+	}
+	local := start.Add(s.clock.Now().Sub(start) / 2)
+
+	response, ok := awsmiddleware.GetRawResponse(out.ResultMetadata).(*smithyhttp.Response)
+	if !ok {
+		return 0, fmt.Errorf("HeadBucket response has no headers")
+	}
+	remote, err := http.ParseTime(response.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", response.Header.Get("Date"), err)
+	}
+	return local.Sub(remote), nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Correlation holds the client's correlation headers for a request. They are
+// sent along with every S3 call made for it, so S3 request IDs found in AWS
+// support cases can be matched with the service's own logs.
+type Correlation struct {
+	RequestID   string // X-Request-ID
+	TraceParent string // W3C traceparent
+}
+
+type correlationKey struct{}
+
+// WithCorrelation returns a context whose S3 calls carry c's headers
+func WithCorrelation(ctx context.Context, c Correlation) context.Context {
+	return context.WithValue(ctx, correlationKey{}, c)
+}
+
+// CorrelationFromContext returns the correlation headers stored in ctx
+func CorrelationFromContext(ctx context.Context) (Correlation, bool) {
+	c, ok := ctx.Value(correlationKey{}).(Correlation)
+	return c, ok
+}
+
+// addCorrelationHeaders adds the context's correlation headers to S3
+// requests. It runs in the build step, before signing.
+func addCorrelationHeaders(stack *middleware.Stack) error {
+	return stack.Build.Add(middleware.BuildMiddlewareFunc("SignerCorrelationHeaders",
+		func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+			if c, ok := CorrelationFromContext(ctx); ok {
+				if req, ok := in.Request.(*smithyhttp.Request); ok {
+					if c.RequestID != "" {
+						req.Header.Set("X-Request-ID", c.RequestID)
+					}
+					if c.TraceParent != "" {
+						req.Header.Set("traceparent", c.TraceParent)
+					}
+				}
+			}
+			return next.HandleBuild(ctx, in)
+		}), middleware.After)
+}
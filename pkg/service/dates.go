@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// dateListConcurrency bounds the per-date listings of ListDateFolders
+const dateListConcurrency = 8
+
+// DateFolder is a YYYY-MM-DD upload folder and the number of uploads (its
+// HH-MM-SS folders) it holds
+type DateFolder struct {
+	Date    string `json:"date"`
+	Uploads int    `json:"uploads"`
+}
+
+// ListDateFolders returns the date folders under the upload prefix in order,
+// limited to dates starting with datePrefix (e.g. "2025-11") when it is not
+// empty. Listings use a delimiter, so the cost grows with the number of
+// folders rather than objects.
+func (s *S3Service) ListDateFolders(ctx context.Context, datePrefix string) ([]DateFolder, error) {
+	root := s.buildObjectKey("inputs/")
+	prefixes, err := s.listFolders(ctx, root+datePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]DateFolder, 0, len(prefixes))
+	for _, p := range prefixes {
+		date := strings.TrimSuffix(strings.TrimPrefix(p, root), "/")
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			continue // Not written by this service
+		}
+		folders = append(folders, DateFolder{Date: date})
+	}
+
+	errs := make([]error, len(folders))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, dateListConcurrency)
+	for i := range folders {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			uploads, err := s.listFolders(ctx, root+folders[i].Date+"/")
+			folders[i].Uploads, errs[i] = len(uploads), err
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return folders, nil
+}
+
+// listFolders returns the common prefixes one "/" level below prefix
+func (s *S3Service) listFolders(ctx context.Context, prefix string) ([]string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket()),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+
+	var folders []string
+	for {
+		page, err := s.listObjects(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list folders: %w", err)
+		}
+
+		for _, p := range page.CommonPrefixes {
+			folders = append(folders, aws.ToString(p.Prefix))
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			return folders, nil
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+}
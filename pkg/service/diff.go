@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MaxDiffObjects bounds the objects listed per folder of a diff
+const MaxDiffObjects = 10000
+
+// ErrDiffTooLarge is returned when a compared folder holds more than
+// MaxDiffObjects objects
+var ErrDiffTooLarge = errors.New("folder holds too many objects to compare")
+
+// DiffFile is a file present in only one of two compared folders
+type DiffFile struct {
+	Path string `json:"path"` // Relative to the folder
+	FolderEntry
+}
+
+// FileChange is a file present in both compared folders with a different
+// size or ETag
+type FileChange struct {
+	Path string      `json:"path"`
+	From FolderEntry `json:"from"`
+	To   FolderEntry `json:"to"`
+}
+
+// FolderDiff lists the files added, removed and changed from one upload
+// folder to another, by path relative to each folder
+type FolderDiff struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Added     []DiffFile   `json:"added"`
+	Removed   []DiffFile   `json:"removed"`
+	Changed   []FileChange `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// DiffFolders compares two upload folders, each a YYYY-MM-DD date or a
+// YYYY-MM-DD/HH-MM-SS upload below inputs/. Files of a date folder are
+// compared by their path below the upload time, and when a day holds several
+// uploads of a path the most recent one stands for it. A file changed when
+// its size or ETag differs; multipart ETags depend on the part size, so the
+// same content uploaded with another part size shows as changed.
+func (s *S3Service) DiffFolders(ctx context.Context, from, to string) (*FolderDiff, error) {
+	fromFiles, err := s.folderFiles(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := s.folderFiles(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &FolderDiff{
+		From:    from,
+		To:      to,
+		Added:   []DiffFile{},
+		Removed: []DiffFile{},
+		Changed: []FileChange{},
+	}
+	for path, old := range fromFiles {
+		current, ok := toFiles[path]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, DiffFile{Path: path, FolderEntry: old})
+		case old.Size != current.Size || old.ETag != current.ETag:
+			diff.Changed = append(diff.Changed, FileChange{Path: path, From: old, To: current})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for path, current := range toFiles {
+		if _, ok := fromFiles[path]; !ok {
+			diff.Added = append(diff.Added, DiffFile{Path: path, FolderEntry: current})
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Path < diff.Changed[j].Path })
+	return diff, nil
+}
+
+// folderFiles lists the objects of an upload folder by path relative to it
+func (s *S3Service) folderFiles(ctx context.Context, folder string) (map[string]FolderEntry, error) {
+	prefix := s.buildObjectKey("inputs/" + folder + "/")
+	dateFolder := !strings.Contains(folder, "/")
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(prefix),
+	}
+
+	files := make(map[string]FolderEntry)
+	listed := 0
+	for {
+		page, err := s.listObjects(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list folder: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			if listed++; listed > MaxDiffObjects {
+				return nil, fmt.Errorf("%w: %s holds more than %d", ErrDiffTooLarge, folder, MaxDiffObjects)
+			}
+			entry := FolderEntry{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+				LastModified: aws.ToTime(obj.LastModified).UTC(),
+			}
+			path := strings.TrimPrefix(entry.Key, prefix)
+			if dateFolder {
+				upload, rest, ok := strings.Cut(path, "/")
+				if _, err := time.Parse("15-04-05", upload); !ok || err != nil {
+					continue // Not written by this service
+				}
+				path = rest
+			}
+			if previous, ok := files[path]; ok && !entry.LastModified.After(previous.LastModified) {
+				continue
+			}
+			files[path] = entry
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			return files, nil
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Duplicate is an existing upload matching a new one
+type Duplicate struct {
+	ObjectKey    string    `json:"object_key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// NormalizeChecksumSHA256 converts a SHA-256 digest given as hex or base64
+// into the base64 form S3 uses in x-amz-checksum-sha256
+func NormalizeChecksumSHA256(checksum string) (string, error) {
+	if len(checksum) == 64 {
+		if digest, err := hex.DecodeString(checksum); err == nil {
+			return base64.StdEncoding.EncodeToString(digest), nil
+		}
+	}
+	digest, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(digest) != 32 {
+		return "", fmt.Errorf("checksum_sha256 must be a SHA-256 digest in hex or base64")
+	}
+	return checksum, nil
+}
+
+// FindDuplicate looks for an earlier upload of filename under the company
+// prefix, returning the most recent one or nil. With a checksum (base64, see
+// NormalizeChecksumSHA256) only objects S3 stored with that SHA-256 checksum
+// match. This lists the prefix's uploads, so it costs one ListObjectsV2 call
+// per 1000 objects.
+func (s *S3Service) FindDuplicate(ctx context.Context, filename, checksum string) (*Duplicate, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(s.buildObjectKey("inputs/")),
+	}
+
+	var candidates []Duplicate
+	for {
+		var page *s3.ListObjectsV2Output
+		err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+			var err error
+			page, err = s.api().ListObjectsV2(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if path.Base(key) == filename && !strings.HasSuffix(key, "/") {
+				candidates = append(candidates, Duplicate{
+					ObjectKey:    key,
+					Size:         aws.ToInt64(obj.Size),
+					LastModified: aws.ToTime(obj.LastModified),
+				})
+			}
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].LastModified.After(candidates[j].LastModified) })
+	for i := range candidates {
+		if checksum == "" {
+			return &candidates[i], nil
+		}
+		stored, err := s.ChecksumSHA256(ctx, candidates[i].ObjectKey)
+		if err != nil {
+			return nil, err
+		}
+		if stored == checksum {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ChecksumSHA256 returns the base64 SHA-256 checksum S3 stored for an
+// object, or "" if it was uploaded without one. Returns ErrObjectNotFound if
+// the object doesn't exist.
+func (s *S3Service) ChecksumSHA256(ctx context.Context, objectKey string) (string, error) {
+	var result *s3.HeadObjectOutput
+	err := s.call(ctx, "HeadObject", func(ctx context.Context) error {
+		var err error
+		result, err = s.api().HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:       aws.String(s.bucket()),
+			Key:          aws.String(objectKey),
+			ChecksumMode: types.ChecksumModeEnabled,
+		})
+		return err
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return "", ErrObjectNotFound
+		}
+		return "", fmt.Errorf("failed to head object: %w", err)
+	}
+	// Multipart uploads report a checksum of part checksums, suffixed with
+	// the part count, which never equals a whole-object digest
+	return aws.ToString(result.ChecksumSHA256), nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// endpoint describes where and how a bucket or access point is signed for
+type endpoint struct {
+	host    string
+	region  string // Signing region, empty for the signer's region
+	service string // Signing service, empty for the signer's service
+	sigV4A  bool   // Sign with SigV4A (ECDSA) instead of SigV4 (HMAC)
+
+	scheme     string // URL scheme, empty for https
+	pathPrefix string // "/<bucket>" for path-style addressing
+}
+
+// urlScheme returns the scheme of presigned URLs for the endpoint
+func (e endpoint) urlScheme() string {
+	if e.scheme == "" {
+		return "https"
+	}
+	return e.scheme
+}
+
+// resolveEndpoint returns the endpoint for bucket, addressed path-style on
+// the configured S3-compatible endpoint if any
+func (s *AWSSigner) resolveEndpoint(bucket string) (endpoint, error) {
+	if s.endpoint == nil {
+		return resolveEndpoint(bucket, s.region)
+	}
+	return customEndpoint(s.endpoint, bucket), nil
+}
+
+// customEndpoint addresses bucket path-style on an S3-compatible endpoint
+func customEndpoint(u *url.URL, bucket string) endpoint {
+	return endpoint{host: u.Host, scheme: u.Scheme, pathPrefix: "/" + bucket}
+}
+
+// resolveEndpoint returns the endpoint for a bucket name or one of these ARNs:
+//
+//	arn:aws:s3:<region>:<account>:accesspoint/<name>                (access point)
+//	arn:aws:s3-object-lambda:<region>:<account>:accesspoint/<name>  (Object Lambda access point)
+//	arn:aws:s3::<account>:accesspoint/<alias>.mrap                  (Multi-Region Access Point)
+func resolveEndpoint(bucket, region string) (endpoint, error) {
+	if !strings.HasPrefix(bucket, "arn:") {
+		return endpoint{host: fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, region)}, nil
+	}
+
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(bucket, ":", 6)
+	if len(parts) != 6 {
+		return endpoint{}, fmt.Errorf("malformed bucket ARN %q", bucket)
+	}
+	partition, arnService, arnRegion, account := parts[1], parts[2], parts[3], parts[4]
+
+	name, ok := strings.CutPrefix(parts[5], "accesspoint/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return endpoint{}, fmt.Errorf("unsupported bucket ARN resource %q", parts[5])
+	}
+	suffix := dnsSuffix(partition)
+
+	switch {
+	case arnService == "s3" && arnRegion == "":
+		// Multi-Region Access Points have no region and sign with SigV4A
+		return endpoint{host: fmt.Sprintf("%s.accesspoint.s3-global.%s", name, suffix), sigV4A: true}, nil
+	case arnService == "s3":
+		return endpoint{
+			host:   fmt.Sprintf("%s-%s.s3-accesspoint.%s.%s", name, account, arnRegion, suffix),
+			region: arnRegion,
+		}, nil
+	case arnService == "s3-object-lambda" && arnRegion != "":
+		return endpoint{
+			host:    fmt.Sprintf("%s-%s.s3-object-lambda.%s.%s", name, account, arnRegion, suffix),
+			region:  arnRegion,
+			service: "s3-object-lambda",
+		}, nil
+	default:
+		return endpoint{}, fmt.Errorf("unsupported bucket ARN %q", bucket)
+	}
+}
+
+// dnsSuffix returns the endpoint DNS suffix of an AWS partition
+func dnsSuffix(partition string) string {
+	if partition == "aws-cn" {
+		return "amazonaws.com.cn"
+	}
+	return "amazonaws.com"
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/logging"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+)
+
+// Buckets reported in FailoverState.Active
+const (
+	BackendPrimary   = "primary"
+	BackendSecondary = "secondary"
+)
+
+// FailoverState reports which bucket serves requests and the outcome of the
+// last health checks
+type FailoverState struct {
+	Ready            bool      `json:"ready"`  // The active bucket is healthy, or its failures are below the threshold
+	Active           string    `json:"active"` // primary or secondary
+	PrimaryHealthy   bool      `json:"primary_healthy"`
+	SecondaryHealthy bool      `json:"secondary_healthy"`
+	PrimaryFailures  int       `json:"primary_failures"`  // Consecutive failed checks
+	PrimarySuccesses int       `json:"primary_successes"` // Consecutive passed checks
+	LastError        string    `json:"last_error,omitempty"`
+	Since            time.Time `json:"since,omitzero"` // Last switch between buckets
+	CheckedAt        time.Time `json:"checked_at,omitzero"`
+}
+
+// failover routes S3 calls and presigning to the DR bucket while the primary
+// is failing. It is shared by the tenant-scoped copies of a service.
+type failover struct {
+	client    S3API               // SDK client for the DR region
+	breaker   *resilience.Breaker // Kept apart so primary failures don't block the secondary
+	threshold int                 // Consecutive checks needed to switch either way
+
+	secondary atomic.Bool
+	mu        sync.Mutex
+	state     FailoverState
+}
+
+// WithSecondaryS3Client makes failover use client for the DR bucket instead
+// of the SDK client built from the configuration
+func WithSecondaryS3Client(client S3API) Option {
+	return func(s *S3Service) {
+		if s.failover != nil {
+			s.failover.client = client
+		}
+	}
+}
+
+// api returns the client of the bucket serving requests
+func (s *S3Service) api() S3API {
+	if s.onSecondary() {
+		return s.failover.client
+	}
+	return s.client
+}
+
+// bucket returns the name of the bucket serving requests
+func (s *S3Service) bucket() string {
+	if s.onSecondary() {
+		return s.replicaBucket
+	}
+	return s.bucketName
+}
+
+// activeSigner returns the signer for the bucket serving requests
+func (s *S3Service) activeSigner() *AWSSigner {
+	if s.onSecondary() {
+		return s.replicaSigner
+	}
+	return s.signer
+}
+
+// activeBreaker returns the circuit breaker of the bucket serving requests
+func (s *S3Service) activeBreaker() *resilience.Breaker {
+	if s.onSecondary() {
+		return s.failover.breaker
+	}
+	return s.breaker
+}
+
+// onSecondary reports whether requests are routed to the DR bucket
+func (s *S3Service) onSecondary() bool {
+	return s.failover != nil && s.failover.secondary.Load()
+}
+
+// FailoverEnabled reports whether FAILOVER_ENABLED is set
+func (s *S3Service) FailoverEnabled() bool {
+	return s.failover != nil
+}
+
+// FailoverState returns the current failover state, or nil when failover is
+// disabled
+func (s *S3Service) FailoverState() *FailoverState {
+	if s.failover == nil {
+		return nil
+	}
+	s.failover.mu.Lock()
+	defer s.failover.mu.Unlock()
+
+	state := s.failover.state
+	return &state
+}
+
+// CheckFailover probes both buckets with HeadBucket and switches to the DR
+// bucket after FAILOVER_THRESHOLD consecutive primary failures, provided the
+// DR bucket is healthy, and back after as many consecutive successes. The
+// probes bypass the retry policy and circuit breakers.
+func (s *S3Service) CheckFailover(ctx context.Context) *FailoverState {
+	f := s.failover
+	primaryErr := s.probe(ctx, s.client, s.bucketName)
+	secondaryErr := s.probe(ctx, f.client, s.replicaBucket)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := s.clock.Now().UTC()
+	f.state.CheckedAt = now
+	f.state.PrimaryHealthy = primaryErr == nil
+	f.state.SecondaryHealthy = secondaryErr == nil
+	f.state.LastError = ""
+	if primaryErr != nil {
+		f.state.PrimaryFailures++
+		f.state.PrimarySuccesses = 0
+		f.state.LastError = "primary: " + primaryErr.Error()
+	} else {
+		f.state.PrimarySuccesses++
+		f.state.PrimaryFailures = 0
+	}
+	if secondaryErr != nil && f.state.LastError == "" {
+		f.state.LastError = "secondary: " + secondaryErr.Error()
+	}
+
+	switch {
+	case !f.secondary.Load() && f.state.PrimaryFailures >= f.threshold && secondaryErr == nil:
+		f.switchTo(BackendSecondary, now)
+		s.metrics.IncCounter("s3_failovers_total", metrics.Labels{"to": BackendSecondary})
+		logging.Warnf("Failing over to DR bucket %s after %d failed checks: %v", s.replicaBucket, f.state.PrimaryFailures, primaryErr)
+	case f.secondary.Load() && f.state.PrimarySuccesses >= f.threshold:
+		f.switchTo(BackendPrimary, now)
+		s.metrics.IncCounter("s3_failovers_total", metrics.Labels{"to": BackendPrimary})
+		logging.Infof("Primary bucket %s recovered, failing back", s.bucketName)
+	}
+
+	if f.secondary.Load() {
+		f.state.Ready = secondaryErr == nil
+	} else {
+		f.state.Ready = primaryErr == nil || f.state.PrimaryFailures < f.threshold
+	}
+
+	s.metrics.SetGauge("s3_failover_active", nil, boolGauge(f.secondary.Load()))
+	s.metrics.SetGauge("s3_bucket_healthy", metrics.Labels{"bucket": BackendPrimary}, boolGauge(primaryErr == nil))
+	s.metrics.SetGauge("s3_bucket_healthy", metrics.Labels{"bucket": BackendSecondary}, boolGauge(secondaryErr == nil))
+
+	state := f.state
+	return &state
+}
+
+// switchTo routes requests to backend. It must be called with f.mu held.
+func (f *failover) switchTo(backend string, now time.Time) {
+	f.secondary.Store(backend == BackendSecondary)
+	f.state.Active = backend
+	f.state.Since = now
+}
+
+// probe checks that bucket is reachable through client within the operation
+// timeout
+func (s *S3Service) probe(ctx context.Context, client S3API, bucket string) error {
+	if s.opTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opTimeout)
+		defer cancel()
+	}
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return fmt.Errorf("HeadBucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// boolGauge converts a condition to a 0/1 gauge value
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Inventory exports are written under the company prefix to
+// exports/inventory/<export time>-<id>.<format>
+const (
+	inventoryFolder     = "exports/inventory/"
+	inventoryTimeLayout = "2006-01-02T15-04-05Z"
+)
+
+// ErrInventoryTooLarge is returned when a listing holds more objects than
+// the export limit
+var ErrInventoryTooLarge = errors.New("inventory holds too many objects")
+
+// InventoryObject is one object of an inventory listing
+type InventoryObject struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	StorageClass string
+}
+
+// InventoryExportKey returns the key an inventory export is written to
+func (s *S3Service) InventoryExportKey(id, extension string) string {
+	stamp := s.clock.Now().UTC().Format(inventoryTimeLayout)
+	return s.buildObjectKey(inventoryFolder + stamp + "-" + id + "." + extension)
+}
+
+// IsInventoryExportKey reports whether objectKey is an inventory export of
+// the company prefix
+func (s *S3Service) IsInventoryExportKey(objectKey string) bool {
+	return strings.HasPrefix(objectKey, s.buildObjectKey(inventoryFolder))
+}
+
+// ListInventory lists every object under prefix (default the company prefix)
+// last modified within [from, to), in key order. A zero from or to leaves
+// that end open. Trashed objects and earlier exports are left out.
+// ErrInventoryTooLarge is returned once more than limit objects match.
+func (s *S3Service) ListInventory(ctx context.Context, prefix string, from, to time.Time, limit int) ([]InventoryObject, error) {
+	if prefix == "" {
+		prefix = s.buildObjectKey("")
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(prefix),
+	}
+
+	objects := []InventoryObject{}
+	for {
+		var page *s3.ListObjectsV2Output
+		err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+			var err error
+			page, err = s.api().ListObjectsV2(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			modified := aws.ToTime(obj.LastModified)
+			if s.IsTrashKey(key) || s.IsInventoryExportKey(key) {
+				continue
+			}
+			if (!from.IsZero() && modified.Before(from)) || (!to.IsZero() && !modified.Before(to)) {
+				continue
+			}
+			if len(objects) == limit {
+				return nil, fmt.Errorf("%w: more than %d", ErrInventoryTooLarge, limit)
+			}
+			objects = append(objects, InventoryObject{
+				Key:          key,
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: modified.UTC(),
+				ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+				StorageClass: string(obj.StorageClass),
+			})
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			return objects, nil
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"unicode/utf8"
+)
+
+// MaxKeyBytes is S3's limit on the UTF-8 length of an object key
+const MaxKeyBytes = 1024
+
+// Strategies for upload keys over MaxKeyBytes
+const (
+	KeyStrategyReject   = "reject"   // Refuse the upload
+	KeyStrategyTruncate = "truncate" // Shorten the filename, keeping its extension
+	KeyStrategyHash     = "hash"     // Shorten the filename and append a hash of the original
+)
+
+const (
+	// maxExtensionBytes is the longest extension kept when shortening
+	maxExtensionBytes = 16
+	// filenameHashChars is the length of the hash suffix, in hex characters
+	filenameHashChars = 16
+)
+
+// KeyTooLongError is returned when an upload key would exceed MaxKeyBytes
+type KeyTooLongError struct {
+	KeyBytes         int // Length the key would have
+	MaxFilenameBytes int // Longest filename that fits, 0 if none does
+}
+
+func (e *KeyTooLongError) Error() string {
+	if e.MaxFilenameBytes <= 0 {
+		return fmt.Sprintf("object key would be %d bytes, over the S3 limit of %d; the prefix leaves no room for a filename", e.KeyBytes, MaxKeyBytes)
+	}
+	return fmt.Sprintf("object key would be %d bytes, over the S3 limit of %d; use a filename of at most %d bytes", e.KeyBytes, MaxKeyBytes, e.MaxFilenameBytes)
+}
+
+// UploadKey returns the object key an upload of filename would get now,
+// applying the key strategy when it would exceed MaxKeyBytes. It returns a
+// *KeyTooLongError when the key can't be made to fit.
+func (s *S3Service) UploadKey(filename string) (string, error) {
+	return s.uploadKey("", filename, 0)
+}
+
+// UploadKeyIn is UploadKey for an upload placed under a client-suggested
+// subpath, returning a *SubpathError when CheckSubpath rejects it
+func (s *S3Service) UploadKeyIn(subpath, filename string) (string, error) {
+	if err := s.CheckSubpath(subpath); err != nil {
+		return "", err
+	}
+	return s.uploadKey(subpath, filename, 0)
+}
+
+// uploadKey is UploadKey for a key under subpath that will be extended by up
+// to reserve bytes, which must fit as well
+func (s *S3Service) uploadKey(subpath, filename string, reserve int) (string, error) {
+	key := s.buildObjectKey(s.buildTimestampedPath(subpath, filename))
+	if len(key)+reserve <= MaxKeyBytes {
+		return key, nil
+	}
+
+	// Bytes of the key other than the filename
+	overhead := len(key) - len(filename) + reserve
+	tooLong := &KeyTooLongError{KeyBytes: len(key) + reserve, MaxFilenameBytes: MaxKeyBytes - overhead}
+
+	var short string
+	switch s.keyStrategy {
+	case KeyStrategyTruncate:
+		short = shortenFilename(filename, MaxKeyBytes-overhead, "")
+	case KeyStrategyHash:
+		sum := sha256.Sum256([]byte(filename))
+		short = shortenFilename(filename, MaxKeyBytes-overhead, "-"+hex.EncodeToString(sum[:])[:filenameHashChars])
+	}
+	if short == "" {
+		return "", tooLong
+	}
+	return s.buildObjectKey(s.buildTimestampedPath(subpath, short)), nil
+}
+
+// shortenFilename cuts the stem of filename so that stem, suffix and
+// extension fit in limit bytes, returning "" if they can't
+func shortenFilename(filename string, limit int, suffix string) string {
+	ext := path.Ext(filename)
+	if len(ext) > maxExtensionBytes || ext == filename {
+		ext = ""
+	}
+	stem := filename[:len(filename)-len(ext)]
+
+	room := limit - len(suffix) - len(ext)
+	if room <= 0 {
+		return ""
+	}
+	if room < len(stem) {
+		// Cut at a character boundary so the key stays valid UTF-8
+		for room > 0 && !utf8.RuneStart(stem[room]) {
+			room--
+		}
+		stem = stem[:room]
+	}
+	if stem == "" {
+		return ""
+	}
+	return stem + suffix + ext
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ValidateFilenamePattern checks a FindLatest pattern, a filename or a
+// path.Match glob such as "db-*.dump.gz"
+func ValidateFilenamePattern(pattern string) error {
+	if pattern == "" || strings.Contains(pattern, "/") {
+		return fmt.Errorf("filename must be a file name or glob without /")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid filename pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// FindLatest returns the most recent upload under the company prefix whose
+// file name matches pattern (see ValidateFilenamePattern), or
+// ErrObjectNotFound. Uploads are ordered by the inputs/YYYY-MM-DD/HH-MM-SS/
+// folder of their key, then by LastModified. This lists the prefix's uploads,
+// so it costs one ListObjectsV2 call per 1000 objects.
+func (s *S3Service) FindLatest(ctx context.Context, pattern string) (*ObjectInfo, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(s.buildObjectKey("inputs/")),
+	}
+
+	var latestKey string
+	var latestUploaded, latestModified time.Time
+	for {
+		page, err := s.listObjects(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			if ok, _ := path.Match(pattern, path.Base(key)); !ok {
+				continue
+			}
+			modified := aws.ToTime(obj.LastModified)
+			uploaded, ok := s.uploadTime(key)
+			if !ok {
+				uploaded = modified
+			}
+			if latestKey == "" || uploaded.After(latestUploaded) ||
+				(uploaded.Equal(latestUploaded) && modified.After(latestModified)) {
+				latestKey, latestUploaded, latestModified = key, uploaded, modified
+			}
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	if latestKey == "" {
+		return nil, ErrObjectNotFound
+	}
+	return s.HeadObject(ctx, latestKey)
+}
+
+// uploadTime parses the upload time from a key laid out as
+// [prefix/]inputs/YYYY-MM-DD/HH-MM-SS/filename
+func (s *S3Service) uploadTime(objectKey string) (time.Time, bool) {
+	rest := strings.TrimPrefix(objectKey, s.buildObjectKey("inputs/"))
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 3 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02/15-04-05", parts[0]+"/"+parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
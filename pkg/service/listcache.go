@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+)
+
+// maxCachedListings bounds the pages kept by the listing cache
+const maxCachedListings = 1000
+
+// listCacheKey identifies a ListObjectsV2 page
+type listCacheKey struct {
+	bucket, prefix, delimiter, token, startAfter string
+	maxKeys                                      int32
+}
+
+type cachedListing struct {
+	page    *s3.ListObjectsV2Output
+	expires time.Time
+}
+
+// listCache keeps ListObjectsV2 pages for a TTL, so repeated lookups such as
+// searches during a restore don't list the bucket every time. Writes through
+// the service and confirmed uploads invalidate the pages they could change.
+type listCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu    sync.Mutex
+	pages map[listCacheKey]cachedListing
+}
+
+func newListCache(ttl time.Duration, clock Clock) *listCache {
+	return &listCache{ttl: ttl, clock: clock, pages: make(map[listCacheKey]cachedListing)}
+}
+
+func (c *listCache) get(key listCacheKey) (*s3.ListObjectsV2Output, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.pages[key]
+	if !ok || !c.clock.Now().Before(cached.expires) {
+		return nil, false
+	}
+	return cached.page, true
+}
+
+func (c *listCache) put(key listCacheKey, page *s3.ListObjectsV2Output) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if len(c.pages) >= maxCachedListings {
+		for k, cached := range c.pages {
+			if !now.Before(cached.expires) {
+				delete(c.pages, k)
+			}
+		}
+	}
+	if len(c.pages) >= maxCachedListings {
+		for k := range c.pages { // Evict an arbitrary page
+			delete(c.pages, k)
+			break
+		}
+	}
+	c.pages[key] = cachedListing{page: page, expires: now.Add(c.ttl)}
+}
+
+// invalidate drops every page whose listing could include objectKey
+func (c *listCache) invalidate(objectKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.pages {
+		if strings.HasPrefix(objectKey, k.prefix) {
+			delete(c.pages, k)
+		}
+	}
+}
+
+// listObjects runs ListObjectsV2 through the listing cache when it is
+// enabled. Callers must not modify the returned page.
+func (s *S3Service) listObjects(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	var key listCacheKey
+	if s.listings != nil {
+		key = listCacheKey{
+			bucket:     aws.ToString(input.Bucket),
+			prefix:     aws.ToString(input.Prefix),
+			delimiter:  aws.ToString(input.Delimiter),
+			token:      aws.ToString(input.ContinuationToken),
+			startAfter: aws.ToString(input.StartAfter),
+			maxKeys:    aws.ToInt32(input.MaxKeys),
+		}
+		if page, ok := s.listings.get(key); ok {
+			s.metrics.IncCounter("list_cache_requests_total", metrics.Labels{"result": "hit"})
+			return page, nil
+		}
+		s.metrics.IncCounter("list_cache_requests_total", metrics.Labels{"result": "miss"})
+	}
+
+	var page *s3.ListObjectsV2Output
+	err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+		var err error
+		page, err = s.api().ListObjectsV2(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.listings != nil {
+		s.listings.put(key, page)
+	}
+	return page, nil
+}
+
+// InvalidateListings drops cached listings that could include objectKey, for
+// objects written or deleted outside the service, such as through presigned
+// URLs
+func (s *S3Service) InvalidateListings(objectKey string) {
+	if s.listings != nil {
+		s.listings.invalidate(objectKey)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxCopyObjectBytes is the largest object a single CopyObject call can copy
+const maxCopyObjectBytes = 5 << 30
+
+// ErrObjectTooLarge is returned when an object exceeds the single-copy limit
+var ErrObjectTooLarge = errors.New("object exceeds the 5 GiB copy limit")
+
+// CopyObject copies sourceKey to destinationKey within the bucket, keeping
+// its metadata
+func (s *S3Service) CopyObject(ctx context.Context, sourceKey, destinationKey string) error {
+	err := s.call(ctx, "CopyObject", func(ctx context.Context) error {
+		_, err := s.api().CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:              aws.String(s.bucket()),
+			Key:                 aws.String(destinationKey),
+			CopySource:          aws.String(s.copySource(sourceKey)),
+			ACL:                 types.ObjectCannedACL(s.uploadACL),
+			ExpectedBucketOwner: s.bucketOwner(),
+		})
+		return err
+	})
+	s.InvalidateListings(destinationKey)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	return nil
+}
+
+// copySource formats the CopySource of a CopyObject request: bucket/key, or
+// <access point ARN>/object/key for access points
+func (s *S3Service) copySource(key string) string {
+	if strings.HasPrefix(s.bucket(), "arn:") {
+		return url.PathEscape(s.bucket() + "/object/" + key)
+	}
+	return url.PathEscape(s.bucket() + "/" + key)
+}
+
+// DeleteObject removes an object from the bucket
+func (s *S3Service) DeleteObject(ctx context.Context, objectKey string) error {
+	err := s.call(ctx, "DeleteObject", func(ctx context.Context) error {
+		_, err := s.api().DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket()),
+			Key:    aws.String(objectKey),
+		})
+		return err
+	})
+	s.InvalidateListings(objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	return nil
+}
+
+// MaxDeleteObjects is the most keys S3 DeleteObjects accepts per call
+const MaxDeleteObjects = 1000
+
+// DeleteResult is the outcome of deleting one key of a batch. Code is the S3
+// error code, or the signer's when the key was rejected before calling S3.
+type DeleteResult struct {
+	ObjectKey string `json:"object_key"`
+	Deleted   bool   `json:"deleted"`
+	Code      string `json:"code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DeleteObjects removes objects with DeleteObjects calls of up to
+// MaxDeleteObjects keys and returns a result per key, in order. An error is
+// returned only when a call fails as a whole; keys of earlier calls keep
+// their results.
+func (s *S3Service) DeleteObjects(ctx context.Context, objectKeys []string) ([]DeleteResult, error) {
+	results := make([]DeleteResult, len(objectKeys))
+	for i, key := range objectKeys {
+		results[i] = DeleteResult{ObjectKey: key}
+	}
+
+	for start := 0; start < len(objectKeys); start += MaxDeleteObjects {
+		batch := objectKeys[start:min(start+MaxDeleteObjects, len(objectKeys))]
+		ids := make([]types.ObjectIdentifier, len(batch))
+		for i, key := range batch {
+			ids[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+
+		var out *s3.DeleteObjectsOutput
+		err := s.call(ctx, "DeleteObjects", func(ctx context.Context) error {
+			var err error
+			out, err = s.api().DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(s.bucket()),
+				Delete: &types.Delete{Objects: ids},
+			})
+			return err
+		})
+		for _, key := range batch {
+			s.InvalidateListings(key)
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed to delete objects: %w", err)
+		}
+
+		index := make(map[string]int, len(batch))
+		for i, key := range batch {
+			index[key] = start + i
+		}
+		for _, deleted := range out.Deleted {
+			if i, ok := index[aws.ToString(deleted.Key)]; ok {
+				results[i].Deleted = true
+			}
+		}
+		for _, failed := range out.Errors {
+			if i, ok := index[aws.ToString(failed.Key)]; ok {
+				results[i].Code = aws.ToString(failed.Code)
+				results[i].Error = aws.ToString(failed.Message)
+			}
+		}
+	}
+	return results, nil
+}
+
+// MoveObject copies source to destinationKey, checks the copy matches the
+// source's size and then deletes the source. The source is left in place if
+// anything before the delete fails.
+func (s *S3Service) MoveObject(ctx context.Context, source *ObjectInfo, destinationKey string) (*ObjectInfo, error) {
+	if source.Size > maxCopyObjectBytes {
+		return nil, ErrObjectTooLarge
+	}
+
+	if err := s.CopyObject(ctx, source.Key, destinationKey); err != nil {
+		return nil, err
+	}
+
+	copied, err := s.HeadObject(ctx, destinationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify copied object: %w", err)
+	}
+	if copied.Size != source.Size {
+		return nil, fmt.Errorf("copied object size %d does not match source size %d", copied.Size, source.Size)
+	}
+
+	if err := s.DeleteObject(ctx, source.Key); err != nil {
+		return copied, err
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CompletedPart identifies an uploaded part of a multipart upload
+type CompletedPart struct {
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// CreateMultipartUpload starts a multipart upload under the timestamped path
+// for filename
+// Returns: (uploadID, fullObjectPath, error)
+func (s *S3Service) CreateMultipartUpload(ctx context.Context, filename, contentType, contentEncoding string, metadata map[string]string) (string, string, error) {
+	fullKey, err := s.UploadKey(filename)
+	if err != nil {
+		return "", "", err
+	}
+	// Part URLs sign no headers, so a policy requiring any rules sessions out
+	if err := s.CheckSignedHeaders(nil); err != nil {
+		return "", "", err
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:              aws.String(s.bucket()),
+		Key:                 aws.String(fullKey),
+		Metadata:            metadata,
+		ACL:                 types.ObjectCannedACL(s.uploadACL),
+		ExpectedBucketOwner: s.bucketOwner(),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+
+	var result *s3.CreateMultipartUploadOutput
+	err = s.call(ctx, "CreateMultipartUpload", func(ctx context.Context) error {
+		var err error
+		result, err = s.api().CreateMultipartUpload(ctx, input)
+		return err
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	return aws.ToString(result.UploadId), fullKey, nil
+}
+
+// GeneratePresignedUploadPartURL generates a presigned URL for one part of a
+// multipart upload
+func (s *S3Service) GeneratePresignedUploadPartURL(objectKey, uploadID string, partNumber int) (string, error) {
+	presignedURL, err := s.activeSigner().GeneratePresignedUploadPartURL(s.bucket(), objectKey, uploadID, partNumber, s.uploadExpiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned part URL: %w", err)
+	}
+	return presignedURL, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the final object
+func (s *S3Service) CompleteMultipartUpload(ctx context.Context, objectKey, uploadID string, parts []CompletedPart) error {
+	sorted := make([]CompletedPart, len(parts))
+	copy(sorted, parts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PartNumber < sorted[j].PartNumber })
+
+	completed := make([]types.CompletedPart, 0, len(sorted))
+	for _, p := range sorted {
+		completed = append(completed, types.CompletedPart{
+			PartNumber: aws.Int32(int32(p.PartNumber)),
+			ETag:       aws.String(p.ETag),
+		})
+	}
+
+	err := s.call(ctx, "CompleteMultipartUpload", func(ctx context.Context) error {
+		_, err := s.api().CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(s.bucket()),
+			Key:             aws.String(objectKey),
+			UploadId:        aws.String(uploadID),
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+		})
+		return err
+	})
+	s.InvalidateListings(objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload cancels a multipart upload and discards its parts
+func (s *S3Service) AbortMultipartUpload(ctx context.Context, objectKey, uploadID string) error {
+	err := s.call(ctx, "AbortMultipartUpload", func(ctx context.Context) error {
+		_, err := s.api().AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket()),
+			Key:      aws.String(objectKey),
+			UploadId: aws.String(uploadID),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	return nil
+}
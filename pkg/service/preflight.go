@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+)
+
+// canaryFolder holds the short-lived objects written by preflight checks
+const canaryFolder = ".signer-canary"
+
+// CheckResult is the outcome of one step of a diagnostic run
+type CheckResult struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CheckReport summarizes a diagnostic run
+type CheckReport struct {
+	OK     bool          `json:"ok"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// run executes a named check, appending its result to the report
+func (r *CheckReport) run(name string, fn func() error) bool {
+	start := time.Now()
+	err := fn()
+
+	result := CheckResult{Name: name, OK: err == nil, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	r.Checks = append(r.Checks, result)
+	r.OK = r.OK && result.OK
+
+	return result.OK
+}
+
+// canaryKey returns a unique key for a canary object under the company prefix
+func (s *S3Service) canaryKey() string {
+	return s.buildObjectKey(fmt.Sprintf("%s/%s", canaryFolder, s.ids.NewID()))
+}
+
+// Preflight verifies the bucket is reachable and the credentials can write,
+// list and delete under the configured prefix by round-tripping a canary
+// object. Results are recorded as preflight_check_ok gauges.
+func (s *S3Service) Preflight(ctx context.Context) *CheckReport {
+	report := &CheckReport{OK: true}
+	key := s.canaryKey()
+
+	report.run("head_bucket", func() error {
+		return s.call(ctx, "HeadBucket", func(ctx context.Context) error {
+			_, err := s.api().HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket())})
+			return err
+		})
+	})
+
+	written := report.run("put_object", func() error {
+		return s.call(ctx, "PutObject", func(ctx context.Context) error {
+			_, err := s.api().PutObject(ctx, &s3.PutObjectInput{
+				Bucket:              aws.String(s.bucket()),
+				Key:                 aws.String(key),
+				Body:                strings.NewReader("signer-service preflight"),
+				ContentType:         aws.String("text/plain"),
+				ACL:                 types.ObjectCannedACL(s.uploadACL),
+				ExpectedBucketOwner: s.bucketOwner(),
+			})
+			return err
+		})
+	})
+
+	report.run("list_bucket", func() error {
+		return s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+			_, err := s.api().ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+				Bucket:  aws.String(s.bucket()),
+				Prefix:  aws.String(s.searchPrefix()),
+				MaxKeys: aws.Int32(1),
+			})
+			return err
+		})
+	})
+
+	if written {
+		report.run("delete_object", func() error {
+			return s.DeleteObject(ctx, key)
+		})
+	}
+
+	s.metrics.Describe("preflight_check_ok", "Result of the startup preflight checks (1 passed, 0 failed)")
+	for _, check := range report.Checks {
+		value := 0.0
+		if check.OK {
+			value = 1
+		}
+		s.metrics.SetGauge("preflight_check_ok", metrics.Labels{"check": check.Name}, value)
+	}
+
+	return report
+}
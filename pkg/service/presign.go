@@ -0,0 +1,269 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+// PresignedURL is a signed URL together with what the client needs to use it
+type PresignedURL struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	ObjectKey string            `json:"object_key"`
+	NotBefore time.Time         `json:"not_before,omitzero"` // Signing time of a deferred URL
+	ExpiresAt time.Time         `json:"expires_at"`
+	Headers   map[string]string `json:"headers,omitempty"` // Headers that must be sent verbatim
+	Debug     *SigningDebug     `json:"-"`                 // Signing inputs, only exposed on request
+}
+
+// Limits of deferred URLs (see UploadOptions.NotBefore)
+const (
+	// MaxNotBeforeAhead is how far in the future a URL may start
+	MaxNotBeforeAhead = 7 * 24 * time.Hour
+	// NotBeforeSkew is how long before its X-Amz-Date S3 accepts a request,
+	// as allowance for client clock skew
+	NotBeforeSkew = 15 * time.Minute
+)
+
+// UploadOptions are the optional properties of a presigned upload
+type UploadOptions struct {
+	ContentType   string
+	ContentLength int64
+	Metadata      map[string]string
+	ObjectLock    *ObjectLock
+	// Base64 SHA-256 of the body (see NormalizeChecksumSHA256); S3 rejects
+	// a body that doesn't match and stores it for duplicate detection
+	ChecksumSHA256 string
+	// Encoding the body is already compressed with (see
+	// ValidContentEncoding); S3 stores it and returns it on downloads
+	ContentEncoding string
+	// Base64 MD5 of the body (see NormalizeContentMD5), signed as
+	// Content-MD5
+	ContentMD5 string
+	// Signing time for a URL meant for a later window: S3 refuses it until
+	// NotBeforeSkew before this time and its expiration counts from here.
+	// Zero signs for now.
+	NotBefore time.Time
+	// Folder under the timestamped prefix the upload is placed in (see
+	// CheckSubpath)
+	Subpath string
+	// Signs If-None-Match: * so S3 refuses the PUT with 412 Precondition
+	// Failed when the key already exists, instead of overwriting it
+	IfNoneMatch bool
+	// Base64 MD5 of an SSE-C key (see NormalizeSSECustomerKeyMD5), signed
+	// with the SSE-C algorithm so the PUT must send that key
+	SSECustomerKeyMD5 string
+}
+
+// DownloadOptions are the optional properties of a presigned download
+type DownloadOptions struct {
+	// Overrides the Content-Encoding S3 responds with: "identity" makes HTTP
+	// clients keep a compressed object's bytes as stored, "gzip" makes them
+	// decompress an object stored without the header
+	ContentEncoding string
+	// Signing time for a deferred URL, as in UploadOptions
+	NotBefore time.Time
+	// Lifetime of the URL when shorter than the configured download
+	// expiration; 0 keeps the configured one
+	Expires time.Duration
+	// Base64 MD5 of the SSE-C key the object was uploaded with, as in
+	// UploadOptions
+	SSECustomerKeyMD5 string
+}
+
+// Content encodings an upload may declare or a download may override
+const (
+	ContentEncodingGzip     = "gzip"
+	ContentEncodingIdentity = "identity" // Downloads only
+)
+
+// ValidContentEncoding reports whether an upload may declare encoding. Only
+// gzip is accepted, as the encoding every HTTP client can decode.
+func ValidContentEncoding(encoding string) bool {
+	return encoding == "" || encoding == ContentEncodingGzip
+}
+
+// ValidResponseContentEncoding reports whether a download may override its
+// Content-Encoding with encoding
+func ValidResponseContentEncoding(encoding string) bool {
+	return ValidContentEncoding(encoding) || encoding == ContentEncodingIdentity
+}
+
+// PresignUpload generates a PUT URL under the timestamped path for filename.
+// Unlike GeneratePresignedPutURL, a non-empty content type, a positive content
+// length, the checksums and Object Lock settings are signed, so the client
+// must send exactly those headers. It returns a *SignedHeadersError when they
+// break the signed header policy.
+func (s *S3Service) PresignUpload(filename string, opts UploadOptions) (*PresignedURL, error) {
+	key, err := s.UploadKeyIn(opts.Subpath, filename)
+	if err != nil {
+		return nil, err
+	}
+	headers := s.SignedUploadHeaders(opts)
+	if err := s.CheckSignedHeaders(headers); err != nil {
+		return nil, err
+	}
+	return s.presign(http.MethodPut, key, headers, nil, opts.NotBefore)
+}
+
+// NewUploadID returns a UUID correlating an issued upload URL, the logs of
+// both sides and, through injected metadata, the stored object
+func (s *S3Service) NewUploadID() string {
+	return idgen.UUID(s.ids)
+}
+
+// SignedUploadHeaders returns the headers PresignUpload signs for opts: its
+// UploadHeaders and the deployment's OwnershipHeaders
+func (s *S3Service) SignedUploadHeaders(opts UploadOptions) map[string]string {
+	headers := UploadHeaders(opts)
+	maps.Copy(headers, s.OwnershipHeaders())
+	return headers
+}
+
+// OwnershipHeaders returns the UPLOAD_ACL and S3_EXPECTED_BUCKET_OWNER
+// headers signed into every upload
+func (s *S3Service) OwnershipHeaders() map[string]string {
+	headers := make(map[string]string)
+	if s.uploadACL != "" {
+		headers["x-amz-acl"] = s.uploadACL
+	}
+	if s.expectedBucketOwner != "" {
+		headers["x-amz-expected-bucket-owner"] = s.expectedBucketOwner
+	}
+	return headers
+}
+
+// bucketOwner returns S3_EXPECTED_BUCKET_OWNER for the service's
+// own writes, nil when unset
+func (s *S3Service) bucketOwner() *string {
+	if s.expectedBucketOwner == "" {
+		return nil
+	}
+	return aws.String(s.expectedBucketOwner)
+}
+
+// UploadHeaders returns the headers opts asks an upload to sign
+func UploadHeaders(opts UploadOptions) map[string]string {
+	headers := MetadataHeaders(opts.Metadata)
+	if opts.ContentType != "" {
+		headers["content-type"] = opts.ContentType
+	}
+	if opts.ContentLength > 0 {
+		headers["content-length"] = strconv.FormatInt(opts.ContentLength, 10)
+	}
+	if opts.ChecksumSHA256 != "" {
+		headers["x-amz-checksum-sha256"] = opts.ChecksumSHA256
+	}
+	if opts.ContentEncoding != "" {
+		headers["content-encoding"] = opts.ContentEncoding
+	}
+	if opts.ContentMD5 != "" {
+		headers["content-md5"] = opts.ContentMD5
+	}
+	if opts.IfNoneMatch {
+		headers["if-none-match"] = "*"
+	}
+	if opts.SSECustomerKeyMD5 != "" {
+		maps.Copy(headers, sseCustomerHeaders(opts.SSECustomerKeyMD5))
+	}
+	if opts.ObjectLock != nil {
+		for k, v := range opts.ObjectLock.headers() {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// PresignDownload generates a GET URL for an existing object key. A content
+// encoding override is signed as the response-content-encoding parameter and
+// an SSE-C key MD5 as headers the GET must send.
+func (s *S3Service) PresignDownload(objectKey string, opts DownloadOptions) (*PresignedURL, error) {
+	var query map[string]string
+	if opts.ContentEncoding != "" {
+		query = map[string]string{"response-content-encoding": opts.ContentEncoding}
+	}
+	headers := DownloadHeaders(opts)
+	expiration := s.Expiration(http.MethodGet)
+	if opts.Expires > 0 && opts.Expires < expiration {
+		expiration = opts.Expires
+	}
+	return s.presignWith(s.activeSigner(), s.bucket(), http.MethodGet, objectKey, headers, query, opts.NotBefore, expiration)
+}
+
+// DownloadHeaders returns the headers opts asks a download to sign, nil when
+// none
+func DownloadHeaders(opts DownloadOptions) map[string]string {
+	if opts.SSECustomerKeyMD5 == "" {
+		return nil
+	}
+	return sseCustomerHeaders(opts.SSECustomerKeyMD5)
+}
+
+// PresignDelete generates a DELETE URL for an existing object key
+func (s *S3Service) PresignDelete(objectKey string) (*PresignedURL, error) {
+	return s.presign(http.MethodDelete, objectKey, nil, nil, time.Time{})
+}
+
+// Expiration returns the lifetime of URLs signed for method: the download
+// expiration for GET and POST (S3 Select only reads), the upload expiration
+// otherwise
+func (s *S3Service) Expiration(method string) time.Duration {
+	if method == http.MethodGet || method == http.MethodPost {
+		return s.downloadExpiry
+	}
+	return s.uploadExpiry
+}
+
+// presign signs method on objectKey in the primary bucket with the
+// expiration configured for the method
+func (s *S3Service) presign(method, objectKey string, headers, query map[string]string, notBefore time.Time) (*PresignedURL, error) {
+	return s.presignWith(s.activeSigner(), s.bucket(), method, objectKey, headers, query, notBefore, s.Expiration(method))
+}
+
+// PresignReplicaDownload generates a GET URL for objectKey in the DR bucket,
+// for use when the primary bucket is unavailable. It fails when no DR bucket
+// is configured.
+func (s *S3Service) PresignReplicaDownload(objectKey string) (*PresignedURL, error) {
+	if s.replicaSigner == nil {
+		return nil, fmt.Errorf("no DR bucket configured")
+	}
+	return s.presignWith(s.replicaSigner, s.replicaBucket, http.MethodGet, objectKey, nil, nil, time.Time{}, s.Expiration(http.MethodGet))
+}
+
+// HasReplica reports whether a DR bucket is configured
+func (s *S3Service) HasReplica() bool {
+	return s.replicaSigner != nil
+}
+
+// presignWith signs method on objectKey in bucket with signer, valid for
+// expiration from notBefore if set and now otherwise
+func (s *S3Service) presignWith(signer *AWSSigner, bucket, method, objectKey string, headers, query map[string]string, notBefore time.Time, expiration time.Duration) (*PresignedURL, error) {
+	signedAt := s.clock.Now().UTC().Truncate(time.Second)
+	if !notBefore.IsZero() {
+		signedAt = notBefore.UTC().Truncate(time.Second)
+	}
+	url, debug, err := signer.presign(signedAt, method, bucket, objectKey, headers, query, expiration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	presigned := &PresignedURL{
+		URL:       url,
+		Method:    method,
+		ObjectKey: objectKey,
+		ExpiresAt: signedAt.Add(expiration),
+		Headers:   headers,
+		Debug:     debug,
+	}
+	if !notBefore.IsZero() {
+		presigned.NotBefore = signedAt
+	}
+	return presigned, nil
+}
@@ -0,0 +1,294 @@
+package service_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+func TestPresignUploadIsReproducible(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+	opts := service.UploadOptions{ContentType: "application/gzip", Metadata: map[string]string{"Source": "nightly"}}
+
+	first, err := svc.PresignUpload("db.dump.gz", opts)
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	second, err := svc.PresignUpload("db.dump.gz", opts)
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	if first.URL != second.URL {
+		t.Errorf("URLs differ with a fixed clock:\n%s\n%s", first.URL, second.URL)
+	}
+
+	u, err := url.Parse(first.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	query := u.Query()
+	if got, want := query.Get("X-Amz-Date"), "20251124T143000Z"; got != want {
+		t.Errorf("X-Amz-Date = %q, want %q", got, want)
+	}
+	if got, want := query.Get("X-Amz-SignedHeaders"), "content-type;host;x-amz-meta-source"; got != want {
+		t.Errorf("X-Amz-SignedHeaders = %q, want %q", got, want)
+	}
+	if got, want := u.Path, "/acme/inputs/2025-11-24/14-30-00/db.dump.gz"; got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+	if first.Method != http.MethodPut || !first.ExpiresAt.After(testTime) {
+		t.Errorf("PresignUpload = %+v", first)
+	}
+}
+
+func TestPresignCustomEndpoint(t *testing.T) {
+	svc, _ := newTestService(t, map[string]string{"S3_ENDPOINT_URL": "http://localhost:4566/"})
+
+	presigned, err := svc.PresignUpload("db.dump", service.UploadOptions{})
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	want := "http://localhost:4566/backups/acme/inputs/2025-11-24/14-30-00/db.dump?"
+	if !strings.HasPrefix(presigned.URL, want) {
+		t.Errorf("URL = %q, want path-style on the endpoint %q", presigned.URL, want)
+	}
+}
+
+func TestUploadHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		opts service.UploadOptions
+		want map[string]string
+	}{
+		{"empty", service.UploadOptions{}, map[string]string{}},
+		{
+			"content properties",
+			service.UploadOptions{ContentType: "text/plain", ContentLength: 42, ContentEncoding: "gzip"},
+			map[string]string{"content-type": "text/plain", "content-length": "42", "content-encoding": "gzip"},
+		},
+		{
+			"checksums",
+			service.UploadOptions{ChecksumSHA256: "c2hh", ContentMD5: "bWQ1"},
+			map[string]string{"x-amz-checksum-sha256": "c2hh", "content-md5": "bWQ1"},
+		},
+		{
+			"metadata keys are lowercased",
+			service.UploadOptions{Metadata: map[string]string{"Backup-ID": "1"}},
+			map[string]string{"x-amz-meta-backup-id": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := service.UploadHeaders(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UploadHeaders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresignDownloadEncoding(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+
+	presigned, err := svc.PresignDownload("acme/inputs/db.dump.gz", service.DownloadOptions{ContentEncoding: "identity"})
+	if err != nil {
+		t.Fatalf("PresignDownload: %v", err)
+	}
+	u, _ := url.Parse(presigned.URL)
+	if got := u.Query().Get("response-content-encoding"); got != "identity" {
+		t.Errorf("response-content-encoding = %q, want identity", got)
+	}
+}
+
+func TestPresignTemporaryCredentials(t *testing.T) {
+	svc, _ := newTestService(t, map[string]string{
+		"AWS_SESSION_TOKEN":               "session/token=",
+		"DOWNLOAD_URL_EXPIRATION_MINUTES": "2880",
+	})
+
+	presigned, err := svc.PresignDownload("acme/inputs/db.dump.gz", service.DownloadOptions{})
+	if err != nil {
+		t.Fatalf("PresignDownload: %v", err)
+	}
+	u, _ := url.Parse(presigned.URL)
+	if got := u.Query().Get("X-Amz-Security-Token"); got != "session/token=" {
+		t.Errorf("X-Amz-Security-Token = %q, want the session token", got)
+	}
+	// Temporary credentials last at most 12 hours
+	if got := u.Query().Get("X-Amz-Expires"); got != "43200" {
+		t.Errorf("X-Amz-Expires = %s, want 43200", got)
+	}
+	if want := testTime.Add(12 * time.Hour); !presigned.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", presigned.ExpiresAt, want)
+	}
+}
+
+func TestPresignNotBefore(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+	notBefore := testTime.Add(6*time.Hour + 500*time.Millisecond)
+
+	presigned, err := svc.PresignUpload("db.dump", service.UploadOptions{NotBefore: notBefore})
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	u, _ := url.Parse(presigned.URL)
+	if got, want := u.Query().Get("X-Amz-Date"), "20251124T203000Z"; got != want {
+		t.Errorf("X-Amz-Date = %q, want %q", got, want)
+	}
+	if !presigned.NotBefore.Equal(notBefore.Truncate(time.Second)) {
+		t.Errorf("NotBefore = %v, want %v", presigned.NotBefore, notBefore.Truncate(time.Second))
+	}
+	if !presigned.ExpiresAt.Equal(presigned.NotBefore.Add(svc.Expiration(http.MethodPut))) {
+		t.Errorf("ExpiresAt = %v, should count from NotBefore", presigned.ExpiresAt)
+	}
+	// The object key keeps the issue time
+	if !strings.Contains(presigned.ObjectKey, "2025-11-24/14-30-00") {
+		t.Errorf("ObjectKey = %q, want the issue time", presigned.ObjectKey)
+	}
+}
+
+func TestExpirationByMethod(t *testing.T) {
+	svc, _ := newTestService(t, map[string]string{
+		"UPLOAD_URL_EXPIRATION_MINUTES":   "5",
+		"DOWNLOAD_URL_EXPIRATION_MINUTES": "120",
+	})
+
+	if got := svc.Expiration(http.MethodPut); got != 5*time.Minute {
+		t.Errorf("PUT expiration = %v, want 5m", got)
+	}
+	if got := svc.Expiration(http.MethodGet); got != 2*time.Hour {
+		t.Errorf("GET expiration = %v, want 2h", got)
+	}
+}
+
+func TestCheckSignedHeaders(t *testing.T) {
+	svc, _ := newTestService(t, map[string]string{
+		"REQUIRED_SIGNED_HEADERS": "content-type,content-md5",
+		"ALLOWED_SIGNED_HEADERS":  "content-type,content-md5,content-length,x-amz-meta-backup-*",
+	})
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		wantMissing    []string
+		wantDisallowed []string
+	}{
+		{
+			name:    "required and allowed",
+			headers: map[string]string{"content-type": "a", "content-md5": "b", "x-amz-meta-backup-id": "1"},
+		},
+		{
+			name:        "missing required",
+			headers:     map[string]string{"content-type": "a"},
+			wantMissing: []string{"content-md5"},
+		},
+		{
+			name:           "disallowed metadata",
+			headers:        map[string]string{"content-type": "a", "content-md5": "b", "x-amz-meta-owner": "x", "content-encoding": "gzip"},
+			wantDisallowed: []string{"content-encoding", "x-amz-meta-owner"},
+		},
+		{
+			name:        "nothing signed",
+			wantMissing: []string{"content-type", "content-md5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.CheckSignedHeaders(tt.headers)
+			if tt.wantMissing == nil && tt.wantDisallowed == nil {
+				if err != nil {
+					t.Fatalf("CheckSignedHeaders = %v, want nil", err)
+				}
+				return
+			}
+			var policyErr *service.SignedHeadersError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("CheckSignedHeaders = %v, want *SignedHeadersError", err)
+			}
+			if !reflect.DeepEqual(policyErr.Missing, tt.wantMissing) || !reflect.DeepEqual(policyErr.Disallowed, tt.wantDisallowed) {
+				t.Errorf("CheckSignedHeaders = missing %v disallowed %v, want %v and %v",
+					policyErr.Missing, policyErr.Disallowed, tt.wantMissing, tt.wantDisallowed)
+			}
+		})
+	}
+}
+
+func TestNormalizeDigests(t *testing.T) {
+	tests := []struct {
+		name      string
+		normalize func(string) (string, error)
+		in        string
+		want      string
+		wantErr   bool
+	}{
+		{"md5 hex", service.NormalizeContentMD5, "d41d8cd98f00b204e9800998ecf8427e", "1B2M2Y8AsgTpgAmY7PhCfg==", false},
+		{"md5 base64", service.NormalizeContentMD5, "1B2M2Y8AsgTpgAmY7PhCfg==", "1B2M2Y8AsgTpgAmY7PhCfg==", false},
+		{"md5 wrong length", service.NormalizeContentMD5, "abcd", "", true},
+		{
+			"sha256 hex", service.NormalizeChecksumSHA256,
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", false,
+		},
+		{"sha256 not a digest", service.NormalizeChecksumSHA256, "not-a-digest", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.normalize(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("normalize(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPresignForRole(t *testing.T) {
+	var assumed []string
+	svc, _ := newTestService(t, map[string]string{"DOWNLOAD_URL_EXPIRATION_MINUTES": "120"},
+		service.WithRoleCredentials(func(roleARN, externalID string) aws.CredentialsProvider {
+			assumed = append(assumed, roleARN+" "+externalID)
+			return credentials.NewStaticCredentialsProvider("ASIAROLE", "role-secret", "role-token")
+		}))
+	const role = "arn:aws:iam::111122223333:role/tenant-a"
+
+	for range 2 {
+		presigned, err := svc.ForPrefix("tenant-a").ForRole(role, "ext").PresignDownload("tenant-a/inputs/db.dump", service.DownloadOptions{})
+		if err != nil {
+			t.Fatalf("PresignDownload: %v", err)
+		}
+		u, err := url.Parse(presigned.URL)
+		if err != nil {
+			t.Fatalf("url.Parse: %v", err)
+		}
+		query := u.Query()
+		if got := query.Get("X-Amz-Credential"); !strings.HasPrefix(got, "ASIAROLE/") {
+			t.Errorf("X-Amz-Credential = %q, want the role's access key", got)
+		}
+		if got := query.Get("X-Amz-Security-Token"); got != "role-token" {
+			t.Errorf("X-Amz-Security-Token = %q, want the role's session token", got)
+		}
+		// URLs expire within the default 60 minute session, less the margin
+		if got := query.Get("X-Amz-Expires"); got != "2700" {
+			t.Errorf("X-Amz-Expires = %s, want 2700", got)
+		}
+	}
+	if want := []string{role + " ext"}; !reflect.DeepEqual(assumed, want) {
+		t.Errorf("assumed roles = %v, want %v", assumed, want)
+	}
+
+	presigned, err := svc.PresignDownload("acme/inputs/db.dump", service.DownloadOptions{})
+	if err != nil {
+		t.Fatalf("PresignDownload: %v", err)
+	}
+	u, _ := url.Parse(presigned.URL)
+	if got := u.Query().Get("X-Amz-Credential"); !strings.HasPrefix(got, "AKIDEXAMPLE/") {
+		t.Errorf("company X-Amz-Credential = %q, want the configured access key", got)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+)
+
+// retryables classifies S3 errors the same way the SDK's standard retryer does
+var retryables = retry.IsErrorRetryables(retry.DefaultRetryables)
+
+// IsRetryable reports whether err is a transient S3 failure (throttling,
+// 5xx, connection errors) worth retrying
+func IsRetryable(err error) bool {
+	return retryables.IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// call runs an S3 operation through the circuit breaker, retry policy and
+// call budget, bounded by the per-operation timeout (retries included). Only
+// transient failures count against the breaker, so missing objects or access
+// errors don't trip it. Every attempt takes a call from the budget.
+func (s *S3Service) call(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	if s.opTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opTimeout)
+		defer cancel()
+	}
+
+	breaker := s.activeBreaker()
+	if !breaker.Allow() {
+		s.metrics.IncCounter("s3_requests_total", metrics.Labels{"operation": operation, "result": "rejected"})
+		return fmt.Errorf("%s: %w", operation, resilience.ErrCircuitOpen)
+	}
+
+	onRetry := func(attempt int, err error) {
+		s.metrics.IncCounter("s3_retries_total", metrics.Labels{"operation": operation})
+	}
+
+	attempt := fn
+	if s.budget != nil {
+		attempt = func(ctx context.Context) error {
+			waited, err := s.budget.Take(ctx)
+			if waited {
+				s.metrics.IncCounter("s3_budget_waits_total", metrics.Labels{"operation": operation})
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", operation, err)
+			}
+			return fn(ctx)
+		}
+	}
+
+	err := resilience.Retry(ctx, s.retryPolicy, IsRetryable, onRetry, attempt)
+	breaker.Record(err != nil && IsRetryable(err))
+
+	var budgetErr *resilience.BudgetError
+	result := "success"
+	switch {
+	case errors.As(err, &budgetErr):
+		result = "budget_exceeded"
+	case err != nil:
+		result = "error"
+	}
+	s.metrics.IncCounter("s3_requests_total", metrics.Labels{"operation": operation, "result": result})
+
+	return err
+}
+
+// breakerStateChanged publishes the breaker state as a gauge
+func (s *S3Service) breakerStateChanged(state resilience.State) {
+	s.metrics.SetGauge("s3_circuit_breaker_state", nil, float64(state))
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// RestoreDrillReport is the result of restoring a randomly picked upload.
+// Checksum names what the download was verified against (sha256 or md5),
+// empty when S3 has no whole-object checksum for it.
+type RestoreDrillReport struct {
+	CheckReport
+	ObjectKey    string     `json:"object_key,omitempty"`
+	Size         int64      `json:"size,omitempty"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	Checksum     string     `json:"checksum,omitempty"`
+}
+
+// drillDownload is what a restore drill read through the presigned URL
+type drillDownload struct {
+	size   int64
+	sha256 []byte
+	md5    []byte
+	header http.Header
+}
+
+// RestoreDrill picks a random upload modified within maxAge and no larger
+// than maxBytes (0 for any size), downloads it through a presigned GET and
+// verifies the download against the size, checksum and metadata S3 reports
+// for it. Each step is reported like SelfTest.
+func (s *S3Service) RestoreDrill(ctx context.Context, maxAge time.Duration, maxBytes int64) *RestoreDrillReport {
+	report := &RestoreDrillReport{CheckReport: CheckReport{OK: true}}
+
+	var info *ObjectInfo
+	var checksum string
+	var get *PresignedURL
+	ready := report.run("pick_object", func() error {
+		var err error
+		report.ObjectKey, err = s.randomUpload(ctx, time.Now().Add(-maxAge), maxBytes)
+		return err
+	}) && report.run("head_object", func() error {
+		var err error
+		if info, err = s.HeadObject(ctx, report.ObjectKey); err != nil {
+			return err
+		}
+		report.Size = info.Size
+		report.LastModified = &info.LastModified
+		checksum, err = s.ChecksumSHA256(ctx, report.ObjectKey)
+		return err
+	}) && report.run("presign_get", func() error {
+		var err error
+		get, err = s.presign(http.MethodGet, report.ObjectKey, nil, nil, time.Time{})
+		return err
+	})
+	if !ready {
+		return report
+	}
+
+	var download *drillDownload
+	if !report.run("download", func() error {
+		var err error
+		download, err = downloadPresigned(ctx, get)
+		return err
+	}) {
+		return report
+	}
+
+	report.run("verify_size", func() error {
+		if download.size != info.Size {
+			return fmt.Errorf("downloaded %d bytes, S3 reports %d", download.size, info.Size)
+		}
+		return nil
+	})
+
+	// Multipart uploads have no whole-object digest: their SHA-256 checksum
+	// and ETag are computed over the parts. SSE-KMS ETags aren't digests.
+	switch {
+	case checksum != "" && !strings.Contains(checksum, "-"):
+		report.Checksum = "sha256"
+		report.run("verify_checksum", func() error {
+			if got := base64.StdEncoding.EncodeToString(download.sha256); got != checksum {
+				return fmt.Errorf("downloaded SHA-256 %s, S3 stored %s", got, checksum)
+			}
+			return nil
+		})
+	case !strings.Contains(info.ETag, "-") && download.header.Get("X-Amz-Server-Side-Encryption") != "aws:kms":
+		report.Checksum = "md5"
+		report.run("verify_checksum", func() error {
+			if got := hex.EncodeToString(download.md5); got != info.ETag {
+				return fmt.Errorf("downloaded MD5 %s, S3 ETag is %s", got, info.ETag)
+			}
+			return nil
+		})
+	}
+
+	report.run("verify_metadata", func() error {
+		if info.ContentType != "" && download.header.Get("Content-Type") != info.ContentType {
+			return fmt.Errorf("downloaded Content-Type %q, S3 reports %q", download.header.Get("Content-Type"), info.ContentType)
+		}
+		for name, value := range info.Metadata {
+			if got := download.header.Get("X-Amz-Meta-" + name); got != value {
+				return fmt.Errorf("downloaded x-amz-meta-%s %q, S3 reports %q", name, got, value)
+			}
+		}
+		return nil
+	})
+
+	return report
+}
+
+// randomUpload picks an upload under inputs/ uniformly at random among those
+// modified since since and no larger than maxBytes
+func (s *S3Service) randomUpload(ctx context.Context, since time.Time, maxBytes int64) (string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(s.buildObjectKey("inputs/")),
+	}
+
+	var picked string
+	candidates := 0
+	for {
+		page, err := s.listObjects(ctx, input)
+		if err != nil {
+			return "", fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if strings.HasSuffix(key, "/") || aws.ToTime(obj.LastModified).Before(since) {
+				continue
+			}
+			if maxBytes > 0 && aws.ToInt64(obj.Size) > maxBytes {
+				continue
+			}
+			// Reservoir sampling keeps each candidate with equal probability
+			candidates++
+			if rand.IntN(candidates) == 0 {
+				picked = key
+			}
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	if picked == "" {
+		return "", fmt.Errorf("%w: no upload modified since %s", ErrObjectNotFound, since.UTC().Format(time.RFC3339))
+	}
+	return picked, nil
+}
+
+// downloadPresigned streams a presigned GET, hashing the body
+func downloadPresigned(ctx context.Context, presigned *PresignedURL) (*drillDownload, error) {
+	req, err := http.NewRequestWithContext(ctx, presigned.Method, presigned.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range presigned.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := selfTestClient.Do(req)
+	if err != nil {
+		// Don't echo the presigned URL, it's usable until it expires
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("GET request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return nil, fmt.Errorf("GET returned %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	sha, sum := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(sha, sum), resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("download interrupted after %d bytes: %w", size, err)
+	}
+	return &drillDownload{size: size, sha256: sha.Sum(nil), md5: sum.Sum(nil), header: resp.Header}, nil
+}
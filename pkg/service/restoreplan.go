@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxRestorePlanObjects bounds the uploads listed to build a restore plan
+const MaxRestorePlanObjects = 100000
+
+// RestorePlanFile is the upload a file is restored from
+type RestorePlanFile struct {
+	Path         string    `json:"path"` // Below the upload folder
+	ObjectKey    string    `json:"object_key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// RestorePlan returns, for every file path ever uploaded below an
+// inputs/YYYY-MM-DD/HH-MM-SS/ folder, its latest upload last modified at or
+// before at, in path order. Files first uploaded after at are left out.
+// ErrInventoryTooLarge is returned when more than MaxRestorePlanObjects
+// uploads precede at.
+func (s *S3Service) RestorePlan(ctx context.Context, at time.Time) ([]RestorePlanFile, error) {
+	root := s.buildObjectKey("inputs/")
+	// The listing's upper bound is exclusive
+	objects, err := s.ListInventory(ctx, root, time.Time{}, at.Add(time.Nanosecond), MaxRestorePlanObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]RestorePlanFile)
+	for _, obj := range objects {
+		path, ok := uploadPath(strings.TrimPrefix(obj.Key, root))
+		if !ok {
+			continue // Not written by this service
+		}
+		if previous, ok := latest[path]; ok && !obj.LastModified.After(previous.LastModified) {
+			continue
+		}
+		latest[path] = RestorePlanFile{
+			Path:         path,
+			ObjectKey:    obj.Key,
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+		}
+	}
+
+	files := make([]RestorePlanFile, 0, len(latest))
+	for _, f := range latest {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// uploadPath returns the path of a key relative to inputs/ below its
+// YYYY-MM-DD/HH-MM-SS upload folder
+func uploadPath(relative string) (string, bool) {
+	date, rest, ok := strings.Cut(relative, "/")
+	if !ok {
+		return "", false
+	}
+	upload, path, ok := strings.Cut(rest, "/")
+	if !ok || path == "" {
+		return "", false
+	}
+	if _, err := time.Parse("2006-01-02/15-04-05", date+"/"+upload); err != nil {
+		return "", false
+	}
+	return path, true
+}
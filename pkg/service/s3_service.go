@@ -0,0 +1,424 @@
+// Package service implements S3 operations and manual AWS Signature V4
+// presigning used by the signer-service handlers.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/metrics"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+)
+
+// ErrObjectNotFound is returned when an object does not exist in the bucket
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrAWSConfig is returned by NewS3Service when the AWS configuration can't
+// be loaded, which may be transient (DNS, instance metadata) and worth
+// retrying, unlike invalid settings
+var ErrAWSConfig = errors.New("failed to load AWS config")
+
+// S3Service handles S3 operations
+type S3Service struct {
+	client         S3API
+	signer         *AWSSigner
+	bucketName     string
+	replicaSigner  *AWSSigner // Signs for the DR region, nil without DR_BUCKET_NAME
+	replicaBucket  string
+	failover       *failover // Nil unless FAILOVER_ENABLED
+	companyPrefix  string    // Includes the environment segment
+	environment    string
+	region         string
+	uploadExpiry   time.Duration // Upload, part and delete URLs
+	downloadExpiry time.Duration
+	opTimeout      time.Duration
+	retryPolicy    resilience.RetryPolicy
+	breaker        *resilience.Breaker
+	budget         *resilience.Budget // S3 calls per minute, nil when unlimited
+	metrics        *metrics.Registry
+	clock          Clock
+	ids            idgen.Generator
+	keyStrategy    string     // Handling of upload keys over MaxKeyBytes
+	listings       *listCache // Cached listing pages, nil when disabled
+	drift          *driftMonitor
+	subpathPattern *regexp.Regexp // Allowed upload subpaths (see CheckSubpath), nil refuses them
+
+	// Signed header policy for upload URLs (see CheckSignedHeaders)
+	requiredHeaders []string
+	allowedHeaders  []string
+
+	// Ownership headers of every upload (see OwnershipHeaders)
+	uploadACL           string
+	expectedBucketOwner string
+
+	roles *roleSet // Clients and signers of tenant roles (see ForRole)
+}
+
+// Option configures optional S3Service dependencies
+type Option func(*S3Service)
+
+// WithMetrics records S3 call, retry and circuit breaker metrics in registry
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(s *S3Service) {
+		s.metrics = registry
+	}
+}
+
+// NewS3Service creates a new S3 service instance. ctx bounds loading the AWS
+// configuration.
+func NewS3Service(ctx context.Context, cfg *config.Config, opts ...Option) (*S3Service, error) {
+	// Create AWS config with explicit credentials using LoadDefaultConfig.
+	// SDK retries are disabled because calls go through our own retry policy.
+	awsCfg, err := awsConfig.LoadDefaultConfig(ctx,
+		awsConfig.WithRegion(cfg.AWSRegion),
+		awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			cfg.AWSAccessKeyID,
+			cfg.AWSSecretAccessKey,
+			cfg.AWSSessionToken,
+		)),
+		awsConfig.WithRetryer(func() aws.Retryer { return aws.NopRetryer{} }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAWSConfig, err)
+	}
+	// Tenant roles are assumed with the configured credentials: assuming
+	// them with AWS_ROLE_ARN's would chain roles, capping sessions at an hour
+	baseCfg := awsCfg.Copy()
+	roleExpiry := cfg.RoleURLExpiration()
+	// With a role, the static credentials only assume it; S3 calls and
+	// presigned URLs use the role's
+	if cfg.AWSRoleARN != "" {
+		awsCfg.Credentials = assumeRoleProvider(baseCfg, cfg, cfg.AWSRoleARN, cfg.AWSRoleExternalID,
+			max(cfg.UploadURLExpiration(), cfg.DownloadURLExpiration()))
+	}
+
+	// Create S3 client. Access point ARNs may live in another region than
+	// AWS_REGION, so the SDK follows the region in the ARN.
+	clientOptions := func(o *s3.Options) {
+		o.UseARNRegion = true
+		o.APIOptions = append(o.APIOptions, addCorrelationHeaders)
+		if cfg.S3EndpointURL != "" {
+			o.BaseEndpoint = aws.String(cfg.S3EndpointURL)
+			o.UsePathStyle = true
+		}
+	}
+	client := s3.NewFromConfig(awsCfg, clientOptions)
+
+	// Create manual signer for presigned URLs
+	signer := NewAWSSigner(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSRegion, "s3")
+	signer.SetDebugLogging(cfg.SignerDebug == "all")
+	signer.SetSessionToken(cfg.AWSSessionToken)
+	if cfg.AWSRoleARN != "" {
+		signer.SetCredentialsProvider(awsCfg.Credentials)
+	}
+	if cfg.S3EndpointURL != "" {
+		endpoint, err := url.Parse(strings.TrimSuffix(cfg.S3EndpointURL, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3_ENDPOINT_URL: %w", err)
+		}
+		signer.SetEndpoint(endpoint)
+	}
+
+	// With failover, the DR bucket gets its own client for the DR region
+	var secondary *failover
+	if cfg.FailoverEnabled {
+		secondary = &failover{
+			client: s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+				o.Region = cfg.DRRegion
+				o.APIOptions = append(o.APIOptions, addCorrelationHeaders)
+				if cfg.S3EndpointURL != "" {
+					o.BaseEndpoint = aws.String(cfg.S3EndpointURL)
+					o.UsePathStyle = true
+				}
+			}),
+			threshold: cfg.FailoverThreshold,
+			state:     FailoverState{Ready: true, Active: BackendPrimary, PrimaryHealthy: true, SecondaryHealthy: true},
+		}
+	}
+
+	// A Multi-Region Access Point ARN is accepted wherever the SDK and the
+	// signer take a bucket name
+	bucketName := cfg.S3BucketName
+	if cfg.S3MRAPARN != "" {
+		bucketName = cfg.S3MRAPARN
+	}
+
+	s := &S3Service{
+		client:         client,
+		signer:         signer,
+		bucketName:     bucketName,
+		replicaBucket:  cfg.DRBucketName,
+		failover:       secondary,
+		companyPrefix:  withEnvironment(cfg.CompanyPrefix, cfg.Environment),
+		environment:    cfg.Environment,
+		region:         cfg.AWSRegion,
+		uploadExpiry:   cfg.UploadURLExpiration(),
+		downloadExpiry: cfg.DownloadURLExpiration(),
+		opTimeout:      time.Duration(cfg.S3OperationTimeoutSeconds) * time.Second,
+		retryPolicy: resilience.RetryPolicy{
+			MaxAttempts: cfg.S3RetryMaxAttempts,
+			BaseDelay:   time.Duration(cfg.S3RetryBaseDelayMS) * time.Millisecond,
+			MaxDelay:    time.Duration(cfg.S3RetryMaxDelayMS) * time.Millisecond,
+		},
+		clock:           systemClock{},
+		ids:             idgen.Random{},
+		keyStrategy:     cfg.LongFilenameStrategy,
+		drift:           &driftMonitor{threshold: time.Duration(cfg.ClockDriftThresholdSeconds) * time.Second},
+		requiredHeaders: cfg.RequiredSignedHeaders,
+		allowedHeaders:  cfg.AllowedSignedHeaders,
+
+		uploadACL:           cfg.UploadACL,
+		expectedBucketOwner: cfg.ExpectedBucketOwner,
+
+		roles: &roleSet{
+			newProvider: func(roleARN, externalID string) aws.CredentialsProvider {
+				return assumeRoleProvider(baseCfg, cfg, roleARN, externalID,
+					min(max(cfg.UploadURLExpiration(), cfg.DownloadURLExpiration()), roleExpiry))
+			},
+			newClient: func(provider aws.CredentialsProvider) S3API {
+				roleCfg := baseCfg.Copy()
+				roleCfg.Credentials = provider
+				return s3.NewFromConfig(roleCfg, clientOptions)
+			},
+			maxExpiry: roleExpiry,
+		},
+	}
+	if s.subpathPattern, err = compileSubpathPattern(cfg.UploadSubpathPattern); err != nil {
+		return nil, fmt.Errorf("invalid UPLOAD_SUBPATH_PATTERN: %w", err)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	signer.SetClock(s.clock)
+	if cfg.ListCacheTTLSeconds > 0 {
+		s.listings = newListCache(time.Duration(cfg.ListCacheTTLSeconds)*time.Second, s.clock)
+	}
+	if cfg.S3CallBudgetPerMinute > 0 {
+		var maxWait time.Duration
+		if cfg.S3CallBudgetMode != "reject" {
+			maxWait = time.Duration(cfg.S3CallBudgetMaxWaitSeconds) * time.Second
+		}
+		s.budget = resilience.NewBudget(cfg.S3CallBudgetPerMinute, time.Minute, maxWait, s.clock.Now)
+	}
+	if cfg.DRBucketName != "" {
+		s.replicaSigner = NewAWSSigner(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.DRRegion, "s3")
+		s.replicaSigner.SetDebugLogging(signer.logDebug)
+		s.replicaSigner.SetSessionToken(cfg.AWSSessionToken)
+		s.replicaSigner.SetCredentialsProvider(signer.provider)
+		s.replicaSigner.SetClock(s.clock)
+		s.replicaSigner.endpoint = signer.endpoint
+	}
+	if s.metrics == nil {
+		s.metrics = metrics.NewRegistry()
+	}
+
+	s.metrics.Describe("s3_requests_total", "S3 API calls by operation and result")
+	s.metrics.Describe("s3_retries_total", "S3 API call retries by operation")
+	s.metrics.Describe("s3_budget_waits_total", "S3 API calls queued for the next budget window by operation")
+	s.metrics.Describe("list_cache_requests_total", "Cacheable bucket listings by result (hit or miss)")
+	s.metrics.Describe("clock_drift_seconds", "Local clock minus S3's at the last clock drift check")
+	s.metrics.Describe("clock_drift_exceeded", "1 while the clock drift exceeds CLOCK_DRIFT_THRESHOLD_SECONDS")
+	s.metrics.Describe("s3_circuit_breaker_state", "S3 circuit breaker state (0 closed, 1 open, 2 half-open)")
+	s.metrics.SetGauge("s3_circuit_breaker_state", nil, float64(resilience.StateClosed))
+	s.breaker = resilience.NewBreaker(
+		cfg.S3BreakerFailureThreshold,
+		time.Duration(cfg.S3BreakerCooldownSeconds)*time.Second,
+		s.breakerStateChanged,
+	)
+	if s.failover != nil {
+		s.metrics.Describe("s3_failover_active", "1 while requests are routed to the DR bucket")
+		s.metrics.Describe("s3_bucket_healthy", "Outcome of the last failover health check per bucket")
+		s.metrics.Describe("s3_failovers_total", "Switches between the primary and DR buckets")
+		s.metrics.SetGauge("s3_failover_active", nil, 0)
+		s.failover.breaker = resilience.NewBreaker(
+			cfg.S3BreakerFailureThreshold,
+			time.Duration(cfg.S3BreakerCooldownSeconds)*time.Second,
+			nil,
+		)
+	}
+
+	return s, nil
+}
+
+// buildObjectKey constructs the full object key with company prefix
+// If company prefix is empty, returns just the objectKey without leading slash
+func (s *S3Service) buildObjectKey(objectKey string) string {
+	if s.companyPrefix == "" {
+		return objectKey
+	}
+	return fmt.Sprintf("%s/%s", s.companyPrefix, objectKey)
+}
+
+// KeyPrefix returns the prefix every key under the company prefix starts
+// with, empty without a company prefix
+func (s *S3Service) KeyPrefix() string {
+	return s.buildObjectKey("")
+}
+
+// buildTimestampedPath constructs object path with inputs/date/time/ prefix
+// Format: inputs/YYYY-MM-DD/HH-MM-SS/[subpath/]filename
+func (s *S3Service) buildTimestampedPath(subpath, filename string) string {
+	now := s.clock.Now().UTC()
+
+	// Format: inputs/2024-01-16/14-30-00/filename
+	datePart := now.Format("2006-01-02") // YYYY-MM-DD
+	timePart := now.Format("15-04-05")   // HH-MM-SS
+
+	path := fmt.Sprintf("inputs/%s/%s/%s", datePart, timePart, filename)
+	if subpath != "" {
+		path = fmt.Sprintf("inputs/%s/%s/%s/%s", datePart, timePart, subpath, filename)
+	}
+	return path
+}
+
+// SearchObjectByFilename searches for a file by name in the company's prefix
+func (s *S3Service) SearchObjectByFilename(ctx context.Context, filename string) (bool, string, error) {
+	// Build search prefix
+	searchPrefix := s.searchPrefix()
+
+	// List all objects in the search prefix
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(searchPrefix),
+	}
+
+	result, err := s.listObjects(ctx, input)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	// Search for the filename in all object keys
+	for _, obj := range result.Contents {
+		if obj.Key != nil {
+			// Check if the key ends with the filename
+			key := *obj.Key
+			if s.IsTrashKey(key) {
+				continue // Soft-deleted
+			}
+			if len(key) >= len(filename) && key[len(key)-len(filename):] == filename {
+				return true, key, nil
+			}
+		}
+	}
+
+	return false, "", nil
+}
+
+// GeneratePresignedPutURL generates a presigned URL for uploading an object
+// Returns: (presignedURL, fullObjectPath, error)
+func (s *S3Service) GeneratePresignedPutURL(ctx context.Context, filename string, contentType string, metadata map[string]string) (string, string, error) {
+	// Build full object key: <company prefix>/inputs/date/time/filename
+	fullKey, err := s.UploadKey(filename)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.CheckSignedHeaders(MetadataHeaders(metadata)); err != nil {
+		return "", "", err
+	}
+
+	// Use manual signer to generate presigned URL
+	presignedURL, err := s.activeSigner().GeneratePresignedPutURL(s.bucket(), fullKey, contentType, metadata, s.uploadExpiry)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return presignedURL, fullKey, nil
+}
+
+// ObjectInfo describes an object as returned by HeadObject
+type ObjectInfo struct {
+	Key             string            `json:"object_key"`
+	Size            int64             `json:"size"`
+	ETag            string            `json:"etag"`
+	ContentType     string            `json:"content_type,omitempty"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	LastModified    time.Time         `json:"last_modified"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	ObjectLock      *ObjectLock       `json:"object_lock,omitempty"`
+	TagCount        int32             `json:"tag_count,omitempty"`
+
+	// PENDING, COMPLETED or FAILED on a replication source, REPLICA on a
+	// replica, empty when no replication rule applies
+	ReplicationStatus string `json:"replication_status,omitempty"`
+}
+
+// OwnsKey reports whether objectKey lies under the company prefix, so callers
+// can't reach objects belonging to other tenants
+func (s *S3Service) OwnsKey(objectKey string) bool {
+	if strings.Contains(objectKey, "..") {
+		return false
+	}
+	if s.companyPrefix == "" {
+		return objectKey != ""
+	}
+	return strings.HasPrefix(objectKey, s.companyPrefix+"/")
+}
+
+// ForPrefix returns a service scoped to another company prefix, in the same
+// environment. It shares the client, signer, circuit breaker and metrics
+// with s.
+func (s *S3Service) ForPrefix(prefix string) *S3Service {
+	scoped := *s
+	scoped.companyPrefix = withEnvironment(prefix, s.environment)
+	return &scoped
+}
+
+// withEnvironment appends the environment segment to prefix, so every
+// environment sharing a bucket gets its own key space
+func withEnvironment(prefix, environment string) string {
+	switch {
+	case environment == "":
+		return prefix
+	case prefix == "":
+		return environment
+	}
+	return prefix + "/" + environment
+}
+
+// HeadObject fetches an object's size, ETag, metadata and Object Lock settings
+// Returns ErrObjectNotFound if the object doesn't exist
+func (s *S3Service) HeadObject(ctx context.Context, objectKey string) (*ObjectInfo, error) {
+	var result *s3.HeadObjectOutput
+	err := s.call(ctx, "HeadObject", func(ctx context.Context) error {
+		var err error
+		result, err = s.api().HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucket()),
+			Key:    aws.String(objectKey),
+		})
+		return err
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to head object: %w", err)
+	}
+
+	return &ObjectInfo{
+		Key:             objectKey,
+		Size:            aws.ToInt64(result.ContentLength),
+		ETag:            strings.Trim(aws.ToString(result.ETag), `"`),
+		ContentType:     aws.ToString(result.ContentType),
+		ContentEncoding: aws.ToString(result.ContentEncoding),
+		LastModified:    aws.ToTime(result.LastModified),
+		Metadata:        result.Metadata,
+		ObjectLock:      objectLockFromHead(result),
+		TagCount:        aws.ToInt32(result.TagCount),
+
+		ReplicationStatus: string(result.ReplicationStatus),
+	}, nil
+}
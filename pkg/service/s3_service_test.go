@@ -0,0 +1,680 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/config"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service/s3fake"
+)
+
+// testTime is the fixed signing time of test services
+var testTime = time.Date(2025, 11, 24, 14, 30, 0, 0, time.UTC)
+
+// newTestService builds a service over an in-memory bucket, configured from
+// settings (environment variable names) on top of test credentials
+func newTestService(t testing.TB, settings map[string]string, opts ...service.Option) (*service.S3Service, *s3fake.Bucket) {
+	t.Helper()
+
+	values := map[string]string{
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"S3_BUCKET_NAME":        "backups",
+		"COMPANY_PREFIX":        "acme",
+	}
+	for k, v := range settings {
+		values[k] = v
+	}
+	cfg, err := config.Load("", values)
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	bucket := s3fake.New()
+	opts = append([]service.Option{service.WithS3Client(bucket), service.WithClock(service.FixedClock(testTime))}, opts...)
+	svc, err := service.NewS3Service(context.Background(), cfg, opts...)
+	if err != nil {
+		t.Fatalf("NewS3Service: %v", err)
+	}
+	return svc, bucket
+}
+
+func TestUploadKey(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+
+	key, err := svc.UploadKey("db.dump.gz")
+	if err != nil {
+		t.Fatalf("UploadKey: %v", err)
+	}
+	if want := "acme/inputs/2025-11-24/14-30-00/db.dump.gz"; key != want {
+		t.Errorf("UploadKey = %q, want %q", key, want)
+	}
+}
+
+func TestUploadKeyTooLong(t *testing.T) {
+	long := strings.Repeat("a", service.MaxKeyBytes) + ".gz"
+
+	tests := []struct {
+		strategy string
+		wantErr  bool
+		suffix   string
+	}{
+		{strategy: "reject", wantErr: true},
+		{strategy: "truncate", suffix: "a.gz"},
+		{strategy: "hash", suffix: ".gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			svc, _ := newTestService(t, map[string]string{"LONG_FILENAME_STRATEGY": tt.strategy})
+
+			key, err := svc.UploadKey(long)
+			if tt.wantErr {
+				var tooLong *service.KeyTooLongError
+				if !errors.As(err, &tooLong) {
+					t.Fatalf("UploadKey error = %v, want *KeyTooLongError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadKey: %v", err)
+			}
+			if len(key) > service.MaxKeyBytes || !strings.HasSuffix(key, tt.suffix) {
+				t.Errorf("UploadKey = %d bytes ending %q, want at most %d ending %q", len(key), key[len(key)-8:], service.MaxKeyBytes, tt.suffix)
+			}
+		})
+	}
+}
+
+func TestUploadKeyIn(t *testing.T) {
+	svc, _ := newTestService(t, map[string]string{"UPLOAD_SUBPATH_PATTERN": `[a-z0-9-]+(/[a-z0-9-]+)?`})
+
+	key, err := svc.UploadKeyIn("host-a", "db.dump")
+	if err != nil {
+		t.Fatalf("UploadKeyIn: %v", err)
+	}
+	if want := "acme/inputs/2025-11-24/14-30-00/host-a/db.dump"; key != want {
+		t.Errorf("UploadKeyIn = %q, want %q", key, want)
+	}
+
+	for _, subpath := range []string{"../globex", "host-a/../..", "/host-a", "host-a/", "host-a//x", `host\a`, "Host-A", "a/b/c"} {
+		var subpathErr *service.SubpathError
+		if _, err := svc.UploadKeyIn(subpath, "db.dump"); !errors.As(err, &subpathErr) {
+			t.Errorf("UploadKeyIn(%q) error = %v, want *SubpathError", subpath, err)
+		}
+	}
+
+	// Without a pattern subpaths are refused
+	svc, _ = newTestService(t, nil)
+	if _, err := svc.UploadKeyIn("host-a", "db.dump"); err == nil {
+		t.Error("UploadKeyIn accepted a subpath without UPLOAD_SUBPATH_PATTERN")
+	}
+}
+
+func TestOwnsKey(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"acme/inputs/2025-11-24/14-30-00/a.txt", true},
+		{"acme-other/inputs/a.txt", false},
+		{"globex/inputs/a.txt", false},
+		{"acme/../globex/a.txt", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := svc.OwnsKey(tt.key); got != tt.want {
+			t.Errorf("OwnsKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestHeadObject(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	bucket.Put("acme/inputs/a.txt", s3fake.Object{
+		Body:            []byte("hello"),
+		ContentType:     "text/plain",
+		ContentEncoding: "gzip",
+		Metadata:        map[string]string{"source": "test"},
+	})
+
+	info, err := svc.HeadObject(context.Background(), "acme/inputs/a.txt")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.Size != 5 || info.ContentType != "text/plain" || info.ContentEncoding != "gzip" || info.Metadata["source"] != "test" {
+		t.Errorf("HeadObject = %+v", info)
+	}
+	if strings.Contains(info.ETag, `"`) {
+		t.Errorf("ETag %q should be unquoted", info.ETag)
+	}
+
+	if _, err := svc.HeadObject(context.Background(), "acme/inputs/missing.txt"); !errors.Is(err, service.ErrObjectNotFound) {
+		t.Errorf("HeadObject of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestHeadObjectFailure(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	bucket.FailWith("HeadObject", s3fake.ErrInjected)
+
+	_, err := svc.HeadObject(context.Background(), "acme/inputs/a.txt")
+	if !errors.Is(err, s3fake.ErrInjected) || errors.Is(err, service.ErrObjectNotFound) {
+		t.Errorf("HeadObject = %v, want the injected error", err)
+	}
+}
+
+func TestSearchObjectByFilename(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	bucket.Put("acme/inputs/2025-11-23/10-00-00/report.pdf", s3fake.Object{Body: []byte("x")})
+	bucket.Put("globex/inputs/2025-11-23/10-00-00/other.pdf", s3fake.Object{Body: []byte("x")})
+
+	found, key, err := svc.SearchObjectByFilename(context.Background(), "report.pdf")
+	if err != nil {
+		t.Fatalf("SearchObjectByFilename: %v", err)
+	}
+	if !found || key != "acme/inputs/2025-11-23/10-00-00/report.pdf" {
+		t.Errorf("SearchObjectByFilename = %v, %q", found, key)
+	}
+
+	found, _, err = svc.SearchObjectByFilename(context.Background(), "other.pdf")
+	if err != nil || found {
+		t.Errorf("SearchObjectByFilename found another prefix's object: %v, %v", found, err)
+	}
+}
+
+func TestEnvironmentIsolation(t *testing.T) {
+	svc, bucket := newTestService(t, map[string]string{"ENVIRONMENT": "prod"})
+	bucket.Put("acme/prod/inputs/2025-11-23/10-00-00/report.pdf", s3fake.Object{Body: []byte("x")})
+	bucket.Put("acme/dev/inputs/2025-11-23/10-00-00/staged.pdf", s3fake.Object{Body: []byte("x")})
+
+	presigned, err := svc.PresignUpload("db.dump", service.UploadOptions{})
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	if want := "acme/prod/inputs/2025-11-24/14-30-00/db.dump"; presigned.ObjectKey != want {
+		t.Errorf("ObjectKey = %q, want %q", presigned.ObjectKey, want)
+	}
+	if found, _, err := svc.SearchObjectByFilename(context.Background(), "report.pdf"); err != nil || !found {
+		t.Errorf("SearchObjectByFilename(report.pdf) = %v, %v, want found", found, err)
+	}
+	if found, _, err := svc.SearchObjectByFilename(context.Background(), "staged.pdf"); err != nil || found {
+		t.Errorf("SearchObjectByFilename found another environment's object: %v, %v", found, err)
+	}
+	if svc.OwnsKey("acme/dev/inputs/2025-11-23/10-00-00/staged.pdf") {
+		t.Error("OwnsKey accepted another environment's key")
+	}
+	if got := svc.ForPrefix("globex").KeyPrefix(); got != "globex/prod/" {
+		t.Errorf("tenant KeyPrefix = %q, want globex/prod/", got)
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	older := testTime.Add(-2 * time.Hour)
+	newer := testTime.Add(-time.Hour)
+	bucket.Put("acme/inputs/2025-11-24/12-30-00/db.dump", s3fake.Object{Body: []byte("a"), ChecksumSHA256: "sum-a", LastModified: older})
+	bucket.Put("acme/inputs/2025-11-24/13-30-00/db.dump", s3fake.Object{Body: []byte("b"), ChecksumSHA256: "sum-b", LastModified: newer})
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     string
+	}{
+		{"newest by name", "", "acme/inputs/2025-11-24/13-30-00/db.dump"},
+		{"matching checksum", "sum-a", "acme/inputs/2025-11-24/12-30-00/db.dump"},
+		{"no matching checksum", "sum-c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dup, err := svc.FindDuplicate(context.Background(), "db.dump", tt.checksum)
+			if err != nil {
+				t.Fatalf("FindDuplicate: %v", err)
+			}
+			var got string
+			if dup != nil {
+				got = dup.ObjectKey
+			}
+			if got != tt.want {
+				t.Errorf("FindDuplicate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	ctx := context.Background()
+
+	uploadID, key, err := svc.CreateMultipartUpload(ctx, "big.tar", "application/x-tar", "", map[string]string{"source": "test"})
+	if err != nil {
+		t.Fatalf("CreateMultipartUpload: %v", err)
+	}
+	etag1, _ := bucket.UploadPart(uploadID, 1, []byte("hello "))
+	etag2, _ := bucket.UploadPart(uploadID, 2, []byte("world"))
+
+	// Parts may be completed in any order
+	err = svc.CompleteMultipartUpload(ctx, key, uploadID, []service.CompletedPart{
+		{PartNumber: 2, ETag: etag2},
+		{PartNumber: 1, ETag: etag1},
+	})
+	if err != nil {
+		t.Fatalf("CompleteMultipartUpload: %v", err)
+	}
+
+	obj, ok := bucket.Get(key)
+	if !ok || string(obj.Body) != "hello world" || obj.Metadata["source"] != "test" {
+		t.Errorf("completed object = %+v, %v", obj, ok)
+	}
+}
+
+func TestCopyObject(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	bucket.Put("acme/inputs/a.txt", s3fake.Object{Body: []byte("a"), Metadata: map[string]string{"k": "v"}})
+
+	if err := svc.CopyObject(context.Background(), "acme/inputs/a.txt", "acme/archive/a b.txt"); err != nil {
+		t.Fatalf("CopyObject: %v", err)
+	}
+	obj, ok := bucket.Get("acme/archive/a b.txt")
+	if !ok || obj.Metadata["k"] != "v" {
+		t.Errorf("copied object = %+v, %v", obj, ok)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	bucket.Put("acme/inputs/a.gz", s3fake.Object{
+		Body:            []byte("a"),
+		ContentType:     "application/gzip",
+		ContentEncoding: "gzip",
+		Metadata:        map[string]string{"source": "nightly", "stage": "uploaded"},
+		Tags:            map[string]string{"team": "dba"},
+	})
+	source, err := svc.HeadObject(context.Background(), "acme/inputs/a.gz")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+
+	updated, err := svc.UpdateMetadata(context.Background(), source, service.MetadataUpdate{
+		Set:    map[string]string{"Verified": "true"},
+		Remove: []string{"stage"},
+		Tags:   map[string]string{"status": "verified", "team": "dba"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	want := map[string]string{"source": "nightly", "verified": "true"}
+	if !reflect.DeepEqual(updated.Metadata, want) || updated.TagCount != 2 {
+		t.Errorf("updated = metadata %v, %d tags; want %v and 2 tags", updated.Metadata, updated.TagCount, want)
+	}
+	obj, _ := bucket.Get("acme/inputs/a.gz")
+	if obj.ContentType != "application/gzip" || obj.ContentEncoding != "gzip" || string(obj.Body) != "a" || obj.Tags["status"] != "verified" {
+		t.Errorf("stored object = %+v, want content headers and body kept", obj)
+	}
+}
+
+func TestGetObjectNotFound(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+
+	if _, err := svc.GetObject(context.Background(), "acme/inputs/missing"); !errors.Is(err, service.ErrObjectNotFound) {
+		t.Errorf("GetObject = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestListDateFolders(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	for _, key := range []string{
+		"acme/inputs/2025-10-31/23-59-59/a.txt",
+		"acme/inputs/2025-11-23/10-00-00/a.txt",
+		"acme/inputs/2025-11-24/09-00-00/a.txt",
+		"acme/inputs/2025-11-24/09-00-00/a.txt.chunk-00001",
+		"acme/inputs/2025-11-24/14-30-00/b.txt",
+		"acme/inputs/not-a-date/10-00-00/c.txt",
+		"globex/inputs/2025-11-25/10-00-00/d.txt",
+	} {
+		bucket.Put(key, s3fake.Object{Body: []byte("x")})
+	}
+
+	tests := []struct {
+		prefix string
+		want   []service.DateFolder
+	}{
+		{"", []service.DateFolder{{Date: "2025-10-31", Uploads: 1}, {Date: "2025-11-23", Uploads: 1}, {Date: "2025-11-24", Uploads: 2}}},
+		{"2025-11", []service.DateFolder{{Date: "2025-11-23", Uploads: 1}, {Date: "2025-11-24", Uploads: 2}}},
+		{"2024", []service.DateFolder{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := svc.ListDateFolders(context.Background(), tt.prefix)
+			if err != nil {
+				t.Fatalf("ListDateFolders: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListDateFolders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindLatest(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	// LastModified disagrees with the upload folders, as after a copy
+	bucket.Put("acme/inputs/2025-11-24/09-00-00/db-1.dump.gz", s3fake.Object{Body: []byte("a"), LastModified: testTime})
+	bucket.Put("acme/inputs/2025-11-24/13-00-00/db-2.dump.gz", s3fake.Object{Body: []byte("b"), LastModified: testTime.Add(-time.Hour)})
+	bucket.Put("acme/inputs/2025-11-23/10-00-00/report.pdf", s3fake.Object{Body: []byte("c"), LastModified: testTime})
+	bucket.Put("globex/inputs/2025-11-25/10-00-00/db-3.dump.gz", s3fake.Object{Body: []byte("d")})
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"db-*.dump.gz", "acme/inputs/2025-11-24/13-00-00/db-2.dump.gz"},
+		{"db-1.dump.gz", "acme/inputs/2025-11-24/09-00-00/db-1.dump.gz"},
+		{"*.pdf", "acme/inputs/2025-11-23/10-00-00/report.pdf"},
+		{"db-3.dump.gz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			info, err := svc.FindLatest(context.Background(), tt.pattern)
+			if tt.want == "" {
+				if !errors.Is(err, service.ErrObjectNotFound) {
+					t.Errorf("FindLatest = %v, %v; want ErrObjectNotFound", info, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindLatest: %v", err)
+			}
+			if info.Key != tt.want {
+				t.Errorf("FindLatest = %q, want %q", info.Key, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrashAndPurge(t *testing.T) {
+	svc, bucket := newTestService(t, nil)
+	bucket.Put("acme/inputs/2025-11-24/09-00-00/db.dump", s3fake.Object{Body: []byte("abc")})
+	bucket.Put("acme/trash/2025-10-01T00-00-00Z/inputs/old.dump", s3fake.Object{Body: []byte("old")})
+
+	info, err := svc.HeadObject(context.Background(), "acme/inputs/2025-11-24/09-00-00/db.dump")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	trashed, err := svc.TrashObject(context.Background(), info)
+	if err != nil {
+		t.Fatalf("TrashObject: %v", err)
+	}
+	if want := "acme/trash/2025-11-24T14-30-00Z/inputs/2025-11-24/09-00-00/db.dump"; trashed.TrashKey != want {
+		t.Errorf("TrashKey = %q, want %q", trashed.TrashKey, want)
+	}
+	if original, ok := svc.TrashOriginalKey(trashed.TrashKey); !ok || original != info.Key {
+		t.Errorf("TrashOriginalKey = %q, %v; want %q", original, ok, info.Key)
+	}
+	if _, ok := bucket.Get(info.Key); ok {
+		t.Error("original object still exists")
+	}
+
+	report, err := svc.PurgeTrash(context.Background(), 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if report.Scanned != 2 || len(report.Purged) != 1 || report.BytesFreed != 3 {
+		t.Errorf("PurgeTrash = %+v, want only the October object purged", report)
+	}
+	if got, want := bucket.Keys(), []string{trashed.TrashKey}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	dr := s3fake.New()
+	svc, primary := newTestService(t, map[string]string{
+		"DR_BUCKET_NAME":     "backups-dr",
+		"DR_REGION":          "us-west-2",
+		"FAILOVER_ENABLED":   "true",
+		"FAILOVER_THRESHOLD": "2",
+	}, service.WithSecondaryS3Client(dr))
+	dr.Put("acme/inputs/2025-11-24/09-00-00/db.dump", s3fake.Object{Body: []byte("x")})
+
+	primary.FailWith("HeadBucket", errors.New("connection refused"))
+	if state := svc.CheckFailover(context.Background()); state.Active != service.BackendPrimary || !state.Ready {
+		t.Fatalf("after one failure: %+v, want primary and ready", state)
+	}
+	if state := svc.CheckFailover(context.Background()); state.Active != service.BackendSecondary || !state.Ready {
+		t.Fatalf("after two failures: %+v, want secondary and ready", state)
+	}
+
+	found, key, err := svc.SearchObjectByFilename(context.Background(), "db.dump")
+	if err != nil || !found {
+		t.Fatalf("SearchObjectByFilename on the secondary = %v, %q, %v", found, key, err)
+	}
+	presigned, err := svc.PresignUpload("db.dump", service.UploadOptions{})
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	if !strings.HasPrefix(presigned.URL, "https://backups-dr.s3.us-west-2.amazonaws.com/") {
+		t.Errorf("URL = %q, want the DR bucket", presigned.URL)
+	}
+
+	primary.FailWith("HeadBucket", nil)
+	svc.CheckFailover(context.Background())
+	if state := svc.CheckFailover(context.Background()); state.Active != service.BackendPrimary {
+		t.Errorf("after recovery: %+v, want primary", state)
+	}
+}
+
+// stepClock is a test clock moved forward by hand
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func TestListingCache(t *testing.T) {
+	clock := &stepClock{now: testTime}
+	svc, bucket := newTestService(t, map[string]string{"LIST_CACHE_TTL_SECONDS": "60"}, service.WithClock(clock))
+	ctx := context.Background()
+	bucket.Put("acme/inputs/2025-11-24/14-30-00/db.dump", s3fake.Object{Body: []byte("x")})
+
+	search := func(filename string, wantFound bool, wantCalls int) {
+		t.Helper()
+		found, _, err := svc.SearchObjectByFilename(ctx, filename)
+		if err != nil {
+			t.Fatalf("SearchObjectByFilename: %v", err)
+		}
+		if found != wantFound || bucket.Calls("ListObjectsV2") != wantCalls {
+			t.Errorf("search %s = %t after %d listings, want %t after %d", filename, found, bucket.Calls("ListObjectsV2"), wantFound, wantCalls)
+		}
+	}
+
+	search("db.dump", true, 1)
+	search("db.dump", true, 1) // Cached
+
+	// Objects uploaded through presigned URLs are seen once the TTL expires
+	bucket.Put("acme/inputs/2025-11-24/14-31-00/new.dump", s3fake.Object{Body: []byte("x")})
+	search("new.dump", false, 1)
+	clock.now = clock.now.Add(time.Minute)
+	search("new.dump", true, 2)
+
+	// Writes through the service and confirmations invalidate the prefix
+	if err := svc.PutObject(ctx, "acme/inputs/2025-11-24/14-32-00/put.dump", []byte("x"), "text/plain"); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	search("put.dump", true, 3)
+	bucket.Put("acme/inputs/2025-11-24/14-33-00/confirmed.dump", s3fake.Object{Body: []byte("x")})
+	svc.InvalidateListings("acme/inputs/2025-11-24/14-33-00/confirmed.dump")
+	search("confirmed.dump", true, 4)
+}
+
+func TestCallBudget(t *testing.T) {
+	clock := &stepClock{now: testTime}
+	svc, bucket := newTestService(t, map[string]string{
+		"S3_CALL_BUDGET_PER_MINUTE": "2",
+		"S3_CALL_BUDGET_MODE":       "reject",
+	}, service.WithClock(clock))
+	ctx := context.Background()
+	bucket.Put("acme/db.dump", s3fake.Object{Body: []byte("x")})
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.HeadObject(ctx, "acme/db.dump"); err != nil {
+			t.Fatalf("HeadObject %d: %v", i, err)
+		}
+	}
+	_, err := svc.HeadObject(ctx, "acme/db.dump")
+	var budgetErr *resilience.BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.RetryAfter != time.Minute {
+		t.Fatalf("HeadObject over budget = %v, want budget error retrying after 1m", err)
+	}
+	if calls := bucket.Calls("HeadObject"); calls != 2 {
+		t.Errorf("HeadObject reached S3 %d times, want 2", calls)
+	}
+
+	// The budget resets every minute
+	clock.now = clock.now.Add(time.Minute)
+	if _, err := svc.HeadObject(ctx, "acme/db.dump"); err != nil {
+		t.Errorf("HeadObject in the next minute: %v", err)
+	}
+}
+
+func TestCheckClockDrift(t *testing.T) {
+	svc, bucket := newTestService(t, map[string]string{"CLOCK_DRIFT_THRESHOLD_SECONDS": "30"})
+	ctx := context.Background()
+
+	// The service clock is pinned; S3 is 45 seconds ahead
+	bucket.SetNow(func() time.Time { return testTime.Add(45 * time.Second) })
+	drift := svc.CheckClockDrift(ctx)
+	if drift.DriftSeconds != -45 || !drift.Exceeded || drift.Error != "" {
+		t.Errorf("drift = %+v, want -45s exceeded", drift)
+	}
+
+	// A failed check keeps the last drift
+	bucket.FailWith("HeadBucket", errors.New("connection refused"))
+	drift = svc.CheckClockDrift(ctx)
+	if drift.DriftSeconds != -45 || !drift.Exceeded || drift.Error == "" {
+		t.Errorf("drift after a failed check = %+v, want -45s exceeded with the error", drift)
+	}
+
+	bucket.FailWith("HeadBucket", nil)
+	bucket.SetNow(func() time.Time { return testTime.Add(10 * time.Second) })
+	if drift := svc.CheckClockDrift(ctx); drift.Exceeded {
+		t.Errorf("drift = %+v, want within the threshold", drift)
+	}
+	if state := svc.ClockDriftState(); state == nil || state.DriftSeconds != -10 {
+		t.Errorf("ClockDriftState = %+v, want -10s", state)
+	}
+}
+
+func TestCorrelationHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("x-amz-request-id", "4442587FB7D0A2F9")
+		w.Header().Set("x-amz-id-2", "host-id")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	cfg, err := config.Load("", map[string]string{
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"S3_BUCKET_NAME":        "backups",
+		"COMPANY_PREFIX":        "acme",
+		"S3_ENDPOINT_URL":       server.URL,
+	})
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	svc, err := service.NewS3Service(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewS3Service: %v", err)
+	}
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := service.WithCorrelation(context.Background(), service.Correlation{RequestID: "req-1", TraceParent: traceParent})
+	_, err = svc.HeadObject(ctx, "acme/inputs/a.txt")
+	if err == nil {
+		t.Fatal("HeadObject succeeded, want the 403")
+	}
+	if got.Get("X-Request-ID") != "req-1" || got.Get("traceparent") != traceParent {
+		t.Errorf("S3 request headers = %v, want the correlation headers", got)
+	}
+	if !strings.Contains(got.Get("Authorization"), "traceparent;") || !strings.Contains(got.Get("Authorization"), "x-request-id") {
+		t.Errorf("Authorization = %q, want the correlation headers signed", got.Get("Authorization"))
+	}
+	if requestID, hostID := service.AWSRequestIDs(err); requestID != "4442587FB7D0A2F9" || hostID != "host-id" {
+		t.Errorf("AWSRequestIDs = %q, %q, want the S3 response IDs", requestID, hostID)
+	}
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	load := func(settings map[string]string) *config.Config {
+		values := map[string]string{
+			"AWS_REGION":            "us-east-1",
+			"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+			"AWS_SECRET_ACCESS_KEY": "secret",
+			"S3_BUCKET_NAME":        "backups",
+		}
+		for k, v := range settings {
+			values[k] = v
+		}
+		cfg, err := config.Load("", values)
+		if err != nil {
+			t.Fatalf("config.Load: %v", err)
+		}
+		return cfg
+	}
+
+	creds, err := service.CredentialsProvider(load(nil)).Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "AKIDEXAMPLE" || creds.SessionToken != "" {
+		t.Errorf("without a role: %+v, %v; want the configured credentials", creds, err)
+	}
+
+	// With a role, the provider assumes it through STS, served like S3 by
+	// S3_ENDPOINT_URL
+	var assumed []string
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		assumed = append(assumed, r.Form.Get("Action")+" "+r.Form.Get("RoleArn")+" "+r.Form.Get("ExternalId"))
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIAROLE</AccessKeyId>
+      <SecretAccessKey>role-secret</SecretAccessKey>
+      <SessionToken>role-token</SessionToken>
+      <Expiration>` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>arn:aws:sts::123456789012:assumed-role/signer/signer-service</Arn>
+      <AssumedRoleId>AROAEXAMPLE:signer-service</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`))
+	}))
+	defer sts.Close()
+
+	provider := service.CredentialsProvider(load(map[string]string{
+		"S3_ENDPOINT_URL":      sts.URL,
+		"AWS_ROLE_ARN":         "arn:aws:iam::123456789012:role/signer",
+		"AWS_ROLE_EXTERNAL_ID": "ext-1",
+	}))
+	for range 2 {
+		creds, err = provider.Retrieve(context.Background())
+		if err != nil || creds.AccessKeyID != "ASIAROLE" || creds.SessionToken != "role-token" {
+			t.Errorf("with a role: %+v, %v; want the role's credentials", creds, err)
+		}
+	}
+	if want := []string{"AssumeRole arn:aws:iam::123456789012:role/signer ext-1"}; !reflect.DeepEqual(assumed, want) {
+		t.Errorf("STS calls = %q, want %q (cached after the first)", assumed, want)
+	}
+}
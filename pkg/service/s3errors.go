@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+)
+
+// S3ErrorCode returns the S3 error code (e.g. NoSuchBucket, AccessDenied or
+// SlowDown) of err, or "" when err is not an error returned by S3
+func S3ErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// AWSRequestIDs returns the S3 request ID (x-amz-request-id) and extended
+// request ID (x-amz-id-2) of the response that caused err, when S3 answered
+func AWSRequestIDs(err error) (requestID, hostID string) {
+	var withRequestID interface{ ServiceRequestID() string }
+	if errors.As(err, &withRequestID) {
+		requestID = withRequestID.ServiceRequestID()
+	}
+	var withHostID interface{ ServiceHostID() string }
+	if errors.As(err, &withHostID) {
+		hostID = withHostID.ServiceHostID()
+	}
+	return requestID, hostID
+}
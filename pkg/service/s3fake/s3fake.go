@@ -0,0 +1,677 @@
+// Package s3fake provides an in-memory S3 bucket implementing service.S3API,
+// so the service and handlers can be tested without AWS.
+//
+// It models what the service relies on: objects with their metadata,
+// prefix listing with pagination, copies, legal holds and multipart uploads.
+// Bucket configuration calls report nothing configured.
+package s3fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/service"
+)
+
+// Object is an object stored in the fake bucket
+type Object struct {
+	Body              []byte
+	ContentType       string
+	ContentEncoding   string
+	Metadata          map[string]string
+	ChecksumSHA256    string
+	LegalHold         types.ObjectLockLegalHoldStatus
+	ReplicationStatus types.ReplicationStatus
+	Tags              map[string]string
+	LastModified      time.Time
+}
+
+// ETag returns the object's ETag, the hex MD5 of its body
+func (o *Object) ETag() string {
+	sum := md5.Sum(o.Body)
+	return hex.EncodeToString(sum[:])
+}
+
+// upload is an in-progress multipart upload
+type upload struct {
+	key       string
+	object    Object
+	parts     map[int32][]byte
+	initiated time.Time
+}
+
+// Bucket is an in-memory bucket. The zero value is not usable; call New.
+// It is safe for concurrent use.
+type Bucket struct {
+	mu      sync.Mutex
+	objects map[string]*Object
+	uploads map[string]*upload
+	nextID  int
+	calls   map[string]int
+	errs    map[string]error         // Failures injected per operation
+	delays  map[string]time.Duration // Latency injected per operation
+	now     func() time.Time
+}
+
+var _ service.S3API = (*Bucket)(nil)
+
+// New creates an empty bucket
+func New() *Bucket {
+	return &Bucket{
+		objects: make(map[string]*Object),
+		uploads: make(map[string]*upload),
+		calls:   make(map[string]int),
+		errs:    make(map[string]error),
+		delays:  make(map[string]time.Duration),
+		now:     time.Now,
+	}
+}
+
+// Put stores an object directly, as if a client had uploaded it
+func (b *Bucket) Put(key string, obj Object) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if obj.LastModified.IsZero() {
+		obj.LastModified = b.now().UTC()
+	}
+	b.objects[key] = &obj
+}
+
+// Get returns a copy of a stored object
+func (b *Bucket) Get(key string) (Object, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	obj, ok := b.objects[key]
+	if !ok {
+		return Object{}, false
+	}
+	return *obj, true
+}
+
+// Keys returns the stored object keys in order
+func (b *Bucket) Keys() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0, len(b.objects))
+	for k := range b.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// UploadPart stores a part of a multipart upload, as if a client had used a
+// part URL, and returns its ETag
+func (b *Bucket) UploadPart(uploadID string, partNumber int32, body []byte) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u, ok := b.uploads[uploadID]
+	if !ok {
+		return "", noSuchUpload()
+	}
+	u.parts[partNumber] = append([]byte(nil), body...)
+	sum := md5.Sum(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FailWith makes every later call to operation (e.g. "HeadObject") return err,
+// or succeed again when err is nil
+func (b *Bucket) FailWith(operation string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		delete(b.errs, operation)
+		return
+	}
+	b.errs[operation] = err
+}
+
+// Delay makes every later call to operation take at least d, widening the
+// window for concurrent requests to interleave
+func (b *Bucket) Delay(operation string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.delays[operation] = d
+}
+
+// Calls returns how many times operation was called
+func (b *Bucket) Calls(operation string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls[operation]
+}
+
+// begin counts a call, waits out any injected delay and returns the error
+// injected for it. It must be called with b.mu held; the lock is released
+// while waiting.
+func (b *Bucket) begin(operation string) error {
+	b.calls[operation]++
+	if d := b.delays[operation]; d > 0 {
+		b.mu.Unlock()
+		time.Sleep(d)
+		b.mu.Lock()
+	}
+	return b.errs[operation]
+}
+
+// SetNow makes the bucket read its time (object LastModified, the Date
+// response header) from now
+func (b *Bucket) SetNow(now func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// HeadBucket succeeds unless an error is injected. Its result metadata
+// carries a raw response with a Date header, as the SDK records it.
+func (b *Bucket) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("HeadBucket"); err != nil {
+		return nil, err
+	}
+	return &s3.HeadBucketOutput{ResultMetadata: b.responseMetadata()}, nil
+}
+
+// responseMetadata returns result metadata holding a raw response dated now
+func (b *Bucket) responseMetadata() middleware.Metadata {
+	response := &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}}
+	response.Header.Set("Date", b.now().UTC().Format(http.TimeFormat))
+	_, metadata, _ := awsmiddleware.AddRawResponse{}.HandleDeserialize(context.Background(), middleware.DeserializeInput{},
+		middleware.DeserializeHandlerFunc(func(ctx context.Context, in middleware.DeserializeInput) (middleware.DeserializeOutput, middleware.Metadata, error) {
+			return middleware.DeserializeOutput{RawResponse: response}, middleware.Metadata{}, nil
+		}))
+	return metadata
+}
+
+// GetBucketEncryption reports no default encryption
+func (b *Bucket) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("GetBucketEncryption"); err != nil {
+		return nil, err
+	}
+	return nil, apiError("ServerSideEncryptionConfigurationNotFoundError")
+}
+
+// GetBucketVersioning reports versioning as never enabled
+func (b *Bucket) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("GetBucketVersioning"); err != nil {
+		return nil, err
+	}
+	return &s3.GetBucketVersioningOutput{}, nil
+}
+
+// GetBucketLifecycleConfiguration reports no lifecycle rules
+func (b *Bucket) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("GetBucketLifecycleConfiguration"); err != nil {
+		return nil, err
+	}
+	return nil, apiError("NoSuchLifecycleConfiguration")
+}
+
+// GetBucketPolicyStatus reports no bucket policy
+func (b *Bucket) GetBucketPolicyStatus(ctx context.Context, params *s3.GetBucketPolicyStatusInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyStatusOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("GetBucketPolicyStatus"); err != nil {
+		return nil, err
+	}
+	return nil, apiError("NoSuchBucketPolicy")
+}
+
+// GetPublicAccessBlock reports no public access block
+func (b *Bucket) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("GetPublicAccessBlock"); err != nil {
+		return nil, err
+	}
+	return nil, apiError("NoSuchPublicAccessBlockConfiguration")
+}
+
+// ListObjectsV2 lists keys under the prefix in order, MaxKeys (default 1000)
+// at a time. With a delimiter, keys sharing the part of their name up to the
+// next delimiter are rolled up into CommonPrefixes.
+func (b *Bucket) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("ListObjectsV2"); err != nil {
+		return nil, err
+	}
+
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	after := aws.ToString(params.ContinuationToken)
+	if after == "" {
+		after = aws.ToString(params.StartAfter)
+	}
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+
+	// Entries are keys or common prefixes, listed together in key order
+	common := make(map[string]bool)
+	var entries []string
+	for k := range b.objects {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		entry := k
+		if i := strings.Index(k[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			entry = k[:len(prefix)+i+len(delimiter)]
+			if common[entry] {
+				continue
+			}
+			common[entry] = true
+		}
+		if entry > after {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+
+	out := &s3.ListObjectsV2Output{Prefix: params.Prefix, Delimiter: params.Delimiter, IsTruncated: aws.Bool(len(entries) > maxKeys)}
+	if len(entries) > maxKeys {
+		entries = entries[:maxKeys]
+		out.NextContinuationToken = aws.String(entries[len(entries)-1])
+	}
+	for _, k := range entries {
+		if common[k] {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(k)})
+			continue
+		}
+		obj := b.objects[k]
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(k),
+			Size:         aws.Int64(int64(len(obj.Body))),
+			ETag:         aws.String(`"` + obj.ETag() + `"`),
+			LastModified: aws.Time(obj.LastModified),
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+	}
+	out.KeyCount = aws.Int32(int32(len(out.Contents)))
+	return out, nil
+}
+
+// HeadObject describes an object, failing with *types.NotFound when missing
+func (b *Bucket) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("HeadObject"); err != nil {
+		return nil, err
+	}
+	obj, ok := b.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+
+	out := &s3.HeadObjectOutput{
+		ContentLength:             aws.Int64(int64(len(obj.Body))),
+		ETag:                      aws.String(`"` + obj.ETag() + `"`),
+		LastModified:              aws.Time(obj.LastModified),
+		Metadata:                  copyMap(obj.Metadata),
+		ObjectLockLegalHoldStatus: obj.LegalHold,
+		ReplicationStatus:         obj.ReplicationStatus,
+	}
+	if len(obj.Tags) > 0 {
+		out.TagCount = aws.Int32(int32(len(obj.Tags)))
+	}
+	if obj.ContentType != "" {
+		out.ContentType = aws.String(obj.ContentType)
+	}
+	if obj.ContentEncoding != "" {
+		out.ContentEncoding = aws.String(obj.ContentEncoding)
+	}
+	if params.ChecksumMode == types.ChecksumModeEnabled && obj.ChecksumSHA256 != "" {
+		out.ChecksumSHA256 = aws.String(obj.ChecksumSHA256)
+	}
+	return out, nil
+}
+
+// GetObject returns an object's body, failing with *types.NoSuchKey when
+// missing
+func (b *Bucket) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("GetObject"); err != nil {
+		return nil, err
+	}
+	obj, ok := b.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(obj.Body)),
+		ContentLength: aws.Int64(int64(len(obj.Body))),
+		ContentType:   params.ResponseContentType,
+		Metadata:      copyMap(obj.Metadata),
+	}, nil
+}
+
+// PutObject stores an object
+func (b *Bucket) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var body []byte
+	if params.Body != nil {
+		var err error
+		if body, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("PutObject"); err != nil {
+		return nil, err
+	}
+	obj := &Object{
+		Body:            body,
+		ContentType:     aws.ToString(params.ContentType),
+		ContentEncoding: aws.ToString(params.ContentEncoding),
+		Metadata:        copyMap(params.Metadata),
+		ChecksumSHA256:  aws.ToString(params.ChecksumSHA256),
+		LastModified:    b.now().UTC(),
+	}
+	b.objects[aws.ToString(params.Key)] = obj
+	return &s3.PutObjectOutput{ETag: aws.String(`"` + obj.ETag() + `"`)}, nil
+}
+
+// CopyObject copies an object within the bucket, keeping its metadata. The
+// copy source is "<bucket>/<key>", path escaped.
+func (b *Bucket) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("CopyObject"); err != nil {
+		return nil, err
+	}
+	source, err := url.PathUnescape(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	bucket := aws.ToString(params.Bucket)
+	key, ok := strings.CutPrefix(source, bucket+"/object/")
+	if !ok {
+		key, ok = strings.CutPrefix(source, bucket+"/")
+	}
+	obj, found := b.objects[key]
+	if !ok || !found {
+		return nil, &types.NoSuchKey{}
+	}
+
+	copied := *obj
+	copied.Body = append([]byte(nil), obj.Body...)
+	copied.Metadata = copyMap(obj.Metadata)
+	copied.Tags = copyMap(obj.Tags)
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		copied.Metadata = copyMap(params.Metadata)
+		copied.ContentType = aws.ToString(params.ContentType)
+		copied.ContentEncoding = aws.ToString(params.ContentEncoding)
+	}
+	if params.TaggingDirective == types.TaggingDirectiveReplace {
+		tags, err := url.ParseQuery(aws.ToString(params.Tagging))
+		if err != nil {
+			return nil, err
+		}
+		copied.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			copied.Tags[k] = v[0]
+		}
+	}
+	copied.LastModified = b.now().UTC()
+	b.objects[aws.ToString(params.Key)] = &copied
+	return &s3.CopyObjectOutput{}, nil
+}
+
+// DeleteObject removes an object; deleting a missing object succeeds
+func (b *Bucket) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("DeleteObject"); err != nil {
+		return nil, err
+	}
+	delete(b.objects, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects removes objects in a batch. Objects under a legal hold are
+// reported as AccessDenied, like deleting a locked object version.
+func (b *Bucket) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("DeleteObjects"); err != nil {
+		return nil, err
+	}
+	out := &s3.DeleteObjectsOutput{}
+	for _, id := range params.Delete.Objects {
+		key := aws.ToString(id.Key)
+		if obj, ok := b.objects[key]; ok && obj.LegalHold == types.ObjectLockLegalHoldStatusOn {
+			out.Errors = append(out.Errors, types.Error{Key: id.Key, Code: aws.String("AccessDenied"), Message: aws.String("Access Denied")})
+			continue
+		}
+		delete(b.objects, key)
+		out.Deleted = append(out.Deleted, types.DeletedObject{Key: id.Key})
+	}
+	return out, nil
+}
+
+// PutObjectLegalHold sets an object's legal hold status
+func (b *Bucket) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("PutObjectLegalHold"); err != nil {
+		return nil, err
+	}
+	obj, ok := b.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	if params.LegalHold != nil {
+		obj.LegalHold = params.LegalHold.Status
+	}
+	return &s3.PutObjectLegalHoldOutput{}, nil
+}
+
+// CreateMultipartUpload starts a multipart upload
+func (b *Bucket) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("CreateMultipartUpload"); err != nil {
+		return nil, err
+	}
+	b.nextID++
+	id := fmt.Sprintf("upload-%d", b.nextID)
+	b.uploads[id] = &upload{
+		key: aws.ToString(params.Key),
+		object: Object{
+			ContentType:     aws.ToString(params.ContentType),
+			ContentEncoding: aws.ToString(params.ContentEncoding),
+			Metadata:        copyMap(params.Metadata),
+		},
+		parts:     make(map[int32][]byte),
+		initiated: b.now().UTC(),
+	}
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   params.Bucket,
+		Key:      params.Key,
+		UploadId: aws.String(id),
+	}, nil
+}
+
+// ListParts lists the uploaded parts of a multipart upload
+func (b *Bucket) ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("ListParts"); err != nil {
+		return nil, err
+	}
+	u, ok := b.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, noSuchUpload()
+	}
+
+	numbers := make([]int32, 0, len(u.parts))
+	for n := range u.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	out := &s3.ListPartsOutput{IsTruncated: aws.Bool(false)}
+	for _, n := range numbers {
+		sum := md5.Sum(u.parts[n])
+		out.Parts = append(out.Parts, types.Part{
+			PartNumber: aws.Int32(n),
+			Size:       aws.Int64(int64(len(u.parts[n]))),
+			ETag:       aws.String(`"` + hex.EncodeToString(sum[:]) + `"`),
+		})
+	}
+	return out, nil
+}
+
+// CompleteMultipartUpload concatenates the listed parts into the object
+func (b *Bucket) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("CompleteMultipartUpload"); err != nil {
+		return nil, err
+	}
+	id := aws.ToString(params.UploadId)
+	u, ok := b.uploads[id]
+	if !ok {
+		return nil, noSuchUpload()
+	}
+	if params.MultipartUpload == nil || len(params.MultipartUpload.Parts) == 0 {
+		return nil, apiError("MalformedXML")
+	}
+
+	obj := u.object
+	for _, p := range params.MultipartUpload.Parts {
+		body, ok := u.parts[aws.ToInt32(p.PartNumber)]
+		if !ok {
+			return nil, apiError("InvalidPart")
+		}
+		obj.Body = append(obj.Body, body...)
+	}
+	obj.LastModified = b.now().UTC()
+	b.objects[u.key] = &obj
+	delete(b.uploads, id)
+	return &s3.CompleteMultipartUploadOutput{Key: aws.String(u.key)}, nil
+}
+
+// AbortMultipartUpload discards a multipart upload and its parts
+func (b *Bucket) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("AbortMultipartUpload"); err != nil {
+		return nil, err
+	}
+	id := aws.ToString(params.UploadId)
+	if _, ok := b.uploads[id]; !ok {
+		return nil, noSuchUpload()
+	}
+	delete(b.uploads, id)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// ListMultipartUploads lists in-progress uploads under the prefix
+func (b *Bucket) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin("ListMultipartUploads"); err != nil {
+		return nil, err
+	}
+	prefix := aws.ToString(params.Prefix)
+
+	ids := make([]string, 0, len(b.uploads))
+	for id, u := range b.uploads {
+		if strings.HasPrefix(u.key, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	out := &s3.ListMultipartUploadsOutput{IsTruncated: aws.Bool(false)}
+	for _, id := range ids {
+		u := b.uploads[id]
+		out.Uploads = append(out.Uploads, types.MultipartUpload{
+			Key:       aws.String(u.key),
+			UploadId:  aws.String(id),
+			Initiated: aws.Time(u.initiated),
+		})
+	}
+	return out, nil
+}
+
+// apiError builds an S3 error with the given code
+func apiError(code string) error {
+	return &smithy.GenericAPIError{Code: code, Message: code}
+}
+
+// noSuchUpload is the error S3 returns for an unknown upload ID
+func noSuchUpload() error {
+	return &types.NoSuchUpload{}
+}
+
+// copyMap copies a metadata map, keeping nil as nil
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// ErrInjected is a ready-made error for FailWith
+var ErrInjected = errors.New("s3fake: injected failure")
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// selfTestClient performs the HTTP requests against presigned URLs
+var selfTestClient = &http.Client{}
+
+// SelfTest round-trips a canary object through presigned URLs: it presigns a
+// PUT, uploads through it, presigns a GET, downloads and compares the content,
+// then deletes the object through a presigned DELETE. Each step is reported,
+// including S3's error response, to diagnose signature problems.
+func (s *S3Service) SelfTest(ctx context.Context) *CheckReport {
+	report := &CheckReport{OK: true}
+	key := s.canaryKey()
+	payload := []byte("signer-service self-test " + key)
+
+	var put, get, del *PresignedURL
+
+	uploaded := report.run("presign_put", func() error {
+		var err error
+		put, err = s.presign(http.MethodPut, key, map[string]string{"content-type": "text/plain"}, nil, time.Time{})
+		return err
+	}) && report.run("upload", func() error {
+		_, err := s.doPresigned(ctx, put, payload, http.StatusOK)
+		return err
+	})
+	if !uploaded {
+		return report
+	}
+
+	if report.run("presign_get", func() error {
+		var err error
+		get, err = s.presign(http.MethodGet, key, nil, nil, time.Time{})
+		return err
+	}) {
+		report.run("download", func() error {
+			body, err := s.doPresigned(ctx, get, nil, http.StatusOK)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(body, payload) {
+				return fmt.Errorf("downloaded content does not match the uploaded content")
+			}
+			return nil
+		})
+	}
+
+	// Always remove the canary once it was uploaded
+	if report.run("presign_delete", func() error {
+		var err error
+		del, err = s.presign(http.MethodDelete, key, nil, nil, time.Time{})
+		return err
+	}) {
+		report.run("delete", func() error {
+			_, err := s.doPresigned(ctx, del, nil, http.StatusNoContent)
+			return err
+		})
+	}
+
+	return report
+}
+
+// doPresigned sends a request to a presigned URL with its required headers
+// and returns the response body, failing on an unexpected status
+func (s *S3Service) doPresigned(ctx context.Context, presigned *PresignedURL, body []byte, wantStatus int) ([]byte, error) {
+	if s.opTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, presigned.Method, presigned.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range presigned.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := selfTestClient.Do(req)
+	if err != nil {
+		// Don't echo the presigned URL, it's usable until it expires
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("%s request failed: %w", presigned.Method, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != wantStatus {
+		return nil, fmt.Errorf("%s returned %d: %s", presigned.Method, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
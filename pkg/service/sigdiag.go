@@ -0,0 +1,265 @@
+package service
+
+import (
+	"crypto/hmac"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSignatureReport is returned when a signature report can't be
+// diagnosed: the URL is not a presigned URL or the S3 error is not XML
+var ErrInvalidSignatureReport = errors.New("invalid signature report")
+
+// SignatureReport describes a request S3 rejected with SignatureDoesNotMatch
+type SignatureReport struct {
+	Method  string            // HTTP method the client used
+	URL     string            // Presigned URL the client used
+	Headers map[string]string // Headers the client sent
+	S3Error string            // XML error body S3 returned
+}
+
+// S3SignatureError is the part of S3's SignatureDoesNotMatch error body that
+// describes how S3 computed the signature
+type S3SignatureError struct {
+	Code              string `xml:"Code" json:"code"`
+	Message           string `xml:"Message" json:"message"`
+	AWSAccessKeyID    string `xml:"AWSAccessKeyId" json:"aws_access_key_id,omitempty"`
+	StringToSign      string `xml:"StringToSign" json:"string_to_sign,omitempty"`
+	SignatureProvided string `xml:"SignatureProvided" json:"signature_provided,omitempty"`
+	CanonicalRequest  string `xml:"CanonicalRequest" json:"canonical_request,omitempty"`
+}
+
+// SignatureDivergence is the first point where the signer's canonical request
+// or string to sign differs from S3's
+type SignatureDivergence struct {
+	Component string `json:"component"` // method, uri, query, header, signed_headers, payload_hash or string_to_sign
+	Name      string `json:"name,omitempty"`
+	Expected  string `json:"expected"` // What the signer signed
+	Actual    string `json:"actual"`   // What S3 computed from the request it received
+}
+
+// SignatureDiagnosis compares how the signer signed a URL with how S3
+// verified the request made with it
+type SignatureDiagnosis struct {
+	// SignatureValid reports whether the URL's signature matches the request
+	// as reported (method, URL and headers). Nil when it can't be checked:
+	// SigV4A signatures or URLs signed with other credentials.
+	SignatureValid   *bool                `json:"signature_valid,omitempty"`
+	CanonicalRequest string               `json:"canonical_request"` // As the signer computes it
+	StringToSign     string               `json:"string_to_sign"`
+	S3               S3SignatureError     `json:"s3"`
+	Divergence       *SignatureDivergence `json:"divergence,omitempty"`
+	Hint             string               `json:"hint"`
+}
+
+// DiagnoseSignature re-derives the canonical request and string to sign of a
+// presigned URL as the signer computes them and reports where they first
+// diverge from those in S3's error. It never reveals a signature the client
+// doesn't already have.
+func (s *S3Service) DiagnoseSignature(report SignatureReport) (*SignatureDiagnosis, error) {
+	var s3Err S3SignatureError
+	if err := xml.Unmarshal([]byte(report.S3Error), &s3Err); err != nil {
+		return nil, fmt.Errorf("%w: s3_error is not an S3 XML error: %v", ErrInvalidSignatureReport, err)
+	}
+	u, err := url.Parse(report.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignatureReport, err)
+	}
+	query := u.Query()
+	for _, param := range []string{"X-Amz-Algorithm", "X-Amz-Credential", "X-Amz-Date", "X-Amz-SignedHeaders", "X-Amz-Signature"} {
+		if query.Get(param) == "" {
+			return nil, fmt.Errorf("%w: url has no %s; it is not a presigned URL", ErrInvalidSignatureReport, param)
+		}
+	}
+	accessKey, scope, _ := strings.Cut(query.Get("X-Amz-Credential"), "/")
+
+	canonical := canonicalRequestFromURL(strings.ToUpper(report.Method), u, report.Headers)
+	stringToSign := query.Get("X-Amz-Algorithm") + "\n" + query.Get("X-Amz-Date") + "\n" + scope + "\n" + s.signer.hash(canonical)
+
+	diagnosis := &SignatureDiagnosis{CanonicalRequest: canonical, StringToSign: stringToSign, S3: s3Err}
+	creds, err := s.signer.credentials()
+	if scopeParts := strings.Split(scope, "/"); err == nil && query.Get("X-Amz-Algorithm") == algorithmSigV4 && accessKey == creds.AccessKeyID && len(scopeParts) == 4 {
+		signature := s.signer.hmacSHA256Hex(s.signer.signingKey(creds, scopeParts[0], scopeParts[1], scopeParts[2]), stringToSign)
+		valid := hmac.Equal([]byte(signature), []byte(query.Get("X-Amz-Signature")))
+		diagnosis.SignatureValid = &valid
+	}
+	if s3Err.CanonicalRequest != "" {
+		diagnosis.Divergence = canonicalDivergence(canonical, s3Err.CanonicalRequest)
+	}
+	if diagnosis.Divergence == nil && s3Err.StringToSign != "" && s3Err.StringToSign != stringToSign {
+		diagnosis.Divergence = &SignatureDivergence{Component: "string_to_sign", Expected: stringToSign, Actual: s3Err.StringToSign}
+	}
+	diagnosis.Hint = signatureHint(diagnosis, accessKey)
+	return diagnosis, nil
+}
+
+// canonicalRequestFromURL builds the canonical request of a presigned URL
+// the way the signer does, taking signed header values from headers and the
+// host from the URL
+func canonicalRequestFromURL(method string, u *url.URL, headers map[string]string) string {
+	lowered := make(map[string]string, len(headers))
+	for name, value := range headers {
+		lowered[strings.ToLower(name)] = value
+	}
+	lowered["host"] = u.Host
+
+	var params []queryParam
+	for key, values := range u.Query() {
+		if key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, queryParam{key, value})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].key != params[j].key {
+			return params[i].key < params[j].key
+		}
+		return params[i].value < params[j].value
+	})
+
+	signedHeaders := u.Query().Get("X-Amz-SignedHeaders")
+	var b strings.Builder
+	b.WriteString(method)
+	b.WriteByte('\n')
+	b.WriteString(u.Path)
+	b.WriteByte('\n')
+	writeQueryString(&b, params, true)
+	b.WriteByte('\n')
+	for _, name := range strings.Split(signedHeaders, ";") {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.TrimSpace(lowered[name]))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	b.WriteString(signedHeaders)
+	b.WriteString("\nUNSIGNED-PAYLOAD")
+	return b.String()
+}
+
+// canonicalDivergence returns the first difference between the expected and
+// actual canonical requests, or nil when they are equal
+func canonicalDivergence(expected, actual string) *SignatureDivergence {
+	if expected == actual {
+		return nil
+	}
+	exp, act := canonicalParts(expected), canonicalParts(actual)
+
+	for _, c := range []struct {
+		component string
+		exp, act  string
+	}{
+		{"method", exp.method, act.method},
+		{"uri", exp.uri, act.uri},
+	} {
+		if c.exp != c.act {
+			return &SignatureDivergence{Component: c.component, Expected: c.exp, Actual: c.act}
+		}
+	}
+	if exp.query != act.query {
+		return listDivergence("query", strings.Split(exp.query, "&"), strings.Split(act.query, "&"), "=")
+	}
+	if exp.signedHeaders != act.signedHeaders {
+		return &SignatureDivergence{Component: "signed_headers", Expected: exp.signedHeaders, Actual: act.signedHeaders}
+	}
+	if d := listDivergence("header", exp.headers, act.headers, ":"); d != nil {
+		return d
+	}
+	if exp.payloadHash != act.payloadHash {
+		return &SignatureDivergence{Component: "payload_hash", Expected: exp.payloadHash, Actual: act.payloadHash}
+	}
+	// Only whitespace or line structure differs
+	return &SignatureDivergence{Component: "canonical_request", Expected: expected, Actual: actual}
+}
+
+// canonicalRequestParts are the components of a canonical request
+type canonicalRequestParts struct {
+	method, uri, query string
+	headers            []string
+	signedHeaders      string
+	payloadHash        string
+}
+
+// canonicalParts splits a canonical request into its components. Missing
+// trailing components are left empty.
+func canonicalParts(canonical string) canonicalRequestParts {
+	lines := strings.Split(canonical, "\n")
+	line := func(i int) string {
+		if i < len(lines) {
+			return lines[i]
+		}
+		return ""
+	}
+
+	parts := canonicalRequestParts{method: line(0), uri: line(1), query: line(2)}
+	i := 3
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		parts.headers = append(parts.headers, lines[i])
+	}
+	parts.signedHeaders = line(i + 1)
+	parts.payloadHash = line(i + 2)
+	return parts
+}
+
+// listDivergence returns the first entry of a sorted name<sep>value list
+// (query parameters or headers) that differs
+func listDivergence(component string, expected, actual []string, sep string) *SignatureDivergence {
+	for i := 0; i < max(len(expected), len(actual)); i++ {
+		var exp, act string
+		if i < len(expected) {
+			exp = expected[i]
+		}
+		if i < len(actual) {
+			act = actual[i]
+		}
+		if exp == act {
+			continue
+		}
+		expName, expValue, _ := strings.Cut(exp, sep)
+		actName, actValue, _ := strings.Cut(act, sep)
+		if expName != actName {
+			// A parameter or header was added or removed
+			name := expName
+			if name == "" || (actName != "" && actName < expName) {
+				name = actName
+			}
+			return &SignatureDivergence{Component: component, Name: name, Expected: exp, Actual: act}
+		}
+		return &SignatureDivergence{Component: component, Name: expName, Expected: expValue, Actual: actValue}
+	}
+	return nil
+}
+
+// signatureHint explains the likely cause of a diagnosed failure
+func signatureHint(d *SignatureDiagnosis, accessKey string) string {
+	if d.S3.AWSAccessKeyID != "" && d.S3.AWSAccessKeyID != accessKey {
+		return "S3 verified the request with another access key than the URL's X-Amz-Credential"
+	}
+	if d.Divergence != nil {
+		switch d.Divergence.Component {
+		case "method":
+			return "The request used a different HTTP method than the URL was signed for"
+		case "uri":
+			return "The client or a proxy re-encoded the object path; use the URL exactly as returned"
+		case "query":
+			return fmt.Sprintf("Query parameter %s was added, removed or re-encoded; use the URL exactly as returned", d.Divergence.Name)
+		case "signed_headers":
+			return "X-Amz-SignedHeaders in the URL was modified"
+		case "header":
+			return fmt.Sprintf("Header %s reached S3 with another value than was signed; send the returned headers verbatim (check for proxies or SDKs adding or rewriting it)", d.Divergence.Name)
+		case "string_to_sign":
+			return "The canonical requests match but the date or credential scope differ; the URL's X-Amz-Date or X-Amz-Credential was modified"
+		}
+		return "The canonical requests differ only in whitespace or line structure"
+	}
+	if d.SignatureValid != nil && !*d.SignatureValid {
+		return "The request matches S3's view, but the URL's signature is not this signer's for it: the URL was modified or the reported method or headers differ from those signed"
+	}
+	return "The signer and S3 agree on the request; the secret key used to sign likely differs from the one S3 has for the access key (rotated or mismatched credentials)"
+}
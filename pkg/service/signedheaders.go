@@ -0,0 +1,92 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SignedHeadersError is returned when the headers an upload URL would sign
+// break the deployment's signed header policy
+type SignedHeadersError struct {
+	Missing    []string // Required headers the upload doesn't sign
+	Disallowed []string // Signed headers outside the allowed list
+}
+
+func (e *SignedHeadersError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "uploads must sign "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Disallowed) > 0 {
+		problems = append(problems, "uploads may not sign "+strings.Join(e.Disallowed, ", "))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// NormalizeContentMD5 converts an MD5 digest given as hex or base64 into the
+// base64 form of the Content-MD5 header
+func NormalizeContentMD5(digest string) (string, error) {
+	if len(digest) == 32 {
+		if raw, err := hex.DecodeString(digest); err == nil {
+			return base64.StdEncoding.EncodeToString(raw), nil
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil || len(raw) != 16 {
+		return "", fmt.Errorf("content_md5 must be an MD5 digest in hex or base64")
+	}
+	return digest, nil
+}
+
+// RequiresSignedHeader reports whether every upload URL must sign header
+func (s *S3Service) RequiresSignedHeader(header string) bool {
+	for _, required := range s.requiredHeaders {
+		if required == header {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSignedHeaders checks the headers an upload URL would sign against
+// the configured required and allowed signed headers. It returns a
+// *SignedHeadersError naming every violation.
+func (s *S3Service) CheckSignedHeaders(headers map[string]string) error {
+	e := &SignedHeadersError{}
+	for _, required := range s.requiredHeaders {
+		if _, ok := headers[required]; !ok {
+			e.Missing = append(e.Missing, required)
+		}
+	}
+	if len(s.allowedHeaders) > 0 {
+		for header := range headers {
+			if !headerAllowed(s.allowedHeaders, header) {
+				e.Disallowed = append(e.Disallowed, header)
+			}
+		}
+		sort.Strings(e.Disallowed)
+	}
+
+	if len(e.Missing) > 0 || len(e.Disallowed) > 0 {
+		return e
+	}
+	return nil
+}
+
+// headerAllowed reports whether header matches an allowed name or a
+// prefix pattern ending in "*" such as x-amz-meta-*
+func headerAllowed(allowed []string, header string) bool {
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(header, prefix) {
+				return true
+			}
+		} else if pattern == header {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package service
+
+import "fmt"
+
+// SSE-C: S3 encrypts and decrypts the object with a key the request sends
+// and keeps only its MD5, so AWS never stores the key
+const (
+	// SSECustomerAlgorithm is the only algorithm S3 accepts
+	SSECustomerAlgorithm = "AES256"
+	// SSECustomerKeyHeader carries the base64 key; clients send it unsigned
+	// next to the signed algorithm and key MD5
+	SSECustomerKeyHeader = "x-amz-server-side-encryption-customer-key"
+
+	sseCustomerAlgorithmHeader = "x-amz-server-side-encryption-customer-algorithm"
+	sseCustomerKeyMD5Header    = "x-amz-server-side-encryption-customer-key-md5"
+)
+
+// NormalizeSSECustomerKeyMD5 converts the MD5 of an SSE-C key given as hex
+// or base64 into the base64 form S3 expects
+func NormalizeSSECustomerKeyMD5(digest string) (string, error) {
+	md5, err := NormalizeContentMD5(digest)
+	if err != nil {
+		return "", fmt.Errorf("sse_customer_key_md5 must be an MD5 digest in hex or base64")
+	}
+	return md5, nil
+}
+
+// sseCustomerHeaders returns the SSE-C headers signed for the key whose
+// base64 MD5 is keyMD5, binding the URL to that key
+func sseCustomerHeaders(keyMD5 string) map[string]string {
+	return map[string]string{
+		sseCustomerAlgorithmHeader: SSECustomerAlgorithm,
+		sseCustomerKeyMD5Header:    keyMD5,
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxSubpathBytes bounds a client-suggested subpath
+const maxSubpathBytes = 255
+
+// SubpathError is returned when a client-suggested subpath is rejected
+type SubpathError struct {
+	Subpath string
+	Reason  string
+}
+
+func (e *SubpathError) Error() string {
+	return fmt.Sprintf("subpath %q %s", e.Subpath, e.Reason)
+}
+
+// compileSubpathPattern anchors pattern so it must match the whole subpath,
+// returning nil for an empty pattern
+func compileSubpathPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// CheckSubpath validates a folder suggested by the client to place an upload
+// under the timestamped prefix (inputs/DATE/TIME/<subpath>/filename). It must
+// be relative folder names separated by "/", without "." or ".." segments,
+// and match UPLOAD_SUBPATH_PATTERN; subpaths are refused when no pattern is
+// configured. An empty subpath is always valid.
+func (s *S3Service) CheckSubpath(subpath string) error {
+	if subpath == "" {
+		return nil
+	}
+	reject := func(reason string) error { return &SubpathError{Subpath: subpath, Reason: reason} }
+
+	if s.subpathPattern == nil {
+		return reject("is not accepted: UPLOAD_SUBPATH_PATTERN is not configured")
+	}
+	if len(subpath) > maxSubpathBytes {
+		return reject(fmt.Sprintf("must be at most %d bytes", maxSubpathBytes))
+	}
+	if strings.ContainsRune(subpath, '\\') || strings.IndexFunc(subpath, unicode.IsControl) >= 0 {
+		return reject("must not contain backslashes or control characters")
+	}
+	for _, segment := range strings.Split(subpath, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return reject(`must be folder names separated by "/", without empty, "." or ".." segments`)
+		}
+	}
+	if !s.subpathPattern.MatchString(subpath) {
+		return reject("does not match the allowed pattern " + s.subpathPattern.String())
+	}
+	return nil
+}
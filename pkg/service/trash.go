@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Soft-deleted objects are moved under the company prefix to
+// trash/<deletion time>/<key relative to the prefix>
+const (
+	trashFolder     = "trash/"
+	trashTimeLayout = "2006-01-02T15-04-05Z"
+)
+
+// TrashedObject is a soft-deleted object
+type TrashedObject struct {
+	TrashKey    string    `json:"trash_key"`
+	OriginalKey string    `json:"original_key"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	Size        int64     `json:"size"`
+}
+
+// TrashPage is one page of the trash listing
+type TrashPage struct {
+	Objects   []TrashedObject `json:"objects"`
+	NextToken string          `json:"next_token,omitempty"` // Continuation token of the next page
+}
+
+// PurgeReport summarizes one trash purge pass
+type PurgeReport struct {
+	Prefix     string          `json:"prefix"`
+	Cutoff     time.Time       `json:"cutoff"`
+	Scanned    int             `json:"scanned"`
+	Purged     []TrashedObject `json:"purged"`
+	BytesFreed int64           `json:"bytes_freed"`
+	Errors     []string        `json:"errors,omitempty"`
+}
+
+// IsTrashKey reports whether objectKey lies in the trash of the company prefix
+func (s *S3Service) IsTrashKey(objectKey string) bool {
+	return strings.HasPrefix(objectKey, s.buildObjectKey(trashFolder))
+}
+
+// parseTrashKey returns the original key and deletion time of a trash key
+func (s *S3Service) parseTrashKey(trashKey string) (string, time.Time, bool) {
+	rest, ok := strings.CutPrefix(trashKey, s.buildObjectKey(trashFolder))
+	if !ok {
+		return "", time.Time{}, false
+	}
+	stamp, relative, ok := strings.Cut(rest, "/")
+	if !ok || relative == "" {
+		return "", time.Time{}, false
+	}
+	deletedAt, err := time.Parse(trashTimeLayout, stamp)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return s.buildObjectKey(relative), deletedAt, true
+}
+
+// TrashOriginalKey returns the key a trashed object is restored to, or false
+// if trashKey is not a trash key of the company prefix
+func (s *S3Service) TrashOriginalKey(trashKey string) (string, bool) {
+	original, _, ok := s.parseTrashKey(trashKey)
+	return original, ok
+}
+
+// trashedObject describes the object stored at trashKey, or false if the key
+// is not laid out as a trash key
+func (s *S3Service) trashedObject(trashKey string, size int64) (TrashedObject, bool) {
+	original, deletedAt, ok := s.parseTrashKey(trashKey)
+	if !ok {
+		return TrashedObject{}, false
+	}
+	return TrashedObject{TrashKey: trashKey, OriginalKey: original, DeletedAt: deletedAt, Size: size}, true
+}
+
+// TrashObject soft-deletes source by moving it into the trash, returning
+// where it went. A *KeyTooLongError is returned when the trash key would
+// exceed MaxKeyBytes.
+func (s *S3Service) TrashObject(ctx context.Context, source *ObjectInfo) (*TrashedObject, error) {
+	relative := source.Key
+	if s.companyPrefix != "" {
+		relative = strings.TrimPrefix(relative, s.companyPrefix+"/")
+	}
+	deletedAt := s.clock.Now().UTC().Truncate(time.Second)
+	trashKey := s.buildObjectKey(trashFolder + deletedAt.Format(trashTimeLayout) + "/" + relative)
+	if len(trashKey) > MaxKeyBytes {
+		return nil, &KeyTooLongError{KeyBytes: len(trashKey)}
+	}
+
+	if _, err := s.MoveObject(ctx, source, trashKey); err != nil {
+		return nil, err
+	}
+	return &TrashedObject{TrashKey: trashKey, OriginalKey: source.Key, DeletedAt: deletedAt, Size: source.Size}, nil
+}
+
+// ListTrash lists one page of soft-deleted objects, oldest deletions first
+func (s *S3Service) ListTrash(ctx context.Context, token string, maxKeys int32) (*TrashPage, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(s.buildObjectKey(trashFolder)),
+	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+	if maxKeys > 0 {
+		input.MaxKeys = aws.Int32(maxKeys)
+	}
+
+	var page *s3.ListObjectsV2Output
+	err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+		var err error
+		page, err = s.api().ListObjectsV2(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	result := &TrashPage{Objects: make([]TrashedObject, 0, len(page.Contents))}
+	for _, obj := range page.Contents {
+		if trashed, ok := s.trashedObject(aws.ToString(obj.Key), aws.ToInt64(obj.Size)); ok {
+			result.Objects = append(result.Objects, trashed)
+		}
+	}
+	if aws.ToBool(page.IsTruncated) {
+		result.NextToken = aws.ToString(page.NextContinuationToken)
+	}
+	return result, nil
+}
+
+// PurgeTrash permanently deletes objects soft-deleted more than maxAge ago.
+// Failures on individual objects are recorded in the report rather than
+// stopping the pass.
+func (s *S3Service) PurgeTrash(ctx context.Context, maxAge time.Duration) (*PurgeReport, error) {
+	report := &PurgeReport{
+		Prefix: s.buildObjectKey(trashFolder),
+		Cutoff: s.clock.Now().UTC().Add(-maxAge),
+		Purged: []TrashedObject{},
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(report.Prefix),
+	}
+	for {
+		var page *s3.ListObjectsV2Output
+		err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+			var err error
+			page, err = s.api().ListObjectsV2(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list trash: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			report.Scanned++
+			trashed, ok := s.trashedObject(aws.ToString(obj.Key), aws.ToInt64(obj.Size))
+			if !ok || !trashed.DeletedAt.Before(report.Cutoff) {
+				continue
+			}
+			if err := s.DeleteObject(ctx, trashed.TrashKey); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", trashed.TrashKey, err))
+				continue
+			}
+			report.Purged = append(report.Purged, trashed)
+			report.BytesFreed += trashed.Size
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	return report, nil
+}
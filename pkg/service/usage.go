@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// otherDay groups objects that don't follow the inputs/DATE/TIME/ layout
+const otherDay = "other"
+
+// DayUsage is the object count and size of one day folder
+type DayUsage struct {
+	Date    string `json:"date"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// UsageReport summarizes storage used under the company prefix
+type UsageReport struct {
+	Prefix       string     `json:"prefix"`
+	CollectedAt  time.Time  `json:"collected_at"`
+	Duration     string     `json:"duration"`
+	TotalObjects int64      `json:"total_objects"`
+	TotalBytes   int64      `json:"total_bytes"`
+	Days         []DayUsage `json:"days"`
+}
+
+// CollectPrefixUsage walks every object under the company prefix and sums
+// object counts and bytes per day folder. This lists the whole prefix and is
+// meant to run from a background job, not per request.
+func (s *S3Service) CollectPrefixUsage(ctx context.Context) (*UsageReport, error) {
+	start := time.Now()
+	prefix := s.searchPrefix()
+	days := make(map[string]*DayUsage)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket()),
+		Prefix: aws.String(prefix),
+	}
+
+	for {
+		var page *s3.ListObjectsV2Output
+		err := s.call(ctx, "ListObjectsV2", func(ctx context.Context) error {
+			var err error
+			page, err = s.api().ListObjectsV2(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			day := s.dayFolder(aws.ToString(obj.Key))
+			usage, ok := days[day]
+			if !ok {
+				usage = &DayUsage{Date: day}
+				days[day] = usage
+			}
+			usage.Objects++
+			usage.Bytes += aws.ToInt64(obj.Size)
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	report := &UsageReport{
+		Prefix:      prefix,
+		CollectedAt: time.Now().UTC(),
+		Duration:    time.Since(start).Round(time.Millisecond).String(),
+		Days:        make([]DayUsage, 0, len(days)),
+	}
+	for _, usage := range days {
+		report.TotalObjects += usage.Objects
+		report.TotalBytes += usage.Bytes
+		report.Days = append(report.Days, *usage)
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+
+	return report, nil
+}
+
+// dayFolder extracts the YYYY-MM-DD folder from a key laid out as
+// [prefix/]inputs/YYYY-MM-DD/HH-MM-SS/filename
+func (s *S3Service) dayFolder(objectKey string) string {
+	if s.companyPrefix != "" {
+		objectKey = strings.TrimPrefix(objectKey, s.companyPrefix+"/")
+	}
+
+	parts := strings.SplitN(objectKey, "/", 3)
+	if len(parts) < 3 || parts[0] != "inputs" {
+		return otherDay
+	}
+	if _, err := time.Parse("2006-01-02", parts[1]); err != nil {
+		return otherDay
+	}
+	return parts[1]
+}
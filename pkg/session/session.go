@@ -0,0 +1,276 @@
+// Package session tracks multipart upload sessions and publishes progress
+// events to subscribers.
+package session
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/idgen"
+)
+
+// Status is the lifecycle state of an upload session
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusCompleted Status = "completed"
+	StatusAborted   Status = "aborted"
+)
+
+// Event types published for a session
+const (
+	EventPartCompleted = "part_completed"
+	EventConfirmed     = "confirmed"
+	EventAborted       = "aborted"
+)
+
+var (
+	// ErrNotFound is returned when a session ID is unknown
+	ErrNotFound = errors.New("session not found")
+	// ErrNotActive is returned when modifying a completed or aborted session
+	ErrNotActive = errors.New("session is not active")
+	// ErrBusy is returned while another request is completing or aborting
+	// the session
+	ErrBusy = errors.New("session is being finished")
+)
+
+// Part is a completed part of a multipart upload
+type Part struct {
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// Session is a multipart upload in progress
+type Session struct {
+	ID        string    `json:"session_id"`
+	Filename  string    `json:"filename"`
+	ObjectKey string    `json:"object_key"`
+	UploadID  string    `json:"upload_id"`
+	Status    Status    `json:"status"`
+	Parts     []Part    `json:"parts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Event describes a change to a session, streamed to progress subscribers
+type Event struct {
+	Type           string    `json:"type"`
+	SessionID      string    `json:"session_id"`
+	PartNumber     int       `json:"part_number,omitempty"`
+	PartsCompleted int       `json:"parts_completed"`
+	Time           time.Time `json:"time"`
+}
+
+// entry is the internal mutable record behind a Session
+type entry struct {
+	session Session
+	parts   map[int]string
+	claimed bool // A request is finishing the upload in S3
+}
+
+// Store keeps upload sessions in memory and fans out their events
+type Store struct {
+	mu          sync.Mutex
+	sessions    map[string]*entry
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewStore creates an empty session store
+func NewStore() *Store {
+	return &Store{
+		sessions:    make(map[string]*entry),
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Create registers a new active session for a multipart upload
+func (s *Store) Create(filename, objectKey, uploadID string) Session {
+	now := time.Now().UTC()
+	e := &entry{
+		session: Session{
+			ID:        idgen.New(),
+			Filename:  filename,
+			ObjectKey: objectKey,
+			UploadID:  uploadID,
+			Status:    StatusActive,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		parts: make(map[int]string),
+	}
+
+	s.mu.Lock()
+	s.sessions[e.session.ID] = e
+	s.mu.Unlock()
+
+	return e.snapshot()
+}
+
+// Get returns a snapshot of the session
+func (s *Store) Get(id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return e.snapshot(), nil
+}
+
+// CompletePart records an uploaded part and publishes a part_completed event
+func (s *Store) CompletePart(id string, partNumber int, etag string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	if e.session.Status != StatusActive {
+		return Session{}, ErrNotActive
+	}
+	if e.claimed {
+		return Session{}, ErrBusy
+	}
+
+	e.parts[partNumber] = etag
+	e.session.UpdatedAt = time.Now().UTC()
+	s.publish(id, Event{
+		Type:           EventPartCompleted,
+		SessionID:      id,
+		PartNumber:     partNumber,
+		PartsCompleted: len(e.parts),
+		Time:           e.session.UpdatedAt,
+	})
+
+	return e.snapshot(), nil
+}
+
+// Claim reserves an active session for the caller about to complete or abort
+// its upload, so concurrent requests do not finish it twice. The claim ends
+// with Finish, or with Release if the upload could not be finished.
+func (s *Store) Claim(id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	if e.session.Status != StatusActive {
+		return Session{}, ErrNotActive
+	}
+	if e.claimed {
+		return Session{}, ErrBusy
+	}
+
+	e.claimed = true
+	return e.snapshot(), nil
+}
+
+// Release drops a claim on a session that is still active
+func (s *Store) Release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.sessions[id]; ok {
+		e.claimed = false
+	}
+}
+
+// Finish moves an active session to completed or aborted, publishes the
+// matching event and closes all subscriber streams
+func (s *Store) Finish(id string, status Status) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	if e.session.Status != StatusActive {
+		return Session{}, ErrNotActive
+	}
+
+	e.session.Status = status
+	e.claimed = false
+	e.session.UpdatedAt = time.Now().UTC()
+
+	eventType := EventConfirmed
+	if status == StatusAborted {
+		eventType = EventAborted
+	}
+	s.publish(id, Event{
+		Type:           eventType,
+		SessionID:      id,
+		PartsCompleted: len(e.parts),
+		Time:           e.session.UpdatedAt,
+	})
+
+	for ch := range s.subscribers[id] {
+		close(ch)
+	}
+	delete(s.subscribers, id)
+
+	return e.snapshot(), nil
+}
+
+// Subscribe returns a channel receiving the session's future events. The
+// channel is closed when the session finishes or cancel is called.
+func (s *Store) Subscribe(id string) (<-chan Event, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.sessions[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	ch := make(chan Event, 16)
+	if e.session.Status != StatusActive {
+		close(ch)
+		return ch, func() {}, nil
+	}
+
+	if s.subscribers[id] == nil {
+		s.subscribers[id] = make(map[chan Event]struct{})
+	}
+	s.subscribers[id][ch] = struct{}{}
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[id][ch]; ok {
+			delete(s.subscribers[id], ch)
+			close(ch)
+		}
+	}
+
+	return ch, cancel, nil
+}
+
+// publish delivers an event to all subscribers of a session. Slow
+// subscribers drop events rather than block the store. Must hold s.mu.
+func (s *Store) publish(id string, event Event) {
+	for ch := range s.subscribers[id] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// snapshot copies the session with its parts sorted by part number
+func (e *entry) snapshot() Session {
+	session := e.session
+	session.Parts = make([]Part, 0, len(e.parts))
+	for n, etag := range e.parts {
+		session.Parts = append(session.Parts, Part{PartNumber: n, ETag: etag})
+	}
+	sort.Slice(session.Parts, func(i, j int) bool { return session.Parts[i].PartNumber < session.Parts[j].PartNumber })
+	return session
+}
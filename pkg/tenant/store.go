@@ -0,0 +1,228 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps tenants in memory; they are lost on restart
+type MemoryStore struct {
+	mu      sync.RWMutex
+	tenants map[string]Tenant
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tenants: make(map[string]Tenant)}
+}
+
+// List returns all tenants ordered by ID
+func (s *MemoryStore) List() ([]Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list(), nil
+}
+
+func (s *MemoryStore) list() []Tenant {
+	tenants := make([]Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// Get returns the tenant with the given ID
+func (s *MemoryStore) Get(id string) (Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tenants[id]
+	if !ok {
+		return Tenant{}, ErrNotFound
+	}
+	return t, nil
+}
+
+// GetByAPIKeyHash returns the tenant whose API key hashes to hash
+func (s *MemoryStore) GetByAPIKeyHash(hash string) (Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tenants {
+		if t.APIKeyHash != "" && t.APIKeyHash == hash {
+			return t, nil
+		}
+	}
+	return Tenant{}, ErrNotFound
+}
+
+// Create adds a new tenant
+func (s *MemoryStore) Create(t Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(t)
+}
+
+func (s *MemoryStore) create(t Tenant) error {
+	if _, ok := s.tenants[t.ID]; ok {
+		return ErrExists
+	}
+	if err := s.checkPrefix(t); err != nil {
+		return err
+	}
+	s.tenants[t.ID] = t
+	return nil
+}
+
+// Update replaces an existing tenant
+func (s *MemoryStore) Update(t Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(t)
+}
+
+func (s *MemoryStore) update(t Tenant) error {
+	if _, ok := s.tenants[t.ID]; !ok {
+		return ErrNotFound
+	}
+	if err := s.checkPrefix(t); err != nil {
+		return err
+	}
+	s.tenants[t.ID] = t
+	return nil
+}
+
+// Delete removes a tenant
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(id)
+}
+
+func (s *MemoryStore) delete(id string) error {
+	if _, ok := s.tenants[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.tenants, id)
+	return nil
+}
+
+// checkPrefix rejects a prefix overlapping another tenant's
+func (s *MemoryStore) checkPrefix(t Tenant) error {
+	for id, other := range s.tenants {
+		if id != t.ID && prefixesOverlap(t.Prefix, other.Prefix) {
+			return fmt.Errorf("%w %s", ErrPrefixConflict, id)
+		}
+	}
+	return nil
+}
+
+// FileStore keeps tenants in memory and rewrites a JSON file on every change
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+// NewFileStore loads the tenants in path, which is created on the first
+// change if it doesn't exist
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: MemoryStore{tenants: make(map[string]Tenant)}, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant store: %w", err)
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenant store %s: %w", path, err)
+	}
+	for _, t := range tenants {
+		if err := s.create(t); err != nil {
+			return nil, fmt.Errorf("invalid tenant %s in %s: %w", t.ID, path, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Create adds a new tenant and persists the store
+func (s *FileStore) Create(t Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.create(t); err != nil {
+		return err
+	}
+	return s.save(func() { delete(s.tenants, t.ID) })
+}
+
+// Update replaces an existing tenant and persists the store
+func (s *FileStore) Update(t Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.tenants[t.ID]
+	if err := s.update(t); err != nil {
+		return err
+	}
+	return s.save(func() { s.tenants[t.ID] = previous })
+}
+
+// Delete removes a tenant and persists the store
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.tenants[id]
+	if err := s.delete(id); err != nil {
+		return err
+	}
+	return s.save(func() { s.tenants[id] = previous })
+}
+
+// save atomically rewrites the file, calling rollback to undo the in-memory
+// change if it can't be written. Must be called with the lock held.
+func (s *FileStore) save(rollback func()) error {
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to encode tenant store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tenants-*")
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to write tenant store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		rollback()
+		return fmt.Errorf("failed to write tenant store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		rollback()
+		return fmt.Errorf("failed to write tenant store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		rollback()
+		return fmt.Errorf("failed to write tenant store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package tenant stores the tenants served by the signer: each tenant has its
+// own key prefix in the bucket, an optional storage quota and content type
+// allowlist, and an API key identifying its requests.
+package tenant
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/cronwindow"
+)
+
+var (
+	// ErrNotFound is returned when a tenant ID is unknown
+	ErrNotFound = errors.New("tenant not found")
+	// ErrExists is returned when creating a tenant whose ID is taken
+	ErrExists = errors.New("tenant already exists")
+	// ErrPrefixConflict is returned when a prefix overlaps another tenant's
+	ErrPrefixConflict = errors.New("prefix overlaps another tenant")
+)
+
+var (
+	idPattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+	prefixPattern = regexp.MustCompile(`^[A-Za-z0-9!_.*'()-]+(/[A-Za-z0-9!_.*'()-]+)*$`)
+	rolePattern   = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]{1,512}$`)
+)
+
+// Tenant is a customer of the signer with its own key prefix
+type Tenant struct {
+	ID                  string           `json:"tenant_id"`
+	Prefix              string           `json:"prefix"`
+	QuotaBytes          int64            `json:"quota_bytes,omitempty"`           // 0 means unlimited
+	AllowedContentTypes []string         `json:"allowed_content_types,omitempty"` // Exact or type/*; empty allows any
+	MinRetentionHours   int              `json:"min_retention_hours,omitempty"`   // Overrides MIN_RETENTION_HOURS when set
+	UploadWindows       []string         `json:"upload_windows,omitempty"`        // Cron expressions; empty allows uploads at any time
+	UploadTimezone      string           `json:"upload_timezone,omitempty"`       // IANA zone of UploadWindows, UTC when empty
+	ExpectedBackups     []ExpectedBackup `json:"expected_backups,omitempty"`      // Uploads checked for staleness
+	RoleARN             string           `json:"role_arn,omitempty"`              // IAM role the tenant's S3 calls and URLs use
+	RoleExternalID      string           `json:"role_external_id,omitempty"`      // External ID the role's trust policy requires
+	APIKeyHash          string           `json:"api_key_hash"`
+	CreatedAt           time.Time        `json:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at"`
+}
+
+// ExpectedBackup is a file name or glob that should receive a new upload at
+// least every MaxAgeHours
+type ExpectedBackup struct {
+	Filename    string `json:"filename"`
+	MaxAgeHours int    `json:"max_age_hours"`
+}
+
+// Validate checks the tenant's ID, prefix, quota, content types, retention,
+// upload windows, expected backups and role
+func (t *Tenant) Validate() error {
+	if !idPattern.MatchString(t.ID) {
+		return fmt.Errorf("tenant_id must be 1-63 lowercase letters, digits or dashes")
+	}
+	if !prefixPattern.MatchString(t.Prefix) || strings.Contains(t.Prefix, "..") {
+		return fmt.Errorf("prefix must be a relative key path without empty, '.' or '..' segments (got %q)", t.Prefix)
+	}
+	if t.QuotaBytes < 0 {
+		return fmt.Errorf("quota_bytes must not be negative")
+	}
+	if t.MinRetentionHours < 0 {
+		return fmt.Errorf("min_retention_hours must not be negative")
+	}
+	for _, contentType := range t.AllowedContentTypes {
+		if _, _, err := mime.ParseMediaType(strings.Replace(contentType, "/*", "/x", 1)); err != nil {
+			return fmt.Errorf("invalid content type %q", contentType)
+		}
+	}
+	if _, err := t.UploadSchedule(); err != nil {
+		return fmt.Errorf("upload_windows: %w", err)
+	}
+	for _, expected := range t.ExpectedBackups {
+		if expected.Filename == "" || strings.Contains(expected.Filename, "/") {
+			return fmt.Errorf("expected_backups: filename must be a file name or glob without / (got %q)", expected.Filename)
+		}
+		if _, err := path.Match(expected.Filename, ""); err != nil {
+			return fmt.Errorf("expected_backups: invalid filename pattern %q", expected.Filename)
+		}
+		if expected.MaxAgeHours < 1 {
+			return fmt.Errorf("expected_backups: max_age_hours of %s must be at least 1", expected.Filename)
+		}
+	}
+	if t.RoleARN != "" && !rolePattern.MatchString(t.RoleARN) {
+		return fmt.Errorf("role_arn must look like arn:aws:iam::<account-id>:role/<name> (got %q)", t.RoleARN)
+	}
+	if t.RoleExternalID != "" && (t.RoleARN == "" || len(t.RoleExternalID) < 2 || len(t.RoleExternalID) > 1224) {
+		return fmt.Errorf("role_external_id must be 2 to 1224 characters and requires role_arn")
+	}
+	return nil
+}
+
+// UploadSchedule returns the windows during which the tenant may be issued
+// upload URLs
+func (t *Tenant) UploadSchedule() (*cronwindow.Schedule, error) {
+	return cronwindow.NewSchedule(t.UploadWindows, t.UploadTimezone)
+}
+
+// Store persists tenants. Implementations must be safe for concurrent use.
+type Store interface {
+	// List returns all tenants ordered by ID
+	List() ([]Tenant, error)
+	// Get returns the tenant with the given ID
+	Get(id string) (Tenant, error)
+	// GetByAPIKeyHash returns the tenant whose API key hashes to hash
+	GetByAPIKeyHash(hash string) (Tenant, error)
+	// Create adds a new tenant
+	Create(t Tenant) error
+	// Update replaces an existing tenant
+	Update(t Tenant) error
+	// Delete removes a tenant
+	Delete(id string) error
+}
+
+// NewAPIKey returns a random API key and its hash
+func NewAPIKey() (string, string) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	key := "tk_" + hex.EncodeToString(b)
+	return key, HashAPIKey(key)
+}
+
+// HashAPIKey returns the hash under which an API key is stored. Keys are
+// 256-bit random values, so an unsalted SHA-256 is enough to make a leaked
+// store useless for authentication.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// prefixesOverlap reports whether one prefix contains the other, which would
+// let one tenant reach the other's objects
+func prefixesOverlap(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
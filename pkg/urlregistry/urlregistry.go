@@ -0,0 +1,172 @@
+// Package urlregistry records the presigned URLs issued by the signer so they
+// can be verified and revoked before they expire. S3 itself keeps honoring a
+// revoked URL; revocation is enforced by whatever checks the verify endpoint.
+package urlregistry
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often expired entries are dropped
+const pruneInterval = time.Minute
+
+var (
+	// ErrNotFound is returned for URLs that weren't issued here or have
+	// expired and been pruned
+	ErrNotFound = errors.New("presigned URL not issued by this signer")
+	// ErrNoSignature is returned for URLs without an X-Amz-Signature
+	ErrNoSignature = errors.New("URL has no X-Amz-Signature")
+)
+
+// Entry describes an issued presigned URL
+type Entry struct {
+	Method    string     `json:"method"`
+	ObjectKey string     `json:"object_key"`
+	Subject   string     `json:"subject,omitempty"`   // Caller the URL was issued to
+	UploadID  string     `json:"upload_id,omitempty"` // Correlation ID of an upload URL
+	IssuedAt  time.Time  `json:"issued_at"`
+	NotBefore *time.Time `json:"not_before,omitempty"` // Start of a deferred URL's window
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// Registry keeps issued URLs in memory, keyed by signature, until they expire
+// (plus the retention)
+type Registry struct {
+	mu        sync.Mutex
+	entries   map[string]*Entry
+	retention time.Duration
+	lastPrune time.Time
+}
+
+// New creates an empty registry
+func New() *Registry {
+	return NewWithRetention(0)
+}
+
+// NewWithRetention creates an empty registry keeping URLs for retention after
+// they expire, so requests logged later can still be matched to them
+func NewWithRetention(retention time.Duration) *Registry {
+	return &Registry{entries: make(map[string]*Entry), retention: retention}
+}
+
+// Signature extracts the X-Amz-Signature identifying a presigned URL
+func Signature(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	signature := u.Query().Get("X-Amz-Signature")
+	if signature == "" {
+		return "", ErrNoSignature
+	}
+	return signature, nil
+}
+
+// Record registers an issued URL
+func (r *Registry) Record(rawURL string, entry Entry) error {
+	signature, err := Signature(rawURL)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(entry.IssuedAt)
+	r.entries[signature] = &entry
+	return nil
+}
+
+// Lookup returns the entry of an issued URL
+func (r *Registry) Lookup(rawURL string) (Entry, error) {
+	signature, err := Signature(rawURL)
+	if err != nil {
+		return Entry{}, err
+	}
+	return r.LookupSignature(signature)
+}
+
+// LookupSignature returns the entry of the issued URL with an X-Amz-Signature
+func (r *Registry) LookupSignature(signature string) (Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[signature]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return *entry, nil
+}
+
+// LookupUpload returns the entry of the upload URL issued with uploadID
+func (r *Registry) LookupUpload(uploadID string) (Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range r.entries {
+		if uploadID != "" && entry.UploadID == uploadID {
+			return *entry, nil
+		}
+	}
+	return Entry{}, ErrNotFound
+}
+
+// List returns up to limit entries for which match reports true, most
+// recently issued first
+func (r *Registry) List(match func(Entry) bool, limit int) []Entry {
+	r.mu.Lock()
+	entries := []Entry{}
+	for _, entry := range r.entries {
+		if match(*entry) {
+			entries = append(entries, *entry)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].IssuedAt.After(entries[j].IssuedAt) })
+	return entries[:min(limit, len(entries))]
+}
+
+// Revoke marks an issued URL as revoked. Revoking twice keeps the first
+// revocation.
+func (r *Registry) Revoke(rawURL, reason string) (Entry, error) {
+	signature, err := Signature(rawURL)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[signature]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	if entry.RevokedAt == nil {
+		now := time.Now().UTC()
+		entry.RevokedAt = &now
+		entry.Reason = reason
+	}
+	return *entry, nil
+}
+
+// prune drops entries expired for longer than the retention at most every
+// pruneInterval. Must be called with the lock held.
+func (r *Registry) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < pruneInterval {
+		return
+	}
+	r.lastPrune = now
+	for signature, entry := range r.entries {
+		if now.After(entry.ExpiresAt.Add(r.retention)) {
+			delete(r.entries, signature)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package webhook posts JSON event notifications to an HTTP endpoint, signed
+// with HMAC-SHA256 so receivers can verify they come from the signer.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/andressep95/aws-backup-bridge/signer-service/pkg/resilience"
+)
+
+// Event is the body of a notification
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Sender posts events to a URL
+type Sender struct {
+	url    string
+	secret []byte
+	client *http.Client
+	retry  resilience.RetryPolicy
+}
+
+// NewSender creates a sender posting to url. When secret is set, each
+// request carries X-Signature-256: sha256=<hex HMAC-SHA256 of the body>.
+func NewSender(url, secret string) *Sender {
+	return &Sender{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 10 * time.Second},
+		retry:  resilience.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second},
+	}
+}
+
+// Send posts event, retrying failed deliveries with backoff. Any non-2xx
+// response is an error.
+func (s *Sender) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	retryable := func(error) bool { return true }
+	return resilience.Retry(ctx, s.retry, retryable, nil, func(ctx context.Context) error {
+		return s.post(ctx, event.Type, body)
+	})
+}
+
+func (s *Sender) post(ctx context.Context, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", eventType)
+	if len(s.secret) > 0 {
+		req.Header.Set("X-Signature-256", "sha256="+Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body under secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}